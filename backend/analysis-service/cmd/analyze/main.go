@@ -0,0 +1,244 @@
+// Command analyze runs the game analyzer over a PGN file without the gRPC
+// service and writes the results as JSON, annotated PGN, CSV or one summary
+// line per game, the default for files of more than one game.
+//
+//	analyze --pgn games.pgn --depth 18 --format pgn --out annotated.pgn
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Exit codes, so scripts can tell bad input from a broken engine. When
+// games fail for both reasons the engine code wins.
+const (
+	exitOK         = 0
+	exitUsage      = 1 // Bad flags, unreadable input or unwritable output
+	exitParseError = 2 // At least one game could not be parsed or is of an unsupported variant
+	exitEngine     = 3 // The engine failed to start or to analyze a game
+
+	exitInterrupted = 130
+)
+
+type options struct {
+	pgnPath   string
+	outPath   string
+	format    string
+	depth     int
+	profile   string
+	untilErr  bool
+	variants  bool
+	stockfish string
+	engines   int
+	threads   int
+	hash      int
+	timeout   time.Duration
+	verbose   bool
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func parseFlags(args []string, stderr io.Writer) (*options, error) {
+	stockfish := os.Getenv("STOCKFISH_PATH")
+	if stockfish == "" {
+		stockfish = "/usr/local/bin/stockfish"
+	}
+
+	opts := &options{}
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.pgnPath, "pgn", "", "PGN file to analyze, - for stdin (required)")
+	fs.StringVar(&opts.outPath, "out", "", "write results to this file instead of stdout")
+	fs.StringVar(&opts.format, "format", "", "output format: json, pgn, csv or summary (default summary for several games, else json)")
+	fs.IntVar(&opts.depth, "depth", 18, "search depth")
+	fs.StringVar(&opts.profile, "profile", "", "move classification threshold profile (default standard)")
+	fs.BoolVar(&opts.untilErr, "until-error", false, "analyze the moves before an illegal move instead of skipping the game")
+	fs.BoolVar(&opts.variants, "variants", false, "analyze games of chess variants as standard chess instead of skipping them")
+	fs.StringVar(&opts.stockfish, "stockfish", stockfish, "Stockfish binaries to try, separated by colons, then stockfish on PATH (env STOCKFISH_PATH)")
+	fs.IntVar(&opts.engines, "engines", 2, "number of Stockfish engines")
+	fs.IntVar(&opts.threads, "threads", 1, "threads per engine")
+	fs.IntVar(&opts.hash, "hash", 256, "hash table size per engine in MB")
+	fs.DurationVar(&opts.timeout, "timeout", 10*time.Minute, "budget per game, partial results are written when it runs out")
+	fs.BoolVar(&opts.verbose, "v", false, "log analyzer activity to stderr")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if opts.pgnPath == "" {
+		return nil, errors.New("--pgn is required")
+	}
+	if _, ok := writers[opts.format]; opts.format != "" && !ok {
+		return nil, fmt.Errorf("--format=%q must be json, pgn, csv or summary", opts.format)
+	}
+	if opts.depth < 1 {
+		return nil, fmt.Errorf("--depth=%d must be at least 1", opts.depth)
+	}
+	if opts.engines < 1 {
+		return nil, fmt.Errorf("--engines=%d must be at least 1", opts.engines)
+	}
+	return opts, nil
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	opts, err := parseFlags(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "analyze:", err)
+		return exitUsage
+	}
+
+	var data []byte
+	if opts.pgnPath == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(opts.pgnPath)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "analyze:", err)
+		return exitUsage
+	}
+	games := analyzer.SplitPGN(string(data))
+	if len(games) == 0 {
+		fmt.Fprintln(stderr, "analyze: no games found in", opts.pgnPath)
+		return exitParseError
+	}
+	if opts.format == "" {
+		opts.format = "json"
+		if len(games) > 1 {
+			opts.format = "summary"
+		}
+	}
+
+	out := stdout
+	if opts.outPath != "" {
+		f, err := os.Create(opts.outPath)
+		if err != nil {
+			fmt.Fprintln(stderr, "analyze:", err)
+			return exitUsage
+		}
+		defer f.Close()
+		out = f
+	}
+
+	logger := zap.NewNop()
+	if opts.verbose {
+		cfg := zap.NewDevelopmentConfig()
+		cfg.Level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+		if logger, err = cfg.Build(); err != nil {
+			fmt.Fprintln(stderr, "analyze:", err)
+			return exitUsage
+		}
+		defer logger.Sync()
+	}
+
+	binary, err := engine.Discover(engine.SplitPaths(opts.stockfish), logger)
+	if err != nil {
+		fmt.Fprintln(stderr, "analyze: start engine:", err)
+		return exitEngine
+	}
+	enginePool, err := pool.NewPool(opts.engines, engine.Config{
+		BinaryPath: binary,
+		Threads:    opts.threads,
+		Hash:       opts.hash,
+		MultiPV:    1,
+	}, logger)
+	if err != nil {
+		fmt.Fprintln(stderr, "analyze: start engine:", err)
+		return exitEngine
+	}
+	defer enginePool.Close()
+
+	// The depth asked for is searched as is
+	a := analyzer.NewAnalyzer(enginePool, logger, 1, opts.depth, opts.depth, opts.timeout)
+	a.SetAnalyzeVariants(opts.variants)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	w := writers[opts.format](out)
+	code := exitOK
+	for i, game := range games {
+		if ctx.Err() != nil {
+			break
+		}
+		id := strconv.Itoa(i + 1)
+		label := fmt.Sprintf("game %d/%d", i+1, len(games))
+		if white, black := game.Tag("White"), game.Tag("Black"); white != "" || black != "" {
+			label += fmt.Sprintf(" (%s - %s)", white, black)
+		}
+
+		progress := func(current, total int, move *analyzer.MoveAnalysis) {
+			if move == nil {
+				fmt.Fprintf(stderr, "\r%s: %d/%d positions", label, current, total)
+			}
+		}
+		gameOpts := analyzer.GameOptions{ThresholdProfile: opts.profile, AnalyzeUntilError: opts.untilErr}
+		analysis, err := a.AnalyzeGame(ctx, id, game.Text, opts.depth, gameOpts, progress)
+		fmt.Fprint(stderr, "\r\033[K")
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", label, err)
+			switch {
+			case errors.Is(err, analyzer.ErrInvalidPGN), errors.Is(err, analyzer.ErrUnsupportedVariant):
+				if code == exitOK {
+					code = exitParseError
+				}
+			case errors.Is(err, analyzer.ErrUnknownThresholdProfile):
+				return exitUsage
+			default:
+				code = exitEngine
+			}
+			continue
+		}
+
+		status := "done"
+		if analysis.TimedOut {
+			status = fmt.Sprintf("timed out after %d of %d moves", len(analysis.Moves), analysis.TotalMoves)
+		}
+		if analysis.Truncated {
+			fmt.Fprintf(stderr, "%s: %s\n", label, analysis.TruncationError)
+			status += fmt.Sprintf(", truncated at ply %d", analysis.TruncatedAtPly)
+		}
+		fmt.Fprintf(stderr, "%s: %s in %.1fs (white %.1f%%, black %.1f%%)\n", label, status,
+			float64(analysis.TotalTimeMs)/1000, analysis.WhiteMetrics.Accuracy, analysis.BlackMetrics.Accuracy)
+
+		if err := w.write(game, analysis); err != nil {
+			fmt.Fprintln(stderr, "analyze: write results:", err)
+			return exitUsage
+		}
+	}
+
+	if err := w.close(); err != nil {
+		fmt.Fprintln(stderr, "analyze: write results:", err)
+		return exitUsage
+	}
+	if ctx.Err() != nil {
+		fmt.Fprintln(stderr, "analyze: interrupted")
+		return exitInterrupted
+	}
+	return code
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"github.com/notnil/chess"
+)
+
+func TestMain(m *testing.M) {
+	enginetest.Main()
+	os.Exit(m.Run())
+}
+
+// fakeEngine scores positions from -120 to 240 so evaluations differ
+// between moves, and answers e2e4
+var fakeEngine = enginetest.Engine{
+	Default: enginetest.Eval{Centipawns: -120, BestMove: "e2e4"},
+	Spread:  360,
+}
+
+const twoGames = `[Event "Club"]
+[Site "?"]
+[White "Alice"]
+[Black "Bob"]
+[Result "1-0"]
+[ECO "C60"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 1-0
+
+[Event "Club"]
+[White "Bob"]
+[Black "Alice"]
+[Result "0-1"]
+
+1. d4 d5 2. c4 e6 0-1
+`
+
+func writeFile(t *testing.T, name, content string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func runAnalyze(t *testing.T, pgn string, args ...string) (int, string, string) {
+	t.Helper()
+	engine := fakeEngine.Binary(t)
+	input := writeFile(t, "games.pgn", pgn, 0o644)
+
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"--pgn", input, "--stockfish", engine, "--engines", "1", "--depth", "8"}, args...)
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_AnnotatedPGNParses(t *testing.T) {
+	code, stdout, stderr := runAnalyze(t, twoGames, "--format", "pgn")
+	if code != exitOK {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr)
+	}
+	if !strings.Contains(stderr, "game 2/2 (Bob - Alice): done") {
+		t.Errorf("stderr lacks progress:\n%s", stderr)
+	}
+	if strings.Count(stdout, "[%eval ") != 10 {
+		t.Errorf("want an eval on each of the 10 moves:\n%s", stdout)
+	}
+
+	scanner := chess.NewScanner(strings.NewReader(stdout))
+	var results []chess.Outcome
+	for scanner.Scan() {
+		game := scanner.Next()
+		if len(game.Moves()) == 0 {
+			continue // The scanner reports the blank line after the last game
+		}
+		if tag := game.GetTagPair("ECO"); results == nil && (tag == nil || tag.Value != "C60") {
+			t.Errorf("original tags should be kept, ECO = %v", tag)
+		}
+		if n := len(game.Moves()); n != 6 && n != 4 {
+			t.Errorf("game has %d moves", n)
+		}
+		results = append(results, game.Outcome())
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		t.Fatalf("annotated PGN does not parse: %v\n%s", err, stdout)
+	}
+	if len(results) != 2 || results[0] != chess.WhiteWon || results[1] != chess.BlackWon {
+		t.Errorf("results = %v", results)
+	}
+}
+
+func TestRun_JSONAndCSV(t *testing.T) {
+	code, stdout, stderr := runAnalyze(t, twoGames, "--format", "json")
+	if code != exitOK {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr)
+	}
+	var analyses []map[string]interface{}
+	if err := json.Unmarshal([]byte(stdout), &analyses); err != nil {
+		t.Fatalf("output is not a JSON array: %v\n%s", err, stdout)
+	}
+	if len(analyses) != 2 || analyses[1]["gameId"] != "2" {
+		t.Errorf("analyses = %v", analyses)
+	}
+
+	code, stdout, stderr = runAnalyze(t, twoGames, "--format", "csv")
+	if code != exitOK {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr)
+	}
+	rows, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 11 || rows[0][0] != "game" || rows[1][4] != "e4" || rows[10][0] != "2" {
+		t.Errorf("rows = %v", rows)
+	}
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	bad := twoGames + "\n[Event \"Broken\"]\n\n1. e4 Ke7 Qxf7 *\n"
+	code, stdout, stderr := runAnalyze(t, bad, "--format", "pgn")
+	if code != exitParseError {
+		t.Errorf("parse error exit = %d, want %d\n%s", code, exitParseError, stderr)
+	}
+	if strings.Count(stdout, "[Event ") != 2 {
+		t.Errorf("valid games should still be written:\n%s", stdout)
+	}
+
+	// Without falling back to a stockfish installed on this machine
+	t.Setenv("PATH", t.TempDir())
+	var out, errOut bytes.Buffer
+	input := writeFile(t, "games.pgn", twoGames, 0o644)
+	code = run([]string{"--pgn", input, "--stockfish", filepath.Join(t.TempDir(), "missing")}, &out, &errOut)
+	if code != exitEngine {
+		t.Errorf("missing engine exit = %d, want %d", code, exitEngine)
+	}
+
+	if code, _, _ := runAnalyze(t, twoGames, "--format", "xml"); code != exitUsage {
+		t.Errorf("bad format exit = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestRun_SummaryByDefault(t *testing.T) {
+	code, stdout, stderr := runAnalyze(t, twoGames)
+	if code != exitOK {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "game 1 (Alice - Bob): White ") || !strings.HasPrefix(lines[1], "game 2 (Bob - Alice): White ") {
+		t.Errorf("summary lines = %q", lines)
+	}
+
+	// A single game is written as JSON unless asked otherwise
+	single := twoGames[:strings.Index(twoGames, "1-0\n")+4]
+	code, stdout, stderr = runAnalyze(t, single)
+	if code != exitOK {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr)
+	}
+	var analyses []map[string]interface{}
+	if err := json.Unmarshal([]byte(stdout), &analyses); err != nil {
+		t.Fatalf("output is not a JSON array: %v\n%s", err, stdout)
+	}
+	if summary, _ := analyses[0]["summary"].(string); len(analyses) != 1 || !strings.HasPrefix(summary, "White ") {
+		t.Errorf("analyses = %v", analyses)
+	}
+}
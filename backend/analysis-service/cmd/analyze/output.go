@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	servergrpc "github.com/eloinsight/analysis-service/internal/grpc"
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+)
+
+// resultWriter writes analyses in one output format
+type resultWriter interface {
+	write(game analyzer.PGNGame, analysis *analyzer.GameAnalysis) error
+	close() error
+}
+
+var writers = map[string]func(io.Writer) resultWriter{
+	"json": func(w io.Writer) resultWriter { return &jsonWriter{w: w} },
+	"pgn":  func(w io.Writer) resultWriter { return &pgnWriter{w: w} },
+	"csv":  func(w io.Writer) resultWriter { return newCSVWriter(w) },
+
+	"summary": func(w io.Writer) resultWriter { return &summaryWriter{w: w} },
+}
+
+// jsonWriter writes a JSON array of GameAnalysis messages, in the same
+// form the service publishes them
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *jsonWriter) write(game analyzer.PGNGame, analysis *analyzer.GameAnalysis) error {
+	data, err := servergrpc.EncodeGameAnalysis(analysis)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if j.count == 0 {
+		sep = "[\n"
+	}
+	j.count++
+	_, err = fmt.Fprintf(j.w, "%s%s", sep, data)
+	return err
+}
+
+func (j *jsonWriter) close() error {
+	if j.count == 0 {
+		_, err := io.WriteString(j.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
+
+// pgnWriter writes each game as an annotated PGN, as ExportGameAnalysis
+// does
+type pgnWriter struct {
+	w io.Writer
+}
+
+func (p *pgnWriter) write(game analyzer.PGNGame, analysis *analyzer.GameAnalysis) error {
+	pgn, err := analyzer.ExportAnnotatedPGN(analysis, game.Text)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n", pgn)
+	return err
+}
+
+func (p *pgnWriter) close() error { return nil }
+
+// csvWriter writes one row per analyzed move
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	cw.w.Write([]string{
+		"game", "ply", "move_number", "color", "move", "best_move",
+		"eval", "cp_loss", "accuracy", "classification", "depth",
+	})
+	return cw
+}
+
+func (c *csvWriter) write(game analyzer.PGNGame, analysis *analyzer.GameAnalysis) error {
+	for i := range analysis.Moves {
+		move := &analysis.Moves[i]
+		c.w.Write([]string{
+			analysis.GameID,
+			strconv.Itoa(move.Ply + 1),
+			strconv.Itoa(move.MoveNumber),
+			move.Color,
+			move.PlayedMove,
+			move.BestMove,
+			analyzer.FormatEval(move),
+			strconv.Itoa(move.CentipawnLoss),
+			strconv.FormatFloat(move.MoveAccuracy, 'f', 1, 64),
+			string(move.Classification),
+			strconv.Itoa(move.AchievedDepth),
+		})
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// summaryWriter writes one line per game: its number, players and summary
+type summaryWriter struct {
+	w io.Writer
+}
+
+func (s *summaryWriter) write(game analyzer.PGNGame, analysis *analyzer.GameAnalysis) error {
+	name := "game " + analysis.GameID
+	if white, black := game.Tag("White"), game.Tag("Black"); white != "" || black != "" {
+		name += fmt.Sprintf(" (%s - %s)", white, black)
+	}
+	summary := analysis.Summary
+	if summary == "" {
+		summary = "no moves analyzed"
+	}
+	_, err := fmt.Fprintf(s.w, "%s: %s\n", name, summary)
+	return err
+}
+
+func (s *summaryWriter) close() error { return nil }
@@ -0,0 +1,273 @@
+// Command backfill scores stored game analyses again after a change of
+// classification thresholds or accuracy model, from their stored
+// evaluations and without an engine, and reports what changed.
+//
+//	backfill --dir analyses --out rescored --profile strict --report report.json
+//	backfill --postgres-dsn "$POSTGRES_DSN" --checkpoint backfill.ckpt
+//
+// Analyses come from a directory of GameAnalysis JSON payloads, one per
+// file, or from the game_analyses and move_analyses tables of the
+// persistence sink, and are written back the same way. A checkpoint file
+// records each game done, so an interrupted run started again with the
+// same checkpoint picks up where it stopped; scoring an analysis again
+// under the same options changes nothing, so games done twice are harmless.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/eloinsight/analysis-service/internal/config"
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	"go.uber.org/zap"
+)
+
+const (
+	exitOK     = 0
+	exitUsage  = 1 // Bad flags, unreadable checkpoint or unwritable report
+	exitFailed = 2 // At least one game could not be read, scored or written
+
+	exitInterrupted = 130
+)
+
+type options struct {
+	dir            string
+	outDir         string
+	dsn            string
+	reportPath     string
+	checkpointPath string
+	workers        int
+	dryRun         bool
+	profile        string
+	accuracyModel  string
+	accuracyMethod string
+	thresholds     config.ThresholdsConfig
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func parseFlags(args []string, stderr io.Writer) (*options, error) {
+	accuracyMethod := os.Getenv("ACCURACY_METHOD")
+	if accuracyMethod == "" {
+		accuracyMethod = string(evaluation.AccuracyCappedLoss)
+	}
+
+	opts := &options{}
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.dir, "dir", "", "directory of GameAnalysis JSON files to score again")
+	fs.StringVar(&opts.outDir, "out", "", "directory to write the rescored JSON files to, may be --dir (required with --dir unless --dry-run)")
+	fs.StringVar(&opts.dsn, "postgres-dsn", "", "score the analyses stored in this database again, in place")
+	fs.StringVar(&opts.reportPath, "report", "", "write the change report to this file instead of stdout")
+	fs.StringVar(&opts.checkpointPath, "checkpoint", "", "record finished games in this file and skip those it lists")
+	fs.IntVar(&opts.workers, "workers", 4, "games scored at once")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "report the changes without writing analyses or the checkpoint")
+	fs.StringVar(&opts.profile, "profile", "", "threshold profile to classify with (default: each analysis's own)")
+	fs.StringVar(&opts.accuracyModel, "accuracy-model", "", "accuracy model to score with (default: each analysis's own)")
+	fs.StringVar(&opts.accuracyMethod, "accuracy-method", accuracyMethod, "game accuracy: capped_loss or move_mean (env ACCURACY_METHOD)")
+	fs.StringVar(&opts.thresholds.Standard, "thresholds-standard", os.Getenv("THRESHOLDS_STANDARD"), "override standard thresholds (env THRESHOLDS_STANDARD)")
+	fs.StringVar(&opts.thresholds.Strict, "thresholds-strict", os.Getenv("THRESHOLDS_STRICT"), "override strict thresholds (env THRESHOLDS_STRICT)")
+	fs.StringVar(&opts.thresholds.Lenient, "thresholds-lenient", os.Getenv("THRESHOLDS_LENIENT"), "override lenient thresholds (env THRESHOLDS_LENIENT)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if (opts.dir == "") == (opts.dsn == "") {
+		return nil, errors.New("one of --dir and --postgres-dsn is required")
+	}
+	if opts.dir != "" && opts.outDir == "" && !opts.dryRun {
+		return nil, errors.New("--out is required with --dir")
+	}
+	if opts.dsn != "" && opts.outDir != "" {
+		return nil, errors.New("--out is for --dir; database analyses are rescored in place")
+	}
+	if opts.workers < 1 {
+		return nil, fmt.Errorf("--workers=%d must be at least 1", opts.workers)
+	}
+	return opts, nil
+}
+
+// newAnalyzer returns an analyzer without engines, scoring as the service
+// configured by opts would
+func newAnalyzer(opts *options) (*analyzer.Analyzer, error) {
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 1, 1, time.Minute)
+	profiles, err := opts.thresholds.Profiles()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.SetThresholdProfiles(profiles, evaluation.ProfileStandard); err != nil {
+		return nil, err
+	}
+	if err := a.SetAccuracyMethod(evaluation.AccuracyMethod(opts.accuracyMethod)); err != nil {
+		return nil, err
+	}
+	if _, _, err := a.Thresholds(opts.profile); opts.profile != "" && err != nil {
+		return nil, err
+	}
+	if model := evaluation.AccuracyModel(opts.accuracyModel); model != "" && !model.Valid() {
+		return nil, fmt.Errorf("%w: %q", analyzer.ErrUnknownAccuracyModel, model)
+	}
+	return a, nil
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	opts, err := parseFlags(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "backfill:", err)
+		return exitUsage
+	}
+	a, err := newAnalyzer(opts)
+	if err != nil {
+		fmt.Fprintln(stderr, "backfill:", err)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var src source
+	if opts.dir != "" {
+		src = &dirSource{dir: opts.dir, outDir: opts.outDir}
+	} else {
+		db, err := openDatabase(ctx, opts.dsn)
+		if err != nil {
+			fmt.Fprintln(stderr, "backfill:", err)
+			return exitUsage
+		}
+		defer db.close()
+		src = db
+	}
+	ids, err := src.ids(ctx)
+	if err != nil {
+		fmt.Fprintln(stderr, "backfill:", err)
+		return exitUsage
+	}
+
+	// A dry run neither skips nor records games
+	var ckpt *checkpoint
+	if opts.checkpointPath != "" && !opts.dryRun {
+		if ckpt, err = openCheckpoint(opts.checkpointPath); err != nil {
+			fmt.Fprintln(stderr, "backfill:", err)
+			return exitUsage
+		}
+		defer ckpt.close()
+	}
+	var todo []string
+	for _, id := range ids {
+		if ckpt == nil || !ckpt.done(id) {
+			todo = append(todo, id)
+		}
+	}
+	if skipped := len(ids) - len(todo); skipped > 0 {
+		fmt.Fprintf(stderr, "%d of %d games already done, as the checkpoint records\n", skipped, len(ids))
+	}
+
+	reclassify := analyzer.ReclassifyOptions{
+		ThresholdProfile: opts.profile,
+		AccuracyModel:    evaluation.AccuracyModel(opts.accuracyModel),
+	}
+	rep := newReport(opts.dryRun)
+	if ckpt != nil {
+		for _, change := range ckpt.changes {
+			rep.add(change)
+		}
+	}
+
+	jobs := make(chan string)
+	var mu sync.Mutex // Guards rep, ckpt and stderr
+	code := exitOK
+	var wg sync.WaitGroup
+	for w := 0; w < opts.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				change, err := backfillGame(ctx, a, src, id, reclassify, opts.dryRun)
+				mu.Lock()
+				if err == nil && ckpt != nil {
+					err = ckpt.record(change)
+				}
+				if err != nil {
+					if ctx.Err() == nil {
+						fmt.Fprintf(stderr, "%s: %v\n", id, err)
+						rep.fail(id, err)
+						code = exitFailed
+					}
+				} else {
+					rep.add(change)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, id := range todo {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := writeReport(opts.reportPath, stdout, rep.finish()); err != nil {
+		fmt.Fprintln(stderr, "backfill: write report:", err)
+		return exitUsage
+	}
+	fmt.Fprintf(stderr, "%d games, %d with changes: %d of %d moves changed class, average accuracy delta %+.2f\n",
+		rep.Games, rep.ChangedGames, rep.ChangedMoves, rep.Moves, rep.AverageAccuracyDelta)
+	if ctx.Err() != nil {
+		fmt.Fprintln(stderr, "backfill: interrupted")
+		return exitInterrupted
+	}
+	return code
+}
+
+// backfillGame scores the analysis of id again and, unless dryRun, writes
+// it back
+func backfillGame(ctx context.Context, a *analyzer.Analyzer, src source, id string, opts analyzer.ReclassifyOptions, dryRun bool) (gameChange, error) {
+	stored, err := src.load(ctx, id)
+	if err != nil {
+		return gameChange{}, err
+	}
+	rescored, err := a.Reclassify(stored, opts)
+	if err != nil {
+		return gameChange{}, err
+	}
+	if !dryRun {
+		if err := src.save(ctx, id, rescored); err != nil {
+			return gameChange{}, err
+		}
+	}
+	return changeOf(id, stored, rescored), nil
+}
+
+func writeReport(path string, stdout io.Writer, rep *report) error {
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "" {
+		_, err = stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	"go.uber.org/zap"
+)
+
+const (
+	startFEN  = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	afterE4   = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	afterE4E5 = "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2"
+)
+
+// storedAnalysis returns an analysis of 1. e4 e5 scored under the standard
+// profile, 1. e4 losing 40 centipawns: good there, an inaccuracy under the
+// strict profile
+func storedAnalysis(t *testing.T, gameID string) *analyzer.GameAnalysis {
+	t.Helper()
+	raw := &analyzer.GameAnalysis{
+		GameID:     gameID,
+		Depth:      12,
+		TotalMoves: 2,
+		Thresholds: evaluation.DefaultThresholds,
+		Moves: []analyzer.MoveAnalysis{
+			{Ply: 0, MoveNumber: 1, Color: "white", PlayedMove: "e4", PlayedMoveUCI: "e2e4", BestMove: "d4", BestMoveUCI: "d2d4",
+				FENBefore: startFEN, FENAfter: afterE4, AchievedDepth: 12,
+				EvalBefore: engine.Evaluation{Centipawns: 30}, EvalAfter: engine.Evaluation{Centipawns: 10}},
+			{Ply: 1, MoveNumber: 1, Color: "black", PlayedMove: "e5", PlayedMoveUCI: "e7e5", BestMove: "e5", BestMoveUCI: "e7e5",
+				FENBefore: afterE4, FENAfter: afterE4E5, AchievedDepth: 12,
+				EvalBefore: engine.Evaluation{Centipawns: -10}, EvalAfter: engine.Evaluation{Centipawns: 35}},
+		},
+	}
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 1, 1, time.Minute)
+	g, err := a.Reclassify(raw, analyzer.ReclassifyOptions{ThresholdProfile: evaluation.ProfileStandard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Moves[0].Classification != analyzer.ClassGood {
+		t.Fatalf("1. e4 is %s, want good", g.Moves[0].Classification)
+	}
+	return g
+}
+
+// writeAnalyses stores analyses of the games in a new directory, one file
+// each named after the game
+func writeAnalyses(t *testing.T, gameIDs ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, id := range gameIDs {
+		data, err := json.Marshal(storedAnalysis(t, id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, id+".json"), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func runBackfill(t *testing.T, args ...string) (int, *report, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	var rep report
+	if code == exitOK || code == exitFailed {
+		if err := json.Unmarshal(stdout.Bytes(), &rep); err != nil {
+			t.Fatalf("report is not JSON: %v\n%s", err, stdout.String())
+		}
+	}
+	return code, &rep, stderr.String()
+}
+
+func readAnalysis(t *testing.T, path string) *analyzer.GameAnalysis {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g analyzer.GameAnalysis
+	if err := json.Unmarshal(data, &g); err != nil {
+		t.Fatal(err)
+	}
+	if err := analyzer.VerifyGameAnalysis(&g); err != nil {
+		t.Fatal(err)
+	}
+	return &g
+}
+
+func TestRun_Dir(t *testing.T) {
+	dir := writeAnalyses(t, "g1", "g2")
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an analysis"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "rescored")
+
+	code, rep, stderr := runBackfill(t, "--dir", dir, "--out", out, "--profile", "strict", "--workers", "2")
+	if code != exitOK {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr)
+	}
+	if rep.Games != 2 || rep.ChangedGames != 2 || rep.Moves != 4 || rep.ChangedMoves != 2 || rep.Changes["good->inaccuracy"] != 2 {
+		t.Errorf("report = %+v", rep)
+	}
+	if rep.AverageAccuracyDelta != 0 {
+		t.Errorf("thresholds changed accuracy by %v", rep.AverageAccuracyDelta)
+	}
+	if !strings.Contains(stderr, "2 of 4 moves changed class") {
+		t.Errorf("stderr lacks the summary:\n%s", stderr)
+	}
+	g1 := readAnalysis(t, filepath.Join(out, "g1.json"))
+	if g1.ThresholdProfile != evaluation.ProfileStrict || g1.Moves[0].Classification != analyzer.ClassInaccuracy || g1.WhiteMetrics.Inaccuracies != 1 {
+		t.Errorf("rescored g1 = %s, %s, %+v", g1.ThresholdProfile, g1.Moves[0].Classification, g1.WhiteMetrics)
+	}
+	if _, err := os.Stat(filepath.Join(out, "notes.txt")); !os.IsNotExist(err) {
+		t.Errorf("a file other than an analysis was written: %v", err)
+	}
+
+	// Again in place: nothing changes
+	before, _ := os.ReadFile(filepath.Join(out, "g1.json"))
+	code, rep, stderr = runBackfill(t, "--dir", out, "--out", out, "--profile", "strict")
+	if code != exitOK || rep.Games != 2 || rep.ChangedMoves != 0 || rep.AverageAccuracyDelta != 0 {
+		t.Errorf("second run: exit %d, %+v\n%s", code, rep, stderr)
+	}
+	if after, _ := os.ReadFile(filepath.Join(out, "g1.json")); !bytes.Equal(before, after) {
+		t.Errorf("second run changed g1:\n%s\n%s", before, after)
+	}
+}
+
+func TestRun_AccuracyModel(t *testing.T) {
+	dir := writeAnalyses(t, "g1")
+	code, rep, stderr := runBackfill(t, "--dir", dir, "--dry-run", "--accuracy-model", "lichess")
+	if code != exitOK {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr)
+	}
+	if rep.ChangedMoves != 0 || rep.AverageAccuracyDelta == 0 ||
+		rep.AverageAccuracyDelta != (rep.AverageWhiteAccuracyDelta+rep.AverageBlackAccuracyDelta)/2 {
+		t.Errorf("report = %+v, want accuracy changed and classes not", rep)
+	}
+}
+
+func TestRun_DryRun(t *testing.T) {
+	dir := writeAnalyses(t, "g1")
+	out := filepath.Join(t.TempDir(), "rescored")
+	ckpt := filepath.Join(t.TempDir(), "backfill.ckpt")
+
+	code, rep, stderr := runBackfill(t, "--dir", dir, "--out", out, "--checkpoint", ckpt, "--profile", "strict", "--dry-run")
+	if code != exitOK || !rep.DryRun || rep.ChangedMoves != 1 {
+		t.Errorf("exit %d, report %+v\n%s", code, rep, stderr)
+	}
+	for _, path := range []string{out, ckpt} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("dry run wrote %s", path)
+		}
+	}
+	if g := readAnalysis(t, filepath.Join(dir, "g1.json")); g.ThresholdProfile != evaluation.ProfileStandard {
+		t.Errorf("dry run changed the input: %s", g.ThresholdProfile)
+	}
+}
+
+func TestRun_Checkpoint(t *testing.T) {
+	dir := writeAnalyses(t, "g1", "g2", "g3")
+	out := filepath.Join(t.TempDir(), "rescored")
+	ckpt := filepath.Join(t.TempDir(), "backfill.ckpt")
+
+	// g1 was done before a crash cut the line of g2 short
+	done := `{"id":"g1.json","moves":2,"changed_moves":1,"changes":{"good->inaccuracy":1},"white_accuracy_delta":0,"black_accuracy_delta":0}` + "\n" +
+		`{"id":"g2.js`
+	if err := os.WriteFile(ckpt, []byte(done), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, rep, stderr := runBackfill(t, "--dir", dir, "--out", out, "--checkpoint", ckpt, "--profile", "strict")
+	if code != exitOK {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr)
+	}
+	if !strings.Contains(stderr, "1 of 3 games already done") {
+		t.Errorf("stderr:\n%s", stderr)
+	}
+	if _, err := os.Stat(filepath.Join(out, "g1.json")); !os.IsNotExist(err) {
+		t.Error("the game the checkpoint lists was done again")
+	}
+	for _, name := range []string{"g2.json", "g3.json"} {
+		readAnalysis(t, filepath.Join(out, name))
+	}
+	// The report covers the games of both runs
+	if rep.Games != 3 || rep.ChangedMoves != 3 {
+		t.Errorf("report = %+v, want 3 games", rep)
+	}
+
+	data, err := os.ReadFile(ckpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"g1.json"`) {
+		t.Errorf("checkpoint:\n%s", data)
+	}
+
+	// Everything done: a third run does nothing but report
+	code, rep, _ = runBackfill(t, "--dir", dir, "--out", out, "--checkpoint", ckpt, "--profile", "strict")
+	if code != exitOK || rep.Games != 3 {
+		t.Errorf("rerun: exit %d, %+v", code, rep)
+	}
+}
+
+func TestRun_BadPayload(t *testing.T) {
+	dir := writeAnalyses(t, "g1", "g2")
+	g2 := storedAnalysis(t, "g2")
+	g2.WhiteMetrics.Accuracy = 12 // Not what its checksum covers
+	data, _ := json.Marshal(g2)
+	if err := os.WriteFile(filepath.Join(dir, "g2.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "g3.json"), []byte(`{"schema_version": 1,`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ckpt := filepath.Join(t.TempDir(), "backfill.ckpt")
+
+	code, rep, stderr := runBackfill(t, "--dir", dir, "--out", t.TempDir(), "--checkpoint", ckpt)
+	if code != exitFailed {
+		t.Errorf("exit %d, want %d\n%s", code, exitFailed, stderr)
+	}
+	if rep.Games != 1 || len(rep.Failed) != 2 || rep.Failed[0].ID != "g2.json" || !strings.Contains(rep.Failed[0].Error, "checksum") {
+		t.Errorf("report = %+v", rep)
+	}
+	// Failed games are left for the next run
+	if data, _ := os.ReadFile(ckpt); strings.Count(string(data), "\n") != 1 {
+		t.Errorf("checkpoint:\n%s", data)
+	}
+}
+
+func TestParseFlags_Errors(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "one of --dir and --postgres-dsn"},
+		{[]string{"--dir", dir, "--postgres-dsn", "postgres://x"}, "one of --dir and --postgres-dsn"},
+		{[]string{"--dir", dir}, "--out is required"},
+		{[]string{"--postgres-dsn", "postgres://x", "--out", dir}, "--out is for --dir"},
+		{[]string{"--dir", dir, "--dry-run", "--workers", "0"}, "--workers=0"},
+		{[]string{"--dir", dir, "--dry-run", "extra"}, "unexpected arguments"},
+	} {
+		var stderr bytes.Buffer
+		if _, err := parseFlags(tt.args, &stderr); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: err = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"--dir", dir, "--dry-run", "--profile", "nope"},
+		{"--dir", dir, "--dry-run", "--accuracy-model", "nope"},
+		{"--dir", dir, "--dry-run", "--accuracy-method", "nope"},
+		{"--dir", dir, "--dry-run", "--thresholds-strict", "1,2"},
+	} {
+		if code, _, stderr := runBackfill(t, args...); code != exitUsage {
+			t.Errorf("%v: exit %d, want %d\n%s", args, code, exitUsage, stderr)
+		}
+	}
+}
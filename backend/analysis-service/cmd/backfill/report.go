@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+)
+
+// gameChange is what scoring one game again changed. It is also a line of
+// the checkpoint file, so a resumed run reports the games done before.
+type gameChange struct {
+	ID                 string         `json:"id"`
+	Moves              int            `json:"moves"`
+	ChangedMoves       int            `json:"changed_moves"`
+	Changes            map[string]int `json:"changes,omitempty"` // By "from->to" class
+	WhiteAccuracyDelta float64        `json:"white_accuracy_delta"`
+	BlackAccuracyDelta float64        `json:"black_accuracy_delta"`
+}
+
+// changeOf compares a stored analysis with its rescored form
+func changeOf(id string, stored, rescored *analyzer.GameAnalysis) gameChange {
+	diff := analyzer.CompareAnalyses(stored, rescored)
+	change := gameChange{
+		ID:                 id,
+		Moves:              diff.ComparedMoves,
+		ChangedMoves:       diff.ClassificationDiffs,
+		WhiteAccuracyDelta: diff.WhiteAccuracyDelta,
+		BlackAccuracyDelta: diff.BlackAccuracyDelta,
+	}
+	for _, move := range diff.Moves {
+		if move.ClassificationA != move.ClassificationB {
+			if change.Changes == nil {
+				change.Changes = make(map[string]int)
+			}
+			change.Changes[string(move.ClassificationA)+"->"+string(move.ClassificationB)]++
+		}
+	}
+	return change
+}
+
+// report sums up the changes of a backfill
+type report struct {
+	DryRun       bool           `json:"dry_run"`
+	Games        int            `json:"games"`
+	ChangedGames int            `json:"changed_games"` // With a move changing class
+	Moves        int            `json:"moves"`
+	ChangedMoves int            `json:"changed_moves"`
+	Changes      map[string]int `json:"changes"`
+
+	// Mean change in accuracy per player and game, of both players
+	// together and of each
+	AverageAccuracyDelta      float64 `json:"average_accuracy_delta"`
+	AverageWhiteAccuracyDelta float64 `json:"average_white_accuracy_delta"`
+	AverageBlackAccuracyDelta float64 `json:"average_black_accuracy_delta"`
+
+	Failed []failure `json:"failed,omitempty"`
+
+	whiteDelta, blackDelta float64
+}
+
+// failure is a game the backfill couldn't do
+type failure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+func newReport(dryRun bool) *report {
+	return &report{DryRun: dryRun, Changes: make(map[string]int)}
+}
+
+func (r *report) add(change gameChange) {
+	r.Games++
+	if change.ChangedMoves > 0 {
+		r.ChangedGames++
+	}
+	r.Moves += change.Moves
+	r.ChangedMoves += change.ChangedMoves
+	for k, n := range change.Changes {
+		r.Changes[k] += n
+	}
+	r.whiteDelta += change.WhiteAccuracyDelta
+	r.blackDelta += change.BlackAccuracyDelta
+}
+
+func (r *report) fail(id string, err error) {
+	r.Failed = append(r.Failed, failure{ID: id, Error: err.Error()})
+}
+
+// finish works out the averages and orders the failures
+func (r *report) finish() *report {
+	if r.Games > 0 {
+		n := float64(r.Games)
+		r.AverageWhiteAccuracyDelta = r.whiteDelta / n
+		r.AverageBlackAccuracyDelta = r.blackDelta / n
+		r.AverageAccuracyDelta = (r.whiteDelta + r.blackDelta) / (2 * n)
+	}
+	sort.Slice(r.Failed, func(i, j int) bool { return r.Failed[i].ID < r.Failed[j].ID })
+	return r
+}
+
+// checkpoint is a file of the games done, a gameChange per line, appended
+// to as each game is done
+type checkpoint struct {
+	f       *os.File
+	changes map[string]gameChange
+}
+
+// openCheckpoint reads the games path records, creating it if needed. A
+// last line cut short by a crash is dropped, so its game is done again.
+func openCheckpoint(path string) (*checkpoint, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	c := &checkpoint{f: f, changes: make(map[string]gameChange)}
+
+	var kept int64
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("read checkpoint %s: %w", path, err)
+		}
+		var change gameChange
+		if err := json.Unmarshal(line, &change); err != nil || change.ID == "" {
+			f.Close()
+			return nil, fmt.Errorf("checkpoint %s: bad line %q", path, line)
+		}
+		c.changes[change.ID] = change
+		kept += int64(len(line))
+	}
+	if err := f.Truncate(kept); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(kept, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *checkpoint) done(id string) bool {
+	_, ok := c.changes[id]
+	return ok
+}
+
+// record adds a game done
+func (c *checkpoint) record(change gameChange) error {
+	line, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	if _, err := c.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	c.changes[change.ID] = change
+	return nil
+}
+
+func (c *checkpoint) close() error {
+	return c.f.Close()
+}
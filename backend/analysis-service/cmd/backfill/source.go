@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/eloinsight/analysis-service/internal/store"
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"go.uber.org/zap"
+)
+
+// source is where stored analyses are read from and written back to, by
+// an ID of the source's choosing
+type source interface {
+	ids(ctx context.Context) ([]string, error)
+	load(ctx context.Context, id string) (*analyzer.GameAnalysis, error)
+	save(ctx context.Context, id string, g *analyzer.GameAnalysis) error
+}
+
+// dirSource reads the .json files of dir, each a GameAnalysis in the JSON
+// schema, and writes them under the same names to outDir. IDs are the
+// file names.
+type dirSource struct {
+	dir    string
+	outDir string
+}
+
+func (d *dirSource) ids(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// load reads a payload, refusing one that doesn't match its checksum
+func (d *dirSource) load(ctx context.Context, name string) (*analyzer.GameAnalysis, error) {
+	data, err := os.ReadFile(filepath.Join(d.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	var g analyzer.GameAnalysis
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, err
+	}
+	if err := analyzer.VerifyGameAnalysis(&g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// save writes the payload to a temporary file and renames it into place,
+// so an interrupted run never leaves half a file
+func (d *dirSource) save(ctx context.Context, name string, g *analyzer.GameAnalysis) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(d.outDir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(d.outDir, "."+name+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(d.outDir, name))
+}
+
+// dbSource reads and writes the persistence sink's tables. IDs are game
+// IDs.
+type dbSource struct {
+	db *store.Postgres
+}
+
+func openDatabase(ctx context.Context, dsn string) (*dbSource, error) {
+	db, err := store.Open(ctx, dsn, zap.NewNop())
+	if err != nil {
+		return nil, err
+	}
+	return &dbSource{db: db}, nil
+}
+
+func (s *dbSource) close() {
+	s.db.Close()
+}
+
+func (s *dbSource) ids(ctx context.Context) ([]string, error) {
+	return s.db.GameIDs(ctx)
+}
+
+func (s *dbSource) load(ctx context.Context, gameID string) (*analyzer.GameAnalysis, error) {
+	return s.db.LoadGameAnalysis(ctx, gameID)
+}
+
+// save replaces the stored rows; a deeper analysis stored since the game
+// was read is kept
+func (s *dbSource) save(ctx context.Context, gameID string, g *analyzer.GameAnalysis) error {
+	_, err := s.db.SaveGameAnalysis(ctx, g)
+	return err
+}
@@ -0,0 +1,303 @@
+// Command seedgen searches the starting position and the most common
+// positions of the first plies with Stockfish and writes them in the
+// evaluation import format, for the seeds the analyzer embeds.
+//
+//	seedgen --depth 20 --out pkg/analyzer/seeds.csv
+//
+// Without --pgn the positions come from a built-in list of common opening
+// lines; with it they are the positions the games reach most often.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"go.uber.org/zap"
+)
+
+const (
+	exitOK     = 0
+	exitUsage  = 1 // Bad flags, unreadable input or unwritable output
+	exitEngine = 2 // The engine failed to start or to search a position
+
+	exitInterrupted = 130
+)
+
+const startingFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// commonLines are the opening lines seeded without a corpus, in the order
+// their positions are kept
+var commonLines = []string{
+	"1. e4 e5 2. Nf3 Nc6",
+	"1. d4 d5 2. c4 e6",
+	"1. e4 c5 2. Nf3 d6",
+	"1. d4 Nf6 2. c4 e6",
+	"1. e4 e6 2. d4 d5",
+	"1. c4 e5 2. Nc3 Nf6",
+	"1. e4 c6 2. d4 d5",
+	"1. Nf3 d5 2. g3 Nf6",
+	"1. e4 c5 2. Nf3 Nc6",
+	"1. d4 Nf6 2. c4 g6",
+	"1. d4 d5 2. c4 c6",
+	"1. e4 d5 2. exd5 Qxd5",
+}
+
+type options struct {
+	pgnPath   string
+	outPath   string
+	depth     int
+	plies     int
+	top       int
+	stockfish string
+	threads   int
+	hash      int
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func parseFlags(args []string, stderr io.Writer) (*options, error) {
+	stockfish := os.Getenv("STOCKFISH_PATH")
+	if stockfish == "" {
+		stockfish = "/usr/local/bin/stockfish"
+	}
+
+	opts := &options{}
+	fs := flag.NewFlagSet("seedgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.pgnPath, "pgn", "", "PGN corpus to rank positions by how often its games reach them (default: built-in opening lines)")
+	fs.StringVar(&opts.outPath, "out", "", "write the seeds to this file instead of stdout")
+	fs.IntVar(&opts.depth, "depth", 20, "search depth")
+	fs.IntVar(&opts.plies, "plies", 4, "seed positions up to this many plies into the game")
+	fs.IntVar(&opts.top, "top", 30, "number of positions to seed besides the starting position")
+	fs.StringVar(&opts.stockfish, "stockfish", stockfish, "Stockfish binaries to try, separated by colons, then stockfish on PATH (env STOCKFISH_PATH)")
+	fs.IntVar(&opts.threads, "threads", 1, "engine threads")
+	fs.IntVar(&opts.hash, "hash", 256, "engine hash table size in MB")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if opts.depth < 1 {
+		return nil, fmt.Errorf("--depth=%d must be at least 1", opts.depth)
+	}
+	if opts.plies < 0 {
+		return nil, fmt.Errorf("--plies=%d must not be negative", opts.plies)
+	}
+	if opts.top < 0 {
+		return nil, fmt.Errorf("--top=%d must not be negative", opts.top)
+	}
+	return opts, nil
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	opts, err := parseFlags(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "seedgen:", err)
+		return exitUsage
+	}
+
+	var fens []string
+	if opts.pgnPath != "" {
+		data, err := os.ReadFile(opts.pgnPath)
+		if err != nil {
+			fmt.Fprintln(stderr, "seedgen:", err)
+			return exitUsage
+		}
+		fens, err = analyzer.PGNPositions(string(data))
+	} else {
+		fens, err = linePositions(commonLines)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "seedgen:", err)
+		return exitUsage
+	}
+	positions := seedPositions(fens, opts.plies, opts.top)
+
+	out := stdout
+	if opts.outPath != "" {
+		f, err := os.Create(opts.outPath)
+		if err != nil {
+			fmt.Fprintln(stderr, "seedgen:", err)
+			return exitUsage
+		}
+		defer f.Close()
+		out = f
+	}
+
+	binary, err := engine.Discover(engine.SplitPaths(opts.stockfish), zap.NewNop())
+	if err != nil {
+		fmt.Fprintln(stderr, "seedgen: start engine:", err)
+		return exitEngine
+	}
+	enginePool, err := pool.NewPool(1, engine.Config{
+		BinaryPath: binary,
+		Threads:    opts.threads,
+		Hash:       opts.hash,
+		MultiPV:    1,
+	}, zap.NewNop())
+	if err != nil {
+		fmt.Fprintln(stderr, "seedgen: start engine:", err)
+		return exitEngine
+	}
+	defer enginePool.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(out, "# Seed evaluations for the position cache, embedded by LoadSeeds.\n")
+	fmt.Fprintf(out, "# Generated by make seeds (go run ./cmd/seedgen); scores are from White's point of view.\n")
+	w := csv.NewWriter(out)
+	w.Write([]string{"fen", "depth", "cp", "mate", "best_move", "source"})
+	for i, fen := range positions {
+		fmt.Fprintf(stderr, "\rposition %d/%d", i+1, len(positions))
+		row, err := searchSeed(ctx, enginePool, fen, opts.depth)
+		if ctx.Err() != nil {
+			fmt.Fprintln(stderr, "\nseedgen: interrupted")
+			return exitInterrupted
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "\nseedgen: %s: %v\n", fen, err)
+			return exitEngine
+		}
+		w.Write(row)
+	}
+	fmt.Fprint(stderr, "\r\033[K")
+	w.Flush()
+	if err := w.Error(); err != nil {
+		fmt.Fprintln(stderr, "seedgen: write seeds:", err)
+		return exitUsage
+	}
+	fmt.Fprintf(stderr, "%d seeds at depth %d\n", len(positions), opts.depth)
+	return exitOK
+}
+
+// seedPositions returns the starting position and the top positions of
+// fens at most plies into their games, most frequent first and in order of
+// first appearance among equals. Positions are told apart as the position
+// cache does, by the first four FEN fields.
+func seedPositions(fens []string, plies, top int) []string {
+	type candidate struct {
+		fen   string
+		count int
+	}
+	byKey := make(map[string]*candidate)
+	var candidates []*candidate
+	for _, fen := range fens {
+		ply, ok := fenPly(fen)
+		if !ok || ply > plies {
+			continue
+		}
+		key := positionKey(fen)
+		if c, ok := byKey[key]; ok {
+			c.count++
+			continue
+		}
+		c := &candidate{fen: fen, count: 1}
+		byKey[key] = c
+		candidates = append(candidates, c)
+	}
+
+	start := positionKey(startingFEN)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].count > candidates[j].count
+	})
+	positions := []string{startingFEN}
+	for _, c := range candidates {
+		if len(positions) > top {
+			break
+		}
+		if positionKey(c.fen) != start {
+			positions = append(positions, c.fen)
+		}
+	}
+	return positions
+}
+
+// linePositions returns the FEN of every position of lines, each the
+// movetext of a game from the starting position
+func linePositions(lines []string) ([]string, error) {
+	var fens []string
+	for _, line := range lines {
+		positions, err := analyzer.ParsePGN(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", line, err)
+		}
+		for _, pos := range positions {
+			fens = append(fens, pos.FEN)
+		}
+	}
+	return fens, nil
+}
+
+// fenPly returns how many plies into a game from the starting position fen
+// is, from its move counter and side to move
+func fenPly(fen string) (int, bool) {
+	fields := strings.Fields(fen)
+	if len(fields) != 6 {
+		return 0, false
+	}
+	fullmove, err := strconv.Atoi(fields[5])
+	if err != nil || fullmove < 1 {
+		return 0, false
+	}
+	ply := (fullmove - 1) * 2
+	if fields[1] == "b" {
+		ply++
+	}
+	return ply, true
+}
+
+// positionKey is fen without its move counters
+func positionKey(fen string) string {
+	fields := strings.Fields(fen)
+	return strings.Join(fields[:min(4, len(fields))], " ")
+}
+
+// searchSeed searches fen to depth and returns its import row, scored
+// from White's point of view
+func searchSeed(ctx context.Context, p *pool.Pool, fen string, depth int) ([]string, error) {
+	eng, err := p.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Put(eng)
+
+	result, err := eng.AnalyzePositionContext(ctx, fen, depth, 1)
+	if err != nil {
+		return nil, err
+	}
+	if result.Stopped || len(result.Evaluations) == 0 {
+		return nil, fmt.Errorf("search stopped before depth %d", depth)
+	}
+	eval := result.Evaluations[0]
+
+	sign := 1
+	if strings.Fields(fen)[1] == "b" {
+		sign = -1
+	}
+	cp, mate := strconv.Itoa(sign*eval.Centipawns), ""
+	if eval.IsMate && eval.MateIn != nil {
+		cp, mate = "", strconv.Itoa(sign**eval.MateIn)
+	}
+	return []string{fen, strconv.Itoa(eval.Depth), cp, mate, result.BestMove, "seedgen"}, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	enginetest.Main()
+	os.Exit(m.Run())
+}
+
+// fakeEngine answers every search with a fixed score and e2e4
+var fakeEngine = enginetest.Engine{Default: enginetest.Eval{Centipawns: 25, BestMove: "e2e4"}}
+
+func TestSeedPositions(t *testing.T) {
+	fens, err := linePositions([]string{"1. d4 d5", "1. e4 e5 2. Nf3", "1. e4 c5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	afterE4 := fens[4]
+
+	got := seedPositions(fens, 1, 2)
+	want := []string{startingFEN, afterE4, fens[1]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("seedPositions() = %q, want %q", got, want)
+	}
+
+	// The starting position is seeded even with nothing else
+	if got := seedPositions(fens, 4, 0); !reflect.DeepEqual(got, []string{startingFEN}) {
+		t.Errorf("seedPositions(top 0) = %q", got)
+	}
+}
+
+func TestRun_FakeEngine(t *testing.T) {
+	stockfish := fakeEngine.Binary(t)
+	out := filepath.Join(t.TempDir(), "seeds.csv")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--stockfish", stockfish, "--depth", "8", "--plies", "1", "--top", "3", "--out", out}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Scores come back from White's point of view, and the file loads as seeds
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 7 || lines[3] != startingFEN+",8,25,,e2e4,seedgen" || !strings.Contains(lines[4], " b ") || !strings.Contains(lines[4], ",8,-25,,") {
+		t.Errorf("seeds:\n%s", data)
+	}
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	if report, err := a.ImportEvaluations(bytes.NewReader(data)); err != nil || report.Imported != 1 {
+		t.Errorf("imported %+v, %v; want the starting position, where e2e4 is legal", report, err)
+	}
+}
@@ -2,18 +2,29 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net"
 	"os"
 	"os/signal"
+	"runtime"
 	"syscall"
 	"time"
 
-	"github.com/eloinsight/analysis-service/internal/analyzer"
+	"github.com/eloinsight/analysis-service/internal/buildinfo"
+	"github.com/eloinsight/analysis-service/internal/cloudeval"
 	"github.com/eloinsight/analysis-service/internal/config"
-	"github.com/eloinsight/analysis-service/internal/engine"
+	"github.com/eloinsight/analysis-service/internal/debugserver"
 	servergrpc "github.com/eloinsight/analysis-service/internal/grpc"
-	"github.com/eloinsight/analysis-service/internal/pool"
+	"github.com/eloinsight/analysis-service/internal/logging"
+	"github.com/eloinsight/analysis-service/internal/queue"
+	"github.com/eloinsight/analysis-service/internal/quota"
+	"github.com/eloinsight/analysis-service/internal/store"
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	"github.com/eloinsight/analysis-service/pkg/pool"
 	pb "github.com/eloinsight/analysis-service/proto"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -25,29 +36,60 @@ import (
 
 func main() {
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
 		os.Exit(1)
 	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
 
 	// Setup logger
-	logger := setupLogger(cfg.LogLevel, cfg.LogFormat)
+	logger, logLevel := setupLogger(cfg.LogLevel, cfg.LogFormat)
 	defer logger.Sync()
 
+	// Runtime log level control: SIGUSR1/SIGHUP toggle debug, the admin
+	// SetLogLevel RPC sets a level that reverts on its own
+	levels := logging.NewLevelController(logLevel, cfg.LogUCI, engine.SetUCIDebug, logger)
+	go toggleLogLevelOnSignal(levels)
+
+	for _, warning := range cfg.Warnings {
+		logger.Warn("Config value ignored", zap.String("reason", warning))
+	}
+	logger.Debug("Effective configuration", zap.Any("config", cfg.Redacted()))
+
 	logger.Info("Starting EloInsight Analysis Service",
 		zap.String("grpcPort", cfg.GRPCPort),
 		zap.Int("workers", cfg.WorkerPoolSize))
 
+	// Pick the first Stockfish binary that works, for the cross-check
+	// pool too
+	binary, err := engine.Discover(engine.SplitPaths(cfg.Stockfish.BinaryPath), logger)
+	if err != nil {
+		logger.Fatal("No usable Stockfish binary", zap.Error(err))
+	}
+	cfg.Stockfish.BinaryPath = binary
+
 	// Create engine pool
+	uciOptions, err := cfg.Stockfish.UCIOptions()
+	if err != nil {
+		logger.Fatal("Invalid engine options", zap.Error(err))
+	}
 	engineConfig := engine.Config{
 		BinaryPath: cfg.Stockfish.BinaryPath,
 		Threads:    cfg.Stockfish.Threads,
 		Hash:       cfg.Stockfish.Hash,
 		MultiPV:    cfg.Stockfish.MultiPV,
+		Options:    uciOptions,
 	}
 
-	enginePool, err := pool.NewPool(cfg.WorkerPoolSize, engineConfig, logger)
+	// The engines start once the server listens, see below
+	enginePool, err := pool.New(cfg.WorkerPoolSize, engineConfig, logger)
 	if err != nil {
 		logger.Fatal("Failed to create engine pool", zap.Error(err))
 	}
@@ -57,24 +99,156 @@ func main() {
 	analyzerService := analyzer.NewAnalyzer(
 		enginePool,
 		logger,
+		cfg.MinDepth,
 		cfg.DefaultDepth,
 		cfg.MaxDepth,
 		cfg.AnalysisTimeout,
 	)
 
+	profiles, err := cfg.Thresholds.Profiles()
+	if err == nil {
+		err = analyzerService.SetThresholdProfiles(profiles, cfg.Thresholds.Profile)
+	}
+	if err != nil {
+		logger.Fatal("Invalid threshold profiles", zap.Error(err))
+	}
+	if err := analyzerService.SetAccuracyMethod(evaluation.AccuracyMethod(cfg.AccuracyMethod)); err != nil {
+		logger.Fatal("Invalid accuracy method", zap.Error(err))
+	}
+	analyzerService.SetGarbageTimeExclusion(cfg.ExcludeGarbageTime)
+	analyzerService.SetCacheMaxHalfmoveClock(cfg.CacheMaxHalfmoveClock)
+	if cfg.CacheAdmission.Enabled {
+		analyzerService.SetCacheAdmission(analyzer.CacheAdmission{
+			Plies:     cfg.CacheAdmission.Plies,
+			Pieces:    cfg.CacheAdmission.Pieces,
+			Sightings: cfg.CacheAdmission.Sightings,
+		})
+	}
+	analyzerService.SetGameLengthLimits(cfg.MaxGamePlies, cfg.FastModePlies)
+	analyzerService.SetAnalyzeVariants(cfg.AnalyzeVariants)
+	if cfg.BookPlies > 0 {
+		analyzerService.SetBookDetector(analyzer.BookHeuristic{Plies: cfg.BookPlies})
+	}
+	analyzerService.SetPonderPrefetch(cfg.PonderPrefetch)
+	analyzerService.SetStaleWhileRevalidate(cfg.StaleDepthMargin, cfg.StaleRefreshQueue)
+	analyzerService.SetMaxPVLength(cfg.MaxPVLength)
+	analyzerService.SetPreviewTolerance(cfg.PreviewToleranceCP)
+	analyzerService.SetMaxMultiPV(cfg.MaxMultiPV)
+	analyzerService.SetScrambleThresholds(map[string]time.Duration{
+		analyzer.TimeClassBullet:    cfg.TimeScramble.Bullet,
+		analyzer.TimeClassBlitz:     cfg.TimeScramble.Blitz,
+		analyzer.TimeClassRapid:     cfg.TimeScramble.Rapid,
+		analyzer.TimeClassClassical: cfg.TimeScramble.Classical,
+	})
+	if cfg.SeedCache {
+		seeds, err := analyzerService.LoadSeeds()
+		if err != nil {
+			logger.Fatal("Failed to load cache seeds", zap.Error(err))
+		}
+		logger.Info("Loaded cache seeds", zap.Int("seeds", seeds))
+	}
+	if cfg.ImportEvals != "" {
+		importEvaluations(analyzerService, cfg.ImportEvals, logger)
+	}
+
+	// Answer position requests from the cloud while the pool is saturated
+	cloud := newCloudEval(cfg, logger)
+	if cloud != nil {
+		analyzerService.SetCloudFallback(cloud, cfg.CloudEval.PoolWait, cfg.CloudEval.MaxDepth)
+	}
+
+	// Shallower game analyses while the pool is starved
+	if cfg.Degrade.Enabled {
+		depth := cfg.Degrade.Depth
+		if depth == 0 {
+			depth = max(cfg.DefaultDepth-6, cfg.MinDepth)
+		}
+		analyzerService.SetDegradation(analyzer.DegradeOptions{
+			PoolWait:    cfg.Degrade.PoolWait,
+			RecoverWait: cfg.Degrade.RecoverWait,
+			Window:      cfg.Degrade.Window,
+			Depth:       depth,
+		})
+	}
+
+	// Cached positions searched again while engines are idle
+	if cfg.Drift.Enabled {
+		analyzerService.SetDriftSampling(analyzer.DriftOptions{
+			Interval: cfg.Drift.Interval,
+			Samples:  cfg.Drift.Samples,
+			MaxP95:   cfg.Drift.MaxP95,
+		})
+	}
+
+	// Second engine for cross-check analyses
+	if crossPool := newCrossCheckPool(cfg, logger); crossPool != nil {
+		defer crossPool.Close()
+		err := analyzerService.AddEngineProfile(analyzer.EngineProfile{
+			Name:  cfg.CrossCheck.Name,
+			Pool:  crossPool,
+			Depth: cfg.CrossCheck.Depth,
+		})
+		if err != nil {
+			logger.Fatal("Invalid cross-check engine", zap.Error(err))
+		}
+	}
+
 	// Create gRPC server
-	grpcServer := grpc.NewServer(
+	serverOpts := append(servergrpc.ServerOptions(cfg.GRPC),
 		grpc.MaxRecvMsgSize(10*1024*1024), // 10MB max message size
 		grpc.MaxSendMsgSize(10*1024*1024),
 	)
+	// Admin calls need the admin token
+	adminUnary, adminStream := servergrpc.AdminInterceptors(cfg.AdminToken)
+	serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(adminUnary), grpc.ChainStreamInterceptor(adminStream))
+	if cfg.AdminToken == "" {
+		logger.Info("AdminService disabled, ADMIN_TOKEN is not set")
+	}
+	// Analysis calls are turned away with a retry hint until the engines started
+	startingUnary, startingStream := servergrpc.StartupInterceptors(enginePool)
+	serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(startingUnary), grpc.ChainStreamInterceptor(startingStream))
+	// Engine time is attributed to the RPC it was lent for
+	tagUnary, tagStream := servergrpc.TagInterceptors()
+	serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(tagUnary), grpc.ChainStreamInterceptor(tagStream))
+	if meter, closeQuota := newQuotaMeter(cfg, logger); meter != nil {
+		defer closeQuota()
+		unary, stream := servergrpc.QuotaInterceptors(meter, logger)
+		serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(unary), grpc.ChainStreamInterceptor(stream))
+	}
+	grpcServer := grpc.NewServer(serverOpts...)
 
 	// Register analysis service
-	analysisServer := servergrpc.NewServer(analyzerService, enginePool, logger)
+	analysisServer := servergrpc.NewServer(analyzerService, enginePool, logger, cfg.StreamHeartbeatInterval)
+	analysisServer.SetOpeningMinGames(cfg.OpeningMinGames)
+	analysisServer.SetMetricsInterval(cfg.StreamMetricsInterval)
+	analysisServer.SetProgressInterval(cfg.StreamProgressInterval)
+	analysisServer.SetLimits(servergrpc.Limits{
+		MinDepth:         cfg.MinDepth,
+		DefaultDepth:     cfg.DefaultDepth,
+		MaxDepth:         cfg.MaxDepth,
+		DefaultBestMoves: cfg.Stockfish.MultiPV,
+		MaxMultiPV:       cfg.MaxMultiPV,
+	})
+	if sink := openStore(cfg, logger); sink != nil {
+		defer sink.Close()
+		analysisServer.SetStore(sink)
+	}
 	pb.RegisterAnalysisServiceServer(grpcServer, analysisServer)
+	adminServer := servergrpc.NewAdminServer(levels, cfg.LogLevelRevert, logger)
+	adminServer.SetImporter(analyzerService, cfg.ImportEvalsDir)
+	adminServer.SetStatsSource(analyzerService)
+	adminServer.SetCacheWarmer(analyzerService)
+	if cfg.Transcripts.Enabled {
+		transcripts := servergrpc.NewTranscripts(cfg.Transcripts.MaxBytes)
+		analysisServer.SetTranscripts(transcripts)
+		adminServer.SetTranscripts(transcripts)
+	}
+	pb.RegisterAdminServiceServer(grpcServer, adminServer)
 
-	// Register health service
+	// Register health service, NOT_SERVING until the engines started
 	healthServer := health.NewServer()
-	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	healthServer.SetServingStatus(servergrpc.HealthService, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
 	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
 
 	// Enable reflection for debugging
@@ -93,6 +267,36 @@ func main() {
 		}
 	}()
 
+	// Start the engines, then serve and follow the pool's engines
+	if err := enginePool.Start(); err != nil {
+		logger.Fatal("Failed to start engine pool", zap.Error(err))
+	}
+	poolStats := enginePool.GetStats()
+	logger.Info("Build info",
+		zap.String("gitSha", buildinfo.GitSHA),
+		zap.String("buildTime", buildinfo.BuildTime),
+		zap.String("goVersion", buildinfo.GoVersion()),
+		zap.String("stockfishVersion", poolStats.StockfishVersion),
+		zap.String("nnueNet", poolStats.NNUENet),
+		zap.Int("minDepth", cfg.MinDepth),
+		zap.Int("defaultDepth", cfg.DefaultDepth),
+		zap.Int("maxDepth", cfg.MaxDepth),
+		zap.Duration("analysisTimeout", cfg.AnalysisTimeout))
+
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(servergrpc.HealthService, grpc_health_v1.HealthCheckResponse_SERVING)
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	defer stopHealth()
+	go servergrpc.WatchHealth(healthCtx, healthServer, enginePool, cfg.HealthGracePeriod, logger)
+
+	// Start queue consumer, sharing the engine pool with the gRPC API
+	consumerCtx, stopConsumer := context.WithCancel(context.Background())
+	defer stopConsumer()
+	consumer, consumerDone := startConsumer(consumerCtx, cfg, analyzerService, logger)
+
+	// Start debug HTTP server (pprof, expvar, healthz)
+	debugServer := startDebugServer(cfg, enginePool, analyzerService, consumer, cloud, logger)
+
 	// Wait for shutdown signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -104,9 +308,28 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Stop taking jobs; interrupted ones are handed back to the broker
+	stopConsumer()
+
+	// Fail health checks so traffic moves away while requests drain
+	stopHealth()
+	healthServer.Shutdown()
+
 	// Stop accepting new requests
 	grpcServer.GracefulStop()
 
+	if debugServer != nil {
+		if err := debugServer.Shutdown(ctx); err != nil {
+			logger.Warn("Debug HTTP server shutdown failed", zap.Error(err))
+		}
+	}
+
+	// Wait for the consumer to settle its jobs
+	select {
+	case <-consumerDone:
+	case <-ctx.Done():
+	}
+
 	// Wait for pool to drain
 	select {
 	case <-ctx.Done():
@@ -116,7 +339,298 @@ func main() {
 	}
 }
 
-func setupLogger(level string, format string) *zap.Logger {
+// queuePoolTag is the pool tag of the engines lent for queued jobs, see
+// pool.WithTag
+const queuePoolTag = "queue"
+
+// startConsumer connects to the configured broker and consumes analysis
+// jobs until ctx is done. The returned channel is closed once the consumer
+// stopped; with consumer mode off it is already closed and the consumer is
+// nil.
+func startConsumer(ctx context.Context, cfg *config.Config, a *analyzer.Analyzer, logger *zap.Logger) (*queue.Consumer, <-chan struct{}) {
+	done := make(chan struct{})
+	if cfg.Consumer.Driver == "" {
+		close(done)
+		return nil, done
+	}
+
+	// A job is redelivered when it isn't settled in time, so allow for the
+	// whole game budget plus publishing the result
+	redeliverAfter := cfg.AnalysisTimeout + time.Minute
+
+	var broker queue.Broker
+	var err error
+	switch cfg.Consumer.Driver {
+	case "redis":
+		name, _ := os.Hostname()
+		if name == "" {
+			name = "analysis-service"
+		}
+		broker, err = queue.NewRedisBroker(cfg.Consumer.URL, cfg.Consumer.JobsSubject, cfg.Consumer.Group,
+			fmt.Sprintf("%s-%d", name, os.Getpid()), redeliverAfter)
+	case "nats":
+		broker, err = queue.NewNATSBroker(cfg.Consumer.URL, cfg.Consumer.Stream, cfg.Consumer.JobsSubject,
+			cfg.Consumer.Group, redeliverAfter)
+	}
+	if err != nil {
+		logger.Fatal("Failed to connect queue consumer", zap.String("driver", cfg.Consumer.Driver), zap.Error(err))
+	}
+
+	concurrency := cfg.Consumer.Concurrency
+	if concurrency == 0 {
+		concurrency = cfg.MaxConcurrentAnalyses
+	}
+	consumer := queue.NewConsumer(broker, a.AnalyzeGame, servergrpc.EncodeGameAnalysis, queue.Options{
+		ResultsSubject:    cfg.Consumer.ResultsSubject,
+		DeadLetterSubject: cfg.Consumer.DeadLetterSubject,
+		Concurrency:       concurrency,
+		MaxRetries:        cfg.Consumer.MaxRetries,
+		RetryDelay:        cfg.Consumer.RetryDelay,
+	}, logger.With(zap.String("driver", cfg.Consumer.Driver), zap.String("jobs", cfg.Consumer.JobsSubject)))
+
+	go func() {
+		defer close(done)
+		consumer.Run(pool.WithTag(ctx, queuePoolTag))
+		if err := broker.Close(); err != nil {
+			logger.Warn("Failed to close queue connection", zap.Error(err))
+		}
+	}()
+	return consumer, done
+}
+
+// newCloudEval returns the cloud eval client, or nil when the fallback is off
+func newCloudEval(cfg *config.Config, logger *zap.Logger) *cloudeval.Client {
+	if !cfg.CloudEval.Enabled {
+		return nil
+	}
+
+	logger.Info("Cloud eval fallback enabled",
+		zap.String("url", cfg.CloudEval.URL),
+		zap.Duration("poolWait", cfg.CloudEval.PoolWait),
+		zap.Int("maxDepth", cfg.CloudEval.MaxDepth))
+
+	return cloudeval.New(cloudeval.Options{
+		Endpoint:         cfg.CloudEval.URL,
+		Timeout:          cfg.CloudEval.Timeout,
+		MinInterval:      cfg.CloudEval.MinInterval,
+		FailureThreshold: cfg.CloudEval.FailureThreshold,
+		Cooldown:         cfg.CloudEval.Cooldown,
+	}, logger)
+}
+
+// importEvaluations loads the evaluation file named by --import-evals into
+// the position cache
+func importEvaluations(a *analyzer.Analyzer, path string, logger *zap.Logger) {
+	f, err := os.Open(path)
+	if err != nil {
+		logger.Fatal("Failed to open evaluation import", zap.Error(err))
+	}
+	defer f.Close()
+
+	report, err := a.ImportEvaluations(f)
+	if err != nil {
+		logger.Fatal("Failed to import evaluations", zap.String("path", path), zap.Error(err))
+	}
+	for _, rowErr := range report.Errors {
+		logger.Warn("Invalid evaluation row", zap.String("path", path), zap.String("error", rowErr))
+	}
+}
+
+// openStore connects the Postgres sink and applies its migrations, or
+// returns nil when the sink is off
+func openStore(cfg *config.Config, logger *zap.Logger) *store.Postgres {
+	if !cfg.Postgres.Enabled {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	sink, err := store.Open(ctx, cfg.Postgres.DSN, logger)
+	if err != nil {
+		logger.Fatal("Failed to open Postgres sink", zap.Error(err))
+	}
+
+	logger.Info("Postgres sink enabled")
+	return sink
+}
+
+// newQuotaMeter returns the engine time meter and a function closing its
+// store, or nil when quotas are off
+func newQuotaMeter(cfg *config.Config, logger *zap.Logger) (*quota.Meter, func() error) {
+	if cfg.Quota.DailyEngineTime <= 0 {
+		return nil, nil
+	}
+
+	if cfg.Quota.RedisURL == "" {
+		logger.Info("Engine time quotas enabled, counted in memory",
+			zap.Duration("daily", cfg.Quota.DailyEngineTime))
+		return quota.NewMeter(quota.NewMemoryStore(), cfg.Quota.DailyEngineTime, logger), func() error { return nil }
+	}
+	counters, err := quota.NewRedisStore(cfg.Quota.RedisURL)
+	if err != nil {
+		logger.Fatal("Failed to connect the quota store", zap.Error(err))
+	}
+	logger.Info("Engine time quotas enabled, counted in Redis",
+		zap.Duration("daily", cfg.Quota.DailyEngineTime))
+	return quota.NewMeter(counters, cfg.Quota.DailyEngineTime, logger), counters.Close
+}
+
+// newCrossCheckPool starts the second engine pool, or returns nil when
+// cross-checking is off
+func newCrossCheckPool(cfg *config.Config, logger *zap.Logger) *pool.Pool {
+	if !cfg.CrossCheck.Enabled {
+		return nil
+	}
+
+	binary := cfg.CrossCheck.BinaryPath
+	if binary == "" {
+		binary = cfg.Stockfish.BinaryPath
+	}
+	uciOptions, _ := cfg.Stockfish.UCIOptions() // Checked at startup
+	crossPool, err := pool.NewPool(cfg.CrossCheck.PoolSize, engine.Config{
+		BinaryPath: binary,
+		Threads:    cfg.CrossCheck.Threads,
+		Hash:       cfg.CrossCheck.Hash,
+		MultiPV:    cfg.Stockfish.MultiPV,
+		Options:    uciOptions,
+	}, logger)
+	if err != nil {
+		logger.Fatal("Failed to create cross-check engine pool", zap.Error(err))
+	}
+
+	logger.Info("Cross-check engine enabled",
+		zap.String("profile", cfg.CrossCheck.Name),
+		zap.String("engine", crossPool.GetStats().StockfishVersion),
+		zap.Int("workers", cfg.CrossCheck.PoolSize),
+		zap.Int("depth", cfg.CrossCheck.Depth))
+	return crossPool
+}
+
+// startDebugServer serves pprof, expvar and healthz, failing while the
+// queue consumer's broker is unreachable, on the HTTP port. A
+// bind failure is only fatal when HTTP_REQUIRED is set; otherwise the
+// service runs without it and nil is returned.
+func startDebugServer(cfg *config.Config, enginePool *pool.Pool, a *analyzer.Analyzer, consumer *queue.Consumer, cloud *cloudeval.Client, logger *zap.Logger) *debugserver.Server {
+	host := "127.0.0.1"
+	if cfg.HTTPListenAll {
+		host = ""
+	}
+
+	vars := map[string]func() interface{}{
+		"pool": func() interface{} {
+			stats := enginePool.GetStats()
+			tags := make(map[string]interface{}, len(stats.Tags))
+			for _, tag := range stats.Tags {
+				tags[tag.Tag] = map[string]interface{}{
+					"busyMs": tag.Busy.Milliseconds(),
+					"share":  tag.Share,
+					"loans":  tag.Loans,
+					"inUse":  tag.InUse,
+				}
+			}
+			return map[string]interface{}{
+				"size":          stats.Size,
+				"available":     stats.Available,
+				"inUse":         stats.InUse,
+				"waiting":       enginePool.Waiting(),
+				"uptimeSeconds": int64(stats.Uptime.Seconds()),
+				"busyMs":        stats.BusyTime.Milliseconds(),
+				"tags":          tags,
+			}
+		},
+		"cache": func() interface{} {
+			stats := a.CacheStats()
+			return map[string]interface{}{
+				"size":       stats.Size,
+				"hits":       stats.Hits,
+				"misses":     stats.Misses,
+				"nearMisses": stats.NearMisses,
+				"hitRate":    stats.HitRate,
+				"sources":    a.CacheSizeBySource(),
+				"policies":   a.CachePolicyCounts(),
+
+				"rejected":           stats.Rejected,
+				"rejections":         stats.Rejections,
+				"quarantinedEngines": stats.QuarantinedEngines,
+
+				"admitted":    stats.Admitted,
+				"notAdmitted": stats.NotAdmitted,
+			}
+		},
+		"pgnInputs": func() interface{} {
+			return a.PGNParseCounts()
+		},
+		"degradation": func() interface{} {
+			state := a.Degradation()
+			return map[string]interface{}{
+				"enabled":       state.Enabled,
+				"active":        state.Active,
+				"since":         state.Since,
+				"poolWaitP95Ms": state.PoolWaitP95.Milliseconds(),
+				"samples":       state.Samples,
+				"depth":         state.Depth,
+				"activations":   state.Activations,
+				"degradedGames": state.DegradedGames,
+			}
+		},
+		"drift": func() interface{} {
+			state := a.Drift()
+			return map[string]interface{}{
+				"enabled":   state.Enabled,
+				"p50Cp":     state.P50,
+				"p95Cp":     state.P95,
+				"samples":   state.Samples,
+				"sampled":   state.Sampled,
+				"failed":    state.Failed,
+				"backedOff": state.BackedOff,
+				"maxP95Cp":  state.MaxP95,
+				"flushes":   state.Flushes,
+				"flushed":   state.Flushed,
+				"lastFlush": state.LastFlush,
+			}
+		},
+		"goroutines": func() interface{} {
+			return runtime.NumGoroutine()
+		},
+	}
+	if consumer != nil {
+		vars["consumer"] = func() interface{} {
+			return consumer.Stats()
+		}
+	}
+	if cloud != nil {
+		vars["cloudEval"] = func() interface{} {
+			return cloud.Stats()
+		}
+	}
+
+	server := debugserver.New(net.JoinHostPort(host, cfg.HTTPPort), vars, logger)
+	if consumer != nil {
+		// Unhealthy while the broker can't be reached, so a consumer that
+		// can't reconnect gets restarted
+		server.AddHealthCheck("consumer", consumer.Err)
+	}
+	if err := server.Start(); err != nil {
+		if cfg.HTTPRequired {
+			logger.Fatal("Failed to start debug HTTP server", zap.Error(err))
+		}
+		logger.Warn("Debug HTTP server disabled", zap.Error(err))
+		return nil
+	}
+	return server
+}
+
+// toggleLogLevelOnSignal flips between debug and the configured level on
+// each SIGUSR1 or SIGHUP
+func toggleLogLevelOnSignal(levels *logging.LevelController) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGHUP)
+	for range sigs {
+		levels.Toggle()
+	}
+}
+
+func setupLogger(level string, format string) (*zap.Logger, zap.AtomicLevel) {
 	var logLevel zapcore.Level
 	switch level {
 	case "debug":
@@ -145,5 +659,5 @@ func setupLogger(level string, format string) *zap.Logger {
 		panic(err)
 	}
 
-	return logger
+	return logger, config.Level
 }
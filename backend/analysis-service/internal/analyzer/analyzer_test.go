@@ -0,0 +1,41 @@
+package analyzer
+
+import (
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// frozen are the alias packages nothing in the module may import
+var frozen = map[string]bool{
+	"github.com/eloinsight/analysis-service/internal/analyzer":   true,
+	"github.com/eloinsight/analysis-service/internal/engine":     true,
+	"github.com/eloinsight/analysis-service/internal/evaluation": true,
+	"github.com/eloinsight/analysis-service/internal/pool":       true,
+}
+
+func TestFrozen_NoImporters(t *testing.T) {
+	root := filepath.Join("..", "..")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, spec := range file.Imports {
+			if imported, _ := strconv.Unquote(spec.Path.Value); frozen[imported] {
+				t.Errorf("%s imports %s; import its pkg/ package instead", path, imported)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,18 @@
+// Package buildinfo exposes build metadata for the running binary.
+// GitSHA and BuildTime are injected at link time, e.g.
+//
+//	go build -ldflags "-X github.com/eloinsight/analysis-service/internal/buildinfo.GitSHA=abc123"
+package buildinfo
+
+import "runtime"
+
+// Values injected via -ldflags -X. They stay "unknown" for plain `go run` builds.
+var (
+	GitSHA    = "unknown"
+	BuildTime = "unknown"
+)
+
+// GoVersion returns the Go toolchain version the binary was built with
+func GoVersion() string {
+	return runtime.Version()
+}
@@ -0,0 +1,260 @@
+// Package cloudeval queries the Lichess cloud evaluation API, or a
+// self-hosted mirror of it, for positions the local engines are too busy
+// to analyze. It only knows positions someone already had analyzed, so a
+// miss is normal and callers fall back to the engine pool.
+package cloudeval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNotFound means the cloud has no evaluation of the position
+	ErrNotFound = errors.New("position not in cloud eval")
+
+	// ErrUnavailable means no request was made: the circuit is open after
+	// failures or rate limiting, or the last request was too recent
+	ErrUnavailable = errors.New("cloud eval unavailable")
+)
+
+// Options configures a Client
+type Options struct {
+	Endpoint         string        // e.g. https://lichess.org/api/cloud-eval
+	Timeout          time.Duration // Per request
+	MinInterval      time.Duration // Minimum time between requests
+	FailureThreshold int           // Consecutive failures that open the circuit
+	Cooldown         time.Duration // How long an open circuit rejects requests
+}
+
+// Stats counts cloud eval requests
+type Stats struct {
+	Requests    int64 `json:"requests"`
+	Hits        int64 `json:"hits"`
+	NotFound    int64 `json:"notFound"`
+	Failures    int64 `json:"failures"`
+	Rejected    int64 `json:"rejected"` // Not sent: circuit open or rate limited
+	CircuitOpen bool  `json:"circuitOpen"`
+}
+
+// Client queries the cloud eval API. Requests are spaced at least
+// MinInterval apart; a 429 answer or FailureThreshold consecutive failures
+// open a circuit that rejects requests for the cooldown.
+type Client struct {
+	opts   Options
+	http   *http.Client
+	logger *zap.Logger
+
+	mu          sync.Mutex
+	nextRequest time.Time
+	failures    int
+	openUntil   time.Time
+
+	requests, hits, notFound, failed, rejected atomic.Int64
+}
+
+// New creates a cloud eval client
+func New(opts Options, logger *zap.Logger) *Client {
+	return &Client{
+		opts:   opts,
+		http:   &http.Client{Timeout: opts.Timeout},
+		logger: logger,
+	}
+}
+
+// cloudEval is the API's answer. Scores are from White's point of view.
+type cloudEval struct {
+	FEN    string `json:"fen"`
+	KNodes int64  `json:"knodes"`
+	Depth  int    `json:"depth"`
+	PVs    []struct {
+		Moves string `json:"moves"`
+		CP    *int   `json:"cp"`
+		Mate  *int   `json:"mate"`
+	} `json:"pvs"`
+}
+
+// Evaluate returns the cloud evaluation of fen as a single-PV result with
+// Source set to engine.SourceCloud. Scores are converted to the side to
+// move's point of view, like the engine's.
+func (c *Client) Evaluate(ctx context.Context, fen string) (*engine.AnalysisResult, error) {
+	if !c.acquire() {
+		c.rejected.Add(1)
+		return nil, ErrUnavailable
+	}
+	c.requests.Add(1)
+
+	u, err := url.Parse(c.opts.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("cloud eval endpoint: %w", err)
+	}
+	q := u.Query()
+	q.Set("fen", fen)
+	q.Set("multiPv", "1")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// A caller giving up says nothing about the service
+		if ctx.Err() == nil {
+			c.failure(0)
+		}
+		return nil, fmt.Errorf("cloud eval request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		c.success()
+		c.notFound.Add(1)
+		return nil, ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests:
+		// Lichess asks clients to back off for a full minute
+		c.failure(max(retryAfter(resp), time.Minute))
+		return nil, fmt.Errorf("%w: rate limited", ErrUnavailable)
+	case resp.StatusCode != http.StatusOK:
+		c.failure(0)
+		return nil, fmt.Errorf("cloud eval: unexpected status %s", resp.Status)
+	}
+
+	var body cloudEval
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		c.failure(0)
+		return nil, fmt.Errorf("cloud eval response: %w", err)
+	}
+	c.success()
+
+	result, err := toResult(fen, &body)
+	if err != nil {
+		c.notFound.Add(1)
+		return nil, err
+	}
+	c.hits.Add(1)
+	return result, nil
+}
+
+// Stats returns request counters and the circuit state
+func (c *Client) Stats() Stats {
+	c.mu.Lock()
+	open := time.Now().Before(c.openUntil)
+	c.mu.Unlock()
+
+	return Stats{
+		Requests:    c.requests.Load(),
+		Hits:        c.hits.Load(),
+		NotFound:    c.notFound.Load(),
+		Failures:    c.failed.Load(),
+		Rejected:    c.rejected.Load(),
+		CircuitOpen: open,
+	}
+}
+
+// acquire reserves the next request slot, or reports that none is free
+func (c *Client) acquire() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(c.openUntil) || now.Before(c.nextRequest) {
+		return false
+	}
+	c.nextRequest = now.Add(c.opts.MinInterval)
+	return true
+}
+
+func (c *Client) success() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = 0
+}
+
+// failure records a failed request. A non-zero backoff opens the circuit
+// for that long at once.
+func (c *Client) failure(backoff time.Duration) {
+	c.failed.Add(1)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures++
+	if backoff == 0 && c.failures >= c.opts.FailureThreshold {
+		backoff = c.opts.Cooldown
+	}
+	if backoff > 0 {
+		c.openUntil = time.Now().Add(backoff)
+		c.failures = 0
+		c.logger.Warn("Cloud eval circuit opened", zap.Duration("for", backoff))
+	}
+}
+
+// retryAfter returns the Retry-After header of resp in seconds, or zero
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// toResult converts a cloud evaluation to an engine result
+func toResult(fen string, body *cloudEval) (*engine.AnalysisResult, error) {
+	if len(body.PVs) == 0 {
+		return nil, fmt.Errorf("%w: no principal variation", ErrNotFound)
+	}
+	pv := body.PVs[0]
+	moves := strings.Fields(pv.Moves)
+	if len(moves) == 0 {
+		return nil, fmt.Errorf("%w: empty principal variation", ErrNotFound)
+	}
+
+	// The engine reports scores for the side to move
+	sign := 1
+	if fields := strings.Fields(fen); len(fields) > 1 && fields[1] == "b" {
+		sign = -1
+	}
+
+	eval := engine.Evaluation{
+		Depth:   body.Depth,
+		Nodes:   body.KNodes * 1000,
+		PV:      moves,
+		MultiPV: 1,
+	}
+	switch {
+	case pv.Mate != nil:
+		mateIn := sign * *pv.Mate
+		eval.IsMate = true
+		eval.MateIn = &mateIn
+	case pv.CP != nil:
+		eval.Centipawns = sign * *pv.CP
+	default:
+		return nil, fmt.Errorf("%w: no score", ErrNotFound)
+	}
+
+	return &engine.AnalysisResult{
+		Evaluations: []engine.Evaluation{eval},
+		PVCount:     1,
+		BestMove:    moves[0],
+		FEN:         fen,
+		Depth:       body.Depth,
+		Source:      engine.SourceCloud,
+	}, nil
+}
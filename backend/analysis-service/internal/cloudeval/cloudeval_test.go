@@ -0,0 +1,171 @@
+package cloudeval
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"go.uber.org/zap"
+)
+
+// newTestClient returns a client for a server answering with handler
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts Options) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	opts.Endpoint = server.URL + "/api/cloud-eval"
+	if opts.Timeout == 0 {
+		opts.Timeout = time.Second
+	}
+	if opts.FailureThreshold == 0 {
+		opts.FailureThreshold = 3
+	}
+	if opts.Cooldown == 0 {
+		opts.Cooldown = time.Minute
+	}
+	return New(opts, zap.NewNop())
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		body     string
+		wantCP   int
+		wantMate int
+	}{
+		{
+			name:   "white to move",
+			fen:    "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			body:   `{"fen":"x","knodes":1500,"depth":40,"pvs":[{"moves":"e2e4 e7e5 g1f3","cp":18}]}`,
+			wantCP: 18,
+		},
+		{
+			name:   "black to move is flipped",
+			fen:    "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+			body:   `{"fen":"x","knodes":1500,"depth":40,"pvs":[{"moves":"c7c5 g1f3","cp":30}]}`,
+			wantCP: -30,
+		},
+		{
+			name:     "mate",
+			fen:      "6k1/5ppp/8/8/8/8/5PPP/R5K1 b - - 0 1",
+			body:     `{"fen":"x","knodes":10,"depth":40,"pvs":[{"moves":"h7h6 a1a8","mate":2}]}`,
+			wantMate: -2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotFEN, gotMultiPV string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				gotFEN, gotMultiPV = r.URL.Query().Get("fen"), r.URL.Query().Get("multiPv")
+				w.Write([]byte(tt.body))
+			}, Options{})
+
+			result, err := c.Evaluate(context.Background(), tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotFEN != tt.fen || gotMultiPV != "1" {
+				t.Errorf("query fen = %q multiPv = %q", gotFEN, gotMultiPV)
+			}
+			if result.Source != engine.SourceCloud || result.Depth != 40 || result.FEN != tt.fen {
+				t.Errorf("result = %+v", result)
+			}
+			eval := result.Evaluations[0]
+			if result.BestMove != eval.PV[0] {
+				t.Errorf("best move = %q, pv = %v", result.BestMove, eval.PV)
+			}
+			if tt.wantMate != 0 {
+				if !eval.IsMate || eval.MateIn == nil || *eval.MateIn != tt.wantMate {
+					t.Errorf("mate = %v %v, want %d", eval.IsMate, eval.MateIn, tt.wantMate)
+				}
+			} else if eval.IsMate || eval.Centipawns != tt.wantCP {
+				t.Errorf("centipawns = %d, want %d", eval.Centipawns, tt.wantCP)
+			}
+		})
+	}
+}
+
+func TestEvaluate_NotFound(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"Not found"}`, http.StatusNotFound)
+	}, Options{})
+
+	for i := 0; i < 5; i++ {
+		if _, err := c.Evaluate(context.Background(), "8/8/8/8/8/8/8/K6k w - - 0 1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Evaluate() = %v, want ErrNotFound", err)
+		}
+	}
+	if stats := c.Stats(); stats.NotFound != 5 || stats.CircuitOpen {
+		t.Errorf("stats = %+v, want 5 misses and a closed circuit", stats)
+	}
+}
+
+func TestEvaluate_RateLimitedOpensCircuit(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}, Options{})
+
+	if _, err := c.Evaluate(context.Background(), "8/8/8/8/8/8/8/K6k w - - 0 1"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Evaluate() = %v, want ErrUnavailable", err)
+	}
+	if _, err := c.Evaluate(context.Background(), "8/8/8/8/8/8/8/K6k w - - 0 1"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Evaluate() = %v, want ErrUnavailable", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("server calls = %d, want 1 (circuit open after 429)", calls.Load())
+	}
+	if stats := c.Stats(); !stats.CircuitOpen || stats.Rejected != 1 {
+		t.Errorf("stats = %+v, want an open circuit and 1 rejection", stats)
+	}
+}
+
+func TestEvaluate_FailuresOpenCircuit(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}, Options{FailureThreshold: 2, Cooldown: 100 * time.Millisecond})
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Evaluate(context.Background(), "8/8/8/8/8/8/8/K6k w - - 0 1"); err == nil {
+			t.Fatal("Evaluate() = nil error, want failure")
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("server calls = %d, want 2 before the circuit opens", calls.Load())
+	}
+
+	// The circuit closes again after the cooldown
+	time.Sleep(150 * time.Millisecond)
+	if c.Stats().CircuitOpen {
+		t.Error("circuit still open after the cooldown")
+	}
+	c.Evaluate(context.Background(), "8/8/8/8/8/8/8/K6k w - - 0 1")
+	if calls.Load() != 3 {
+		t.Errorf("server calls = %d, want 3 after the cooldown", calls.Load())
+	}
+}
+
+func TestEvaluate_MinInterval(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}, Options{MinInterval: time.Hour})
+
+	c.Evaluate(context.Background(), "8/8/8/8/8/8/8/K6k w - - 0 1")
+	if _, err := c.Evaluate(context.Background(), "8/8/8/8/8/8/8/K6k w - - 0 1"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("second Evaluate() = %v, want ErrUnavailable", err)
+	}
+	if stats := c.Stats(); stats.Requests != 1 || stats.Rejected != 1 || stats.CircuitOpen {
+		t.Errorf("stats = %+v, want 1 request and 1 rejection", stats)
+	}
+}
@@ -0,0 +1,377 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns a config that passes validation
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+
+	binary := filepath.Join(t.TempDir(), "stockfish")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Config{
+		GRPCPort: "50051",
+		HTTPPort: "8081",
+		GRPC: GRPCConfig{
+			KeepaliveTime:    30 * time.Second,
+			KeepaliveTimeout: 10 * time.Second,
+		},
+		Stockfish: StockfishConfig{
+			BinaryPath: binary,
+			Threads:    4,
+			Hash:       2048,
+			MultiPV:    3,
+		},
+		Thresholds:            ThresholdsConfig{Profile: "standard"},
+		AccuracyMethod:        "capped_loss",
+		OpeningMinGames:       3,
+		WorkerPoolSize:        4,
+		MaxConcurrentAnalyses: 10,
+		DefaultDepth:          20,
+		MaxDepth:              30,
+		MinDepth:              10,
+		MaxMultiPV:            10,
+		AnalysisTimeout:       60 * time.Second,
+		LogLevel:              "info",
+		LogFormat:             "json",
+	}
+}
+
+// enableConsumer turns on Redis consumer mode with the default settings
+func enableConsumer(c *Config) {
+	c.Consumer = ConsumerConfig{
+		Driver:            "redis",
+		URL:               "redis://localhost:6379/0",
+		JobsSubject:       "analysis.jobs",
+		ResultsSubject:    "analysis.results",
+		DeadLetterSubject: "analysis.dead",
+		Group:             "analysis-service",
+		MaxRetries:        3,
+		RetryDelay:        30 * time.Second,
+	}
+}
+
+func TestValidate_Valid(t *testing.T) {
+	if err := validConfig(t).Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+
+	cfg := validConfig(t)
+	enableConsumer(cfg)
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with consumer = %v, want nil", err)
+	}
+
+	// One usable binary of the list is enough
+	cfg = validConfig(t)
+	cfg.Stockfish.BinaryPath = "/nonexistent/stockfish:" + cfg.Stockfish.BinaryPath
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with a missing candidate first = %v, want nil", err)
+	}
+}
+
+func TestValidate_Failures(t *testing.T) {
+	// Keep a stockfish installed on this machine out of the binary checks
+	t.Setenv("PATH", t.TempDir())
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   string
+	}{
+		{"bad grpc port", func(c *Config) { c.GRPCPort = "abc" }, `GRPC_PORT="abc" must be a port number`},
+		{"http port out of range", func(c *Config) { c.HTTPPort = "70000" }, `HTTP_PORT="70000" must be a port number`},
+		{"missing binary", func(c *Config) { c.Stockfish.BinaryPath = "/nonexistent/stockfish" }, `STOCKFISH_PATH="/nonexistent/stockfish" has no usable binary: /nonexistent/stockfish does not exist; stockfish is not on PATH`},
+		{"no usable candidate", func(c *Config) { c.Stockfish.BinaryPath = "/nonexistent/stockfish:" + os.TempDir() },
+			"/nonexistent/stockfish does not exist; " + os.TempDir() + " is a directory, not a binary; stockfish is not on PATH"},
+		{"binary is directory", func(c *Config) { c.Stockfish.BinaryPath = os.TempDir() }, "is a directory"},
+		{"zero threads", func(c *Config) { c.Stockfish.Threads = 0 }, "STOCKFISH_THREADS=0 must be at least 1"},
+		{"negative hash", func(c *Config) { c.Stockfish.Hash = -5 }, "STOCKFISH_HASH=-5 must be at least 1"},
+		{"multipv too high", func(c *Config) { c.Stockfish.MultiPV = 11 }, "STOCKFISH_MULTI_PV=11 must be between 1 and 10"},
+		{"engine option without value", func(c *Config) { c.Stockfish.Options = "Contempt=0,Ponder" }, `STOCKFISH_OPTIONS="Contempt=0,Ponder": "Ponder" is not Name=value`},
+		{"zero pool", func(c *Config) { c.WorkerPoolSize = 0 }, "WORKER_POOL_SIZE=0 must be at least 1"},
+		{"zero concurrency", func(c *Config) { c.MaxConcurrentAnalyses = 0 }, "MAX_CONCURRENT_ANALYSES=0 must be at least 1"},
+		{"zero min depth", func(c *Config) { c.MinDepth = 0 }, "MIN_DEPTH=0 must be at least 1"},
+		{"min above max", func(c *Config) { c.MinDepth = 25; c.MaxDepth = 15; c.DefaultDepth = 20 }, "MIN_DEPTH=25 must not exceed MAX_DEPTH=15"},
+		{"default out of range", func(c *Config) { c.DefaultDepth = 40 }, "DEFAULT_DEPTH=40 must be between MIN_DEPTH=10 and MAX_DEPTH=30"},
+		{"max multipv zero", func(c *Config) { c.MaxMultiPV = 0 }, "MAX_MULTI_PV=0 must be between 1 and 10"},
+		{"negative max game plies", func(c *Config) { c.MaxGamePlies = -1 }, "MAX_GAME_PLIES=-1 must not be negative"},
+		{"fast mode above max plies", func(c *Config) { c.MaxGamePlies, c.FastModePlies = 600, 600 }, "FAST_MODE_PLIES=600 must be below MAX_GAME_PLIES=600"},
+		{"negative book plies", func(c *Config) { c.BookPlies = -1 }, "BOOK_PLIES=-1 must not be negative"},
+		{"negative pv length", func(c *Config) { c.MaxPVLength = -1 }, "MAX_PV_LENGTH=-1 must not be negative"},
+		{"negative preview tolerance", func(c *Config) { c.PreviewToleranceCP = -1 }, "PREVIEW_TOLERANCE_CP=-1 must not be negative"},
+		{"zero timeout", func(c *Config) { c.AnalysisTimeout = 0 }, "ANALYSIS_TIMEOUT_SECONDS=0 must be greater than 0"},
+		{"negative health grace", func(c *Config) { c.HealthGracePeriod = -time.Second }, "HEALTH_GRACE_SECONDS=-1 must not be negative"},
+		{"negative heartbeat", func(c *Config) { c.StreamHeartbeatInterval = -time.Second }, "STREAM_HEARTBEAT_SECONDS=-1 must not be negative"},
+		{"negative progress interval", func(c *Config) { c.StreamProgressInterval = -time.Second }, "STREAM_PROGRESS_INTERVAL_SECONDS=-1s must not be negative"},
+		{"negative metrics interval", func(c *Config) { c.StreamMetricsInterval = -1 }, "STREAM_METRICS_INTERVAL=-1 must not be negative"},
+		{"zero keepalive", func(c *Config) { c.GRPC.KeepaliveTime = 0 }, "GRPC_KEEPALIVE_TIME_SECONDS=0 must be greater than 0"},
+		{"zero keepalive timeout", func(c *Config) { c.GRPC.KeepaliveTimeout = 0 }, "GRPC_KEEPALIVE_TIMEOUT_SECONDS=0 must be greater than 0"},
+		{"bad log level", func(c *Config) { c.LogLevel = "verbose" }, `LOG_LEVEL="verbose" must be one of`},
+		{"bad log format", func(c *Config) { c.LogFormat = "xml" }, `LOG_FORMAT="xml" must be json or console`},
+		{"unknown profile", func(c *Config) { c.Thresholds.Profile = "expert" }, `THRESHOLD_PROFILE="expert" must be one of`},
+		{"unknown accuracy method", func(c *Config) { c.AccuracyMethod = "average" }, `ACCURACY_METHOD="average" must be capped_loss or move_mean`},
+		{"zero opening min games", func(c *Config) { c.OpeningMinGames = 0 }, "OPENING_MIN_GAMES=0 must be at least 1"},
+		{"negative cache halfmove clock", func(c *Config) { c.CacheMaxHalfmoveClock = -1 }, "CACHE_MAX_HALFMOVE_CLOCK=-1 must not be negative"},
+		{"negative stale margin", func(c *Config) { c.StaleDepthMargin = -1 }, "STALE_DEPTH_MARGIN=-1 must not be negative"},
+		{"stale without queue", func(c *Config) { c.StaleDepthMargin = 2; c.StaleRefreshQueue = 0 }, "STALE_REFRESH_QUEUE=0 must be at least 1 with STALE_DEPTH_MARGIN set"},
+		{"negative time scramble", func(c *Config) { c.TimeScramble.Blitz = -time.Second }, "TIME_SCRAMBLE_BLITZ_SECONDS=-1 must not be negative"},
+		{"bad thresholds", func(c *Config) { c.Thresholds.Strict = "5,15,30" }, `THRESHOLDS_*: thresholds "5,15,30" must have 5 or 7 comma-separated values`},
+		{"negative log revert", func(c *Config) { c.LogLevelRevert = -time.Minute }, "LOG_LEVEL_REVERT_SECONDS=-60 must not be negative"},
+		{"unknown consumer driver", func(c *Config) { c.Consumer.Driver = "kafka" }, `CONSUMER_DRIVER="kafka" must be redis, nats or empty`},
+		{"consumer url scheme", func(c *Config) { enableConsumer(c); c.Consumer.URL = "nats://localhost:4222" }, "CONSUMER_URL must be a redis://host:port URL"},
+		{"consumer without subject", func(c *Config) { enableConsumer(c); c.Consumer.ResultsSubject = "" }, "CONSUMER_RESULTS_SUBJECT must not be empty"},
+		{"consumer above analysis limit", func(c *Config) { enableConsumer(c); c.Consumer.Concurrency = 11 }, "CONSUMER_CONCURRENCY=11 must be between 0 and MAX_CONCURRENT_ANALYSES=10"},
+		{"cloud eval url", func(c *Config) { c.CloudEval.Enabled = true; c.CloudEval.URL = "lichess.org/api/cloud-eval" }, `CLOUD_EVAL_URL="lichess.org/api/cloud-eval" must be an http(s) URL`},
+		{"zero cloud eval timeout", func(c *Config) { c.CloudEval.Enabled = true; c.CloudEval.Timeout = 0 }, "CLOUD_EVAL_TIMEOUT_SECONDS=0s must be greater than 0"},
+		{"zero cloud eval threshold", func(c *Config) { c.CloudEval.Enabled = true; c.CloudEval.FailureThreshold = 0 }, "CLOUD_EVAL_FAILURE_THRESHOLD=0 must be at least 1"},
+		{"primary cross-check name", func(c *Config) { c.CrossCheck.Enabled = true; c.CrossCheck.Name = "primary" }, `CROSS_CHECK_ENGINE_NAME="primary" must be set`},
+		{"cross-check too deep", func(c *Config) { c.CrossCheck.Enabled = true; c.CrossCheck.Depth = 99 }, "CROSS_CHECK_DEPTH=99 must be between 0 and MAX_DEPTH=30"},
+		{"degrade recover above pool wait", func(c *Config) {
+			c.Degrade = DegradeConfig{Enabled: true, PoolWait: 5 * time.Second, RecoverWait: 10 * time.Second, Window: time.Minute}
+		}, "DEGRADE_RECOVER_WAIT_SECONDS=10s must be greater than 0 and at most DEGRADE_POOL_WAIT_SECONDS=5s"},
+		{"degrade window", func(c *Config) {
+			c.Degrade = DegradeConfig{Enabled: true, PoolWait: 5 * time.Second, RecoverWait: time.Second}
+		}, "DEGRADE_WINDOW_SECONDS=0s must be greater than 0"},
+		{"degrade depth too shallow", func(c *Config) {
+			c.Degrade = DegradeConfig{Enabled: true, PoolWait: 5 * time.Second, RecoverWait: time.Second, Window: time.Minute, Depth: 5}
+		}, "DEGRADE_DEPTH=5 must be 0 or between MIN_DEPTH=10 and MAX_DEPTH=30"},
+		{"cache admission pieces", func(c *Config) {
+			c.CacheAdmission = CacheAdmissionConfig{Enabled: true, Pieces: 33, Sightings: 2}
+		}, "CACHE_ADMISSION_PIECES=33 must be between 0 and 32"},
+		{"cache admission sightings", func(c *Config) { c.CacheAdmission = CacheAdmissionConfig{Enabled: true, Plies: 20, Sightings: 1} }, "CACHE_ADMISSION_SIGHTINGS=1 must be at least 2"},
+		{"drift interval", func(c *Config) { c.Drift = DriftConfig{Enabled: true, Samples: 10} }, "DRIFT_SAMPLE_INTERVAL_SECONDS=0s must be greater than 0"},
+		{"no drift samples", func(c *Config) { c.Drift = DriftConfig{Enabled: true, Interval: time.Minute} }, "DRIFT_SAMPLES=0 must be at least 1"},
+		{"negative drift ceiling", func(c *Config) {
+			c.Drift = DriftConfig{Enabled: true, Interval: time.Minute, Samples: 10, MaxP95: -1}
+		}, "DRIFT_FLUSH_P95_CP=-1 must not be negative"},
+		{"negative consumer retries", func(c *Config) { enableConsumer(c); c.Consumer.MaxRetries = -1 }, "CONSUMER_MAX_RETRIES=-1 must not be negative"},
+		{"missing evaluation import", func(c *Config) { c.ImportEvals = "/nonexistent/evals.csv" }, `IMPORT_EVALS="/nonexistent/evals.csv" cannot be read`},
+		{"postgres sink without dsn", func(c *Config) { c.Postgres.Enabled = true }, "POSTGRES_DSN must be set when POSTGRES_SINK_ENABLED is true"},
+		{"postgres dsn scheme", func(c *Config) { c.Postgres.Enabled = true; c.Postgres.DSN = "mysql://localhost/eloinsight" }, "POSTGRES_DSN must be a postgres://host:port/db URL"},
+		{"negative quota", func(c *Config) { c.Quota.DailyEngineTime = -time.Hour }, "QUOTA_DAILY_ENGINE_SECONDS=-3600 must not be negative"},
+		{"empty transcripts", func(c *Config) { c.Transcripts.Enabled = true; c.Transcripts.MaxBytes = 0 }, "ENGINE_TRANSCRIPT_MAX_BYTES=0 must be at least 1"},
+		{"quota redis url scheme", func(c *Config) { c.Quota.RedisURL = "localhost:6379" }, "QUOTA_REDIS_URL must be a redis://host:port URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(t)
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Validate() = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStockfishConfig_UCIOptions(t *testing.T) {
+	s := StockfishConfig{Options: " Contempt = 20, Skill Level=10,,UCI_AnalyseMode=false"}
+	got, err := s.UCIOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"Contempt": "20", "Skill Level": "10", "UCI_AnalyseMode": "false"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UCIOptions() = %v, want %v", got, want)
+	}
+}
+
+func TestValidate_NotExecutable(t *testing.T) {
+	cfg := validConfig(t)
+	if err := os.Chmod(cfg.Stockfish.BinaryPath, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "is not executable") {
+		t.Errorf("Validate() = %v, want not executable error", err)
+	}
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.WorkerPoolSize = 0
+	cfg.Stockfish.Hash = -5
+	cfg.AnalysisTimeout = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want error")
+	}
+	if lines := strings.Split(err.Error(), "\n"); len(lines) != 3 {
+		t.Errorf("got %d problems, want 3:\n%v", len(lines), err)
+	}
+}
+
+func TestLoad_WarnsOnUnparsableValues(t *testing.T) {
+	t.Setenv("WORKER_POOL_SIZE", "four")
+	t.Setenv("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", "maybe")
+	t.Setenv("DEFAULT_DEPTH", "18")
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.WorkerPoolSize != 4 || cfg.DefaultDepth != 18 {
+		t.Errorf("WorkerPoolSize=%d DefaultDepth=%d, want 4 and 18", cfg.WorkerPoolSize, cfg.DefaultDepth)
+	}
+	if len(cfg.Warnings) != 2 {
+		t.Fatalf("got %d warnings, want 2: %v", len(cfg.Warnings), cfg.Warnings)
+	}
+	if !strings.Contains(cfg.Warnings[0], `GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM="maybe"`) ||
+		!strings.Contains(cfg.Warnings[1], `WORKER_POOL_SIZE="four" could not be parsed, using 4`) {
+		t.Errorf("unexpected warnings: %v", cfg.Warnings)
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfigFile(t, `
+# Production overrides
+min_depth:
+default_depth: 16
+worker_pool_size: 8
+max_depth: 28
+analysis_timeout: 90s
+stockfish:
+  path: "/opt/sf/stockfish"   # mounted binary
+  threads: 2
+grpc:
+  keepalive_time: 45s
+`)
+	t.Setenv("WORKER_POOL_SIZE", "6")
+	t.Setenv("MAX_DEPTH", "25")
+
+	cfg, err := Load([]string{"--config", path, "--pool-size=2", "--stockfish=/opt/sf16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"default from tag", cfg.MinDepth, 10},
+		{"file over default", cfg.DefaultDepth, 16},
+		{"file duration", cfg.AnalysisTimeout, 90 * time.Second},
+		{"file nested duration", cfg.GRPC.KeepaliveTime, 45 * time.Second},
+		{"file nested", cfg.Stockfish.Threads, 2},
+		{"env over file", cfg.MaxDepth, 25},
+		{"flag over env", cfg.WorkerPoolSize, 2},
+		{"flag over file", cfg.Stockfish.BinaryPath, "/opt/sf16"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoad_FileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", "default_dpeth: 16\n", "line 1: field default_dpeth not found"},
+		{"unknown nested key", "stockfish:\n  thread: 2\n", "line 2: field thread not found in type config.StockfishConfig"},
+		{"bad value", "max_depth: deep\n", "line 1: cannot unmarshal !!str `deep` into int"},
+		{"duration without unit", "analysis_timeout: 90\n", "line 1: cannot unmarshal !!int `90` into time.Duration"},
+		{"section as value", "grpc: 30\n", "line 1: cannot unmarshal !!int `30` into config.GRPCConfig"},
+		{"list", "stockfish:\n  - path\n", "line 2: cannot unmarshal !!seq into config.StockfishConfig"},
+		{"malformed", "stockfish: [path\n", "did not find expected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load([]string{"--config", writeConfigFile(t, tt.content)})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_ExampleFile(t *testing.T) {
+	if _, err := Load([]string{"--config", "../../config.example.yaml"}); err != nil {
+		t.Errorf("config.example.yaml: %v", err)
+	}
+}
+
+func TestLoad_FlagErrors(t *testing.T) {
+	if _, err := Load([]string{"--depth=fourteen"}); err == nil {
+		t.Error("expected error for non-integer --depth")
+	}
+	if _, err := Load([]string{"--no-such-flag"}); err == nil {
+		t.Error("expected error for unknown flag")
+	}
+	if _, err := Load([]string{"--config", "/nonexistent/config.yaml"}); err == nil {
+		t.Error("expected error for missing config file")
+	}
+}
+
+func TestLoad_BoolFlag(t *testing.T) {
+	t.Setenv("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", "false")
+
+	cfg, err := Load([]string{"--grpc-permit-without-stream"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.GRPC.PermitWithoutStream {
+		t.Error("bare bool flag should set PermitWithoutStream")
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := cfg.Redacted()
+	if values["stockfish.threads"] != "4" || values["analysis_timeout"] != "1m0s" {
+		t.Errorf("unexpected values: %v", values)
+	}
+	if _, ok := values["warnings"]; ok {
+		t.Error("warnings should not be part of the effective configuration")
+	}
+}
+
+func TestThresholdsConfig_Profiles(t *testing.T) {
+	profiles, err := ThresholdsConfig{Strict: "8,20,40,80,250"}.Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := profiles["strict"]; got.Best != 8 || got.Mistake != 250 {
+		t.Errorf("strict override not applied: %+v", got)
+	}
+	if profiles["standard"].Best != 10 || profiles["lenient"].Best != 15 {
+		t.Errorf("unset profiles should keep built-in values: %+v", profiles)
+	}
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// field is one setting of Config, located through its struct tags
+type field struct {
+	value    reflect.Value
+	env      string
+	yamlPath string // dotted path, e.g. "stockfish.threads"
+	flag     string
+	def      string
+	usage    string
+	secret   bool
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// collectFields walks cfg and returns its settings in declaration order.
+// Nested structs become YAML sections.
+func collectFields(cfg *Config) []field {
+	var fields []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name := sf.Tag.Get("yaml")
+			if name == "" || name == "-" {
+				continue
+			}
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+				walk(v.Field(i), name)
+				continue
+			}
+			fields = append(fields, field{
+				value:    v.Field(i),
+				env:      sf.Tag.Get("env"),
+				yamlPath: name,
+				flag:     sf.Tag.Get("flag"),
+				def:      sf.Tag.Get("default"),
+				usage:    sf.Tag.Get("usage"),
+				secret:   sf.Tag.Get("secret") == "true",
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return fields
+}
+
+// setValue parses raw into v according to v's type
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Uint32:
+		n, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%q is not a non-negative integer", raw)
+		}
+		v.SetUint(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// parseDuration accepts Go durations ("90s", "2m") and bare integers,
+// which are seconds to stay compatible with the *_SECONDS env vars
+func parseDuration(raw string) (time.Duration, error) {
+	if n, err := strconv.Atoi(raw); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration", raw)
+	}
+	return d, nil
+}
+
+func formatValue(v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// flagValue records a command-line value so it can be applied after the
+// config file and environment
+type flagValue struct {
+	field *field
+	raw   string
+}
+
+func (f *flagValue) String() string {
+	if f == nil || f.field == nil {
+		return ""
+	}
+	return f.field.def
+}
+
+func (f *flagValue) Set(raw string) error {
+	// Parse into a scratch value to reject bad input during flag parsing
+	if err := setValue(reflect.New(f.field.value.Type()).Elem(), raw); err != nil {
+		return err
+	}
+	f.raw = raw
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be passed as a bare --flag
+func (f *flagValue) IsBoolFlag() bool {
+	return f.field.value.Kind() == reflect.Bool
+}
+
+// newFlagSet registers --config and one flag per tagged setting
+func newFlagSet(fields []field) (*flag.FlagSet, *string, map[string]*flagValue) {
+	fs := flag.NewFlagSet("analysis-service", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to a YAML config file")
+
+	values := make(map[string]*flagValue)
+	for i := range fields {
+		f := &fields[i]
+		if f.flag == "" {
+			continue
+		}
+		fv := &flagValue{field: f}
+		values[f.flag] = fv
+		fs.Var(fv, f.flag, fmt.Sprintf("%s (env %s)", f.usage, f.env))
+	}
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+		fmt.Fprintln(fs.Output(), "Precedence: defaults < --config file < environment < flags")
+		fs.PrintDefaults()
+	}
+	return fs, configPath, values
+}
+
+// loadFile applies a YAML config file over cfg. Unknown keys are errors so
+// typos don't silently fall back to defaults, and keys left empty (null)
+// keep their defaults.
+func loadFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
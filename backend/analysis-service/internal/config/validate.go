@@ -0,0 +1,357 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+// Validate checks the configuration and returns every problem found,
+// joined into a single error, so a bad deployment fails at startup with
+// an actionable message instead of misbehaving later.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// Ports
+	for _, p := range []struct{ key, port string }{
+		{"GRPC_PORT", c.GRPCPort},
+		{"HTTP_PORT", c.HTTPPort},
+	} {
+		if n, err := strconv.Atoi(p.port); err != nil || n < 1 || n > 65535 {
+			add("%s=%q must be a port number between 1 and 65535", p.key, p.port)
+		}
+	}
+
+	// Stockfish binaries: startup probes them, here one must at least exist
+	if err := checkCandidates(c.Stockfish.BinaryPath); err != nil {
+		add("STOCKFISH_PATH=%q has no usable binary: %v", c.Stockfish.BinaryPath, err)
+	}
+	if c.Stockfish.Threads < 1 {
+		add("STOCKFISH_THREADS=%d must be at least 1", c.Stockfish.Threads)
+	}
+	if c.Stockfish.Hash < 1 {
+		add("STOCKFISH_HASH=%d must be at least 1 (MB)", c.Stockfish.Hash)
+	}
+	if c.Stockfish.MultiPV < 1 || c.Stockfish.MultiPV > 10 {
+		add("STOCKFISH_MULTI_PV=%d must be between 1 and 10", c.Stockfish.MultiPV)
+	}
+	if _, err := c.Stockfish.UCIOptions(); err != nil {
+		add("STOCKFISH_OPTIONS=%q: %v", c.Stockfish.Options, err)
+	}
+
+	// Worker pool
+	if c.WorkerPoolSize < 1 {
+		add("WORKER_POOL_SIZE=%d must be at least 1", c.WorkerPoolSize)
+	}
+	if c.MaxConcurrentAnalyses < 1 {
+		add("MAX_CONCURRENT_ANALYSES=%d must be at least 1", c.MaxConcurrentAnalyses)
+	}
+
+	// Depths
+	if c.MinDepth < 1 {
+		add("MIN_DEPTH=%d must be at least 1", c.MinDepth)
+	}
+	if c.MinDepth > c.MaxDepth {
+		add("MIN_DEPTH=%d must not exceed MAX_DEPTH=%d", c.MinDepth, c.MaxDepth)
+	}
+	if c.DefaultDepth < c.MinDepth || c.DefaultDepth > c.MaxDepth {
+		add("DEFAULT_DEPTH=%d must be between MIN_DEPTH=%d and MAX_DEPTH=%d", c.DefaultDepth, c.MinDepth, c.MaxDepth)
+	}
+	if c.MaxMultiPV < 1 || c.MaxMultiPV > 10 {
+		add("MAX_MULTI_PV=%d must be between 1 and 10", c.MaxMultiPV)
+	}
+
+	// Game length
+	if c.MaxGamePlies < 0 {
+		add("MAX_GAME_PLIES=%d must not be negative", c.MaxGamePlies)
+	}
+	if c.FastModePlies < 0 {
+		add("FAST_MODE_PLIES=%d must not be negative", c.FastModePlies)
+	}
+	if c.MaxGamePlies > 0 && c.FastModePlies >= c.MaxGamePlies {
+		add("FAST_MODE_PLIES=%d must be below MAX_GAME_PLIES=%d", c.FastModePlies, c.MaxGamePlies)
+	}
+	if c.BookPlies < 0 {
+		add("BOOK_PLIES=%d must not be negative", c.BookPlies)
+	}
+	if c.MaxPVLength < 0 {
+		add("MAX_PV_LENGTH=%d must not be negative", c.MaxPVLength)
+	}
+	if c.PreviewToleranceCP < 0 {
+		add("PREVIEW_TOLERANCE_CP=%d must not be negative", c.PreviewToleranceCP)
+	}
+
+	// Classification thresholds
+	if profiles, err := c.Thresholds.Profiles(); err != nil {
+		add("THRESHOLDS_*: %v", err)
+	} else if _, ok := profiles[c.Thresholds.Profile]; !ok {
+		add("THRESHOLD_PROFILE=%q must be one of standard, strict, lenient", c.Thresholds.Profile)
+	}
+	if !evaluation.AccuracyMethod(c.AccuracyMethod).Valid() {
+		add("ACCURACY_METHOD=%q must be capped_loss or move_mean", c.AccuracyMethod)
+	}
+	for _, t := range []struct {
+		key       string
+		threshold time.Duration
+	}{
+		{"TIME_SCRAMBLE_BULLET_SECONDS", c.TimeScramble.Bullet},
+		{"TIME_SCRAMBLE_BLITZ_SECONDS", c.TimeScramble.Blitz},
+		{"TIME_SCRAMBLE_RAPID_SECONDS", c.TimeScramble.Rapid},
+		{"TIME_SCRAMBLE_CLASSICAL_SECONDS", c.TimeScramble.Classical},
+	} {
+		if t.threshold < 0 {
+			add("%s=%d must not be negative", t.key, int(t.threshold.Seconds()))
+		}
+	}
+	if c.OpeningMinGames < 1 {
+		add("OPENING_MIN_GAMES=%d must be at least 1", c.OpeningMinGames)
+	}
+	if c.CacheMaxHalfmoveClock < 0 {
+		add("CACHE_MAX_HALFMOVE_CLOCK=%d must not be negative", c.CacheMaxHalfmoveClock)
+	}
+	if c.StaleDepthMargin < 0 {
+		add("STALE_DEPTH_MARGIN=%d must not be negative", c.StaleDepthMargin)
+	}
+	if c.StaleDepthMargin > 0 && c.StaleRefreshQueue < 1 {
+		add("STALE_REFRESH_QUEUE=%d must be at least 1 with STALE_DEPTH_MARGIN set", c.StaleRefreshQueue)
+	}
+
+	// Timeouts
+	if c.AnalysisTimeout <= 0 {
+		add("ANALYSIS_TIMEOUT_SECONDS=%d must be greater than 0", int(c.AnalysisTimeout.Seconds()))
+	}
+	if c.HealthGracePeriod < 0 {
+		add("HEALTH_GRACE_SECONDS=%d must not be negative", int(c.HealthGracePeriod.Seconds()))
+	}
+	if c.StreamHeartbeatInterval < 0 {
+		add("STREAM_HEARTBEAT_SECONDS=%d must not be negative (0 disables heartbeats)", int(c.StreamHeartbeatInterval.Seconds()))
+	}
+	if c.StreamProgressInterval < 0 {
+		add("STREAM_PROGRESS_INTERVAL_SECONDS=%s must not be negative (0 sends every update)", c.StreamProgressInterval)
+	}
+	if c.StreamMetricsInterval < 0 {
+		add("STREAM_METRICS_INTERVAL=%d must not be negative (0 disables running metrics)", c.StreamMetricsInterval)
+	}
+	if c.GRPC.KeepaliveTime <= 0 {
+		add("GRPC_KEEPALIVE_TIME_SECONDS=%d must be greater than 0", int(c.GRPC.KeepaliveTime.Seconds()))
+	}
+	if c.GRPC.KeepaliveTimeout <= 0 {
+		add("GRPC_KEEPALIVE_TIMEOUT_SECONDS=%d must be greater than 0", int(c.GRPC.KeepaliveTimeout.Seconds()))
+	}
+
+	// Queue consumer
+	switch c.Consumer.Driver {
+	case "":
+	case "redis", "nats":
+		if u, err := url.Parse(c.Consumer.URL); c.Consumer.URL != "" && (err != nil || u.Scheme != c.Consumer.Driver || u.Host == "") {
+			add("CONSUMER_URL must be a %s://host:port URL", c.Consumer.Driver)
+		}
+		for _, s := range []struct{ key, value string }{
+			{"CONSUMER_JOBS_SUBJECT", c.Consumer.JobsSubject},
+			{"CONSUMER_RESULTS_SUBJECT", c.Consumer.ResultsSubject},
+			{"CONSUMER_DEAD_LETTER_SUBJECT", c.Consumer.DeadLetterSubject},
+			{"CONSUMER_GROUP", c.Consumer.Group},
+		} {
+			if s.value == "" {
+				add("%s must not be empty when CONSUMER_DRIVER is set", s.key)
+			}
+		}
+		if c.Consumer.Driver == "nats" && c.Consumer.Stream == "" {
+			add("CONSUMER_STREAM must not be empty when CONSUMER_DRIVER=nats")
+		}
+		if c.Consumer.Concurrency < 0 || c.Consumer.Concurrency > c.MaxConcurrentAnalyses {
+			add("CONSUMER_CONCURRENCY=%d must be between 0 and MAX_CONCURRENT_ANALYSES=%d", c.Consumer.Concurrency, c.MaxConcurrentAnalyses)
+		}
+		if c.Consumer.MaxRetries < 0 {
+			add("CONSUMER_MAX_RETRIES=%d must not be negative", c.Consumer.MaxRetries)
+		}
+		if c.Consumer.RetryDelay <= 0 {
+			add("CONSUMER_RETRY_DELAY_SECONDS=%d must be greater than 0", int(c.Consumer.RetryDelay.Seconds()))
+		}
+	default:
+		add("CONSUMER_DRIVER=%q must be redis, nats or empty", c.Consumer.Driver)
+	}
+
+	// Cloud eval fallback
+	if c.CloudEval.Enabled {
+		if u, err := url.Parse(c.CloudEval.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("CLOUD_EVAL_URL=%q must be an http(s) URL", c.CloudEval.URL)
+		}
+		if c.CloudEval.PoolWait <= 0 {
+			add("CLOUD_EVAL_POOL_WAIT_SECONDS=%s must be greater than 0", c.CloudEval.PoolWait)
+		}
+		if c.CloudEval.MaxDepth < 1 {
+			add("CLOUD_EVAL_MAX_DEPTH=%d must be at least 1", c.CloudEval.MaxDepth)
+		}
+		if c.CloudEval.Timeout <= 0 {
+			add("CLOUD_EVAL_TIMEOUT_SECONDS=%s must be greater than 0", c.CloudEval.Timeout)
+		}
+		if c.CloudEval.MinInterval < 0 {
+			add("CLOUD_EVAL_MIN_INTERVAL_SECONDS=%s must not be negative", c.CloudEval.MinInterval)
+		}
+		if c.CloudEval.FailureThreshold < 1 {
+			add("CLOUD_EVAL_FAILURE_THRESHOLD=%d must be at least 1", c.CloudEval.FailureThreshold)
+		}
+		if c.CloudEval.Cooldown <= 0 {
+			add("CLOUD_EVAL_COOLDOWN_SECONDS=%s must be greater than 0", c.CloudEval.Cooldown)
+		}
+	}
+
+	if c.CrossCheck.Enabled {
+		if c.CrossCheck.Name == "" || c.CrossCheck.Name == "primary" {
+			add("CROSS_CHECK_ENGINE_NAME=%q must be set and not \"primary\"", c.CrossCheck.Name)
+		}
+		if c.CrossCheck.BinaryPath != "" {
+			if err := checkExecutable(c.CrossCheck.BinaryPath); err != nil {
+				add("CROSS_CHECK_ENGINE_PATH=%q %v", c.CrossCheck.BinaryPath, err)
+			}
+		}
+		if c.CrossCheck.PoolSize < 1 {
+			add("CROSS_CHECK_POOL_SIZE=%d must be at least 1", c.CrossCheck.PoolSize)
+		}
+		if c.CrossCheck.Threads < 1 {
+			add("CROSS_CHECK_THREADS=%d must be at least 1", c.CrossCheck.Threads)
+		}
+		if c.CrossCheck.Hash < 1 {
+			add("CROSS_CHECK_HASH=%d must be at least 1 (MB)", c.CrossCheck.Hash)
+		}
+		if c.CrossCheck.Depth < 0 || c.CrossCheck.Depth > c.MaxDepth {
+			add("CROSS_CHECK_DEPTH=%d must be between 0 and MAX_DEPTH=%d", c.CrossCheck.Depth, c.MaxDepth)
+		}
+	}
+
+	if c.Degrade.Enabled {
+		if c.Degrade.PoolWait <= 0 {
+			add("DEGRADE_POOL_WAIT_SECONDS=%s must be greater than 0", c.Degrade.PoolWait)
+		}
+		if c.Degrade.RecoverWait <= 0 || c.Degrade.RecoverWait > c.Degrade.PoolWait {
+			add("DEGRADE_RECOVER_WAIT_SECONDS=%s must be greater than 0 and at most DEGRADE_POOL_WAIT_SECONDS=%s", c.Degrade.RecoverWait, c.Degrade.PoolWait)
+		}
+		if c.Degrade.Window <= 0 {
+			add("DEGRADE_WINDOW_SECONDS=%s must be greater than 0", c.Degrade.Window)
+		}
+		if c.Degrade.Depth != 0 && (c.Degrade.Depth < c.MinDepth || c.Degrade.Depth > c.MaxDepth) {
+			add("DEGRADE_DEPTH=%d must be 0 or between MIN_DEPTH=%d and MAX_DEPTH=%d", c.Degrade.Depth, c.MinDepth, c.MaxDepth)
+		}
+	}
+
+	if c.CacheAdmission.Enabled {
+		if c.CacheAdmission.Plies < 0 {
+			add("CACHE_ADMISSION_PLIES=%d must not be negative", c.CacheAdmission.Plies)
+		}
+		if c.CacheAdmission.Pieces < 0 || c.CacheAdmission.Pieces > 32 {
+			add("CACHE_ADMISSION_PIECES=%d must be between 0 and 32", c.CacheAdmission.Pieces)
+		}
+		if c.CacheAdmission.Sightings < 2 {
+			add("CACHE_ADMISSION_SIGHTINGS=%d must be at least 2 (1 would cache every position)", c.CacheAdmission.Sightings)
+		}
+	}
+
+	if c.Drift.Enabled {
+		if c.Drift.Interval <= 0 {
+			add("DRIFT_SAMPLE_INTERVAL_SECONDS=%s must be greater than 0", c.Drift.Interval)
+		}
+		if c.Drift.Samples < 1 {
+			add("DRIFT_SAMPLES=%d must be at least 1", c.Drift.Samples)
+		}
+		if c.Drift.MaxP95 < 0 {
+			add("DRIFT_FLUSH_P95_CP=%d must not be negative (0 never flushes)", c.Drift.MaxP95)
+		}
+	}
+
+	if c.Quota.DailyEngineTime < 0 {
+		add("QUOTA_DAILY_ENGINE_SECONDS=%d must not be negative (0 disables quotas)", int(c.Quota.DailyEngineTime.Seconds()))
+	}
+	if c.Transcripts.Enabled && c.Transcripts.MaxBytes < 1 {
+		add("ENGINE_TRANSCRIPT_MAX_BYTES=%d must be at least 1", c.Transcripts.MaxBytes)
+	}
+	if c.Quota.RedisURL != "" {
+		if u, err := url.Parse(c.Quota.RedisURL); err != nil || u.Scheme != "redis" || u.Host == "" {
+			add("QUOTA_REDIS_URL must be a redis://host:port URL")
+		}
+	}
+
+	// Evaluation import
+	if c.ImportEvals != "" {
+		if info, err := os.Stat(c.ImportEvals); err != nil {
+			add("IMPORT_EVALS=%q cannot be read: %v", c.ImportEvals, err)
+		} else if info.IsDir() {
+			add("IMPORT_EVALS=%q is a directory, not a file", c.ImportEvals)
+		}
+	}
+
+	// Postgres sink
+	if c.Postgres.Enabled {
+		if c.Postgres.DSN == "" {
+			add("POSTGRES_DSN must be set when POSTGRES_SINK_ENABLED is true")
+		} else if u, err := url.Parse(c.Postgres.DSN); strings.Contains(c.Postgres.DSN, "://") &&
+			(err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") || u.Host == "") {
+			add("POSTGRES_DSN must be a postgres://host:port/db URL or a key=value connection string")
+		}
+	}
+
+	// Logging
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		add("LOG_LEVEL=%q must be one of debug, info, warn, error", c.LogLevel)
+	}
+	switch c.LogFormat {
+	case "json", "console":
+	default:
+		add("LOG_FORMAT=%q must be json or console", c.LogFormat)
+	}
+	if c.LogLevelRevert < 0 {
+		add("LOG_LEVEL_REVERT_SECONDS=%d must not be negative (0 never reverts)", int(c.LogLevelRevert.Seconds()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkCandidates verifies that one of the binaries of list, or
+// engine.FallbackBinary on PATH, is executable, and otherwise says what is
+// wrong with each
+func checkCandidates(list string) error {
+	var problems []string
+	for _, path := range engine.SplitPaths(list) {
+		err := checkExecutable(path)
+		if err == nil {
+			return nil
+		}
+		problems = append(problems, fmt.Sprintf("%s %v", path, err))
+	}
+	if _, err := exec.LookPath(engine.FallbackBinary); err == nil {
+		return nil
+	}
+	problems = append(problems, engine.FallbackBinary+" is not on PATH")
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// checkExecutable verifies that path is an existing, executable regular file
+func checkExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.New("does not exist")
+		}
+		return fmt.Errorf("cannot be accessed: %v", err)
+	}
+	if info.IsDir() {
+		return errors.New("is a directory, not a binary")
+	}
+	if info.Mode().Perm()&0o111 == 0 {
+		return errors.New("is not executable")
+	}
+	return nil
+}
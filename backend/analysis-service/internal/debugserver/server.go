@@ -0,0 +1,141 @@
+// Package debugserver serves live-process inspection endpoints (pprof,
+// expvar and a liveness check) on the service's HTTP port.
+package debugserver
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Server is the debug HTTP server
+type Server struct {
+	addr       string
+	httpServer *http.Server
+	listener   net.Listener
+	logger     *zap.Logger
+	checks     map[string]func() error
+}
+
+// New creates a debug server for addr. Each entry of vars is evaluated on
+// every /debug/vars request and reported next to the standard expvar
+// variables (cmdline, memstats).
+func New(addr string, vars map[string]func() interface{}, logger *zap.Logger) *Server {
+	// Service vars are kept in an unpublished map so servers don't collide
+	// in the global expvar registry
+	extra := new(expvar.Map)
+	for name, fn := range vars {
+		extra.Set(name, expvar.Func(fn))
+	}
+
+	s := &Server{addr: addr, logger: logger, checks: make(map[string]func() error)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.healthz)
+	mux.Handle("/debug/vars", varsHandler(extra))
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	s.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// AddHealthCheck makes /healthz fail with 503 while check returns an
+// error, reported under name. Checks are added before Start.
+func (s *Server) AddHealthCheck(name string, check func() error) {
+	s.checks[name] = check
+}
+
+// healthz answers "ok" while every health check passes, and lists the
+// failing ones with 503 otherwise
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	names := make([]string, 0, len(s.checks))
+	for name := range s.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var failures []string
+	for _, name := range names {
+		if err := s.checks[name](); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		for _, failure := range failures {
+			fmt.Fprintln(w, failure)
+		}
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// Start binds the listen address and serves in the background. A bind
+// error is returned so the caller can decide whether it is fatal.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
+	}
+	s.listener = listener
+
+	go func() {
+		s.logger.Info("Debug HTTP server listening", zap.String("address", listener.Addr().String()))
+		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Debug HTTP server error", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// Addr returns the bound address, or the configured one before Start
+func (s *Server) Addr() string {
+	if s.listener != nil {
+		return s.listener.Addr().String()
+	}
+	return s.addr
+}
+
+// Shutdown stops the server, waiting for in-flight requests until ctx ends
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+// varsHandler writes the global expvar variables followed by extra, in the
+// same JSON shape as expvar.Handler
+func varsHandler(extra *expvar.Map) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		first := true
+		write := func(kv expvar.KeyValue) {
+			if !first {
+				fmt.Fprint(w, ",\n")
+			}
+			first = false
+			name, _ := json.Marshal(kv.Key)
+			fmt.Fprintf(w, "%s: %s", name, kv.Value)
+		}
+
+		fmt.Fprint(w, "{\n")
+		expvar.Do(write)
+		extra.Do(write)
+		fmt.Fprint(w, "\n}\n")
+	})
+}
@@ -0,0 +1,110 @@
+package debugserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func startTestServer(t *testing.T, vars map[string]func() interface{}) *Server {
+	t.Helper()
+	s := New("127.0.0.1:0", vars, zap.NewNop())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+	return s
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestEndpoints(t *testing.T) {
+	s := startTestServer(t, map[string]func() interface{}{
+		"cache": func() interface{} { return map[string]int{"size": 42} },
+	})
+	base := "http://" + s.Addr()
+
+	if code, body := get(t, base+"/healthz"); code != http.StatusOK || strings.TrimSpace(body) != "ok" {
+		t.Errorf("/healthz = %d %q", code, body)
+	}
+
+	code, body := get(t, base+"/debug/vars")
+	if code != http.StatusOK {
+		t.Fatalf("/debug/vars = %d", code)
+	}
+	var vars map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &vars); err != nil {
+		t.Fatalf("/debug/vars is not JSON: %v\n%s", err, body)
+	}
+	if _, ok := vars["memstats"]; !ok {
+		t.Error("/debug/vars should include the standard memstats var")
+	}
+	if string(vars["cache"]) != `{"size":42}` {
+		t.Errorf("cache var = %s", vars["cache"])
+	}
+
+	if code, body := get(t, base+"/debug/pprof/"); code != http.StatusOK || !strings.Contains(body, "goroutine") {
+		t.Errorf("/debug/pprof/ = %d", code)
+	}
+	if code, _ := get(t, base+"/debug/pprof/goroutine?debug=1"); code != http.StatusOK {
+		t.Errorf("/debug/pprof/goroutine = %d", code)
+	}
+}
+
+func TestHealthz_Checks(t *testing.T) {
+	var brokerErr error
+	s := New("127.0.0.1:0", nil, zap.NewNop())
+	s.AddHealthCheck("consumer", func() error { return brokerErr })
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+	url := "http://" + s.Addr() + "/healthz"
+
+	if code, body := get(t, url); code != http.StatusOK || strings.TrimSpace(body) != "ok" {
+		t.Errorf("/healthz with passing checks = %d %q", code, body)
+	}
+	brokerErr = errors.New("connection refused")
+	if code, body := get(t, url); code != http.StatusServiceUnavailable || strings.TrimSpace(body) != "consumer: connection refused" {
+		t.Errorf("/healthz with a failing check = %d %q", code, body)
+	}
+}
+
+func TestTwoServersDoNotCollide(t *testing.T) {
+	// expvar.Publish panics on duplicate names; vars must stay per server
+	vars := map[string]func() interface{}{"pool": func() interface{} { return 1 }}
+	startTestServer(t, vars)
+	startTestServer(t, vars)
+}
+
+func TestStart_PortTaken(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s := New(l.Addr().String(), nil, zap.NewNop())
+	if err := s.Start(); err == nil {
+		t.Error("Start() should fail when the port is taken")
+	}
+}
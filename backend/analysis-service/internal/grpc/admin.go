@@ -0,0 +1,375 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eloinsight/analysis-service/internal/logging"
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AdminServer implements the AdminService gRPC server
+type AdminServer struct {
+	pb.UnimplementedAdminServiceServer
+	levels        *logging.LevelController
+	defaultRevert time.Duration
+	logger        *zap.Logger
+	importer      EvaluationImporter
+	importDir     string
+	stats         StatsSource
+	transcripts   *Transcripts
+	warmer        CacheWarmer
+}
+
+// EvaluationImporter loads precomputed evaluations into the position
+// cache, normally (*analyzer.Analyzer)
+type EvaluationImporter interface {
+	ImportEvaluations(r io.Reader) (analyzer.ImportReport, error)
+	CacheSizeBySource() map[string]int
+}
+
+// StatsSource reports what the analyzer has measured of its engines,
+// normally (*analyzer.Analyzer)
+type StatsSource interface {
+	DepthTimings() []analyzer.DepthTiming
+	Degradation() analyzer.DegradationState
+	Drift() analyzer.DriftState
+	EstimateCapacity(depth, moves int) analyzer.CapacityEstimate
+	PoolStats() pool.Stats
+}
+
+// AdminInterceptors returns the unary and stream interceptors turning away
+// AdminService calls without token as their x-admin-token metadata, with
+// Unauthenticated, or with PermissionDenied while token is empty and the
+// service is disabled. Other services' calls go through, those carrying
+// the token marked as admin calls for their admin-only options such as
+// record_engine_output.
+func AdminInterceptors(token string) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	check := func(ctx context.Context, method string) (context.Context, error) {
+		if strings.HasPrefix(method, "/"+pb.AdminService_ServiceDesc.ServiceName+"/") {
+			return ctx, checkAdmin(ctx, token)
+		}
+		if checkAdmin(ctx, token) == nil {
+			ctx = context.WithValue(ctx, adminCallKey{}, true)
+		}
+		return ctx, nil
+	}
+
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := check(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := check(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+	return unary, stream
+}
+
+type adminCallKey struct{}
+
+// isAdminCall reports whether ctx's call carried the admin token
+func isAdminCall(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminCallKey{}).(bool)
+	return admin
+}
+
+// checkAdmin returns the error of a call whose x-admin-token metadata
+// isn't token, nil when it is
+func checkAdmin(ctx context.Context, token string) error {
+	if token == "" {
+		return status.Error(codes.PermissionDenied, "admin calls are disabled, ADMIN_TOKEN is not set")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	tokens := md.Get("x-admin-token")
+	if len(tokens) == 0 || subtle.ConstantTimeCompare([]byte(tokens[0]), []byte(token)) != 1 {
+		return status.Error(codes.Unauthenticated, "admin calls need a valid x-admin-token")
+	}
+	return nil
+}
+
+// NewAdminServer creates a new admin server. defaultRevert applies when a
+// SetLogLevel request doesn't choose its own revert time.
+func NewAdminServer(levels *logging.LevelController, defaultRevert time.Duration, logger *zap.Logger) *AdminServer {
+	return &AdminServer{
+		levels:        levels,
+		defaultRevert: defaultRevert,
+		logger:        logger,
+	}
+}
+
+// SetImporter enables ImportEvaluations, of paths in dir only; with dir
+// empty only of data
+func (s *AdminServer) SetImporter(importer EvaluationImporter, dir string) {
+	s.importer = importer
+	s.importDir = dir
+}
+
+// SetStatsSource enables GetAnalysisStats and GetCapacityEstimate
+func (s *AdminServer) SetStatsSource(stats StatsSource) {
+	s.stats = stats
+}
+
+// SetLogLevel changes the log level and engine UCI logging at runtime
+func (s *AdminServer) SetLogLevel(ctx context.Context, req *pb.SetLogLevelRequest) (*pb.SetLogLevelResponse, error) {
+	s.logger.Info("SetLogLevel request",
+		zap.String("level", req.Level),
+		zap.Int32("revertAfterSeconds", req.RevertAfterSeconds))
+
+	level := s.levels.Level()
+	if req.Level != "" {
+		switch req.Level {
+		case "debug", "info", "warn", "error":
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "level %q must be one of debug, info, warn, error", req.Level)
+		}
+		if err := level.Set(req.Level); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid level: %v", err)
+		}
+	}
+
+	revertAfter := s.defaultRevert
+	switch {
+	case req.RevertAfterSeconds < 0:
+		revertAfter = 0
+	case req.RevertAfterSeconds > 0:
+		revertAfter = time.Duration(req.RevertAfterSeconds) * time.Second
+	}
+
+	previous := s.levels.Set(level, req.UciDebug, revertAfter)
+
+	resp := &pb.SetLogLevelResponse{
+		PreviousLevel: previous.String(),
+		Level:         level.String(),
+		UciDebug:      s.levels.UCIDebug(),
+	}
+	if revertAt := s.levels.RevertAt(); !revertAt.IsZero() {
+		resp.RevertAtUnixMs = revertAt.UnixMilli()
+	}
+	return resp, nil
+}
+
+// ImportEvaluations loads a CSV of precomputed evaluations, from the
+// request or a file on the server, into the position cache
+func (s *AdminServer) ImportEvaluations(ctx context.Context, req *pb.ImportEvaluationsRequest) (*pb.ImportEvaluationsResponse, error) {
+	s.logger.Info("ImportEvaluations request",
+		zap.String("path", req.Path),
+		zap.Int("bytes", len(req.Data)))
+
+	if s.importer == nil {
+		return nil, status.Error(codes.Unimplemented, "evaluation import is not available")
+	}
+
+	var r io.Reader
+	switch {
+	case req.Path != "" && len(req.Data) > 0:
+		return nil, status.Error(codes.InvalidArgument, "set either path or data, not both")
+	case req.Path != "":
+		path, err := s.importPath(req.Path)
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			s.logger.Warn("Failed to open evaluation import", zap.String("path", path), zap.Error(err))
+			return nil, status.Errorf(codes.InvalidArgument, "can't open %q in the import directory", req.Path)
+		}
+		defer f.Close()
+		r = f
+	case len(req.Data) > 0:
+		r = bytes.NewReader(req.Data)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "path or data is required")
+	}
+
+	report, err := s.importer.ImportEvaluations(r)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "import failed after %d rows: %v", report.Imported+report.Skipped+report.Invalid, err)
+	}
+
+	return &pb.ImportEvaluationsResponse{
+		Imported:      int32(report.Imported),
+		Skipped:       int32(report.Skipped),
+		Invalid:       int32(report.Invalid),
+		Sources:       toInt32Map(report.Sources),
+		Errors:        report.Errors,
+		CacheBySource: toInt32Map(s.importer.CacheSizeBySource()),
+	}, nil
+}
+
+// importPath resolves the path of an ImportEvaluations request in the
+// import directory. Relative paths are taken from it; paths with ".." or
+// outside it are refused, as are all paths without one.
+func (s *AdminServer) importPath(path string) (string, error) {
+	if s.importDir == "" {
+		return "", status.Error(codes.FailedPrecondition, "path imports are disabled, IMPORT_EVALS_DIR is not set; send the file as data")
+	}
+	for _, elem := range strings.Split(filepath.ToSlash(path), "/") {
+		if elem == ".." {
+			return "", status.Errorf(codes.InvalidArgument, "path %q must not contain ..", path)
+		}
+	}
+	dir, resolved := filepath.Clean(s.importDir), filepath.Clean(path)
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(dir, resolved)
+	}
+	if rel, err := filepath.Rel(dir, resolved); err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", status.Errorf(codes.InvalidArgument, "path %q is outside the import directory", path)
+	}
+	return resolved, nil
+}
+
+// GetAnalysisStats returns the analyzer's rolling engine time per position
+// by depth bucket, the state of its degradation controller, its pool's
+// busy time by tag and the drift of its cache from the engines
+func (s *AdminServer) GetAnalysisStats(ctx context.Context, req *pb.GetAnalysisStatsRequest) (*pb.AnalysisStats, error) {
+	if s.stats == nil {
+		return nil, status.Error(codes.Unimplemented, "analysis stats are not available")
+	}
+
+	timings := s.stats.DepthTimings()
+	resp := &pb.AnalysisStats{
+		DepthTimings:      make([]*pb.DepthTiming, 0, len(timings)),
+		HistogramBoundsMs: analyzer.TimingHistogramBounds,
+	}
+	for _, timing := range timings {
+		resp.DepthTimings = append(resp.DepthTimings, convertDepthTiming(timing))
+	}
+	resp.PoolUsage = convertPoolUsage(s.stats.PoolStats())
+
+	degradation := s.stats.Degradation()
+	resp.Degradation = &pb.Degradation{
+		Enabled:       degradation.Enabled,
+		Active:        degradation.Active,
+		PoolWaitP95Ms: degradation.PoolWaitP95.Milliseconds(),
+		Samples:       int32(degradation.Samples),
+		Depth:         int32(degradation.Depth),
+		Activations:   degradation.Activations,
+		DegradedGames: degradation.DegradedGames,
+	}
+	if !degradation.Since.IsZero() {
+		resp.Degradation.SinceUnixMs = degradation.Since.UnixMilli()
+	}
+
+	drift := s.stats.Drift()
+	resp.EvalDrift = &pb.EvalDrift{
+		Enabled:        drift.Enabled,
+		P50Cp:          int32(drift.P50),
+		P95Cp:          int32(drift.P95),
+		Samples:        int32(drift.Samples),
+		Sampled:        drift.Sampled,
+		Failed:         drift.Failed,
+		BackedOff:      drift.BackedOff,
+		MaxP95Cp:       int32(drift.MaxP95),
+		Flushes:        drift.Flushes,
+		FlushedEntries: drift.Flushed,
+	}
+	if !drift.LastFlush.IsZero() {
+		resp.EvalDrift.LastFlushUnixMs = drift.LastFlush.UnixMilli()
+	}
+	return resp, nil
+}
+
+// convertDepthTiming converts the engine time of a depth range, and of its
+// phases, to proto
+func convertDepthTiming(timing analyzer.DepthTiming) *pb.DepthTiming {
+	result := &pb.DepthTiming{
+		MinDepth:      int32(timing.MinDepth),
+		MaxDepth:      int32(timing.MaxDepth),
+		MsPerPosition: timing.MsPerPosition,
+		Searches:      timing.Searches,
+		Phase:         string(timing.Phase),
+		Samples:       timing.Samples,
+		Histogram:     timing.Histogram,
+	}
+	for _, phase := range timing.Phases {
+		result.Phases = append(result.Phases, convertDepthTiming(phase))
+	}
+	return result
+}
+
+// convertPoolUsage converts a pool's busy time by tag and engine to proto.
+// The total is that of the tags in whole milliseconds, so they add up.
+func convertPoolUsage(stats pool.Stats) *pb.EnginePoolUsage {
+	usage := &pb.EnginePoolUsage{
+		Tags:    make([]*pb.EngineTagUsage, 0, len(stats.Tags)),
+		Engines: make([]*pb.EngineUsage, 0, len(stats.Engines)),
+	}
+	for _, tag := range stats.Tags {
+		usage.BusyMs += tag.Busy.Milliseconds()
+		usage.Tags = append(usage.Tags, &pb.EngineTagUsage{
+			Tag:    tag.Tag,
+			BusyMs: tag.Busy.Milliseconds(),
+			Share:  tag.Share,
+			Loans:  tag.Loans,
+			InUse:  int32(tag.InUse),
+		})
+	}
+	for _, e := range stats.Engines {
+		busy := make(map[string]int64, len(e.Busy))
+		for tag, d := range e.Busy {
+			busy[tag] = d.Milliseconds()
+		}
+		usage.Engines = append(usage.Engines, &pb.EngineUsage{Id: int32(e.ID), BusyMs: busy})
+	}
+	return usage
+}
+
+// GetCapacityEstimate returns the game analyses per hour the engine pool
+// can finish at a depth, from the engine time measured at depths of its
+// range
+func (s *AdminServer) GetCapacityEstimate(ctx context.Context, req *pb.GetCapacityEstimateRequest) (*pb.CapacityEstimate, error) {
+	if s.stats == nil {
+		return nil, status.Error(codes.Unimplemented, "analysis stats are not available")
+	}
+	if req.Depth < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "depth %d must be at least 1", req.Depth)
+	}
+	if req.AverageMoves < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "average_moves %d must be at least 1", req.AverageMoves)
+	}
+
+	capacity := s.stats.EstimateCapacity(int(req.Depth), int(req.AverageMoves))
+	return &pb.CapacityEstimate{
+		Depth:            int32(capacity.Depth),
+		MinDepth:         int32(capacity.MinDepth),
+		MaxDepth:         int32(capacity.MaxDepth),
+		AverageMoves:     int32(capacity.Moves),
+		PositionsPerGame: int32(capacity.Positions),
+		Engines:          int32(capacity.Engines),
+		MsPerPosition:    capacity.MsPerPosition,
+		P90MsPerPosition: capacity.P90MsPerPosition,
+		GamesPerHour:     capacity.GamesPerHour,
+		GamesPerHourP90:  capacity.GamesPerHourP90,
+		Samples:          capacity.Samples,
+		Confidence:       capacity.Confidence,
+		Known:            capacity.Known,
+	}, nil
+}
+
+func toInt32Map(m map[string]int) map[string]int32 {
+	out := make(map[string]int32, len(m))
+	for k, v := range m {
+		out[k] = int32(v)
+	}
+	return out
+}
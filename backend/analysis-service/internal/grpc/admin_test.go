@@ -0,0 +1,341 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/internal/logging"
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestAdminServer() *AdminServer {
+	levels := logging.NewLevelController(zap.NewAtomicLevelAt(zapcore.InfoLevel), false, nil, zap.NewNop())
+	return NewAdminServer(levels, 15*time.Minute, zap.NewNop())
+}
+
+// newAdminConn serves an admin server and health checks behind the admin
+// interceptors of token
+func newAdminConn(t *testing.T, token string) *grpc.ClientConn {
+	t.Helper()
+	unary, stream := AdminInterceptors(token)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(unary), grpc.ChainStreamInterceptor(stream))
+	pb.RegisterAdminServiceServer(server, newTestAdminServer())
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestAdminInterceptors(t *testing.T) {
+	conn := newAdminConn(t, "admin-secret")
+	admin := pb.NewAdminServiceClient(conn)
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-admin-token", token)
+	}
+
+	// Unary and stream calls without the token are refused
+	for name, ctx := range map[string]context.Context{
+		"no token":    context.Background(),
+		"wrong token": withToken("admin-guess"),
+	} {
+		if _, err := admin.SetLogLevel(ctx, &pb.SetLogLevelRequest{Level: "debug"}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("SetLogLevel with %s: %v, want Unauthenticated", name, err)
+		}
+		stream, err := admin.WarmCache(ctx, &pb.WarmCacheRequest{DryRun: true})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("WarmCache with %s: %v, want Unauthenticated", name, err)
+		}
+	}
+
+	if _, err := admin.SetLogLevel(withToken("admin-secret"), &pb.SetLogLevelRequest{Level: "debug"}); err != nil {
+		t.Errorf("SetLogLevel with the token: %v", err)
+	}
+	// Other services don't need it, and their calls carrying it are
+	// admin calls
+	if _, err := grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Errorf("health check without the token: %v", err)
+	}
+	unary, _ := AdminInterceptors("admin-secret")
+	for token, want := range map[string]bool{"": false, "admin-guess": false, "admin-secret": true} {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-admin-token", token))
+		var admin bool
+		_, err := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: pb.AnalysisService_AnalyzeGame_FullMethodName},
+			func(ctx context.Context, _ interface{}) (interface{}, error) {
+				admin = isAdminCall(ctx)
+				return nil, nil
+			})
+		if err != nil || admin != want {
+			t.Errorf("AnalyzeGame with token %q: admin call %v, %v; want %v", token, admin, err, want)
+		}
+	}
+}
+
+func TestAdminInterceptors_NoToken(t *testing.T) {
+	admin := pb.NewAdminServiceClient(newAdminConn(t, ""))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-admin-token", "")
+	if _, err := admin.SetLogLevel(ctx, &pb.SetLogLevelRequest{Level: "debug"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("SetLogLevel without ADMIN_TOKEN: %v, want PermissionDenied", err)
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	s := newTestAdminServer()
+	uci := true
+
+	before := time.Now()
+	resp, err := s.SetLogLevel(context.Background(), &pb.SetLogLevelRequest{Level: "debug", UciDebug: &uci})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.PreviousLevel != "info" || resp.Level != "debug" || !resp.UciDebug {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	// Server default revert applies when the request doesn't set one
+	revertAt := time.UnixMilli(resp.RevertAtUnixMs)
+	if revertAt.Before(before.Add(14*time.Minute)) || revertAt.After(time.Now().Add(16*time.Minute)) {
+		t.Errorf("revert at %v, want about 15 minutes from now", revertAt)
+	}
+
+	resp, err = s.SetLogLevel(context.Background(), &pb.SetLogLevelRequest{Level: "debug", RevertAfterSeconds: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.RevertAtUnixMs != 0 {
+		t.Errorf("RevertAtUnixMs = %d, want 0 for a permanent change", resp.RevertAtUnixMs)
+	}
+	if !resp.UciDebug {
+		t.Error("unset uci_debug should leave it unchanged")
+	}
+}
+
+func TestSetLogLevel_InvalidLevel(t *testing.T) {
+	s := newTestAdminServer()
+
+	_, err := s.SetLogLevel(context.Background(), &pb.SetLogLevelRequest{Level: "verbose"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestImportEvaluations(t *testing.T) {
+	s := newTestAdminServer()
+	req := &pb.ImportEvaluationsRequest{
+		Data: []byte("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -,36,18,,e2e4,lichess\nnot a fen,1,0,,e2e4,lichess\n"),
+	}
+
+	if _, err := s.ImportEvaluations(context.Background(), req); status.Code(err) != codes.Unimplemented {
+		t.Errorf("without an importer: code = %v, want Unimplemented", status.Code(err))
+	}
+
+	dir := t.TempDir()
+	s.SetImporter(analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute), dir)
+	resp, err := s.ImportEvaluations(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Imported != 1 || resp.Invalid != 1 || resp.Sources["lichess"] != 1 || len(resp.Errors) != 1 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.CacheBySource[engine.SourceImported] != 1 {
+		t.Errorf("cache by source = %v", resp.CacheBySource)
+	}
+
+	// Importing the same file again finds everything cached, by its path
+	// in the import directory or from it
+	path := filepath.Join(dir, "evals.csv")
+	if err := os.WriteFile(path, req.Data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{path, "evals.csv"} {
+		resp, err = s.ImportEvaluations(context.Background(), &pb.ImportEvaluationsRequest{Path: p})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Imported != 0 || resp.Skipped != 1 {
+			t.Errorf("import of %s again: %+v, want the row skipped", p, resp)
+		}
+	}
+
+	outside := filepath.Join(t.TempDir(), "evals.csv")
+	if err := os.WriteFile(outside, req.Data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []*pb.ImportEvaluationsRequest{
+		{},
+		{Path: path, Data: req.Data},
+		{Path: "missing.csv"},
+		{Path: outside},
+		{Path: "../" + filepath.Base(filepath.Dir(outside)) + "/evals.csv"},
+		{Path: dir + "/../" + filepath.Base(dir) + "/evals.csv"},
+		{Path: "/etc/passwd"},
+	} {
+		_, err := s.ImportEvaluations(context.Background(), bad)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("ImportEvaluations(%+v) code = %v, want InvalidArgument", bad, status.Code(err))
+		}
+		if msg := status.Convert(err).Message(); strings.Contains(msg, "no such file") {
+			t.Errorf("ImportEvaluations(%+v) error %q passes on the OS error", bad, msg)
+		}
+	}
+
+	// Without an import directory only data is imported
+	s.SetImporter(analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute), "")
+	if _, err := s.ImportEvaluations(context.Background(), &pb.ImportEvaluationsRequest{Path: path}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("path without an import directory: code = %v, want FailedPrecondition", status.Code(err))
+	}
+}
+
+// fixedStats reports a fixed set of depth timings, degraded since a fixed
+// time
+type fixedStats []analyzer.DepthTiming
+
+func (f fixedStats) DepthTimings() []analyzer.DepthTiming { return f }
+
+func (f fixedStats) Degradation() analyzer.DegradationState {
+	return analyzer.DegradationState{
+		Enabled:     true,
+		Active:      true,
+		Since:       time.UnixMilli(1700000000000),
+		PoolWaitP95: 6 * time.Second,
+		Samples:     40,
+		Depth:       14,
+		Activations: 1,
+	}
+}
+
+// Drift reports a cache drifted enough to have been flushed once
+func (f fixedStats) Drift() analyzer.DriftState {
+	return analyzer.DriftState{
+		Enabled: true, MaxP95: 50, P50: 4, P95: 31, Samples: 40, Sampled: 260, Failed: 2, BackedOff: 5,
+		Flushes: 1, Flushed: 1800, LastFlush: time.UnixMilli(1700000000000),
+	}
+}
+
+// PoolStats reports an engine pool busy with a game and a position
+func (f fixedStats) PoolStats() pool.Stats {
+	return pool.Stats{
+		BusyTime: 4500 * time.Millisecond,
+		Tags: []pool.TagStats{
+			{Tag: "AnalyzeGame", Busy: 3600400 * time.Microsecond, Share: 80.01, Loans: 3, InUse: 2},
+			{Tag: "AnalyzePosition", Busy: 899600 * time.Microsecond, Share: 19.99, Loans: 1},
+		},
+		Engines: []pool.EngineStats{
+			{ID: 1, Busy: map[string]time.Duration{"AnalyzeGame": 2 * time.Second, "AnalyzePosition": 899600 * time.Microsecond}},
+			{ID: 2, Busy: map[string]time.Duration{"AnalyzeGame": 1600400 * time.Microsecond}},
+		},
+	}
+}
+
+// EstimateCapacity reports games of a fixed 50ms per position on 4 engines
+func (f fixedStats) EstimateCapacity(depth, moves int) analyzer.CapacityEstimate {
+	positions := 2*moves + 1
+	return analyzer.CapacityEstimate{
+		Depth: depth, MinDepth: 21, MaxDepth: 24, Moves: moves, Positions: positions, Engines: 4,
+		MsPerPosition: 50, GamesPerHour: 4 * 3600000 / float64(50*positions),
+		Samples: 20, Confidence: 0.5, Known: true,
+	}
+}
+
+func TestGetAnalysisStats(t *testing.T) {
+	s := newTestAdminServer()
+	if _, err := s.GetAnalysisStats(context.Background(), &pb.GetAnalysisStatsRequest{}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("without a stats source: code = %v, want Unimplemented", status.Code(err))
+	}
+
+	s.SetStatsSource(fixedStats{
+		{MinDepth: 9, MaxDepth: 12, MsPerPosition: 42.5, Searches: 7},
+		{MinDepth: 17, MaxDepth: 20, MsPerPosition: 310, Searches: 2},
+	})
+	resp, err := s.GetAnalysisStats(context.Background(), &pb.GetAnalysisStatsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.DepthTimings) != 2 {
+		t.Fatalf("depth timings = %v, want 2", resp.DepthTimings)
+	}
+	if got := resp.DepthTimings[0]; got.MinDepth != 9 || got.MaxDepth != 12 || got.MsPerPosition != 42.5 || got.Searches != 7 {
+		t.Errorf("first timing = %v", got)
+	}
+	if got := resp.Degradation; !got.Active || got.SinceUnixMs != 1700000000000 || got.PoolWaitP95Ms != 6000 || got.Depth != 14 {
+		t.Errorf("degradation = %v", got)
+	}
+
+	if got := resp.EvalDrift; !got.Enabled || got.P50Cp != 4 || got.P95Cp != 31 || got.Samples != 40 || got.MaxP95Cp != 50 ||
+		got.Flushes != 1 || got.FlushedEntries != 1800 || got.LastFlushUnixMs != 1700000000000 {
+		t.Errorf("eval drift = %v", got)
+	}
+
+	// The tags' whole milliseconds add up to the total
+	usage := resp.PoolUsage
+	if len(usage.Tags) != 2 || len(usage.Engines) != 2 {
+		t.Fatalf("pool usage = %v", usage)
+	}
+	if got := usage.Tags[0]; got.Tag != "AnalyzeGame" || got.BusyMs != 3600 || got.Share != 80.01 || got.Loans != 3 || got.InUse != 2 {
+		t.Errorf("first tag = %v", got)
+	}
+	if usage.BusyMs != usage.Tags[0].BusyMs+usage.Tags[1].BusyMs {
+		t.Errorf("busy %dms, tags %dms and %dms", usage.BusyMs, usage.Tags[0].BusyMs, usage.Tags[1].BusyMs)
+	}
+	if got := usage.Engines[0]; got.Id != 1 || got.BusyMs["AnalyzeGame"] != 2000 || got.BusyMs["AnalyzePosition"] != 899 {
+		t.Errorf("first engine = %v", got)
+	}
+}
+
+func TestGetCapacityEstimate(t *testing.T) {
+	s := newTestAdminServer()
+	req := &pb.GetCapacityEstimateRequest{Depth: 22, AverageMoves: 40}
+	if _, err := s.GetCapacityEstimate(context.Background(), req); status.Code(err) != codes.Unimplemented {
+		t.Errorf("without a stats source: code = %v, want Unimplemented", status.Code(err))
+	}
+
+	s.SetStatsSource(fixedStats{})
+	for _, bad := range []*pb.GetCapacityEstimateRequest{{AverageMoves: 40}, {Depth: 22}, {Depth: 22, AverageMoves: -1}} {
+		if _, err := s.GetCapacityEstimate(context.Background(), bad); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("GetCapacityEstimate(%v) code = %v, want InvalidArgument", bad, status.Code(err))
+		}
+	}
+
+	resp, err := s.GetCapacityEstimate(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Known || resp.Depth != 22 || resp.MinDepth != 21 || resp.PositionsPerGame != 81 || resp.Engines != 4 {
+		t.Errorf("capacity = %v", resp)
+	}
+	if want := 4 * 3600000 / (50.0 * 81); resp.GamesPerHour != want || resp.Confidence != 0.5 {
+		t.Errorf("games per hour %v at confidence %v, want %v at 0.5", resp.GamesPerHour, resp.Confidence, want)
+	}
+}
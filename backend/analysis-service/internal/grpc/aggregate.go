@@ -0,0 +1,218 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AggregateAnalyses aggregates a player's analyzed games into a report
+func (s *Server) AggregateAnalyses(ctx context.Context, req *pb.AggregateAnalysesRequest) (*pb.PlayerReport, error) {
+	s.logger.Info("AggregateAnalyses request",
+		zap.String("player", req.Player),
+		zap.Int("games", len(req.Games)))
+
+	if req.Player == "" {
+		return nil, status.Error(codes.InvalidArgument, "player is required")
+	}
+	if len(req.GameIds) > 0 {
+		return nil, status.Error(codes.FailedPrecondition, "game_ids need a job store, which this service doesn't have; send the analyses in games")
+	}
+
+	games := make([]evaluation.GameEvaluation, 0, len(req.Games))
+	for i, game := range req.Games {
+		eval, err := toGameEvaluation(game)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "game %d: %v", i, err)
+		}
+		games = append(games, eval)
+	}
+
+	return convertPlayerReport(evaluation.AggregatePlayerReport(games, req.Player)), nil
+}
+
+// toGameEvaluation converts an analyzed game to the evaluation package's
+// form, with evaluations from White's point of view
+func toGameEvaluation(game *pb.AnalyzedGame) (evaluation.GameEvaluation, error) {
+	if game.Analysis == nil {
+		return evaluation.GameEvaluation{}, fmt.Errorf("analysis is required")
+	}
+
+	var result evaluation.GameResult
+	switch game.Result {
+	case "1-0":
+		result = evaluation.ResultWin
+	case "0-1":
+		result = evaluation.ResultLoss
+	case "1/2-1/2":
+		result = evaluation.ResultDraw
+	case "*", "":
+		result = evaluation.ResultUnknown
+	default:
+		return evaluation.GameEvaluation{}, fmt.Errorf("result %q must be 1-0, 0-1, 1/2-1/2, * or empty", game.Result)
+	}
+
+	analysis := toGameAnalysis(game.Analysis)
+	eval := evaluation.GameEvaluation{
+		GameID:       analysis.GameID,
+		WhitePlayer:  game.WhitePlayer,
+		BlackPlayer:  game.BlackPlayer,
+		Result:       result,
+		WhiteMetrics: toPlayerMetrics(game.Analysis.WhiteMetrics),
+		BlackMetrics: toPlayerMetrics(game.Analysis.BlackMetrics),
+		Moves:        make([]evaluation.MoveEvaluation, 0, len(analysis.Moves)),
+		ECO:          game.Eco,
+		OpeningName:  game.OpeningName,
+		TimeClass:    game.TimeClass,
+	}
+	if game.PlayedAtUnixMs > 0 {
+		eval.PlayedAt = time.UnixMilli(game.PlayedAtUnixMs)
+	}
+
+	for _, move := range analysis.Moves {
+		// Scores before the move are the mover's, after it the opponent's
+		sign := 1
+		if move.Color == "black" {
+			sign = -1
+		}
+		eval.Moves = append(eval.Moves, evaluation.MoveEvaluation{
+			Ply:            move.Ply,
+			MoveNumber:     move.MoveNumber,
+			Color:          move.Color,
+			PlayedMove:     move.PlayedMove,
+			BestMove:       move.BestMove,
+			EvalBefore:     sign * centipawns(move.EvalBefore),
+			EvalAfter:      -sign * centipawns(move.EvalAfter),
+			IsMateScore:    move.EvalAfter.IsMate,
+			CentipawnLoss:  move.CentipawnLoss,
+			WasBestMove:    analyzer.SameMove(move.BestMoveUCI, move.PlayedMoveUCI),
+			Classification: evaluation.MoveClassification(move.Classification),
+			FEN:            move.FENBefore,
+		})
+	}
+
+	return eval, nil
+}
+
+// centipawns returns an evaluation in centipawns, mates normalized
+func centipawns(eval engine.Evaluation) int {
+	if eval.IsMate && eval.MateIn != nil {
+		return evaluation.NormalizeMateScore(*eval.MateIn)
+	}
+	return eval.Centipawns
+}
+
+// toPlayerMetrics converts proto game metrics to the evaluation type
+func toPlayerMetrics(metrics *pb.GameMetrics) evaluation.PlayerMetrics {
+	if metrics == nil {
+		return evaluation.PlayerMetrics{}
+	}
+	return evaluation.PlayerMetrics{
+		Accuracy:          float64(metrics.Accuracy),
+		ACPL:              float64(metrics.Acpl),
+		Blunders:          int(metrics.Blunders),
+		Mistakes:          int(metrics.Mistakes),
+		Inaccuracies:      int(metrics.Inaccuracies),
+		GoodMoves:         int(metrics.GoodMoves),
+		ExcellentMoves:    int(metrics.ExcellentMoves),
+		BestMoves:         int(metrics.BestMoves),
+		BrilliantMoves:    int(metrics.BrilliantMoves),
+		BookMoves:         int(metrics.BookMoves),
+		TotalMoves:        int(metrics.TotalMoves),
+		PerformanceRating: int(metrics.PerformanceRating),
+		GarbageTimeMoves:  int(metrics.GarbageTimeMoves),
+		AccuracyStddev:    float64(metrics.AccuracyStddev),
+		ACPLStddev:        float64(metrics.AcplStddev),
+		Resilience:        toResilience(metrics.Resilience),
+
+		ExpectedPointsLost: float64(metrics.ExpectedPointsLost),
+	}
+}
+
+// convertPlayerReport converts a player report to proto
+func convertPlayerReport(report evaluation.PlayerReport) *pb.PlayerReport {
+	result := &pb.PlayerReport{
+		Player:            report.Player,
+		Games:             int32(report.Games),
+		Wins:              int32(report.Wins),
+		Draws:             int32(report.Draws),
+		Losses:            int32(report.Losses),
+		GamesAsWhite:      int32(report.GamesAsWhite),
+		GamesAsBlack:      int32(report.GamesAsBlack),
+		SkippedGames:      int32(report.SkippedGames),
+		AverageAccuracy:   float32(evaluation.RoundAccuracy(report.AverageAccuracy)),
+		Acpl:              float32(evaluation.RoundACPL(report.ACPL)),
+		TotalMoves:        int32(report.TotalMoves),
+		Blunders:          int32(report.Blunders),
+		BlunderRate:       float32(report.BlunderRate),
+		LongestWinStreak:  int32(report.LongestWinStreak),
+		LongestLossStreak: int32(report.LongestLossStreak),
+		CurrentStreak:     int32(report.CurrentStreak),
+		UnknownResults:    int32(report.UnknownResults),
+	}
+
+	for _, tc := range report.TimeClasses {
+		result.TimeClasses = append(result.TimeClasses, &pb.TimeClassStats{
+			TimeClass:       tc.TimeClass,
+			Games:           int32(tc.Games),
+			Wins:            int32(tc.Wins),
+			Draws:           int32(tc.Draws),
+			Losses:          int32(tc.Losses),
+			AverageAccuracy: float32(evaluation.RoundAccuracy(tc.AverageAccuracy)),
+		})
+	}
+	for _, o := range report.Openings {
+		result.Openings = append(result.Openings, &pb.OpeningRecord{
+			Eco:             o.ECO,
+			Name:            o.Name,
+			Games:           int32(o.Games),
+			Wins:            int32(o.Wins),
+			Draws:           int32(o.Draws),
+			Losses:          int32(o.Losses),
+			Score:           float32(o.Score),
+			AverageAccuracy: float32(evaluation.RoundAccuracy(o.AverageAccuracy)),
+		})
+	}
+	for _, b := range report.AccuracyBuckets {
+		result.AccuracyBuckets = append(result.AccuracyBuckets, &pb.AccuracyBucket{
+			Min:   int32(b.Min),
+			Max:   int32(b.Max),
+			Games: int32(b.Games),
+		})
+	}
+	for _, p := range report.Phases {
+		result.Phases = append(result.Phases, &pb.PhaseStats{
+			Phase:        string(p.Phase),
+			Moves:        int32(p.Moves),
+			Blunders:     int32(p.Blunders),
+			Mistakes:     int32(p.Mistakes),
+			Inaccuracies: int32(p.Inaccuracies),
+			BlunderRate:  float32(p.BlunderRate),
+			Acpl:         float32(evaluation.RoundACPL(p.ACPL)),
+		})
+	}
+	for _, point := range report.Trend {
+		var playedAt int64
+		if !point.PlayedAt.IsZero() {
+			playedAt = point.PlayedAt.UnixMilli()
+		}
+		result.Trend = append(result.Trend, &pb.GameTrendPoint{
+			GameId:         point.GameID,
+			PlayedAtUnixMs: playedAt,
+			Accuracy:       float32(evaluation.RoundAccuracy(point.Accuracy)),
+			Blunders:       int32(point.Blunders),
+			BlunderRate:    float32(point.BlunderRate),
+			Result:         string(point.Result),
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAggregateAnalyses(t *testing.T) {
+	s := NewServer(nil, nil, zap.NewNop(), 0)
+
+	analysis := exportAnalysis()
+	analysis.WhiteMetrics = &pb.GameMetrics{Accuracy: 82, TotalMoves: 2}
+	analysis.BlackMetrics = &pb.GameMetrics{Accuracy: 64, TotalMoves: 2}
+
+	report, err := s.AggregateAnalyses(context.Background(), &pb.AggregateAnalysesRequest{
+		Player: "alice",
+		Games: []*pb.AnalyzedGame{
+			{Analysis: analysis, WhitePlayer: "Alice", BlackPlayer: "Bob", Result: "1-0", Eco: "C20", TimeClass: "blitz", PlayedAtUnixMs: 1_700_000_000_000},
+			{Analysis: analysis, WhitePlayer: "Bob", BlackPlayer: "Alice", Result: "1-0", Eco: "C20", TimeClass: "blitz", PlayedAtUnixMs: 1_700_000_100_000},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Games != 2 || report.Wins != 1 || report.Losses != 1 {
+		t.Errorf("games = %d, W/L = %d/%d, want 2, 1/1", report.Games, report.Wins, report.Losses)
+	}
+	if report.AverageAccuracy != 73 {
+		t.Errorf("average accuracy = %v, want 73", report.AverageAccuracy)
+	}
+	if report.TotalMoves != 4 {
+		t.Errorf("total moves = %d, want 4", report.TotalMoves)
+	}
+	if len(report.Trend) != 2 || report.Trend[0].Result != "win" || report.Trend[1].PlayedAtUnixMs != 1_700_000_100_000 {
+		t.Errorf("trend = %v", report.Trend)
+	}
+	if len(report.Openings) != 1 || report.Openings[0].Eco != "C20" || report.Openings[0].Score != 0.5 {
+		t.Errorf("openings = %v", report.Openings)
+	}
+	if len(report.Phases) != 3 || report.Phases[0].Moves != 4 {
+		t.Errorf("phases = %v", report.Phases)
+	}
+}
+
+func TestAggregateAnalyses_UnfinishedGames(t *testing.T) {
+	s := NewServer(nil, nil, zap.NewNop(), 0)
+
+	report, err := s.AggregateAnalyses(context.Background(), &pb.AggregateAnalysesRequest{
+		Player: "alice",
+		Games: []*pb.AnalyzedGame{
+			{Analysis: exportAnalysis(), WhitePlayer: "Alice", BlackPlayer: "Bob", Result: "1/2-1/2"},
+			{Analysis: exportAnalysis(), WhitePlayer: "Alice", BlackPlayer: "Bob", Result: "*"},
+			{Analysis: exportAnalysis(), WhitePlayer: "Alice", BlackPlayer: "Bob"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Games != 3 || report.UnknownResults != 2 || report.Draws != 1 || report.Openings[0].Score != 0.5 {
+		t.Errorf("games %d, unknown %d, draws %d, score %v; want 3, 2, 1, 0.5",
+			report.Games, report.UnknownResults, report.Draws, report.Openings[0].Score)
+	}
+}
+
+func TestAggregateAnalyses_Empty(t *testing.T) {
+	s := NewServer(nil, nil, zap.NewNop(), 0)
+
+	report, err := s.AggregateAnalyses(context.Background(), &pb.AggregateAnalysesRequest{Player: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Games != 0 || len(report.AccuracyBuckets) != 10 {
+		t.Errorf("report = %v", report)
+	}
+}
+
+func TestAggregateAnalyses_Errors(t *testing.T) {
+	s := NewServer(nil, nil, zap.NewNop(), 0)
+
+	tests := []struct {
+		name string
+		req  *pb.AggregateAnalysesRequest
+		code codes.Code
+	}{
+		{"missing player", &pb.AggregateAnalysesRequest{}, codes.InvalidArgument},
+		{"missing analysis", &pb.AggregateAnalysesRequest{Player: "alice", Games: []*pb.AnalyzedGame{{Result: "1-0"}}}, codes.InvalidArgument},
+		{"malformed result", &pb.AggregateAnalysesRequest{Player: "alice", Games: []*pb.AnalyzedGame{{Analysis: exportAnalysis(), Result: "1-1"}}}, codes.InvalidArgument},
+		{"game IDs", &pb.AggregateAnalysesRequest{Player: "alice", GameIds: []string{"g1"}}, codes.FailedPrecondition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.AggregateAnalyses(context.Background(), tt.req)
+			if status.Code(err) != tt.code {
+				t.Errorf("code = %v, want %v (err %v)", status.Code(err), tt.code, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DiffAnalyses lists the moves whose judgment changed between two analyses
+// of the same game
+func (s *Server) DiffAnalyses(ctx context.Context, req *pb.DiffAnalysesRequest) (*pb.AnalysisDiff, error) {
+	s.logger.Info("DiffAnalyses request",
+		zap.String("gameId", req.GetAnalysisA().GetGameId()),
+		zap.Int32("depthA", req.GetAnalysisA().GetDepth()),
+		zap.Int32("depthB", req.GetAnalysisB().GetDepth()))
+
+	if req.JobIdA != "" || req.JobIdB != "" {
+		return nil, status.Error(codes.FailedPrecondition, "job IDs need a job store, which this service doesn't have; send the analyses")
+	}
+	if req.AnalysisA == nil || req.AnalysisB == nil {
+		return nil, status.Error(codes.InvalidArgument, "analysis_a and analysis_b are required")
+	}
+	if req.CpLossThreshold < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "cp_loss_threshold=%d must not be negative", req.CpLossThreshold)
+	}
+
+	diff, err := analyzer.DiffAnalyses(toGameAnalysis(req.AnalysisA), toGameAnalysis(req.AnalysisB), int(req.CpLossThreshold), req.AllowConfigMismatch)
+	if err != nil {
+		return nil, toStatus(err, "analyses can't be compared")
+	}
+	return convertAnalysisDiff(&diff), nil
+}
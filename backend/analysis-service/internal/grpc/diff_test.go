@@ -0,0 +1,105 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// depthAnalysis is perspectiveAnalysis as analyzed at depth
+func depthAnalysis(depth int) *analyzer.GameAnalysis {
+	analysis := perspectiveAnalysis()
+	analysis.Depth = depth
+	for i := range analysis.Moves {
+		move := &analysis.Moves[i]
+		move.FENBefore = "fen" + string(rune('0'+i))
+		move.PlayedMoveUCI = "move" + string(rune('0'+i))
+		move.Classification = analyzer.ClassGood
+	}
+	return analysis
+}
+
+func TestDiffAnalyses(t *testing.T) {
+	s := NewServer(nil, nil, zap.NewNop(), 0)
+
+	deep := depthAnalysis(24)
+	deep.Moves[1].Classification = analyzer.ClassBlunder
+	deep.BlackMetrics.Blunders = 1
+
+	// The perspective of each analysis is undone before comparing
+	diff, err := s.DiffAnalyses(context.Background(), &pb.DiffAnalysesRequest{
+		AnalysisA: convertGameAnalysis(depthAnalysis(14), pb.EvalPerspective_WHITE),
+		AnalysisB: convertGameAnalysis(deep, pb.EvalPerspective_SIDE_TO_MOVE),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.DepthA != 14 || diff.DepthB != 24 || diff.ComparedMoves != 3 || len(diff.Moves) != 1 {
+		t.Fatalf("unexpected diff: %+v", diff)
+	}
+	if m := diff.Moves[0]; m.Ply != 1 || m.ClassificationB != pb.MoveClassification_BLUNDER {
+		t.Errorf("move diff = %+v", m)
+	}
+	if diff.BlackDelta.GetBlunders() != 1 || diff.WhiteDelta.GetBlunders() != 0 {
+		t.Errorf("deltas = %+v / %+v", diff.WhiteDelta, diff.BlackDelta)
+	}
+
+	// Another game is rejected naming the ply
+	other := depthAnalysis(24)
+	other.Moves[2].FENBefore = "elsewhere"
+	_, err = s.DiffAnalyses(context.Background(), &pb.DiffAnalysesRequest{
+		AnalysisA: convertGameAnalysis(depthAnalysis(14), pb.EvalPerspective_SIDE_TO_MOVE),
+		AnalysisB: convertGameAnalysis(other, pb.EvalPerspective_SIDE_TO_MOVE),
+	})
+	if status.Code(err) != codes.FailedPrecondition || !strings.Contains(status.Convert(err).Message(), "ply 2") {
+		t.Errorf("other game: err = %v, want FailedPrecondition naming ply 2", err)
+	}
+}
+
+func TestDiffAnalyses_ConfigMismatch(t *testing.T) {
+	s := NewServer(nil, nil, zap.NewNop(), 0)
+	quick, deep := depthAnalysis(14), depthAnalysis(24)
+	quick.Config = &analyzer.AnalysisConfigSnapshot{Threads: 1, HashMB: 256, MultiPV: 1, Depth: 14, Deterministic: true}
+	deep.Config = &analyzer.AnalysisConfigSnapshot{Threads: 8, HashMB: 256, MultiPV: 1, Depth: 24}
+	req := &pb.DiffAnalysesRequest{
+		AnalysisA: convertGameAnalysis(quick, pb.EvalPerspective_SIDE_TO_MOVE),
+		AnalysisB: convertGameAnalysis(deep, pb.EvalPerspective_SIDE_TO_MOVE),
+	}
+
+	_, err := s.DiffAnalyses(context.Background(), req)
+	if status.Code(err) != codes.FailedPrecondition || !strings.Contains(status.Convert(err).Message(), "threads 1 vs 8") {
+		t.Errorf("err = %v, want FailedPrecondition naming the threads", err)
+	}
+	req.AllowConfigMismatch = true
+	if _, err := s.DiffAnalyses(context.Background(), req); err != nil {
+		t.Errorf("with allow_config_mismatch: %v", err)
+	}
+}
+
+func TestDiffAnalyses_Errors(t *testing.T) {
+	s := NewServer(nil, nil, zap.NewNop(), 0)
+	analysis := convertGameAnalysis(depthAnalysis(14), pb.EvalPerspective_SIDE_TO_MOVE)
+
+	tests := []struct {
+		name string
+		req  *pb.DiffAnalysesRequest
+		code codes.Code
+	}{
+		{"missing analysis", &pb.DiffAnalysesRequest{AnalysisA: analysis}, codes.InvalidArgument},
+		{"negative threshold", &pb.DiffAnalysesRequest{AnalysisA: analysis, AnalysisB: analysis, CpLossThreshold: -1}, codes.InvalidArgument},
+		{"job IDs", &pb.DiffAnalysesRequest{JobIdA: "1", JobIdB: "2"}, codes.FailedPrecondition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.DiffAnalyses(context.Background(), tt.req); status.Code(err) != tt.code {
+				t.Errorf("code = %v, want %v", status.Code(err), tt.code)
+			}
+		})
+	}
+}
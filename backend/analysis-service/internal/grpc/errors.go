@@ -0,0 +1,82 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain identifies this service in ErrorInfo details
+const errorDomain = "analysis.eloinsight"
+
+// errorClass maps an analyzer/pool error class to a gRPC code and reason
+type errorClass struct {
+	target error
+	code   codes.Code
+	reason string
+}
+
+// errorClasses is checked in order; the first match wins
+var errorClasses = []errorClass{
+	{analyzer.ErrInvalidPGN, codes.InvalidArgument, "INVALID_PGN"},
+	{analyzer.ErrEmptyGame, codes.InvalidArgument, "EMPTY_GAME"},
+	{analyzer.ErrGameTooLong, codes.InvalidArgument, "GAME_TOO_LONG"},
+	{analyzer.ErrUnsupportedVariant, codes.InvalidArgument, "UNSUPPORTED_VARIANT"},
+	{analyzer.ErrInvalidFEN, codes.InvalidArgument, "INVALID_FEN"},
+	{analyzer.ErrUnknownThresholdProfile, codes.InvalidArgument, "UNKNOWN_THRESHOLD_PROFILE"},
+	{analyzer.ErrUnknownEngineProfile, codes.InvalidArgument, "UNKNOWN_ENGINE_PROFILE"},
+	{analyzer.ErrUnknownAccuracyModel, codes.InvalidArgument, "UNKNOWN_ACCURACY_MODEL"},
+	{analyzer.ErrPrefixMismatch, codes.InvalidArgument, "PREFIX_MISMATCH"},
+	{analyzer.ErrNotCached, codes.NotFound, "NOT_CACHED"},
+	{analyzer.ErrAnalysesMismatch, codes.FailedPrecondition, "ANALYSES_MISMATCH"},
+	{analyzer.ErrConfigMismatch, codes.FailedPrecondition, "CONFIG_MISMATCH"},
+	{analyzer.ErrChecksumMismatch, codes.DataLoss, "CHECKSUM_MISMATCH"},
+	{pool.ErrPoolExhausted, codes.ResourceExhausted, "POOL_EXHAUSTED"},
+	{pool.ErrPoolClosed, codes.Unavailable, "POOL_CLOSED"},
+	{analyzer.ErrTimeout, codes.DeadlineExceeded, "TIMEOUT"},
+	{context.DeadlineExceeded, codes.DeadlineExceeded, "TIMEOUT"},
+	{context.Canceled, codes.Canceled, "CANCELED"},
+	{analyzer.ErrEngineFailure, codes.Internal, "ENGINE_FAILURE"},
+}
+
+// toStatus converts an analyzer error into a gRPC status error.
+// The code tells clients whether to fix the request, retry later or alert;
+// an ErrorInfo detail carries the reason and the underlying message, and
+// for a PGN that didn't parse the class of the input as pgn_input.
+func toStatus(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	code, reason := codes.Internal, "INTERNAL"
+	for _, class := range errorClasses {
+		if errors.Is(err, class.target) {
+			code, reason = class.code, class.reason
+			break
+		}
+	}
+
+	metadata := map[string]string{"message": err.Error()}
+	var parseErr *analyzer.PGNParseError
+	if errors.As(err, &parseErr) {
+		metadata["pgn_input"] = parseErr.Input.String()
+	}
+	st := status.Newf(code, "%s: %v", msg, err)
+	withDetails, detailErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   errorDomain,
+		Metadata: metadata,
+	})
+	if detailErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
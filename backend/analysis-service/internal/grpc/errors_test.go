@@ -0,0 +1,130 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus_Codes(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{"invalid PGN", fmt.Errorf("%w: unexpected token", analyzer.ErrInvalidPGN), codes.InvalidArgument, "INVALID_PGN"},
+		{"empty game", analyzer.ErrEmptyGame, codes.InvalidArgument, "EMPTY_GAME"},
+		{"variant", &analyzer.VariantError{Variant: "Atomic"}, codes.InvalidArgument, "UNSUPPORTED_VARIANT"},
+		{"invalid FEN", fmt.Errorf("%w: too few parts", analyzer.ErrInvalidFEN), codes.InvalidArgument, "INVALID_FEN"},
+		{"unknown profile", fmt.Errorf("%w: \"expert\"", analyzer.ErrUnknownThresholdProfile), codes.InvalidArgument, "UNKNOWN_THRESHOLD_PROFILE"},
+		{"unknown accuracy model", fmt.Errorf("%w: \"chesscom\"", analyzer.ErrUnknownAccuracyModel), codes.InvalidArgument, "UNKNOWN_ACCURACY_MODEL"},
+		{"not cached", fmt.Errorf("%w: 1 PV at depth 20", analyzer.ErrNotCached), codes.NotFound, "NOT_CACHED"},
+		{"pool exhausted", fmt.Errorf("failed to get engine: %w", fmt.Errorf("%w: deadline", pool.ErrPoolExhausted)), codes.ResourceExhausted, "POOL_EXHAUSTED"},
+		{"pool closed", fmt.Errorf("failed to get engine: %w", pool.ErrPoolClosed), codes.Unavailable, "POOL_CLOSED"},
+		{"engine failure", fmt.Errorf("%w: broken pipe", analyzer.ErrEngineFailure), codes.Internal, "ENGINE_FAILURE"},
+		{"analysis timeout", fmt.Errorf("%w: context deadline exceeded", analyzer.ErrTimeout), codes.DeadlineExceeded, "TIMEOUT"},
+		{"context deadline", context.DeadlineExceeded, codes.DeadlineExceeded, "TIMEOUT"},
+		{"context canceled", context.Canceled, codes.Canceled, "CANCELED"},
+		{"unclassified", errors.New("boom"), codes.Internal, "INTERNAL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(toStatus(tt.err, "analysis failed"))
+			if st.Code() != tt.code {
+				t.Errorf("code = %v, want %v", st.Code(), tt.code)
+			}
+
+			var info *errdetails.ErrorInfo
+			for _, d := range st.Details() {
+				if ei, ok := d.(*errdetails.ErrorInfo); ok {
+					info = ei
+				}
+			}
+			if info == nil {
+				t.Fatal("missing ErrorInfo detail")
+			}
+			if info.Reason != tt.reason {
+				t.Errorf("reason = %q, want %q", info.Reason, tt.reason)
+			}
+			if info.Metadata["message"] != tt.err.Error() {
+				t.Errorf("message = %q, want %q", info.Metadata["message"], tt.err.Error())
+			}
+		})
+	}
+}
+
+func TestToStatus_PGNInput(t *testing.T) {
+	_, err := analyzer.ParsePGN("1.e4 e5 2.Ke3")
+	st := status.Convert(toStatus(err, "failed to parse PGN"))
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			if info.Reason != "INVALID_PGN" || info.Metadata["pgn_input"] != "numbered" {
+				t.Errorf("reason %q, pgn_input %q; want INVALID_PGN and numbered", info.Reason, info.Metadata["pgn_input"])
+			}
+			return
+		}
+	}
+	t.Fatal("missing ErrorInfo detail")
+}
+
+func TestToStatus_PassesThroughStatusErrors(t *testing.T) {
+	in := status.Error(codes.FailedPrecondition, "already a status")
+	if got := toStatus(in, "ignored"); got != in {
+		t.Errorf("toStatus() = %v, want the original status error", got)
+	}
+	if toStatus(nil, "ignored") != nil {
+		t.Error("toStatus(nil) should be nil")
+	}
+}
+
+func newTestServer() *Server {
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	return NewServer(a, nil, zap.NewNop(), 0)
+}
+
+func TestHandlers_InvalidInputIsInvalidArgument(t *testing.T) {
+	s := newTestServer()
+	ctx := context.Background()
+
+	_, err := s.AnalyzePosition(ctx, &pb.AnalyzePositionRequest{Fen: "not a fen"})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("AnalyzePosition code = %v, want InvalidArgument", code)
+	}
+
+	_, err = s.GetBestMoves(ctx, &pb.GetBestMovesRequest{Fen: "8/8/8 w"})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("GetBestMoves code = %v, want InvalidArgument", code)
+	}
+
+	_, err = s.AnalyzeGame(ctx, &pb.AnalyzeGameRequest{GameId: "bad", Pgn: "1. e4 e5 2. Qxf7 *"})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("AnalyzeGame code = %v, want InvalidArgument", code)
+	}
+
+	_, err = s.AnalyzeGame(ctx, &pb.AnalyzeGameRequest{GameId: "g", Pgn: "1. e4 e5 *", ThresholdProfile: "expert"})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("AnalyzeGame with unknown profile code = %v, want InvalidArgument", code)
+	}
+}
+
+// errorReason returns the ErrorInfo reason of a status error, "" without one
+func errorReason(err error) string {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
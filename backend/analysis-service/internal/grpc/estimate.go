@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// convertGameEstimate converts a game analysis's estimate to proto
+func convertGameEstimate(e analyzer.GameEstimate) *pb.GameEstimate {
+	return &pb.GameEstimate{
+		Positions:        int32(e.Positions),
+		CacheHits:        int32(e.CacheHits),
+		Searches:         int32(e.Searches),
+		Searched:         int32(e.Searched),
+		EnginesAvailable: int32(e.EnginesAvailable),
+		PoolSize:         int32(e.PoolSize),
+		Workers:          int32(e.Workers),
+		ColdStart:        e.ColdStart,
+		EtaMs:            e.ETA.Milliseconds(),
+		EtaKnown:         e.ETAKnown,
+	}
+}
+
+// estimateMetadata is a unary game analysis's estimate as it started, in
+// the response header: x-analysis-positions, x-analysis-cache-hits,
+// x-analysis-searches, x-analysis-engines-available, x-analysis-pool-size,
+// x-analysis-cold-start and x-analysis-eta-ms, the last left out when the
+// ETA wasn't known
+func estimateMetadata(e analyzer.GameEstimate) metadata.MD {
+	md := metadata.Pairs(
+		"x-analysis-positions", strconv.Itoa(e.Positions),
+		"x-analysis-cache-hits", strconv.Itoa(e.CacheHits),
+		"x-analysis-searches", strconv.Itoa(e.Searches),
+		"x-analysis-engines-available", strconv.Itoa(e.EnginesAvailable),
+		"x-analysis-pool-size", strconv.Itoa(e.PoolSize),
+		"x-analysis-cold-start", strconv.FormatBool(e.ColdStart),
+	)
+	if e.ETAKnown {
+		md.Set("x-analysis-eta-ms", strconv.FormatInt(e.ETA.Milliseconds(), 10))
+	}
+	return md
+}
+
+// setEstimateHeader sends estimate, when there is one, as the response
+// header of a unary call
+func setEstimateHeader(ctx context.Context, estimate *analyzer.GameEstimate) {
+	if estimate != nil {
+		// Fails only outside a call or once the header is sent; the
+		// estimate is informational either way
+		_ = grpc.SetHeader(ctx, estimateMetadata(*estimate))
+	}
+}
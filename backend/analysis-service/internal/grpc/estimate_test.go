@@ -0,0 +1,85 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/internal/quota"
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const estimatePGN = "1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 Nf6 5. O-O Be7 *"
+
+func TestAnalyzeGameStream_Estimate(t *testing.T) {
+	binary := fakeEngine.Binary(t)
+	p, err := pool.NewPool(1, engine.Config{BinaryPath: binary, Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	s := NewServer(analyzer.NewAnalyzer(p, zap.NewNop(), 1, 20, 30, time.Minute), p, zap.NewNop(), 0)
+
+	stream := &recordingStream{}
+	if err := s.AnalyzeGameStream(&pb.AnalyzeGameRequest{GameId: "g1", Pgn: estimatePGN}, stream); err != nil {
+		t.Fatal(err)
+	}
+	msgs := stream.messages()
+
+	// The first message is the estimate, before any move
+	first := msgs[0].Estimate
+	if first == nil || msgs[0].CurrentMove != 0 {
+		t.Fatalf("first message = %v, want an estimate before any move", msgs[0])
+	}
+	if first.Positions != 11 || first.Searches != 11 || first.CacheHits != 0 || first.PoolSize != 1 || !first.ColdStart || first.EtaKnown {
+		t.Errorf("first estimate = %v, want 11 positions to search cold on a pool of 1", first)
+	}
+
+	// Later messages carry it updated as the searches finish
+	searched := int32(0)
+	for _, msg := range msgs[1:] {
+		if msg.Estimate == nil || msg.Estimate.Searched < searched {
+			t.Fatalf("message %v: estimate missing or going back", msg)
+		}
+		searched = msg.Estimate.Searched
+	}
+	if last := msgs[len(msgs)-1].Estimate; last.Searched != 11 || !last.EtaKnown || last.EtaMs != 0 {
+		t.Errorf("final estimate = %v, want all 11 searched", last)
+	}
+}
+
+func TestAnalyzeGame_EstimateHeader(t *testing.T) {
+	client := newQuotaClient(t, quota.NewMeter(quota.NewMemoryStore(), time.Hour, zap.NewNop()))
+	req := &pb.AnalyzeGameRequest{GameId: "g1", Pgn: estimatePGN}
+
+	var header metadata.MD
+	if _, err := client.AnalyzeGame(context.Background(), req, grpc.Header(&header)); err != nil {
+		t.Fatal(err)
+	}
+	get := func(key string) string {
+		if values := header.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	if get("x-analysis-positions") != "11" || get("x-analysis-searches") != "11" || get("x-analysis-cold-start") != "true" {
+		t.Errorf("cold header = %v", header)
+	}
+	if get("x-analysis-eta-ms") != "" {
+		t.Errorf("cold start: ETA %q, want none", get("x-analysis-eta-ms"))
+	}
+
+	// The same game again is all in the cache
+	if _, err := client.AnalyzeGame(context.Background(), req, grpc.Header(&header)); err != nil {
+		t.Fatal(err)
+	}
+	if get("x-analysis-cache-hits") != "11" || get("x-analysis-searches") != "0" || get("x-analysis-cold-start") != "false" || get("x-analysis-eta-ms") != "0" {
+		t.Errorf("cached header = %v", header)
+	}
+}
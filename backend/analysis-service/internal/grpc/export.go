@@ -0,0 +1,334 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ExportGameAnalysis renders a game analysis as an annotated PGN, so
+// clients don't have to merge the analysis into the PGN themselves, or as
+// the versioned JSON of analyzer.GameAnalysis for storage
+func (s *Server) ExportGameAnalysis(ctx context.Context, req *pb.ExportGameAnalysisRequest) (*pb.ExportGameAnalysisResponse, error) {
+	if req.Analysis == nil {
+		return nil, status.Error(codes.InvalidArgument, "analysis is required")
+	}
+
+	switch req.Format {
+	case pb.ExportFormat_EXPORT_FORMAT_UNSPECIFIED, pb.ExportFormat_ANNOTATED_PGN:
+		if req.Pgn == "" {
+			return nil, status.Error(codes.InvalidArgument, "PGN is required for an annotated PGN export")
+		}
+		pgn, err := analyzer.ExportAnnotatedPGN(toGameAnalysis(req.Analysis), req.Pgn)
+		if err != nil {
+			s.logger.Debug("PGN export failed", zap.String("gameId", req.Analysis.GameId), zap.Error(err))
+			return nil, toStatus(err, "PGN export failed")
+		}
+		return &pb.ExportGameAnalysisResponse{Content: pgn, ContentType: "application/x-chess-pgn"}, nil
+
+	case pb.ExportFormat_JSON:
+		data, err := json.Marshal(toGameAnalysis(req.Analysis))
+		if err != nil {
+			return nil, toStatus(err, "JSON export failed")
+		}
+		return &pb.ExportGameAnalysisResponse{Content: string(data), ContentType: "application/json"}, nil
+
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown export format %v", req.Format)
+	}
+}
+
+// toGameAnalysis converts a proto game analysis back to the analyzer type,
+// with evaluations from the side to move whatever its eval_perspective
+func toGameAnalysis(pbAnalysis *pb.GameAnalysis) *analyzer.GameAnalysis {
+	analysis := &analyzer.GameAnalysis{
+		GameID:                 pbAnalysis.GameId,
+		Info:                   toGameInfo(pbAnalysis.GameInfo),
+		TotalTimeMs:            pbAnalysis.TotalTimeMs,
+		EngineVersion:          pbAnalysis.EngineVersion,
+		Depth:                  int(pbAnalysis.Depth),
+		TimedOut:               pbAnalysis.TimedOut,
+		TotalMoves:             int(pbAnalysis.TotalMoves),
+		ThresholdProfile:       pbAnalysis.ThresholdProfile,
+		EngineProfile:          pbAnalysis.EngineProfile,
+		Truncated:              pbAnalysis.Truncated,
+		TruncatedAtPly:         int(pbAnalysis.TruncatedAtPly),
+		TruncationError:        pbAnalysis.TruncationError,
+		Termination:            toTermination(pbAnalysis.Termination),
+		WinnerColor:            pbAnalysis.WinnerColor,
+		ResultAgainstRunOfPlay: pbAnalysis.ResultAgainstRunOfPlay,
+		WhiteMetrics:           toGameMetrics(pbAnalysis.WhiteMetrics),
+		BlackMetrics:           toGameMetrics(pbAnalysis.BlackMetrics),
+		Moves:                  make([]analyzer.MoveAnalysis, 0, len(pbAnalysis.Moves)),
+		WhiteTime:              toTimeManagement(pbAnalysis.WhiteTime),
+		BlackTime:              toTimeManagement(pbAnalysis.BlackTime),
+		StartedAt:              pbAnalysis.StartedAtUnixMs,
+		CompletedAt:            pbAnalysis.CompletedAtUnixMs,
+		CacheCoverage:          float64(pbAnalysis.CacheCoverage),
+		Diagnostics:            toDiagnostics(pbAnalysis.Diagnostics),
+		TimeBudgetMs:           pbAnalysis.TimeBudgetMs,
+		BudgetUsedMs:           pbAnalysis.BudgetUsedMs,
+		BudgetUtilization:      float64(pbAnalysis.BudgetUtilization),
+		Config:                 toConfigSnapshot(pbAnalysis.Config),
+		SourceCounts:           toSourceCounts(pbAnalysis.SourceCounts),
+		Summary:                pbAnalysis.Summary,
+	}
+	if t := pbAnalysis.Thresholds; t != nil {
+		analysis.Thresholds = evaluation.Thresholds{
+			Best:        int(t.Best),
+			Excellent:   int(t.Excellent),
+			Good:        int(t.Good),
+			Inaccuracy:  int(t.Inaccuracy),
+			Mistake:     int(t.Mistake),
+			GarbageWin:  int(t.GarbageWin),
+			GarbageLoss: int(t.GarbageLoss),
+		}
+	}
+
+	for _, move := range pbAnalysis.Moves {
+		analysis.Moves = append(analysis.Moves, analyzer.MoveAnalysis{
+			MoveNumber:     int(move.MoveNumber),
+			Ply:            int(move.Ply),
+			Color:          move.Color,
+			PlayedMove:     move.PlayedMove,
+			PlayedMoveUCI:  move.PlayedMoveUci,
+			BestMove:       move.BestMove,
+			BestMoveUCI:    move.BestMoveUci,
+			FENBefore:      move.FenBefore,
+			FENAfter:       move.FenAfter,
+			EvalBefore:     toEvaluation(move.EvalBefore),
+			EvalAfter:      toEvaluation(move.EvalAfter),
+			CentipawnLoss:  int(move.CentipawnLoss),
+			Classification: toClassification(move.Classification),
+			PV:             move.Pv,
+			AchievedDepth:  int(move.Depth),
+			MoveAccuracy:   float64(move.MoveAccuracy),
+			Forced:         move.Forced,
+			RequestedDepth: int(move.RequestedDepth),
+			FromCache:      move.FromCache,
+			GarbageTime:    move.GarbageTime,
+
+			ExpectedPointsLoss: float64(move.ExpectedPointsLoss),
+
+			MissedRepetition:  move.MissedRepetition,
+			AllowedRepetition: move.AllowedRepetition,
+
+			MaterialBefore:     toMaterial(move.MaterialBefore),
+			MaterialAfter:      toMaterial(move.MaterialAfter),
+			MaterialSacrificed: int(move.MaterialSacrificed),
+			Explanation:        move.Explanation,
+
+			AnalyzedAt:   move.AnalyzedAtUnixMs,
+			EngineTimeMs: move.EngineTimeMs,
+			QueueTimeMs:  move.QueueTimeMs,
+			SearchTimeMs: move.SearchTimeMs,
+			MovetimeMs:   move.MovetimeMs,
+		})
+
+		// Back to the analyzer's side-to-move evaluations
+		last := &analysis.Moves[len(analysis.Moves)-1]
+		last.EvalBefore, last.EvalAfter = perspectiveEvaluations(last, pbAnalysis.EvalPerspective)
+		last.Source = toSource(move.Source, last)
+	}
+
+	return analysis
+}
+
+// toGameMetrics converts proto game metrics back to the analyzer type
+func toGameMetrics(metrics *pb.GameMetrics) analyzer.GameMetrics {
+	if metrics == nil {
+		return analyzer.GameMetrics{}
+	}
+	return analyzer.GameMetrics{
+		Accuracy:          float64(metrics.Accuracy),
+		ACPL:              float64(metrics.Acpl),
+		Blunders:          int(metrics.Blunders),
+		Mistakes:          int(metrics.Mistakes),
+		Inaccuracies:      int(metrics.Inaccuracies),
+		GoodMoves:         int(metrics.GoodMoves),
+		ExcellentMoves:    int(metrics.ExcellentMoves),
+		BestMoves:         int(metrics.BestMoves),
+		BrilliantMoves:    int(metrics.BrilliantMoves),
+		BookMoves:         int(metrics.BookMoves),
+		TotalMoves:        int(metrics.TotalMoves),
+		PerformanceRating: int(metrics.PerformanceRating),
+		GarbageTimeMoves:  int(metrics.GarbageTimeMoves),
+		AccuracyStddev:    float64(metrics.AccuracyStddev),
+		ACPLStddev:        float64(metrics.AcplStddev),
+		AccuracyModel:     toAccuracyModel(metrics.AccuracyModel),
+		Resilience:        toResilience(metrics.Resilience),
+
+		ExpectedPointsLost: float64(metrics.ExpectedPointsLost),
+	}
+}
+
+// toResilience converts proto resilience back, nil when unset
+func toResilience(r *pb.Resilience) *evaluation.Resilience {
+	if r == nil {
+		return nil
+	}
+	return &evaluation.Resilience{
+		Swindles:       int(r.Swindles),
+		BotchedWins:    int(r.BotchedWins),
+		Gifts:          int(r.Gifts),
+		GiftConversion: float64(r.GiftConversion),
+	}
+}
+
+// toTimeManagement converts proto time management back, nil when unset
+func toTimeManagement(tm *pb.TimeManagement) *analyzer.TimeManagement {
+	if tm == nil {
+		return nil
+	}
+	result := &analyzer.TimeManagement{
+		TimeClass:           tm.TimeClass,
+		ScrambleThresholdMs: tm.ScrambleThresholdMs,
+		ScrambleStartPly:    int(tm.ScrambleStartPly),
+		ScrambleMoves:       int(tm.ScrambleMoves),
+		ScrambleErrorRate:   float64(tm.ScrambleErrorRate),
+		OtherErrorRate:      float64(tm.OtherErrorRate),
+		ThinkingTimeMs:      tm.ThinkingTimeMs,
+		CPLossPerSecond:     float64(tm.CpLossPerSecond),
+	}
+	for _, ply := range tm.ScrambleBlunders {
+		result.ScrambleBlunders = append(result.ScrambleBlunders, int(ply))
+	}
+	return result
+}
+
+// toMaterial converts proto material counts back, zero when unset
+func toMaterial(m *pb.Material) analyzer.Material {
+	return analyzer.Material{White: int(m.GetWhite()), Black: int(m.GetBlack())}
+}
+
+// toEvaluation converts a proto evaluation back to the engine type
+func toEvaluation(pbEval *pb.Evaluation) engine.Evaluation {
+	var eval engine.Evaluation
+	switch score := pbEval.GetScore().(type) {
+	case *pb.Evaluation_MateIn:
+		mateIn := int(score.MateIn)
+		eval.IsMate = true
+		eval.MateIn = &mateIn
+	case *pb.Evaluation_Centipawns:
+		eval.Centipawns = int(score.Centipawns)
+	}
+	return eval
+}
+
+// toClassification converts a proto classification back to the analyzer one
+func toClassification(class pb.MoveClassification) analyzer.MoveClassification {
+	switch class {
+	case pb.MoveClassification_BRILLIANT:
+		return analyzer.ClassBrilliant
+	case pb.MoveClassification_GREAT:
+		return analyzer.ClassGreat
+	case pb.MoveClassification_BEST:
+		return analyzer.ClassBest
+	case pb.MoveClassification_EXCELLENT:
+		return analyzer.ClassExcellent
+	case pb.MoveClassification_GOOD:
+		return analyzer.ClassGood
+	case pb.MoveClassification_BOOK:
+		return analyzer.ClassBook
+	case pb.MoveClassification_INACCURACY:
+		return analyzer.ClassInaccuracy
+	case pb.MoveClassification_MISTAKE:
+		return analyzer.ClassMistake
+	case pb.MoveClassification_BLUNDER:
+		return analyzer.ClassBlunder
+	case pb.MoveClassification_MISSED_WIN:
+		return analyzer.ClassMissedWin
+	default:
+		return analyzer.ClassNormal
+	}
+}
+
+// toDiagnostics converts proto diagnostics back to the analyzer type
+func toDiagnostics(d *pb.AnalysisDiagnostics) analyzer.Diagnostics {
+	var result analyzer.Diagnostics
+	if d == nil {
+		return result
+	}
+	result.Retries = int(d.Retries)
+	if len(d.Failures) > 0 {
+		result.Failures = make(map[analyzer.FailureKind]int, len(d.Failures))
+		for kind, n := range d.Failures {
+			result.Failures[analyzer.FailureKind(kind)] = int(n)
+		}
+	}
+	for _, index := range d.FailedPositions {
+		result.FailedPositions = append(result.FailedPositions, int(index))
+	}
+	return result
+}
+
+// toSource converts a proto source back to the analyzer's; an unspecified
+// one is told from move's other fields, as for stored JSON
+func toSource(source pb.AnalysisSource, move *analyzer.MoveAnalysis) analyzer.AnalysisSource {
+	for s, p := range analysisSources {
+		if p == source {
+			return s
+		}
+	}
+	return analyzer.LegacySource(move)
+}
+
+// toTermination converts a proto termination back to the analyzer type
+func toTermination(termination pb.Termination) analyzer.Termination {
+	for t, p := range terminations {
+		if p == termination {
+			return t
+		}
+	}
+	return analyzer.TerminationUnknown
+}
+
+// toSourceCounts converts proto plies by source back to the analyzer type
+func toSourceCounts(counts map[string]int32) map[analyzer.AnalysisSource]int {
+	if len(counts) == 0 {
+		return nil
+	}
+	result := make(map[analyzer.AnalysisSource]int, len(counts))
+	for source, n := range counts {
+		result[analyzer.AnalysisSource(source)] = int(n)
+	}
+	return result
+}
+
+// toGameInfo converts proto PGN tags back to the analyzer type
+func toGameInfo(info *pb.GameInfo) analyzer.GameInfo {
+	return analyzer.GameInfo{
+		White:       info.GetWhite(),
+		Black:       info.GetBlack(),
+		WhiteElo:    int(info.GetWhiteElo()),
+		BlackElo:    int(info.GetBlackElo()),
+		Result:      info.GetResult(),
+		TimeControl: info.GetTimeControl(),
+		Date:        info.GetDate(),
+	}
+}
+
+// toConfigSnapshot converts proto engine settings back to the analyzer type
+func toConfigSnapshot(c *pb.AnalysisConfigSnapshot) *analyzer.AnalysisConfigSnapshot {
+	if c == nil {
+		return nil
+	}
+	return &analyzer.AnalysisConfigSnapshot{
+		Threads:         int(c.Threads),
+		HashMB:          int(c.HashMb),
+		MultiPV:         int(c.MultiPv),
+		Depth:           int(c.Depth),
+		NodesLimit:      c.NodesLimit,
+		MovetimeMs:      c.MovetimeMs,
+		NNUENet:         c.NnueNet,
+		CacheHitPercent: float64(c.CacheHitPercent),
+		Deterministic:   c.Deterministic,
+	}
+}
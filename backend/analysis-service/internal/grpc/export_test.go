@@ -0,0 +1,119 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const exportPGN = "[White \"Alice\"]\n[Black \"Bob\"]\n\n1. e4 e5 2. Qh5 Nc6 *"
+
+// exportAnalysis is an analysis of exportPGN as AnalyzeGame would return it
+func exportAnalysis() *pb.GameAnalysis {
+	return &pb.GameAnalysis{
+		GameId:        "g1",
+		EngineVersion: "Stockfish 17",
+		Depth:         18,
+		Moves: []*pb.MoveAnalysis{
+			{Ply: 0, Color: "white", PlayedMoveUci: "e2e4", EvalAfter: &pb.Evaluation{Score: &pb.Evaluation_Centipawns{Centipawns: -30}}},
+			{Ply: 1, Color: "black", PlayedMoveUci: "e7e5", EvalAfter: &pb.Evaluation{Score: &pb.Evaluation_Centipawns{Centipawns: 35}}},
+			{
+				Ply: 2, Color: "white", PlayedMoveUci: "d1h5", BestMove: "Nf3", BestMoveUci: "g1f3",
+				FenBefore:      "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+				EvalAfter:      &pb.Evaluation{Score: &pb.Evaluation_Centipawns{Centipawns: 20}},
+				Classification: pb.MoveClassification_INACCURACY,
+				Pv:             []string{"g1f3", "b8c6"},
+			},
+			{
+				Ply: 3, Color: "black", PlayedMoveUci: "b8c6",
+				EvalAfter:      &pb.Evaluation{Score: &pb.Evaluation_MateIn{MateIn: -4}, IsMate: true},
+				Classification: pb.MoveClassification_BEST,
+			},
+		},
+	}
+}
+
+func TestExportGameAnalysis_AnnotatedPGN(t *testing.T) {
+	s := NewServer(nil, nil, zap.NewNop(), 0)
+
+	resp, err := s.ExportGameAnalysis(context.Background(), &pb.ExportGameAnalysisRequest{
+		Analysis: exportAnalysis(),
+		Pgn:      exportPGN,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ContentType != "application/x-chess-pgn" {
+		t.Errorf("content type = %q", resp.ContentType)
+	}
+	flat := strings.Join(strings.Fields(resp.Content), " ")
+	for _, want := range []string{
+		`[White "Alice"]`,
+		`[Annotator "EloInsight (Stockfish 17, depth 18)"]`,
+		"1. e4 {[%eval 0.30]} 1... e5 {[%eval 0.35]} 2. Qh5 $6 {[%eval -0.20]} 2... Nc6 {[%eval #-4]} *",
+	} {
+		if !strings.Contains(flat, want) {
+			t.Errorf("export is missing %q:\n%s", want, resp.Content)
+		}
+	}
+}
+
+func TestExportGameAnalysis_JSON(t *testing.T) {
+	s := NewServer(nil, nil, zap.NewNop(), 0)
+	analysis := exportAnalysis()
+
+	resp, err := s.ExportGameAnalysis(context.Background(), &pb.ExportGameAnalysisRequest{
+		Analysis: analysis,
+		Format:   pb.ExportFormat_JSON,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`"schema_version":1`, `"eval_after":{"mate":-4}`, `"eval_after":{"cp":20}`} {
+		if !strings.Contains(resp.Content, want) {
+			t.Errorf("export is missing %s:\n%s", want, resp.Content)
+		}
+	}
+
+	var decoded analyzer.GameAnalysis
+	if err := json.Unmarshal([]byte(resp.Content), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, toGameAnalysis(analysis)) {
+		t.Errorf("JSON export does not round-trip:\n%s", resp.Content)
+	}
+}
+
+func TestExportGameAnalysis_InvalidArgument(t *testing.T) {
+	s := NewServer(nil, nil, zap.NewNop(), 0)
+
+	mismatched := exportAnalysis()
+	mismatched.Moves[2].PlayedMoveUci = "g1f3"
+
+	tests := []struct {
+		name string
+		req  *pb.ExportGameAnalysisRequest
+	}{
+		{"missing analysis", &pb.ExportGameAnalysisRequest{Pgn: exportPGN}},
+		{"missing PGN", &pb.ExportGameAnalysisRequest{Analysis: exportAnalysis()}},
+		{"PGN does not match", &pb.ExportGameAnalysisRequest{Analysis: mismatched, Pgn: exportPGN}},
+		{"unknown format", &pb.ExportGameAnalysisRequest{Analysis: exportAnalysis(), Format: 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.ExportGameAnalysis(context.Background(), tt.req)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("code = %v, want InvalidArgument (err %v)", status.Code(err), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// HealthService is the service name probes can check the analysis service
+// under, besides the server-wide ""
+const HealthService = "analysis"
+
+// EngineSource reports how many engines can analyze, normally
+// (*pool.Pool)
+type EngineSource interface {
+	Engines() int
+
+	// Subscribe returns a channel receiving the count whenever it changes
+	Subscribe() <-chan int
+}
+
+// WatchHealth keeps the server-wide and HealthService statuses of hs in
+// step with src until ctx ends: NOT_SERVING once src has had no engines
+// for grace, SERVING again as soon as it has one. Both statuses are
+// expected to start out SERVING.
+func WatchHealth(ctx context.Context, hs *health.Server, src EngineSource, grace time.Duration, logger *zap.Logger) {
+	updates := src.Subscribe()
+	setStatus := func(status grpc_health_v1.HealthCheckResponse_ServingStatus) {
+		hs.SetServingStatus("", status)
+		hs.SetServingStatus(HealthService, status)
+	}
+
+	serving := true
+	var timer *time.Timer
+	var expired <-chan time.Time
+	update := func(engines int) {
+		if engines > 0 {
+			if timer != nil {
+				timer.Stop()
+				timer, expired = nil, nil
+			}
+			if !serving {
+				serving = true
+				setStatus(grpc_health_v1.HealthCheckResponse_SERVING)
+				logger.Info("Engines recovered, serving", zap.Int("engines", engines))
+			}
+			return
+		}
+		if serving && timer == nil {
+			timer = time.NewTimer(grace)
+			expired = timer.C
+		}
+	}
+
+	update(src.Engines())
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case engines := <-updates:
+			update(engines)
+		case <-expired:
+			timer, expired = nil, nil
+			serving = false
+			setStatus(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+			logger.Error("No engines left, not serving", zap.Duration("grace", grace))
+		}
+	}
+}
+
+// startupRetryDelay is the retry hint of calls turned away while the
+// engines start
+const startupRetryDelay = time.Second
+
+// ReadySource reports whether the engines have started, normally
+// (*pool.Pool)
+type ReadySource interface {
+	Ready() bool
+}
+
+// StartupInterceptors returns the unary and stream interceptors turning
+// away AnalysisService calls with Unavailable and a RetryInfo hint until
+// src is ready, so a server can listen while its engines start. Health,
+// admin and reflection calls always go through.
+func StartupInterceptors(src ReadySource) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	check := func(method string) error {
+		if src.Ready() || !strings.HasPrefix(method, "/"+pb.AnalysisService_ServiceDesc.ServiceName+"/") {
+			return nil
+		}
+		return startingStatus()
+	}
+
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := check(info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := check(info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+	return unary, stream
+}
+
+// startingStatus is the Unavailable error of calls made before the engines
+// started
+func startingStatus() error {
+	st := status.New(codes.Unavailable, "analysis engines are starting, retry shortly")
+	withDetails, err := st.WithDetails(
+		&errdetails.ErrorInfo{Reason: "ENGINES_STARTING", Domain: errorDomain},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(startupRetryDelay)},
+	)
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
@@ -0,0 +1,173 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeEngines is an EngineSource whose count the test sets
+type fakeEngines struct {
+	engines atomic.Int32
+	updates chan int
+}
+
+func newFakeEngines(n int) *fakeEngines {
+	f := &fakeEngines{updates: make(chan int, 1)}
+	f.engines.Store(int32(n))
+	return f
+}
+
+func (f *fakeEngines) Engines() int          { return int(f.engines.Load()) }
+func (f *fakeEngines) Subscribe() <-chan int { return f.updates }
+
+func (f *fakeEngines) set(n int) {
+	f.engines.Store(int32(n))
+	f.updates <- n
+}
+
+func TestWatchHealth(t *testing.T) {
+	hs := health.NewServer()
+	hs.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	hs.SetServingStatus(HealthService, grpc_health_v1.HealthCheckResponse_SERVING)
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, hs)
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	engines := newFakeEngines(2)
+	go WatchHealth(ctx, hs, engines, 100*time.Millisecond, zap.NewNop())
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := grpc_health_v1.NewHealthClient(conn)
+
+	watch, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{Service: HealthService})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func() grpc_health_v1.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := watch.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Status
+	}
+	if status := next(); status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("initial status = %v, want SERVING", status)
+	}
+
+	// A blip shorter than the grace period goes unnoticed
+	engines.set(0)
+	engines.set(1)
+	time.Sleep(200 * time.Millisecond)
+
+	engines.set(0)
+	start := time.Now()
+	if status := next(); status != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("status without engines = %v, want NOT_SERVING", status)
+	}
+	if waited := time.Since(start); waited < 100*time.Millisecond {
+		t.Errorf("NOT_SERVING after %v, before the grace period", waited)
+	}
+	resp, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil || resp.Status != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("server-wide status = %v, %v; want NOT_SERVING", resp.GetStatus(), err)
+	}
+
+	engines.set(1)
+	if status := next(); status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("status after recovery = %v, want SERVING", status)
+	}
+}
+
+// readyFlag is a ReadySource the test flips
+type readyFlag struct{ atomic.Bool }
+
+func (r *readyFlag) Ready() bool { return r.Load() }
+
+func TestStartupInterceptors(t *testing.T) {
+	var ready readyFlag
+	unary, stream := StartupInterceptors(&ready)
+	handled := 0
+	call := func(method string) error {
+		_, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(context.Context, interface{}) (interface{}, error) {
+				handled++
+				return nil, nil
+			})
+		return err
+	}
+	callStream := func(method string) error {
+		return stream(nil, nil, &grpc.StreamServerInfo{FullMethod: method},
+			func(interface{}, grpc.ServerStream) error {
+				handled++
+				return nil
+			})
+	}
+
+	for _, err := range []error{
+		call(pb.AnalysisService_AnalyzePosition_FullMethodName),
+		callStream(pb.AnalysisService_AnalyzeGameStream_FullMethodName),
+	} {
+		st := status.Convert(err)
+		if st.Code() != codes.Unavailable {
+			t.Fatalf("before ready: err = %v, want Unavailable", err)
+		}
+		var retry *errdetails.RetryInfo
+		for _, d := range st.Details() {
+			if r, ok := d.(*errdetails.RetryInfo); ok {
+				retry = r
+			}
+		}
+		if retry == nil || retry.RetryDelay.AsDuration() != startupRetryDelay {
+			t.Errorf("retry hint = %v, want %v", retry, startupRetryDelay)
+		}
+	}
+	if handled != 0 {
+		t.Fatalf("%d analysis calls handled before ready", handled)
+	}
+
+	// Health and admin calls don't need the engines
+	if err := call(grpc_health_v1.Health_Check_FullMethodName); err != nil {
+		t.Errorf("health check before ready: %v", err)
+	}
+	if err := call(pb.AdminService_SetLogLevel_FullMethodName); err != nil {
+		t.Errorf("admin call before ready: %v", err)
+	}
+
+	ready.Store(true)
+	if err := call(pb.AnalysisService_AnalyzePosition_FullMethodName); err != nil {
+		t.Errorf("unary call once ready: %v", err)
+	}
+	if err := callStream(pb.AnalysisService_AnalyzeGameStream_FullMethodName); err != nil {
+		t.Errorf("stream call once ready: %v", err)
+	}
+	if handled != 4 {
+		t.Errorf("%d calls handled, want 4", handled)
+	}
+}
@@ -0,0 +1,50 @@
+package grpc
+
+// depthStep is the depth added between AnalyzePositionStream updates
+const depthStep = 4
+
+// Limits are the defaults and bounds applied to the depth and number of
+// principal variations of every request, from the service configuration
+type Limits struct {
+	MinDepth     int // Shallower requests are raised to it
+	DefaultDepth int // Depth of requests that leave it unset
+	MaxDepth     int // Deeper requests are lowered to it
+
+	DefaultBestMoves int // Moves GetBestMoves returns when count is unset
+	MaxMultiPV       int // Most principal variations a request gets
+}
+
+// DefaultLimits are the limits of the default configuration
+func DefaultLimits() Limits {
+	return Limits{MinDepth: 10, DefaultDepth: 20, MaxDepth: 30, DefaultBestMoves: 3, MaxMultiPV: 10}
+}
+
+// depth returns the depth searched for a requested one: the default for
+// zero or less, otherwise limited to [MinDepth, MaxDepth]
+func (l Limits) depth(requested int32) int {
+	depth := int(requested)
+	if depth <= 0 {
+		depth = l.DefaultDepth
+	}
+	return max(l.MinDepth, min(l.MaxDepth, depth))
+}
+
+// multiPV returns the principal variations searched for a requested
+// number, def when it is zero or less, at most MaxMultiPV
+func (l Limits) multiPV(requested int32, def int) int {
+	multiPV := int(requested)
+	if multiPV <= 0 {
+		multiPV = def
+	}
+	return max(1, min(l.MaxMultiPV, multiPV))
+}
+
+// depthSteps returns the depths AnalyzePositionStream searches on the way
+// to target: MinDepth and every depthStep after it, then target itself
+func (l Limits) depthSteps(target int) []int {
+	var steps []int
+	for depth := l.MinDepth; depth < target; depth += depthStep {
+		steps = append(steps, depth)
+	}
+	return append(steps, target)
+}
@@ -0,0 +1,143 @@
+package grpc
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// fakeEngine answers every search at once, scoring 20 with e2e4 and
+// reporting 10ms
+var fakeEngine = enginetest.Engine{
+	Default:      enginetest.Eval{Centipawns: 20, BestMove: "e2e4"},
+	ReportedTime: 10 * time.Millisecond,
+}
+
+// positionStream captures messages sent on a position analysis stream
+type positionStream struct {
+	grpc.ServerStream
+	mu   sync.Mutex
+	sent []*pb.PositionAnalysis
+}
+
+func (p *positionStream) Send(msg *pb.PositionAnalysis) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent = append(p.sent, msg)
+	return nil
+}
+
+func (p *positionStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestLimits_NoHandlerSearchesDeeper(t *testing.T) {
+	fake := fakeEngine
+	fake.Log = filepath.Join(t.TempDir(), "uci")
+	p, err := pool.NewPool(1, engine.Config{BinaryPath: fake.Binary(t), Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	// The analyzer would allow depth 30; the server's limits are tighter
+	a := analyzer.NewAnalyzer(p, zap.NewNop(), 1, 20, 30, time.Minute)
+	s := NewServer(a, p, zap.NewNop(), 0)
+	s.SetLimits(Limits{MinDepth: 10, DefaultDepth: 12, MaxDepth: 16, DefaultBestMoves: 3, MaxMultiPV: 4})
+	ctx := context.Background()
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+
+	for _, depth := range []int32{0, 5, 30, 99} {
+		resp, err := s.AnalyzePosition(ctx, &pb.AnalyzePositionRequest{Fen: fen, Depth: depth, MultiPv: 8})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := s.limits.depth(depth)
+		if resp.TargetDepth != int32(want) || resp.MultiPv != 4 {
+			t.Errorf("AnalyzePosition(depth %d): target depth %d, multi-PV %d; want %d, 4", depth, resp.TargetDepth, resp.MultiPv, want)
+		}
+	}
+
+	stream := &positionStream{}
+	if err := s.AnalyzePositionStream(&pb.AnalyzePositionRequest{Fen: fen, Depth: 30}, stream); err != nil {
+		t.Fatal(err)
+	}
+	var steps []int32
+	for _, msg := range stream.sent {
+		steps = append(steps, msg.TargetDepth)
+	}
+	if want := []int32{10, 14, 16}; !reflect.DeepEqual(steps, want) {
+		t.Errorf("stream depths = %v, want %v", steps, want)
+	}
+
+	moves, err := s.GetBestMoves(ctx, &pb.GetBestMovesRequest{Fen: fen, Count: 9, Depth: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moves.TargetDepth != 16 || moves.Count != 4 {
+		t.Errorf("GetBestMoves: target depth %d, count %d; want 16, 4", moves.TargetDepth, moves.Count)
+	}
+	if moves, err := s.GetBestMoves(ctx, &pb.GetBestMovesRequest{Fen: fen}); err != nil || moves.Count != 3 || moves.TargetDepth != 12 {
+		t.Errorf("GetBestMoves defaults: %v, %v; want count 3 at depth 12", moves, err)
+	}
+
+	game, err := s.AnalyzeGame(ctx, &pb.AnalyzeGameRequest{Pgn: "1. e4 e5 2. Nf3 *", Depth: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Depth != 16 {
+		t.Errorf("AnalyzeGame depth = %d, want 16", game.Depth)
+	}
+	if err := s.AnalyzeGameStream(&pb.AnalyzeGameRequest{GameId: "g1", Pgn: "1. d4 d5 2. c4 *", Depth: 99}, &recordingStream{}); err != nil {
+		t.Fatal(err)
+	}
+
+	searches := 0
+	for _, commands := range enginetest.Commands(t, fake.Log) {
+		for _, command := range commands {
+			if value, ok := strings.CutPrefix(command, "setoption name MultiPV value "); ok {
+				if n, _ := strconv.Atoi(value); n > 4 {
+					t.Errorf("engine sent MultiPV %d, want at most 4", n)
+				}
+			}
+			if depth, ok := strings.CutPrefix(command, "go depth "); ok {
+				searches++
+				if n, _ := strconv.Atoi(depth); n < 10 || n > 16 {
+					t.Errorf("engine searched depth %d, want 10 to 16", n)
+				}
+			}
+		}
+	}
+	if searches == 0 {
+		t.Fatal("no searches logged")
+	}
+}
+
+func TestLimits_DepthSteps(t *testing.T) {
+	l := DefaultLimits()
+	for _, tt := range []struct {
+		target int
+		want   []int
+	}{
+		{10, []int{10}},
+		{20, []int{10, 14, 18, 20}},
+		{22, []int{10, 14, 18, 22}},
+	} {
+		if got := l.depthSteps(tt.target); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("depthSteps(%d) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
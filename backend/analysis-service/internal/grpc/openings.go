@@ -0,0 +1,68 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AggregateOpenings aggregates a player's analyzed games by opening
+func (s *Server) AggregateOpenings(ctx context.Context, req *pb.AggregateOpeningsRequest) (*pb.OpeningsReport, error) {
+	s.logger.Info("AggregateOpenings request",
+		zap.String("player", req.Player),
+		zap.Int("games", len(req.Games)))
+
+	if req.Player == "" {
+		return nil, status.Error(codes.InvalidArgument, "player is required")
+	}
+	if req.MinGames < 0 || req.LastGames < 0 {
+		return nil, status.Error(codes.InvalidArgument, "min_games and last_games must not be negative")
+	}
+
+	games := make([]evaluation.GameEvaluation, 0, len(req.Games))
+	for i, game := range req.Games {
+		eval, err := toGameEvaluation(game)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "game %d: %v", i, err)
+		}
+		games = append(games, eval)
+	}
+
+	minGames := int(req.MinGames)
+	if minGames == 0 {
+		minGames = s.openingMinGames
+	}
+	report := evaluation.AggregateOpenings(games, req.Player, evaluation.OpeningOptions{
+		MinGames:  minGames,
+		LastGames: int(req.LastGames),
+	})
+
+	result := &pb.OpeningsReport{
+		Player:         report.Player,
+		Games:          int32(report.Games),
+		MinGames:       int32(minGames),
+		HiddenOpenings: int32(report.Hidden),
+	}
+	for _, o := range report.Openings {
+		result.Openings = append(result.Openings, &pb.OpeningStats{
+			Family:            o.Family,
+			Name:              o.Name,
+			Color:             o.Color,
+			Games:             int32(o.Games),
+			Wins:              int32(o.Wins),
+			Draws:             int32(o.Draws),
+			Losses:            int32(o.Losses),
+			ScorePercent:      float32(o.ScorePercent),
+			AverageAccuracy:   float32(evaluation.RoundAccuracy(o.AverageAccuracy)),
+			OutOfBookAcpl:     float32(evaluation.RoundACPL(o.OutOfBookACPL)),
+			TopDeviation:      o.TopDeviation,
+			TopDeviationGames: int32(o.TopDeviationGames),
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAggregateOpenings(t *testing.T) {
+	s := NewServer(nil, nil, zap.NewNop(), 0)
+
+	game := func(white, black, eco string) *pb.AnalyzedGame {
+		return &pb.AnalyzedGame{Analysis: exportAnalysis(), WhitePlayer: white, BlackPlayer: black, Result: "1-0", Eco: eco}
+	}
+	req := &pb.AggregateOpeningsRequest{
+		Player: "alice",
+		Games: []*pb.AnalyzedGame{
+			game("Alice", "Bob", "C20"),
+			game("Alice", "Carol", "C24"),
+			game("Alice", "Dave", "C25"),
+			game("Bob", "Alice", "B12"),
+			game("Bob", "Alice", ""),
+		},
+	}
+
+	report, err := s.AggregateOpenings(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Games != 5 || report.MinGames != 3 || report.HiddenOpenings != 2 || len(report.Openings) != 1 {
+		t.Fatalf("report = %v", report)
+	}
+	if o := report.Openings[0]; o.Family != "C20-C29" || o.Color != "white" || o.Games != 3 || o.ScorePercent != 100 {
+		t.Errorf("opening = %v", o)
+	}
+
+	req.MinGames = 1
+	if report, err = s.AggregateOpenings(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Openings) != 3 || report.Openings[2].Family != "unknown" || report.Openings[2].ScorePercent != 0 {
+		t.Errorf("openings = %v", report.Openings)
+	}
+}
+
+func TestAggregateOpenings_Errors(t *testing.T) {
+	s := NewServer(nil, nil, zap.NewNop(), 0)
+
+	tests := []struct {
+		name string
+		req  *pb.AggregateOpeningsRequest
+	}{
+		{"missing player", &pb.AggregateOpeningsRequest{}},
+		{"negative min games", &pb.AggregateOpeningsRequest{Player: "alice", MinGames: -1}},
+		{"missing analysis", &pb.AggregateOpeningsRequest{Player: "alice", Games: []*pb.AnalyzedGame{{Result: "1-0"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.AggregateOpenings(context.Background(), tt.req)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("code = %v, want InvalidArgument (err %v)", status.Code(err), err)
+			}
+		})
+	}
+}
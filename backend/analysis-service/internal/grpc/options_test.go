@@ -0,0 +1,101 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/internal/config"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// silentStreamServer holds a game stream open without sending anything,
+// like a deep pre-analysis phase, then sends a single final message.
+type silentStreamServer struct {
+	pb.UnimplementedAnalysisServiceServer
+	silence time.Duration
+}
+
+func (s *silentStreamServer) AnalyzeGameStream(req *pb.AnalyzeGameRequest, stream pb.AnalysisService_AnalyzeGameStreamServer) error {
+	select {
+	case <-time.After(s.silence):
+	case <-stream.Context().Done():
+		return stream.Context().Err()
+	}
+	return stream.Send(&pb.GameAnalysisProgress{GameId: req.GameId, Status: "completed"})
+}
+
+func defaultGRPCConfig() config.GRPCConfig {
+	return config.GRPCConfig{
+		KeepaliveTime:         30 * time.Second,
+		KeepaliveTimeout:      10 * time.Second,
+		KeepaliveMinTime:      10 * time.Second,
+		PermitWithoutStream:   true,
+		MaxConcurrentStreams:  100,
+		MaxConnectionIdle:     15 * time.Minute,
+		MaxConnectionAgeGrace: 30 * time.Second,
+	}
+}
+
+func TestServerOptions_AggressiveClientKeepsStream(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for a real client keepalive ping")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	server := grpc.NewServer(ServerOptions(defaultGRPCConfig())...)
+	// Long enough for the client to send at least one keepalive ping
+	pb.RegisterAnalysisServiceServer(server, &silentStreamServer{silence: 12 * time.Second})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	// 10s is the most aggressive ping interval grpc-go clients allow
+	conn, err := grpc.NewClient(listener.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                10 * time.Second,
+			Timeout:             time.Second,
+			PermitWithoutStream: true,
+		}),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stream, err := pb.NewAnalysisServiceClient(conn).AnalyzeGameStream(ctx, &pb.AnalyzeGameRequest{GameId: "keepalive"})
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("stream was killed: %v", err)
+	}
+	if msg.GameId != "keepalive" || msg.Status != "completed" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
+
+func TestServerOptions_ZeroStreamLimitOmitted(t *testing.T) {
+	cfg := defaultGRPCConfig()
+
+	withLimit := len(ServerOptions(cfg))
+	cfg.MaxConcurrentStreams = 0
+	withoutLimit := len(ServerOptions(cfg))
+
+	if withLimit != withoutLimit+1 {
+		t.Errorf("expected MaxConcurrentStreams option only when limit > 0 (got %d and %d options)", withLimit, withoutLimit)
+	}
+}
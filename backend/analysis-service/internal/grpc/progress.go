@@ -0,0 +1,220 @@
+package grpc
+
+import (
+	"time"
+
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// progressSender owns all Send calls on a game analysis stream.
+// gRPC streams are not safe for concurrent sends, so analyzer callbacks and
+// heartbeats are funneled through a single goroutine. It also keeps long
+// games on a warm cache from flooding the stream: an update within
+// minInterval of the previous message is held back and merged into the
+// next one, unless it is a preview or its move is a mistake or a blunder.
+type progressSender struct {
+	stream            pb.AnalysisService_AnalyzeGameStreamServer
+	updates           chan *pb.GameAnalysisProgress
+	done              chan struct{}
+	heartbeatInterval time.Duration
+	minInterval       time.Duration
+	coalesced         int // Updates merged into later messages
+	queuePosition     func() int
+	initial           *pb.GameAnalysisProgress
+	startTime         time.Time
+	logger            *zap.Logger
+	err               error
+}
+
+// progressPercent returns current out of total as a percentage, 0 when
+// there is nothing to do so a game without moves doesn't report NaN
+func progressPercent(current, total int) float32 {
+	if total <= 0 {
+		return 0
+	}
+	return float32(current) / float32(total) * 100
+}
+
+// newProgressSender starts the sender goroutine. Heartbeats echo initial until
+// the first real update. A zero heartbeat interval disables heartbeats, a
+// zero minInterval sends every update.
+func newProgressSender(
+	stream pb.AnalysisService_AnalyzeGameStreamServer,
+	initial *pb.GameAnalysisProgress,
+	heartbeatInterval time.Duration,
+	minInterval time.Duration,
+	queuePosition func() int,
+	logger *zap.Logger,
+) *progressSender {
+	ps := &progressSender{
+		stream:            stream,
+		updates:           make(chan *pb.GameAnalysisProgress, 64),
+		done:              make(chan struct{}),
+		heartbeatInterval: heartbeatInterval,
+		minInterval:       minInterval,
+		queuePosition:     queuePosition,
+		initial:           initial,
+		startTime:         time.Now(),
+		logger:            logger,
+	}
+	go ps.run()
+	return ps
+}
+
+// Send queues a progress message. It never blocks once the sender has stopped.
+func (ps *progressSender) Send(progress *pb.GameAnalysisProgress) {
+	select {
+	case ps.updates <- progress:
+	case <-ps.done:
+	}
+}
+
+// Finish queues the final message and waits until it has been written,
+// after any update still held back. No heartbeat is sent after the final
+// message.
+func (ps *progressSender) Finish(final *pb.GameAnalysisProgress) error {
+	ps.Send(final)
+	close(ps.updates)
+	<-ps.done
+	return ps.err
+}
+
+func (ps *progressSender) run() {
+	defer close(ps.done)
+
+	var tick <-chan time.Time
+	var ticker *time.Ticker
+	if ps.heartbeatInterval > 0 {
+		ticker = time.NewTicker(ps.heartbeatInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// Last real progress, echoed by heartbeats so progress bars don't move
+	last := ps.initial
+	var lastWrite time.Time
+
+	// An update held back by minInterval, sent when flush fires
+	var pending *pb.GameAnalysisProgress
+	var flush <-chan time.Time
+
+	send := func(progress *pb.GameAnalysisProgress) bool {
+		if !ps.write(progress) {
+			return false
+		}
+		last, lastWrite = progress, time.Now()
+		if ticker != nil {
+			ticker.Reset(ps.heartbeatInterval)
+		}
+		return true
+	}
+
+	for {
+		select {
+		case progress, ok := <-ps.updates:
+			if !ok {
+				return
+			}
+			if progress.Status != "analyzing" {
+				// Final message, after what was held back
+				if pending != nil && !send(pending) {
+					return
+				}
+				final := proto.Clone(progress).(*pb.GameAnalysisProgress)
+				final.CoalescedUpdates = int32(ps.coalesced)
+				send(final)
+				return
+			}
+			if pending != nil {
+				progress = ps.merge(pending, progress)
+				pending = nil
+			}
+			if wait := ps.minInterval - time.Since(lastWrite); wait > 0 && !urgentProgress(progress) {
+				pending = progress
+				if flush == nil {
+					flush = time.After(wait)
+				}
+				continue
+			}
+			flush = nil
+			if !send(progress) {
+				return
+			}
+		case <-flush:
+			flush = nil
+			if pending != nil {
+				if !send(pending) {
+					return
+				}
+				pending = nil
+			}
+		case <-tick:
+			heartbeat := &pb.GameAnalysisProgress{
+				GameId:          last.GameId,
+				CurrentMove:     last.CurrentMove,
+				TotalMoves:      last.TotalMoves,
+				ProgressPercent: last.ProgressPercent,
+				Status:          "analyzing",
+				Heartbeat:       true,
+				ChunksCompleted: last.ChunksCompleted,
+				ChunksTotal:     last.ChunksTotal,
+			}
+			if ps.queuePosition != nil {
+				heartbeat.QueuePosition = int32(ps.queuePosition())
+			}
+			if !ps.write(heartbeat) {
+				return
+			}
+		}
+	}
+}
+
+// urgentProgress reports whether progress goes out however soon after the
+// previous message: it is a preview, or it analyzes a mistake or a blunder
+func urgentProgress(progress *pb.GameAnalysisProgress) bool {
+	if progress.Preliminary {
+		return true
+	}
+	switch progress.GetMoveAnalysis().GetClassification() {
+	case pb.MoveClassification_MISTAKE, pb.MoveClassification_BLUNDER:
+		return true
+	}
+	return false
+}
+
+// merge returns next with held, an update held back before it, folded in:
+// held's moves go into batched_moves, and its metrics and estimate stay
+// until next has newer ones
+func (ps *progressSender) merge(held, next *pb.GameAnalysisProgress) *pb.GameAnalysisProgress {
+	ps.coalesced++
+	merged := proto.Clone(next).(*pb.GameAnalysisProgress)
+	merged.BatchedMoves = append([]*pb.MoveAnalysis(nil), held.BatchedMoves...)
+	if held.MoveAnalysis != nil {
+		merged.BatchedMoves = append(merged.BatchedMoves, held.MoveAnalysis)
+	}
+	merged.BatchedMoves = append(merged.BatchedMoves, next.BatchedMoves...)
+	if merged.WhiteMetrics == nil {
+		merged.WhiteMetrics, merged.BlackMetrics = held.WhiteMetrics, held.BlackMetrics
+	}
+	if merged.Estimate == nil {
+		merged.Estimate = held.Estimate
+	}
+	return merged
+}
+
+// write stamps elapsed time and sends one message, reporting whether the
+// stream is still usable
+func (ps *progressSender) write(progress *pb.GameAnalysisProgress) bool {
+	// Callers may still hold a reference to the message
+	msg := proto.Clone(progress).(*pb.GameAnalysisProgress)
+	msg.ElapsedMs = time.Since(ps.startTime).Milliseconds()
+
+	if err := ps.stream.Send(msg); err != nil {
+		ps.logger.Warn("Failed to send progress", zap.Error(err))
+		ps.err = err
+		return false
+	}
+	return true
+}
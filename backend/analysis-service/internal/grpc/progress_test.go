@@ -0,0 +1,225 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// recordingStream captures messages sent on a game analysis stream
+type recordingStream struct {
+	grpc.ServerStream
+	mu   sync.Mutex
+	sent []*pb.GameAnalysisProgress
+}
+
+func (r *recordingStream) Send(msg *pb.GameAnalysisProgress) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func (r *recordingStream) Context() context.Context {
+	return context.Background()
+}
+
+func (r *recordingStream) messages() []*pb.GameAnalysisProgress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*pb.GameAnalysisProgress(nil), r.sent...)
+}
+
+func TestProgressSender_HeartbeatsDuringSilence(t *testing.T) {
+	stream := &recordingStream{}
+	initial := &pb.GameAnalysisProgress{GameId: "g1", TotalMoves: 40, Status: "analyzing"}
+	sender := newProgressSender(stream, initial, 20*time.Millisecond, 0, func() int { return 3 }, zap.NewNop())
+
+	// Silent pre-analysis phase
+	time.Sleep(110 * time.Millisecond)
+	sender.Send(&pb.GameAnalysisProgress{GameId: "g1", CurrentMove: 5, TotalMoves: 40, ProgressPercent: 12.5, Status: "analyzing"})
+	time.Sleep(60 * time.Millisecond)
+
+	if err := sender.Finish(&pb.GameAnalysisProgress{GameId: "g1", CurrentMove: 40, TotalMoves: 40, ProgressPercent: 100, Status: "completed"}); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	finishedCount := len(stream.messages())
+
+	// Nothing may follow the final message
+	time.Sleep(60 * time.Millisecond)
+	msgs := stream.messages()
+	if len(msgs) != finishedCount {
+		t.Fatalf("got %d messages after final, want none", len(msgs)-finishedCount)
+	}
+	if last := msgs[len(msgs)-1]; last.Status != "completed" || last.Heartbeat {
+		t.Fatalf("last message = %+v, want completed non-heartbeat", last)
+	}
+
+	var heartbeats, realUpdates int
+	sawUpdate := false
+	for _, msg := range msgs {
+		if !msg.Heartbeat {
+			realUpdates++
+			if msg.CurrentMove == 5 {
+				sawUpdate = true
+			}
+			continue
+		}
+		heartbeats++
+		want := int32(0)
+		if sawUpdate {
+			want = 5
+		}
+		if msg.CurrentMove != want || msg.TotalMoves != 40 {
+			t.Errorf("heartbeat counts = %d/%d, want %d/40", msg.CurrentMove, msg.TotalMoves, want)
+		}
+		if msg.Status != "analyzing" || msg.QueuePosition != 3 {
+			t.Errorf("heartbeat = %+v, want analyzing with queue position 3", msg)
+		}
+	}
+
+	if heartbeats < 3 {
+		t.Errorf("got %d heartbeats, want at least 3", heartbeats)
+	}
+	if realUpdates != 2 {
+		t.Errorf("got %d real updates, want 2", realUpdates)
+	}
+}
+
+func TestProgressSender_HeartbeatsDisabled(t *testing.T) {
+	stream := &recordingStream{}
+	sender := newProgressSender(stream, &pb.GameAnalysisProgress{Status: "analyzing"}, 0, 0, nil, zap.NewNop())
+
+	time.Sleep(30 * time.Millisecond)
+	sender.Finish(&pb.GameAnalysisProgress{Status: "completed"})
+
+	if msgs := stream.messages(); len(msgs) != 1 {
+		t.Errorf("got %d messages, want only the final one", len(msgs))
+	}
+}
+
+// moveProgress is an update analyzing move ply+1, classified class
+func moveProgress(ply int, class pb.MoveClassification) *pb.GameAnalysisProgress {
+	return &pb.GameAnalysisProgress{
+		GameId:       "g1",
+		CurrentMove:  int32(ply + 1),
+		TotalMoves:   300,
+		Status:       "analyzing",
+		MoveAnalysis: &pb.MoveAnalysis{Ply: int32(ply), Classification: class},
+	}
+}
+
+func TestProgressSender_Coalesces(t *testing.T) {
+	stream := &recordingStream{}
+	sender := newProgressSender(stream, &pb.GameAnalysisProgress{Status: "analyzing"}, 0, time.Hour, nil, zap.NewNop())
+
+	// A warm cache: 300 moves at once, two of them blunders
+	for ply := 0; ply < 300; ply++ {
+		class := pb.MoveClassification_GOOD
+		if ply == 100 || ply == 200 {
+			class = pb.MoveClassification_BLUNDER
+		}
+		progress := moveProgress(ply, class)
+		if ply == 150 {
+			progress.WhiteMetrics, progress.BlackMetrics = &pb.GameMetrics{TotalMoves: 75}, &pb.GameMetrics{TotalMoves: 75}
+		}
+		sender.Send(progress)
+	}
+	if err := sender.Finish(&pb.GameAnalysisProgress{GameId: "g1", CurrentMove: 300, TotalMoves: 300, ProgressPercent: 100, Status: "completed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first update, each blunder with what was held back before it,
+	// what was held back after the last blunder, and the final message
+	msgs := stream.messages()
+	if len(msgs) != 5 {
+		t.Fatalf("got %d messages, want 5", len(msgs))
+	}
+	var plies []int32
+	for _, msg := range msgs {
+		for _, move := range msg.BatchedMoves {
+			plies = append(plies, move.Ply)
+		}
+		if msg.MoveAnalysis != nil {
+			plies = append(plies, msg.MoveAnalysis.Ply)
+		}
+	}
+	for i, ply := range plies {
+		if ply != int32(i) {
+			t.Fatalf("moves arrived as plies %v..., want every ply in order", plies[:i+1])
+		}
+	}
+	if len(plies) != 300 {
+		t.Errorf("%d moves arrived, want 300", len(plies))
+	}
+	if got := msgs[2].MoveAnalysis; got.GetClassification() != pb.MoveClassification_BLUNDER || got.Ply != 200 {
+		t.Errorf("third message analyzes %v, want the blunder at ply 200", got)
+	}
+	if msgs[2].WhiteMetrics.GetTotalMoves() != 75 {
+		t.Errorf("metrics held back were dropped: %v", msgs[2].WhiteMetrics)
+	}
+	if final := msgs[4]; final.Status != "completed" || final.ProgressPercent != 100 || final.CoalescedUpdates != 296 {
+		t.Errorf("final message: %s, %v%%, %d coalesced, want completed, 100%%, 296", final.Status, final.ProgressPercent, final.CoalescedUpdates)
+	}
+}
+
+func TestProgressSender_FlushesAfterInterval(t *testing.T) {
+	stream := &recordingStream{}
+	sender := newProgressSender(stream, &pb.GameAnalysisProgress{Status: "analyzing"}, 0, 30*time.Millisecond, nil, zap.NewNop())
+
+	sender.Send(moveProgress(0, pb.MoveClassification_GOOD))
+	sender.Send(moveProgress(1, pb.MoveClassification_GOOD))
+	sender.Send(moveProgress(2, pb.MoveClassification_GOOD))
+
+	// The updates held back go out once the interval is over, without
+	// waiting for another
+	time.Sleep(80 * time.Millisecond)
+	msgs := stream.messages()
+	if len(msgs) != 2 || msgs[1].CurrentMove != 3 || len(msgs[1].BatchedMoves) != 1 {
+		t.Fatalf("messages = %v, want move 1 then move 3 with move 2 batched", msgs)
+	}
+	sender.Finish(&pb.GameAnalysisProgress{Status: "completed"})
+	if final := stream.messages()[2]; final.CoalescedUpdates != 1 {
+		t.Errorf("final message counts %d coalesced updates, want 1", final.CoalescedUpdates)
+	}
+}
+
+func TestAnalyzeGameStream_ChunkProgress(t *testing.T) {
+	binary := fakeEngine.Binary(t)
+	p, err := pool.NewPool(1, engine.Config{BinaryPath: binary, Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	s := NewServer(analyzer.NewAnalyzer(p, zap.NewNop(), 1, 20, 30, time.Minute), p, zap.NewNop(), 0)
+
+	// 11 positions on one engine: chunks of 8 and 3
+	stream := &recordingStream{}
+	pgn := "1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 Nf6 5. O-O Be7 *"
+	if err := s.AnalyzeGameStream(&pb.AnalyzeGameRequest{GameId: "g1", Pgn: pgn}, stream); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each chunk is reported once, after its last position's progress
+	var chunks []string
+	completed := int32(0)
+	for _, msg := range stream.messages() {
+		if msg.ChunksCompleted != completed {
+			completed = msg.ChunksCompleted
+			chunks = append(chunks, fmt.Sprintf("%d/%d at move %d", msg.ChunksCompleted, msg.ChunksTotal, msg.CurrentMove))
+		}
+	}
+	if want := []string{"1/2 at move 8", "2/2 at move 10"}; !reflect.DeepEqual(chunks, want) {
+		t.Errorf("chunk messages = %v, want %v", chunks, want)
+	}
+}
@@ -0,0 +1,183 @@
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/eloinsight/analysis-service/internal/quota"
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// anonymousPrincipal is charged for calls without x-api-key or x-user-id
+// metadata
+const anonymousPrincipal = "anonymous"
+
+// meteredMethods are the calls that can search, and so are turned away
+// once their caller's quota is used up
+var meteredMethods = map[string]bool{
+	pb.AnalysisService_AnalyzePosition_FullMethodName:       true,
+	pb.AnalysisService_AnalyzePositionStream_FullMethodName: true,
+	pb.AnalysisService_AnalyzeGame_FullMethodName:           true,
+	pb.AnalysisService_AnalyzeGameStream_FullMethodName:     true,
+	pb.AnalysisService_GetBestMoves_FullMethodName:          true,
+}
+
+// principalFrom returns who ctx's call is charged to: "key:" and a hash of
+// its x-api-key, so keys are never stored or logged, else "user:" and its
+// x-user-id
+func principalFrom(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get("x-api-key"); len(keys) > 0 && keys[0] != "" {
+		sum := sha256.Sum256([]byte(keys[0]))
+		return "key:" + hex.EncodeToString(sum[:8])
+	}
+	if ids := md.Get("x-user-id"); len(ids) > 0 && ids[0] != "" {
+		return "user:" + ids[0]
+	}
+	return anonymousPrincipal
+}
+
+// callQuota is the meter and principal of a call, kept in its context
+type callQuota struct {
+	meter     *quota.Meter
+	principal string
+}
+
+type callQuotaKey struct{}
+
+func quotaFrom(ctx context.Context) (callQuota, bool) {
+	q, ok := ctx.Value(callQuotaKey{}).(callQuota)
+	return q, ok
+}
+
+// QuotaInterceptors returns the unary and stream interceptors charging the
+// engine time of every call to its principal with meter. Metered calls of
+// a principal whose quota is used up fail with ResourceExhausted; when the
+// usage can't be read they go ahead.
+func QuotaInterceptors(meter *quota.Meter, logger *zap.Logger) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	start := func(ctx context.Context, method string) (context.Context, error) {
+		principal := principalFrom(ctx)
+		if meteredMethods[method] {
+			usage, err := meter.Check(ctx, principal, 0)
+			if errors.Is(err, quota.ErrExceeded) {
+				return nil, quotaStatus(usage, 0)
+			}
+			if err != nil {
+				logger.Warn("Failed to read quota usage", zap.String("principal", principal), zap.Error(err))
+			}
+		}
+		ctx = context.WithValue(ctx, callQuotaKey{}, callQuota{meter: meter, principal: principal})
+		return analyzer.WithSearchMeter(ctx, func(d time.Duration) {
+			meter.Charge(principal, d)
+		}), nil
+	}
+
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := start(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := start(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+	return unary, stream
+}
+
+// contextStream is a ServerStream with the context an interceptor made
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
+
+// quotaStatus is the ResourceExhausted error of a call usage can't afford;
+// estimate is its expected engine time, 0 when unknown
+func quotaStatus(usage quota.Usage, estimate time.Duration) error {
+	msg := fmt.Sprintf("%v: %s of %s used today", quota.ErrExceeded, usage.Used, usage.Limit)
+	if estimate > 0 {
+		msg += fmt.Sprintf(", analysis needs about %s", estimate)
+	}
+	st := status.New(codes.ResourceExhausted, msg)
+
+	info := map[string]string{
+		"principal":         usage.Principal,
+		"used_ms":           strconv.FormatInt(usage.Used.Milliseconds(), 10),
+		"limit_ms":          strconv.FormatInt(usage.Limit.Milliseconds(), 10),
+		"remaining_ms":      strconv.FormatInt(usage.Remaining().Milliseconds(), 10),
+		"resets_at_unix_ms": strconv.FormatInt(usage.ResetsAt.UnixMilli(), 10),
+	}
+	if estimate > 0 {
+		info["estimate_ms"] = strconv.FormatInt(estimate.Milliseconds(), 10)
+	}
+	withDetails, err := st.WithDetails(
+		&errdetails.ErrorInfo{Reason: "QUOTA_EXCEEDED", Domain: errorDomain, Metadata: info},
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     usage.Principal,
+			Description: fmt.Sprintf("daily engine time, %s left until %s", usage.Remaining(), usage.ResetsAt.Format(time.RFC3339)),
+		}}},
+	)
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// checkGameQuota turns away a game analysis expected to take more engine
+// time than its caller has left. Games without an estimate go ahead and
+// are stopped by the interceptor on the next call once over.
+func (s *Server) checkGameQuota(ctx context.Context, pgn string, depth int, opts analyzer.GameOptions) error {
+	q, ok := quotaFrom(ctx)
+	if !ok {
+		return nil
+	}
+	estimate, ok := s.analyzer.EstimateGameTime(pgn, depth, opts)
+	if !ok || estimate == 0 {
+		return nil
+	}
+	usage, err := q.meter.Check(ctx, q.principal, estimate)
+	if errors.Is(err, quota.ErrExceeded) {
+		return quotaStatus(usage, estimate)
+	}
+	if err != nil {
+		s.logger.Warn("Failed to read quota usage", zap.String("principal", q.principal), zap.Error(err))
+	}
+	return nil
+}
+
+// GetQuota returns the caller's engine time today
+func (s *Server) GetQuota(ctx context.Context, req *pb.GetQuotaRequest) (*pb.QuotaUsage, error) {
+	q, ok := quotaFrom(ctx)
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "engine time quotas are not enabled")
+	}
+	usage, err := q.meter.Usage(ctx, q.principal)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to read quota usage: %v", err)
+	}
+	return &pb.QuotaUsage{
+		Principal:      usage.Principal,
+		UsedMs:         usage.Used.Milliseconds(),
+		LimitMs:        usage.Limit.Milliseconds(),
+		RemainingMs:    usage.Remaining().Milliseconds(),
+		ResetsAtUnixMs: usage.ResetsAt.UnixMilli(),
+	}, nil
+}
@@ -0,0 +1,162 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/internal/quota"
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newQuotaClient serves an analysis service on a fake engine behind the
+// quota interceptors of meter
+func newQuotaClient(t *testing.T, meter *quota.Meter) pb.AnalysisServiceClient {
+	t.Helper()
+	binary := fakeEngine.Binary(t)
+	p, err := pool.NewPool(1, engine.Config{BinaryPath: binary, Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	unary, stream := QuotaInterceptors(meter, zap.NewNop())
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(unary), grpc.ChainStreamInterceptor(stream))
+	a := analyzer.NewAnalyzer(p, zap.NewNop(), 1, 20, 30, time.Minute)
+	pb.RegisterAnalysisServiceServer(server, NewServer(a, p, zap.NewNop(), 0))
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewAnalysisServiceClient(conn)
+}
+
+func TestQuota_ChargesSearchesToPrincipal(t *testing.T) {
+	meter := quota.NewMeter(quota.NewMemoryStore(), time.Hour, zap.NewNop())
+	client := newQuotaClient(t, meter)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-user-id", "alice")
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+
+	if _, err := client.AnalyzePosition(ctx, &pb.AnalyzePositionRequest{Fen: fen, Depth: 12}); err != nil {
+		t.Fatal(err)
+	}
+	usage, err := client.GetQuota(ctx, &pb.GetQuotaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Principal != "user:alice" || usage.UsedMs < 1 || usage.LimitMs != time.Hour.Milliseconds() {
+		t.Fatalf("usage after a search = %+v", usage)
+	}
+
+	// The same position again comes from the cache and is free
+	if _, err := client.AnalyzePosition(ctx, &pb.AnalyzePositionRequest{Fen: fen, Depth: 12}); err != nil {
+		t.Fatal(err)
+	}
+	again, err := client.GetQuota(ctx, &pb.GetQuotaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.UsedMs != usage.UsedMs {
+		t.Errorf("cached answer charged: used %dms, then %dms", usage.UsedMs, again.UsedMs)
+	}
+
+	other, err := client.GetQuota(metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "secret"), &pb.GetQuotaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.UsedMs != 0 || !strings.HasPrefix(other.Principal, "key:") || strings.Contains(other.Principal, "secret") {
+		t.Errorf("API key usage = %+v, want an unused hashed key", other)
+	}
+}
+
+func TestQuota_RejectsExhaustedPrincipal(t *testing.T) {
+	meter := quota.NewMeter(quota.NewMemoryStore(), time.Second, zap.NewNop())
+	client := newQuotaClient(t, meter)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-user-id", "bob")
+	meter.Charge("user:bob", time.Second)
+
+	_, err := client.AnalyzePosition(ctx, &pb.AnalyzePositionRequest{Fen: "8/8/8/8/8/8/8/K6k w - - 0 1"})
+	if code := status.Code(err); code != codes.ResourceExhausted {
+		t.Fatalf("code = %v, want ResourceExhausted", code)
+	}
+	if reason := errorReason(err); reason != "QUOTA_EXCEEDED" {
+		t.Errorf("reason = %q, want QUOTA_EXCEEDED", reason)
+	}
+	var violation *errdetails.QuotaFailure
+	for _, d := range status.Convert(err).Details() {
+		if v, ok := d.(*errdetails.QuotaFailure); ok {
+			violation = v
+		}
+	}
+	if violation == nil || len(violation.Violations) != 1 || violation.Violations[0].Subject != "user:bob" {
+		t.Errorf("QuotaFailure = %v", violation)
+	}
+
+	// Asking how much is left still works
+	usage, err := client.GetQuota(ctx, &pb.GetQuotaRequest{})
+	if err != nil || usage.RemainingMs != 0 {
+		t.Errorf("GetQuota = %+v, %v; want 0ms remaining", usage, err)
+	}
+}
+
+func TestQuota_GameEstimateOverRemaining(t *testing.T) {
+	meter := quota.NewMeter(quota.NewMemoryStore(), time.Hour, zap.NewNop())
+	client := newQuotaClient(t, meter)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-user-id", "carol")
+
+	// One search at the depth gives the analyzer an estimate per position
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	if _, err := client.AnalyzePosition(ctx, &pb.AnalyzePositionRequest{Fen: fen, Depth: 12}); err != nil {
+		t.Fatal(err)
+	}
+	usage, err := meter.Usage(context.Background(), "user:carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	meter.Charge("user:carol", usage.Remaining()-5*time.Millisecond)
+
+	_, err = client.AnalyzeGame(ctx, &pb.AnalyzeGameRequest{GameId: "g", Pgn: "1. d4 d5 2. c4 c6 3. Nc3 Nf6 *", Depth: 12})
+	if code := status.Code(err); code != codes.ResourceExhausted {
+		t.Fatalf("code = %v, want ResourceExhausted", code)
+	}
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.Metadata["estimate_ms"] == "" {
+			t.Errorf("ErrorInfo without estimate_ms: %v", info.Metadata)
+		}
+	}
+
+	// Without a pool search left to make, the game costs nothing
+	_, err = client.AnalyzeGame(ctx, &pb.AnalyzeGameRequest{GameId: "g", Pgn: "1. d4 d5 2. c4 c6 3. Nc3 Nf6 *", Depth: 12, CacheOnly: true})
+	if code := status.Code(err); code == codes.ResourceExhausted {
+		t.Errorf("cache-only game rejected: %v", err)
+	}
+}
+
+func TestGetQuota_Disabled(t *testing.T) {
+	_, err := newTestServer().GetQuota(context.Background(), &pb.GetQuotaRequest{})
+	if code := status.Code(err); code != codes.FailedPrecondition {
+		t.Errorf("code = %v, want FailedPrecondition", code)
+	}
+}
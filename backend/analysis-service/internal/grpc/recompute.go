@@ -0,0 +1,128 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecomputeMetrics scores a game's stored moves again. It only calculates,
+// so it never waits for an engine.
+func (s *Server) RecomputeMetrics(ctx context.Context, req *pb.RecomputeMetricsRequest) (*pb.RecomputeMetricsResponse, error) {
+	s.logger.Debug("RecomputeMetrics request",
+		zap.Int("moves", len(req.Moves)),
+		zap.String("thresholdProfile", req.ThresholdProfile))
+
+	if !validResult(req.Result) {
+		return nil, status.Errorf(codes.InvalidArgument, "result %q must be 1-0, 0-1, 1/2-1/2 or *", req.Result)
+	}
+	moves, err := toStoredMoves(req)
+	if err != nil {
+		return nil, err
+	}
+
+	recomputed, err := s.analyzer.RecomputeMetrics(moves, analyzer.GameOptions{
+		ThresholdProfile:   req.ThresholdProfile,
+		ExcludeGarbageTime: req.ExcludeGarbageTime,
+		AccuracyModel:      toAccuracyModel(req.AccuracyModel),
+		Result:             req.Result,
+	})
+	if err != nil {
+		return nil, toStatus(err, "failed to recompute metrics")
+	}
+
+	return &pb.RecomputeMetricsResponse{
+		WhiteMetrics:     convertPlayerMetrics(recomputed.White, recomputed.AccuracyModel),
+		BlackMetrics:     convertPlayerMetrics(recomputed.Black, recomputed.AccuracyModel),
+		ThresholdProfile: recomputed.ThresholdProfile,
+		Thresholds:       convertThresholds(recomputed.Thresholds),
+	}, nil
+}
+
+// toStoredMoves converts and checks a request's moves: each needs both
+// evaluations, plies must increase, and each ply must be its color's,
+// counting from fen_start_offset
+func toStoredMoves(req *pb.RecomputeMetricsRequest) ([]analyzer.StoredMove, error) {
+	if len(req.Moves) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "moves are required")
+	}
+	if req.FenStartOffset != 0 && req.FenStartOffset != 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "fen_start_offset %d must be 0, White to move first, or 1, Black to move first", req.FenStartOffset)
+	}
+
+	moves := make([]analyzer.StoredMove, 0, len(req.Moves))
+	for i, m := range req.Moves {
+		if m.Ply < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "move %d: ply %d is negative", i, m.Ply)
+		}
+		if i > 0 && m.Ply <= req.Moves[i-1].Ply {
+			return nil, status.Errorf(codes.InvalidArgument, "move %d: ply %d follows ply %d, plies must increase", i, m.Ply, req.Moves[i-1].Ply)
+		}
+		want := "white"
+		if (m.Ply+req.FenStartOffset)%2 == 1 {
+			want = "black"
+		}
+		switch {
+		case m.Color != "white" && m.Color != "black":
+			return nil, status.Errorf(codes.InvalidArgument, "move %d (ply %d): color %q must be white or black", i, m.Ply, m.Color)
+		case m.Color != want && i > 0 && m.Color == req.Moves[i-1].Color && m.Ply == req.Moves[i-1].Ply+1:
+			return nil, status.Errorf(codes.InvalidArgument, "move %d (ply %d): %s moves twice in a row, colors must alternate", i, m.Ply, m.Color)
+		case m.Color != want:
+			return nil, status.Errorf(codes.InvalidArgument, "move %d (ply %d): color %s, but with fen_start_offset %d ply %d is %s's; set fen_start_offset 1 for a game from a FEN with Black to move",
+				i, m.Ply, m.Color, req.FenStartOffset, m.Ply, want)
+		}
+		if m.EvalBefore == nil || m.EvalAfter == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "move %d (ply %d): eval_before and eval_after are required", i, m.Ply)
+		}
+
+		before, after := toEvaluation(m.EvalBefore), toEvaluation(m.EvalAfter)
+		if req.EvalPerspective == pb.EvalPerspective_WHITE {
+			// The mover's before the move, the opponent's after it
+			if m.Color == "black" {
+				before = negateEvaluation(before)
+			} else {
+				after = negateEvaluation(after)
+			}
+		}
+		before.Depth, after.Depth = int(m.DepthBefore), int(m.DepthAfter)
+		moves = append(moves, analyzer.StoredMove{
+			Ply:        int(m.Ply),
+			Color:      m.Color,
+			EvalBefore: before,
+			EvalAfter:  after,
+			BestMove:   m.BestMove,
+			Book:       m.Book,
+		})
+	}
+	return moves, nil
+}
+
+// convertPlayerMetrics converts metrics of the evaluation package to proto
+func convertPlayerMetrics(metrics evaluation.PlayerMetrics, model evaluation.AccuracyModel) *pb.GameMetrics {
+	return &pb.GameMetrics{
+		Accuracy:          float32(evaluation.RoundAccuracy(metrics.Accuracy)),
+		Acpl:              float32(evaluation.RoundACPL(metrics.ACPL)),
+		Blunders:          int32(metrics.Blunders),
+		Mistakes:          int32(metrics.Mistakes),
+		Inaccuracies:      int32(metrics.Inaccuracies),
+		GoodMoves:         int32(metrics.GoodMoves),
+		ExcellentMoves:    int32(metrics.ExcellentMoves),
+		BestMoves:         int32(metrics.BestMoves),
+		BrilliantMoves:    int32(metrics.BrilliantMoves),
+		BookMoves:         int32(metrics.BookMoves),
+		TotalMoves:        int32(metrics.TotalMoves),
+		PerformanceRating: int32(metrics.PerformanceRating),
+		GarbageTimeMoves:  int32(metrics.GarbageTimeMoves),
+		AccuracyStddev:    float32(evaluation.RoundAccuracy(metrics.AccuracyStddev)),
+		AcplStddev:        float32(evaluation.RoundACPL(metrics.ACPLStddev)),
+		AccuracyModel:     convertAccuracyModel(model),
+		Resilience:        convertResilience(metrics.Resilience),
+
+		ExpectedPointsLost: float32(evaluation.RoundExpectedPoints(metrics.ExpectedPointsLost)),
+	}
+}
@@ -0,0 +1,121 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func pbCentipawns(n int32) *pb.Evaluation {
+	return &pb.Evaluation{Score: &pb.Evaluation_Centipawns{Centipawns: n}}
+}
+
+// storedMoves is a four ply game from the side to move's point of view:
+// White's second move loses 180cp
+func storedMoves() []*pb.StoredMoveEvaluation {
+	cp := pbCentipawns
+	return []*pb.StoredMoveEvaluation{
+		{Ply: 0, Color: "white", EvalBefore: cp(30), EvalAfter: cp(-20), BestMove: true, DepthBefore: 20, DepthAfter: 20},
+		{Ply: 1, Color: "black", EvalBefore: cp(-20), EvalAfter: cp(25), DepthBefore: 20, DepthAfter: 20},
+		{Ply: 2, Color: "white", EvalBefore: cp(25), EvalAfter: cp(155), DepthBefore: 20, DepthAfter: 20},
+		{Ply: 3, Color: "black", EvalBefore: cp(155), EvalAfter: cp(-150), BestMove: true, DepthBefore: 20, DepthAfter: 20},
+	}
+}
+
+func TestRecomputeMetrics(t *testing.T) {
+	// Without an engine pool: recomputing never searches
+	s := NewServer(analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute), nil, zap.NewNop(), 0)
+
+	resp, err := s.RecomputeMetrics(context.Background(), &pb.RecomputeMetricsRequest{Moves: storedMoves(), Result: "0-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.WhiteMetrics.TotalMoves != 2 || resp.BlackMetrics.TotalMoves != 2 {
+		t.Fatalf("total moves = %d/%d, want 2/2", resp.WhiteMetrics.TotalMoves, resp.BlackMetrics.TotalMoves)
+	}
+	if resp.WhiteMetrics.Mistakes != 1 || resp.WhiteMetrics.Acpl != 95 {
+		t.Errorf("white: %d mistakes, ACPL %v, want 1 and 95", resp.WhiteMetrics.Mistakes, resp.WhiteMetrics.Acpl)
+	}
+	if resp.ThresholdProfile != "standard" || resp.Thresholds == nil {
+		t.Errorf("profile = %q, thresholds %v", resp.ThresholdProfile, resp.Thresholds)
+	}
+
+	// The same game from White's point of view scores the same
+	white := storedMoves()
+	for _, m := range white {
+		if m.Color == "black" {
+			m.EvalBefore = pbCentipawns(-m.EvalBefore.GetCentipawns())
+		} else {
+			m.EvalAfter = pbCentipawns(-m.EvalAfter.GetCentipawns())
+		}
+	}
+	whiteResp, err := s.RecomputeMetrics(context.Background(), &pb.RecomputeMetricsRequest{
+		Moves: white, Result: "0-1", EvalPerspective: pb.EvalPerspective_WHITE,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if whiteResp.WhiteMetrics.Acpl != resp.WhiteMetrics.Acpl || whiteResp.BlackMetrics.Accuracy != resp.BlackMetrics.Accuracy {
+		t.Errorf("from White's point of view: %v / %v, want %v / %v",
+			whiteResp.WhiteMetrics, whiteResp.BlackMetrics, resp.WhiteMetrics, resp.BlackMetrics)
+	}
+
+	// Another accuracy model changes accuracy, not the counts
+	lichess, err := s.RecomputeMetrics(context.Background(), &pb.RecomputeMetricsRequest{
+		Moves: storedMoves(), AccuracyModel: pb.AccuracyModel_LICHESS,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lichess.WhiteMetrics.AccuracyModel != pb.AccuracyModel_LICHESS || lichess.WhiteMetrics.Mistakes != 1 {
+		t.Errorf("lichess: %v", lichess.WhiteMetrics)
+	}
+}
+
+func TestRecomputeMetrics_Validation(t *testing.T) {
+	s := NewServer(analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute), nil, zap.NewNop(), 0)
+
+	tests := []struct {
+		name   string
+		modify func(req *pb.RecomputeMetricsRequest)
+		want   string
+	}{
+		{"no moves", func(req *pb.RecomputeMetricsRequest) { req.Moves = nil }, "moves are required"},
+		{"bad offset", func(req *pb.RecomputeMetricsRequest) { req.FenStartOffset = 2 }, "fen_start_offset 2"},
+		{"bad color", func(req *pb.RecomputeMetricsRequest) { req.Moves[1].Color = "red" }, `move 1 (ply 1): color "red"`},
+		{"repeated ply", func(req *pb.RecomputeMetricsRequest) { req.Moves[2].Ply = 1 }, "move 2: ply 1 follows ply 1"},
+		{"same color twice", func(req *pb.RecomputeMetricsRequest) { req.Moves[1].Color = "white" }, "white moves twice in a row"},
+		{"black to move first", func(req *pb.RecomputeMetricsRequest) {
+			for _, m := range req.Moves {
+				m.Ply++
+			}
+		}, "set fen_start_offset 1"},
+		{"missing eval", func(req *pb.RecomputeMetricsRequest) { req.Moves[3].EvalAfter = nil }, "move 3 (ply 3): eval_before and eval_after are required"},
+		{"bad result", func(req *pb.RecomputeMetricsRequest) { req.Result = "1-1" }, `result "1-1"`},
+		{"unknown profile", func(req *pb.RecomputeMetricsRequest) { req.ThresholdProfile = "nope" }, "nope"},
+	}
+	for _, tt := range tests {
+		req := &pb.RecomputeMetricsRequest{Moves: storedMoves()}
+		tt.modify(req)
+		_, err := s.RecomputeMetrics(context.Background(), req)
+		if status.Code(err) != codes.InvalidArgument || !strings.Contains(status.Convert(err).Message(), tt.want) {
+			t.Errorf("%s: %v, want InvalidArgument containing %q", tt.name, err, tt.want)
+		}
+	}
+
+	// Black to move first is fine with the offset set
+	req := &pb.RecomputeMetricsRequest{Moves: storedMoves()[1:], FenStartOffset: 1}
+	for _, m := range req.Moves {
+		m.Ply--
+	}
+	if _, err := s.RecomputeMetrics(context.Background(), req); err != nil {
+		t.Errorf("with fen_start_offset 1: %v", err)
+	}
+}
@@ -2,36 +2,88 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"time"
 
-	"github.com/eloinsight/analysis-service/internal/analyzer"
-	"github.com/eloinsight/analysis-service/internal/engine"
-	"github.com/eloinsight/analysis-service/internal/pool"
+	"github.com/eloinsight/analysis-service/internal/buildinfo"
+	"github.com/eloinsight/analysis-service/internal/store"
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	"github.com/eloinsight/analysis-service/pkg/pool"
 	pb "github.com/eloinsight/analysis-service/proto"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 // Server implements the AnalysisService gRPC server
 type Server struct {
 	pb.UnimplementedAnalysisServiceServer
-	analyzer  *analyzer.Analyzer
-	pool      *pool.Pool
-	logger    *zap.Logger
-	startTime time.Time
+	analyzer          *analyzer.Analyzer
+	pool              *pool.Pool
+	logger            *zap.Logger
+	startTime         time.Time
+	heartbeatInterval time.Duration
+	openingMinGames   int
+	metricsInterval   int
+	progressInterval  time.Duration
+	limits            Limits
+	store             AnalysisStore
+	transcripts       *Transcripts
+}
+
+// AnalysisStore persists completed game analyses for requests that set
+// persist
+type AnalysisStore interface {
+	SaveGameAnalysis(ctx context.Context, a *analyzer.GameAnalysis) (store.Stored, error)
 }
 
 // NewServer creates a new gRPC server
-func NewServer(a *analyzer.Analyzer, p *pool.Pool, logger *zap.Logger) *Server {
+func NewServer(a *analyzer.Analyzer, p *pool.Pool, logger *zap.Logger, heartbeatInterval time.Duration) *Server {
 	return &Server{
-		analyzer:  a,
-		pool:      p,
-		logger:    logger,
-		startTime: time.Now(),
+		analyzer:          a,
+		pool:              p,
+		logger:            logger,
+		startTime:         time.Now(),
+		heartbeatInterval: heartbeatInterval,
+		openingMinGames:   evaluation.DefaultOpeningMinGames,
+		limits:            DefaultLimits(),
 	}
 }
 
+// SetLimits sets the defaults and bounds of request depths and principal
+// variations
+func (s *Server) SetLimits(l Limits) {
+	s.limits = l
+}
+
+// SetOpeningMinGames sets the games an opening needs to be reported by
+// AggregateOpenings when the request doesn't say
+func (s *Server) SetOpeningMinGames(n int) {
+	s.openingMinGames = n
+}
+
+// SetMetricsInterval makes AnalyzeGameStream send the metrics so far every
+// n analyzed moves (0 = only on completion)
+func (s *Server) SetMetricsInterval(n int) {
+	s.metricsInterval = n
+}
+
+// SetProgressInterval makes AnalyzeGameStream hold back progress messages
+// within d of the previous one and merge them into the next, except for
+// mistakes and blunders (0 = send every update)
+func (s *Server) SetProgressInterval(d time.Duration) {
+	s.progressInterval = d
+}
+
+// SetStore enables persist on AnalyzeGame requests; without a store they
+// are rejected
+func (s *Server) SetStore(st AnalysisStore) {
+	s.store = st
+}
+
 // AnalyzePosition analyzes a single FEN position
 func (s *Server) AnalyzePosition(ctx context.Context, req *pb.AnalyzePositionRequest) (*pb.PositionAnalysis, error) {
 	s.logger.Info("AnalyzePosition request",
@@ -42,27 +94,37 @@ func (s *Server) AnalyzePosition(ctx context.Context, req *pb.AnalyzePositionReq
 		return nil, status.Error(codes.InvalidArgument, "FEN is required")
 	}
 
-	depth := int(req.Depth)
-	if depth <= 0 {
-		depth = 20
-	}
-
-	multiPV := int(req.MultiPv)
-	if multiPV <= 0 {
-		multiPV = 1
+	cache, err := toCacheOptions(req.UseCache, req.CacheOnly, req.NoStore)
+	if err != nil {
+		return nil, err
 	}
+	cache.Stale = true // Board positions may be answered while they are refreshed
+	depth := s.limits.depth(req.Depth)
+	multiPV := s.limits.multiPV(req.MultiPv, 1)
 
-	result, err := s.analyzer.AnalyzePosition(ctx, req.Fen, depth, multiPV)
+	result, err := s.analyzer.AnalyzePositionWithCache(ctx, req.Fen, depth, multiPV, cache)
 	if err != nil {
 		s.logger.Error("Analysis failed", zap.Error(err))
-		return nil, status.Errorf(codes.Internal, "analysis failed: %v", err)
+		return nil, toStatus(err, "analysis failed")
 	}
 
 	response := &pb.PositionAnalysis{
-		Fen:      req.Fen,
-		Depth:    int32(result.Depth),
-		BestMove: result.BestMove,
-		TimeMs:   result.TimeMs,
+		Fen:         req.Fen,
+		Depth:       int32(result.Depth),
+		BestMove:    result.BestMove,
+		TimeMs:      result.TimeMs,
+		TargetDepth: int32(depth),
+		MultiPv:     int32(multiPV),
+		TimedOut:    result.Stopped,
+		Source:      result.Source,
+		ServedStale: result.Stale,
+
+		GameOverReason: result.GameOver,
+
+		QueueTimeMs:   result.QueueTimeMs,
+		SearchTimeMs:  result.SearchTimeMs,
+		PoolWaiting:   int32(result.PoolWaiting),
+		PoolAvailable: int32(result.PoolAvailable),
 	}
 
 	if len(result.Evaluations) > 0 {
@@ -72,6 +134,7 @@ func (s *Server) AnalyzePosition(ctx context.Context, req *pb.AnalyzePositionReq
 		response.Nodes = eval.Nodes
 		response.Nps = eval.NPS
 	}
+	response.PonderMoveUci, response.PonderMoveSan = ponderMove(req.Fen, result)
 
 	return response, nil
 }
@@ -86,44 +149,47 @@ func (s *Server) AnalyzePositionStream(req *pb.AnalyzePositionRequest, stream pb
 		return status.Error(codes.InvalidArgument, "FEN is required")
 	}
 
-	maxDepth := int(req.Depth)
-	if maxDepth <= 0 {
-		maxDepth = 20
-	}
-
-	multiPV := int(req.MultiPv)
-	if multiPV <= 0 {
-		multiPV = 1
+	cache, err := toCacheOptions(req.UseCache, req.CacheOnly, req.NoStore)
+	if err != nil {
+		return err
 	}
+	multiPV := s.limits.multiPV(req.MultiPv, 1)
 
 	// Progressive depth analysis
-	depths := []int{8, 12, 16, 20}
-	if maxDepth > 20 {
-		depths = append(depths, maxDepth)
-	}
-
-	for _, depth := range depths {
-		if depth > maxDepth {
-			break
-		}
-
+	for _, depth := range s.limits.depthSteps(s.limits.depth(req.Depth)) {
 		select {
 		case <-stream.Context().Done():
 			return stream.Context().Err()
 		default:
 		}
 
-		result, err := s.analyzer.AnalyzePosition(stream.Context(), req.Fen, depth, multiPV)
+		result, err := s.analyzer.AnalyzePositionWithCache(stream.Context(), req.Fen, depth, multiPV, cache)
 		if err != nil {
+			// Bad input, a cache miss or a gone client won't improve at
+			// the next depth
+			if errors.Is(err, analyzer.ErrInvalidFEN) || errors.Is(err, analyzer.ErrNotCached) || stream.Context().Err() != nil {
+				return toStatus(err, "analysis failed")
+			}
 			s.logger.Warn("Analysis at depth failed", zap.Int("depth", depth), zap.Error(err))
 			continue
 		}
 
 		response := &pb.PositionAnalysis{
-			Fen:      req.Fen,
-			Depth:    int32(result.Depth),
-			BestMove: result.BestMove,
-			TimeMs:   result.TimeMs,
+			Fen:         req.Fen,
+			Depth:       int32(result.Depth),
+			BestMove:    result.BestMove,
+			TimeMs:      result.TimeMs,
+			TargetDepth: int32(depth),
+			MultiPv:     int32(multiPV),
+			TimedOut:    result.Stopped,
+			Source:      result.Source,
+
+			GameOverReason: result.GameOver,
+
+			QueueTimeMs:   result.QueueTimeMs,
+			SearchTimeMs:  result.SearchTimeMs,
+			PoolWaiting:   int32(result.PoolWaiting),
+			PoolAvailable: int32(result.PoolAvailable),
 		}
 
 		if len(result.Evaluations) > 0 {
@@ -133,10 +199,16 @@ func (s *Server) AnalyzePositionStream(req *pb.AnalyzePositionRequest, stream pb
 			response.Nodes = eval.Nodes
 			response.Nps = eval.NPS
 		}
+		response.PonderMoveUci, response.PonderMoveSan = ponderMove(req.Fen, result)
 
 		if err := stream.Send(response); err != nil {
 			return err
 		}
+		// Out of budget at this depth, deeper steps would be too; a
+		// finished game has nothing deeper
+		if result.Stopped || result.GameOver != "" {
+			break
+		}
 	}
 
 	return nil
@@ -151,19 +223,133 @@ func (s *Server) AnalyzeGame(ctx context.Context, req *pb.AnalyzeGameRequest) (*
 	if req.Pgn == "" {
 		return nil, status.Error(codes.InvalidArgument, "PGN is required")
 	}
+	if !validResult(req.Result) {
+		return nil, status.Errorf(codes.InvalidArgument, "result %q must be 1-0, 0-1, 1/2-1/2 or *", req.Result)
+	}
+	if err := s.checkPersist(req); err != nil {
+		return nil, err
+	}
+	if err := validatePreview(req); err != nil {
+		return nil, err
+	}
+	if req.PreviewDepth > 0 {
+		return nil, status.Error(codes.InvalidArgument, "preview_depth is only supported by AnalyzeGameStream")
+	}
 
-	depth := int(req.Depth)
-	if depth <= 0 {
-		depth = 20
+	prefix, err := toPrefixEvaluations(req)
+	if err != nil {
+		return nil, err
 	}
+	cache, err := toCacheOptions(req.UseCache, req.CacheOnly, req.NoStore)
+	if err != nil {
+		return nil, err
+	}
+	if req.TimeBudgetMs < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "time_budget_ms %d is negative", req.TimeBudgetMs)
+	}
+
+	depth := s.limits.depth(req.Depth)
 
-	result, err := s.analyzer.AnalyzeGame(ctx, req.GameId, req.Pgn, depth, nil)
+	opts := analyzer.GameOptions{
+		ThresholdProfile:   req.ThresholdProfile,
+		EngineProfile:      req.EngineProfile,
+		AnalyzeUntilError:  req.AnalyzeUntilError,
+		ExcludeGarbageTime: req.ExcludeGarbageTime,
+		AccuracyModel:      toAccuracyModel(req.AccuracyModel),
+		Result:             req.Result,
+		StartPly:           int(req.StartPly),
+		Prefix:             prefix,
+		Cache:              cache,
+		TimeBudget:         time.Duration(req.TimeBudgetMs) * time.Millisecond,
+		FullDepth:          req.FullDepth,
+	}
+	if err := s.checkGameQuota(ctx, req.Pgn, depth, opts); err != nil {
+		return nil, err
+	}
+	var transcriptJobID string
+	if opts.OnEngineOutput, transcriptJobID, err = s.engineOutput(ctx, req); err != nil {
+		return nil, err
+	}
+	// The estimate as the analysis started goes out in the response header
+	var estimate *analyzer.GameEstimate
+	opts.OnEstimate = func(e analyzer.GameEstimate) {
+		if estimate == nil {
+			estimate = &e
+		}
+	}
+	if req.CrossCheckEngine != "" {
+		check, err := s.analyzer.AnalyzeGameWithEngines(ctx, req.GameId, req.Pgn, depth, opts, req.CrossCheckEngine)
+		if err != nil {
+			s.logger.Error("Cross-check analysis failed", zap.Error(err))
+			return nil, toStatus(err, "cross-check analysis failed")
+		}
+		setEstimateHeader(ctx, estimate)
+
+		result := convertGameAnalysis(check.Primary, req.EvalPerspective)
+		result.TranscriptJobId = transcriptJobID
+		result.CrossCheck = &pb.CrossCheck{
+			Secondary: convertGameAnalysis(check.Secondary, req.EvalPerspective),
+			Diff:      convertAnalysisDiff(&check.Diff),
+		}
+		if req.Persist {
+			if result.Stored, err = s.persist(ctx, check.Primary); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+
+	analysis, err := s.analyzer.AnalyzeGame(ctx, req.GameId, req.Pgn, depth, opts, nil)
 	if err != nil {
 		s.logger.Error("Game analysis failed", zap.Error(err))
-		return nil, status.Errorf(codes.Internal, "game analysis failed: %v", err)
+		return nil, toStatus(err, "game analysis failed")
 	}
+	setEstimateHeader(ctx, estimate)
 
-	return convertGameAnalysis(result), nil
+	result := convertGameAnalysis(analysis, req.EvalPerspective)
+	result.TranscriptJobId = transcriptJobID
+	if req.Persist {
+		if result.Stored, err = s.persist(ctx, analysis); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// checkPersist rejects a persist request the server can't store
+func (s *Server) checkPersist(req *pb.AnalyzeGameRequest) error {
+	if !req.Persist {
+		return nil
+	}
+	if s.store == nil {
+		return status.Error(codes.FailedPrecondition, "persist requested but the Postgres sink is not enabled")
+	}
+	if req.GameId == "" {
+		return status.Error(codes.InvalidArgument, "game ID is required to persist the analysis")
+	}
+	return nil
+}
+
+// persist stores a completed analysis and returns its row IDs
+func (s *Server) persist(ctx context.Context, a *analyzer.GameAnalysis) (*pb.StoredAnalysis, error) {
+	stored, err := s.store.SaveGameAnalysis(ctx, a)
+	if err != nil {
+		s.logger.Error("Failed to store analysis", zap.String("gameId", a.GameID), zap.Error(err))
+		if errors.Is(err, analyzer.ErrChecksumMismatch) {
+			return nil, toStatus(err, "refused to store analysis")
+		}
+		return nil, status.Errorf(codes.Unavailable, "failed to store analysis: %v", err)
+	}
+	if stored.KeptExisting {
+		s.logger.Info("Kept deeper stored analysis",
+			zap.String("gameId", a.GameID),
+			zap.Int("depth", a.Depth))
+	}
+	return &pb.StoredAnalysis{
+		GameRowId:    stored.GameRowID,
+		MoveRowIds:   stored.MoveRowIDs,
+		KeptExisting: stored.KeptExisting,
+	}, nil
 }
 
 // AnalyzeGameStream streams game analysis progress
@@ -175,48 +361,182 @@ func (s *Server) AnalyzeGameStream(req *pb.AnalyzeGameRequest, stream pb.Analysi
 	if req.Pgn == "" {
 		return status.Error(codes.InvalidArgument, "PGN is required")
 	}
+	if !validResult(req.Result) {
+		return status.Errorf(codes.InvalidArgument, "result %q must be 1-0, 0-1, 1/2-1/2 or *", req.Result)
+	}
+	if err := s.checkPersist(req); err != nil {
+		return err
+	}
+	prefix, err := toPrefixEvaluations(req)
+	if err != nil {
+		return err
+	}
+	cache, err := toCacheOptions(req.UseCache, req.CacheOnly, req.NoStore)
+	if err != nil {
+		return err
+	}
+	if req.TimeBudgetMs < 0 {
+		return status.Errorf(codes.InvalidArgument, "time_budget_ms %d is negative", req.TimeBudgetMs)
+	}
+	if err := validatePreview(req); err != nil {
+		return err
+	}
+	if req.CrossCheckEngine != "" {
+		return status.Error(codes.InvalidArgument, "cross_check_engine is only supported by AnalyzeGame")
+	}
+
+	depth := s.limits.depth(req.Depth)
 
-	depth := int(req.Depth)
-	if depth <= 0 {
-		depth = 20
+	// Reject an unknown profile before streaming anything
+	if _, _, err := s.analyzer.Thresholds(req.ThresholdProfile); err != nil {
+		return toStatus(err, "invalid threshold profile")
+	}
+	if err := s.analyzer.CheckEngineProfile(req.EngineProfile); err != nil {
+		return toStatus(err, "invalid engine profile")
 	}
 
 	// Parse to get total moves
-	positions, err := analyzer.ParsePGN(req.Pgn)
-	if err != nil {
-		return status.Errorf(codes.InvalidArgument, "failed to parse PGN: %v", err)
+	positions, err := s.analyzer.ParsePGN(req.Pgn)
+	var moveErr *analyzer.PGNMoveError
+	if err != nil && !(req.AnalyzeUntilError && errors.As(err, &moveErr)) {
+		// AnalyzeGame records the games it parses; this one never gets there
+		s.analyzer.RecordPGNParse(req.Pgn, err)
+		return toStatus(err, "failed to parse PGN")
 	}
 	totalMoves := len(positions) - 1
+	if totalMoves == 0 && moveErr == nil {
+		return toStatus(analyzer.ErrEmptyGame, "failed to parse PGN")
+	}
+	if err := s.analyzer.CheckGameLength(totalMoves); err != nil {
+		return toStatus(err, "game too long")
+	}
+
+	quotaOpts := analyzer.GameOptions{
+		EngineProfile: req.EngineProfile,
+		Prefix:        prefix,
+		Cache:         cache,
+		TimeBudget:    time.Duration(req.TimeBudgetMs) * time.Millisecond,
+		FullDepth:     req.FullDepth,
+		PreviewDepth:  int(req.PreviewDepth),
+	}
+	if err := s.checkGameQuota(stream.Context(), req.Pgn, depth, quotaOpts); err != nil {
+		return err
+	}
+	onEngineOutput, transcriptJobID, err := s.engineOutput(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	sender := newProgressSender(stream, &pb.GameAnalysisProgress{
+		GameId:     req.GameId,
+		TotalMoves: int32(totalMoves),
+		Status:     "analyzing",
+	}, s.heartbeatInterval, s.progressInterval, s.pool.Waiting, s.logger)
 
+	// The analyzer calls back from one goroutine, so the chunk counts and
+	// the estimate are only touched there
+	var lastMove, chunksCompleted, chunksTotal int
+	var estimate *pb.GameEstimate
 	callback := func(current, total int, move *analyzer.MoveAnalysis) {
+		lastMove = current
 		progress := &pb.GameAnalysisProgress{
 			GameId:          req.GameId,
 			CurrentMove:     int32(current),
 			TotalMoves:      int32(total),
-			ProgressPercent: float32(current) / float32(total) * 100,
+			ProgressPercent: progressPercent(current, total),
 			Status:          "analyzing",
+			ChunksCompleted: int32(chunksCompleted),
+			ChunksTotal:     int32(chunksTotal),
+			Estimate:        estimate,
 		}
 
 		if move != nil {
-			progress.MoveAnalysis = convertMoveAnalysis(move)
+			progress.MoveAnalysis = convertMoveAnalysis(move, req.EvalPerspective)
 		}
 
-		if err := stream.Send(progress); err != nil {
-			s.logger.Warn("Failed to send progress", zap.Error(err))
-		}
+		sender.Send(progress)
 	}
 
-	result, err := s.analyzer.AnalyzeGame(stream.Context(), req.GameId, req.Pgn, depth, callback)
+	opts := analyzer.GameOptions{
+		ThresholdProfile:   req.ThresholdProfile,
+		EngineProfile:      req.EngineProfile,
+		AnalyzeUntilError:  req.AnalyzeUntilError,
+		ExcludeGarbageTime: req.ExcludeGarbageTime,
+		AccuracyModel:      toAccuracyModel(req.AccuracyModel),
+		Result:             req.Result,
+		StartPly:           int(req.StartPly),
+		Prefix:             prefix,
+		Cache:              cache,
+		TimeBudget:         time.Duration(req.TimeBudgetMs) * time.Millisecond,
+		FullDepth:          req.FullDepth,
+		PreviewDepth:       int(req.PreviewDepth),
+		OnEngineOutput:     onEngineOutput,
+		MetricsInterval:    s.metricsInterval,
+		OnMetrics: func(analyzed int, white, black analyzer.GameMetrics) {
+			sender.Send(&pb.GameAnalysisProgress{
+				GameId:          req.GameId,
+				CurrentMove:     int32(analyzed),
+				TotalMoves:      int32(totalMoves),
+				ProgressPercent: progressPercent(analyzed, totalMoves),
+				Status:          "analyzing",
+				WhiteMetrics:    convertGameMetrics(&white),
+				BlackMetrics:    convertGameMetrics(&black),
+				ChunksCompleted: int32(chunksCompleted),
+				ChunksTotal:     int32(chunksTotal),
+				Estimate:        estimate,
+			})
+		},
+		OnChunk: func(completed, total int) {
+			chunksCompleted, chunksTotal = completed, total
+			sender.Send(&pb.GameAnalysisProgress{
+				GameId:          req.GameId,
+				CurrentMove:     int32(lastMove),
+				TotalMoves:      int32(totalMoves),
+				ProgressPercent: progressPercent(lastMove, totalMoves),
+				Status:          "analyzing",
+				ChunksCompleted: int32(completed),
+				ChunksTotal:     int32(total),
+				Estimate:        estimate,
+			})
+		},
+		OnEstimate: func(e analyzer.GameEstimate) {
+			// Sent on its own before the searches start, then with the
+			// next progress message
+			first := estimate == nil
+			estimate = convertGameEstimate(e)
+			if first {
+				sender.Send(&pb.GameAnalysisProgress{
+					GameId:     req.GameId,
+					TotalMoves: int32(totalMoves),
+					Status:     "analyzing",
+					Estimate:   estimate,
+				})
+			}
+		},
+		OnPreview: func(preview *analyzer.GameAnalysis) {
+			// The deep pass starts over, from the first move
+			sender.Send(&pb.GameAnalysisProgress{
+				GameId:       req.GameId,
+				TotalMoves:   int32(totalMoves),
+				Status:       "analyzing",
+				Preliminary:  true,
+				Analysis:     convertGameAnalysis(preview, req.EvalPerspective),
+				WhiteMetrics: convertGameMetrics(&preview.WhiteMetrics),
+				BlackMetrics: convertGameMetrics(&preview.BlackMetrics),
+			})
+		},
+	}
+	result, err := s.analyzer.AnalyzeGame(stream.Context(), req.GameId, req.Pgn, depth, opts, callback)
 	if err != nil {
 		// Send error status
-		stream.Send(&pb.GameAnalysisProgress{
+		sender.Finish(&pb.GameAnalysisProgress{
 			GameId:       req.GameId,
 			CurrentMove:  int32(totalMoves),
 			TotalMoves:   int32(totalMoves),
 			Status:       "error",
 			ErrorMessage: err.Error(),
 		})
-		return status.Errorf(codes.Internal, "game analysis failed: %v", err)
+		return toStatus(err, "game analysis failed")
 	}
 
 	// Send completed status with final analysis
@@ -226,15 +546,40 @@ func (s *Server) AnalyzeGameStream(req *pb.AnalyzeGameRequest, stream pb.Analysi
 		TotalMoves:      int32(totalMoves),
 		ProgressPercent: 100,
 		Status:          "completed",
+		TimedOut:        result.TimedOut,
+		ChunksCompleted: int32(chunksCompleted),
+		ChunksTotal:     int32(chunksTotal),
+		Estimate:        estimate,
+		WhiteMetrics:    convertGameMetrics(&result.WhiteMetrics),
+		BlackMetrics:    convertGameMetrics(&result.BlackMetrics),
+		TranscriptJobId: transcriptJobID,
 	}
 
 	// Include the last move if available
 	if len(result.Moves) > 0 {
 		lastMove := result.Moves[len(result.Moves)-1]
-		finalProgress.MoveAnalysis = convertMoveAnalysis(&lastMove)
+		finalProgress.MoveAnalysis = convertMoveAnalysis(&lastMove, req.EvalPerspective)
+	}
+	// A client that asked for a preview gets the final analysis the same
+	// way, as does one that persists it, with the stored row IDs
+	if req.PreviewDepth > 0 || req.Persist {
+		finalProgress.Preliminary = result.Preliminary
+		finalProgress.Analysis = convertGameAnalysis(result, req.EvalPerspective)
+	}
+	if req.Persist && !result.Preliminary {
+		if finalProgress.Analysis.Stored, err = s.persist(stream.Context(), result); err != nil {
+			sender.Finish(&pb.GameAnalysisProgress{
+				GameId:       req.GameId,
+				CurrentMove:  int32(totalMoves),
+				TotalMoves:   int32(totalMoves),
+				Status:       "error",
+				ErrorMessage: status.Convert(err).Message(),
+			})
+			return err
+		}
 	}
 
-	return stream.Send(finalProgress)
+	return sender.Finish(finalProgress)
 }
 
 // GetBestMoves returns multiple best moves for a position
@@ -248,58 +593,140 @@ func (s *Server) GetBestMoves(ctx context.Context, req *pb.GetBestMovesRequest)
 		return nil, status.Error(codes.InvalidArgument, "FEN is required")
 	}
 
-	count := int(req.Count)
-	if count <= 0 {
-		count = 3
-	}
+	count := s.limits.multiPV(req.Count, s.limits.DefaultBestMoves)
+	depth := s.limits.depth(req.Depth)
 
-	depth := int(req.Depth)
-	if depth <= 0 {
-		depth = 20
-	}
-
-	evals, err := s.analyzer.GetBestMoves(ctx, req.Fen, count, depth)
+	result, err := s.analyzer.GetBestMoves(ctx, req.Fen, count, depth)
 	if err != nil {
 		s.logger.Error("GetBestMoves failed", zap.Error(err))
-		return nil, status.Errorf(codes.Internal, "analysis failed: %v", err)
+		return nil, toStatus(err, "analysis failed")
 	}
 
+	response := convertBestMoves(req.Fen, result)
+	response.TargetDepth = int32(depth)
+	response.Count = int32(count)
+	return response, nil
+}
+
+// convertBestMoves converts a multi-PV result to proto, ranking each move
+// by its PV index
+func convertBestMoves(fen string, result *engine.AnalysisResult) *pb.BestMovesResponse {
 	response := &pb.BestMovesResponse{
-		Fen:   req.Fen,
-		Depth: int32(depth),
-		Moves: make([]*pb.BestMove, 0, len(evals)),
+		Fen:      fen,
+		Depth:    int32(result.Depth),
+		Moves:    make([]*pb.BestMove, 0, len(result.Evaluations)),
+		TimedOut: result.Stopped,
+		PvCount:  int32(result.PVCount),
+
+		GameOverReason: result.GameOver,
+	}
+	for _, note := range result.Notes {
+		response.Notes = append(response.Notes, string(note))
 	}
 
-	for i, eval := range evals {
+	for i, eval := range result.Evaluations {
+		rank := i + 1
+		if eval.MultiPV > 0 {
+			rank = eval.MultiPV
+		}
 		bestMove := &pb.BestMove{
-			Rank:       int32(i + 1),
+			Rank:       int32(rank),
 			MoveUci:    "",
 			Evaluation: convertEvaluation(&eval),
 			Pv:         eval.PV,
+
+			TransposesToRank: int32(eval.TransposesTo),
 		}
 		if len(eval.PV) > 0 {
-			bestMove.MoveUci = eval.PV[0]
+			// Promotions keep their piece in both notations, e.g. e7e8n and e8=N+
+			bestMove.MoveUci = analyzer.NormalizeUCI(eval.PV[0])
+			if san, err := analyzer.UCIToSAN(fen, bestMove.MoveUci); err == nil {
+				bestMove.MoveSan = san
+			}
 		}
 		response.Moves = append(response.Moves, bestMove)
 	}
+	response.PonderMoveUci, response.PonderMoveSan = ponderMove(fen, result)
 
-	return response, nil
+	return response
+}
+
+// ponderMove returns result's ponder move for fen in UCI and SAN, the SAN
+// left empty when the move isn't legal after the best move
+func ponderMove(fen string, result *engine.AnalysisResult) (uci, san string) {
+	if result.PonderMove == "" {
+		return "", ""
+	}
+	uci = analyzer.NormalizeUCI(result.PonderMove)
+	if next, err := analyzer.PlayUCI(fen, result.BestMove); err == nil {
+		san, _ = analyzer.UCIToSAN(next, uci)
+	}
+	return uci, san
 }
 
 // HealthCheck returns the service health status
 func (s *Server) HealthCheck(ctx context.Context, req *pb.HealthCheckRequest) (*pb.HealthCheckResponse, error) {
 	stats := s.pool.GetStats()
+	degradation := s.analyzer.Degradation()
 
+	healthStatus := "ok"
+	if degradation.Active {
+		healthStatus = "degraded"
+	}
 	return &pb.HealthCheckResponse{
-		Healthy:           stats.Available > 0,
-		Status:            "ok",
-		AvailableWorkers:  int32(stats.Available),
-		TotalWorkers:      int32(stats.Size),
-		StockfishVersion:  stats.StockfishVersion,
-		UptimeSeconds:     int64(stats.Uptime.Seconds()),
+		Healthy:          stats.Available > 0,
+		Status:           healthStatus,
+		AvailableWorkers: int32(stats.Available),
+		TotalWorkers:     int32(stats.Size),
+		StockfishVersion: stats.StockfishVersion,
+		UptimeSeconds:    int64(stats.Uptime.Seconds()),
+		Degraded:         degradation.Active,
+		PoolWaitP95Ms:    degradation.PoolWaitP95.Milliseconds(),
 	}, nil
 }
 
+// GetServiceInfo returns build and configuration info of the running service
+func (s *Server) GetServiceInfo(ctx context.Context, req *pb.GetServiceInfoRequest) (*pb.ServiceInfo, error) {
+	stats := s.pool.GetStats()
+	profiles, defaultProfile := s.analyzer.ThresholdProfiles()
+	maxPlies, fastPlies, fastDepth := s.analyzer.GameLengthLimits()
+
+	info := &pb.ServiceInfo{
+		GitSha:                  buildinfo.GitSHA,
+		BuildTime:               buildinfo.BuildTime,
+		GoVersion:               buildinfo.GoVersion(),
+		StockfishVersion:        stats.StockfishVersion,
+		NnueNet:                 stats.NNUENet,
+		DefaultDepth:            int32(s.limits.DefaultDepth),
+		MaxDepth:                int32(s.limits.MaxDepth),
+		Thresholds:              convertThresholds(profiles[defaultProfile]),
+		DefaultThresholdProfile: defaultProfile,
+		ThresholdProfiles:       make(map[string]*pb.ClassificationThresholds, len(profiles)),
+		EngineProfiles:          s.analyzer.EngineProfiles(),
+		MaxGamePlies:            int32(maxPlies),
+		FastModePlies:           int32(fastPlies),
+		FastModeDepth:           int32(fastDepth),
+		StockfishPath:           s.pool.Config().BinaryPath,
+	}
+	for name, t := range profiles {
+		info.ThresholdProfiles[name] = convertThresholds(t)
+	}
+	return info, nil
+}
+
+// convertThresholds converts classification thresholds to proto
+func convertThresholds(t evaluation.Thresholds) *pb.ClassificationThresholds {
+	return &pb.ClassificationThresholds{
+		Best:        int32(t.Best),
+		Excellent:   int32(t.Excellent),
+		Good:        int32(t.Good),
+		Inaccuracy:  int32(t.Inaccuracy),
+		Mistake:     int32(t.Mistake),
+		GarbageWin:  int32(t.GarbageWin),
+		GarbageLoss: int32(t.GarbageLoss),
+	}
+}
+
 // convertEvaluation converts engine evaluation to proto
 func convertEvaluation(eval *engine.Evaluation) *pb.Evaluation {
 	pbEval := &pb.Evaluation{
@@ -315,25 +742,204 @@ func convertEvaluation(eval *engine.Evaluation) *pb.Evaluation {
 	return pbEval
 }
 
-// convertMoveAnalysis converts analyzer move to proto
-func convertMoveAnalysis(move *analyzer.MoveAnalysis) *pb.MoveAnalysis {
+// perspectiveEvaluations returns the evaluations before and after move in
+// perspective. The analyzer keeps them from the side to move, the mover
+// before the move and the opponent after it; converting back uses the
+// same signs.
+func perspectiveEvaluations(move *analyzer.MoveAnalysis, perspective pb.EvalPerspective) (before, after engine.Evaluation) {
+	before, after = move.EvalBefore, move.EvalAfter
+	if perspective != pb.EvalPerspective_WHITE {
+		return before, after
+	}
+	if move.Color == "black" {
+		return negateEvaluation(before), after
+	}
+	return before, negateEvaluation(after)
+}
+
+// negateEvaluation returns eval from the other side's point of view
+func negateEvaluation(eval engine.Evaluation) engine.Evaluation {
+	eval.Centipawns = -eval.Centipawns
+	if eval.MateIn != nil {
+		mateIn := -*eval.MateIn
+		eval.MateIn = &mateIn
+	}
+	return eval
+}
+
+// normalizePerspective resolves the default perspective
+func normalizePerspective(perspective pb.EvalPerspective) pb.EvalPerspective {
+	if perspective == pb.EvalPerspective_WHITE {
+		return perspective
+	}
+	return pb.EvalPerspective_SIDE_TO_MOVE
+}
+
+// toAccuracyModel converts a requested accuracy model. Unknown values are
+// passed on by name for the analyzer to reject.
+func toAccuracyModel(model pb.AccuracyModel) evaluation.AccuracyModel {
+	switch model {
+	case pb.AccuracyModel_ACCURACY_MODEL_UNSPECIFIED:
+		return ""
+	case pb.AccuracyModel_ELOINSIGHT:
+		return evaluation.AccuracyModelEloInsight
+	case pb.AccuracyModel_LICHESS:
+		return evaluation.AccuracyModelLichess
+	case pb.AccuracyModel_CHESSCOM_APPROX:
+		return evaluation.AccuracyModelChessComApprox
+	default:
+		return evaluation.AccuracyModel(model.String())
+	}
+}
+
+// convertAccuracyModel converts the model metrics were scored with
+func convertAccuracyModel(model evaluation.AccuracyModel) pb.AccuracyModel {
+	switch model {
+	case evaluation.AccuracyModelEloInsight:
+		return pb.AccuracyModel_ELOINSIGHT
+	case evaluation.AccuracyModelLichess:
+		return pb.AccuracyModel_LICHESS
+	case evaluation.AccuracyModelChessComApprox:
+		return pb.AccuracyModel_CHESSCOM_APPROX
+	default:
+		return pb.AccuracyModel_ACCURACY_MODEL_UNSPECIFIED
+	}
+}
+
+// convertMoveAnalysis converts analyzer move to proto, with evaluations
+// in perspective
+func convertMoveAnalysis(move *analyzer.MoveAnalysis, perspective pb.EvalPerspective) *pb.MoveAnalysis {
+	evalBefore, evalAfter := perspectiveEvaluations(move, perspective)
+	glyph, color, label := evaluation.ClassificationDisplay(evaluation.MoveClassification(move.Classification))
 	return &pb.MoveAnalysis{
-		MoveNumber:      int32(move.MoveNumber),
-		Ply:             int32(move.Ply),
-		Color:           move.Color,
-		PlayedMove:      move.PlayedMove,
-		PlayedMoveUci:   move.PlayedMoveUCI,
-		BestMove:        move.BestMove,
-		BestMoveUci:     move.BestMoveUCI,
-		FenBefore:       move.FENBefore,
-		FenAfter:        move.FENAfter,
-		EvalBefore:      convertEvaluation(&move.EvalBefore),
-		EvalAfter:       convertEvaluation(&move.EvalAfter), // FIX: Was missing - now sending evaluation after move
-		CentipawnLoss:   int32(move.CentipawnLoss),
-		Classification:  convertClassification(move.Classification),
-		Pv:              move.PV,
-		Depth:           int32(move.Depth),
+		MoveNumber:     int32(move.MoveNumber),
+		Ply:            int32(move.Ply),
+		Color:          move.Color,
+		PlayedMove:     move.PlayedMove,
+		PlayedMoveUci:  move.PlayedMoveUCI,
+		BestMove:       move.BestMove,
+		BestMoveUci:    move.BestMoveUCI,
+		FenBefore:      move.FENBefore,
+		FenAfter:       move.FENAfter,
+		EvalBefore:     convertEvaluation(&evalBefore),
+		EvalAfter:      convertEvaluation(&evalAfter), // FIX: Was missing - now sending evaluation after move
+		CentipawnLoss:  int32(move.CentipawnLoss),
+		Classification: convertClassification(move.Classification),
+		Pv:             move.PV,
+
+		ClassificationGlyph: glyph,
+		ClassificationLabel: label,
+		ClassificationColor: color,
+
+		Depth:          int32(move.AchievedDepth),
+		MoveAccuracy:   float32(evaluation.RoundAccuracy(move.MoveAccuracy)),
+		Forced:         move.Forced,
+		RequestedDepth: int32(move.RequestedDepth),
+		FromCache:      move.FromCache,
+		Source:         analysisSources[move.Source],
+		GarbageTime:    move.GarbageTime,
+
+		ExpectedPointsLoss: float32(evaluation.RoundExpectedPoints(move.ExpectedPointsLoss)),
+		Highlights:         convertHighlights(analyzer.MoveHighlights(move)),
+
+		MissedRepetition:  move.MissedRepetition,
+		AllowedRepetition: move.AllowedRepetition,
+
+		MaterialBefore:     convertMaterial(move.MaterialBefore),
+		MaterialAfter:      convertMaterial(move.MaterialAfter),
+		MaterialSacrificed: int32(move.MaterialSacrificed),
+		Explanation:        move.Explanation,
+
+		AnalyzedAtUnixMs: move.AnalyzedAt,
+		EngineTimeMs:     move.EngineTimeMs,
+		QueueTimeMs:      move.QueueTimeMs,
+		SearchTimeMs:     move.SearchTimeMs,
+		MovetimeMs:       move.MovetimeMs,
+	}
+}
+
+// convertHighlights converts a move's squares to highlight to proto
+func convertHighlights(h analyzer.Highlights) *pb.Highlights {
+	return &pb.Highlights{
+		BestMove:      convertMoveSquares(h.BestMove),
+		Refutation:    convertMoveSquares(h.Refutation),
+		HangingSquare: h.Hanging,
+	}
+}
+
+// convertMoveSquares converts a move's squares to proto, nil without a move
+func convertMoveSquares(s *analyzer.MoveSquares) *pb.MoveSquares {
+	if s == nil {
+		return nil
+	}
+	return &pb.MoveSquares{
+		From:      s.From,
+		To:        s.To,
+		Promotion: s.Promotion,
+		RookFrom:  s.RookFrom,
+		RookTo:    s.RookTo,
+		Captured:  s.Captured,
+	}
+}
+
+// convertDiagnostics converts a game's engine failures to proto
+func convertDiagnostics(d analyzer.Diagnostics) *pb.AnalysisDiagnostics {
+	result := &pb.AnalysisDiagnostics{Retries: int32(d.Retries)}
+	if len(d.Failures) > 0 {
+		result.Failures = make(map[string]int32, len(d.Failures))
+		for kind, n := range d.Failures {
+			result.Failures[string(kind)] = int32(n)
+		}
+	}
+	for _, index := range d.FailedPositions {
+		result.FailedPositions = append(result.FailedPositions, int32(index))
+	}
+	return result
+}
+
+// convertConfigSnapshot converts a game's engine settings to proto, nil
+// when it has none
+func convertConfigSnapshot(c *analyzer.AnalysisConfigSnapshot) *pb.AnalysisConfigSnapshot {
+	if c == nil {
+		return nil
+	}
+	return &pb.AnalysisConfigSnapshot{
+		Threads:         int32(c.Threads),
+		HashMb:          int32(c.HashMB),
+		MultiPv:         int32(c.MultiPV),
+		Depth:           int32(c.Depth),
+		NodesLimit:      c.NodesLimit,
+		MovetimeMs:      c.MovetimeMs,
+		NnueNet:         c.NNUENet,
+		CacheHitPercent: float32(c.CacheHitPercent),
+		Deterministic:   c.Deterministic,
+	}
+}
+
+// convertGameInfo converts a game's PGN tags to proto, leaving unknown
+// ratings unset
+func convertGameInfo(info analyzer.GameInfo) *pb.GameInfo {
+	result := &pb.GameInfo{
+		White:       info.White,
+		Black:       info.Black,
+		Result:      info.Result,
+		TimeControl: info.TimeControl,
+		Date:        info.Date,
+	}
+	if info.WhiteElo > 0 {
+		elo := int32(info.WhiteElo)
+		result.WhiteElo = &elo
 	}
+	if info.BlackElo > 0 {
+		elo := int32(info.BlackElo)
+		result.BlackElo = &elo
+	}
+	return result
+}
+
+// convertMaterial converts material counts to proto
+func convertMaterial(m analyzer.Material) *pb.Material {
+	return &pb.Material{White: int32(m.White), Black: int32(m.Black)}
 }
 
 // convertClassification converts analyzer classification to proto enum
@@ -366,29 +972,153 @@ func convertClassification(class analyzer.MoveClassification) pb.MoveClassificat
 	}
 }
 
-// convertGameAnalysis converts analyzer result to proto
-func convertGameAnalysis(analysis *analyzer.GameAnalysis) *pb.GameAnalysis {
+// analysisSources are the proto enum values of the analyzer's sources
+var analysisSources = map[analyzer.AnalysisSource]pb.AnalysisSource{
+	analyzer.PlyEngine:        pb.AnalysisSource_ENGINE,
+	analyzer.PlyCache:         pb.AnalysisSource_CACHE,
+	analyzer.PlyImported:      pb.AnalysisSource_IMPORTED,
+	analyzer.PlyBookSkipped:   pb.AnalysisSource_BOOK_SKIPPED,
+	analyzer.PlyForcedSkipped: pb.AnalysisSource_FORCED_SKIPPED,
+	analyzer.PlyFailed:        pb.AnalysisSource_FAILED,
+	analyzer.PlyOutOfRange:    pb.AnalysisSource_OUT_OF_RANGE,
+}
+
+// terminations are the proto enum values of the analyzer's terminations
+var terminations = map[analyzer.Termination]pb.Termination{
+	analyzer.TerminationCheckmate:            pb.Termination_CHECKMATE,
+	analyzer.TerminationResignation:          pb.Termination_RESIGNATION,
+	analyzer.TerminationTimeForfeit:          pb.Termination_TIME_FORFEIT,
+	analyzer.TerminationAbandonment:          pb.Termination_ABANDONMENT,
+	analyzer.TerminationStalemate:            pb.Termination_STALEMATE,
+	analyzer.TerminationInsufficientMaterial: pb.Termination_INSUFFICIENT_MATERIAL,
+	analyzer.TerminationRepetition:           pb.Termination_REPETITION,
+	analyzer.TerminationFiftyMoveRule:        pb.Termination_FIFTY_MOVE_RULE,
+	analyzer.TerminationAgreement:            pb.Termination_AGREEMENT,
+}
+
+// convertSourceCounts converts a game's plies by source to proto, nil
+// when none were counted
+func convertSourceCounts(counts map[analyzer.AnalysisSource]int) map[string]int32 {
+	if len(counts) == 0 {
+		return nil
+	}
+	result := make(map[string]int32, len(counts))
+	for source, n := range counts {
+		result[string(source)] = int32(n)
+	}
+	return result
+}
+
+// convertGameAnalysis converts analyzer result to proto, with move
+// evaluations in perspective
+func convertGameAnalysis(analysis *analyzer.GameAnalysis, perspective pb.EvalPerspective) *pb.GameAnalysis {
+	perspective = normalizePerspective(perspective)
 	result := &pb.GameAnalysis{
 		GameId:        analysis.GameID,
+		GameInfo:      convertGameInfo(analysis.Info),
 		TotalTimeMs:   analysis.TotalTimeMs,
 		EngineVersion: analysis.EngineVersion,
 		WhiteMetrics:  convertGameMetrics(&analysis.WhiteMetrics),
 		BlackMetrics:  convertGameMetrics(&analysis.BlackMetrics),
 		Moves:         make([]*pb.MoveAnalysis, 0, len(analysis.Moves)),
+
+		ThresholdProfile:       analysis.ThresholdProfile,
+		Thresholds:             convertThresholds(analysis.Thresholds),
+		Depth:                  int32(analysis.Depth),
+		TimedOut:               analysis.TimedOut,
+		FastMode:               analysis.FastMode,
+		Degraded:               analysis.Degraded,
+		TotalMoves:             int32(analysis.TotalMoves),
+		EngineProfile:          analysis.EngineProfile,
+		Truncated:              analysis.Truncated,
+		TruncatedAtPly:         int32(analysis.TruncatedAtPly),
+		TruncationError:        analysis.TruncationError,
+		Termination:            terminations[analysis.Termination],
+		WinnerColor:            analysis.WinnerColor,
+		ResultAgainstRunOfPlay: analysis.ResultAgainstRunOfPlay,
+		ResultSummary:          analysis.ResultSummary(),
+		Summary:                analysis.Summary,
+		EvalPerspective:        perspective,
+		WhiteTime:              convertTimeManagement(analysis.WhiteTime),
+		BlackTime:              convertTimeManagement(analysis.BlackTime),
+
+		StartedAtUnixMs:   analysis.StartedAt,
+		CompletedAtUnixMs: analysis.CompletedAt,
+		CacheCoverage:     float32(analysis.CacheCoverage),
+		Diagnostics:       convertDiagnostics(analysis.Diagnostics),
+		TimeBudgetMs:      analysis.TimeBudgetMs,
+		BudgetUsedMs:      analysis.BudgetUsedMs,
+		BudgetUtilization: float32(analysis.BudgetUtilization),
+		Checksum:          analysis.Checksum,
+		Config:            convertConfigSnapshot(analysis.Config),
+		SourceCounts:      convertSourceCounts(analysis.SourceCounts),
+		Preliminary:       analysis.Preliminary,
+		PreviewKept:       int32(analysis.PreviewKept),
 	}
 
 	for _, move := range analysis.Moves {
-		result.Moves = append(result.Moves, convertMoveAnalysis(&move))
+		result.Moves = append(result.Moves, convertMoveAnalysis(&move, perspective))
 	}
 
 	return result
 }
 
+// convertAnalysisDiff converts a cross-check diff to proto
+func convertAnalysisDiff(diff *analyzer.AnalysisDiff) *pb.AnalysisDiff {
+	result := &pb.AnalysisDiff{
+		EngineA:             diff.EngineA,
+		EngineB:             diff.EngineB,
+		ComparedMoves:       int32(diff.ComparedMoves),
+		ClassificationDiffs: int32(diff.ClassificationDiffs),
+		CentipawnLossDiffs:  int32(diff.CentipawnLossDiffs),
+		WhiteAccuracyDelta:  float32(evaluation.RoundAccuracy(diff.WhiteAccuracyDelta)),
+		BlackAccuracyDelta:  float32(evaluation.RoundAccuracy(diff.BlackAccuracyDelta)),
+		BestMoveDiffs:       int32(diff.BestMoveDiffs),
+		DepthA:              int32(diff.DepthA),
+		DepthB:              int32(diff.DepthB),
+		WhiteDelta:          convertMetricsDelta(diff.White),
+		BlackDelta:          convertMetricsDelta(diff.Black),
+		Moves:               make([]*pb.MoveDiff, 0, len(diff.Moves)),
+	}
+	for _, move := range diff.Moves {
+		result.Moves = append(result.Moves, &pb.MoveDiff{
+			Ply:             int32(move.Ply),
+			MoveNumber:      int32(move.MoveNumber),
+			Color:           move.Color,
+			PlayedMove:      move.PlayedMove,
+			ClassificationA: convertClassification(move.ClassificationA),
+			ClassificationB: convertClassification(move.ClassificationB),
+			CentipawnLossA:  int32(move.CentipawnLossA),
+			CentipawnLossB:  int32(move.CentipawnLossB),
+			BestMoveA:       move.BestMoveA,
+			BestMoveB:       move.BestMoveB,
+		})
+	}
+	return result
+}
+
+func convertMetricsDelta(delta analyzer.MetricsDelta) *pb.MetricsDelta {
+	return &pb.MetricsDelta{
+		Accuracy:          float32(evaluation.RoundAccuracy(delta.Accuracy)),
+		Acpl:              float32(evaluation.RoundACPL(delta.ACPL)),
+		Blunders:          int32(delta.Blunders),
+		Mistakes:          int32(delta.Mistakes),
+		Inaccuracies:      int32(delta.Inaccuracies),
+		PerformanceRating: int32(delta.PerformanceRating),
+	}
+}
+
+// EncodeGameAnalysis serializes a game analysis as the JSON form of the
+// GameAnalysis message, for publishing outside of gRPC
+func EncodeGameAnalysis(analysis *analyzer.GameAnalysis) ([]byte, error) {
+	return protojson.Marshal(convertGameAnalysis(analysis, pb.EvalPerspective_SIDE_TO_MOVE))
+}
+
 // convertGameMetrics converts analyzer metrics to proto
 func convertGameMetrics(metrics *analyzer.GameMetrics) *pb.GameMetrics {
 	return &pb.GameMetrics{
-		Accuracy:          float32(metrics.Accuracy),
-		Acpl:              float32(metrics.ACPL),
+		Accuracy:          float32(evaluation.RoundAccuracy(metrics.Accuracy)),
+		Acpl:              float32(evaluation.RoundACPL(metrics.ACPL)),
 		Blunders:          int32(metrics.Blunders),
 		Mistakes:          int32(metrics.Mistakes),
 		Inaccuracies:      int32(metrics.Inaccuracies),
@@ -399,5 +1129,107 @@ func convertGameMetrics(metrics *analyzer.GameMetrics) *pb.GameMetrics {
 		BookMoves:         int32(metrics.BookMoves),
 		TotalMoves:        int32(metrics.TotalMoves),
 		PerformanceRating: int32(metrics.PerformanceRating),
+		GarbageTimeMoves:  int32(metrics.GarbageTimeMoves),
+		AccuracyStddev:    float32(evaluation.RoundAccuracy(metrics.AccuracyStddev)),
+		AcplStddev:        float32(evaluation.RoundACPL(metrics.ACPLStddev)),
+		AccuracyModel:     convertAccuracyModel(metrics.AccuracyModel),
+		Resilience:        convertResilience(metrics.Resilience),
+
+		ExpectedPointsLost: float32(evaluation.RoundExpectedPoints(metrics.ExpectedPointsLost)),
+	}
+}
+
+// convertTimeManagement converts time management to proto, nil without
+// clock data
+func convertTimeManagement(tm *analyzer.TimeManagement) *pb.TimeManagement {
+	if tm == nil {
+		return nil
+	}
+	result := &pb.TimeManagement{
+		TimeClass:           tm.TimeClass,
+		ScrambleThresholdMs: tm.ScrambleThresholdMs,
+		ScrambleStartPly:    int32(tm.ScrambleStartPly),
+		ScrambleMoves:       int32(tm.ScrambleMoves),
+		ScrambleErrorRate:   float32(tm.ScrambleErrorRate),
+		OtherErrorRate:      float32(tm.OtherErrorRate),
+		ThinkingTimeMs:      tm.ThinkingTimeMs,
+		CpLossPerSecond:     float32(tm.CPLossPerSecond),
+	}
+	for _, ply := range tm.ScrambleBlunders {
+		result.ScrambleBlunders = append(result.ScrambleBlunders, int32(ply))
+	}
+	return result
+}
+
+// validResult reports whether result is empty or a PGN game result
+func validResult(result string) bool {
+	switch result {
+	case "", "1-0", "0-1", "1/2-1/2", "*":
+		return true
+	}
+	return false
+}
+
+// toCacheOptions converts a request's cache flags, rejecting cache_only
+// with use_cache false
+func toCacheOptions(useCache *bool, cacheOnly, noStore bool) (analyzer.CacheOptions, error) {
+	bypass := useCache != nil && !*useCache
+	if bypass && cacheOnly {
+		return analyzer.CacheOptions{}, status.Error(codes.InvalidArgument, "cache_only can't be combined with use_cache false")
+	}
+	return analyzer.CacheOptions{Bypass: bypass, Only: cacheOnly, NoStore: noStore}, nil
+}
+
+// validatePreview rejects a preview_depth that is negative or comes with
+// cache_only or a time budget, which don't search to a depth
+func validatePreview(req *pb.AnalyzeGameRequest) error {
+	switch {
+	case req.PreviewDepth < 0:
+		return status.Errorf(codes.InvalidArgument, "preview_depth %d is negative", req.PreviewDepth)
+	case req.PreviewDepth > 0 && req.CacheOnly:
+		return status.Error(codes.InvalidArgument, "preview_depth can't be combined with cache_only")
+	case req.PreviewDepth > 0 && req.TimeBudgetMs > 0:
+		return status.Error(codes.InvalidArgument, "preview_depth can't be combined with time_budget_ms")
+	}
+	return nil
+}
+
+// toPrefixEvaluations converts the request's prefix evaluations to the side
+// to move, the analyzer's perspective
+func toPrefixEvaluations(req *pb.AnalyzeGameRequest) ([]analyzer.PrefixEvaluation, error) {
+	var prefix []analyzer.PrefixEvaluation
+	var start string
+	if len(req.PrefixEvaluations) > 0 {
+		start = analyzer.StartingFEN(req.Pgn)
+	}
+	for _, p := range req.PrefixEvaluations {
+		if p.Evaluation == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "prefix evaluation of ply %d has no evaluation", p.Ply)
+		}
+		eval := toEvaluation(p.Evaluation)
+		if color, _ := analyzer.PlyMove(start, int(p.Ply)); req.EvalPerspective == pb.EvalPerspective_WHITE && color == "black" {
+			eval = negateEvaluation(eval)
+		}
+		eval.Depth = int(p.Depth)
+		prefix = append(prefix, analyzer.PrefixEvaluation{
+			Ply:      int(p.Ply),
+			Eval:     eval,
+			BestMove: p.BestMoveUci,
+			FEN:      p.Fen,
+		})
+	}
+	return prefix, nil
+}
+
+// convertResilience converts resilience to proto, nil when unknown
+func convertResilience(r *evaluation.Resilience) *pb.Resilience {
+	if r == nil {
+		return nil
+	}
+	return &pb.Resilience{
+		Swindles:       int32(r.Swindles),
+		BotchedWins:    int32(r.BotchedWins),
+		Gifts:          int32(r.Gifts),
+		GiftConversion: float32(r.GiftConversion),
 	}
 }
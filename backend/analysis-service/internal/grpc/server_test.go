@@ -0,0 +1,640 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/internal/buildinfo"
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMain(m *testing.M) {
+	enginetest.Main()
+	os.Exit(m.Run())
+}
+
+// perspectiveAnalysis has a White move, a Black blunder and a White blunder
+// into mate; evaluations are from the side to move, as the analyzer keeps them
+func perspectiveAnalysis() *analyzer.GameAnalysis {
+	mateIn := 3
+	return &analyzer.GameAnalysis{
+		GameID: "g1",
+		Moves: []analyzer.MoveAnalysis{
+			{Ply: 0, Color: "white", EvalBefore: engine.Evaluation{Centipawns: 30}, EvalAfter: engine.Evaluation{Centipawns: -25}},
+			{Ply: 1, Color: "black", EvalBefore: engine.Evaluation{Centipawns: -25}, EvalAfter: engine.Evaluation{Centipawns: 450}},
+			{Ply: 2, Color: "white", EvalBefore: engine.Evaluation{Centipawns: 450}, EvalAfter: engine.Evaluation{IsMate: true, MateIn: &mateIn}},
+		},
+	}
+}
+
+// score returns a proto evaluation as centipawns, or mate moves
+func score(eval *pb.Evaluation) int32 {
+	if eval.IsMate {
+		return eval.GetMateIn()
+	}
+	return eval.GetCentipawns()
+}
+
+func TestConvertGameAnalysis_EvalPerspective(t *testing.T) {
+	tests := []struct {
+		perspective pb.EvalPerspective
+		echoed      pb.EvalPerspective
+		before      []int32
+		after       []int32
+	}{
+		{
+			// Black's blunder swings White's score up by 425, White's
+			// blunder swings it down into Black mating
+			perspective: pb.EvalPerspective_WHITE,
+			echoed:      pb.EvalPerspective_WHITE,
+			before:      []int32{30, 25, 450},
+			after:       []int32{25, 450, -3},
+		},
+		{
+			// Each score is for the side to move, so Black's score before
+			// its blunder and White's after its own have the other sign
+			perspective: pb.EvalPerspective_SIDE_TO_MOVE,
+			echoed:      pb.EvalPerspective_SIDE_TO_MOVE,
+			before:      []int32{30, -25, 450},
+			after:       []int32{-25, 450, 3},
+		},
+		{
+			perspective: pb.EvalPerspective_EVAL_PERSPECTIVE_UNSPECIFIED,
+			echoed:      pb.EvalPerspective_SIDE_TO_MOVE,
+			before:      []int32{30, -25, 450},
+			after:       []int32{-25, 450, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.perspective.String(), func(t *testing.T) {
+			result := convertGameAnalysis(perspectiveAnalysis(), tt.perspective)
+			if result.EvalPerspective != tt.echoed {
+				t.Errorf("eval perspective = %v, want %v", result.EvalPerspective, tt.echoed)
+			}
+			for i, move := range result.Moves {
+				if got := score(move.EvalBefore); got != tt.before[i] {
+					t.Errorf("ply %d: eval before = %d, want %d", i, got, tt.before[i])
+				}
+				if got := score(move.EvalAfter); got != tt.after[i] {
+					t.Errorf("ply %d: eval after = %d, want %d", i, got, tt.after[i])
+				}
+			}
+
+			// Converting back restores the side-to-move evaluations
+			back := toGameAnalysis(result)
+			for i, move := range perspectiveAnalysis().Moves {
+				if back.Moves[i].EvalBefore.Centipawns != move.EvalBefore.Centipawns || back.Moves[i].EvalAfter.Centipawns != move.EvalAfter.Centipawns {
+					t.Errorf("ply %d: round trip = %+v / %+v", i, back.Moves[i].EvalBefore, back.Moves[i].EvalAfter)
+				}
+			}
+			if mate := back.Moves[2].EvalAfter.MateIn; mate == nil || *mate != 3 {
+				t.Errorf("round trip mate = %v, want 3", mate)
+			}
+		})
+	}
+}
+
+func TestAnalyzeGame_InvalidResult(t *testing.T) {
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	s := NewServer(a, nil, zap.NewNop(), 0)
+
+	_, err := s.AnalyzeGame(context.Background(), &pb.AnalyzeGameRequest{Pgn: "1. e4 e5", Result: "1-1"})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("code = %v, want InvalidArgument", code)
+	}
+}
+
+func TestConvertMoveAnalysis_ClassificationDisplay(t *testing.T) {
+	for value, name := range pb.MoveClassification_name {
+		class := pb.MoveClassification(value)
+		if class == pb.MoveClassification_CLASSIFICATION_UNKNOWN {
+			continue
+		}
+		move := analyzer.MoveAnalysis{Classification: toClassification(class)}
+		got := convertMoveAnalysis(&move, pb.EvalPerspective_EVAL_PERSPECTIVE_UNSPECIFIED)
+		if got.Classification != class {
+			t.Errorf("%s converts back as %s", name, got.Classification)
+		}
+		if got.ClassificationLabel == "" || got.ClassificationColor == "" {
+			t.Errorf("%s has no display data: %q %q", name, got.ClassificationLabel, got.ClassificationColor)
+		}
+	}
+
+	move := analyzer.MoveAnalysis{Classification: analyzer.ClassBlunder}
+	got := convertMoveAnalysis(&move, pb.EvalPerspective_EVAL_PERSPECTIVE_UNSPECIFIED)
+	if got.ClassificationGlyph != "??" || got.ClassificationLabel != "Blunder" || got.ClassificationColor != "#CA3431" {
+		t.Errorf("blunder shown as %q %q %q", got.ClassificationGlyph, got.ClassificationLabel, got.ClassificationColor)
+	}
+}
+
+func TestConvertMoveAnalysis_Highlights(t *testing.T) {
+	// The engine castles as the king taking its rook
+	move := analyzer.MoveAnalysis{FENBefore: "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", BestMoveUCI: "e1h1"}
+	got := convertMoveAnalysis(&move, pb.EvalPerspective_EVAL_PERSPECTIVE_UNSPECIFIED).Highlights
+	best := got.GetBestMove()
+	if best.GetFrom() != "e1" || best.GetTo() != "g1" || best.GetRookFrom() != "h1" || best.GetRookTo() != "f1" {
+		t.Errorf("best move squares = %v", best)
+	}
+	if got.Refutation != nil || got.HangingSquare != "" {
+		t.Errorf("refutation %v, hanging %q without a PV after the move", got.Refutation, got.HangingSquare)
+	}
+}
+
+func TestConvertGameMetrics_Resilience(t *testing.T) {
+	metrics := analyzer.GameMetrics{Accuracy: 80}
+	if pbMetrics := convertGameMetrics(&metrics); pbMetrics.Resilience != nil {
+		t.Errorf("unknown result: resilience = %v, want unset", pbMetrics.Resilience)
+	}
+
+	metrics.Resilience = &evaluation.Resilience{Swindles: 2, BotchedWins: 1, Gifts: 3, GiftConversion: 12.5}
+	back := toGameMetrics(convertGameMetrics(&metrics))
+	if back.Resilience == nil || *back.Resilience != *metrics.Resilience {
+		t.Errorf("round trip = %+v, want %+v", back.Resilience, metrics.Resilience)
+	}
+}
+
+func TestConvertGameMetrics_RoundsToOneDecimal(t *testing.T) {
+	metrics := analyzer.GameMetrics{Accuracy: 87.349, ACPL: 23.96}
+	pbMetrics := convertGameMetrics(&metrics)
+	if pbMetrics.Accuracy != 87.3 || pbMetrics.Acpl != 24.0 {
+		t.Errorf("accuracy %v, ACPL %v; want 87.3 and 24.0", pbMetrics.Accuracy, pbMetrics.Acpl)
+	}
+}
+
+func TestAccuracyModel_RoundTrip(t *testing.T) {
+	for _, model := range []pb.AccuracyModel{pb.AccuracyModel_ELOINSIGHT, pb.AccuracyModel_LICHESS, pb.AccuracyModel_CHESSCOM_APPROX} {
+		metrics := analyzer.GameMetrics{AccuracyModel: toAccuracyModel(model)}
+		if got := convertGameMetrics(&metrics).AccuracyModel; got != model {
+			t.Errorf("%v converted to %v", model, got)
+		}
+	}
+
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	s := NewServer(a, nil, zap.NewNop(), 0)
+	_, err := s.AnalyzeGame(context.Background(), &pb.AnalyzeGameRequest{Pgn: "1. e4 e5", AccuracyModel: pb.AccuracyModel(9)})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("unknown model: code = %v, want InvalidArgument", code)
+	}
+}
+
+func TestConvertGameAnalysis_TimeManagement(t *testing.T) {
+	analysis := perspectiveAnalysis()
+	analysis.WhiteTime = &analyzer.TimeManagement{
+		TimeClass: analyzer.TimeClassBlitz, ScrambleThresholdMs: 30000, ScrambleStartPly: 2,
+		ScrambleMoves: 1, ScrambleErrorRate: 1, ThinkingTimeMs: 9000, CPLossPerSecond: 0.5, ScrambleBlunders: []int{2},
+	}
+	analysis.Summary = "White 90.0%, Black 70.0% (1 blunder) — turning point: 1...f6??"
+
+	result := convertGameAnalysis(analysis, pb.EvalPerspective_SIDE_TO_MOVE)
+	if result.Summary != analysis.Summary {
+		t.Errorf("summary = %q, want %q", result.Summary, analysis.Summary)
+	}
+	if result.BlackTime != nil {
+		t.Errorf("black time = %v, want unset without clocks", result.BlackTime)
+	}
+	back := toGameAnalysis(result)
+	if back.Summary != analysis.Summary {
+		t.Errorf("round trip summary = %q, want %q", back.Summary, analysis.Summary)
+	}
+	if back.BlackTime != nil || !reflect.DeepEqual(back.WhiteTime, analysis.WhiteTime) {
+		t.Errorf("round trip = %+v, want %+v", back.WhiteTime, analysis.WhiteTime)
+	}
+}
+
+func TestToPrefixEvaluations(t *testing.T) {
+	req := &pb.AnalyzeGameRequest{
+		EvalPerspective: pb.EvalPerspective_WHITE,
+		PrefixEvaluations: []*pb.PrefixEvaluation{
+			{Ply: 0, Evaluation: &pb.Evaluation{Score: &pb.Evaluation_Centipawns{Centipawns: 30}}, BestMoveUci: "e2e4", Depth: 22},
+			{Ply: 1, Evaluation: &pb.Evaluation{Score: &pb.Evaluation_MateIn{MateIn: 2}, IsMate: true}, Depth: 22},
+		},
+	}
+
+	// White's view is turned to the side to move at odd plies
+	prefix, err := toPrefixEvaluations(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix[0].Eval.Centipawns != 30 || prefix[0].Eval.Depth != 22 || prefix[0].BestMove != "e2e4" {
+		t.Errorf("ply 0 = %+v", prefix[0])
+	}
+	if m := prefix[1].Eval.MateIn; m == nil || *m != -2 {
+		t.Errorf("ply 1 mate = %v, want -2", m)
+	}
+
+	req.EvalPerspective = pb.EvalPerspective_SIDE_TO_MOVE
+	if prefix, _ := toPrefixEvaluations(req); *prefix[1].Eval.MateIn != 2 {
+		t.Errorf("side to move: ply 1 mate = %d, want 2", *prefix[1].Eval.MateIn)
+	}
+
+	req.PrefixEvaluations[1].Evaluation = nil
+	if _, err := toPrefixEvaluations(req); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("missing evaluation: code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestConvertBestMoves_FewerLegalMoves(t *testing.T) {
+	result := &engine.AnalysisResult{
+		Evaluations: []engine.Evaluation{
+			{Centipawns: 0, PV: []string{"a2g2", "a8b7"}, MultiPV: 1},
+			{Centipawns: -900, PV: []string{"h1g2"}, MultiPV: 2},
+		},
+		PVCount: 2,
+		Notes:   []engine.Note{engine.NoteFewerLegalMoves},
+	}
+
+	resp := convertBestMoves("k7/8/8/8/8/8/R5q1/7K w - - 0 1", result)
+	if resp.PvCount != 2 || len(resp.Moves) != 2 || !reflect.DeepEqual(resp.Notes, []string{"fewer_legal_moves"}) {
+		t.Fatalf("response = %v, want 2 PVs and the fewer_legal_moves note", resp)
+	}
+	if resp.Moves[0].Rank != 1 || resp.Moves[0].MoveUci != "a2g2" || resp.Moves[1].Rank != 2 {
+		t.Errorf("moves = %v, want a2g2 ranked first", resp.Moves)
+	}
+}
+
+func TestConvertBestMoves_Transpositions(t *testing.T) {
+	result := &engine.AnalysisResult{
+		Evaluations: []engine.Evaluation{
+			{Centipawns: 30, PV: []string{"g1f3", "d7d5", "d2d4", "g8f6"}, MultiPV: 1},
+			{Centipawns: 28, PV: []string{"d2d4", "d7d5", "g1f3", "g8f6"}, MultiPV: 2, TransposesTo: 1},
+		},
+		PVCount: 2,
+	}
+	resp := convertBestMoves("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", result)
+	if resp.Moves[0].TransposesToRank != 0 || resp.Moves[1].TransposesToRank != 1 {
+		t.Errorf("transposes to ranks %d and %d, want 0 and 1", resp.Moves[0].TransposesToRank, resp.Moves[1].TransposesToRank)
+	}
+}
+
+func TestConvertBestMoves_Promotions(t *testing.T) {
+	result := &engine.AnalysisResult{
+		Evaluations: []engine.Evaluation{
+			{Centipawns: 900, PV: []string{"e7d8N"}, MultiPV: 1},
+			{Centipawns: 800, PV: []string{"e7e8q", "f7e8"}, MultiPV: 2},
+			{Centipawns: 500, PV: []string{"e7d8r"}, MultiPV: 3},
+		},
+		PVCount: 3,
+	}
+
+	resp := convertBestMoves("3r4/4Pk2/8/8/8/8/8/K7 w - - 0 1", result)
+	want := [][2]string{{"e7d8n", "exd8=N+"}, {"e7e8q", "e8=Q+"}, {"e7d8r", "exd8=R"}}
+	for i, move := range resp.Moves {
+		if move.MoveUci != want[i][0] || move.MoveSan != want[i][1] {
+			t.Errorf("move %d = %s (%s), want %s (%s)", i+1, move.MoveUci, move.MoveSan, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestConvertBestMoves_PonderMove(t *testing.T) {
+	result := &engine.AnalysisResult{
+		Evaluations: []engine.Evaluation{{Centipawns: 900, PV: []string{"e7e8q", "f7e8"}, MultiPV: 1}},
+		PVCount:     1,
+		BestMove:    "e7e8q",
+		PonderMove:  "f7e8",
+	}
+
+	resp := convertBestMoves("3r4/4Pk2/8/8/8/8/8/K7 w - - 0 1", result)
+	if resp.PonderMoveUci != "f7e8" || resp.PonderMoveSan != "Kxe8" {
+		t.Errorf("ponder move %s (%s), want f7e8 (Kxe8)", resp.PonderMoveUci, resp.PonderMoveSan)
+	}
+
+	// A ponder move that isn't legal after the best move keeps its UCI
+	result.PonderMove = "a1a2"
+	if uci, san := ponderMove("3r4/4Pk2/8/8/8/8/8/K7 w - - 0 1", result); uci != "a1a2" || san != "" {
+		t.Errorf("illegal ponder move = %s (%s), want a1a2 without SAN", uci, san)
+	}
+}
+
+func TestAnalyzePosition_GameOver(t *testing.T) {
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	s := NewServer(a, nil, zap.NewNop(), 0)
+	fen := "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"
+
+	resp, err := s.AnalyzePosition(context.Background(), &pb.AnalyzePositionRequest{Fen: fen})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GameOverReason != "checkmate" || resp.BestMove != "" || !resp.Evaluation.IsMate || resp.Evaluation.GetMateIn() != 0 {
+		t.Errorf("response = %v, want checkmate scored mate 0", resp)
+	}
+
+	moves, err := s.GetBestMoves(context.Background(), &pb.GetBestMovesRequest{Fen: fen, Count: 3})
+	if err != nil || moves.GameOverReason != "checkmate" || len(moves.Moves) != 0 {
+		t.Errorf("GetBestMoves() = %v, %v; want checkmate and no moves", moves, err)
+	}
+}
+
+func TestAnalyzeGame_NoMoves(t *testing.T) {
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	s := NewServer(a, nil, zap.NewNop(), 0)
+	pgn := "[Event \"Rated blitz game\"]\n[Result \"*\"]\n\n*"
+
+	_, err := s.AnalyzeGame(context.Background(), &pb.AnalyzeGameRequest{Pgn: pgn})
+	if code, reason := status.Code(err), errorReason(err); code != codes.InvalidArgument || reason != "EMPTY_GAME" {
+		t.Errorf("AnalyzeGame: %v, %s; want InvalidArgument, EMPTY_GAME", code, reason)
+	}
+
+	stream := &recordingStream{}
+	err = s.AnalyzeGameStream(&pb.AnalyzeGameRequest{GameId: "g1", Pgn: pgn}, stream)
+	if code, reason := status.Code(err), errorReason(err); code != codes.InvalidArgument || reason != "EMPTY_GAME" {
+		t.Errorf("AnalyzeGameStream: %v, %s; want InvalidArgument, EMPTY_GAME", code, reason)
+	}
+	if sent := stream.messages(); len(sent) != 0 {
+		t.Errorf("stream sent %v for a game without moves", sent)
+	}
+}
+
+func TestAnalyzeGame_Variant(t *testing.T) {
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	s := NewServer(a, nil, zap.NewNop(), 0)
+	pgn := "[Event \"Rated atomic game\"]\n[Variant \"Atomic\"]\n\n1. e4 d5 2. exd5 *"
+
+	_, err := s.AnalyzeGame(context.Background(), &pb.AnalyzeGameRequest{Pgn: pgn})
+	if code, reason := status.Code(err), errorReason(err); code != codes.InvalidArgument || reason != "UNSUPPORTED_VARIANT" {
+		t.Errorf("AnalyzeGame: %v, %s; want InvalidArgument, UNSUPPORTED_VARIANT", code, reason)
+	}
+	if !strings.Contains(status.Convert(err).Message(), "Atomic") {
+		t.Errorf("message %q doesn't name the variant", status.Convert(err).Message())
+	}
+
+	stream := &recordingStream{}
+	err = s.AnalyzeGameStream(&pb.AnalyzeGameRequest{GameId: "g1", Pgn: pgn}, stream)
+	if reason := errorReason(err); reason != "UNSUPPORTED_VARIANT" || len(stream.messages()) != 0 {
+		t.Errorf("AnalyzeGameStream: %v after %d messages, want UNSUPPORTED_VARIANT at once", err, len(stream.messages()))
+	}
+}
+
+func TestAnalyzeGame_TooLong(t *testing.T) {
+	s, _ := newTranscriptServers(t, nil)
+	s.analyzer.SetGameLengthLimits(600, 300)
+
+	// 1,000 plies of knights moving out and back
+	var b strings.Builder
+	for ply := 0; ply < 1000; ply++ {
+		if ply%2 == 0 {
+			fmt.Fprintf(&b, "%d. ", ply/2+1)
+		}
+		b.WriteString([]string{"Nf3", "Nf6", "Ng1", "Ng8"}[ply%4] + " ")
+	}
+	req := &pb.AnalyzeGameRequest{GameId: "g1", Pgn: b.String() + "*"}
+
+	_, err := s.AnalyzeGame(context.Background(), req)
+	if code, reason := status.Code(err), errorReason(err); code != codes.InvalidArgument || reason != "GAME_TOO_LONG" {
+		t.Errorf("AnalyzeGame: %v, %s; want InvalidArgument, GAME_TOO_LONG", code, reason)
+	}
+	if !strings.Contains(status.Convert(err).Message(), "1000 plies") {
+		t.Errorf("message %q doesn't name the ply count", status.Convert(err).Message())
+	}
+	stream := &recordingStream{}
+	err = s.AnalyzeGameStream(req, stream)
+	if reason := errorReason(err); reason != "GAME_TOO_LONG" || len(stream.messages()) != 0 {
+		t.Errorf("AnalyzeGameStream: %v after %d messages, want GAME_TOO_LONG at once", err, len(stream.messages()))
+	}
+
+	info, err := s.GetServiceInfo(context.Background(), &pb.GetServiceInfoRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MaxGamePlies != 600 || info.FastModePlies != 300 || info.FastModeDepth != 1 {
+		t.Errorf("service info limits = %d, %d, depth %d; want 600, 300, depth 1", info.MaxGamePlies, info.FastModePlies, info.FastModeDepth)
+	}
+	if info.StockfishPath == "" || info.StockfishPath != s.pool.Config().BinaryPath {
+		t.Errorf("service info stockfish path = %q, want the pool's binary", info.StockfishPath)
+	}
+}
+
+func TestGetServiceInfo(t *testing.T) {
+	s, _ := newTranscriptServers(t, nil)
+	s.SetLimits(Limits{MinDepth: 8, DefaultDepth: 14, MaxDepth: 22, DefaultBestMoves: 3, MaxMultiPV: 5})
+	club := evaluation.Thresholds{Best: 5, Excellent: 15, Good: 40, Inaccuracy: 90, Mistake: 250, GarbageWin: 800, GarbageLoss: -800}
+	profiles := map[string]evaluation.Thresholds{evaluation.ProfileStandard: evaluation.DefaultThresholds, "club": club}
+	if err := s.analyzer.SetThresholdProfiles(profiles, "club"); err != nil {
+		t.Fatal(err)
+	}
+
+	sha, built := buildinfo.GitSHA, buildinfo.BuildTime
+	buildinfo.GitSHA, buildinfo.BuildTime = "abc123", "2024-03-01T12:00:00Z"
+	t.Cleanup(func() { buildinfo.GitSHA, buildinfo.BuildTime = sha, built })
+
+	info, err := s.GetServiceInfo(context.Background(), &pb.GetServiceInfoRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.GitSha != "abc123" || info.BuildTime != "2024-03-01T12:00:00Z" || info.GoVersion != runtime.Version() {
+		t.Errorf("build info = %q, %q, %q; want abc123, the build time and %s", info.GitSha, info.BuildTime, info.GoVersion, runtime.Version())
+	}
+	if info.DefaultDepth != 14 || info.MaxDepth != 22 {
+		t.Errorf("depths = %d, max %d; want the limits' 14, max 22", info.DefaultDepth, info.MaxDepth)
+	}
+	if info.DefaultThresholdProfile != "club" || !proto.Equal(info.Thresholds, convertThresholds(club)) {
+		t.Errorf("thresholds = %s %v, want club's", info.DefaultThresholdProfile, info.Thresholds)
+	}
+	if len(info.ThresholdProfiles) != 2 || !proto.Equal(info.ThresholdProfiles[evaluation.ProfileStandard], convertThresholds(evaluation.DefaultThresholds)) {
+		t.Errorf("threshold profiles = %v, want standard and club", info.ThresholdProfiles)
+	}
+	if info.Thresholds.Inaccuracy != 90 || info.Thresholds.GarbageLoss != -800 {
+		t.Errorf("club thresholds converted as %v", info.Thresholds)
+	}
+}
+
+func TestProgressPercent(t *testing.T) {
+	for _, tt := range []struct {
+		current, total int
+		want           float32
+	}{
+		{0, 0, 0},
+		{3, 0, 0},
+		{1, 4, 25},
+		{4, 4, 100},
+	} {
+		if got := progressPercent(tt.current, tt.total); got != tt.want {
+			t.Errorf("progressPercent(%d, %d) = %v, want %v", tt.current, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzePosition_CacheOnly(t *testing.T) {
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	s := NewServer(a, nil, zap.NewNop(), 0)
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+
+	// Without a pool, only the cache can answer
+	_, err := s.AnalyzePosition(context.Background(), &pb.AnalyzePositionRequest{Fen: fen, CacheOnly: true})
+	if code, reason := status.Code(err), errorReason(err); code != codes.NotFound || reason != "NOT_CACHED" {
+		t.Errorf("uncached: %v, %s; want NotFound, NOT_CACHED", code, reason)
+	}
+	stream := &positionStream{}
+	err = s.AnalyzePositionStream(&pb.AnalyzePositionRequest{Fen: fen, CacheOnly: true}, stream)
+	if status.Code(err) != codes.NotFound || len(stream.sent) != 0 {
+		t.Errorf("stream: %v after %d messages, want NotFound at once", err, len(stream.sent))
+	}
+
+	useCache := false
+	_, err = s.AnalyzePosition(context.Background(), &pb.AnalyzePositionRequest{Fen: fen, CacheOnly: true, UseCache: &useCache})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("cache_only without the cache: code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestAnalyzeGame_NegativeTimeBudget(t *testing.T) {
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	s := NewServer(a, nil, zap.NewNop(), 0)
+	req := &pb.AnalyzeGameRequest{GameId: "g1", Pgn: "1. e4 e5 *", TimeBudgetMs: -1}
+
+	if _, err := s.AnalyzeGame(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("code = %v, want InvalidArgument", status.Code(err))
+	}
+	stream := &recordingStream{}
+	if err := s.AnalyzeGameStream(req, stream); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("stream: code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestAnalyzeGameStream_Preview(t *testing.T) {
+	s, _ := newTranscriptServers(t, nil)
+	stream := &recordingStream{}
+	req := &pb.AnalyzeGameRequest{GameId: "g1", Pgn: "1. e4 e5 2. Nf3 Nc6 3. Bb5 *", Depth: 12, PreviewDepth: 6}
+	if err := s.AnalyzeGameStream(req, stream); err != nil {
+		t.Fatal(err)
+	}
+	msgs := stream.messages()
+
+	// One preliminary message, before any move of the deep pass, then the
+	// final analysis on "completed"
+	var preview, final *pb.GameAnalysisProgress
+	for _, msg := range msgs {
+		switch {
+		case msg.Preliminary:
+			if preview != nil || final != nil {
+				t.Fatalf("preliminary message %v after another or the final one", msg)
+			}
+			preview = msg
+		case msg.Status == "completed":
+			final = msg
+		case msg.MoveAnalysis != nil && preview == nil:
+			t.Fatalf("move %v before the preview", msg.MoveAnalysis)
+		}
+	}
+	if preview == nil || final == nil {
+		t.Fatalf("messages = %v, want a preliminary and a completed one", msgs)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		msg   *pb.GameAnalysisProgress
+		depth int32
+	}{{"preview", preview, 6}, {"final", final, 12}} {
+		analysis := tt.msg.Analysis
+		if analysis == nil || analysis.Depth != tt.depth || len(analysis.Moves) != 5 || analysis.Preliminary != (tt.depth == 6) {
+			t.Fatalf("%s analysis = %v, want all 5 moves at depth %d", tt.name, analysis, tt.depth)
+		}
+		for _, move := range analysis.Moves {
+			if move.Depth != tt.depth {
+				t.Errorf("%s: ply %d at depth %d", tt.name, move.Ply, move.Depth)
+			}
+		}
+		// The message's metrics are the analysis's
+		if !proto.Equal(tt.msg.WhiteMetrics, analysis.WhiteMetrics) || !proto.Equal(tt.msg.BlackMetrics, analysis.BlackMetrics) {
+			t.Errorf("%s: message metrics %v, %v; analysis %v, %v", tt.name,
+				tt.msg.WhiteMetrics, tt.msg.BlackMetrics, analysis.WhiteMetrics, analysis.BlackMetrics)
+		}
+		if white, black := analysis.WhiteMetrics.TotalMoves, analysis.BlackMetrics.TotalMoves; white != 3 || black != 2 {
+			t.Errorf("%s: metrics over %d and %d moves, want 3 and 2", tt.name, white, black)
+		}
+	}
+	if final.Preliminary {
+		t.Error("final message marked preliminary")
+	}
+}
+
+func TestAnalyzeGameStream_PreviewConflicts(t *testing.T) {
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	s := NewServer(a, nil, zap.NewNop(), 0)
+	for _, req := range []*pb.AnalyzeGameRequest{
+		{GameId: "g1", Pgn: "1. e4 e5 *", PreviewDepth: -1},
+		{GameId: "g1", Pgn: "1. e4 e5 *", PreviewDepth: 8, CacheOnly: true},
+		{GameId: "g1", Pgn: "1. e4 e5 *", PreviewDepth: 8, TimeBudgetMs: 1000},
+	} {
+		stream := &recordingStream{}
+		if err := s.AnalyzeGameStream(req, stream); status.Code(err) != codes.InvalidArgument || len(stream.messages()) != 0 {
+			t.Errorf("%v: %v after %d messages, want InvalidArgument at once", req, err, len(stream.messages()))
+		}
+	}
+}
+
+func TestAnalyzeGame_PreviewRejected(t *testing.T) {
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	s := NewServer(a, nil, zap.NewNop(), 0)
+	for _, depth := range []int32{-1, 8} {
+		req := &pb.AnalyzeGameRequest{GameId: "g1", Pgn: "1. e4 e5 *", PreviewDepth: depth}
+		if _, err := s.AnalyzeGame(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("preview_depth %d: %v, want InvalidArgument", depth, err)
+		}
+	}
+}
+
+func TestAnalyzeGameStream_CrossCheck(t *testing.T) {
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	s := NewServer(a, nil, zap.NewNop(), 0)
+	stream := &recordingStream{}
+	err := s.AnalyzeGameStream(&pb.AnalyzeGameRequest{GameId: "g1", Pgn: "1. e4 e5 *", CrossCheckEngine: "secondary"}, stream)
+	if status.Code(err) != codes.InvalidArgument || len(stream.messages()) != 0 {
+		t.Errorf("cross_check_engine: %v after %d messages, want InvalidArgument at once", err, len(stream.messages()))
+	}
+}
+
+func TestDiagnostics_RoundTrip(t *testing.T) {
+	d := analyzer.Diagnostics{
+		Retries:         2,
+		Failures:        map[analyzer.FailureKind]int{analyzer.FailureEngineDied: 1, analyzer.FailureOther: 1},
+		FailedPositions: []int{3, 7},
+	}
+	if got := toDiagnostics(convertDiagnostics(d)); !reflect.DeepEqual(got, d) {
+		t.Errorf("round trip = %+v, want %+v", got, d)
+	}
+	if got := toDiagnostics(convertDiagnostics(analyzer.Diagnostics{})); !reflect.DeepEqual(got, analyzer.Diagnostics{}) {
+		t.Errorf("empty round trip = %+v", got)
+	}
+}
+
+func TestGameInfo_RoundTrip(t *testing.T) {
+	info := analyzer.GameInfo{White: "Alice", Black: "Bob", WhiteElo: 1850, Result: "1-0", TimeControl: "180+2"}
+	converted := convertGameInfo(info)
+	if converted.WhiteElo == nil || *converted.WhiteElo != 1850 {
+		t.Errorf("white_elo = %v, want 1850", converted.WhiteElo)
+	}
+	if converted.BlackElo != nil {
+		t.Errorf("black_elo = %d, want unset for an unrated player", *converted.BlackElo)
+	}
+	if got := toGameInfo(converted); got != info {
+		t.Errorf("round trip = %+v, want %+v", got, info)
+	}
+	if got := toGameInfo(nil); got != (analyzer.GameInfo{}) {
+		t.Errorf("nil = %+v, want no tags", got)
+	}
+}
+
+func TestTermination_RoundTrip(t *testing.T) {
+	for termination := range terminations {
+		if got := toTermination(terminations[termination]); got != termination {
+			t.Errorf("round trip of %q = %q", termination, got)
+		}
+	}
+	if got := toTermination(pb.Termination_TERMINATION_UNKNOWN); got != analyzer.TerminationUnknown {
+		t.Errorf("unknown = %q", got)
+	}
+}
@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/internal/store"
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeStore struct {
+	stored store.Stored
+	err    error
+	saved  []*analyzer.GameAnalysis
+}
+
+func (f *fakeStore) SaveGameAnalysis(ctx context.Context, a *analyzer.GameAnalysis) (store.Stored, error) {
+	f.saved = append(f.saved, a)
+	return f.stored, f.err
+}
+
+func TestAnalyzeGame_PersistRequiresStore(t *testing.T) {
+	a := analyzer.NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	s := NewServer(a, nil, zap.NewNop(), 0)
+
+	_, err := s.AnalyzeGame(context.Background(), &pb.AnalyzeGameRequest{GameId: "g1", Pgn: "1. e4 e5", Persist: true})
+	if code := status.Code(err); code != codes.FailedPrecondition {
+		t.Errorf("without a store: code = %v, want FailedPrecondition", code)
+	}
+
+	s.SetStore(&fakeStore{})
+	_, err = s.AnalyzeGame(context.Background(), &pb.AnalyzeGameRequest{Pgn: "1. e4 e5", Persist: true})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("without a game ID: code = %v, want InvalidArgument", code)
+	}
+}
+
+func TestPersist(t *testing.T) {
+	fake := &fakeStore{stored: store.Stored{GameRowID: 7, MoveRowIDs: []int64{70, 71, 72}, KeptExisting: true}}
+	s := NewServer(nil, nil, zap.NewNop(), 0)
+	s.SetStore(fake)
+
+	analysis := perspectiveAnalysis()
+	stored, err := s.persist(context.Background(), analysis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.saved) != 1 || fake.saved[0] != analysis {
+		t.Fatalf("saved %d analyses, want the one given", len(fake.saved))
+	}
+	if stored.GameRowId != 7 || len(stored.MoveRowIds) != 3 || stored.MoveRowIds[2] != 72 || !stored.KeptExisting {
+		t.Errorf("stored = %+v", stored)
+	}
+
+	fake.err = errors.New("connection refused")
+	if _, err := s.persist(context.Background(), analysis); status.Code(err) != codes.Unavailable {
+		t.Errorf("store failure: err = %v, want Unavailable", err)
+	}
+
+	fake.err = fmt.Errorf("store: %w", analyzer.ErrChecksumMismatch)
+	_, err = s.persist(context.Background(), analysis)
+	if code, reason := status.Code(err), errorReason(err); code != codes.DataLoss || reason != "CHECKSUM_MISMATCH" {
+		t.Errorf("checksum mismatch: %v, %s; want DataLoss, CHECKSUM_MISMATCH", code, reason)
+	}
+}
+
+func TestAnalyzeGameStream_Persist(t *testing.T) {
+	s, _ := newTranscriptServers(t, nil)
+	req := &pb.AnalyzeGameRequest{GameId: "g1", Pgn: "1. e4 e5 *", Depth: 12, Persist: true}
+
+	if err := s.AnalyzeGameStream(req, &recordingStream{}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("without a store: %v, want FailedPrecondition", err)
+	}
+
+	fake := &fakeStore{stored: store.Stored{GameRowID: 7, MoveRowIDs: []int64{70, 71}}}
+	s.SetStore(fake)
+	stream := &recordingStream{}
+	if err := s.AnalyzeGameStream(req, stream); err != nil {
+		t.Fatal(err)
+	}
+	messages := stream.messages()
+	final := messages[len(messages)-1]
+	if len(fake.saved) != 1 || fake.saved[0].GameID != "g1" || len(fake.saved[0].Moves) != 2 {
+		t.Fatalf("saved %d analyses, want the streamed one", len(fake.saved))
+	}
+	if final.Status != "completed" || final.Analysis == nil || final.Analysis.Stored.GetGameRowId() != 7 || len(final.Analysis.Stored.GetMoveRowIds()) != 2 {
+		t.Errorf("final message %s, analysis %v; want completed with the stored row IDs", final.Status, final.GetAnalysis().GetStored())
+	}
+
+	fake.err = errors.New("connection refused")
+	stream = &recordingStream{}
+	err := s.AnalyzeGameStream(req, stream)
+	messages = stream.messages()
+	if status.Code(err) != codes.Unavailable || messages[len(messages)-1].Status != "error" {
+		t.Errorf("store failure: %v, final status %s; want Unavailable and an error message", err, messages[len(messages)-1].Status)
+	}
+}
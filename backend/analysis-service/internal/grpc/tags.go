@@ -0,0 +1,24 @@
+package grpc
+
+import (
+	"context"
+	"path"
+
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"google.golang.org/grpc"
+)
+
+// TagInterceptors returns the unary and stream interceptors lending the
+// engines of every call for the name of its RPC, like "AnalyzeGame", so
+// the pool's busy time is attributed to the RPCs that caused it; see
+// pool.WithTag
+func TagInterceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(pool.WithTag(ctx, path.Base(info.FullMethod)), req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := pool.WithTag(ss.Context(), path.Base(info.FullMethod))
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+	return unary, stream
+}
@@ -0,0 +1,76 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestTagInterceptors_AttributeEngineTime(t *testing.T) {
+	binary := fakeEngine.Binary(t)
+	p, err := pool.NewPool(2, engine.Config{BinaryPath: binary, Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	unary, stream := TagInterceptors()
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(unary), grpc.ChainStreamInterceptor(stream))
+	a := analyzer.NewAnalyzer(p, zap.NewNop(), 1, 20, 30, time.Minute)
+	pb.RegisterAnalysisServiceServer(server, NewServer(a, p, zap.NewNop(), 0))
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	client := pb.NewAnalysisServiceClient(conn)
+
+	ctx := context.Background()
+	if _, err := client.AnalyzePosition(ctx, &pb.AnalyzePositionRequest{Fen: "4k3/8/8/8/8/8/8/4K2R w K - 0 1", Depth: 12}); err != nil {
+		t.Fatal(err)
+	}
+	games, err := client.AnalyzeGameStream(ctx, &pb.AnalyzeGameRequest{GameId: "g1", Pgn: "1. e4 e5 2. Nf3 Nc6 *", Depth: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := games.Recv(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each call's engine time goes to its RPC, the game's workers included
+	stats := p.GetStats()
+	busy := map[string]time.Duration{}
+	var sum time.Duration
+	for _, tag := range stats.Tags {
+		busy[tag.Tag] = tag.Busy
+		sum += tag.Busy
+	}
+	if len(busy) != 2 || busy["AnalyzePosition"] == 0 || busy["AnalyzeGameStream"] == 0 {
+		t.Errorf("busy by tag = %v, want AnalyzePosition and AnalyzeGameStream", busy)
+	}
+	if sum != stats.BusyTime {
+		t.Errorf("tags add up to %v of %v", sum, stats.BusyTime)
+	}
+}
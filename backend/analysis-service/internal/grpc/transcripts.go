@@ -0,0 +1,144 @@
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// transcriptTTL is how long a job's engine transcripts are kept after its
+// analysis started
+const transcriptTTL = time.Hour
+
+// Transcripts keeps the UCI conversations of game analyses requested with
+// record_engine_output, by job (an ID the server assigns each recording)
+// and position. A job keeps at most maxBytes of lines; from the first that
+// doesn't fit on they are dropped and its transcripts marked truncated.
+type Transcripts struct {
+	maxBytes int
+	now      func() time.Time
+
+	mu   sync.Mutex
+	jobs map[string]*jobTranscript
+}
+
+type jobTranscript struct {
+	expires   time.Time
+	bytes     int
+	truncated bool
+	positions map[int][]string
+}
+
+// NewTranscripts returns an empty store keeping up to maxBytes per job
+func NewTranscripts(maxBytes int) *Transcripts {
+	return &Transcripts{maxBytes: maxBytes, now: time.Now, jobs: make(map[string]*jobTranscript)}
+}
+
+// recorder starts a recording and returns its job ID and the callback
+// filling it. Expired jobs are dropped on the way.
+func (t *Transcripts) recorder() (string, analyzer.EngineOutputCallback) {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	jobID := hex.EncodeToString(buf)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweep(now)
+	job := &jobTranscript{expires: now.Add(transcriptTTL), positions: make(map[int][]string)}
+	t.jobs[jobID] = job
+
+	return jobID, func(ply int, line string) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if job.truncated || job.bytes+len(line) > t.maxBytes {
+			job.truncated = true
+			return
+		}
+		job.bytes += len(line)
+		job.positions[ply] = append(job.positions[ply], line)
+	}
+}
+
+// get returns the lines recorded for ply of jobID, false when the job
+// has no recording or it expired. Expired jobs are dropped on the way.
+func (t *Transcripts) get(jobID string, ply int) (*pb.EngineTranscript, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sweep(t.now())
+	job, ok := t.jobs[jobID]
+	if !ok {
+		return nil, false
+	}
+	return &pb.EngineTranscript{
+		Lines:           append([]string(nil), job.positions[ply]...),
+		Truncated:       job.truncated,
+		ExpiresAtUnixMs: job.expires.UnixMilli(),
+	}, true
+}
+
+// sweep drops the jobs expired at now; t.mu must be held
+func (t *Transcripts) sweep(now time.Time) {
+	for id, job := range t.jobs {
+		if !now.Before(job.expires) {
+			delete(t.jobs, id)
+		}
+	}
+}
+
+// SetTranscripts enables record_engine_output on game analysis requests;
+// without a store they are rejected
+func (s *Server) SetTranscripts(t *Transcripts) {
+	s.transcripts = t
+}
+
+// engineOutput returns the recorder of a game analysis request and its job
+// ID, sent to the caller as the x-transcript-job-id response header; nil
+// and "" when the request doesn't ask for one
+func (s *Server) engineOutput(ctx context.Context, req *pb.AnalyzeGameRequest) (analyzer.EngineOutputCallback, string, error) {
+	if !req.RecordEngineOutput {
+		return nil, "", nil
+	}
+	if s.transcripts == nil {
+		return nil, "", status.Error(codes.PermissionDenied, "engine output recording is not enabled")
+	}
+	if !isAdminCall(ctx) {
+		return nil, "", status.Error(codes.Unauthenticated, "record_engine_output needs a valid x-admin-token")
+	}
+	jobID, record := s.transcripts.recorder()
+	// Fails only outside a call, the job ID is in the result as well
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-transcript-job-id", jobID))
+	return record, jobID, nil
+}
+
+// SetTranscripts enables GetEngineTranscript
+func (s *AdminServer) SetTranscripts(t *Transcripts) {
+	s.transcripts = t
+}
+
+// GetEngineTranscript returns the UCI conversation of a position of a game
+// analyzed with record_engine_output
+func (s *AdminServer) GetEngineTranscript(ctx context.Context, req *pb.GetEngineTranscriptRequest) (*pb.EngineTranscript, error) {
+	if s.transcripts == nil {
+		return nil, status.Error(codes.Unimplemented, "engine output recording is not enabled")
+	}
+	transcript, ok := s.transcripts.get(req.JobId, int(req.Ply))
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no engine output recorded for job %q, or it expired", req.JobId)
+	}
+	if len(transcript.Lines) == 0 && !transcript.Truncated {
+		return nil, status.Errorf(codes.NotFound, "ply %d of job %q wasn't searched", req.Ply, req.JobId)
+	}
+	return transcript, nil
+}
@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTranscriptServers returns analysis and admin servers sharing
+// transcripts, on a fake engine
+func newTranscriptServers(t *testing.T, transcripts *Transcripts) (*Server, *AdminServer) {
+	t.Helper()
+	binary := fakeEngine.Binary(t)
+	p, err := pool.NewPool(1, engine.Config{BinaryPath: binary, Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	s := NewServer(analyzer.NewAnalyzer(p, zap.NewNop(), 1, 20, 30, time.Minute), p, zap.NewNop(), 0)
+	admin := NewAdminServer(nil, 0, zap.NewNop())
+	if transcripts != nil {
+		s.SetTranscripts(transcripts)
+		admin.SetTranscripts(transcripts)
+	}
+	return s, admin
+}
+
+// adminCall is the context of a call that carried the admin token
+func adminCall() context.Context {
+	return context.WithValue(context.Background(), adminCallKey{}, true)
+}
+
+func TestGetEngineTranscript(t *testing.T) {
+	s, admin := newTranscriptServers(t, NewTranscripts(1<<20))
+	ctx := adminCall()
+
+	result, err := s.AnalyzeGame(ctx, &pb.AnalyzeGameRequest{GameId: "g1", Pgn: "1. e4 e5 *", Depth: 12, RecordEngineOutput: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobID := result.TranscriptJobId
+	if jobID == "" || jobID == "g1" {
+		t.Fatalf("transcript job ID = %q, want one assigned by the server", jobID)
+	}
+
+	// Recording the same game_id again starts another job
+	again, err := s.AnalyzeGame(ctx, &pb.AnalyzeGameRequest{GameId: "g1", Pgn: "1. d4 *", Depth: 12, RecordEngineOutput: true, UseCache: new(bool)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.TranscriptJobId == jobID {
+		t.Fatalf("second recording of g1 reused job %q", jobID)
+	}
+
+	transcript, err := admin.GetEngineTranscript(ctx, &pb.GetEngineTranscriptRequest{JobId: jobID, Ply: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sentPosition, gotBestMove bool
+	for _, line := range transcript.Lines {
+		sentPosition = sentPosition || (strings.HasPrefix(line, "> position ") && strings.HasSuffix(line, " moves e2e4"))
+		gotBestMove = gotBestMove || line == "< bestmove e2e4"
+	}
+	if !sentPosition || !gotBestMove || transcript.Truncated {
+		t.Errorf("ply 1 transcript = %q, truncated %v", transcript.Lines, transcript.Truncated)
+	}
+	if transcript.ExpiresAtUnixMs <= time.Now().UnixMilli() {
+		t.Errorf("expires at %d, already past", transcript.ExpiresAtUnixMs)
+	}
+
+	if _, err := admin.GetEngineTranscript(ctx, &pb.GetEngineTranscriptRequest{JobId: jobID, Ply: 9}); status.Code(err) != codes.NotFound {
+		t.Errorf("ply not searched: code = %v, want NotFound", status.Code(err))
+	}
+	if _, err := admin.GetEngineTranscript(ctx, &pb.GetEngineTranscriptRequest{JobId: "g1", Ply: 0}); status.Code(err) != codes.NotFound {
+		t.Errorf("game_id as the job: code = %v, want NotFound", status.Code(err))
+	}
+}
+
+func TestRecordEngineOutput_Rejected(t *testing.T) {
+	ctx := adminCall()
+	s, admin := newTranscriptServers(t, nil)
+	_, err := s.AnalyzeGame(ctx, &pb.AnalyzeGameRequest{GameId: "g1", Pgn: "1. e4 e5 *", RecordEngineOutput: true})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("recording off: code = %v, want PermissionDenied", code)
+	}
+	if _, err := admin.GetEngineTranscript(ctx, &pb.GetEngineTranscriptRequest{JobId: "g1"}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("GetEngineTranscript with recording off: code = %v, want Unimplemented", status.Code(err))
+	}
+
+	s, _ = newTranscriptServers(t, NewTranscripts(1<<20))
+	_, err = s.AnalyzeGame(context.Background(), &pb.AnalyzeGameRequest{GameId: "g1", Pgn: "1. e4 e5 *", RecordEngineOutput: true})
+	if code := status.Code(err); code != codes.Unauthenticated {
+		t.Errorf("without the admin token: code = %v, want Unauthenticated", code)
+	}
+}
+
+func TestTranscripts_CapAndExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	transcripts := NewTranscripts(10)
+	transcripts.now = func() time.Time { return now }
+
+	g1, record := transcripts.recorder()
+	record(0, "> go 1")
+	record(1, "< info 1")
+	record(1, "< x")
+	transcript, ok := transcripts.get(g1, 1)
+	if !ok || len(transcript.Lines) != 0 || !transcript.Truncated {
+		t.Errorf("over the cap: %+v, %v; want ply 1 dropped and truncated", transcript, ok)
+	}
+	if transcript, _ := transcripts.get(g1, 0); len(transcript.Lines) != 1 {
+		t.Errorf("ply 0 = %q, want the line within the cap", transcript.Lines)
+	}
+
+	now = now.Add(transcriptTTL)
+	if _, ok := transcripts.get(g1, 0); ok {
+		t.Error("transcript kept past its hour")
+	}
+	if _, ok := transcripts.jobs[g1]; ok {
+		t.Error("expired job kept after a lookup")
+	}
+
+	// Lookups of other jobs drop expired ones, as do new recordings
+	g2, _ := transcripts.recorder()
+	now = now.Add(transcriptTTL)
+	transcripts.get("other", 0)
+	if _, ok := transcripts.jobs[g2]; ok {
+		t.Error("expired job kept after a lookup of another")
+	}
+	g3, _ := transcripts.recorder()
+	now = now.Add(transcriptTTL)
+	transcripts.recorder()
+	if _, ok := transcripts.jobs[g3]; ok {
+		t.Error("expired job kept after the next recording")
+	}
+}
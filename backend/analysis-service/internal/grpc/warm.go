@@ -0,0 +1,84 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CacheWarmer searches positions into the position cache as background
+// work, normally (*analyzer.Analyzer)
+type CacheWarmer interface {
+	WarmCache(ctx context.Context, fens []string, depth int, dryRun bool, progress func(analyzer.WarmReport)) (analyzer.WarmReport, error)
+}
+
+// SetCacheWarmer enables WarmCache
+func (s *AdminServer) SetCacheWarmer(warmer CacheWarmer) {
+	s.warmer = warmer
+}
+
+// WarmCache searches the positions of FENs or games into the cache,
+// streaming progress after each search and a last message once done
+func (s *AdminServer) WarmCache(req *pb.WarmCacheRequest, stream pb.AdminService_WarmCacheServer) error {
+	s.logger.Info("WarmCache request",
+		zap.Int("fens", len(req.Fens)),
+		zap.Int("pgnBytes", len(req.Pgn)),
+		zap.Int32("depth", req.Depth),
+		zap.Bool("dryRun", req.DryRun))
+
+	if s.warmer == nil {
+		return status.Error(codes.Unimplemented, "cache warming is not available")
+	}
+
+	var fens []string
+	switch {
+	case len(req.Fens) > 0 && req.Pgn != "":
+		return status.Error(codes.InvalidArgument, "set either fens or pgn, not both")
+	case len(req.Fens) > 0:
+		fens = req.Fens
+	case req.Pgn != "":
+		var err error
+		if fens, err = analyzer.PGNPositions(req.Pgn); err != nil {
+			return toStatus(err, "invalid PGN")
+		}
+	default:
+		return status.Error(codes.InvalidArgument, "fens or pgn is required")
+	}
+
+	var sendErr error
+	progress := func(report analyzer.WarmReport) {
+		if sendErr == nil {
+			sendErr = stream.Send(convertWarmReport(report, req.DryRun, false))
+		}
+	}
+	report, err := s.warmer.WarmCache(stream.Context(), fens, int(req.Depth), req.DryRun, progress)
+	if err != nil {
+		return toStatus(err, "cache warming failed")
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	return stream.Send(convertWarmReport(report, req.DryRun, true))
+}
+
+// convertWarmReport converts a cache warming report to proto
+func convertWarmReport(report analyzer.WarmReport, dryRun, done bool) *pb.WarmCacheProgress {
+	return &pb.WarmCacheProgress{
+		Depth:       int32(report.Depth),
+		Positions:   int32(report.Positions),
+		Duplicates:  int32(report.Duplicates),
+		Cached:      int32(report.Cached),
+		GameOver:    int32(report.GameOver),
+		Pending:     int32(report.Pending),
+		Analyzed:    int32(report.Analyzed),
+		Failed:      int32(report.Failed),
+		EstimatedMs: report.EstimatedTime.Milliseconds(),
+		Estimated:   report.Estimated,
+		Done:        done,
+		DryRun:      dryRun,
+	}
+}
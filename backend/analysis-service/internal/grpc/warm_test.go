@@ -0,0 +1,99 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const warmFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// warmStream records the progress messages of a WarmCache call
+type warmStream struct {
+	grpc.ServerStream
+	sent []*pb.WarmCacheProgress
+}
+
+func (w *warmStream) Send(msg *pb.WarmCacheProgress) error {
+	w.sent = append(w.sent, msg)
+	return nil
+}
+
+func (w *warmStream) Context() context.Context {
+	return context.Background()
+}
+
+// stubWarmer reports a search per position it is given
+type stubWarmer struct {
+	fens []string
+}
+
+func (w *stubWarmer) WarmCache(ctx context.Context, fens []string, depth int, dryRun bool, progress func(analyzer.WarmReport)) (analyzer.WarmReport, error) {
+	w.fens = fens
+	report := analyzer.WarmReport{Depth: depth, Positions: len(fens), Pending: len(fens)}
+	if dryRun {
+		return report, nil
+	}
+	for range fens {
+		report.Pending--
+		report.Analyzed++
+		progress(report)
+	}
+	return report, nil
+}
+
+func TestWarmCache(t *testing.T) {
+	admin := NewAdminServer(nil, 0, zap.NewNop())
+	warmer := &stubWarmer{}
+	admin.SetCacheWarmer(warmer)
+
+	pgn := "[Event \"Round 1\"]\n\n1. e4 e5 *\n\n[Event \"Round 2\"]\n\n1. d4 *\n"
+	stream := &warmStream{}
+	if err := admin.WarmCache(&pb.WarmCacheRequest{Pgn: pgn, Depth: 14}, stream); err != nil {
+		t.Fatal(err)
+	}
+	// Both games' positions, starting positions included
+	if len(warmer.fens) != 5 {
+		t.Fatalf("warmed %d positions, want 5", len(warmer.fens))
+	}
+	if len(stream.sent) != 6 {
+		t.Fatalf("sent %d messages, want a progress message per position and a last one", len(stream.sent))
+	}
+	last := stream.sent[len(stream.sent)-1]
+	if !last.Done || last.Analyzed != 5 || last.Pending != 0 || last.Depth != 14 || stream.sent[0].Done {
+		t.Errorf("last message %+v, first done %v", last, stream.sent[0].Done)
+	}
+
+	dry := &warmStream{}
+	if err := admin.WarmCache(&pb.WarmCacheRequest{Fens: []string{warmFEN}, DryRun: true}, dry); err != nil {
+		t.Fatal(err)
+	}
+	if len(dry.sent) != 1 || !dry.sent[0].Done || !dry.sent[0].DryRun || dry.sent[0].Pending != 1 {
+		t.Errorf("dry run sent %v", dry.sent)
+	}
+}
+
+func TestWarmCache_InvalidRequests(t *testing.T) {
+	admin := NewAdminServer(nil, 0, zap.NewNop())
+	if err := admin.WarmCache(&pb.WarmCacheRequest{Fens: []string{warmFEN}}, &warmStream{}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("without a warmer: %v, want Unimplemented", err)
+	}
+	admin.SetCacheWarmer(&stubWarmer{})
+
+	tests := map[string]*pb.WarmCacheRequest{
+		"empty":    {},
+		"both":     {Fens: []string{warmFEN}, Pgn: "1. e4 *"},
+		"bad game": {Pgn: "[Event \"Round 1\"]\n\n1. e4 e5 *\n\n[Event \"Round 2\"]\n\n1. e5 *\n"},
+	}
+	for name, req := range tests {
+		if err := admin.WarmCache(req, &warmStream{}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: %v, want InvalidArgument", name, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelController changes the log level of a running service and restores
+// the configured level after a timeout, so debug logging can't be left on
+// by accident.
+type LevelController struct {
+	mu          sync.Mutex
+	level       zap.AtomicLevel
+	base        zapcore.Level
+	baseUCI     bool
+	uciDebug    bool
+	setUCIDebug func(bool)
+	timer       *time.Timer
+	timerGen    int // Invalidates a timer that fired while being replaced
+	revertAt    time.Time
+	logger      *zap.Logger
+}
+
+// NewLevelController wraps level, treating its current value as the
+// configured level. setUCIDebug switches engine UCI line logging and may
+// be nil.
+func NewLevelController(level zap.AtomicLevel, uciDebug bool, setUCIDebug func(bool), logger *zap.Logger) *LevelController {
+	if setUCIDebug == nil {
+		setUCIDebug = func(bool) {}
+	}
+	setUCIDebug(uciDebug)
+
+	return &LevelController{
+		level:       level,
+		base:        level.Level(),
+		baseUCI:     uciDebug,
+		uciDebug:    uciDebug,
+		setUCIDebug: setUCIDebug,
+		logger:      logger,
+	}
+}
+
+// Level returns the level currently in effect
+func (c *LevelController) Level() zapcore.Level {
+	return c.level.Level()
+}
+
+// UCIDebug reports whether engine UCI lines are logged
+func (c *LevelController) UCIDebug() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.uciDebug
+}
+
+// RevertAt returns when the configured level will be restored, or the zero
+// time if no revert is pending
+func (c *LevelController) RevertAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.revertAt
+}
+
+// Toggle switches between debug and the configured level. It is used by
+// the SIGUSR1/SIGHUP handler and never schedules a revert.
+func (c *LevelController) Toggle() zapcore.Level {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	next := zapcore.DebugLevel
+	if c.level.Level() == zapcore.DebugLevel {
+		next = c.base
+	}
+	c.stopTimerLocked()
+	c.level.SetLevel(next)
+	c.logger.Info("Log level toggled", zap.Stringer("level", next))
+	return next
+}
+
+// Set changes the log level and, when uciDebug is non-nil, engine UCI
+// logging. If revertAfter is positive both are restored to their configured
+// values after that long. It returns the previous level.
+func (c *LevelController) Set(level zapcore.Level, uciDebug *bool, revertAfter time.Duration) zapcore.Level {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous := c.level.Level()
+	c.stopTimerLocked()
+	c.level.SetLevel(level)
+	if uciDebug != nil {
+		c.uciDebug = *uciDebug
+		c.setUCIDebug(*uciDebug)
+	}
+
+	changed := level != c.base || c.uciDebug != c.baseUCI
+	if changed && revertAfter > 0 {
+		c.revertAt = time.Now().Add(revertAfter)
+		gen := c.timerGen
+		c.timer = time.AfterFunc(revertAfter, func() { c.revert(gen) })
+	}
+
+	c.logger.Info("Log level changed",
+		zap.Stringer("previous", previous),
+		zap.Stringer("level", level),
+		zap.Bool("uciDebug", c.uciDebug),
+		zap.Duration("revertAfter", revertAfter))
+	return previous
+}
+
+// revert restores the configured level when the timer fires
+func (c *LevelController) revert(gen int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.timerGen {
+		return
+	}
+	c.timer = nil
+	c.revertAt = time.Time{}
+	c.level.SetLevel(c.base)
+	c.uciDebug = c.baseUCI
+	c.setUCIDebug(c.baseUCI)
+	c.logger.Info("Log level reverted", zap.Stringer("level", c.base))
+}
+
+func (c *LevelController) stopTimerLocked() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.timerGen++
+	c.revertAt = time.Time{}
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newTestController(uciDebug bool) (*LevelController, *atomic.Bool) {
+	var uci atomic.Bool
+	c := NewLevelController(zap.NewAtomicLevelAt(zapcore.InfoLevel), uciDebug, uci.Store, zap.NewNop())
+	return c, &uci
+}
+
+func TestToggle(t *testing.T) {
+	c, _ := newTestController(false)
+
+	if got := c.Toggle(); got != zapcore.DebugLevel {
+		t.Errorf("first Toggle() = %v, want debug", got)
+	}
+	if got := c.Toggle(); got != zapcore.InfoLevel {
+		t.Errorf("second Toggle() = %v, want info", got)
+	}
+}
+
+func TestSet_Reverts(t *testing.T) {
+	c, uci := newTestController(false)
+	on := true
+
+	previous := c.Set(zapcore.DebugLevel, &on, 50*time.Millisecond)
+	if previous != zapcore.InfoLevel {
+		t.Errorf("previous = %v, want info", previous)
+	}
+	if c.Level() != zapcore.DebugLevel || !uci.Load() {
+		t.Fatalf("level=%v uci=%v, want debug and true", c.Level(), uci.Load())
+	}
+	if c.RevertAt().IsZero() {
+		t.Error("expected a pending revert")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for c.Level() != zapcore.InfoLevel && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c.Level() != zapcore.InfoLevel {
+		t.Fatalf("level = %v after revert, want info", c.Level())
+	}
+	if uci.Load() || c.UCIDebug() {
+		t.Error("UCI debug should be reverted")
+	}
+	if !c.RevertAt().IsZero() {
+		t.Error("no revert should be pending")
+	}
+}
+
+func TestSet_NoRevert(t *testing.T) {
+	c, _ := newTestController(false)
+
+	c.Set(zapcore.DebugLevel, nil, 0)
+	if !c.RevertAt().IsZero() {
+		t.Error("zero revertAfter should not schedule a revert")
+	}
+
+	// Setting the configured level needs no revert either
+	c.Set(zapcore.InfoLevel, nil, time.Minute)
+	if !c.RevertAt().IsZero() {
+		t.Error("configured level should not schedule a revert")
+	}
+}
+
+func TestSet_ReplacesPendingRevert(t *testing.T) {
+	c, _ := newTestController(false)
+
+	c.Set(zapcore.DebugLevel, nil, 20*time.Millisecond)
+	c.Set(zapcore.WarnLevel, nil, time.Hour)
+
+	time.Sleep(100 * time.Millisecond)
+	if c.Level() != zapcore.WarnLevel {
+		t.Errorf("level = %v, the first timer should have been cancelled", c.Level())
+	}
+}
+
+func TestToggle_CancelsRevert(t *testing.T) {
+	c, _ := newTestController(false)
+
+	c.Set(zapcore.DebugLevel, nil, time.Hour)
+	c.Toggle()
+	if !c.RevertAt().IsZero() {
+		t.Error("Toggle should cancel the pending revert")
+	}
+	if c.Level() != zapcore.InfoLevel {
+		t.Errorf("level = %v, want info", c.Level())
+	}
+}
@@ -0,0 +1,74 @@
+// Package queue consumes game analysis jobs from a message broker and
+// publishes the results, as an alternative to calling the gRPC API.
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Message is one delivery of a job
+type Message struct {
+	ID         string // Broker-specific identifier used to ack the message
+	Data       []byte
+	Deliveries int // Times the broker delivered this message, including this one
+}
+
+// Broker is a message queue with at-least-once delivery. Messages that are
+// neither acked nor nacked are redelivered by the broker after a timeout.
+type Broker interface {
+	// Fetch blocks until a message is available or ctx is done
+	Fetch(ctx context.Context) (*Message, error)
+
+	// Ack removes a message from the queue
+	Ack(ctx context.Context, msg *Message) error
+
+	// Nak asks for the message to be redelivered after delay
+	Nak(ctx context.Context, msg *Message, delay time.Duration) error
+
+	// Publish appends data to subject and returns once the broker stored it
+	Publish(ctx context.Context, subject string, data []byte) error
+
+	// Err returns why the broker can't reach its server, nil while it can
+	Err() error
+
+	Close() error
+}
+
+// Waits between the reconnection attempts of a connection that keeps
+// failing: the first attempt after a failure is immediate, later ones
+// wait twice as long as the one before, from minRedialWait up to
+// maxRedialWait
+const (
+	minRedialWait = 100 * time.Millisecond
+	maxRedialWait = 30 * time.Second
+)
+
+// redialBackoff spaces out the dials of a dropped connection
+type redialBackoff struct {
+	wait    time.Duration
+	next    time.Time
+	lastErr error
+}
+
+// ready returns nil when a dial may be attempted at now, else the error
+// that dropped the connection
+func (r *redialBackoff) ready(now time.Time) error {
+	if now.Before(r.next) {
+		return fmt.Errorf("reconnecting in %s: %w", r.next.Sub(now).Round(time.Millisecond), r.lastErr)
+	}
+	return nil
+}
+
+// failed records a failure of the connection, or of dialing it, at now
+func (r *redialBackoff) failed(now time.Time, err error) {
+	r.lastErr = err
+	r.next = now.Add(r.wait)
+	r.wait = min(max(2*r.wait, minRedialWait), maxRedialWait)
+}
+
+// succeeded resets the waits once the connection works again
+func (r *redialBackoff) succeeded() {
+	*r = redialBackoff{}
+}
@@ -0,0 +1,327 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"go.uber.org/zap"
+)
+
+// Job is a game analysis request read from the jobs subject
+type Job struct {
+	GameID           string `json:"game_id"`
+	PGN              string `json:"pgn"`
+	Depth            int    `json:"depth"`
+	ThresholdProfile string `json:"threshold_profile,omitempty"`
+
+	// AnalyzeUntilError analyzes the moves before an invalid one instead
+	// of dead-lettering the job
+	AnalyzeUntilError bool `json:"analyze_until_error,omitempty"`
+
+	// ExcludeGarbageTime overrides the server default for leaving moves
+	// in decided positions out of accuracy and ACPL
+	ExcludeGarbageTime *bool `json:"exclude_garbage_time,omitempty"`
+}
+
+// Event is published to the results subject for every finished job
+type Event struct {
+	GameID   string          `json:"game_id"`
+	Status   string          `json:"status"` // "completed" or "error"
+	Analysis json.RawMessage `json:"analysis,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// DeadLetter is published to the dead-letter subject for a message that
+// could not be processed. Payload is the original message body.
+type DeadLetter struct {
+	MessageID  string `json:"message_id"`
+	Payload    string `json:"payload"`
+	Error      string `json:"error"`
+	Deliveries int    `json:"deliveries"`
+}
+
+// ErrInvalidJob means a message is not a valid job and will never succeed
+var ErrInvalidJob = errors.New("invalid job")
+
+// AnalyzeFunc runs a game analysis, normally (*analyzer.Analyzer).AnalyzeGame
+type AnalyzeFunc func(ctx context.Context, gameID, pgn string, depth int, opts analyzer.GameOptions, callback analyzer.ProgressCallback) (*analyzer.GameAnalysis, error)
+
+// EncodeFunc serializes a finished analysis as JSON for the results subject
+type EncodeFunc func(*analyzer.GameAnalysis) ([]byte, error)
+
+// Options configures a Consumer
+type Options struct {
+	ResultsSubject    string
+	DeadLetterSubject string
+	Concurrency       int           // Jobs analyzed at once
+	MaxRetries        int           // Redeliveries of a failing job before it is dead-lettered
+	RetryDelay        time.Duration // Wait before a failed job is redelivered
+}
+
+// Stats counts the jobs a consumer handled
+type Stats struct {
+	InFlight     int64 `json:"inFlight"`
+	Completed    int64 `json:"completed"`
+	Failed       int64 `json:"failed"` // Error events published
+	Retried      int64 `json:"retried"`
+	DeadLettered int64 `json:"deadLettered"`
+
+	// HeldBytes estimates the memory the moves analyzed so far by the
+	// jobs in flight hold until their results are published
+	HeldBytes int64 `json:"heldBytes"`
+}
+
+// Consumer reads jobs from a broker, analyzes them and publishes the
+// results. A message is acked only after its result was published, so a
+// crash or a broker outage leads to redelivery rather than lost work.
+type Consumer struct {
+	broker  Broker
+	analyze AnalyzeFunc
+	encode  EncodeFunc
+	opts    Options
+	logger  *zap.Logger
+
+	inFlight, completed, failed, retried, deadLettered atomic.Int64
+	heldBytes                                          atomic.Int64
+}
+
+// NewConsumer creates a consumer. Concurrency below 1 is treated as 1.
+func NewConsumer(broker Broker, analyze AnalyzeFunc, encode EncodeFunc, opts Options, logger *zap.Logger) *Consumer {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	return &Consumer{
+		broker:  broker,
+		analyze: analyze,
+		encode:  encode,
+		opts:    opts,
+		logger:  logger,
+	}
+}
+
+// Stats returns the job counters
+func (c *Consumer) Stats() Stats {
+	return Stats{
+		InFlight:     c.inFlight.Load(),
+		Completed:    c.completed.Load(),
+		Failed:       c.failed.Load(),
+		Retried:      c.retried.Load(),
+		DeadLettered: c.deadLettered.Load(),
+		HeldBytes:    c.heldBytes.Load(),
+	}
+}
+
+// Err returns why the consumer can't take jobs, its broker's failure to
+// reach the server, nil while it can
+func (c *Consumer) Err() error {
+	if err := c.broker.Err(); err != nil {
+		return fmt.Errorf("queue broker: %w", err)
+	}
+	return nil
+}
+
+// Run consumes jobs until ctx is done, then waits for the jobs in flight.
+// Jobs interrupted by ctx are nacked and picked up again later.
+func (c *Consumer) Run(ctx context.Context) {
+	c.logger.Info("Queue consumer started", zap.Int("concurrency", c.opts.Concurrency))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, c.opts.Concurrency)
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		msg, err := c.broker.Fetch(ctx)
+		if err != nil {
+			<-slots
+			if ctx.Err() != nil {
+				break
+			}
+			c.logger.Warn("Failed to fetch job", zap.Error(err))
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			c.handle(ctx, msg)
+		}()
+	}
+
+	wg.Wait()
+	c.logger.Info("Queue consumer stopped")
+}
+
+// handle processes one message and settles it with the broker
+func (c *Consumer) handle(ctx context.Context, msg *Message) {
+	c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+
+	// Settling must survive shutdown, or finished work would be redone
+	settleCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	job, err := decodeJob(msg.Data)
+	if err != nil {
+		c.deadLetter(settleCtx, msg, job, err)
+		return
+	}
+	log := c.logger.With(zap.String("gameId", job.GameID), zap.String("messageId", msg.ID), zap.Int("deliveries", msg.Deliveries))
+
+	// Guards against jobs that crash the service or keep failing to publish
+	if msg.Deliveries > c.opts.MaxRetries+1 {
+		c.deadLetter(settleCtx, msg, job, fmt.Errorf("delivered %d times without being settled", msg.Deliveries))
+		return
+	}
+
+	opts := analyzer.GameOptions{
+		ThresholdProfile:   job.ThresholdProfile,
+		AnalyzeUntilError:  job.AnalyzeUntilError,
+		ExcludeGarbageTime: job.ExcludeGarbageTime,
+	}
+	var held int64
+	defer func() { c.heldBytes.Add(-held) }()
+	trackMemory := func(_, _ int, move *analyzer.MoveAnalysis) {
+		if move != nil {
+			n := analyzer.MoveMemoryEstimate(move)
+			held += n
+			c.heldBytes.Add(n)
+		}
+	}
+	analysis, err := c.analyze(ctx, job.GameID, job.PGN, job.Depth, opts, trackMemory)
+	if err != nil {
+		switch {
+		case ctx.Err() != nil:
+			// Shutting down: hand the job back without using up a retry
+			log.Info("Job interrupted by shutdown")
+			c.nak(settleCtx, msg, 0)
+		case permanent(err):
+			log.Warn("Job rejected", zap.Error(err))
+			c.publishEvent(settleCtx, msg, Event{GameID: job.GameID, Status: "error", Error: err.Error()})
+		case msg.Deliveries > c.opts.MaxRetries:
+			c.deadLetter(settleCtx, msg, job, err)
+		default:
+			log.Warn("Job failed, will retry", zap.Error(err), zap.Duration("retryDelay", c.opts.RetryDelay))
+			c.retried.Add(1)
+			c.nak(settleCtx, msg, c.opts.RetryDelay)
+		}
+		return
+	}
+
+	data, err := c.encode(analysis)
+	if err != nil {
+		c.deadLetter(settleCtx, msg, job, fmt.Errorf("encode analysis: %w", err))
+		return
+	}
+	if c.publishEvent(settleCtx, msg, Event{GameID: job.GameID, Status: "completed", Analysis: data}) {
+		log.Info("Job completed", zap.Int("moves", len(analysis.Moves)), zap.Bool("timedOut", analysis.TimedOut))
+	}
+}
+
+// publishEvent publishes a result event and acks msg once it is stored.
+// It reports whether both succeeded; otherwise msg is left for redelivery.
+func (c *Consumer) publishEvent(ctx context.Context, msg *Message, event Event) bool {
+	data, err := json.Marshal(event)
+	if err == nil {
+		err = c.broker.Publish(ctx, c.opts.ResultsSubject, data)
+	}
+	if err != nil {
+		c.logger.Error("Failed to publish result, job will be redelivered",
+			zap.String("messageId", msg.ID), zap.Error(err))
+		c.nak(ctx, msg, c.opts.RetryDelay)
+		return false
+	}
+
+	if event.Status == "completed" {
+		c.completed.Add(1)
+	} else {
+		c.failed.Add(1)
+	}
+	return c.ack(ctx, msg)
+}
+
+// deadLetter moves msg to the dead-letter subject and reports the failure
+// on the results subject when the job could be decoded
+func (c *Consumer) deadLetter(ctx context.Context, msg *Message, job Job, cause error) {
+	c.logger.Error("Dead-lettering job",
+		zap.String("messageId", msg.ID),
+		zap.String("gameId", job.GameID),
+		zap.Int("deliveries", msg.Deliveries),
+		zap.Error(cause))
+
+	data, err := json.Marshal(DeadLetter{
+		MessageID:  msg.ID,
+		Payload:    string(msg.Data),
+		Error:      cause.Error(),
+		Deliveries: msg.Deliveries,
+	})
+	if err == nil {
+		err = c.broker.Publish(ctx, c.opts.DeadLetterSubject, data)
+	}
+	if err != nil {
+		c.logger.Error("Failed to publish dead letter, job will be redelivered",
+			zap.String("messageId", msg.ID), zap.Error(err))
+		c.nak(ctx, msg, c.opts.RetryDelay)
+		return
+	}
+	c.deadLettered.Add(1)
+
+	if job.GameID == "" {
+		c.ack(ctx, msg)
+		return
+	}
+	c.publishEvent(ctx, msg, Event{GameID: job.GameID, Status: "error", Error: cause.Error()})
+}
+
+func (c *Consumer) ack(ctx context.Context, msg *Message) bool {
+	if err := c.broker.Ack(ctx, msg); err != nil {
+		c.logger.Warn("Failed to ack job, it may be processed again",
+			zap.String("messageId", msg.ID), zap.Error(err))
+		return false
+	}
+	return true
+}
+
+func (c *Consumer) nak(ctx context.Context, msg *Message, delay time.Duration) {
+	if err := c.broker.Nak(ctx, msg, delay); err != nil {
+		c.logger.Warn("Failed to nak job", zap.String("messageId", msg.ID), zap.Error(err))
+	}
+}
+
+// decodeJob parses and checks a job message
+func decodeJob(data []byte) (Job, error) {
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return Job{}, fmt.Errorf("%w: %v", ErrInvalidJob, err)
+	}
+	if job.GameID == "" {
+		return job, fmt.Errorf("%w: game_id is required", ErrInvalidJob)
+	}
+	if job.PGN == "" {
+		return job, fmt.Errorf("%w: pgn is required", ErrInvalidJob)
+	}
+	return job, nil
+}
+
+// permanent reports whether retrying the job can't change the outcome
+func permanent(err error) bool {
+	return errors.Is(err, analyzer.ErrInvalidPGN) ||
+		errors.Is(err, analyzer.ErrInvalidFEN) ||
+		errors.Is(err, analyzer.ErrUnknownThresholdProfile)
+}
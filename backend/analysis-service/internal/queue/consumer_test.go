@@ -0,0 +1,344 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"go.uber.org/zap"
+)
+
+// fakeBroker hands out queued messages and records how they were settled
+type fakeBroker struct {
+	msgs chan *Message
+
+	mu         sync.Mutex
+	acked      []string
+	nacked     map[string]time.Duration
+	published  map[string][][]byte
+	publishErr error
+	connErr    error
+}
+
+func newFakeBroker(msgs ...*Message) *fakeBroker {
+	b := &fakeBroker{
+		msgs:      make(chan *Message, len(msgs)),
+		nacked:    make(map[string]time.Duration),
+		published: make(map[string][][]byte),
+	}
+	for _, msg := range msgs {
+		b.msgs <- msg
+	}
+	return b
+}
+
+func (b *fakeBroker) Fetch(ctx context.Context) (*Message, error) {
+	select {
+	case msg := <-b.msgs:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *fakeBroker) Ack(ctx context.Context, msg *Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.acked = append(b.acked, msg.ID)
+	return nil
+}
+
+func (b *fakeBroker) Nak(ctx context.Context, msg *Message, delay time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nacked[msg.ID] = delay
+	return nil
+}
+
+func (b *fakeBroker) Publish(ctx context.Context, subject string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.publishErr != nil {
+		return b.publishErr
+	}
+	b.published[subject] = append(b.published[subject], data)
+	return nil
+}
+
+func (b *fakeBroker) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.connErr
+}
+
+func (b *fakeBroker) Close() error { return nil }
+
+func (b *fakeBroker) events(t *testing.T) []Event {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var events []Event
+	for _, data := range b.published["results"] {
+		var event Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			t.Fatalf("result is not an Event: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+var testOptions = Options{
+	ResultsSubject:    "results",
+	DeadLetterSubject: "dead",
+	Concurrency:       2,
+	MaxRetries:        2,
+	RetryDelay:        time.Minute,
+}
+
+func jobMessage(id string, deliveries int) *Message {
+	data, _ := json.Marshal(Job{GameID: "game-" + id, PGN: "1. e4 e5", Depth: 12})
+	return &Message{ID: id, Data: data, Deliveries: deliveries}
+}
+
+func encodeTest(a *analyzer.GameAnalysis) ([]byte, error) {
+	return json.Marshal(map[string]string{"gameId": a.GameID})
+}
+
+// runUntilSettled runs the consumer until n messages were acked or nacked
+func runUntilSettled(t *testing.T, c *Consumer, b *fakeBroker, n int) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		b.mu.Lock()
+		settled := len(b.acked) + len(b.nacked)
+		b.mu.Unlock()
+		if settled >= n {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of %d messages settled", settled, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestConsumer_PublishesThenAcks(t *testing.T) {
+	b := newFakeBroker(jobMessage("1", 1))
+	var gotDepth int
+	analyze := func(ctx context.Context, gameID, pgn string, depth int, opts analyzer.GameOptions, cb analyzer.ProgressCallback) (*analyzer.GameAnalysis, error) {
+		gotDepth = depth
+		return &analyzer.GameAnalysis{GameID: gameID}, nil
+	}
+	c := NewConsumer(b, analyze, encodeTest, testOptions, zap.NewNop())
+	runUntilSettled(t, c, b, 1)
+
+	if gotDepth != 12 {
+		t.Errorf("depth = %d, want 12", gotDepth)
+	}
+	events := b.events(t)
+	if len(events) != 1 || events[0].Status != "completed" || events[0].GameID != "game-1" {
+		t.Fatalf("events = %+v, want one completed event for game-1", events)
+	}
+	if string(events[0].Analysis) != `{"gameId":"game-1"}` {
+		t.Errorf("analysis = %s", events[0].Analysis)
+	}
+	if len(b.acked) != 1 || len(b.nacked) != 0 {
+		t.Errorf("acked %v, nacked %v; want one ack", b.acked, b.nacked)
+	}
+	if s := c.Stats(); s.Completed != 1 || s.InFlight != 0 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestConsumer_HeldBytes(t *testing.T) {
+	b := newFakeBroker(jobMessage("1", 1))
+	move := &analyzer.MoveAnalysis{FENBefore: "fen0", FENAfter: "fen1", PV: []string{"e2e4", "e7e5"}}
+	var c *Consumer
+	var during int64
+	analyze := func(ctx context.Context, gameID, pgn string, depth int, opts analyzer.GameOptions, cb analyzer.ProgressCallback) (*analyzer.GameAnalysis, error) {
+		cb(1, 2, nil)
+		cb(1, 2, move)
+		cb(2, 2, move)
+		during = c.Stats().HeldBytes
+		return &analyzer.GameAnalysis{GameID: gameID}, nil
+	}
+	c = NewConsumer(b, analyze, encodeTest, testOptions, zap.NewNop())
+	runUntilSettled(t, c, b, 1)
+
+	if want := 2 * analyzer.MoveMemoryEstimate(move); during != want {
+		t.Errorf("held %d bytes during the job, want %d", during, want)
+	}
+	if held := c.Stats().HeldBytes; held != 0 {
+		t.Errorf("held %d bytes after the job, want 0", held)
+	}
+}
+
+func TestConsumer_PublishFailureIsNotAcked(t *testing.T) {
+	b := newFakeBroker(jobMessage("1", 1))
+	b.publishErr = errors.New("broker down")
+	analyze := func(ctx context.Context, gameID, pgn string, depth int, opts analyzer.GameOptions, cb analyzer.ProgressCallback) (*analyzer.GameAnalysis, error) {
+		return &analyzer.GameAnalysis{GameID: gameID}, nil
+	}
+	c := NewConsumer(b, analyze, encodeTest, testOptions, zap.NewNop())
+	runUntilSettled(t, c, b, 1)
+
+	if len(b.acked) != 0 {
+		t.Errorf("acked %v before the result was published", b.acked)
+	}
+	if delay, ok := b.nacked["1"]; !ok || delay != time.Minute {
+		t.Errorf("nacked = %v, want message 1 with the retry delay", b.nacked)
+	}
+}
+
+func TestConsumer_RetriesThenDeadLetters(t *testing.T) {
+	b := newFakeBroker(jobMessage("first", 1), jobMessage("last", 3))
+	analyze := func(ctx context.Context, gameID, pgn string, depth int, opts analyzer.GameOptions, cb analyzer.ProgressCallback) (*analyzer.GameAnalysis, error) {
+		return nil, fmt.Errorf("%w: engine exited", analyzer.ErrEngineFailure)
+	}
+	c := NewConsumer(b, analyze, encodeTest, testOptions, zap.NewNop())
+	runUntilSettled(t, c, b, 2)
+
+	if _, ok := b.nacked["first"]; !ok {
+		t.Error("first delivery should be nacked for retry")
+	}
+	if len(b.acked) != 1 || b.acked[0] != "last" {
+		t.Errorf("acked = %v, want the last delivery", b.acked)
+	}
+	if len(b.published["dead"]) != 1 {
+		t.Fatalf("dead letters = %d, want 1", len(b.published["dead"]))
+	}
+	var dl DeadLetter
+	if err := json.Unmarshal(b.published["dead"][0], &dl); err != nil {
+		t.Fatal(err)
+	}
+	if dl.MessageID != "last" || dl.Deliveries != 3 || dl.Payload != string(jobMessage("last", 3).Data) {
+		t.Errorf("dead letter = %+v", dl)
+	}
+	if events := b.events(t); len(events) != 1 || events[0].Status != "error" || events[0].GameID != "game-last" {
+		t.Errorf("events = %+v, want one error event for game-last", events)
+	}
+	if s := c.Stats(); s.Retried != 1 || s.DeadLettered != 1 || s.Failed != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestConsumer_PermanentErrorIsNotRetried(t *testing.T) {
+	b := newFakeBroker(jobMessage("1", 1))
+	analyze := func(ctx context.Context, gameID, pgn string, depth int, opts analyzer.GameOptions, cb analyzer.ProgressCallback) (*analyzer.GameAnalysis, error) {
+		return nil, fmt.Errorf("%w: illegal move", analyzer.ErrInvalidPGN)
+	}
+	c := NewConsumer(b, analyze, encodeTest, testOptions, zap.NewNop())
+	runUntilSettled(t, c, b, 1)
+
+	if events := b.events(t); len(events) != 1 || events[0].Status != "error" {
+		t.Errorf("events = %+v, want one error event", events)
+	}
+	if len(b.acked) != 1 || len(b.published["dead"]) != 0 {
+		t.Errorf("acked %v, dead letters %d; want ack without dead letter", b.acked, len(b.published["dead"]))
+	}
+}
+
+func TestConsumer_InvalidJobIsDeadLettered(t *testing.T) {
+	b := newFakeBroker(&Message{ID: "1", Data: []byte("not json"), Deliveries: 1})
+	analyze := func(ctx context.Context, gameID, pgn string, depth int, opts analyzer.GameOptions, cb analyzer.ProgressCallback) (*analyzer.GameAnalysis, error) {
+		t.Error("invalid job must not be analyzed")
+		return nil, nil
+	}
+	c := NewConsumer(b, analyze, encodeTest, testOptions, zap.NewNop())
+	runUntilSettled(t, c, b, 1)
+
+	if len(b.published["dead"]) != 1 || len(b.acked) != 1 {
+		t.Errorf("dead letters %d, acked %v; want both 1", len(b.published["dead"]), b.acked)
+	}
+	if events := b.events(t); len(events) != 0 {
+		t.Errorf("events = %+v, want none without a game id", events)
+	}
+}
+
+func TestConsumer_RespectsConcurrency(t *testing.T) {
+	var msgs []*Message
+	for i := 0; i < 6; i++ {
+		msgs = append(msgs, jobMessage(fmt.Sprint(i), 1))
+	}
+	b := newFakeBroker(msgs...)
+
+	var running, peak atomic.Int32
+	analyze := func(ctx context.Context, gameID, pgn string, depth int, opts analyzer.GameOptions, cb analyzer.ProgressCallback) (*analyzer.GameAnalysis, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return &analyzer.GameAnalysis{GameID: gameID}, nil
+	}
+	c := NewConsumer(b, analyze, encodeTest, testOptions, zap.NewNop())
+	runUntilSettled(t, c, b, len(msgs))
+
+	if p := peak.Load(); p != int32(testOptions.Concurrency) {
+		t.Errorf("peak concurrency = %d, want %d", p, testOptions.Concurrency)
+	}
+}
+
+func TestConsumer_ShutdownHandsJobBack(t *testing.T) {
+	b := newFakeBroker(jobMessage("1", 1))
+	started := make(chan struct{})
+	analyze := func(ctx context.Context, gameID, pgn string, depth int, opts analyzer.GameOptions, cb analyzer.ProgressCallback) (*analyzer.GameAnalysis, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	c := NewConsumer(b, analyze, encodeTest, testOptions, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(ctx)
+	}()
+	<-started
+	cancel()
+	<-done
+
+	if delay, ok := b.nacked["1"]; !ok || delay != 0 {
+		t.Errorf("nacked = %v, want message 1 without delay", b.nacked)
+	}
+	if s := c.Stats(); s.Retried != 0 {
+		t.Errorf("shutdown must not use up a retry, stats = %+v", s)
+	}
+}
+
+func TestConsumer_Err(t *testing.T) {
+	b := newFakeBroker()
+	c := NewConsumer(b, nil, encodeTest, Options{}, zap.NewNop())
+	if err := c.Err(); err != nil {
+		t.Fatalf("Err() = %v with the broker connected", err)
+	}
+
+	b.mu.Lock()
+	b.connErr = errors.New("connection refused")
+	b.mu.Unlock()
+	if err := c.Err(); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Err() = %v with the broker down", err)
+	}
+}
@@ -0,0 +1,564 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsPullExpiry is how long one pull request waits before Fetch checks
+// ctx again
+const natsPullExpiry = 2 * time.Second
+
+// natsMsg is a MSG or HMSG received from the server
+type natsMsg struct {
+	subject string
+	reply   string
+	status  string // Status code of a header-only message, e.g. "404"
+	data    []byte
+}
+
+// NATSBroker reads jobs from a JetStream stream through a durable pull
+// consumer, and publishes to subjects that JetStream stores. It speaks
+// the NATS text protocol directly and supports one server. A connection
+// the server closed or that failed is dropped, and a later call dials
+// again, once the redial backoff allows, and recreates the consumer.
+type NATSBroker struct {
+	addr     string
+	user     string
+	password string
+	stream   string
+	subject  string
+	durable  string
+	ackWait  time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	conn   *natsConn // Nil while dropped
+	closed bool
+	retry  redialBackoff
+}
+
+// natsConn is one connection to the server, with its own inbox
+type natsConn struct {
+	inbox string // Prefix of reply subjects, subscribed as inbox.*
+
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	mu      sync.Mutex
+	waiters map[string]chan natsMsg
+	nextID  int
+	readErr error
+
+	conn net.Conn
+	done chan struct{} // Closed when the read loop exits
+}
+
+// NewNATSBroker connects to rawURL (nats://[user:pass@]host:port) and
+// creates, or reuses, the durable consumer for subject on stream. The
+// stream itself must already exist. Unacked messages are redelivered once
+// ackWait has passed, which must exceed the longest analysis.
+func NewNATSBroker(rawURL, stream, subject, durable string, ackWait time.Duration) (*NATSBroker, error) {
+	addr, user, password, err := parseNATSURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &NATSBroker{
+		addr:     addr,
+		user:     user,
+		password: password,
+		stream:   stream,
+		subject:  subject,
+		durable:  durable,
+		ackWait:  ackWait,
+		now:      time.Now,
+	}
+	if b.conn, err = b.dial(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// dial connects to the server and creates the durable consumer
+func (b *NATSBroker) dial() (*natsConn, error) {
+	conn, err := net.DialTimeout("tcp", b.addr, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("connect to NATS at %s: %w", b.addr, err)
+	}
+	c, err := newNATSConn(conn, b.user, b.password)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := c.createConsumer(b.stream, b.subject, b.durable, b.ackWait); err != nil {
+		c.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// connection returns the connection, dialed again if the last one failed
+// and the backoff allows
+func (b *NATSBroker) connection() (*natsConn, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, errors.New("NATS connection closed")
+	}
+	if b.conn != nil {
+		err := b.conn.err()
+		if err == nil {
+			return b.conn, nil
+		}
+		b.conn.close()
+		b.conn = nil
+		b.retry.failed(b.now(), err)
+	}
+	if err := b.retry.ready(b.now()); err != nil {
+		return nil, fmt.Errorf("NATS: %w", err)
+	}
+	conn, err := b.dial()
+	if err != nil {
+		b.retry.failed(b.now(), err)
+		return nil, err
+	}
+	b.retry.succeeded()
+	b.conn = conn
+	return conn, nil
+}
+
+// newNATSConn performs the protocol handshake on conn
+func newNATSConn(conn net.Conn, user, password string) (*natsConn, error) {
+	r := bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read NATS INFO: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return nil, fmt.Errorf("unexpected NATS greeting %q", strings.TrimSpace(line))
+	}
+
+	options := map[string]interface{}{
+		"verbose":       false,
+		"pedantic":      false,
+		"headers":       true,
+		"no_responders": true,
+		"lang":          "go",
+		"name":          "analysis-service",
+	}
+	if user != "" {
+		options["user"] = user
+		options["pass"] = password
+	}
+	connect, _ := json.Marshal(options)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		return nil, err
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("NATS handshake: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "PONG" {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			return nil, fmt.Errorf("NATS handshake: %s", line)
+		}
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &natsConn{
+		inbox:   "_INBOX." + randomToken(),
+		w:       bufio.NewWriter(conn),
+		waiters: make(map[string]chan natsMsg),
+		conn:    conn,
+		done:    make(chan struct{}),
+	}
+	if err := c.write(fmt.Sprintf("SUB %s.* 1\r\n", c.inbox)); err != nil {
+		return nil, err
+	}
+	go c.readLoop(r)
+	return c, nil
+}
+
+// createConsumer creates the durable pull consumer with explicit acks
+func (c *natsConn) createConsumer(stream, subject, durable string, ackWait time.Duration) error {
+	req, _ := json.Marshal(map[string]interface{}{
+		"stream_name": stream,
+		"config": map[string]interface{}{
+			"durable_name":   durable,
+			"ack_policy":     "explicit",
+			"ack_wait":       ackWait.Nanoseconds(),
+			"filter_subject": subject,
+			"deliver_policy": "all",
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := c.request(ctx, fmt.Sprintf("$JS.API.CONSUMER.DURABLE.CREATE.%s.%s", stream, durable), req)
+	if err != nil {
+		return fmt.Errorf("create JetStream consumer: %w", err)
+	}
+	return jsError(resp)
+}
+
+// Fetch pulls the next message for the durable consumer
+func (b *NATSBroker) Fetch(ctx context.Context) (*Message, error) {
+	req, _ := json.Marshal(map[string]interface{}{
+		"batch":   1,
+		"expires": natsPullExpiry.Nanoseconds(),
+	})
+	subject := fmt.Sprintf("$JS.API.CONSUMER.MSG.NEXT.%s.%s", b.stream, b.durable)
+
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, natsPullExpiry+5*time.Second)
+		msg, err := b.request(waitCtx, subject, req)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+
+		switch msg.status {
+		case "":
+		case "404", "408", "409":
+			// No messages, request expired or consumer busy: pull again
+			continue
+		default:
+			return nil, fmt.Errorf("JetStream pull failed with status %s", msg.status)
+		}
+
+		deliveries, err := natsDeliveries(msg.reply)
+		if err != nil {
+			return nil, err
+		}
+		return &Message{ID: msg.reply, Data: msg.data, Deliveries: deliveries}, nil
+	}
+}
+
+// Ack acknowledges the message and waits for the server to confirm it
+func (b *NATSBroker) Ack(ctx context.Context, msg *Message) error {
+	resp, err := b.request(ctx, msg.ID, []byte("+ACK"))
+	if err != nil {
+		return err
+	}
+	if resp.status != "" {
+		return fmt.Errorf("JetStream ack failed with status %s", resp.status)
+	}
+	return nil
+}
+
+// Nak asks JetStream to redeliver the message after delay
+func (b *NATSBroker) Nak(ctx context.Context, msg *Message, delay time.Duration) error {
+	payload := "-NAK"
+	if delay > 0 {
+		payload = fmt.Sprintf(`-NAK {"delay": %d}`, delay.Nanoseconds())
+	}
+	conn, err := b.connection()
+	if err != nil {
+		return err
+	}
+	return conn.publish(msg.ID, "", []byte(payload))
+}
+
+// Publish sends data to subject and waits for JetStream's publish ack
+func (b *NATSBroker) Publish(ctx context.Context, subject string, data []byte) error {
+	resp, err := b.request(ctx, subject, data)
+	if err != nil {
+		return err
+	}
+	if resp.status == "503" {
+		return fmt.Errorf("no JetStream stream stores subject %q", subject)
+	}
+	return jsError(resp)
+}
+
+// Err returns why the broker's connection is down, nil while it works
+func (b *NATSBroker) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.closed:
+		return errors.New("NATS connection closed")
+	case b.conn == nil:
+		return b.retry.lastErr
+	}
+	return b.conn.err()
+}
+
+// Close closes the connection
+func (b *NATSBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.close()
+	b.conn = nil
+	return err
+}
+
+// request sends a request on the current connection
+func (b *NATSBroker) request(ctx context.Context, subject string, data []byte) (natsMsg, error) {
+	conn, err := b.connection()
+	if err != nil {
+		return natsMsg{}, err
+	}
+	return conn.request(ctx, subject, data)
+}
+
+// close closes the connection and waits for its read loop
+func (c *natsConn) close() error {
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
+
+// request publishes data with a fresh reply subject and waits for the reply
+func (c *natsConn) request(ctx context.Context, subject string, data []byte) (natsMsg, error) {
+	c.mu.Lock()
+	if c.readErr != nil {
+		c.mu.Unlock()
+		return natsMsg{}, c.readErr
+	}
+	c.nextID++
+	reply := fmt.Sprintf("%s.%d", c.inbox, c.nextID)
+	ch := make(chan natsMsg, 1)
+	c.waiters[reply] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.waiters, reply)
+		c.mu.Unlock()
+	}()
+
+	if err := c.publish(subject, reply, data); err != nil {
+		return natsMsg{}, err
+	}
+
+	select {
+	case msg := <-ch:
+		return msg, nil
+	case <-c.done:
+		return natsMsg{}, c.err()
+	case <-ctx.Done():
+		return natsMsg{}, ctx.Err()
+	}
+}
+
+func (c *natsConn) publish(subject, reply string, data []byte) error {
+	var cmd strings.Builder
+	if reply == "" {
+		fmt.Fprintf(&cmd, "PUB %s %d\r\n", subject, len(data))
+	} else {
+		fmt.Fprintf(&cmd, "PUB %s %s %d\r\n", subject, reply, len(data))
+	}
+	cmd.Write(data)
+	cmd.WriteString("\r\n")
+	return c.write(cmd.String())
+}
+
+// write sends s. A failed write may have sent part of it, so the
+// connection is closed, which ends the read loop and gets it dropped.
+func (c *natsConn) write(s string) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	_, err := c.w.WriteString(s)
+	if err == nil {
+		err = c.w.Flush()
+	}
+	if err != nil {
+		c.conn.Close()
+	}
+	return err
+}
+
+// err returns why the read loop ended, nil while it runs
+func (c *natsConn) err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.readErr
+}
+
+// readLoop dispatches replies to waiting requests and answers pings
+func (c *natsConn) readLoop(r *bufio.Reader) {
+	defer close(c.done)
+
+	err := func() error {
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return err
+			}
+			line = strings.TrimRight(line, "\r\n")
+
+			switch {
+			case line == "PING":
+				if err := c.write("PONG\r\n"); err != nil {
+					return err
+				}
+			case strings.HasPrefix(line, "MSG "), strings.HasPrefix(line, "HMSG "):
+				msg, err := readNATSMsg(r, line)
+				if err != nil {
+					return err
+				}
+				c.mu.Lock()
+				ch := c.waiters[msg.subject]
+				c.mu.Unlock()
+				if ch != nil {
+					select {
+					case ch <- msg:
+					default:
+					}
+				}
+			case strings.HasPrefix(line, "-ERR"):
+				return fmt.Errorf("NATS server error: %s", strings.TrimSpace(line[4:]))
+			}
+		}
+	}()
+
+	c.mu.Lock()
+	if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+		err = errors.New("NATS connection closed")
+	}
+	c.readErr = err
+	c.mu.Unlock()
+	// A read loop ended by a protocol error leaves the socket open
+	c.conn.Close()
+}
+
+// readNATSMsg reads the payload announced by a MSG or HMSG line:
+// "MSG <subject> <sid> [reply] <size>" or
+// "HMSG <subject> <sid> [reply] <header size> <total size>"
+func readNATSMsg(r *bufio.Reader, line string) (natsMsg, error) {
+	fields := strings.Fields(line)
+	headers := fields[0] == "HMSG"
+
+	sizes := 1
+	if headers {
+		sizes = 2
+	}
+	if len(fields) != 3+sizes && len(fields) != 4+sizes {
+		return natsMsg{}, fmt.Errorf("malformed NATS message line %q", line)
+	}
+	msg := natsMsg{subject: fields[1]}
+	if len(fields) == 4+sizes {
+		msg.reply = fields[3]
+	}
+
+	total, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || total < 0 {
+		return natsMsg{}, fmt.Errorf("malformed NATS message line %q", line)
+	}
+	headerSize := 0
+	if headers {
+		if headerSize, err = strconv.Atoi(fields[len(fields)-2]); err != nil || headerSize > total {
+			return natsMsg{}, fmt.Errorf("malformed NATS message line %q", line)
+		}
+	}
+
+	buf := make([]byte, total+2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return natsMsg{}, err
+	}
+	msg.data = buf[headerSize:total]
+
+	// "NATS/1.0 404 No Messages\r\n..." carries a status code
+	if headers {
+		first, _, _ := strings.Cut(string(buf[:headerSize]), "\r\n")
+		if parts := strings.Fields(first); len(parts) >= 2 {
+			msg.status = parts[1]
+		}
+	}
+	return msg, nil
+}
+
+// natsDeliveries reads the delivery count from a JetStream ack subject:
+// $JS.ACK.<stream>.<consumer>.<delivered>.<stream seq>.<consumer seq>.<time>.<pending>
+// or the newer form with domain and account hash before the stream
+func natsDeliveries(ackSubject string) (int, error) {
+	tokens := strings.Split(ackSubject, ".")
+	var delivered string
+	switch {
+	case len(tokens) == 9 && tokens[0] == "$JS" && tokens[1] == "ACK":
+		delivered = tokens[4]
+	case len(tokens) >= 12 && tokens[0] == "$JS" && tokens[1] == "ACK":
+		delivered = tokens[6]
+	default:
+		return 0, fmt.Errorf("unexpected JetStream reply subject %q", ackSubject)
+	}
+	n, err := strconv.Atoi(delivered)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected JetStream reply subject %q", ackSubject)
+	}
+	return n, nil
+}
+
+// jsError returns the error of a JetStream API or publish response
+func jsError(msg natsMsg) error {
+	var resp struct {
+		Error *struct {
+			Code        int    `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	}
+	if len(msg.data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(msg.data, &resp); err != nil {
+		return fmt.Errorf("invalid JetStream response: %v", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("JetStream error %d: %s", resp.Error.Code, resp.Error.Description)
+	}
+	return nil
+}
+
+func parseNATSURL(rawURL string) (addr, user, password string, err error) {
+	if rawURL == "" {
+		return "localhost:4222", "", "", nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "nats" || u.Host == "" {
+		return "", "", "", fmt.Errorf("invalid NATS URL %q", rawURL)
+	}
+
+	addr = u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if u.User != nil {
+		user = u.User.Username()
+		password, _ = u.User.Password()
+	}
+	return addr, user, password, nil
+}
+
+func randomToken() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
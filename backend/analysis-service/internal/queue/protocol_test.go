@@ -0,0 +1,96 @@
+package queue
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRespConn_ReadsNestedReplies(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		r := bufio.NewReader(server)
+		// Consume the command: array header plus two lines per argument
+		for i := 0; i < 1+2*3; i++ {
+			r.ReadString('\n')
+		}
+		server.Write([]byte("*2\r\n$3\r\n1-0\r\n*2\r\n$4\r\ndata\r\n$2\r\n{}\r\n"))
+	}()
+
+	reply, err := newRespConn(client).do(time.Second, "XRANGE", "jobs", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"1-0", []interface{}{"data", "{}"}}
+	if !reflect.DeepEqual(reply, want) {
+		t.Fatalf("reply = %#v, want %#v", reply, want)
+	}
+
+	msg, err := parseStreamEntry(reply)
+	if err != nil || msg == nil || msg.ID != "1-0" || string(msg.Data) != "{}" {
+		t.Errorf("parseStreamEntry = %+v, %v", msg, err)
+	}
+}
+
+func TestRespConn_ErrorReply(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		bufio.NewReader(server).ReadString('\n')
+		server.Write([]byte("-BUSYGROUP Consumer Group name already exists\r\n"))
+	}()
+
+	_, err := newRespConn(client).do(time.Second, "PING")
+	if redisErr, ok := err.(redisError); !ok || !strings.HasPrefix(string(redisErr), "BUSYGROUP") {
+		t.Errorf("err = %v, want BUSYGROUP redisError", err)
+	}
+}
+
+func TestReadNATSMsg_Status(t *testing.T) {
+	header := "NATS/1.0 404 No Messages\r\n\r\n"
+	r := bufio.NewReader(strings.NewReader(header + "\r\n"))
+	msg, err := readNATSMsg(r, "HMSG _INBOX.x.1 1 28 28")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.subject != "_INBOX.x.1" || msg.status != "404" || len(msg.data) != 0 {
+		t.Errorf("msg = %+v", msg)
+	}
+
+	r = bufio.NewReader(strings.NewReader("hello\r\n"))
+	msg, err = readNATSMsg(r, "MSG analysis.jobs 1 $JS.ACK.ANALYSIS.svc.1.2.3.4.5 5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.reply != "$JS.ACK.ANALYSIS.svc.1.2.3.4.5" || string(msg.data) != "hello" {
+		t.Errorf("msg = %+v", msg)
+	}
+}
+
+func TestNATSDeliveries(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    int
+	}{
+		{"$JS.ACK.ANALYSIS.svc.3.10.8.1700000000000000000.0", 3},
+		{"$JS.ACK.domain.acchash.ANALYSIS.svc.2.10.8.1700000000000000000.0.token", 2},
+	}
+	for _, tt := range tests {
+		got, err := natsDeliveries(tt.subject)
+		if err != nil || got != tt.want {
+			t.Errorf("natsDeliveries(%q) = %d, %v; want %d", tt.subject, got, err, tt.want)
+		}
+	}
+
+	if _, err := natsDeliveries("_INBOX.abc.1"); err == nil {
+		t.Error("natsDeliveries should reject a non-ack subject")
+	}
+}
@@ -0,0 +1,220 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeServer accepts connections on localhost and serves each with
+// handle until the test ends or dropConnections closes them
+type fakeServer struct {
+	listener net.Listener
+	handle   func(conn net.Conn)
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func startFakeServer(t *testing.T, handle func(conn net.Conn)) *fakeServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{listener: listener, handle: handle}
+	t.Cleanup(func() {
+		listener.Close()
+		s.dropConnections()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go handle(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) addr() string { return s.listener.Addr().String() }
+
+// dropConnections closes the connections accepted so far, as a restart
+// of the server would
+func (s *fakeServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+// serveRedis answers each RESP command read from conn with reply(args)
+func serveRedis(reply func(args []string) string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			args := make([]string, n)
+			for i := range args {
+				r.ReadString('\n') // $len
+				arg, _ := r.ReadString('\n')
+				args[i] = strings.TrimSuffix(arg, "\r\n")
+			}
+			if _, err := conn.Write([]byte(reply(args))); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestRedisConn_TimeoutDropsConnection(t *testing.T) {
+	server := startFakeServer(t, serveRedis(func(args []string) string {
+		if args[0] == "SLOW" {
+			time.Sleep(300 * time.Millisecond)
+			return "+slow\r\n"
+		}
+		return "+" + args[0] + "\r\n"
+	}))
+	c, err := newRedisConn(server.addr(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := c.do(50*time.Millisecond, "SLOW"); err == nil {
+		t.Fatal("SLOW answered within its timeout")
+	}
+	// The late reply to SLOW is never read as another command's
+	for _, cmd := range []string{"PING", "ECHO"} {
+		if reply, err := c.do(time.Second, cmd); err != nil || reply != cmd {
+			t.Errorf("%s = %v, %v after a timed out command", cmd, reply, err)
+		}
+	}
+}
+
+func TestRedisConn_RedialsAfterRestart(t *testing.T) {
+	server := startFakeServer(t, serveRedis(func(args []string) string { return "+PONG\r\n" }))
+	c, err := newRedisConn(server.addr(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	server.dropConnections()
+	if _, err := c.do(time.Second, "PING"); err == nil {
+		t.Fatal("PING on a dropped connection succeeded")
+	}
+	if c.err() == nil {
+		t.Error("err() = nil with the connection dropped")
+	}
+
+	// The first redial is immediate, and works
+	if reply, err := c.do(time.Second, "PING"); err != nil || reply != "PONG" {
+		t.Fatalf("PING after the restart = %v, %v", reply, err)
+	}
+	if err := c.err(); err != nil {
+		t.Errorf("err() = %v once connected again", err)
+	}
+
+	// While the server is down, dials are spaced out
+	server.listener.Close()
+	server.dropConnections()
+	for i := 0; i < 2; i++ {
+		if _, err := c.do(time.Second, "PING"); err == nil {
+			t.Fatal("PING with the server down succeeded")
+		}
+	}
+	if _, err := c.do(time.Second, "PING"); err == nil || !strings.Contains(err.Error(), "reconnecting in") {
+		t.Errorf("PING within the backoff = %v, want reconnecting", err)
+	}
+	now = now.Add(maxRedialWait)
+	if _, err := c.do(time.Second, "PING"); err == nil || strings.Contains(err.Error(), "reconnecting in") {
+		t.Errorf("PING after the backoff = %v, want a failed dial", err)
+	}
+}
+
+// serveNATS speaks enough of the NATS protocol for a broker: it answers
+// the handshake, counts consumer creations and answers every request
+// with an empty JetStream ack
+func serveNATS(creates *atomic.Int32) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		fmt.Fprint(conn, "INFO {}\r\n")
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 0:
+			case fields[0] == "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+			case fields[0] == "PUB":
+				size, _ := strconv.Atoi(fields[len(fields)-1])
+				r.Discard(size + 2)
+				if strings.HasPrefix(fields[1], "$JS.API.CONSUMER.DURABLE.CREATE.") {
+					creates.Add(1)
+				}
+				if len(fields) == 4 {
+					fmt.Fprintf(conn, "MSG %s 1 2\r\n{}\r\n", fields[2])
+				}
+			}
+		}
+	}
+}
+
+func TestNATSBroker_RedialsAfterRestart(t *testing.T) {
+	var creates atomic.Int32
+	server := startFakeServer(t, serveNATS(&creates))
+	b, err := NewNATSBroker("nats://"+server.addr(), "ANALYSIS", "analysis.jobs", "svc", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	ctx := context.Background()
+
+	if err := b.Publish(ctx, "analysis.results", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+
+	server.dropConnections()
+	deadline := time.Now().Add(5 * time.Second)
+	for b.Err() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Err() = nil after the server dropped the connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The next call dials again and recreates the consumer
+	if err := b.Publish(ctx, "analysis.results", []byte("{}")); err != nil {
+		t.Fatalf("Publish after the restart: %v", err)
+	}
+	if err := b.Err(); err != nil {
+		t.Errorf("Err() = %v once connected again", err)
+	}
+	if n := creates.Load(); n != 2 {
+		t.Errorf("consumer created %d times, want again on reconnecting", n)
+	}
+}
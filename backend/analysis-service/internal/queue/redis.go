@@ -0,0 +1,525 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisBlock is how long one XREADGROUP waits before Fetch checks ctx again
+const redisBlock = 2 * time.Second
+
+// RedisBroker reads jobs from a Redis Stream through a consumer group.
+// Job bodies are stored in the "data" field of stream entries, and
+// Publish appends to the stream named by the subject with XADD.
+//
+// A nacked entry stays pending in the group and is claimed again with
+// XAUTOCLAIM once it has been idle for claimIdle, which also recovers
+// entries left behind by a crashed consumer. Entries being analyzed are
+// idle too, so claimIdle must exceed the longest analysis. Requires Redis
+// 6.2+.
+type RedisBroker struct {
+	stream    string
+	group     string
+	consumer  string
+	claimIdle time.Duration
+
+	fetchConn *redisConn // Used only by Fetch, which may block
+	cmdConn   *redisConn
+}
+
+// NewRedisBroker connects to rawURL (redis://[:password@]host:port[/db])
+// and creates the consumer group on stream if it doesn't exist.
+// Entries pending for longer than claimIdle are redelivered.
+func NewRedisBroker(rawURL, stream, group, consumer string, claimIdle time.Duration) (*RedisBroker, error) {
+	addr, password, db, err := parseRedisURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &RedisBroker{
+		stream:    stream,
+		group:     group,
+		consumer:  consumer,
+		claimIdle: claimIdle,
+	}
+	if b.fetchConn, err = newRedisConn(addr, password, db); err != nil {
+		return nil, err
+	}
+	if b.cmdConn, err = newRedisConn(addr, password, db); err != nil {
+		b.fetchConn.Close()
+		return nil, err
+	}
+
+	_, err = b.cmdConn.do(5*time.Second, "XGROUP", "CREATE", stream, group, "0", "MKSTREAM")
+	var redisErr redisError
+	if err != nil && !(errors.As(err, &redisErr) && strings.HasPrefix(string(redisErr), "BUSYGROUP")) {
+		b.Close()
+		return nil, fmt.Errorf("create consumer group: %w", err)
+	}
+	return b, nil
+}
+
+// Fetch returns a pending entry that is due for redelivery, or else the
+// next new entry of the stream
+func (b *RedisBroker) Fetch(ctx context.Context) (*Message, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		msg, err := b.claim()
+		if err != nil || msg != nil {
+			return msg, err
+		}
+
+		reply, err := b.fetchConn.do(redisBlock+5*time.Second,
+			"XREADGROUP", "GROUP", b.group, b.consumer, "COUNT", "1",
+			"BLOCK", strconv.FormatInt(redisBlock.Milliseconds(), 10),
+			"STREAMS", b.stream, ">")
+		if err != nil {
+			return nil, err
+		}
+		if reply == nil {
+			continue // Timed out, nothing new
+		}
+
+		// [[stream, [[id, [field, value, ...]]]]]
+		streams, _ := reply.([]interface{})
+		if len(streams) == 0 {
+			continue
+		}
+		stream, _ := streams[0].([]interface{})
+		if len(stream) < 2 {
+			return nil, fmt.Errorf("unexpected XREADGROUP reply %v", reply)
+		}
+		entries, _ := stream[1].([]interface{})
+		if len(entries) == 0 {
+			continue
+		}
+		msg, err = parseStreamEntry(entries[0])
+		if err != nil {
+			return nil, err
+		}
+		if msg == nil {
+			continue
+		}
+		msg.Deliveries = 1
+		return msg, nil
+	}
+}
+
+// claim takes over one entry that has been pending for claimIdle
+func (b *RedisBroker) claim() (*Message, error) {
+	// [next-id, [[id, [field, value, ...]]], deleted-ids]
+	reply, err := b.fetchConn.do(5*time.Second,
+		"XAUTOCLAIM", b.stream, b.group, b.consumer,
+		strconv.FormatInt(b.claimIdle.Milliseconds(), 10), "0-0", "COUNT", "1")
+	if err != nil {
+		return nil, err
+	}
+	parts, _ := reply.([]interface{})
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("unexpected XAUTOCLAIM reply %v", reply)
+	}
+	entries, _ := parts[1].([]interface{})
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	msg, err := parseStreamEntry(entries[0])
+	if err != nil || msg == nil {
+		return nil, err
+	}
+
+	// [[id, consumer, idle-ms, delivery-count]]
+	reply, err = b.fetchConn.do(5*time.Second, "XPENDING", b.stream, b.group, msg.ID, msg.ID, "1")
+	if err != nil {
+		return nil, err
+	}
+	msg.Deliveries = 2 // At least; the entry was delivered before
+	if pending, _ := reply.([]interface{}); len(pending) > 0 {
+		if info, _ := pending[0].([]interface{}); len(info) == 4 {
+			if n, ok := info[3].(int64); ok {
+				msg.Deliveries = int(n)
+			}
+		}
+	}
+	return msg, nil
+}
+
+// Ack removes the entry from the group's pending list
+func (b *RedisBroker) Ack(ctx context.Context, msg *Message) error {
+	_, err := b.command(ctx, "XACK", b.stream, b.group, msg.ID)
+	return err
+}
+
+// Nak leaves the entry pending; it is claimed again once idle for the
+// claimIdle given to NewRedisBroker, so delay is not used
+func (b *RedisBroker) Nak(ctx context.Context, msg *Message, delay time.Duration) error {
+	return nil
+}
+
+// Publish appends data as the "data" field of a new entry on the stream
+// named subject
+func (b *RedisBroker) Publish(ctx context.Context, subject string, data []byte) error {
+	_, err := b.command(ctx, "XADD", subject, "*", "data", string(data))
+	return err
+}
+
+// Err returns why the broker's connections are down, nil while both work
+func (b *RedisBroker) Err() error {
+	if err := b.fetchConn.err(); err != nil {
+		return err
+	}
+	return b.cmdConn.err()
+}
+
+// Close closes both connections
+func (b *RedisBroker) Close() error {
+	err := b.fetchConn.Close()
+	if cerr := b.cmdConn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// command runs one command on the shared command connection
+func (b *RedisBroker) command(ctx context.Context, args ...string) (interface{}, error) {
+	return b.cmdConn.do(commandTimeout(ctx), args...)
+}
+
+// commandTimeout is the time left until ctx's deadline, 5 seconds without
+// one
+func commandTimeout(ctx context.Context) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		return time.Until(deadline)
+	}
+	return 5 * time.Second
+}
+
+// RedisClient runs single commands on a Redis connection, for state other
+// than the job stream kept in the same Redis
+type RedisClient struct {
+	conn *redisConn
+}
+
+// DialRedis connects to rawURL (redis://[:password@]host:port[/db])
+func DialRedis(rawURL string) (*RedisClient, error) {
+	addr, password, db, err := parseRedisURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := newRedisConn(addr, password, db)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisClient{conn: conn}, nil
+}
+
+// Do runs a command until ctx's deadline, 5 seconds without one. Replies
+// decode to string, int64, nil or []interface{}; an error reply is
+// returned as the error. A command that fails otherwise, or times out,
+// drops the connection, which the next command dials again.
+func (c *RedisClient) Do(ctx context.Context, args ...string) (interface{}, error) {
+	return c.conn.do(commandTimeout(ctx), args...)
+}
+
+// Err returns why the connection is down, nil while it works
+func (c *RedisClient) Err() error {
+	return c.conn.err()
+}
+
+// Close closes the connection
+func (c *RedisClient) Close() error {
+	return c.conn.Close()
+}
+
+// parseStreamEntry reads [id, [field, value, ...]]. Nil is returned for an
+// entry deleted while pending.
+func parseStreamEntry(v interface{}) (*Message, error) {
+	if v == nil {
+		return nil, nil
+	}
+	entry, _ := v.([]interface{})
+	if len(entry) != 2 {
+		return nil, fmt.Errorf("unexpected stream entry %v", v)
+	}
+	id, ok := entry[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected stream entry id %v", entry[0])
+	}
+	fields, _ := entry[1].([]interface{})
+	if fields == nil {
+		return nil, nil
+	}
+
+	msg := &Message{ID: id}
+	for i := 0; i+1 < len(fields); i += 2 {
+		if name, _ := fields[i].(string); name == "data" {
+			value, _ := fields[i+1].(string)
+			msg.Data = []byte(value)
+		}
+	}
+	return msg, nil
+}
+
+func parseRedisURL(rawURL string) (addr, password string, db int, err error) {
+	if rawURL == "" {
+		return "localhost:6379", "", 0, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "redis" || u.Host == "" {
+		return "", "", 0, fmt.Errorf("invalid Redis URL %q", rawURL)
+	}
+
+	addr = u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		password, _ = u.User.Password()
+	}
+	if path := strings.TrimPrefix(u.Path, "/"); path != "" {
+		if db, err = strconv.Atoi(path); err != nil {
+			return "", "", 0, fmt.Errorf("invalid Redis database %q", path)
+		}
+	}
+	return addr, password, db, nil
+}
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisConn runs commands one at a time on a Redis connection that is
+// dropped on any failure other than an error reply, and dialed again by a
+// later command once the redial backoff allows. A command that timed out
+// or broke off leaves its reply, or the rest of it, unread, which the
+// next command on the same connection would take for its own.
+type redisConn struct {
+	addr     string
+	password string
+	db       int
+	now      func() time.Time
+
+	cmdMu sync.Mutex // Held for a whole command
+
+	mu     sync.Mutex // Guards the fields below; Close takes only this
+	conn   *respConn  // Nil while dropped
+	closed bool
+	retry  redialBackoff
+}
+
+// newRedisConn dials addr, so a bad address or password fails at once
+func newRedisConn(addr, password string, db int) (*redisConn, error) {
+	conn, err := dialRedis(addr, password, db)
+	if err != nil {
+		return nil, err
+	}
+	return &redisConn{addr: addr, password: password, db: db, now: time.Now, conn: conn}, nil
+}
+
+// do runs a command on the connection, dialing it first when it was
+// dropped
+func (c *redisConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(timeout, args...)
+	var redisErr redisError
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil && !errors.As(err, &redisErr) {
+		conn.Close()
+		if c.conn == conn {
+			c.conn = nil
+		}
+		c.retry.failed(c.now(), err)
+		return nil, err
+	}
+	c.retry.succeeded()
+	return reply, err
+}
+
+// connect returns the connection, dialed again if it was dropped and the
+// backoff allows
+func (c *redisConn) connect() (*respConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, errors.New("redis: connection closed")
+	}
+	if c.conn != nil {
+		return c.conn, nil
+	}
+	if err := c.retry.ready(c.now()); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	// Dialing under mu keeps Close from missing the new connection; it
+	// is bounded by the dial timeouts
+	conn, err := dialRedis(c.addr, c.password, c.db)
+	if err != nil {
+		c.retry.failed(c.now(), err)
+		return nil, err
+	}
+	c.conn = conn
+	return conn, nil
+}
+
+// err returns the failure that dropped the connection, nil while it is
+// up or before a command found it down
+func (c *redisConn) err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errors.New("redis: connection closed")
+	}
+	if c.conn == nil {
+		return c.retry.lastErr
+	}
+	return nil
+}
+
+// Close closes the connection, interrupting a command in progress
+func (c *redisConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// respConn is a single Redis connection speaking RESP2. Replies decode to
+// string, int64, nil, []interface{} or redisError.
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialRedis(addr, password string, db int) (*respConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("connect to Redis at %s: %w", addr, err)
+	}
+	c := newRespConn(conn)
+
+	if password != "" {
+		if _, err := c.do(5*time.Second, "AUTH", password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("Redis AUTH: %w", err)
+		}
+	}
+	if db != 0 {
+		if _, err := c.do(5*time.Second, "SELECT", strconv.Itoa(db)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("Redis SELECT %d: %w", db, err)
+		}
+	}
+	return c, nil
+}
+
+func newRespConn(conn net.Conn) *respConn {
+	return &respConn{conn: conn, r: bufio.NewReader(conn)}
+}
+
+// do sends a command and reads its reply. An error reply is returned as
+// the error.
+func (c *respConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.conn.Write([]byte(cmd.String())); err != nil {
+		return nil, err
+	}
+
+	reply, err := c.readReply()
+	if err != nil {
+		return nil, err
+	}
+	if redisErr, ok := reply.(redisError); ok {
+		return nil, redisErr
+	}
+	return reply, nil
+}
+
+func (c *respConn) readReply() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return redisError(line[1:]), nil
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad integer reply %q", line)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad array length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			// Errors nested in arrays are values, not failures of the command
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+func (c *respConn) Close() error {
+	return c.conn.Close()
+}
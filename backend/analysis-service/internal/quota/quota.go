@@ -0,0 +1,114 @@
+// Package quota meters the engine time each principal, an API key or user
+// ID, spends on analyses against a daily ceiling. Days are UTC, and a
+// principal's usage starts over at midnight.
+package quota
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrExceeded means a principal's engine time for the day is used up, or
+// would be by the analysis asked for
+var ErrExceeded = errors.New("engine time quota exceeded")
+
+// chargeTimeout bounds how long recording a search may hold up the worker
+// that made it
+const chargeTimeout = 2 * time.Second
+
+// Store keeps the usage counters. Counters are in milliseconds and
+// expire, so old days need no cleaning up.
+type Store interface {
+	// Add adds ms to key's counter, which expires ttl from now, and
+	// returns the new total
+	Add(ctx context.Context, key string, ms int64, ttl time.Duration) (int64, error)
+
+	// Get returns key's counter, 0 when there is none
+	Get(ctx context.Context, key string) (int64, error)
+}
+
+// Usage is a principal's engine time in the current day
+type Usage struct {
+	Principal string
+	Used      time.Duration
+	Limit     time.Duration
+	ResetsAt  time.Time // When Used starts over
+}
+
+// Remaining returns the engine time left in the day, never below zero
+func (u Usage) Remaining() time.Duration {
+	return max(0, u.Limit-u.Used)
+}
+
+// Meter charges principals' engine time to a Store
+type Meter struct {
+	store  Store
+	limit  time.Duration
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewMeter returns a Meter allowing each principal limit of engine time a
+// day
+func NewMeter(store Store, limit time.Duration, logger *zap.Logger) *Meter {
+	return &Meter{store: store, limit: limit, logger: logger, now: time.Now}
+}
+
+// window returns the store key of principal's counter for the day of now,
+// and when the day ends
+func (m *Meter) window(principal string) (string, time.Time) {
+	now := m.now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return "quota:" + principal + ":" + day.Format(time.DateOnly), day.AddDate(0, 0, 1)
+}
+
+// Charge adds d, rounded up to the millisecond, to principal's usage.
+// Store errors are logged: a search already made can't be undone, and
+// failing the analysis over it would waste it.
+func (m *Meter) Charge(principal string, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	ms := int64((d + time.Millisecond - 1) / time.Millisecond)
+	key, resetsAt := m.window(principal)
+	ctx, cancel := context.WithTimeout(context.Background(), chargeTimeout)
+	defer cancel()
+	// Kept a day past the window, for late GetQuota calls around midnight
+	if _, err := m.store.Add(ctx, key, ms, resetsAt.Sub(m.now())+24*time.Hour); err != nil {
+		m.logger.Warn("Failed to record engine time",
+			zap.String("principal", principal),
+			zap.Int64("ms", ms),
+			zap.Error(err))
+	}
+}
+
+// Usage returns principal's usage of the current day
+func (m *Meter) Usage(ctx context.Context, principal string) (Usage, error) {
+	key, resetsAt := m.window(principal)
+	ms, err := m.store.Get(ctx, key)
+	if err != nil {
+		return Usage{}, err
+	}
+	return Usage{
+		Principal: principal,
+		Used:      time.Duration(ms) * time.Millisecond,
+		Limit:     m.limit,
+		ResetsAt:  resetsAt,
+	}, nil
+}
+
+// Check returns principal's usage, with ErrExceeded when the day's quota is
+// used up or has less than estimate left
+func (m *Meter) Check(ctx context.Context, principal string, estimate time.Duration) (Usage, error) {
+	usage, err := m.Usage(ctx, principal)
+	if err != nil {
+		return usage, err
+	}
+	if usage.Used >= usage.Limit || estimate > usage.Remaining() {
+		return usage, ErrExceeded
+	}
+	return usage, nil
+}
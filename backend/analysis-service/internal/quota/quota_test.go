@@ -0,0 +1,212 @@
+package quota
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTestMeter returns a Meter on a MemoryStore, both on the clock *now
+func newTestMeter(limit time.Duration, now *time.Time) *Meter {
+	store := NewMemoryStore()
+	store.now = func() time.Time { return *now }
+	m := NewMeter(store, limit, zap.NewNop())
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+func TestMeter_ChargesPerPrincipal(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)
+	m := newTestMeter(time.Second, &now)
+
+	m.Charge("alice", 300*time.Millisecond)
+	m.Charge("alice", 400*time.Millisecond)
+	m.Charge("bob", 100*time.Millisecond)
+
+	usage, err := m.Usage(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Used != 700*time.Millisecond || usage.Remaining() != 300*time.Millisecond {
+		t.Errorf("alice used %v with %v remaining, want 700ms and 300ms", usage.Used, usage.Remaining())
+	}
+	if want := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC); !usage.ResetsAt.Equal(want) {
+		t.Errorf("ResetsAt = %v, want %v", usage.ResetsAt, want)
+	}
+	if usage, _ := m.Usage(ctx, "bob"); usage.Used != 100*time.Millisecond {
+		t.Errorf("bob used %v, want 100ms", usage.Used)
+	}
+}
+
+func TestMeter_Check(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := newTestMeter(time.Second, &now)
+	m.Charge("alice", 800*time.Millisecond)
+
+	if _, err := m.Check(ctx, "alice", 0); err != nil {
+		t.Errorf("Check with quota left = %v", err)
+	}
+	if _, err := m.Check(ctx, "alice", 200*time.Millisecond); err != nil {
+		t.Errorf("Check of an estimate that just fits = %v", err)
+	}
+	if _, err := m.Check(ctx, "alice", 300*time.Millisecond); !errors.Is(err, ErrExceeded) {
+		t.Errorf("Check of an estimate over the rest = %v, want ErrExceeded", err)
+	}
+
+	m.Charge("alice", 300*time.Millisecond)
+	usage, err := m.Check(ctx, "alice", 0)
+	if !errors.Is(err, ErrExceeded) {
+		t.Errorf("Check once used up = %v, want ErrExceeded", err)
+	}
+	if usage.Remaining() != 0 {
+		t.Errorf("Remaining once overdrawn = %v, want 0", usage.Remaining())
+	}
+}
+
+func TestMeter_StartsOverAtMidnight(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 23, 59, 0, 0, time.UTC)
+	m := newTestMeter(time.Second, &now)
+	m.Charge("alice", time.Second)
+	if _, err := m.Check(ctx, "alice", 0); !errors.Is(err, ErrExceeded) {
+		t.Fatalf("Check = %v, want ErrExceeded", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	usage, err := m.Check(ctx, "alice", 0)
+	if err != nil || usage.Used != 0 {
+		t.Errorf("next day: used %v, err %v; want 0, nil", usage.Used, err)
+	}
+}
+
+func TestMemoryStore_Expires(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	if total, _ := s.Add(ctx, "a", 5, time.Hour); total != 5 {
+		t.Errorf("total = %d, want 5", total)
+	}
+	if total, _ := s.Add(ctx, "a", 7, time.Hour); total != 12 {
+		t.Errorf("total = %d, want 12", total)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if ms, _ := s.Get(ctx, "a"); ms != 0 {
+		t.Errorf("expired counter = %d, want 0", ms)
+	}
+	s.Add(ctx, "b", 1, time.Hour)
+	if _, ok := s.counters["a"]; ok {
+		t.Error("expired counter kept after Add")
+	}
+}
+
+// fakeRedis keeps counters for the commands RedisStore sends, and the
+// TTLs EVAL set
+type fakeRedis struct {
+	mu       sync.Mutex
+	counters map[string]int64
+	ttls     map[string]string
+}
+
+// startFakeRedis serves a fakeRedis on localhost and returns its URL
+func startFakeRedis(t *testing.T) (*fakeRedis, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	f := &fakeRedis{counters: make(map[string]int64), ttls: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, "redis://" + listener.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			size, _ := r.ReadString('\n')
+			length, _ := strconv.Atoi(strings.TrimSpace(size[1:]))
+			buf := make([]byte, length+2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			args[i] = string(buf[:length])
+		}
+
+		f.mu.Lock()
+		var reply string
+		switch {
+		case args[0] == "EVAL" && len(args) == 6 && strings.Contains(args[1], "INCRBY") && strings.Contains(args[1], "EXPIRE"):
+			ms, _ := strconv.ParseInt(args[4], 10, 64)
+			f.counters[args[3]] += ms
+			f.ttls[args[3]] = args[5]
+			reply = fmt.Sprintf(":%d\r\n", f.counters[args[3]])
+		case args[0] == "GET":
+			value := strconv.FormatInt(f.counters[args[1]], 10)
+			reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+		default:
+			reply = fmt.Sprintf("-ERR unexpected command %q\r\n", args)
+		}
+		f.mu.Unlock()
+		conn.Write([]byte(reply))
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	f, url := startFakeRedis(t)
+	s, err := NewRedisStore(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// The counter and its TTL are set by one command, under a timeout of
+	// the store's own rather than the caller's deadline
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if total, err := s.Add(expired, "quota:user:alice", 5, 90*time.Minute); err != nil || total != 5 {
+		t.Fatalf("Add = %d, %v; want 5", total, err)
+	}
+	if total, err := s.Add(context.Background(), "quota:user:alice", 7, 90*time.Minute); err != nil || total != 12 {
+		t.Fatalf("Add = %d, %v; want 12", total, err)
+	}
+	f.mu.Lock()
+	ttl := f.ttls["quota:user:alice"]
+	f.mu.Unlock()
+	if ttl != "5400" {
+		t.Errorf("TTL = %q, want 5400 seconds", ttl)
+	}
+	if ms, err := s.Get(expired, "quota:user:alice"); err != nil || ms != 12 {
+		t.Errorf("Get = %d, %v; want 12", ms, err)
+	}
+}
@@ -0,0 +1,126 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/eloinsight/analysis-service/internal/queue"
+)
+
+// MemoryStore is a Store local to the process, so each replica meters on
+// its own and a restart forgets all usage
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]memoryCounter
+	now      func() time.Time
+}
+
+type memoryCounter struct {
+	ms      int64
+	expires time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]memoryCounter), now: time.Now}
+}
+
+// Add implements Store. Expired counters are dropped on the way.
+func (s *MemoryStore) Add(_ context.Context, key string, ms int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, c := range s.counters {
+		if !now.Before(c.expires) {
+			delete(s.counters, k)
+		}
+	}
+	c := s.counters[key]
+	c.ms += ms
+	c.expires = now.Add(ttl)
+	s.counters[key] = c
+	return c.ms, nil
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(_ context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[key]
+	if !ok || !s.now().Before(c.expires) {
+		return 0, nil
+	}
+	return c.ms, nil
+}
+
+// redisTimeout bounds each quota command, whatever the deadline of the
+// call it is made for
+const redisTimeout = 2 * time.Second
+
+// addScript increments a counter and sets its TTL in one step, so a
+// failure between the two can't leave a counter that never expires
+const addScript = `local total = redis.call("INCRBY", KEYS[1], ARGV[1])
+redis.call("EXPIRE", KEYS[1], ARGV[2])
+return total`
+
+// RedisStore is a Store in Redis, shared by every replica using it.
+// Commands run under their own redisTimeout, not the deadline of the
+// call they are made for, and a failed one drops the connection, see
+// queue.RedisClient.
+type RedisStore struct {
+	client *queue.RedisClient
+}
+
+// NewRedisStore connects to rawURL (redis://[:password@]host:port[/db])
+func NewRedisStore(rawURL string) (*RedisStore, error) {
+	client, err := queue.DialRedis(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisStore{client: client}, nil
+}
+
+// Add implements Store with a script running INCRBY and EXPIRE
+func (s *RedisStore) Add(ctx context.Context, key string, ms int64, ttl time.Duration) (int64, error) {
+	reply, err := s.do(ctx, "EVAL", addScript, "1", key,
+		strconv.FormatInt(ms, 10), strconv.FormatInt(int64(ttl/time.Second), 10))
+	if err != nil {
+		return 0, err
+	}
+	total, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected INCRBY reply %v", reply)
+	}
+	return total, nil
+}
+
+// Get implements Store
+func (s *RedisStore) Get(ctx context.Context, key string) (int64, error) {
+	reply, err := s.do(ctx, "GET", key)
+	if err != nil || reply == nil {
+		return 0, err
+	}
+	value, _ := reply.(string)
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected counter %q at %s", value, key)
+	}
+	return ms, nil
+}
+
+// do runs a command within redisTimeout, whatever ctx's deadline
+func (s *RedisStore) do(ctx context.Context, args ...string) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), redisTimeout)
+	defer cancel()
+	return s.client.Do(ctx, args...)
+}
+
+// Close closes the connection
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,73 @@
+-- Completed game analyses, one row per game. Re-analyzing a game replaces
+-- its row unless the stored analysis was searched deeper.
+CREATE TABLE game_analyses (
+    id                BIGSERIAL PRIMARY KEY,
+    game_id           TEXT NOT NULL UNIQUE,
+    depth             INTEGER NOT NULL,
+    engine_version    TEXT NOT NULL,
+    engine_profile    TEXT NOT NULL,
+    threshold_profile TEXT NOT NULL,
+    thresholds        TEXT NOT NULL,
+    total_moves       INTEGER NOT NULL,
+    total_time_ms     BIGINT NOT NULL,
+    timed_out         BOOLEAN NOT NULL,
+    truncated         BOOLEAN NOT NULL,
+
+    white_accuracy            DOUBLE PRECISION NOT NULL,
+    white_acpl                DOUBLE PRECISION NOT NULL,
+    white_blunders            INTEGER NOT NULL,
+    white_mistakes            INTEGER NOT NULL,
+    white_inaccuracies        INTEGER NOT NULL,
+    white_good_moves          INTEGER NOT NULL,
+    white_excellent_moves     INTEGER NOT NULL,
+    white_best_moves          INTEGER NOT NULL,
+    white_brilliant_moves     INTEGER NOT NULL,
+    white_book_moves          INTEGER NOT NULL,
+    white_total_moves         INTEGER NOT NULL,
+    white_performance_rating  INTEGER NOT NULL,
+    white_garbage_time_moves  INTEGER NOT NULL,
+
+    black_accuracy            DOUBLE PRECISION NOT NULL,
+    black_acpl                DOUBLE PRECISION NOT NULL,
+    black_blunders            INTEGER NOT NULL,
+    black_mistakes            INTEGER NOT NULL,
+    black_inaccuracies        INTEGER NOT NULL,
+    black_good_moves          INTEGER NOT NULL,
+    black_excellent_moves     INTEGER NOT NULL,
+    black_best_moves          INTEGER NOT NULL,
+    black_brilliant_moves     INTEGER NOT NULL,
+    black_book_moves          INTEGER NOT NULL,
+    black_total_moves         INTEGER NOT NULL,
+    black_performance_rating  INTEGER NOT NULL,
+    black_garbage_time_moves  INTEGER NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Analyzed moves of a game. Evaluations are from the side to move, a
+-- mate score sets the *_mate column and leaves *_cp null.
+CREATE TABLE move_analyses (
+    id               BIGSERIAL PRIMARY KEY,
+    game_analysis_id BIGINT NOT NULL REFERENCES game_analyses (id) ON DELETE CASCADE,
+    ply              INTEGER NOT NULL,
+    move_number      INTEGER NOT NULL,
+    color            TEXT NOT NULL,
+    played_move      TEXT NOT NULL,
+    played_move_uci  TEXT NOT NULL,
+    best_move        TEXT NOT NULL,
+    best_move_uci    TEXT NOT NULL,
+    fen_before       TEXT NOT NULL,
+    eval_before_cp   INTEGER,
+    eval_before_mate INTEGER,
+    eval_after_cp    INTEGER,
+    eval_after_mate  INTEGER,
+    centipawn_loss   INTEGER NOT NULL,
+    classification   TEXT NOT NULL,
+    pv               TEXT[] NOT NULL,
+    depth            INTEGER NOT NULL,
+    move_accuracy    DOUBLE PRECISION NOT NULL,
+    forced           BOOLEAN NOT NULL,
+    garbage_time     BOOLEAN NOT NULL,
+    UNIQUE (game_analysis_id, ply)
+);
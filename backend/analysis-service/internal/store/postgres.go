@@ -0,0 +1,420 @@
+// Package store persists completed game analyses to PostgreSQL. It is an
+// optional sink: the service stays stateless unless it is configured.
+package store
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLock is the advisory lock key held while migrating, so replicas
+// starting together don't apply the same migration twice
+const migrationLock = 0x656c6f // "elo"
+
+// Stored identifies the rows an analysis was stored in
+type Stored struct {
+	GameRowID  int64
+	MoveRowIDs []int64 // In move order
+
+	// KeptExisting is set when a deeper analysis of the game was already
+	// stored; it was left in place and the IDs are its rows
+	KeptExisting bool
+}
+
+// Postgres writes analyses to the game_analyses and move_analyses tables
+type Postgres struct {
+	pool   *pgxpool.Pool
+	logger *zap.Logger
+}
+
+// Open connects to dsn and applies any migrations not applied yet
+func Open(ctx context.Context, dsn string, logger *zap.Logger) (*Postgres, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("connect to postgres: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("connect to postgres: %w", err)
+	}
+
+	p := &Postgres{pool: pool, logger: logger}
+	if err := p.migrate(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Close closes the connection pool
+func (p *Postgres) Close() {
+	p.pool.Close()
+}
+
+// migrate applies the embedded migrations in file name order, each in its
+// own transaction, recording them in schema_migrations
+func (p *Postgres) migrate(ctx context.Context) error {
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLock); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLock)
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	for _, name := range names {
+		version := strings.TrimSuffix(strings.TrimPrefix(name, "migrations/"), ".sql")
+
+		var applied bool
+		err := conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+		if applied {
+			continue
+		}
+
+		sql, err := migrations.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, string(sql)); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", version, err)
+		}
+		p.logger.Info("Applied database migration", zap.String("version", version))
+	}
+	return nil
+}
+
+// SaveGameAnalysis stores the analysis in one transaction: the game row
+// with both players' metrics, then its moves in bulk. An analysis not
+// matching its checksum is refused before anything is written. A game already
+// stored is replaced when the new analysis is at least as deep; a deeper
+// stored analysis is kept.
+func (p *Postgres) SaveGameAnalysis(ctx context.Context, a *analyzer.GameAnalysis) (Stored, error) {
+	if a.GameID == "" {
+		return Stored{}, errors.New("store: game ID is required")
+	}
+	if err := analyzer.VerifyGameAnalysis(a); err != nil {
+		return Stored{}, fmt.Errorf("store: %w", err)
+	}
+
+	var stored Stored
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		columns, values := gameRow(a)
+		err := tx.QueryRow(ctx, upsertGameSQL(columns), values...).Scan(&stored.GameRowID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The upsert's WHERE kept a deeper analysis
+			stored.KeptExisting = true
+			err = tx.QueryRow(ctx, `SELECT id FROM game_analyses WHERE game_id = $1`, a.GameID).Scan(&stored.GameRowID)
+			if err != nil {
+				return err
+			}
+			stored.MoveRowIDs, err = moveRowIDs(ctx, tx, stored.GameRowID)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM move_analyses WHERE game_analysis_id = $1`, stored.GameRowID); err != nil {
+			return err
+		}
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"move_analyses"}, moveColumns, pgx.CopyFromSlice(len(a.Moves), func(i int) ([]any, error) {
+			return moveRow(stored.GameRowID, &a.Moves[i]), nil
+		}))
+		if err != nil {
+			return err
+		}
+		stored.MoveRowIDs, err = moveRowIDs(ctx, tx, stored.GameRowID)
+		return err
+	})
+	if err != nil {
+		return Stored{}, fmt.Errorf("store analysis of game %s: %w", a.GameID, err)
+	}
+	return stored, nil
+}
+
+// GameIDs returns the IDs of the stored games, in order
+func (p *Postgres) GameIDs(ctx context.Context) ([]string, error) {
+	rows, err := p.pool.Query(ctx, `SELECT game_id FROM game_analyses ORDER BY game_id`)
+	if err != nil {
+		return nil, fmt.Errorf("list stored games: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("list stored games: %w", err)
+	}
+	return ids, nil
+}
+
+// LoadGameAnalysis reads back the analysis of gameID from its rows. The
+// tables keep less than an analysis holds: the game info, timings, clock
+// figures and the depths of evaluations other than the achieved depth
+// before each move are not stored and are left zero. Each move's position
+// after it is replayed from the one before, its expected points loss and
+// the players' totals are computed again, and the summary and checksum
+// are those of what was read.
+func (p *Postgres) LoadGameAnalysis(ctx context.Context, gameID string) (*analyzer.GameAnalysis, error) {
+	a := &analyzer.GameAnalysis{GameID: gameID}
+	var rowID int64
+	var thresholds string
+	columns, targets := gameScanTargets(a, &thresholds)
+	err := p.pool.QueryRow(ctx, `SELECT id, `+strings.Join(columns, ", ")+` FROM game_analyses WHERE game_id = $1`, gameID).
+		Scan(append([]any{&rowID}, targets...)...)
+	if err != nil {
+		return nil, fmt.Errorf("load analysis of game %s: %w", gameID, err)
+	}
+	if a.Thresholds, err = evaluation.ParseThresholds(thresholds); err != nil {
+		return nil, fmt.Errorf("load analysis of game %s: %w", gameID, err)
+	}
+
+	rows, err := p.pool.Query(ctx, `SELECT `+strings.Join(moveColumns[1:], ", ")+
+		` FROM move_analyses WHERE game_analysis_id = $1 ORDER BY ply`, rowID)
+	if err != nil {
+		return nil, fmt.Errorf("load analysis of game %s: %w", gameID, err)
+	}
+	a.Moves, err = pgx.CollectRows(rows, scanMove)
+	if err != nil {
+		return nil, fmt.Errorf("load analysis of game %s: %w", gameID, err)
+	}
+	for i := range a.Moves {
+		metrics := &a.WhiteMetrics
+		if a.Moves[i].Color == "black" {
+			metrics = &a.BlackMetrics
+		}
+		metrics.ExpectedPointsLost += a.Moves[i].ExpectedPointsLoss
+	}
+	a.Summary = analyzer.Summarize(a)
+	a.Checksum = analyzer.Checksum(a)
+	return a, nil
+}
+
+// gameScanTargets returns the game_analyses columns gameRow writes, but
+// game_id, with where to scan each into a; the thresholds go to thresholds
+// as stored
+func gameScanTargets(a *analyzer.GameAnalysis, thresholds *string) ([]string, []any) {
+	columns := []string{
+		"depth", "engine_version", "engine_profile", "threshold_profile", "thresholds",
+		"total_moves", "total_time_ms", "timed_out", "truncated",
+	}
+	targets := []any{
+		&a.Depth, &a.EngineVersion, &a.EngineProfile, &a.ThresholdProfile, thresholds,
+		&a.TotalMoves, &a.TotalTimeMs, &a.TimedOut, &a.Truncated,
+	}
+	for _, side := range []struct {
+		prefix  string
+		metrics *analyzer.GameMetrics
+	}{
+		{"white_", &a.WhiteMetrics},
+		{"black_", &a.BlackMetrics},
+	} {
+		m := side.metrics
+		for _, c := range []struct {
+			name   string
+			target any
+		}{
+			{"accuracy", &m.Accuracy},
+			{"acpl", &m.ACPL},
+			{"blunders", &m.Blunders},
+			{"mistakes", &m.Mistakes},
+			{"inaccuracies", &m.Inaccuracies},
+			{"good_moves", &m.GoodMoves},
+			{"excellent_moves", &m.ExcellentMoves},
+			{"best_moves", &m.BestMoves},
+			{"brilliant_moves", &m.BrilliantMoves},
+			{"book_moves", &m.BookMoves},
+			{"total_moves", &m.TotalMoves},
+			{"performance_rating", &m.PerformanceRating},
+			{"garbage_time_moves", &m.GarbageTimeMoves},
+		} {
+			columns = append(columns, side.prefix+c.name)
+			targets = append(targets, c.target)
+		}
+	}
+	return columns, targets
+}
+
+// scanMove reads a move_analyses row of the columns moveRow writes, but
+// game_analysis_id
+func scanMove(row pgx.CollectableRow) (analyzer.MoveAnalysis, error) {
+	var m analyzer.MoveAnalysis
+	var beforeCP, beforeMate, afterCP, afterMate *int
+	var classification string
+	err := row.Scan(
+		&m.Ply, &m.MoveNumber, &m.Color,
+		&m.PlayedMove, &m.PlayedMoveUCI, &m.BestMove, &m.BestMoveUCI, &m.FENBefore,
+		&beforeCP, &beforeMate, &afterCP, &afterMate,
+		&m.CentipawnLoss, &classification, &m.PV, &m.AchievedDepth, &m.MoveAccuracy, &m.Forced, &m.GarbageTime,
+	)
+	if err != nil {
+		return m, err
+	}
+	m.EvalBefore = unscore(beforeCP, beforeMate)
+	m.EvalBefore.Depth = m.AchievedDepth
+	m.EvalAfter = unscore(afterCP, afterMate)
+	m.Classification = analyzer.MoveClassification(classification)
+	m.FENAfter, _ = analyzer.PlayUCI(m.FENBefore, m.PlayedMoveUCI)
+	m.Source = analyzer.LegacySource(&m)
+	m.ExpectedPointsLoss = analyzer.ExpectedPointsLoss(&m)
+	return m, nil
+}
+
+// upsertGameSQL inserts a game row, or updates the stored one unless it
+// was analyzed deeper, and returns its ID; no row is returned when the
+// stored analysis is kept
+func upsertGameSQL(columns []string) string {
+	placeholders := make([]string, len(columns))
+	updates := make([]string, 0, len(columns))
+	for i, col := range columns {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		if col != "game_id" {
+			updates = append(updates, col+" = EXCLUDED."+col)
+		}
+	}
+	updates = append(updates, "updated_at = now()")
+
+	return "INSERT INTO game_analyses (" + strings.Join(columns, ", ") + ")" +
+		" VALUES (" + strings.Join(placeholders, ", ") + ")" +
+		" ON CONFLICT (game_id) DO UPDATE SET " + strings.Join(updates, ", ") +
+		" WHERE game_analyses.depth <= EXCLUDED.depth" +
+		" RETURNING id"
+}
+
+// gameRow returns the game_analyses columns and values of an analysis
+func gameRow(a *analyzer.GameAnalysis) ([]string, []any) {
+	columns := []string{
+		"game_id", "depth", "engine_version", "engine_profile", "threshold_profile", "thresholds",
+		"total_moves", "total_time_ms", "timed_out", "truncated",
+	}
+	values := []any{
+		a.GameID, a.Depth, a.EngineVersion, a.EngineProfile, a.ThresholdProfile, a.Thresholds.String(),
+		a.TotalMoves, a.TotalTimeMs, a.TimedOut, a.Truncated,
+	}
+	for _, side := range []struct {
+		prefix  string
+		metrics analyzer.GameMetrics
+	}{
+		{"white_", a.WhiteMetrics},
+		{"black_", a.BlackMetrics},
+	} {
+		m := side.metrics
+		for _, c := range []struct {
+			name  string
+			value any
+		}{
+			{"accuracy", m.Accuracy},
+			{"acpl", m.ACPL},
+			{"blunders", m.Blunders},
+			{"mistakes", m.Mistakes},
+			{"inaccuracies", m.Inaccuracies},
+			{"good_moves", m.GoodMoves},
+			{"excellent_moves", m.ExcellentMoves},
+			{"best_moves", m.BestMoves},
+			{"brilliant_moves", m.BrilliantMoves},
+			{"book_moves", m.BookMoves},
+			{"total_moves", m.TotalMoves},
+			{"performance_rating", m.PerformanceRating},
+			{"garbage_time_moves", m.GarbageTimeMoves},
+		} {
+			columns = append(columns, side.prefix+c.name)
+			values = append(values, c.value)
+		}
+	}
+	return columns, values
+}
+
+// moveColumns are the move_analyses columns written by moveRow
+var moveColumns = []string{
+	"game_analysis_id", "ply", "move_number", "color",
+	"played_move", "played_move_uci", "best_move", "best_move_uci", "fen_before",
+	"eval_before_cp", "eval_before_mate", "eval_after_cp", "eval_after_mate",
+	"centipawn_loss", "classification", "pv", "depth", "move_accuracy", "forced", "garbage_time",
+}
+
+func moveRow(gameRowID int64, m *analyzer.MoveAnalysis) []any {
+	beforeCP, beforeMate := score(m.EvalBefore)
+	afterCP, afterMate := score(m.EvalAfter)
+	pv := m.PV
+	if pv == nil {
+		pv = []string{}
+	}
+	return []any{
+		gameRowID, m.Ply, m.MoveNumber, m.Color,
+		m.PlayedMove, m.PlayedMoveUCI, m.BestMove, m.BestMoveUCI, m.FENBefore,
+		beforeCP, beforeMate, afterCP, afterMate,
+		m.CentipawnLoss, string(m.Classification), pv, m.AchievedDepth, m.MoveAccuracy, m.Forced, m.GarbageTime,
+	}
+}
+
+// score splits an evaluation into its centipawn and mate columns, one of
+// which is null
+func score(eval engine.Evaluation) (cp, mate *int) {
+	if eval.IsMate && eval.MateIn != nil {
+		m := *eval.MateIn
+		return nil, &m
+	}
+	c := eval.Centipawns
+	return &c, nil
+}
+
+// unscore joins the centipawn and mate columns of an evaluation
+func unscore(cp, mate *int) engine.Evaluation {
+	if mate != nil {
+		m := *mate
+		return engine.Evaluation{IsMate: true, MateIn: &m}
+	}
+	var eval engine.Evaluation
+	if cp != nil {
+		eval.Centipawns = *cp
+	}
+	return eval
+}
+
+func moveRowIDs(ctx context.Context, tx pgx.Tx, gameRowID int64) ([]int64, error) {
+	rows, err := tx.Query(ctx, `SELECT id FROM move_analyses WHERE game_analysis_id = $1 ORDER BY ply`, gameRowID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
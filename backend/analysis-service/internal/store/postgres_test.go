@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"go.uber.org/zap"
+)
+
+// migratedColumns returns the columns each table has after the migrations
+func migratedColumns(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+	sql, err := migrations.ReadFile("migrations/0001_create_analysis_tables.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := make(map[string]map[string]bool)
+	var current map[string]bool
+	table := regexp.MustCompile(`^CREATE TABLE (\w+)`)
+	column := regexp.MustCompile(`^\s+(\w+)\s+[A-Z]`)
+	for _, line := range strings.Split(string(sql), "\n") {
+		if m := table.FindStringSubmatch(line); m != nil {
+			current = make(map[string]bool)
+			tables[m[1]] = current
+		} else if m := column.FindStringSubmatch(line); m != nil && current != nil && m[1] != "UNIQUE" {
+			current[m[1]] = true
+		}
+	}
+	return tables
+}
+
+func TestRowsMatchMigrations(t *testing.T) {
+	tables := migratedColumns(t)
+
+	columns, values := gameRow(&analyzer.GameAnalysis{GameID: "g1"})
+	if len(columns) != len(values) {
+		t.Fatalf("game row has %d columns and %d values", len(columns), len(values))
+	}
+	for _, col := range columns {
+		if !tables["game_analyses"][col] {
+			t.Errorf("game_analyses has no column %s", col)
+		}
+	}
+
+	scanned, _ := gameScanTargets(&analyzer.GameAnalysis{}, new(string))
+	if !slices.Equal(scanned, columns[1:]) {
+		t.Errorf("loaded columns %v, want those saved but game_id %v", scanned, columns[1:])
+	}
+
+	row := moveRow(1, &analyzer.MoveAnalysis{})
+	if len(row) != len(moveColumns) {
+		t.Fatalf("move row has %d values for %d columns", len(row), len(moveColumns))
+	}
+	for _, col := range moveColumns {
+		if !tables["move_analyses"][col] {
+			t.Errorf("move_analyses has no column %s", col)
+		}
+	}
+}
+
+func TestUpsertGameSQL_KeepsDeeperAnalysis(t *testing.T) {
+	sql := upsertGameSQL([]string{"game_id", "depth"})
+	want := "INSERT INTO game_analyses (game_id, depth) VALUES ($1, $2)" +
+		" ON CONFLICT (game_id) DO UPDATE SET depth = EXCLUDED.depth, updated_at = now()" +
+		" WHERE game_analyses.depth <= EXCLUDED.depth RETURNING id"
+	if sql != want {
+		t.Errorf("upsertGameSQL =\n%s\nwant\n%s", sql, want)
+	}
+}
+
+func testAnalysis(depth int) *analyzer.GameAnalysis {
+	mate := 2
+	a := &analyzer.GameAnalysis{
+		GameID: "store-test-game",
+		Depth:  depth,
+		Moves: []analyzer.MoveAnalysis{
+			{Ply: 0, MoveNumber: 1, Color: "white", PlayedMove: "e4", EvalBefore: engine.Evaluation{Centipawns: 30}, EvalAfter: engine.Evaluation{Centipawns: -25}, Classification: analyzer.ClassBest},
+			{Ply: 1, MoveNumber: 1, Color: "black", PlayedMove: "f6", EvalBefore: engine.Evaluation{Centipawns: -25}, EvalAfter: engine.Evaluation{IsMate: true, MateIn: &mate}, Classification: analyzer.ClassBlunder},
+		},
+		WhiteMetrics: analyzer.GameMetrics{Accuracy: 100, TotalMoves: 1},
+		BlackMetrics: analyzer.GameMetrics{Accuracy: 12.5, Blunders: 1, TotalMoves: 1},
+		TotalMoves:   2,
+	}
+	a.Checksum = analyzer.Checksum(a)
+	return a
+}
+
+func TestSaveGameAnalysis_RefusesBadChecksum(t *testing.T) {
+	// Refused before the database is touched
+	p := &Postgres{logger: zap.NewNop()}
+	truncated := testAnalysis(18)
+	truncated.Moves = truncated.Moves[:1]
+	if _, err := p.SaveGameAnalysis(context.Background(), truncated); !errors.Is(err, analyzer.ErrChecksumMismatch) {
+		t.Errorf("truncated analysis: err = %v, want ErrChecksumMismatch", err)
+	}
+}
+
+// TestSaveGameAnalysis runs against the database in POSTGRES_TEST_DSN
+func TestSaveGameAnalysis(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN not set")
+	}
+	ctx := context.Background()
+
+	p, err := Open(ctx, dsn, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if _, err := p.pool.Exec(ctx, `DELETE FROM game_analyses WHERE game_id = 'store-test-game'`); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := p.SaveGameAnalysis(ctx, testAnalysis(18))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.KeptExisting || len(first.MoveRowIDs) != 2 {
+		t.Fatalf("first save = %+v, want a new game with 2 moves", first)
+	}
+
+	// Shallower re-analysis keeps the stored rows
+	shallow, err := p.SaveGameAnalysis(ctx, testAnalysis(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !shallow.KeptExisting || shallow.GameRowID != first.GameRowID || shallow.MoveRowIDs[0] != first.MoveRowIDs[0] {
+		t.Errorf("shallower save = %+v, want the first rows kept", shallow)
+	}
+
+	// Deeper re-analysis replaces them in the same game row
+	deep, err := p.SaveGameAnalysis(ctx, testAnalysis(24))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deep.KeptExisting || deep.GameRowID != first.GameRowID || deep.MoveRowIDs[0] == first.MoveRowIDs[0] {
+		t.Errorf("deeper save = %+v, want new moves in game row %d", deep, first.GameRowID)
+	}
+
+	var depth, mate int
+	err = p.pool.QueryRow(ctx, `SELECT g.depth, m.eval_after_mate FROM game_analyses g
+		JOIN move_analyses m ON m.game_analysis_id = g.id WHERE g.id = $1 AND m.ply = 1`, deep.GameRowID).Scan(&depth, &mate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depth != 24 || mate != 2 {
+		t.Errorf("stored depth = %d, mate = %d, want 24 and 2", depth, mate)
+	}
+
+	// What is read back has the checksum of what was saved
+	loaded, err := p.LoadGameAnalysis(ctx, "store-test-game")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := testAnalysis(24); loaded.Checksum != want.Checksum || loaded.Depth != 24 || !loaded.Moves[1].EvalAfter.IsMate {
+		t.Errorf("loaded %+v, want checksum %s", loaded, want.Checksum)
+	}
+	ids, err := p.GameIDs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(ids, "store-test-game") {
+		t.Errorf("game IDs %v don't list the stored game", ids)
+	}
+
+	// Migrating again is a no-op
+	if err := p.migrate(ctx); err != nil {
+		t.Errorf("second migrate: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package analyzer
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// CacheAdmission is the position cache's admission policy: which searched
+// positions new to the cache it keeps. Opening positions are shared across
+// games and produce the cache's hits; a middlegame or endgame position is
+// rarely reached again, and caching it evicts one that would be.
+type CacheAdmission struct {
+	// Positions up to Plies into the game, or with at least Pieces on the
+	// board, kings included, are always admitted (0 = no such threshold)
+	Plies  int
+	Pieces int
+
+	// Other positions are admitted from their Sightings-th search, as a
+	// TinyLFU-style frequency sketch of the positions searched counts
+	// them; 1 or less admits them all
+	Sightings int
+}
+
+// admissionPolicy is a CacheAdmission at work, with the sketch it counts
+// searches in and what it decided
+type admissionPolicy struct {
+	CacheAdmission
+	sketch      *frequencySketch
+	admitted    int64
+	notAdmitted int64
+}
+
+// SetAdmission turns on an admission policy for positions the cache
+// doesn't hold yet. Imported and seed evaluations, and searches made to
+// warm the cache, are admitted regardless.
+func (c *PositionCache) SetAdmission(policy CacheAdmission) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.admission = &admissionPolicy{CacheAdmission: policy, sketch: newFrequencySketch(c.maxSize)}
+}
+
+// admit reports whether the admission policy lets fen, new to the cache
+// under key, in, and counts the decision (must be called with lock held).
+// Without a policy every position is admitted.
+func (c *PositionCache) admit(key, fen string) bool {
+	p := c.admission
+	if p == nil {
+		return true
+	}
+	if p.early(fen) || p.sketch.add(key) >= p.Sightings {
+		p.admitted++
+		return true
+	}
+	p.notAdmitted++
+	return false
+}
+
+// early reports whether fen is within the plies or pieces admitted
+// without counting
+func (p *admissionPolicy) early(fen string) bool {
+	if p.Plies > 0 {
+		if ply, ok := fenPly(fen); ok && ply <= p.Plies {
+			return true
+		}
+	}
+	return p.Pieces > 0 && fenPieces(fen) >= p.Pieces
+}
+
+// fenPly returns the ply of fen's position from its fullmove number and
+// side to move, false when it has no fullmove number
+func fenPly(fen string) (int, bool) {
+	fields := strings.Fields(fen)
+	if len(fields) < 6 {
+		return 0, false
+	}
+	moveNumber, err := strconv.Atoi(fields[5])
+	if err != nil || moveNumber < 1 {
+		return 0, false
+	}
+	ply := 2 * (moveNumber - 1)
+	if fields[1] == "b" {
+		ply++
+	}
+	return ply, true
+}
+
+// fenPieces returns the number of pieces on fen's board, kings and pawns
+// included
+func fenPieces(fen string) int {
+	board, _, _ := strings.Cut(fen, " ")
+	n := 0
+	for _, c := range board {
+		if c != '/' && (c < '0' || c > '9') {
+			n++
+		}
+	}
+	return n
+}
+
+const (
+	// sketchRows is the number of counters a key has, one per row; its
+	// count is the smallest, the one least inflated by other keys
+	sketchRows = 4
+
+	// sketchMaxCount saturates counters, as TinyLFU's 4-bit ones
+	sketchMaxCount = 15
+
+	// sketchResetFactor times the cache size is how many additions the
+	// sketch counts before it halves every counter, so positions popular
+	// long ago don't stay admitted
+	sketchResetFactor = 10
+)
+
+// frequencySketch is a count-min sketch of how often keys were added:
+// each key has a counter in every row, at an index of its hash
+type frequencySketch struct {
+	rows       [sketchRows][]uint8
+	mask       uint64
+	additions  int
+	resetAfter int
+}
+
+// newFrequencySketch returns a sketch for a cache of capacity entries.
+// Most positions between resets are distinct, so each row has a counter
+// for every addition until the reset, lest the counts of positions seen
+// once add up to an admission.
+func newFrequencySketch(capacity int) *frequencySketch {
+	resetAfter := sketchResetFactor * max(capacity, 1)
+	width := 64
+	for width < resetAfter {
+		width *= 2
+	}
+	s := &frequencySketch{mask: uint64(width - 1), resetAfter: resetAfter}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// add counts key once and returns its estimated count, this one included
+func (s *frequencySketch) add(key string) int {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	// Double hashing: the rows' indexes are sum + i*step
+	step := sum>>32 | 1
+
+	count := sketchMaxCount
+	for i := range s.rows {
+		counter := &s.rows[i][(sum+uint64(i)*step)&s.mask]
+		if *counter < sketchMaxCount {
+			*counter++
+		}
+		count = min(count, int(*counter))
+	}
+
+	s.additions++
+	if s.additions >= s.resetAfter {
+		s.halve()
+	}
+	return count
+}
+
+// halve halves every counter and the additions counted
+func (s *frequencySketch) halve() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] /= 2
+		}
+	}
+	s.additions /= 2
+}
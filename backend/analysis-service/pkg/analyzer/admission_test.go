@@ -0,0 +1,190 @@
+package analyzer
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+)
+
+func TestPositionCache_Admission(t *testing.T) {
+	const middlegame = "r1bq1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N2N2/PP2BPPP/R2QKB1R w KQ - 4 9"
+	c := NewPositionCache(100)
+	c.SetAdmission(CacheAdmission{Plies: 4, Sightings: 2})
+	set := func(fen string, depth int) {
+		c.Set(PrimaryEngine, fen, depth, engine.Evaluation{Depth: depth}, "e2e4", engine.SourceEngine)
+	}
+	cached := func(fen string) bool { return c.has(PrimaryEngine, fen, CacheQuery{Depth: 1}) }
+
+	// The opening is admitted at once
+	set(startFEN, 12)
+	if !cached(startFEN) {
+		t.Error("start position not admitted")
+	}
+
+	// A middlegame position once it is searched again
+	set(middlegame, 12)
+	if cached(middlegame) {
+		t.Fatal("middlegame position admitted on its first search")
+	}
+	set(middlegame, 12)
+	if !cached(middlegame) {
+		t.Fatal("middlegame position not admitted on its second search")
+	}
+
+	// Deeper searches of a cached position, imports and warming searches
+	// aren't asked
+	set(middlegame, 16)
+	c.Import(PrimaryEngine, "4k3/8/8/8/8/8/4P3/4K3 w - - 0 60", 30, engine.Evaluation{Depth: 30}, "e2e4")
+	c.setResult(PrimaryEngine, "4k3/8/8/8/8/8/3P4/4K3 w - - 0 60", 12, 1, &engine.AnalysisResult{
+		Evaluations: []engine.Evaluation{{Depth: 12}}, BestMove: "d2d4", Source: engine.SourceEngine,
+	}, true)
+	stats := c.Stats()
+	if stats.Size != 4 || stats.Admitted != 2 || stats.NotAdmitted != 1 {
+		t.Errorf("size %d, admitted %d, not admitted %d; want 4, 2 and 1", stats.Size, stats.Admitted, stats.NotAdmitted)
+	}
+	if got, _ := c.Get(PrimaryEngine, middlegame, CacheQuery{Depth: 16}); got == nil {
+		t.Error("deeper search of the admitted position not stored")
+	}
+}
+
+func TestPositionCache_AdmissionByPieces(t *testing.T) {
+	c := NewPositionCache(100)
+	c.SetAdmission(CacheAdmission{Pieces: 30, Sightings: 2})
+
+	// A FEN without move numbers is judged by its pieces alone
+	full := "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq -"
+	traded := "r1bqkb1r/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/R1BQKB1R w KQkq - 0 6"
+	for _, fen := range []string{full, traded} {
+		c.Set(PrimaryEngine, fen, 12, engine.Evaluation{Depth: 12}, "e1g1", engine.SourceEngine)
+	}
+	if stats := c.Stats(); stats.Admitted != 1 || stats.NotAdmitted != 1 || !c.has(PrimaryEngine, full, CacheQuery{Depth: 12}) {
+		t.Errorf("stats = %+v, want the 32-piece position admitted and the 28-piece one not", stats)
+	}
+
+	// Without a policy nothing is counted
+	if stats := NewPositionCache(100).Stats(); stats.Admitted != 0 || stats.NotAdmitted != 0 {
+		t.Errorf("stats without a policy = %+v", stats)
+	}
+}
+
+func TestFENPlyAndPieces(t *testing.T) {
+	tests := []struct {
+		fen    string
+		ply    int
+		hasPly bool
+		pieces int
+	}{
+		{startFEN, 0, true, 32},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", 1, true, 32},
+		{"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", 4, true, 32},
+		{"4k3/8/8/8/8/8/4P3/4K3 b - - 0 60", 119, true, 3},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - -", 0, false, 3},
+	}
+	for _, tt := range tests {
+		ply, ok := fenPly(tt.fen)
+		if ply != tt.ply || ok != tt.hasPly {
+			t.Errorf("%s: ply %d, %v; want %d, %v", tt.fen, ply, ok, tt.ply, tt.hasPly)
+		}
+		if pieces := fenPieces(tt.fen); pieces != tt.pieces {
+			t.Errorf("%s: %d pieces, want %d", tt.fen, pieces, tt.pieces)
+		}
+	}
+}
+
+func TestFrequencySketch(t *testing.T) {
+	s := newFrequencySketch(100)
+	for i := 1; i <= 20; i++ {
+		if got, want := s.add("popular"), min(i, sketchMaxCount); got != want {
+			t.Fatalf("addition %d counted %d, want %d", i, got, want)
+		}
+	}
+	if got := s.add("rare"); got != 1 {
+		t.Errorf("first addition of another key counted %d", got)
+	}
+
+	// Every resetAfter additions the counts halve
+	for s.additions != 0 && s.additions < s.resetAfter-1 {
+		s.add("filler")
+	}
+	s.add("filler")
+	if got := s.add("popular"); got != sketchMaxCount/2+1 {
+		t.Errorf("after halving, count %d, want %d", got, sketchMaxCount/2+1)
+	}
+}
+
+// replayFENs returns the positions of the replay corpus, parsed once
+var replayFENs = sync.OnceValues(func() ([]string, error) {
+	pgn, err := os.ReadFile("testdata/replay_games.pgn")
+	if err != nil {
+		return nil, err
+	}
+	return PGNPositions(string(pgn))
+})
+
+// replayHitRate replays the searches of analyzing every game of the replay
+// corpus, in order, against a cache of size entries: each position is
+// looked up and, on a miss, searched and stored
+func replayHitRate(tb testing.TB, size int, admission *CacheAdmission) CacheStats {
+	tb.Helper()
+	fens, err := replayFENs()
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	c := NewPositionCache(size)
+	if admission != nil {
+		c.SetAdmission(*admission)
+	}
+	for _, fen := range fens {
+		if _, found := c.get(PrimaryEngine, fen, CacheQuery{Depth: 12}); !found {
+			c.Set(PrimaryEngine, fen, 12, engine.Evaluation{Depth: 12}, "e2e4", engine.SourceEngine)
+		}
+	}
+	return c.Stats()
+}
+
+// TestCacheAdmission_Replay checks the policy against the replay corpus,
+// real games from Anderssen's to the 2021 World Cup: with a cache much
+// smaller than the games, admitting everything lets the middlegames evict
+// the openings the games share
+func TestCacheAdmission_Replay(t *testing.T) {
+	unbounded := replayHitRate(t, 1_000_000, nil)
+	all := replayHitRate(t, 100, nil)
+	admitted := replayHitRate(t, 100, &CacheAdmission{Plies: 8, Sightings: 2})
+	t.Logf("hits: %d unbounded, %d admitting all, %d with the policy (%d not admitted)",
+		unbounded.Hits, all.Hits, admitted.Hits, admitted.NotAdmitted)
+
+	if admitted.Hits <= all.Hits {
+		t.Errorf("%d hits with the policy, %d without", admitted.Hits, all.Hits)
+	}
+	if admitted.Size > 100 || admitted.NotAdmitted == 0 {
+		t.Errorf("cache of %d entries, %d positions not admitted", admitted.Size, admitted.NotAdmitted)
+	}
+}
+
+// BenchmarkCacheAdmission_Replay reports the hit rate of the replay corpus
+// with and without the policy: go test -bench CacheAdmission -run ^$
+func BenchmarkCacheAdmission_Replay(b *testing.B) {
+	for _, bench := range []struct {
+		name      string
+		admission *CacheAdmission
+	}{
+		{"admit_all", nil},
+		{"plies_8", &CacheAdmission{Plies: 8, Sightings: 2}},
+		{"pieces_32", &CacheAdmission{Pieces: 32, Sightings: 2}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			if _, err := replayFENs(); err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			var stats CacheStats
+			for range b.N {
+				stats = replayHitRate(b, 100, bench.admission)
+			}
+			b.ReportMetric(stats.HitRate, "hit%")
+		})
+	}
+}
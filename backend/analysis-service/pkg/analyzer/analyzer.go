@@ -0,0 +1,2039 @@
+// Package analyzer analyzes chess games and positions with a pool of UCI
+// engines: it parses PGN, evaluates every position once, and classifies
+// each move and scores both players.
+//
+// GameAnalysis, MoveAnalysis and GameMetrics, with Analyzer's AnalyzeGame
+// and AnalyzePosition and the GameOptions they take, are the stable API:
+// fields are only ever added. Other exported identifiers may change
+// between releases.
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"github.com/eloinsight/analysis-service/pkg/timing"
+	"github.com/notnil/chess"
+	"go.uber.org/zap"
+)
+
+// PositionCache caches analysis results to avoid re-analyzing common positions
+// This is especially effective for opening positions shared across many games
+//
+// It holds one entry per engine profile and position, the deepest search
+// seen, which answers any request up to that depth and its number of PVs
+// (see CacheQuery). A deeper request searches again; UCI has no way to
+// seed a search with a known line, but the engine's hash table keeps what
+// it found last time.
+//
+// Imported evaluations don't count towards maxSize and are never evicted,
+// so a large opening import can't push out recent results or be pushed
+// out by them.
+//
+// Keys leave out the halfmove clock, but close to the fifty-move rule the
+// clock changes the evaluation: positions with a clock above
+// maxHalfmoveClock are neither served nor stored.
+//
+// With an admission policy (see SetAdmission) searches of positions past
+// the opening are only cached once the position is searched again.
+type PositionCache struct {
+	mu               sync.RWMutex
+	cache            map[string]cachedEvaluation
+	maxSize          int
+	maxHalfmoveClock int
+	hits             int64
+	misses           int64
+	nearMisses       int64
+	bySource         map[string]int           // Entries per source
+	rejected         map[CacheRejection]int64 // Searches kept out, see cacheable
+	admission        *admissionPolicy         // nil admits every position
+}
+
+// DefaultMaxHalfmoveClock is the halfmove clock above which the position
+// cache is bypassed, 20 plies before the fifty-move rule
+const DefaultMaxHalfmoveClock = 80
+
+// CacheQuery is what a request needs of a cached entry. An entry answers
+// it when its search was at least as deep, asked for at least as many PVs
+// and, with NeedPV, kept the lines of its PVs: imported and seed entries
+// only know the best move.
+type CacheQuery struct {
+	Depth   int
+	MultiPV int  // PVs wanted; 0 is 1
+	NeedPV  bool // The PVs' lines are used, not just their scores
+}
+
+// cachedEvaluation is a cached search: its result, every PV it reported
+// and its stats, and the request parameters it answers
+type cachedEvaluation struct {
+	result    engine.AnalysisResult
+	multiPV   int  // PVs the search was asked for
+	fullPV    bool // Evaluations hold the search's lines
+	source    string
+	depth     int
+	timestamp time.Time
+}
+
+// evaluation is the entry's first PV
+func (e cachedEvaluation) evaluation() engine.Evaluation {
+	if len(e.result.Evaluations) == 0 {
+		return engine.Evaluation{}
+	}
+	return e.result.Evaluations[0]
+}
+
+// answers reports whether the entry satisfies q
+func (e cachedEvaluation) answers(q CacheQuery) bool {
+	return e.depth >= q.Depth && e.multiPV >= max(q.MultiPV, 1) && (e.fullPV || !q.NeedPV)
+}
+
+// answer returns the entry as a result for q, with only the PVs q asks for
+// and none of the time or pool fields of the search
+func (e cachedEvaluation) answer(q CacheQuery) *engine.AnalysisResult {
+	n := min(max(q.MultiPV, 1), len(e.result.Evaluations))
+	return &engine.AnalysisResult{
+		Evaluations: append([]engine.Evaluation(nil), e.result.Evaluations[:n]...),
+		PVCount:     n,
+		BestMove:    e.result.BestMove,
+		PonderMove:  e.result.PonderMove,
+		Depth:       e.result.Depth,
+		Source:      e.source,
+	}
+}
+
+// NewPositionCache creates a new position cache
+func NewPositionCache(maxSize int) *PositionCache {
+	if maxSize <= 0 {
+		maxSize = 10000 // Default 10k positions
+	}
+	return &PositionCache{
+		cache:            make(map[string]cachedEvaluation),
+		maxSize:          maxSize,
+		maxHalfmoveClock: DefaultMaxHalfmoveClock,
+		bySource:         make(map[string]int),
+		rejected:         make(map[CacheRejection]int64),
+	}
+}
+
+// SetMaxHalfmoveClock sets the halfmove clock above which positions are
+// neither served from nor stored in the cache
+func (c *PositionCache) SetMaxHalfmoveClock(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxHalfmoveClock = n
+}
+
+// nearFiftyMoves reports whether fen's halfmove clock is above the limit
+// (must be called with lock held). FENs without one have a fresh clock.
+func (c *PositionCache) nearFiftyMoves(fen string) bool {
+	parts := strings.Fields(fen)
+	if len(parts) < 5 {
+		return false
+	}
+	clock, err := strconv.Atoi(parts[4])
+	return err == nil && clock > c.maxHalfmoveClock
+}
+
+// cacheKey creates a unique key for engine profile + FEN, so different
+// engines never answer for each other
+func (c *PositionCache) cacheKey(engineProfile, fen string) string {
+	// Only use the position part of FEN (first 4 fields) to normalize
+	// This ignores halfmove clock and fullmove number
+	parts := strings.Fields(fen)
+	if len(parts) >= 4 {
+		return fmt.Sprintf("%s|%s %s %s %s", engineProfile, parts[0], parts[1], parts[2], parts[3])
+	}
+	return fmt.Sprintf("%s|%s", engineProfile, fen)
+}
+
+// Get retrieves a cached result by an engine profile if one answers q,
+// with the PVs q asks for
+func (c *PositionCache) Get(engineProfile, fen string, q CacheQuery) (*engine.AnalysisResult, bool) {
+	cached, ok := c.get(engineProfile, fen, q)
+	if !ok {
+		return nil, false
+	}
+	return cached.answer(q), true
+}
+
+func (c *PositionCache) get(engineProfile, fen string, q CacheQuery) (cachedEvaluation, bool) {
+	return c.getWithin(engineProfile, fen, q, 0)
+}
+
+// getWithin is get accepting an entry up to margin depths shallower than
+// q asks for, which counts as a hit
+func (c *PositionCache) getWithin(engineProfile, fen string, q CacheQuery, margin int) (cachedEvaluation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := c.cacheKey(engineProfile, fen)
+	if cached, ok := c.cache[key]; ok && !c.nearFiftyMoves(fen) {
+		shallower := q
+		shallower.Depth -= margin
+		if cached.answers(shallower) {
+			c.hits++
+			return cached, true
+		}
+		// Cached, but too shallow, with too few PVs or without their lines
+		c.nearMisses++
+		return cachedEvaluation{}, false
+	}
+	c.misses++
+	return cachedEvaluation{}, false
+}
+
+// has reports whether get would find an entry answering q, without
+// counting a hit or miss
+func (c *PositionCache) has(engineProfile, fen string, q CacheQuery) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.cache[c.cacheKey(engineProfile, fen)]
+	return ok && cached.answers(q) && !c.nearFiftyMoves(fen)
+}
+
+// Set stores a single-PV evaluation by an engine profile in the cache;
+// source is where it came from (engine.SourceEngine or
+// engine.SourceCloud). It is SetResult of a result holding just eval.
+func (c *PositionCache) Set(engineProfile, fen string, depth int, eval engine.Evaluation, bestMove, source string) {
+	c.SetResult(engineProfile, fen, depth, 1, &engine.AnalysisResult{
+		Evaluations: []engine.Evaluation{eval},
+		PVCount:     1,
+		BestMove:    bestMove,
+		Depth:       eval.Depth,
+		Source:      source,
+	})
+}
+
+// SetResult stores a complete search to depth for multiPV PVs by an
+// engine profile in the cache, from result.Source. A shallower search than
+// the cached one is dropped, as is one of the same depth the cached entry
+// already answers for; a deeper one replaces the entry even with fewer
+// PVs. A position new to the cache is only stored if the admission
+// policy admits it.
+func (c *PositionCache) SetResult(engineProfile, fen string, depth, multiPV int, result *engine.AnalysisResult) {
+	c.setResult(engineProfile, fen, depth, multiPV, result, false)
+}
+
+// setResult is SetResult, storing a new position without asking the
+// admission policy when admitted is set, as for a search made to warm the
+// cache
+func (c *PositionCache) setResult(engineProfile, fen string, depth, multiPV int, result *engine.AnalysisResult, admitted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.nearFiftyMoves(fen) {
+		return
+	}
+	key := c.cacheKey(engineProfile, fen)
+	if existing, ok := c.cache[key]; ok {
+		if existing.depth > depth || existing.depth == depth && existing.answers(CacheQuery{Depth: depth, MultiPV: multiPV, NeedPV: true}) {
+			return
+		}
+	} else if !admitted && !c.admit(key, fen) {
+		return
+	}
+	c.store(key, depth, max(multiPV, 1), true, result)
+}
+
+// Import stores an evaluation from an evaluation database with source
+// engine.SourceImported, unless the cache already holds one at least as
+// deep. It reports whether the evaluation was stored.
+func (c *PositionCache) Import(engineProfile, fen string, depth int, eval engine.Evaluation, bestMove string) bool {
+	return c.importAs(engineProfile, fen, depth, eval, bestMove, engine.SourceImported)
+}
+
+// importAs stores an evaluation from source unless the cache already holds
+// one at least as deep, and reports whether it was stored. Its PV is no
+// more than the best move, so it doesn't answer queries needing PVs.
+func (c *PositionCache) importAs(engineProfile, fen string, depth int, eval engine.Evaluation, bestMove, source string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.nearFiftyMoves(fen) {
+		return false
+	}
+	key := c.cacheKey(engineProfile, fen)
+	if existing, ok := c.cache[key]; ok && existing.depth >= depth {
+		return false
+	}
+	c.store(key, depth, 1, false, &engine.AnalysisResult{
+		Evaluations: []engine.Evaluation{eval},
+		PVCount:     1,
+		BestMove:    bestMove,
+		Depth:       eval.Depth,
+		Source:      source,
+	})
+	return true
+}
+
+// store adds or replaces an entry with a copy of result (must be called
+// with lock held)
+func (c *PositionCache) store(key string, depth, multiPV int, fullPV bool, result *engine.AnalysisResult) {
+	existing, ok := c.cache[key]
+	if ok {
+		c.bySource[existing.source]--
+	}
+
+	// Simple eviction: if at capacity, remove oldest entries
+	source := result.Source
+	if !ok && source != engine.SourceImported && len(c.cache)-c.bySource[engine.SourceImported] >= c.maxSize {
+		c.evictOldest(c.maxSize / 10) // Remove 10% oldest
+	}
+
+	stored := *result
+	stored.Evaluations = append([]engine.Evaluation(nil), result.Evaluations...)
+	c.cache[key] = cachedEvaluation{
+		result:    stored,
+		multiPV:   multiPV,
+		fullPV:    fullPV,
+		source:    source,
+		depth:     depth,
+		timestamp: time.Now(),
+	}
+	c.bySource[source]++
+}
+
+// evictOldest removes the n oldest entries, leaving imported ones (must be
+// called with lock held)
+func (c *PositionCache) evictOldest(n int) {
+	if n <= 0 || len(c.cache) == 0 {
+		return
+	}
+
+	// Simple approach: find and remove oldest entries
+	type entry struct {
+		key    string
+		ts     time.Time
+		source string
+	}
+	entries := make([]entry, 0, len(c.cache))
+	for k, v := range c.cache {
+		if v.source != engine.SourceImported {
+			entries = append(entries, entry{k, v.timestamp, v.source})
+		}
+	}
+
+	// Sort by timestamp (oldest first) - simple bubble for small n
+	for i := 0; i < n && i < len(entries); i++ {
+		for j := i + 1; j < len(entries); j++ {
+			if entries[j].ts.Before(entries[i].ts) {
+				entries[i], entries[j] = entries[j], entries[i]
+			}
+		}
+		delete(c.cache, entries[i].key)
+		c.bySource[entries[i].source]--
+	}
+}
+
+// CacheStats are position cache statistics. Misses found no entry for the
+// position; near misses found one that didn't answer the query, a sign
+// the cached searches are shallower or narrower than requests need.
+// Rejected searches were kept out of the cache as implausible, and
+// quarantined engines replaced for too many of them. Admitted and
+// NotAdmitted count the new positions the admission policy let in and
+// kept out, 0 without one.
+type CacheStats struct {
+	Size       int
+	Hits       int64
+	Misses     int64
+	NearMisses int64
+	HitRate    float64 // Percent of queries answered
+
+	Rejected           int64
+	Rejections         map[CacheRejection]int64 // Rejected by reason
+	QuarantinedEngines int64
+
+	Admitted    int64
+	NotAdmitted int64
+}
+
+// Stats returns cache statistics
+func (c *PositionCache) Stats() CacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := CacheStats{Size: len(c.cache), Hits: c.hits, Misses: c.misses, NearMisses: c.nearMisses}
+	if total := c.hits + c.misses + c.nearMisses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total) * 100
+	}
+	stats.Rejections = make(map[CacheRejection]int64, len(c.rejected))
+	for reason, n := range c.rejected {
+		stats.Rejections[reason] = n
+		stats.Rejected += n
+	}
+	if c.admission != nil {
+		stats.Admitted, stats.NotAdmitted = c.admission.admitted, c.admission.notAdmitted
+	}
+	return stats
+}
+
+// reject counts a search kept out of the cache for reason
+func (c *PositionCache) reject(reason CacheRejection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rejected[reason]++
+}
+
+// SizeBySource returns the number of entries from each source
+func (c *PositionCache) SizeBySource() map[string]int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make(map[string]int, len(c.bySource))
+	for source, n := range c.bySource {
+		if n > 0 {
+			out[source] = n
+		}
+	}
+	return out
+}
+
+// MoveClassification represents the quality of a move
+type MoveClassification string
+
+const (
+	ClassBrilliant  MoveClassification = "brilliant"
+	ClassGreat      MoveClassification = "great"
+	ClassBest       MoveClassification = "best"
+	ClassExcellent  MoveClassification = "excellent"
+	ClassGood       MoveClassification = "good"
+	ClassBook       MoveClassification = "book"
+	ClassNormal     MoveClassification = "normal"
+	ClassInaccuracy MoveClassification = "inaccuracy"
+	ClassMistake    MoveClassification = "mistake"
+	ClassBlunder    MoveClassification = "blunder"
+	ClassMissedWin  MoveClassification = "missed_win"
+)
+
+// MoveAnalysis holds analysis for a single move
+type MoveAnalysis struct {
+	MoveNumber     int
+	Ply            int
+	Color          string // "white" or "black"
+	PlayedMove     string // SAN
+	PlayedMoveUCI  string
+	BestMove       string // SAN
+	BestMoveUCI    string
+	FENBefore      string
+	FENAfter       string
+	EvalBefore     engine.Evaluation
+	EvalAfter      engine.Evaluation
+	CentipawnLoss  int
+	Classification MoveClassification
+	PV             []string // Line of the search that reached AchievedDepth
+
+	// AchievedDepth is the depth of the evaluation before the move, which
+	// can exceed RequestedDepth when a deeper search was cached
+	AchievedDepth  int
+	RequestedDepth int
+	FromCache      bool // The evaluation before the move came from the cache
+
+	// Source is where the move's evaluation came from or why it doesn't
+	// count toward the metrics
+	Source AnalysisSource
+
+	// MoveAccuracy is 0-100 from the drop in the mover's win probability;
+	// forced moves (the only legal one) score 100 and are left out of
+	// move-mean game accuracy
+	MoveAccuracy float64
+	Forced       bool
+
+	// ExpectedPointsLoss is the expected points, a win counting 1 and a
+	// draw half, the move gave away from the mover's point of view, e.g.
+	// 0.4 for a move from a won position to an equal one; see
+	// ExpectedPointsLoss
+	ExpectedPointsLoss float64
+
+	// GarbageTime is set for moves made in a decided position, beyond the
+	// thresholds' garbage time window; they are classified but left out
+	// of accuracy and ACPL
+	GarbageTime bool
+
+	// MissedRepetition is set when a losing mover could have drawn by
+	// threefold repetition and didn't; AllowedRepetition when a winning
+	// mover repeated or let the opponent repeat. Winning and losing are
+	// from RepetitionAdvantage.
+	MissedRepetition  bool
+	AllowedRepetition bool
+
+	// Material of both sides around the move, and the material the mover
+	// gave up by two plies after it (negative when they won material)
+	MaterialBefore     Material
+	MaterialAfter      Material
+	MaterialSacrificed int
+
+	// Explanation is a sentence on the move for players, e.g. "Missed mate
+	// in 3 starting with Qh7+."; see ExplainMove
+	Explanation string
+
+	// AnalyzedAt is when both evaluations were available (Unix ms);
+	// EngineTimeMs is the engine's own search time for the position
+	// before the move, without queueing, and 0 when it wasn't searched
+	AnalyzedAt   int64
+	EngineTimeMs int64
+
+	// QueueTimeMs and SearchTimeMs split the wall clock time of getting
+	// the evaluation before the move between waiting for an engine and
+	// searching, retries included; 0 when it wasn't searched
+	QueueTimeMs  int64
+	SearchTimeMs int64
+
+	// MovetimeMs is the search time the game's time budget allotted the
+	// position before the move, over both passes; 0 without a budget
+	MovetimeMs int64
+}
+
+// GameMetrics holds aggregated metrics for a player
+type GameMetrics struct {
+	Accuracy          float64
+	ACPL              float64
+	Blunders          int
+	Mistakes          int
+	Inaccuracies      int
+	GoodMoves         int
+	ExcellentMoves    int
+	BestMoves         int
+	BrilliantMoves    int
+	BookMoves         int
+	TotalMoves        int
+	PerformanceRating int
+	GarbageTimeMoves  int // Of TotalMoves, left out of accuracy and ACPL
+
+	// ExpectedPointsLost is the sum of the ExpectedPointsLoss of the
+	// player's moves, garbage time and book moves included: the points
+	// they gave away over the game
+	ExpectedPointsLost float64
+
+	// Standard deviations of Accuracy and ACPL from the noise of the
+	// evaluations at the depths each move was searched to, see
+	// evaluation.EvalNoise. Deeper analyses have tighter bands.
+	AccuracyStddev float64
+	ACPLStddev     float64
+
+	// AccuracyModel is the model Accuracy was calculated with
+	AccuracyModel evaluation.AccuracyModel
+
+	// Resilience needs the game result and is nil when it is unknown
+	Resilience *evaluation.Resilience
+}
+
+// GameAnalysis holds the complete game analysis
+type GameAnalysis struct {
+	GameID        string
+	Info          GameInfo // From the PGN's tags
+	Moves         []MoveAnalysis
+	WhiteMetrics  GameMetrics
+	BlackMetrics  GameMetrics
+	TotalTimeMs   int64
+	StartedAt     int64 // Unix ms
+	CompletedAt   int64 // Unix ms
+	EngineVersion string
+	EngineProfile string // PrimaryEngine or a cross-check profile
+
+	// Depth is the effective search depth after clamping to the
+	// configured range, or the engine profile's fixed depth
+	Depth int
+
+	// TimedOut is set when the game budget ran out; Moves then holds only
+	// the moves analyzed in time, out of TotalMoves
+	TimedOut   bool
+	TotalMoves int
+
+	// FastMode is set when the game was long enough to be searched at the
+	// minimum depth instead of the requested one
+	FastMode bool
+
+	// Degraded is set when the engine pool was starved as the analysis
+	// started, so it was searched to the degraded depth of SetDegradation
+	// at most, without the pre-pass of a time budget and without searching
+	// alternatives to find book moves
+	Degraded bool
+
+	// CacheCoverage is the percentage of the game's positions evaluated
+	// without a search: cached, seeded from the prefix or finished
+	CacheCoverage float64
+
+	// Preliminary is set on the quick analysis of GameOptions.PreviewDepth,
+	// and on one returned because the deep pass after it was canceled
+	Preliminary bool
+
+	// PreviewKept counts the positions whose preview evaluation the deep
+	// pass kept instead of searching them again, see SetPreviewTolerance
+	PreviewKept int
+
+	// TimeBudgetMs is the requested time budget, 0 for none. BudgetUsedMs
+	// is the search time spent of it and BudgetUtilization its percentage.
+	TimeBudgetMs      int64
+	BudgetUsedMs      int64
+	BudgetUtilization float64
+
+	// Truncated is set when the PGN has a move that can't be played and
+	// only the moves before it, TotalMoves of them, were analyzed
+	Truncated       bool
+	TruncatedAtPly  int    // Ply of the first bad move
+	TruncationError string // What is wrong with it
+
+	// Termination and WinnerColor are how the game ended, from its result
+	// and Termination tag checked against the final position, WinnerColor
+	// "" for a draw or an unknown result. ResultAgainstRunOfPlay is set when
+	// a player won other than by checkmate although the final position is
+	// a dead draw or the loser was winning on the board, as after a flag
+	// fall or a resignation in a better position.
+	Termination            Termination
+	WinnerColor            string
+	ResultAgainstRunOfPlay bool
+
+	// Classification thresholds used, recorded so stored results remain
+	// interpretable if profiles change; the garbage time window is zero
+	// when garbage time wasn't excluded
+	ThresholdProfile string
+	Thresholds       evaluation.Thresholds
+
+	// How each player's clock related to their errors; nil when the PGN
+	// has no [%clk] comments
+	WhiteTime *TimeManagement
+	BlackTime *TimeManagement
+
+	// Engine failures met on the way and their retries
+	Diagnostics Diagnostics
+
+	// Config records the engine settings the game was searched with; nil
+	// in analyses stored before it was
+	Config *AnalysisConfigSnapshot
+
+	// SourceCounts counts the plies of the game by AnalysisSource, those
+	// left out of Moves included, so they add up to TotalMoves
+	SourceCounts map[AnalysisSource]int
+
+	// Summary is the one-line summary of the game for sharing, see
+	// Summarize
+	Summary string
+
+	// Checksum of the moves and metrics, see Checksum for what it covers.
+	// VerifyGameAnalysis checks a payload against it.
+	Checksum string
+
+	// pass is every position's evaluation, kept on a preview for the deep
+	// pass after it
+	pass *positionPass
+}
+
+// GameOptions holds per-request game analysis options
+type GameOptions struct {
+	// ThresholdProfile selects the classification thresholds
+	// (empty = analyzer default)
+	ThresholdProfile string
+
+	// EngineProfile selects the engine (empty = PrimaryEngine)
+	EngineProfile string
+
+	// ExcludeGarbageTime leaves moves made in decided positions out of
+	// accuracy and ACPL (nil = analyzer default)
+	ExcludeGarbageTime *bool
+
+	// AccuracyModel selects how the players' accuracy is scored (empty =
+	// AccuracyModelEloInsight, the analyzer's accuracy method). The other
+	// models count every move, garbage time included, as their sites do.
+	AccuracyModel evaluation.AccuracyModel
+
+	// AnalyzeUntilError analyzes the moves before an illegal or ambiguous
+	// move instead of rejecting the game
+	AnalyzeUntilError bool
+
+	// Result of the game in PGN notation, "1-0", "0-1" or "1/2-1/2", for
+	// the players' resilience (empty = the PGN's Result tag); resilience
+	// is left out for any other result
+	Result string
+
+	// StartPly is the first move searched. The positions before it take
+	// their evaluations from Prefix, one per ply, so the move at StartPly
+	// still has its centipawn loss.
+	StartPly int
+	Prefix   []PrefixEvaluation
+
+	// Cache is the request's use of the position cache. With Cache.Only
+	// the moves missing an evaluation are left out, as on a timeout.
+	Cache CacheOptions
+
+	// TimeBudget, when set, searches the uncached positions by movetime
+	// instead of to the depth, splitting the budget between them. A quick
+	// pre-pass finds the critical positions, which get a larger share.
+	TimeBudget time.Duration
+
+	// OnMetrics, when set, is called with both players' metrics so far
+	// every MetricsInterval analyzed moves
+	OnMetrics       MetricsCallback
+	MetricsInterval int
+
+	// OnChunk, when set, is called as each chunk of consecutive positions
+	// a worker took is searched; chunks of positions cut short by a
+	// timeout never are
+	OnChunk ChunkCallback
+
+	// OnEstimate, when set, is called with the analysis's estimate once
+	// the cache has been checked, before any search, and after each
+	// search finishes; searches cut short by a timeout aren't counted
+	OnEstimate EstimateCallback
+
+	// OnEngineOutput, when set, receives every UCI line of the searches of
+	// the game's positions, retries included. It is called from the
+	// workers, concurrently.
+	OnEngineOutput EngineOutputCallback
+
+	// FullDepth searches a game longer than the fast mode limit of
+	// SetGameLengthLimits to the requested depth anyway
+	FullDepth bool
+
+	// PreviewDepth, when below the game's depth, first analyzes the whole
+	// game at PreviewDepth and hands the analysis, marked Preliminary, to
+	// OnPreview before searching the game to its depth. The callbacks
+	// above only follow the deep pass, and each pass has the game
+	// timeout. A caller canceling the deep pass gets the preview back.
+	// Ignored with Cache.Only or a TimeBudget.
+	PreviewDepth int
+	OnPreview    PreviewCallback
+
+	preview bool // Keep the positions' evaluations for a deep pass
+}
+
+// EngineOutputCallback receives a UCI line, formatted as by
+// engine.Recorder, of the search of the position ply half-moves into a
+// game
+type EngineOutputCallback func(ply int, line string)
+
+type engineOutputKey struct{}
+
+// ProgressCallback is called for each move analyzed
+type ProgressCallback func(current, total int, move *MoveAnalysis)
+
+// MetricsCallback receives the metrics over the first analyzed moves
+type MetricsCallback func(analyzed int, white, black GameMetrics)
+
+// ChunkCallback receives the number of chunks of a game's positions
+// searched so far and of all of them
+type ChunkCallback func(completed, total int)
+
+// Analyzer performs chess game analysis
+type Analyzer struct {
+	pool         *pool.Pool
+	logger       *zap.Logger
+	minDepth     int
+	defaultDepth int
+	maxDepth     int
+	timeout      time.Duration  // Budget for one position search or one whole game
+	posCache     *PositionCache // Cache for analyzed positions
+	cacheGuard   cacheGuard     // Engines whose searches the cache rejects
+
+	profiles       map[string]evaluation.Thresholds
+	defaultProfile string
+	accuracyMethod evaluation.AccuracyMethod
+	excludeGarbage bool // Default for GameOptions.ExcludeGarbageTime
+
+	// Effective time left, by time class, below which a player is in a
+	// time scramble
+	scrambleThresholds map[string]time.Duration
+
+	// Extra engines games can be cross-checked with, by name
+	engines map[string]EngineProfile
+
+	// Optional fallback for single-PV positions when the pool is busy
+	cloud         CloudEvaluator
+	cloudWait     time.Duration // Pool wait before asking the cloud
+	cloudMaxDepth int           // Deepest request the cloud may answer
+
+	// Rolling engine time per position of the primary pool's searches
+	timings timing.Recorder
+
+	cachePolicies cachePolicyCounts // Requests by CacheOptions.Policy
+	pgnInputs     pgnInputCounts    // Games parsed for analysis by PGN class
+
+	degrade degrader // Caps game depth while the pool is starved
+
+	maxPlies  int // Longest game analyzed, 0 for no limit
+	fastPlies int // Games longer are searched at minDepth, 0 for never
+
+	book BookDetector // Finds a game's book moves, nil for none
+
+	ponderPrefetch bool        // Search the position after best move and ponder move when idle
+	prefetching    atomic.Bool // A ponder prefetch is in flight
+
+	stale *staleRefresher // Stale-while-revalidate of position requests, nil when off
+
+	drift driftSampler // Cached positions searched again, see SetDriftSampling
+
+	maxPVLength int // Plies of each move's PV kept in game analyses, 0 for all
+	maxMultiPV  int // Most PVs GetBestMoves asks an engine for
+
+	analyzeVariants bool // Analyze games of unsupported variants as standard chess
+
+	previewTolerance int // Centipawns within which a deep pass keeps preview evaluations, 0 for none
+}
+
+// PrimaryEngine is the engine profile of the analyzer's own pool
+const PrimaryEngine = "primary"
+
+// EngineProfile is an extra engine games can be analyzed with to
+// cross-check the primary one: a different binary, or a very different
+// depth
+type EngineProfile struct {
+	Name  string
+	Pool  *pool.Pool
+	Depth int // Searched whatever the request asks (0 = as requested)
+}
+
+// CloudEvaluator evaluates positions without the local engines, normally
+// (*cloudeval.Client). Results must have Source set.
+type CloudEvaluator interface {
+	Evaluate(ctx context.Context, fen string) (*engine.AnalysisResult, error)
+}
+
+// NewAnalyzer creates a new analyzer. Requested depths are clamped to
+// [minDepth, maxDepth]; timeout bounds each position search and each game.
+func NewAnalyzer(p *pool.Pool, logger *zap.Logger, minDepth, defaultDepth, maxDepth int, timeout time.Duration) *Analyzer {
+	return &Analyzer{
+		pool:         p,
+		logger:       logger,
+		minDepth:     minDepth,
+		defaultDepth: defaultDepth,
+		maxDepth:     maxDepth,
+		timeout:      timeout,
+		posCache:     NewPositionCache(50000), // Cache 50k positions (~common openings + recent games)
+
+		profiles:       evaluation.DefaultProfiles(),
+		defaultProfile: evaluation.ProfileStandard,
+		accuracyMethod: evaluation.AccuracyCappedLoss,
+		excludeGarbage: true,
+
+		scrambleThresholds: DefaultScrambleThresholds(),
+		maxMultiPV:         maxEngineMultiPV,
+	}
+}
+
+// SetThresholdProfiles replaces the classification threshold profiles.
+// defaultProfile is used when a request doesn't name one and must exist.
+func (a *Analyzer) SetThresholdProfiles(profiles map[string]evaluation.Thresholds, defaultProfile string) error {
+	if _, ok := profiles[defaultProfile]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownThresholdProfile, defaultProfile)
+	}
+	a.profiles = profiles
+	a.defaultProfile = defaultProfile
+	return nil
+}
+
+// AddEngineProfile makes an extra engine available to GameOptions and
+// AnalyzeGameWithEngines. The caller keeps ownership of its pool.
+func (a *Analyzer) AddEngineProfile(profile EngineProfile) error {
+	if profile.Name == "" || profile.Name == PrimaryEngine {
+		return fmt.Errorf("engine profile name %q is reserved", profile.Name)
+	}
+	if _, ok := a.engines[profile.Name]; ok {
+		return fmt.Errorf("engine profile %q already exists", profile.Name)
+	}
+	if a.engines == nil {
+		a.engines = make(map[string]EngineProfile)
+	}
+	a.engines[profile.Name] = profile
+	return nil
+}
+
+// EngineProfiles returns the names of the extra engine profiles, sorted
+func (a *Analyzer) EngineProfiles() []string {
+	names := make([]string, 0, len(a.engines))
+	for name := range a.engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckEngineProfile returns ErrUnknownEngineProfile for a name that is
+// neither empty, the primary engine nor an added profile
+func (a *Analyzer) CheckEngineProfile(name string) error {
+	_, _, _, err := a.engineProfile(name, 0)
+	return err
+}
+
+// engineProfile resolves a profile name, with empty meaning the primary
+// engine, and returns its pool and the depth it searches for depth
+func (a *Analyzer) engineProfile(name string, depth int) (string, *pool.Pool, int, error) {
+	if name == "" || name == PrimaryEngine {
+		return PrimaryEngine, a.pool, a.ClampDepth(depth), nil
+	}
+	profile, ok := a.engines[name]
+	if !ok {
+		return "", nil, 0, fmt.Errorf("%w: %q", ErrUnknownEngineProfile, name)
+	}
+	if profile.Depth > 0 {
+		return name, profile.Pool, profile.Depth, nil
+	}
+	return name, profile.Pool, a.ClampDepth(depth), nil
+}
+
+// SetAccuracyMethod selects how game accuracy is calculated from the moves
+func (a *Analyzer) SetAccuracyMethod(method evaluation.AccuracyMethod) error {
+	if !method.Valid() {
+		return fmt.Errorf("unknown accuracy method %q", method)
+	}
+	a.accuracyMethod = method
+	return nil
+}
+
+// SetGarbageTimeExclusion sets whether games leave moves made in decided
+// positions out of accuracy and ACPL when the request doesn't say
+func (a *Analyzer) SetGarbageTimeExclusion(exclude bool) {
+	a.excludeGarbage = exclude
+}
+
+// SetCacheMaxHalfmoveClock sets the halfmove clock above which positions
+// bypass the position cache (DefaultMaxHalfmoveClock by default)
+func (a *Analyzer) SetCacheMaxHalfmoveClock(n int) {
+	a.posCache.SetMaxHalfmoveClock(n)
+}
+
+// SetCacheAdmission sets the position cache's admission policy, which
+// keeps one-off positions past the opening out of it
+func (a *Analyzer) SetCacheAdmission(policy CacheAdmission) {
+	a.posCache.SetAdmission(policy)
+}
+
+// SetCloudFallback lets single-PV position requests up to maxDepth be
+// answered by cloud when no engine frees up within wait. Answers shallower
+// than the requested depth are ignored.
+func (a *Analyzer) SetCloudFallback(cloud CloudEvaluator, wait time.Duration, maxDepth int) {
+	a.cloud = cloud
+	a.cloudWait = wait
+	a.cloudMaxDepth = maxDepth
+}
+
+// ThresholdProfiles returns the configured profiles and the default name
+func (a *Analyzer) ThresholdProfiles() (map[string]evaluation.Thresholds, string) {
+	return a.profiles, a.defaultProfile
+}
+
+// Thresholds resolves a profile name, with empty meaning the default
+func (a *Analyzer) Thresholds(profile string) (string, evaluation.Thresholds, error) {
+	if profile == "" {
+		profile = a.defaultProfile
+	}
+	t, ok := a.profiles[profile]
+	if !ok {
+		return "", evaluation.Thresholds{}, fmt.Errorf("%w: %q", ErrUnknownThresholdProfile, profile)
+	}
+	return profile, t, nil
+}
+
+// DefaultDepth returns the depth used when a request does not specify one
+func (a *Analyzer) DefaultDepth() int {
+	return a.defaultDepth
+}
+
+// MaxDepth returns the maximum depth a request can ask for
+func (a *Analyzer) MaxDepth() int {
+	return a.maxDepth
+}
+
+// MinDepth returns the minimum depth searched, whatever the request
+func (a *Analyzer) MinDepth() int {
+	return a.minDepth
+}
+
+// ClampDepth returns the depth actually searched for a requested depth:
+// the default for zero or less, otherwise limited to [MinDepth, MaxDepth]
+func (a *Analyzer) ClampDepth(depth int) int {
+	if depth <= 0 {
+		depth = a.defaultDepth
+	}
+	if depth < a.minDepth {
+		depth = a.minDepth
+	}
+	if depth > a.maxDepth {
+		depth = a.maxDepth
+	}
+	return depth
+}
+
+// withTimeout applies the analysis budget to ctx, if one is configured
+func (a *Analyzer) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, a.timeout)
+}
+
+// CacheStats returns position cache statistics
+func (a *Analyzer) CacheStats() CacheStats {
+	stats := a.posCache.Stats()
+	stats.QuarantinedEngines = a.cacheGuard.quarantinedEngines()
+	return stats
+}
+
+// CacheSizeBySource returns the number of cached positions from each source
+func (a *Analyzer) CacheSizeBySource() map[string]int {
+	return a.posCache.SizeBySource()
+}
+
+// AnalyzePosition analyzes a single FEN position. If the analysis budget
+// runs out mid-search the shallower result is returned with Stopped set.
+func (a *Analyzer) AnalyzePosition(ctx context.Context, fen string, depth int, multiPV int) (*engine.AnalysisResult, error) {
+	return a.AnalyzePositionWithCache(ctx, fen, depth, multiPV, CacheOptions{})
+}
+
+// dispatch is the engine pool's state when a search asked it for an engine
+type dispatch struct {
+	at        time.Time
+	waiting   int
+	available int
+}
+
+func (a *Analyzer) dispatch() dispatch {
+	return dispatch{at: time.Now(), waiting: a.pool.Waiting(), available: a.pool.Available()}
+}
+
+// timeResult sets result's queue and search times, the search having
+// started at searchStart, and makes TimeMs their sum
+func (d dispatch) timeResult(result *engine.AnalysisResult, searchStart time.Time) {
+	result.QueueTimeMs = searchStart.Sub(d.at).Milliseconds()
+	result.SearchTimeMs = time.Since(searchStart).Milliseconds()
+	result.TimeMs = result.QueueTimeMs + result.SearchTimeMs
+	result.PoolWaiting = d.waiting
+	result.PoolAvailable = d.available
+}
+
+// search runs one engine search within the analysis budget
+func (a *Analyzer) search(ctx context.Context, fen string, depth int, multiPV int) (*engine.AnalysisResult, error) {
+	searchCtx, cancel := a.withTimeout(ctx)
+	defer cancel()
+
+	d := a.dispatch()
+	eng, err := a.getEngine(searchCtx, a.pool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get engine: %w", err)
+	}
+	return a.runSearch(ctx, searchCtx, eng, fen, depth, multiPV, d)
+}
+
+// searchOrCloud is search for a single PV, asking the cloud when the pool
+// stays busy for the cloud wait. On a cloud miss it keeps waiting for an
+// engine.
+func (a *Analyzer) searchOrCloud(ctx context.Context, fen string, depth int) (*engine.AnalysisResult, error) {
+	searchCtx, cancel := a.withTimeout(ctx)
+	defer cancel()
+
+	d := a.dispatch()
+	waitCtx, cancelWait := context.WithTimeout(searchCtx, a.cloudWait)
+	eng, err := a.getEngine(waitCtx, a.pool)
+	cancelWait()
+	if err == nil {
+		return a.runSearch(ctx, searchCtx, eng, fen, depth, 1, d)
+	}
+	if searchCtx.Err() != nil || !errors.Is(err, pool.ErrPoolExhausted) {
+		return nil, fmt.Errorf("failed to get engine: %w", err)
+	}
+
+	cloudStart := time.Now()
+	result, err := a.cloud.Evaluate(searchCtx, fen)
+	switch {
+	case err != nil:
+		a.logger.Debug("Cloud eval fallback missed", zap.String("fen", fen), zap.Error(err))
+	case result.Depth < depth:
+		a.logger.Debug("Cloud eval too shallow", zap.String("fen", fen), zap.Int("depth", result.Depth))
+	default:
+		d.timeResult(result, cloudStart)
+		return result, nil
+	}
+
+	// The cloud's time counts as queueing for the engine that searches
+	eng, err = a.getEngine(searchCtx, a.pool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get engine: %w", err)
+	}
+	return a.runSearch(ctx, searchCtx, eng, fen, depth, 1, d)
+}
+
+// runSearch searches with eng, which it returns to the pool. searchCtx is
+// ctx with the analysis budget applied; d is when the engine was asked for.
+func (a *Analyzer) runSearch(ctx, searchCtx context.Context, eng *engine.Engine, fen string, depth int, multiPV int, d dispatch) (*engine.AnalysisResult, error) {
+	quarantined := false
+	defer func() {
+		if quarantined {
+			a.pool.Discard(eng)
+		} else {
+			a.pool.Put(eng)
+		}
+	}()
+
+	searchStart := time.Now()
+	result, err := eng.AnalyzePositionContext(searchCtx, fen, depth, multiPV)
+	meterSearch(ctx, time.Since(searchStart))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEngineFailure, err)
+	}
+	// A caller that went away gets its error, not a partial result
+	if result.Stopped && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	result.Source = engine.SourceEngine
+	if !result.Stopped && len(result.Evaluations) > 0 {
+		quarantined = a.judgeEngine(eng, depth, result.Evaluations[0], result.BestMove)
+	}
+	if multiPV == 1 && !result.Stopped {
+		a.recordTiming(fen, depth, result.TimeMs)
+	}
+	d.timeResult(result, searchStart)
+	return result, nil
+}
+
+// positionWork represents a position to analyze
+type positionWork struct {
+	index    int
+	pos      engine.GamePosition
+	movetime time.Duration // Search for this long instead of to the depth
+}
+
+// gameChunkSize is the most positions of a game, in order, a worker takes
+// at a time, so its engine searches each after the one before
+const gameChunkSize = 8
+
+// positionResult represents the result of analyzing a position
+type positionResult struct {
+	index    int
+	eval     engine.Evaluation
+	bestMove string
+	err      error
+	retries  int           // Searches repeated after a failure
+	failures []FailureKind // Of every failed search
+	chunkEnd bool          // Last position of the worker's chunk
+
+	// Wall clock spent waiting for an engine and searching, over every
+	// attempt
+	queueTime  time.Duration
+	searchTime time.Duration
+}
+
+// AnalyzeGame analyzes a complete game. A PGN without moves returns
+// ErrEmptyGame.
+// OPTIMIZED:
+// 1. Evaluations are cached - each position is only analyzed ONCE
+// 2. Uses parallel analysis with multiple engines when available
+// 3. The "after" evaluation of move N is reused as the "before" evaluation of move N+1
+func (a *Analyzer) AnalyzeGame(ctx context.Context, gameID string, pgn string, depth int, opts GameOptions, callback ProgressCallback) (*GameAnalysis, error) {
+	startTime := time.Now()
+
+	profile, thresholds, err := a.Thresholds(opts.ThresholdProfile)
+	if err != nil {
+		return nil, err
+	}
+	excludeGarbage := a.excludeGarbage
+	if opts.ExcludeGarbageTime != nil {
+		excludeGarbage = *opts.ExcludeGarbageTime
+	}
+	if !excludeGarbage {
+		thresholds = thresholds.WithoutGarbageTime()
+	}
+	model := opts.AccuracyModel
+	if model == "" {
+		model = evaluation.AccuracyModelEloInsight
+	}
+	if !model.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAccuracyModel, model)
+	}
+
+	engineProfile, enginePool, depth, err := a.engineProfile(opts.EngineProfile, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse PGN to get positions
+	positions, err := a.ParsePGN(pgn)
+	if !opts.preview {
+		a.RecordPGNParse(pgn, err)
+	}
+	var moveErr *PGNMoveError
+	if err != nil && !(opts.AnalyzeUntilError && errors.As(err, &moveErr)) {
+		return nil, err
+	}
+
+	if len(positions) == 0 {
+		return nil, fmt.Errorf("%w: no positions found", ErrInvalidPGN)
+	}
+	if len(positions) == 1 && moveErr == nil {
+		// Nothing to analyze, and no metrics worth reporting
+		return nil, ErrEmptyGame
+	}
+	prefix, err := seedPrefix(positions, opts.StartPly, opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	totalMoves := len(positions) - 1 // Exclude starting position
+	depth, fastMode, err := a.gameDepth(totalMoves, depth, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Games searched at an engine profile's own depth, or not at all,
+	// aren't degraded
+	degraded := false
+	if engineProfile == PrimaryEngine && !opts.Cache.Only {
+		depth, degraded = a.degradeGame(depth)
+	}
+
+	// A preview analyzes the whole game quickly first
+	var preview *GameAnalysis
+	if previewDepth, ok := a.previewDepth(opts, depth); ok {
+		if preview, err = a.analyzePreview(ctx, gameID, pgn, previewDepth, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	// The whole game shares one budget. When it runs out the moves analyzed
+	// so far are returned; the caller's own deadline is still an error.
+	gameCtx, cancelGame := a.withTimeout(ctx)
+	defer cancelGame()
+	if opts.OnEngineOutput != nil {
+		gameCtx = context.WithValue(gameCtx, engineOutputKey{}, opts.OnEngineOutput)
+	}
+
+	if !opts.preview {
+		a.cachePolicies.add(opts.Cache.Policy())
+	}
+
+	// Engine version and network for results, as the pool last saw them;
+	// a cache-only analysis goes without
+	var engineVersion, netName string
+	if !opts.Cache.Only {
+		engineVersion, netName = enginePool.Identity()
+	}
+
+	analysis := &GameAnalysis{
+		GameID:        gameID,
+		Moves:         make([]MoveAnalysis, 0, totalMoves),
+		StartedAt:     startTime.UnixMilli(),
+		EngineVersion: engineVersion,
+		EngineProfile: engineProfile,
+		Depth:         depth,
+		TotalMoves:    totalMoves,
+		FastMode:      fastMode,
+		Degraded:      degraded,
+		Config:        configSnapshot(enginePool, netName, depth, opts),
+		SourceCounts:  make(map[AnalysisSource]int),
+
+		ThresholdProfile: profile,
+		Thresholds:       thresholds,
+	}
+	if moveErr != nil {
+		analysis.Truncated = true
+		analysis.TruncatedAtPly = moveErr.Ply
+		analysis.TruncationError = moveErr.Error()
+		a.logger.Warn("Analyzing game up to an invalid move",
+			zap.String("gameId", gameID),
+			zap.Error(moveErr))
+	}
+
+	// OPTIMIZATION: Pre-analyze all positions once instead of 2x per move
+	evaluations := make([]engine.Evaluation, len(positions))
+	bestMoves := make([]string, len(positions))
+	evaluated := make([]bool, len(positions))
+	fromCache := make([]bool, len(positions))
+	sources := make([]AnalysisSource, len(positions))
+	failed := make([]bool, len(positions))
+	analyzedAt := make([]int64, len(positions))
+	queueTimes := make([]time.Duration, len(positions))
+	searchTimes := make([]time.Duration, len(positions))
+	movetimes := make([]time.Duration, len(positions))
+	budgetOut := false
+
+	// Separate cached vs uncached positions
+	var uncachedWork []positionWork
+	cacheHits, seeded, kept := 0, 0, 0
+
+	a.logger.Info("Starting optimized game analysis",
+		zap.String("gameId", gameID),
+		zap.String("engineProfile", engineProfile),
+		zap.Int("totalPositions", len(positions)),
+		zap.Int("depth", depth))
+
+	// Positions are searched as moves from the start, so an engine
+	// searching several in a row keeps its hash
+	moves := make([]string, len(positions))
+	for i := 1; i < len(positions); i++ {
+		moves[i-1] = positions[i].MoveUCI
+	}
+	gamePosition := func(i int) engine.GamePosition {
+		return engine.GamePosition{StartFEN: positions[0].FEN, Moves: moves[:i:i], FEN: positions[i].FEN}
+	}
+
+	// First pass: seed the prefix, check cache and collect uncached positions
+	for i, pos := range positions {
+		analyzedAt[i] = time.Now().UnixMilli()
+		if i < len(prefix) {
+			evaluations[i] = prefix[i].Eval
+			bestMoves[i] = prefix[i].BestMove
+			evaluated[i] = true
+			sources[i] = PlyImported
+			seeded++
+			continue
+		}
+		if pos.LegalMoves == 0 {
+			// The game ended in mate or stalemate: nothing to search
+			evaluations[i] = gameOverEvaluation(gameOver(pos.FEN))
+			evaluated[i] = true
+			seeded++
+			continue
+		}
+		var cached cachedEvaluation
+		found := false
+		if opts.Cache.reads() {
+			cached, found = a.posCache.get(engineProfile, pos.FEN, CacheQuery{Depth: depth})
+		}
+		switch {
+		case found:
+			evaluations[i] = cached.evaluation()
+			bestMoves[i] = cached.result.BestMove
+			evaluated[i] = true
+			fromCache[i] = true
+			sources[i] = cacheSource(cached.source)
+			cacheHits++
+		case preview != nil && preview.pass.keeps(i, a.previewTolerance):
+			// Settled in the preview: not worth a deeper search
+			evaluations[i] = preview.pass.evaluations[i]
+			bestMoves[i] = preview.pass.bestMoves[i]
+			evaluated[i] = true
+			fromCache[i] = preview.pass.fromCache[i]
+			sources[i] = preview.pass.sources[i]
+			kept++
+		case !opts.Cache.Only:
+			uncachedWork = append(uncachedWork, positionWork{index: i, pos: gamePosition(i)})
+		}
+	}
+	analysis.PreviewKept = kept
+
+	analysis.CacheCoverage = float64(cacheHits+seeded) / float64(len(positions)) * 100
+	analysis.Config.CacheHitPercent = analysis.CacheCoverage
+
+	a.logger.Info("Cache check completed",
+		zap.Int("cacheHits", cacheHits),
+		zap.Int("seeded", seeded),
+		zap.Int("previewKept", kept),
+		zap.Int("toAnalyze", len(uncachedWork)))
+
+	estimate := a.newGameEstimate(enginePool, engineProfile, depth, len(positions), cacheHits, len(uncachedWork), opts.TimeBudget)
+	if opts.OnEstimate != nil {
+		opts.OnEstimate(estimate)
+	}
+
+	// OPTIMIZATION: Parallel analysis of uncached positions
+	if len(uncachedWork) > 0 {
+		// Create worker context; a time budget ends the searches at most
+		// budgetSlack past it
+		workerCtx, cancel := context.WithCancel(gameCtx)
+		if opts.TimeBudget > 0 {
+			workerCtx, cancel = context.WithTimeout(gameCtx, opts.TimeBudget+time.Duration(float64(opts.TimeBudget)*budgetSlack))
+		}
+		defer cancel()
+
+		// A time budget is spent in two passes: a quick search of every
+		// position, then the rest of the budget weighted to the critical
+		// positions the first pass found
+		if opts.TimeBudget > 0 {
+			remaining := opts.TimeBudget
+			if movetime := prepassMovetime(opts.TimeBudget, len(uncachedWork)); movetime > 0 && !degraded {
+				prepass := make([]positionWork, len(uncachedWork))
+				known := append([]bool(nil), evaluated...)
+				quick := append([]engine.Evaluation(nil), evaluations...)
+				for i, w := range uncachedWork {
+					prepass[i] = positionWork{index: w.index, pos: w.pos, movetime: movetime}
+				}
+				results, _ := a.startWorkers(workerCtx, enginePool, prepass, depth)
+				for result := range results {
+					analysis.Diagnostics.add(result)
+					queueTimes[result.index] += result.queueTime
+					searchTimes[result.index] += result.searchTime
+					movetimes[result.index] += movetime
+					remaining -= result.searchTime
+					if result.err == nil {
+						quick[result.index] = result.eval
+						known[result.index] = true
+					}
+				}
+				if err := ctx.Err(); err != nil {
+					return nil, callerError(err)
+				}
+				planMovetimes(uncachedWork, criticalPositions(quick, known, thresholds), max(remaining, 0))
+			} else {
+				planMovetimes(uncachedWork, make([]bool, len(positions)), opts.TimeBudget)
+			}
+			for _, w := range uncachedWork {
+				movetimes[w.index] += w.movetime
+			}
+		}
+		resultChan, chunks := a.startWorkers(workerCtx, enginePool, uncachedWork, depth)
+
+		// Collect results and report progress
+		analyzed := cacheHits + seeded + kept
+		chunksDone := 0
+		for result := range resultChan {
+			select {
+			case <-ctx.Done():
+				// Let the workers stop their searches and return their engines
+				cancel()
+				for range resultChan {
+				}
+				return a.previewOnly(ctx, gameID, preview)
+			default:
+			}
+
+			analysis.Diagnostics.add(result)
+			queueTimes[result.index] += result.queueTime
+			searchTimes[result.index] += result.searchTime
+
+			if result.err == nil {
+				evaluations[result.index] = result.eval
+				bestMoves[result.index] = result.bestMove
+				evaluated[result.index] = true
+				sources[result.index] = PlyEngine
+				analyzedAt[result.index] = time.Now().UnixMilli()
+				cacheDepth := depth
+				if opts.TimeBudget > 0 {
+					// A movetime search is as good as the depth it reached
+					cacheDepth = result.eval.Depth
+				} else if engineProfile == PrimaryEngine {
+					a.recordTiming(positions[result.index].FEN, depth, result.eval.TimeMs)
+				}
+				if !opts.Cache.NoStore && a.cacheable(cacheDepth, result.eval, result.bestMove) {
+					a.posCache.Set(engineProfile, positions[result.index].FEN, cacheDepth, result.eval, result.bestMove, engine.SourceEngine)
+				}
+			} else if workerCtx.Err() != nil {
+				// Out of budget: remaining positions are dropped, not progress
+				continue
+			} else {
+				analysis.Diagnostics.FailedPositions = append(analysis.Diagnostics.FailedPositions, result.index)
+				failed[result.index] = true
+			}
+
+			analyzed++
+			estimate.searched()
+			if opts.OnEstimate != nil {
+				opts.OnEstimate(estimate)
+			}
+			if callback != nil {
+				progress := analyzed
+				if progress > totalMoves {
+					progress = totalMoves
+				}
+				callback(progress, totalMoves, nil)
+			}
+			if result.chunkEnd {
+				chunksDone++
+				if opts.OnChunk != nil {
+					opts.OnChunk(chunksDone, chunks)
+				}
+			}
+		}
+		sort.Ints(analysis.Diagnostics.FailedPositions)
+		budgetOut = opts.TimeBudget > 0 && workerCtx.Err() != nil
+	}
+	if opts.TimeBudget > 0 {
+		analysis.TimeBudgetMs = opts.TimeBudget.Milliseconds()
+		for _, searchTime := range searchTimes {
+			analysis.BudgetUsedMs += searchTime.Milliseconds()
+		}
+		analysis.BudgetUtilization = float64(analysis.BudgetUsedMs) / float64(analysis.TimeBudgetMs) * 100
+	}
+
+	// Build move analyses from evaluations, keeping the metrics up to date
+	draws := repetitionDraws(positions)
+	metrics := newGameMetrics(a.accuracyMethod, model, totalMoves)
+	// Cap each position's PV once; the moves before and after it share it
+	for i := range evaluations {
+		capPV(&evaluations[i], a.maxPVLength)
+	}
+	inBook := a.book != nil
+	for i := 0; i < len(positions)-1; i++ {
+		pos := positions[i]
+		nextPos := positions[i+1]
+
+		evalBefore := evaluations[i]
+		evalAfter := evaluations[i+1]
+
+		// Skip moves missing either evaluation
+		if !evaluated[i] || !evaluated[i+1] {
+			analysis.SourceCounts[missingSource(failed[i] || failed[i+1])]++
+			continue
+		}
+
+		moveAnalysis := a.createMoveAnalysis(i, pos, nextPos, &evalBefore, &evalAfter, bestMoves[i], thresholds)
+		if inBook {
+			topMoves := func(n int) []string {
+				// The multi-PV search for alternatives is skipped when
+				// no engine may be used or the pool is starved
+				if opts.Cache.Only || degraded {
+					return nil
+				}
+				return a.topMoves(gameCtx, enginePool, pos.FEN, depth, n)
+			}
+			if inBook = a.book.IsBook(&moveAnalysis, topMoves); inBook {
+				moveAnalysis.Classification = ClassBook
+			}
+		}
+		moveAnalysis.RequestedDepth = depth
+		moveAnalysis.FromCache = fromCache[i]
+		moveAnalysis.AnalyzedAt = max(analyzedAt[i], analyzedAt[i+1])
+		if i >= len(prefix) && !fromCache[i] {
+			moveAnalysis.EngineTimeMs = evalBefore.TimeMs
+			moveAnalysis.QueueTimeMs = queueTimes[i].Milliseconds()
+			moveAnalysis.SearchTimeMs = searchTimes[i].Milliseconds()
+			moveAnalysis.MovetimeMs = movetimes[i].Milliseconds()
+		}
+		flagRepetition(&moveAnalysis, draws[i], draws[i+1])
+		moveAnalysis.MaterialSacrificed = materialSacrificed(positions, i, moveAnalysis.Color)
+		moveAnalysis.Source = moveSource(&moveAnalysis, sources[i])
+		analysis.SourceCounts[moveAnalysis.Source]++
+		moveAnalysis.Explanation = ExplainMove(&moveAnalysis).Explain()
+		analysis.Moves = append(analysis.Moves, moveAnalysis)
+		metrics.add(&moveAnalysis)
+
+		// Call progress callback with completed move analysis
+		if callback != nil {
+			callback(i+1, totalMoves, &moveAnalysis)
+		}
+		if opts.OnMetrics != nil && opts.MetricsInterval > 0 && len(analysis.Moves)%opts.MetricsInterval == 0 {
+			opts.OnMetrics(len(analysis.Moves), metrics.result("white"), metrics.result("black"))
+		}
+	}
+
+	analysis.WhiteMetrics = metrics.result("white")
+	analysis.BlackMetrics = metrics.result("black")
+	analysis.Info = ParseGameInfo(pgn)
+	result := opts.Result
+	if result == "" {
+		result = analysis.Info.Result
+	}
+	analysis.WhiteMetrics.Resilience = resilience(metrics.evals, "white", result, thresholds)
+	analysis.BlackMetrics.Resilience = resilience(metrics.evals, "black", result, thresholds)
+	analysis.WhiteMetrics.PerformanceRating = performanceRating(analysis.WhiteMetrics, "white", analysis.Info.BlackElo, result)
+	analysis.BlackMetrics.PerformanceRating = performanceRating(analysis.BlackMetrics, "black", analysis.Info.WhiteElo, result)
+	analysis.endGame(positions, result, tagValue(parsePGNTags(pgn), "Termination"))
+	analysis.WhiteTime = a.timeManagement(analysis.Moves, positions, analysis.Info.TimeControl, "white")
+	analysis.BlackTime = a.timeManagement(analysis.Moves, positions, analysis.Info.TimeControl, "black")
+	completedAt := time.Now()
+	analysis.TotalTimeMs = completedAt.Sub(startTime).Milliseconds()
+	analysis.CompletedAt = completedAt.UnixMilli()
+	analysis.TimedOut = gameCtx.Err() != nil || budgetOut
+	analysis.Summary = Summarize(analysis)
+	analysis.Checksum = Checksum(analysis)
+	if opts.preview {
+		analysis.Preliminary = true
+		analysis.pass = &positionPass{
+			evaluations: evaluations,
+			bestMoves:   bestMoves,
+			evaluated:   evaluated,
+			fromCache:   fromCache,
+			sources:     sources,
+		}
+	}
+
+	if analysis.TimedOut {
+		a.logger.Warn("Game analysis timed out, returning partial results",
+			zap.String("gameId", gameID),
+			zap.Duration("timeout", a.timeout),
+			zap.Int("movesAnalyzed", len(analysis.Moves)),
+			zap.Int("totalMoves", totalMoves))
+	}
+
+	a.logger.Info("Game analysis completed",
+		zap.String("gameId", gameID),
+		zap.Int("movesAnalyzed", len(analysis.Moves)),
+		zap.Bool("timedOut", analysis.TimedOut),
+		zap.Int("cacheHits", cacheHits),
+		zap.Int64("totalTimeMs", analysis.TotalTimeMs))
+
+	return analysis, nil
+}
+
+// startWorkers analyzes work in parallel on up to maxGameWorkers engines
+// of enginePool, returning the results as they come and the number of
+// chunks of consecutive positions the workers take. The channel is closed
+// once every position is done.
+func (a *Analyzer) startWorkers(ctx context.Context, enginePool *pool.Pool, work []positionWork, depth int) (<-chan positionResult, int) {
+	numWorkers := gameWorkers(enginePool)
+
+	// Chunks small enough to keep every worker busy
+	chunkSize := min(gameChunkSize, (len(work)+numWorkers-1)/numWorkers)
+	chunks := (len(work) + chunkSize - 1) / chunkSize
+
+	// Create work and result channels
+	workChan := make(chan []positionWork, chunks)
+	resultChan := make(chan positionResult, len(work))
+
+	// Send all work to channel
+	for start := 0; start < len(work); start += chunkSize {
+		workChan <- work[start:min(start+chunkSize, len(work))]
+	}
+	close(workChan)
+
+	// Start workers
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.analyzeWorker(ctx, enginePool, workChan, resultChan, depth)
+		}()
+	}
+
+	// Close result channel when all workers done
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+	return resultChan, chunks
+}
+
+// callerError returns the error of a game analysis the caller's context
+// ended
+func callerError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+	}
+	return err
+}
+
+// analyzeWorker is a goroutine worker that analyzes positions in parallel,
+// each chunk's in order on the engine it holds.
+// A failed search is retried up to maxSearchRetries times on another
+// engine: a dead engine is discarded for a replacement, a live one goes
+// back to the pool.
+func (a *Analyzer) analyzeWorker(ctx context.Context, enginePool *pool.Pool, work <-chan []positionWork, results chan<- positionResult, depth int) {
+	var eng *engine.Engine
+	defer func() {
+		if eng != nil {
+			enginePool.Put(eng)
+		}
+	}()
+
+	onOutput, _ := ctx.Value(engineOutputKey{}).(EngineOutputCallback)
+	for chunk := range work {
+		for i, w := range chunk {
+			pr := positionResult{index: w.index, chunkEnd: i == len(chunk)-1}
+			searchCtx := ctx
+			if onOutput != nil {
+				searchCtx = engine.WithRecorder(ctx, func(line string) { onOutput(w.index, line) })
+			}
+			for attempt := 0; attempt <= maxSearchRetries; attempt++ {
+				if ctx.Err() != nil {
+					pr.err = ctx.Err()
+					break
+				}
+				if eng == nil {
+					var err error
+					queued := time.Now()
+					eng, err = a.getEngine(ctx, enginePool)
+					pr.queueTime += time.Since(queued)
+					if err != nil {
+						pr.err = err
+						break
+					}
+				}
+				if attempt > 0 {
+					pr.retries++
+				}
+
+				searchStart := time.Now()
+				var result *engine.AnalysisResult
+				var err error
+				if w.movetime > 0 {
+					result, err = eng.AnalyzeGamePositionWithTimeContext(searchCtx, w.pos, int(w.movetime.Milliseconds()), 1)
+				} else {
+					result, err = eng.AnalyzeGamePositionContext(searchCtx, w.pos, depth, 1)
+				}
+				searchTime := time.Since(searchStart)
+				pr.searchTime += searchTime
+				meterSearch(ctx, searchTime)
+				if err == nil && result.Stopped {
+					// A search cut short by the deadline is too shallow to use
+					pr.err = ctx.Err()
+					break
+				}
+				if err == nil {
+					pr.err = nil
+					if len(result.Evaluations) > 0 {
+						pr.eval = result.Evaluations[0]
+					}
+					pr.bestMove = result.BestMove
+
+					// Judged at the depth the result is cached at
+					judgeDepth := depth
+					if w.movetime > 0 {
+						judgeDepth = pr.eval.Depth
+					}
+					if a.judgeEngine(eng, judgeDepth, pr.eval, pr.bestMove) {
+						enginePool.Discard(eng)
+						eng = nil
+					}
+					break
+				}
+
+				kind := classifyFailure(err)
+				a.logger.Warn("Worker failed to analyze position",
+					zap.Int("index", w.index),
+					zap.Int("attempt", attempt+1),
+					zap.String("failure", string(kind)),
+					zap.Error(err))
+				pr.err = err
+				pr.failures = append(pr.failures, kind)
+				if kind == FailureEngineDied {
+					enginePool.Discard(eng)
+				} else {
+					enginePool.Put(eng)
+				}
+				eng = nil
+			}
+			results <- pr
+		}
+	}
+}
+
+// createMoveAnalysis creates analysis for a single move
+func (a *Analyzer) createMoveAnalysis(
+	ply int,
+	currentPos, nextPos Position,
+	evalBefore, evalAfter *engine.Evaluation,
+	bestMoveUCI string,
+	thresholds evaluation.Thresholds,
+) MoveAnalysis {
+	// The position's own side to move and fullmove number, right for games
+	// set up from a FEN too
+	color, moveNumber := PlyMove(currentPos.FEN, 0)
+
+	// Convert best move from UCI to SAN, decoding the position once for
+	// that and for comparing it with the move played
+	position := decodePosition(currentPos.FEN)
+	bestMoveUCI = position.canonical(bestMoveUCI)
+	bestMoveSAN := a.uciToSAN(position, bestMoveUCI)
+
+	// The played move is stored in nextPos (the position AFTER the move was made)
+	analysis := MoveAnalysis{
+		MoveNumber:    moveNumber,
+		Ply:           ply,
+		Color:         color,
+		PlayedMove:    nextPos.MoveSAN,
+		PlayedMoveUCI: nextPos.MoveUCI,
+		BestMove:      bestMoveSAN,
+		BestMoveUCI:   bestMoveUCI,
+		FENBefore:     currentPos.FEN,
+		FENAfter:      nextPos.FEN,
+		EvalBefore:    *evalBefore,
+		AchievedDepth: evalBefore.Depth,
+		PV:            evalBefore.PV,
+
+		MaterialBefore: material(currentPos.FEN),
+		MaterialAfter:  material(nextPos.FEN),
+	}
+
+	// Store evalAfter if available
+	if evalAfter != nil {
+		analysis.EvalAfter = *evalAfter
+	}
+
+	// Calculate centipawn loss
+	// evalBefore: evaluation from the perspective of the side to move (before the move)
+	// evalAfter: evaluation from the perspective of the opponent (after the move)
+	// Since perspectives flip, centipawnLoss accounts for this
+	if evalBefore != nil && evalAfter != nil {
+		analysis.CentipawnLoss = centipawnLoss(*evalBefore, *evalAfter)
+		if nextPos.LegalMoves == 0 && gameOver(nextPos.FEN) == engine.GameOverCheckmate {
+			// Mating is the best a move can do, whatever the search before it saw
+			analysis.CentipawnLoss = 0
+		}
+
+		// Win probabilities make mates comparable with centipawns
+		analysis.Forced = currentPos.LegalMoves == 1
+		analysis.MoveAccuracy = 100
+		if !analysis.Forced {
+			analysis.MoveAccuracy = evaluation.CalculateMoveAccuracy(centipawns(*evalBefore), -centipawns(*evalAfter))
+		}
+		analysis.ExpectedPointsLoss = ExpectedPointsLoss(&analysis)
+		analysis.GarbageTime = thresholds.IsGarbageTime(centipawns(*evalBefore))
+	}
+
+	// Classify the move (compare played move with best move by the positions they lead to)
+	analysis.Classification = a.classifyMove(analysis.CentipawnLoss, position.same(nextPos.MoveUCI, bestMoveUCI), thresholds)
+
+	return analysis
+}
+
+// classifyMove classifies a move based on centipawn loss
+func (a *Analyzer) classifyMove(cpLoss int, isBestMove bool, t evaluation.Thresholds) MoveClassification {
+	if isBestMove || cpLoss <= t.Best {
+		return ClassBest
+	}
+	if cpLoss <= t.Excellent {
+		return ClassExcellent
+	}
+	if cpLoss <= t.Good {
+		return ClassGood
+	}
+	if cpLoss <= t.Inaccuracy {
+		return ClassInaccuracy
+	}
+	if cpLoss <= t.Mistake {
+		return ClassMistake
+	}
+	return ClassBlunder
+}
+
+// uciToSAN converts a UCI move played from position to SAN, falling back
+// to the UCI when it can't
+func (a *Analyzer) uciToSAN(position fenPosition, uciMove string) string {
+	if uciMove == "" {
+		return ""
+	}
+	san, err := position.san(uciMove)
+	if err != nil {
+		a.logger.Warn("Failed to convert UCI move to SAN", zap.String("fen", position.fen), zap.String("uci", uciMove), zap.Error(err))
+		return uciMove // Return UCI as fallback
+	}
+	return san
+}
+
+// centipawns returns an evaluation from the side to move's point of view
+// in centipawns, mates normalized
+func centipawns(eval engine.Evaluation) int {
+	if eval.IsMate && eval.MateIn != nil {
+		return evaluation.NormalizeMateScore(*eval.MateIn)
+	}
+	return eval.Centipawns
+}
+
+// centipawnLoss returns how much worse a move left the position for the
+// mover, from evalBefore, the mover's, and evalAfter, the opponent's, mates
+// normalized. A move that keeps a mate, delivers it or escapes one loses
+// nothing, and one that shortens the mate against the mover loses the
+// plies it gave up, like any move that loses less than its alternatives.
+// Finding or letting go of a mate loses at most MaxCPLossPerMove: the
+// mate score measures the result, not the size of the error.
+func centipawnLoss(evalBefore, evalAfter engine.Evaluation) int {
+	loss := max(0, centipawns(evalBefore)+centipawns(evalAfter))
+	if evalBefore.IsMate || evalAfter.IsMate {
+		loss = min(loss, int(evaluation.MaxCPLossPerMove))
+	}
+	return loss
+}
+
+// Position represents a chess position in a game
+type Position struct {
+	FEN        string
+	MoveSAN    string
+	MoveUCI    string
+	LegalMoves int // Legal moves in this position
+
+	// Clock is the mover's time left after the move, from a [%clk]
+	// comment; HasClock is false without one
+	Clock    time.Duration
+	HasClock bool
+}
+
+// ParsePGN parses a PGN and returns the list of positions with proper FEN strings
+// Handles both Chess.com format (full PGN with headers) and Lichess format (moves only)
+//
+// Parse errors are *PGNParseError, naming the class of the input. A move
+// that can't be played wraps a *PGNMoveError and comes with the positions
+// up to it, so callers can still use the legal part of the game. A game
+// of a variant other than standard chess returns a *VariantError
+// and no positions.
+func ParsePGN(pgn string) ([]Position, error) {
+	if err := CheckVariant(pgn); err != nil {
+		return nil, err
+	}
+	return parsePGN(pgn)
+}
+
+// parsePGN parses pgn as standard chess whatever its variant. Its errors
+// are *PGNParseError, with the class of pgn.
+func parsePGN(pgn string) ([]Position, error) {
+	positions, err := parseMovetext(pgn)
+	if err != nil {
+		return positions, &PGNParseError{Input: ClassifyPGN(pgn), Err: err}
+	}
+	return positions, nil
+}
+
+// parseMovetext is parsePGN without the class of pgn in its errors
+func parseMovetext(pgn string) ([]Position, error) {
+	movetext := pgnMovetext(pgn)
+	tokens, err := tokenizeMovetext(movetext)
+	if err != nil {
+		return nil, err
+	}
+
+	game := chess.NewGame()
+	if fen, ok := startingFEN(parsePGNTags(pgn)); ok {
+		fenOpt, err := chess.FEN(fen)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FEN tag %q: %v", fen, err)
+		}
+		game = chess.NewGame(fenOpt)
+	}
+
+	// Add starting position
+	positions := make([]Position, 0, len(tokens)+1)
+	positions = append(positions, Position{
+		FEN:        game.Position().String(),
+		MoveSAN:    "",
+		MoveUCI:    "",
+		LegalMoves: len(game.Position().ValidMoves()),
+	})
+
+	for ply, token := range tokens {
+		before := game.Position()
+		move, err := decodeMove(before, token.text)
+		if err == nil {
+			err = game.Move(move)
+		}
+		if err != nil {
+			color, moveNumber := PlyMove(positions[0].FEN, ply)
+			return positions, &PGNMoveError{
+				Ply:        ply,
+				MoveNumber: moveNumber,
+				Color:      color,
+				SAN:        token.text,
+				Context:    movetextContext(movetext, token),
+				Reason:     err.Error(),
+			}
+		}
+
+		// Store position with the move that was played
+		positions = append(positions, Position{
+			FEN:        game.Position().String(),
+			MoveSAN:    chess.AlgebraicNotation{}.Encode(before, move),
+			MoveUCI:    move.String(),
+			LegalMoves: len(game.Position().ValidMoves()),
+			Clock:      token.clock,
+			HasClock:   token.hasClock,
+		})
+	}
+
+	return positions, nil
+}
+
+// GetBestMoves returns the top N moves for a position. Like AnalyzePosition
+// it reports a search cut short by the analysis budget through Stopped.
+// A position with fewer than N legal moves has a PV for each of them and
+// the note engine.NoteFewerLegalMoves.
+//
+// Each move is returned once: lines the engine reported with the same
+// first move are collapsed, see distinctLines, and the position searched
+// again with more PVs, up to maxExtraPVSearches times and no more than
+// SetMaxMultiPV allows, until N distinct moves are found. Lines reaching the same position as a better one are
+// kept but marked with TransposesTo.
+func (a *Analyzer) GetBestMoves(ctx context.Context, fen string, count int, depth int) (*engine.AnalysisResult, error) {
+	if err := engine.ValidateFEN(fen); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFEN, err)
+	}
+
+	if count < 1 {
+		count = 1
+	}
+	if count > a.maxMultiPV {
+		count = a.maxMultiPV
+	}
+	depth = a.ClampDepth(depth)
+
+	if reason := gameOver(fen); reason != "" {
+		result := gameOverResult(fen, reason)
+		result.Evaluations = nil // No moves to rank
+		return result, nil
+	}
+
+	result, err := a.search(ctx, fen, depth, count)
+	if err != nil {
+		return nil, err
+	}
+	reported := len(result.Evaluations)
+	distinct := distinctLines(fen, result)
+	legal, ok := legalMoves(fen)
+	if !ok {
+		legal = count
+	}
+	// Only lines collapsed call for more PVs, not an engine reporting fewer
+	for searched, attempt := count, 0; distinct < min(count, legal) && reported > distinct && !result.Stopped && attempt < maxExtraPVSearches; attempt++ {
+		more := min(legal, searched+count-distinct, a.maxMultiPV)
+		if more <= searched {
+			break
+		}
+		a.logger.Debug("Best moves share first moves, searching more PVs",
+			zap.String("fen", fen), zap.Int("distinct", distinct), zap.Int("multiPV", more))
+		if result, err = a.search(ctx, fen, depth, more); err != nil {
+			return nil, err
+		}
+		searched = more
+		reported = len(result.Evaluations)
+		distinct = distinctLines(fen, result)
+	}
+	if distinct > count {
+		result.Evaluations = result.Evaluations[:count]
+		result.PVCount = count
+	}
+	if result.PVCount < count && legal < count {
+		result.Notes = append(result.Notes, engine.NoteFewerLegalMoves)
+	}
+	fillPonderMove(result)
+	return result, nil
+}
+
+// gameOver returns engine.GameOverCheckmate or engine.GameOverStalemate
+// when the side to move has no legal move, "" otherwise
+func gameOver(fen string) string {
+	fenOpt, err := chess.FEN(fen)
+	if err != nil {
+		return ""
+	}
+	switch chess.NewGame(fenOpt).Position().Status() {
+	case chess.Checkmate:
+		return engine.GameOverCheckmate
+	case chess.Stalemate:
+		return engine.GameOverStalemate
+	}
+	return ""
+}
+
+// gameOverEvaluation scores a finished position for the side to move:
+// mated (mate 0) or drawn
+func gameOverEvaluation(reason string) engine.Evaluation {
+	if reason != engine.GameOverCheckmate {
+		return engine.Evaluation{}
+	}
+	mateIn := 0
+	return engine.Evaluation{IsMate: true, MateIn: &mateIn}
+}
+
+// gameOverResult is the result for a finished position, without a search
+// or a best move
+func gameOverResult(fen, reason string) *engine.AnalysisResult {
+	return &engine.AnalysisResult{
+		Evaluations: []engine.Evaluation{gameOverEvaluation(reason)},
+		FEN:         fen,
+		GameOver:    reason,
+	}
+}
+
+// legalMoves returns the number of legal moves in the position; ok is
+// false when the FEN can't be read
+func legalMoves(fen string) (n int, ok bool) {
+	fenOpt, err := chess.FEN(fen)
+	if err != nil {
+		return 0, false
+	}
+	return len(chess.NewGame(fenOpt).Position().ValidMoves()), true
+}
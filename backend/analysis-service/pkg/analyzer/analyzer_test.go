@@ -0,0 +1,745 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	enginetest.Main()
+	os.Exit(m.Run())
+}
+
+// fakeEngine scores positions from -120 to 240, so evaluations differ
+// between moves but are stable across runs, answers e2e4 whatever the
+// position and reports 10ms for every search
+var fakeEngine = enginetest.Engine{
+	Default:      enginetest.Eval{Centipawns: -120, BestMove: "e2e4"},
+	Spread:       360,
+	ReportedTime: 10 * time.Millisecond,
+}
+
+// newFakeAnalyzer returns an analyzer backed by a pool of fake engines
+func newFakeAnalyzer(t *testing.T) *Analyzer {
+	return NewAnalyzer(newFakePool(t), zap.NewNop(), 1, 12, 20, time.Minute)
+}
+
+func TestPositionCache_OneEntryPerPosition(t *testing.T) {
+	c := NewPositionCache(100)
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2",
+	}
+
+	// A mixed-depth workload: every position at several depths, deepest
+	// in the middle, and the move counters changed
+	for _, depth := range []int{18, 24, 12, 20} {
+		for _, fen := range fens {
+			eval := engine.Evaluation{Depth: depth, Centipawns: depth}
+			c.Set(PrimaryEngine, fen, depth, eval, "e7e5", engine.SourceEngine)
+			c.Set(PrimaryEngine, strings.Replace(fen, " 1", " 9", 1), depth, eval, "e7e5", engine.SourceEngine)
+		}
+	}
+	if stats := c.Stats(); stats.Size != len(fens) {
+		t.Fatalf("cache size = %d, want %d", stats.Size, len(fens))
+	}
+
+	for _, fen := range fens {
+		for _, depth := range []int{12, 18, 24} {
+			result, ok := c.Get(PrimaryEngine, fen, CacheQuery{Depth: depth})
+			if !ok || result.Depth != 24 {
+				t.Errorf("Get(depth %d) = %+v, %v; want the depth 24 entry", depth, result, ok)
+			}
+		}
+		if _, ok := c.Get(PrimaryEngine, fen, CacheQuery{Depth: 25}); ok {
+			t.Error("Get(depth 25) hit a depth 24 entry")
+		}
+	}
+
+	// Other engine profiles get their own entry
+	c.Set("secondary", fens[0], 10, engine.Evaluation{Depth: 10}, "e7e5", engine.SourceEngine)
+	if stats := c.Stats(); stats.Size != len(fens)+1 {
+		t.Errorf("cache size = %d, want %d", stats.Size, len(fens)+1)
+	}
+	if _, ok := c.Get("secondary", fens[0], CacheQuery{Depth: 12}); ok {
+		t.Error("secondary profile answered from the primary entry")
+	}
+}
+
+func TestPositionCache_HalfmoveClock(t *testing.T) {
+	c := NewPositionCache(100)
+	fresh := "8/8/4k3/8/8/3K4/8/7R w - - 0 60"
+	late := "8/8/4k3/8/8/3K4/8/7R w - - 90 100"
+	eval := engine.Evaluation{Depth: 20, Centipawns: 900}
+
+	// An evaluation with a fresh clock isn't served near the fifty-move rule
+	c.Set(PrimaryEngine, fresh, 20, eval, "h1h6", engine.SourceEngine)
+	if _, ok := c.Get(PrimaryEngine, late, CacheQuery{Depth: 20}); ok {
+		t.Error("halfmove clock 90 was served the clock 0 entry")
+	}
+	if _, ok := c.Get(PrimaryEngine, fresh, CacheQuery{Depth: 20}); !ok {
+		t.Error("clock 0 entry missing")
+	}
+
+	// Nor is one from near the rule stored for fresh clocks to reuse
+	c = NewPositionCache(100)
+	c.Set(PrimaryEngine, late, 20, engine.Evaluation{Depth: 20}, "h1h6", engine.SourceEngine)
+	if c.Import(PrimaryEngine, late, 20, engine.Evaluation{Depth: 20}, "h1h6") {
+		t.Error("imported a position with halfmove clock 90")
+	}
+	if stats := c.Stats(); stats.Size != 0 {
+		t.Errorf("cache holds %d entries, want 0", stats.Size)
+	}
+
+	c.SetMaxHalfmoveClock(100)
+	c.Set(PrimaryEngine, late, 20, eval, "h1h6", engine.SourceEngine)
+	if _, ok := c.Get(PrimaryEngine, late, CacheQuery{Depth: 20}); !ok {
+		t.Error("clock 90 not cached with a limit of 100")
+	}
+}
+
+func TestPositionCache_Query(t *testing.T) {
+	c := NewPositionCache(100)
+	pvs := &engine.AnalysisResult{
+		Evaluations: []engine.Evaluation{
+			{Depth: 20, Centipawns: 30, PV: []string{"e2e4", "e7e5"}, Nodes: 1000, MultiPV: 1},
+			{Depth: 20, Centipawns: 20, PV: []string{"d2d4", "d7d5"}, Nodes: 1000, MultiPV: 2},
+			{Depth: 20, Centipawns: 10, PV: []string{"c2c4"}, Nodes: 1000, MultiPV: 3},
+		},
+		PVCount:      3,
+		BestMove:     "e2e4",
+		Depth:        20,
+		Source:       engine.SourceEngine,
+		SearchTimeMs: 500,
+	}
+	c.SetResult(PrimaryEngine, startFEN, 20, 3, pvs)
+
+	// A multi-PV entry answers narrower requests with their PVs only
+	result, ok := c.Get(PrimaryEngine, startFEN, CacheQuery{Depth: 18, MultiPV: 2, NeedPV: true})
+	if !ok || result.PVCount != 2 || len(result.Evaluations) != 2 || result.Evaluations[1].PV[0] != "d2d4" {
+		t.Fatalf("Get(2 PVs) = %+v, %v", result, ok)
+	}
+	if result.SearchTimeMs != 0 || result.Source != engine.SourceEngine {
+		t.Errorf("cached answer reports search time %d from %q", result.SearchTimeMs, result.Source)
+	}
+	result.Evaluations[0].Centipawns = 0
+	if again, _ := c.Get(PrimaryEngine, startFEN, CacheQuery{Depth: 20}); again.Evaluations[0].Centipawns != 30 {
+		t.Error("changing an answer changed the cached entry")
+	}
+
+	// A search of the same depth for fewer PVs doesn't narrow the entry
+	c.Set(PrimaryEngine, startFEN, 20, pvs.Evaluations[0], "e2e4", engine.SourceEngine)
+	if _, ok := c.Get(PrimaryEngine, startFEN, CacheQuery{Depth: 20, MultiPV: 3}); !ok {
+		t.Error("single-PV search of the same depth replaced the 3 PV entry")
+	}
+
+	// Imported entries have scores but not lines
+	c.Import(PrimaryEngine, afterE4FEN, 30, engine.Evaluation{Depth: 30, Centipawns: -30}, "c7c5")
+	if _, ok := c.Get(PrimaryEngine, afterE4FEN, CacheQuery{Depth: 20}); !ok {
+		t.Error("imported entry didn't answer for its score")
+	}
+
+	before := c.Stats()
+	for _, q := range []CacheQuery{
+		{Depth: 21, MultiPV: 1},
+		{Depth: 20, MultiPV: 4},
+		{Depth: 20, NeedPV: true},
+	} {
+		fen := startFEN
+		if q.NeedPV {
+			fen = afterE4FEN
+		}
+		if result, ok := c.Get(PrimaryEngine, fen, q); ok {
+			t.Errorf("Get(%+v) = %+v, want a miss", q, result)
+		}
+	}
+	c.Get(PrimaryEngine, afterE5FEN, CacheQuery{Depth: 1})
+	stats := c.Stats()
+	if stats.NearMisses-before.NearMisses != 3 || stats.Misses-before.Misses != 1 || stats.Hits != before.Hits {
+		t.Errorf("stats went from %+v to %+v, want 3 near misses and 1 miss", before, stats)
+	}
+
+	// A deeper search replaces the entry, even with fewer PVs
+	c.Set(PrimaryEngine, startFEN, 22, engine.Evaluation{Depth: 22, PV: []string{"e2e4"}}, "e2e4", engine.SourceEngine)
+	if _, ok := c.Get(PrimaryEngine, startFEN, CacheQuery{Depth: 22}); !ok {
+		t.Error("deeper search didn't replace the entry")
+	}
+	if _, ok := c.Get(PrimaryEngine, startFEN, CacheQuery{Depth: 20, MultiPV: 3}); ok {
+		t.Error("3 PVs still answered after a single-PV search replaced them")
+	}
+}
+
+// newSlowFakeAnalyzer returns an analyzer whose engine runs search
+// number stalled, every search if 0, until it is stopped, with the given
+// analysis timeout
+func newSlowFakeAnalyzer(t *testing.T, stalled int, timeout time.Duration) *Analyzer {
+	fake := fakeEngine
+	fake.Failures = []enginetest.Failure{{Mode: enginetest.Stall, Search: stalled}}
+	return NewAnalyzer(newEnginePool(t, fake), zap.NewNop(), 1, 12, 20, timeout)
+}
+
+// newFakePool returns a pool of one fakeEngine
+func newFakePool(t *testing.T) *pool.Pool {
+	return newEnginePool(t, fakeEngine)
+}
+
+// newEnginePool returns a pool of one fake engine
+func newEnginePool(t testing.TB, fake enginetest.Engine) *pool.Pool {
+	t.Helper()
+	p, err := pool.NewPool(1, engine.Config{BinaryPath: fake.Binary(t), Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+const testPGN = "1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 Nf6 5. O-O Be7 6. Re1 b5 7. Bb3 d6 *"
+
+func TestAnalyzeGame_ProfileChangesOnlyClassifications(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	ctx := context.Background()
+
+	standard, err := a.AnalyzeGame(ctx, "g1", testPGN, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := a.CacheStats()
+
+	strict, err := a.AnalyzeGame(ctx, "g1", testPGN, 12, GameOptions{ThresholdProfile: evaluation.ProfileStrict}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := a.CacheStats()
+
+	if standard.ThresholdProfile != evaluation.ProfileStandard || standard.Thresholds != evaluation.DefaultThresholds {
+		t.Errorf("standard run recorded %q %+v", standard.ThresholdProfile, standard.Thresholds)
+	}
+	if strict.ThresholdProfile != evaluation.ProfileStrict || strict.Thresholds != evaluation.DefaultProfiles()[evaluation.ProfileStrict] {
+		t.Errorf("strict run recorded %q %+v", strict.ThresholdProfile, strict.Thresholds)
+	}
+
+	// The second run is served entirely from the cache, which it must not modify
+	if after.Size != before.Size {
+		t.Errorf("cache size changed from %d to %d", before.Size, after.Size)
+	}
+	if after.Hits-before.Hits != int64(len(standard.Moves)+1) {
+		t.Errorf("expected every position to hit the cache, got %d hits", after.Hits-before.Hits)
+	}
+	for _, m := range standard.Moves {
+		cached, ok := a.posCache.Get(PrimaryEngine, m.FENBefore, CacheQuery{Depth: 12})
+		if !ok || cached.Evaluations[0].Centipawns != m.EvalBefore.Centipawns {
+			t.Errorf("ply %d: cached eval %+v does not match analysis %+v", m.Ply, cached, m.EvalBefore)
+		}
+	}
+
+	if len(standard.Moves) != len(strict.Moves) {
+		t.Fatalf("move counts differ: %d vs %d", len(standard.Moves), len(strict.Moves))
+	}
+	differ := 0
+	for i := range standard.Moves {
+		s, x := standard.Moves[i], strict.Moves[i]
+		if s.EvalBefore.Centipawns != x.EvalBefore.Centipawns || s.EvalAfter.Centipawns != x.EvalAfter.Centipawns ||
+			s.CentipawnLoss != x.CentipawnLoss || s.BestMoveUCI != x.BestMoveUCI {
+			t.Errorf("ply %d: raw evaluation differs between profiles", s.Ply)
+		}
+		if s.Classification != x.Classification {
+			differ++
+		}
+	}
+	if differ == 0 {
+		t.Error("expected the strict profile to change at least one classification")
+	}
+	if standard.WhiteMetrics.ACPL != strict.WhiteMetrics.ACPL {
+		t.Error("ACPL should not depend on the threshold profile")
+	}
+}
+
+func TestAnalyzeGame_DepthsAcrossCachedRuns(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	ctx := context.Background()
+
+	// The fake engine always reaches depth 12
+	deep, err := a.AnalyzeGame(ctx, "g1", testPGN, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shallow, err := a.AnalyzeGame(ctx, "g1", testPGN, 8, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, m := range shallow.Moves {
+		first := deep.Moves[i]
+		if first.FromCache || first.RequestedDepth != 12 || first.AchievedDepth != 12 {
+			t.Errorf("first run ply %d: requested %d, achieved %d, cached %v", m.Ply, first.RequestedDepth, first.AchievedDepth, first.FromCache)
+		}
+		// The depth 8 run is answered by the depth 12 searches, line and all
+		if !m.FromCache || m.RequestedDepth != 8 || m.AchievedDepth != 12 {
+			t.Errorf("second run ply %d: requested %d, achieved %d, cached %v", m.Ply, m.RequestedDepth, m.AchievedDepth, m.FromCache)
+		}
+		if strings.Join(m.PV, " ") != strings.Join(first.PV, " ") || m.AchievedDepth != m.EvalBefore.Depth {
+			t.Errorf("second run ply %d: pv %v at depth %d, want %v", m.Ply, m.PV, m.EvalBefore.Depth, first.PV)
+		}
+	}
+
+	// A deeper request than cached searches again
+	deeper, err := a.AnalyzeGame(ctx, "g1", testPGN, 15, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := deeper.Moves[0]; m.FromCache || m.RequestedDepth != 15 {
+		t.Errorf("third run: requested %d, cached %v", m.RequestedDepth, m.FromCache)
+	}
+}
+
+func TestAnalyzeGame_UnknownProfile(t *testing.T) {
+	a := NewAnalyzer(nil, zap.NewNop(), 1, 12, 20, time.Minute)
+
+	_, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, GameOptions{ThresholdProfile: "expert"}, nil)
+	if !errors.Is(err, ErrUnknownThresholdProfile) {
+		t.Errorf("AnalyzeGame() = %v, want ErrUnknownThresholdProfile", err)
+	}
+}
+
+func TestSetThresholdProfiles(t *testing.T) {
+	a := NewAnalyzer(nil, zap.NewNop(), 1, 12, 20, time.Minute)
+
+	custom := map[string]evaluation.Thresholds{"club": {Best: 8, Excellent: 20, Good: 45, Inaccuracy: 90, Mistake: 250}}
+	if err := a.SetThresholdProfiles(custom, "standard"); !errors.Is(err, ErrUnknownThresholdProfile) {
+		t.Errorf("missing default profile: got %v", err)
+	}
+	if err := a.SetThresholdProfiles(custom, "club"); err != nil {
+		t.Fatal(err)
+	}
+
+	name, th, err := a.Thresholds("")
+	if err != nil || name != "club" || th.Best != 8 {
+		t.Errorf("Thresholds(\"\") = %q %+v %v", name, th, err)
+	}
+}
+
+func TestClampDepth(t *testing.T) {
+	a := NewAnalyzer(nil, zap.NewNop(), 8, 12, 20, time.Minute)
+
+	tests := []struct {
+		requested, want int
+	}{
+		{0, 12},
+		{-3, 12},
+		{1, 8},
+		{8, 8},
+		{15, 15},
+		{20, 20},
+		{99, 20},
+	}
+	for _, tt := range tests {
+		if got := a.ClampDepth(tt.requested); got != tt.want {
+			t.Errorf("ClampDepth(%d) = %d, want %d", tt.requested, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeGame_TimeoutReturnsPartialResult(t *testing.T) {
+	// Five positions are searched in time, the sixth runs past the budget
+	a := newSlowFakeAnalyzer(t, 6, time.Second)
+
+	start := time.Now()
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 4, GameOptions{}, nil)
+	if err != nil {
+		t.Fatalf("AnalyzeGame() error = %v, want partial result", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("AnalyzeGame() took %v with a 1s timeout", elapsed)
+	}
+
+	if !analysis.TimedOut {
+		t.Error("expected TimedOut to be set")
+	}
+	if analysis.TotalMoves != 14 {
+		t.Errorf("TotalMoves = %d, want 14", analysis.TotalMoves)
+	}
+	if len(analysis.Moves) != 4 {
+		t.Errorf("analyzed %d moves, want the 4 whose positions were searched", len(analysis.Moves))
+	}
+	if analysis.Depth != 4 {
+		t.Errorf("Depth = %d, want 4", analysis.Depth)
+	}
+	for _, m := range analysis.Moves {
+		if m.AchievedDepth != 4 {
+			t.Errorf("ply %d: depth %d, the stopped search must not be used", m.Ply, m.AchievedDepth)
+		}
+	}
+
+	// Only complete searches are cached
+	if stats := a.CacheStats(); stats.Size != 5 {
+		t.Errorf("cache holds %d positions, want 5", stats.Size)
+	}
+}
+
+func TestAnalyzeGame_CallerDeadlineIsAnError(t *testing.T) {
+	a := newSlowFakeAnalyzer(t, 0, time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := a.AnalyzeGame(ctx, "g1", testPGN, 12, GameOptions{}, nil)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("AnalyzeGame() = %v, want ErrTimeout", err)
+	}
+}
+
+func TestAnalyzePosition_TimeoutStopsSearch(t *testing.T) {
+	a := newSlowFakeAnalyzer(t, 0, time.Second)
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+
+	start := time.Now()
+	result, err := a.AnalyzePosition(context.Background(), fen, 12, 1)
+	if err != nil {
+		t.Fatalf("AnalyzePosition() error = %v, want partial result", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("AnalyzePosition() took %v with a 1s timeout", elapsed)
+	}
+
+	if !result.Stopped || result.Depth != 1 || result.BestMove != "e2e4" {
+		t.Errorf("got stopped=%v depth=%d best=%q, want the depth 1 result of a stopped search",
+			result.Stopped, result.Depth, result.BestMove)
+	}
+	if stats := a.CacheStats(); stats.Size != 0 {
+		t.Errorf("stopped search was cached")
+	}
+}
+
+// fakeCloud answers every position with a fixed depth
+type fakeCloud struct {
+	depth int
+	calls int
+}
+
+func (c *fakeCloud) Evaluate(ctx context.Context, fen string) (*engine.AnalysisResult, error) {
+	c.calls++
+	return &engine.AnalysisResult{
+		Evaluations: []engine.Evaluation{{Depth: c.depth, Centipawns: 35, PV: []string{"g1f3"}, MultiPV: 1}},
+		BestMove:    "g1f3",
+		FEN:         fen,
+		Depth:       c.depth,
+		Source:      engine.SourceCloud,
+	}, nil
+}
+
+func TestAnalyzePosition_CloudFallback(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	cloud := &fakeCloud{depth: 30}
+	a.SetCloudFallback(cloud, 50*time.Millisecond, 15)
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+
+	// A free engine is used first
+	result, err := a.AnalyzePosition(context.Background(), fen, 12, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != engine.SourceEngine || cloud.calls != 0 {
+		t.Fatalf("source = %q, cloud calls = %d, want engine and 0", result.Source, cloud.calls)
+	}
+
+	// With the only engine busy, the cloud answers and is cached
+	eng, err := a.pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.pool.Put(eng)
+
+	other := "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2"
+	for i := 0; i < 2; i++ {
+		result, err = a.AnalyzePosition(context.Background(), other, 15, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Source != engine.SourceCloud || result.BestMove != "g1f3" {
+			t.Errorf("call %d: source = %q, best = %q, want the cloud result", i, result.Source, result.BestMove)
+		}
+	}
+	if cloud.calls != 1 {
+		t.Errorf("cloud calls = %d, want 1 (second answer from cache)", cloud.calls)
+	}
+
+	// Multi-PV and deeper requests wait for the engine instead
+	for _, req := range []struct{ depth, multiPV int }{{18, 1}, {12, 3}} {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		_, err := a.AnalyzePosition(ctx, "8/8/8/8/8/8/8/K6k w - - 0 1", req.depth, req.multiPV)
+		cancel()
+		if err == nil || cloud.calls != 1 {
+			t.Errorf("depth %d multipv %d: err = %v, cloud calls = %d, want the engine wait to fail",
+				req.depth, req.multiPV, err, cloud.calls)
+		}
+	}
+}
+
+func TestAnalyzePosition_CloudTooShallow(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	cloud := &fakeCloud{depth: 10}
+	a.SetCloudFallback(cloud, 20*time.Millisecond, 22)
+
+	eng, err := a.pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		a.pool.Put(eng)
+	}()
+
+	result, err := a.AnalyzePosition(context.Background(), "8/8/8/8/8/8/8/K6k w - - 0 1", 15, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cloud.calls != 1 || result.Source != engine.SourceEngine {
+		t.Errorf("cloud calls = %d, source = %q, want the engine to answer after a shallow cloud result", cloud.calls, result.Source)
+	}
+}
+
+func TestCreateMoveAnalysis_MoveAccuracy(t *testing.T) {
+	a := &Analyzer{logger: zap.NewNop()}
+	start := Position{FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", LegalMoves: 20}
+	next := Position{FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", MoveSAN: "e4", MoveUCI: "e2e4"}
+	mate := func(n int) engine.Evaluation { return engine.Evaluation{IsMate: true, MateIn: &n} }
+
+	tests := []struct {
+		name       string
+		legalMoves int
+		before     engine.Evaluation
+		after      engine.Evaluation // Opponent's point of view
+		want       float64
+		forced     bool
+	}{
+		{"kept the balance", 20, engine.Evaluation{Centipawns: 30}, engine.Evaluation{Centipawns: -30}, 100, false},
+		{"dropped a pawn", 20, engine.Evaluation{Centipawns: 0}, engine.Evaluation{Centipawns: 100}, 52.90, false},
+		{"let the mate go", 20, mate(2), engine.Evaluation{Centipawns: -300}, 50.30, false},
+		{"forced", 1, engine.Evaluation{Centipawns: 0}, engine.Evaluation{Centipawns: 100}, 100, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos := start
+			pos.LegalMoves = tt.legalMoves
+			move := a.createMoveAnalysis(0, pos, next, &tt.before, &tt.after, "e2e4", evaluation.DefaultThresholds)
+
+			if move.Forced != tt.forced || math.Abs(move.MoveAccuracy-tt.want) > 0.01 {
+				t.Errorf("accuracy = %.2f, forced = %v, want %.2f, %v", move.MoveAccuracy, move.Forced, tt.want, tt.forced)
+			}
+		})
+	}
+}
+
+func TestCalculateMetrics_AccuracyMethod(t *testing.T) {
+	moves := []MoveAnalysis{
+		{Color: "white", MoveAccuracy: 100, Classification: ClassBest},
+		{Color: "white", CentipawnLoss: 100, MoveAccuracy: 50, Classification: ClassMistake},
+		{Color: "white", MoveAccuracy: 100, Forced: true, Classification: ClassBest, Source: PlyForcedSkipped},
+		{Color: "white", MoveAccuracy: 100, Classification: ClassBook, Source: PlyBookSkipped},
+		{Color: "black", CentipawnLoss: 400, MoveAccuracy: 10, Classification: ClassBlunder},
+		{Color: "black", MoveAccuracy: 100, Forced: true, Classification: ClassBest, Source: PlyForcedSkipped},
+	}
+
+	tests := []struct {
+		method evaluation.AccuracyMethod
+		color  string
+		want   float64
+	}{
+		// Book moves are left out of the loss
+		{evaluation.AccuracyCappedLoss, "white", 280.0 / 3},
+		{evaluation.AccuracyCappedLoss, "black", 60},
+		// Forced and book moves are left out of the mean
+		{evaluation.AccuracyMoveMean, "white", 75},
+		{evaluation.AccuracyMoveMean, "black", 10},
+	}
+	for _, tt := range tests {
+		a := &Analyzer{accuracyMethod: tt.method}
+		if got := a.calculateMetrics(moves, tt.color).Accuracy; math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s %s accuracy = %v, want %v", tt.method, tt.color, got, tt.want)
+		}
+	}
+
+	a := &Analyzer{accuracyMethod: evaluation.AccuracyMoveMean}
+	if got := a.calculateMetrics(moves[2:3], "white").Accuracy; got != 100 {
+		t.Errorf("only forced moves: accuracy = %v, want 100", got)
+	}
+	if err := a.SetAccuracyMethod("average"); err == nil {
+		t.Error("SetAccuracyMethod(average) = nil, want error")
+	}
+}
+
+func TestParsePGN_LegalMoves(t *testing.T) {
+	// After 1. e4 f6 2. Qh5+ Black can only block with g6
+	positions, err := ParsePGN("1. e4 f6 2. Qh5+ g6 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if positions[0].LegalMoves != 20 || positions[3].LegalMoves != 1 {
+		t.Errorf("legal moves = %d and %d, want 20 and 1", positions[0].LegalMoves, positions[3].LegalMoves)
+	}
+}
+
+// twoPVEngine finds two PVs whatever the MultiPV in a position with two
+// legal moves, reporting PV 2 first as engines may while lines change
+// order
+var twoPVEngine = enginetest.Engine{
+	Default:      enginetest.Eval{BestMove: "a2g2", PV: []string{"a8b7"}},
+	ReverseLines: true,
+}
+
+func TestGetBestMoves_FewerLegalMoves(t *testing.T) {
+	a := NewAnalyzer(newEnginePool(t, twoPVEngine), zap.NewNop(), 1, 12, 20, time.Minute)
+
+	// Only Rxg2 and Kxg2 get out of check
+	fen := "k7/8/8/8/8/8/R5q1/7K w - - 0 1"
+	if n, _ := legalMoves(fen); n != 2 {
+		t.Fatalf("position has %d legal moves, want 2", n)
+	}
+
+	result, err := a.GetBestMoves(context.Background(), fen, 5, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PVCount != 2 || len(result.Evaluations) != 2 {
+		t.Fatalf("PV count = %d with %d evaluations, want 2", result.PVCount, len(result.Evaluations))
+	}
+	if result.Evaluations[0].MultiPV != 1 || result.Evaluations[0].PV[0] != "a2g2" || result.Evaluations[1].MultiPV != 2 {
+		t.Errorf("evaluations = %+v, want PV 1 first", result.Evaluations)
+	}
+	if len(result.Notes) != 1 || result.Notes[0] != engine.NoteFewerLegalMoves {
+		t.Errorf("notes = %v, want %s", result.Notes, engine.NoteFewerLegalMoves)
+	}
+
+	// Asking for no more PVs than there are moves needs no note
+	if result, err = a.GetBestMoves(context.Background(), fen, 2, 12); err != nil || len(result.Notes) != 0 {
+		t.Errorf("count 2: notes = %v, err = %v, want none", result.Notes, err)
+	}
+}
+
+func TestAnalyzePosition_GameOver(t *testing.T) {
+	// No pool: finished positions never reach an engine
+	a := NewAnalyzer(nil, zap.NewNop(), 1, 12, 20, time.Minute)
+	tests := []struct {
+		name   string
+		fen    string
+		reason string
+		eval   int
+	}{
+		{"fool's mate", "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", engine.GameOverCheckmate, -evaluation.MateScore},
+		{"stalemate", "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", engine.GameOverStalemate, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := a.AnalyzePosition(context.Background(), tt.fen, 12, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.GameOver != tt.reason || result.BestMove != "" || len(result.Evaluations) != 1 || centipawns(result.Evaluations[0]) != tt.eval {
+				t.Errorf("result = %+v, want %s scored %d without a best move", result, tt.reason, tt.eval)
+			}
+
+			moves, err := a.GetBestMoves(context.Background(), tt.fen, 3, 12)
+			if err != nil || moves.GameOver != tt.reason || len(moves.Evaluations) != 0 {
+				t.Errorf("GetBestMoves() = %+v, %v; want %s and no moves", moves, err, tt.reason)
+			}
+		})
+	}
+	if stats := a.CacheStats(); stats.Size != 0 {
+		t.Errorf("cache holds %d finished positions, want none", stats.Size)
+	}
+}
+
+func TestAnalyzeGame_EndsInMate(t *testing.T) {
+	a := newFakeAnalyzer(t)
+
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", "1. f3 e5 2. g4 Qh4# 0-1", 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Moves) != 4 {
+		t.Fatalf("got %d moves, want 4", len(analysis.Moves))
+	}
+	mate := analysis.Moves[3]
+	if !mate.EvalAfter.IsMate || mate.EvalAfter.MateIn == nil || *mate.EvalAfter.MateIn != 0 {
+		t.Errorf("eval after Qh4# = %+v, want mate 0", mate.EvalAfter)
+	}
+	if _, found := a.posCache.Get(PrimaryEngine, mate.FENAfter, CacheQuery{Depth: 12}); found {
+		t.Error("final mate position was searched and cached")
+	}
+}
+
+func TestAnalyzeGame_NoMoves(t *testing.T) {
+	a := NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+
+	for name, pgn := range map[string]string{
+		"headers only":      "[Event \"Rated blitz game\"]\n[Site \"https://lichess.org/abcd1234\"]\n[Result \"*\"]\n\n",
+		"whitespace only":   " \n\t\n",
+		"result token only": "[Result \"1/2-1/2\"]\n\n1/2-1/2",
+	} {
+		if _, err := a.AnalyzeGame(context.Background(), "g1", pgn, 12, GameOptions{}, nil); !errors.Is(err, ErrEmptyGame) {
+			t.Errorf("%s: err = %v, want ErrEmptyGame", name, err)
+		}
+	}
+}
+
+func TestAnalyzeGame_PoolTag(t *testing.T) {
+	p, err := pool.NewPool(3, engine.Config{BinaryPath: fakeEngine.Binary(t), Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	a := NewAnalyzer(p, zap.NewNop(), 1, 20, 30, time.Minute)
+	workers := gameWorkers(p)
+
+	// A game and positions analyzed at once, for different calls
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ctx := pool.WithTag(context.Background(), "AnalyzeGame")
+		if _, err := a.AnalyzeGame(ctx, "g1", testPGN, 12, GameOptions{}, nil); err != nil {
+			t.Error(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		ctx := pool.WithTag(context.Background(), "AnalyzePosition")
+		for _, fen := range []string{"4k3/8/8/8/8/8/8/4K2R w K - 0 1", "4k3/8/8/8/8/8/8/R3K3 w Q - 0 1"} {
+			if _, err := a.AnalyzePosition(ctx, fen, 12, 1); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	// The game's workers lent engines for its call's tag, and the busy
+	// time is split between the calls without a remainder
+	stats := a.PoolStats()
+	var sum time.Duration
+	loans := map[string]int64{}
+	for _, tag := range stats.Tags {
+		sum += tag.Busy
+		loans[tag.Tag] = tag.Loans
+	}
+	if len(stats.Tags) != 2 || sum != stats.BusyTime || stats.BusyTime == 0 {
+		t.Fatalf("tags = %+v, busy time %v", stats.Tags, stats.BusyTime)
+	}
+	// One loan for the engine version, then one per worker
+	if loans["AnalyzeGame"] < 2 || loans["AnalyzeGame"] > int64(1+workers) || loans["AnalyzePosition"] != 2 {
+		t.Errorf("loans = %v, want 2 to %d for the game and 2 for the positions", loans, 1+workers)
+	}
+}
@@ -0,0 +1,98 @@
+package analyzer
+
+import (
+	"strings"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/notnil/chess"
+)
+
+// maxEngineMultiPV is the most PVs an engine can be asked for
+const maxEngineMultiPV = 10
+
+// maxExtraPVSearches bounds the searches GetBestMoves repeats with more
+// PVs when the engine's lines share first moves
+const maxExtraPVSearches = 2
+
+// transpositionPlies is how far into two lines GetBestMoves looks for a
+// position both reach: two moves of each side, the earliest lines
+// starting with different moves can meet
+const transpositionPlies = 4
+
+// SetMaxMultiPV sets the most PVs GetBestMoves asks an engine for, the
+// searches it repeats for more distinct moves included. It is at most 10,
+// the default; n below 1 keeps the limit.
+func (a *Analyzer) SetMaxMultiPV(n int) {
+	if n >= 1 {
+		a.maxMultiPV = min(n, maxEngineMultiPV)
+	}
+}
+
+// distinctLines collapses the lines of result sharing a first move into
+// the deepest of them, ranked as the best of them was, and numbers the
+// lines left from 1. A line reaching, within transpositionPlies, a
+// position a better line reaches at the same ply gets the better line's
+// MultiPV as TransposesTo. It returns the number of lines left.
+func distinctLines(fen string, result *engine.AnalysisResult) int {
+	start := decodePosition(fen)
+	byMove := make(map[string]int, len(result.Evaluations))
+	lines := make([]engine.Evaluation, 0, len(result.Evaluations))
+	for _, eval := range result.Evaluations {
+		if len(eval.PV) == 0 {
+			lines = append(lines, eval)
+			continue
+		}
+		first := start.canonical(eval.PV[0])
+		if i, ok := byMove[first]; ok {
+			if eval.Depth > lines[i].Depth {
+				eval.MultiPV = lines[i].MultiPV
+				lines[i] = eval
+			}
+			continue
+		}
+		byMove[first] = len(lines)
+		lines = append(lines, eval)
+	}
+
+	type plyPosition struct {
+		ply      int
+		position string
+	}
+	reached := make(map[plyPosition]int) // To the MultiPV of the first line reaching it
+	for i := range lines {
+		lines[i].MultiPV = i + 1
+		lines[i].TransposesTo = 0
+		if start.err != nil {
+			continue
+		}
+		for ply, position := range linePositions(start.position, lines[i].PV) {
+			key := plyPosition{ply, position}
+			if better, ok := reached[key]; ok {
+				if lines[i].TransposesTo == 0 {
+					lines[i].TransposesTo = better
+				}
+				continue
+			}
+			reached[key] = i + 1
+		}
+	}
+	result.Evaluations = lines
+	result.PVCount = len(lines)
+	return len(lines)
+}
+
+// linePositions returns the positions, without the move counters, after
+// each of the first transpositionPlies moves of pv played from position.
+// It stops at the first illegal move.
+func linePositions(position *chess.Position, pv []string) []string {
+	var keys []string
+	for _, uci := range pv[:min(len(pv), transpositionPlies)] {
+		move, ok := legalUCI(position, uci)
+		if !ok {
+			break
+		}
+		position = position.Update(move)
+		keys = append(keys, strings.Join(strings.Fields(position.String())[:4], " "))
+	}
+	return keys
+}
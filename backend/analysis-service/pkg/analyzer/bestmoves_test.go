@@ -0,0 +1,77 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"go.uber.org/zap"
+)
+
+// transposingEngine answers with PV 3 repeating PV 1's first move at a
+// lower depth, and PV 2 transposing into PV 1 after two moves each. A
+// fourth PV, e4, is found only when asked for four.
+var transposingEngine = enginetest.Engine{Default: enginetest.Eval{
+	Centipawns: 30,
+	BestMove:   "g1f3",
+	PV:         []string{"d7d5", "d2d4", "g8f6"},
+	Lines: []enginetest.Line{
+		{Centipawns: 28, PV: []string{"d2d4", "d7d5", "g1f3", "g8f6"}},
+		{Depth: 10, Centipawns: 20, PV: []string{"g1f3", "g8f6"}},
+		{Centipawns: 15, PV: []string{"e2e4", "e7e5"}},
+	},
+}}
+
+func TestGetBestMoves_Transpositions(t *testing.T) {
+	a := NewAnalyzer(newEnginePool(t, transposingEngine), zap.NewNop(), 1, 12, 20, time.Minute)
+
+	result, err := a.GetBestMoves(context.Background(), startFEN, 3, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, eval := range result.Evaluations {
+		got = append(got, eval.PV[0])
+	}
+	if strings.Join(got, " ") != "g1f3 d2d4 e2e4" || result.PVCount != 3 {
+		t.Fatalf("moves %v (PV count %d), want g1f3 d2d4 e2e4", got, result.PVCount)
+	}
+	for i, want := range []struct{ multiPV, depth, transposesTo int }{{1, 12, 0}, {2, 12, 1}, {3, 12, 0}} {
+		eval := result.Evaluations[i]
+		if eval.MultiPV != want.multiPV || eval.Depth != want.depth || eval.TransposesTo != want.transposesTo {
+			t.Errorf("line %d = PV %d at depth %d transposing to %d, want %+v", i+1, eval.MultiPV, eval.Depth, eval.TransposesTo, want)
+		}
+	}
+	if result.BestMove != "g1f3" || len(result.Notes) != 0 {
+		t.Errorf("best move %s, notes %v", result.BestMove, result.Notes)
+	}
+	// The extra PVs stay within the limit
+	a.SetMaxMultiPV(3)
+	if result, err = a.GetBestMoves(context.Background(), startFEN, 3, 12); err != nil || len(result.Evaluations) != 2 {
+		t.Errorf("limited to 3 PVs: %d moves, %v; want the 2 distinct ones found", len(result.Evaluations), err)
+	}
+}
+
+func TestDistinctLines(t *testing.T) {
+	result := &engine.AnalysisResult{Evaluations: []engine.Evaluation{
+		{MultiPV: 1, Depth: 10, Centipawns: 30, PV: []string{"g1f3", "d7d5"}},
+		{MultiPV: 2, Depth: 14, Centipawns: 25, PV: []string{"G1F3", "g8f6"}},
+		{MultiPV: 3, Depth: 14, Centipawns: 20, PV: []string{"e2e4"}},
+		{MultiPV: 4, Depth: 14, Centipawns: 10, PV: []string{"e2e4", "e7e5"}},
+	}}
+	if n := distinctLines(startFEN, result); n != 2 || result.PVCount != 2 {
+		t.Fatalf("%d lines left, PV count %d, want 2", n, result.PVCount)
+	}
+
+	// The deepest line of a move takes the rank of the best
+	first, second := result.Evaluations[0], result.Evaluations[1]
+	if first.MultiPV != 1 || first.Depth != 14 || first.PV[1] != "g8f6" {
+		t.Errorf("first line = %+v, want g1f3's depth 14 line as PV 1", first)
+	}
+	if second.MultiPV != 2 || second.PV[0] != "e2e4" || len(second.PV) != 1 {
+		t.Errorf("second line = %+v, want e2e4 from PV 3", second)
+	}
+}
@@ -0,0 +1,78 @@
+package analyzer
+
+import (
+	"context"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"go.uber.org/zap"
+)
+
+// BookDetector decides which opening moves of a game are book moves.
+// They are classified ClassBook, counted in GameMetrics.BookMoves and left
+// out of accuracy and ACPL. A game is in book until its first move that
+// isn't.
+type BookDetector interface {
+	// IsBook reports whether move, analyzed and classified, is a book
+	// move. topMoves returns the engine's n best moves from the position
+	// before it, best first, searching only when called; it returns nil
+	// when no search can be made.
+	IsBook(move *MoveAnalysis, topMoves func(n int) []string) bool
+}
+
+// BookHeuristic stands in for an opening book: a book move is one of the
+// first Plies plies that changes the mover's evaluation by at most
+// evaluation.BookEvalWindow and is one of the engine's
+// evaluation.BookTopMoves best moves
+type BookHeuristic struct {
+	Plies int
+}
+
+// IsBook implements BookDetector. A move other than the engine's best
+// costs a search of its position for the engine's top moves.
+func (b BookHeuristic) IsBook(move *MoveAnalysis, topMoves func(n int) []string) bool {
+	change := -centipawns(move.EvalAfter) - centipawns(move.EvalBefore)
+	if !evaluation.IsBookMove(move.Ply, b.Plies, change) {
+		return false
+	}
+	if SameMoveFrom(move.FENBefore, move.PlayedMoveUCI, move.BestMoveUCI) {
+		return true
+	}
+	for _, top := range topMoves(evaluation.BookTopMoves) {
+		if SameMoveFrom(move.FENBefore, move.PlayedMoveUCI, top) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetBookDetector sets how AnalyzeGame finds book moves (nil = none)
+func (a *Analyzer) SetBookDetector(book BookDetector) {
+	a.book = book
+}
+
+// topMoves returns the first move of each of the engine's n best lines
+// from fen, best first, or nil when the search fails
+func (a *Analyzer) topMoves(ctx context.Context, enginePool *pool.Pool, fen string, depth, n int) []string {
+	eng, err := enginePool.Get(ctx)
+	if err != nil {
+		return nil
+	}
+	defer enginePool.Put(eng)
+
+	searchStart := time.Now()
+	result, err := eng.AnalyzePositionContext(ctx, fen, depth, n)
+	meterSearch(ctx, time.Since(searchStart))
+	if err != nil {
+		a.logger.Warn("Failed to search top moves for book detection", zap.String("fen", fen), zap.Error(err))
+		return nil
+	}
+	moves := make([]string, 0, len(result.Evaluations))
+	for _, eval := range result.Evaluations {
+		if len(eval.PV) > 0 {
+			moves = append(moves, eval.PV[0])
+		}
+	}
+	return moves
+}
@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+)
+
+func TestBookHeuristic(t *testing.T) {
+	const start = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	engineTop := []string{"e2e4", "d2d4", "c2c4"}
+
+	tests := []struct {
+		name     string
+		ply      int
+		played   string
+		before   int // Mover's evaluation before the move
+		after    int // Opponent's evaluation after it
+		want     bool
+		searched bool // topMoves was called
+	}{
+		{"engine's best", 0, "e2e4", 30, -25, true, false},
+		{"in the top moves", 0, "c2c4", 30, -10, true, true},
+		{"outside the top moves", 0, "g1f3", 30, -20, false, true},
+		{"eval dropped too far", 0, "d2d4", 30, 40, false, false},
+		{"eval rose too far", 0, "d2d4", 30, -90, false, false},
+		{"past the book plies", 10, "e2e4", 30, -30, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			move := &MoveAnalysis{
+				Ply:           tt.ply,
+				FENBefore:     start,
+				PlayedMoveUCI: tt.played,
+				BestMoveUCI:   "e2e4",
+				EvalBefore:    engine.Evaluation{Centipawns: tt.before},
+				EvalAfter:     engine.Evaluation{Centipawns: tt.after},
+			}
+			searched := false
+			topMoves := func(n int) []string {
+				searched = true
+				return engineTop[:n]
+			}
+			if got := (BookHeuristic{Plies: 10}).IsBook(move, topMoves); got != tt.want || searched != tt.searched {
+				t.Errorf("IsBook() = %v, searched %v; want %v, %v", got, searched, tt.want, tt.searched)
+			}
+		})
+	}
+}
+
+// plyBook takes the plies set in it for book moves
+type plyBook map[int]bool
+
+func (b plyBook) IsBook(move *MoveAnalysis, _ func(n int) []string) bool {
+	return b[move.Ply]
+}
+
+func TestAnalyzeGame_BookMoves(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	ctx := context.Background()
+
+	without, err := a.AnalyzeGame(ctx, "g1", testPGN, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ply 4 is out of book, so ply 5 is too
+	a.SetBookDetector(plyBook{0: true, 1: true, 2: true, 3: true, 5: true})
+	with, err := a.AnalyzeGame(ctx, "g1", testPGN, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, move := range with.Moves {
+		if book := move.Classification == ClassBook; book != (i < 4) {
+			t.Errorf("ply %d classified %s", i, move.Classification)
+		}
+		if i >= 4 && move.Classification != without.Moves[i].Classification {
+			t.Errorf("ply %d classified %s, %s without book moves", i, move.Classification, without.Moves[i].Classification)
+		}
+	}
+	if with.WhiteMetrics.BookMoves != 2 || with.BlackMetrics.BookMoves != 2 {
+		t.Errorf("book moves %d and %d, want 2 each", with.WhiteMetrics.BookMoves, with.BlackMetrics.BookMoves)
+	}
+	if with.WhiteMetrics.TotalMoves != without.WhiteMetrics.TotalMoves {
+		t.Errorf("white total moves %d, want %d", with.WhiteMetrics.TotalMoves, without.WhiteMetrics.TotalMoves)
+	}
+
+	// Accuracy and ACPL are those of the moves out of book
+	want := a.calculateMetrics(without.Moves[4:], "white")
+	if got := with.WhiteMetrics; got.ACPL != want.ACPL || got.Accuracy != want.Accuracy {
+		t.Errorf("white accuracy %.2f ACPL %.2f, want %.2f and %.2f", got.Accuracy, got.ACPL, want.Accuracy, want.ACPL)
+	}
+}
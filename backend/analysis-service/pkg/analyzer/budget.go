@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+const (
+	// prepassShare is the part of a game's time budget spent on the quick
+	// first search of every position that finds the critical ones
+	prepassShare = 0.2
+
+	// minPrepassMovetime is the shortest pre-pass search worth making;
+	// budgets too small for it are split evenly without a pre-pass
+	minPrepassMovetime = 10 * time.Millisecond
+
+	// criticalWeight is how many shares of the budget a critical position
+	// gets to every other position's one
+	criticalWeight = 3
+
+	// budgetSlack is how far past a game's time budget its searches may
+	// run before the rest are dropped, as on a timeout
+	budgetSlack = 0.2
+)
+
+// prepassMovetime returns the movetime of each of n pre-pass searches
+// within budget, 0 when the budget is too small for a pre-pass
+func prepassMovetime(budget time.Duration, n int) time.Duration {
+	movetime := time.Duration(float64(budget) * prepassShare / float64(n))
+	if movetime < minPrepassMovetime {
+		return 0
+	}
+	return movetime
+}
+
+// criticalPositions flags the positions on either side of a move that
+// loses more than thresholds.Good by the evaluations known so far
+func criticalPositions(evals []engine.Evaluation, known []bool, thresholds evaluation.Thresholds) []bool {
+	critical := make([]bool, len(evals))
+	for i := 0; i+1 < len(evals); i++ {
+		if !known[i] || !known[i+1] {
+			continue
+		}
+		// Both are from the side to move: the mover's view after the move
+		// is the negated evaluation of the next position
+		if centipawns(evals[i])+centipawns(evals[i+1]) > thresholds.Good {
+			critical[i] = true
+			critical[i+1] = true
+		}
+	}
+	return critical
+}
+
+// planMovetimes splits budget between work, criticalWeight shares to
+// each critical position and one to the others. Every search gets at
+// least a millisecond.
+func planMovetimes(work []positionWork, critical []bool, budget time.Duration) {
+	shares := 0
+	for _, w := range work {
+		if critical[w.index] {
+			shares += criticalWeight
+		} else {
+			shares++
+		}
+	}
+	for i, w := range work {
+		weight := 1
+		if critical[w.index] {
+			weight = criticalWeight
+		}
+		work[i].movetime = max(time.Millisecond, budget*time.Duration(weight)/time.Duration(shares))
+	}
+}
@@ -0,0 +1,102 @@
+package analyzer
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+func TestPrepassMovetime(t *testing.T) {
+	if got := prepassMovetime(10*time.Second, 40); got != 50*time.Millisecond {
+		t.Errorf("10s over 40 positions = %v, want 50ms", got)
+	}
+	if got := prepassMovetime(time.Second, 40); got != 0 {
+		t.Errorf("1s over 40 positions = %v, want no pre-pass", got)
+	}
+}
+
+func TestCriticalPositions(t *testing.T) {
+	evals := []engine.Evaluation{
+		{Centipawns: 20},
+		{Centipawns: -20},  // 0cp lost
+		{Centipawns: 300},  // 280cp lost
+		{Centipawns: -310}, // 10cp lost
+		{Centipawns: 0},    // unknown
+	}
+	known := []bool{true, true, true, true, false}
+	got := criticalPositions(evals, known, evaluation.DefaultThresholds)
+	want := []bool{false, true, true, false, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("critical = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestPlanMovetimes(t *testing.T) {
+	work := []positionWork{{index: 1}, {index: 2}, {index: 4}}
+	critical := []bool{false, false, true, false, false}
+	planMovetimes(work, critical, 500*time.Millisecond)
+
+	want := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 100 * time.Millisecond}
+	for i, w := range work {
+		if w.movetime != want[i] {
+			t.Errorf("movetime of position %d = %v, want %v", w.index, w.movetime, want[i])
+		}
+	}
+
+	planMovetimes(work, critical, 0)
+	for _, w := range work {
+		if w.movetime != time.Millisecond {
+			t.Errorf("movetime of position %d with no budget = %v, want 1ms", w.index, w.movetime)
+		}
+	}
+}
+
+func TestAnalyzeGame_TimeBudget(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	ctx := context.Background()
+	pgn := "1. e4 e5 2. Nf3 *"
+	budget := time.Second
+
+	analysis, err := a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{TimeBudget: budget}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Moves) != 3 || analysis.TimedOut {
+		t.Fatalf("budgeted analysis: %d moves, timed out %v", len(analysis.Moves), analysis.TimedOut)
+	}
+	if analysis.TimeBudgetMs != budget.Milliseconds() {
+		t.Errorf("TimeBudgetMs = %d, want %d", analysis.TimeBudgetMs, budget.Milliseconds())
+	}
+	if math.Abs(analysis.BudgetUtilization-float64(analysis.BudgetUsedMs)/10) > 1e-9 {
+		t.Errorf("utilization %v of %dms used", analysis.BudgetUtilization, analysis.BudgetUsedMs)
+	}
+	if analysis.BudgetUsedMs > analysis.TimeBudgetMs {
+		t.Errorf("%dms used, over the %v budget", analysis.BudgetUsedMs, budget)
+	}
+	for _, move := range analysis.Moves {
+		if move.MovetimeMs <= 0 {
+			t.Errorf("ply %d: no movetime allotted", move.Ply)
+		}
+	}
+
+	// Cached positions cost nothing
+	analysis, err = a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{TimeBudget: budget}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.BudgetUsedMs != 0 || analysis.CacheCoverage != 100 {
+		t.Errorf("cached analysis used %dms, coverage %v", analysis.BudgetUsedMs, analysis.CacheCoverage)
+	}
+	for _, move := range analysis.Moves {
+		if move.MovetimeMs != 0 {
+			t.Errorf("ply %d: %dms allotted to a cached position", move.Ply, move.MovetimeMs)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"sync"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"go.uber.org/zap"
+)
+
+// CacheRejection is why a search was kept out of the position cache. An
+// engine gone wrong, like one whose output was cut short, reports
+// evaluations that would otherwise answer every later request for their
+// positions.
+type CacheRejection string
+
+const (
+	// RejectShallow is an evaluation below half the depth it would be
+	// cached at: a search cut short reports the last depth it finished
+	RejectShallow CacheRejection = "shallow"
+
+	// RejectNoBestMove is a search that gave no best move
+	RejectNoBestMove CacheRejection = "no_best_move"
+
+	// RejectImplausibleScore is a centipawn score beyond
+	// maxPlausibleCentipawns that isn't a mate
+	RejectImplausibleScore CacheRejection = "implausible_score"
+)
+
+// maxPlausibleCentipawns bounds the centipawn scores engines report;
+// Stockfish reports tablebase wins as a little under 20000
+const maxPlausibleCentipawns = 20000
+
+// An engine is quarantined, its searches no longer cached and the engine
+// replaced, once quarantineRejections of its last quarantineWindow
+// searches were rejected
+const (
+	quarantineWindow     = 10
+	quarantineRejections = 3
+)
+
+// cacheRejection returns why eval, the first PV of a search to depth with
+// bestMove, must not be cached, or "" when it may be
+func cacheRejection(depth int, eval engine.Evaluation, bestMove string) CacheRejection {
+	switch {
+	case bestMove == "" || bestMove == "(none)":
+		return RejectNoBestMove
+	case eval.Depth*2 < depth:
+		return RejectShallow
+	case !eval.IsMate && (eval.Centipawns > maxPlausibleCentipawns || eval.Centipawns < -maxPlausibleCentipawns):
+		return RejectImplausibleScore
+	}
+	return ""
+}
+
+// cacheable reports whether a search to depth may be cached, counting it
+// in the cache stats as rejected when not
+func (a *Analyzer) cacheable(depth int, eval engine.Evaluation, bestMove string) bool {
+	reason := cacheRejection(depth, eval, bestMove)
+	if reason != "" {
+		a.posCache.reject(reason)
+	}
+	return reason == ""
+}
+
+// judgeEngine records whether a search of eng's to depth may be cached,
+// and reports whether that got eng quarantined: the caller must then
+// discard it rather than return it to its pool. Together with cacheable
+// this keeps a misbehaving engine's searches out of the cache once it has
+// been noticed.
+func (a *Analyzer) judgeEngine(eng *engine.Engine, depth int, eval engine.Evaluation, bestMove string) bool {
+	reason := cacheRejection(depth, eval, bestMove)
+	if !a.cacheGuard.record(eng, reason != "") {
+		return false
+	}
+	a.logger.Warn("Quarantining engine whose searches were repeatedly kept out of the cache, replacing",
+		zap.String("lastRejection", string(reason)),
+		zap.Int("window", quarantineWindow),
+		zap.Int("rejections", quarantineRejections))
+	return true
+}
+
+// cacheGuard tracks the searches of each engine that were rejected for
+// the cache. Only engines with a rejection among their last
+// quarantineWindow searches are kept track of. The zero value is ready to
+// use.
+type cacheGuard struct {
+	mu          sync.Mutex
+	recent      map[*engine.Engine][]bool // Last searches, oldest first, true when rejected
+	quarantined int64                     // Engines quarantined so far
+}
+
+// record adds a search of eng's and reports whether eng is to be
+// quarantined for it
+func (g *cacheGuard) record(eng *engine.Engine, rejected bool) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	recent, tracked := g.recent[eng]
+	if !tracked && !rejected {
+		return false
+	}
+	recent = append(recent, rejected)
+	if len(recent) > quarantineWindow {
+		recent = recent[1:]
+	}
+	n := 0
+	for _, r := range recent {
+		if r {
+			n++
+		}
+	}
+	switch {
+	case n >= quarantineRejections:
+		delete(g.recent, eng)
+		g.quarantined++
+		return true
+	case n == 0:
+		delete(g.recent, eng)
+	default:
+		if g.recent == nil {
+			g.recent = make(map[*engine.Engine][]bool)
+		}
+		g.recent[eng] = recent
+	}
+	return false
+}
+
+// quarantinedEngines returns the number of engines quarantined so far
+func (g *cacheGuard) quarantinedEngines() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.quarantined
+}
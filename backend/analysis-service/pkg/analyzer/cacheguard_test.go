@@ -0,0 +1,98 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"go.uber.org/zap"
+)
+
+func TestCacheRejection(t *testing.T) {
+	mate := 3
+	for _, tt := range []struct {
+		name     string
+		depth    int
+		eval     engine.Evaluation
+		bestMove string
+		want     CacheRejection
+	}{
+		{"plausible", 20, engine.Evaluation{Depth: 20, Centipawns: 35}, "e2e4", ""},
+		{"a little shallow", 20, engine.Evaluation{Depth: 10, Centipawns: 35}, "e2e4", ""},
+		{"tablebase win", 20, engine.Evaluation{Depth: 20, Centipawns: -19990}, "e2e4", ""},
+		{"mate", 20, engine.Evaluation{Depth: 20, Centipawns: 99999, IsMate: true, MateIn: &mate}, "e2e4", ""},
+		{"far too shallow", 20, engine.Evaluation{Depth: 9, Centipawns: 35}, "e2e4", RejectShallow},
+		{"no depth", 20, engine.Evaluation{Centipawns: 35}, "e2e4", RejectShallow},
+		{"no best move", 20, engine.Evaluation{Depth: 20, Centipawns: 35}, "", RejectNoBestMove},
+		{"best move none", 20, engine.Evaluation{Depth: 20}, "(none)", RejectNoBestMove},
+		{"implausible", 20, engine.Evaluation{Depth: 20, Centipawns: 31000}, "e2e4", RejectImplausibleScore},
+		{"implausible loss", 20, engine.Evaluation{Depth: 20, Centipawns: -20001}, "e2e4", RejectImplausibleScore},
+	} {
+		if got := cacheRejection(tt.depth, tt.eval, tt.bestMove); got != tt.want {
+			t.Errorf("%s: cacheRejection = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCacheGuard(t *testing.T) {
+	var g cacheGuard
+	eng := &engine.Engine{}
+	record := func(outcomes string) bool {
+		quarantined := false
+		for _, c := range outcomes {
+			quarantined = g.record(eng, c == 'r')
+		}
+		return quarantined
+	}
+
+	// Engines without recent rejections aren't tracked
+	record("aaaa")
+	if len(g.recent) != 0 {
+		t.Errorf("tracking %d engines without a rejection", len(g.recent))
+	}
+
+	// Rejections further apart than the window don't add up
+	if record("r" + strings.Repeat("a", quarantineWindow-1) + "rr") {
+		t.Error("quarantined for rejections outside the window")
+	}
+	if !record("r") {
+		t.Errorf("not quarantined after %d rejections in %d searches", quarantineRejections, quarantineWindow)
+	}
+	if len(g.recent) != 0 || g.quarantinedEngines() != 1 {
+		t.Errorf("after quarantine: tracking %d engines, %d quarantined", len(g.recent), g.quarantinedEngines())
+	}
+}
+
+// implausibleEngine reports a score no engine would for every
+// position, as a misread of its output might
+var implausibleEngine = enginetest.Engine{Default: enginetest.Eval{Centipawns: 64000, BestMove: "e2e4"}}
+
+func TestAnalyzeGame_ImplausibleSearchesNotCached(t *testing.T) {
+	a := NewAnalyzer(newEnginePool(t, implausibleEngine), zap.NewNop(), 1, 12, 20, time.Minute)
+	ctx := context.Background()
+
+	analysis, err := a.AnalyzeGame(ctx, "g1", testPGN, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AnalyzePosition(ctx, startFEN, 12, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := a.CacheStats()
+	searches := int64(len(analysis.Moves) + 2) // The final position and startFEN again
+	if stats.Size != 0 || stats.Rejected != searches || stats.Rejections[RejectImplausibleScore] != searches {
+		t.Errorf("stats = %+v, want %d implausible searches rejected and none cached", stats, searches)
+	}
+	if want := searches / quarantineRejections; stats.QuarantinedEngines != want {
+		t.Errorf("%d engines quarantined, want %d", stats.QuarantinedEngines, want)
+	}
+
+	// Quarantined engines are replaced
+	if n := a.pool.Engines(); n != 1 {
+		t.Errorf("pool has %d engines, want 1", n)
+	}
+}
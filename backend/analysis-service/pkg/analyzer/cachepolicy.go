@@ -0,0 +1,141 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+)
+
+// Cache policies requests are counted by
+const (
+	CachePolicyDefault = "default"
+	CachePolicyBypass  = "bypass"
+	CachePolicyOnly    = "cache_only"
+	CachePolicyNoStore = "no_store"
+)
+
+// CacheOptions are a request's use of the position cache. The zero value
+// reads cached evaluations and caches new ones.
+type CacheOptions struct {
+	// Bypass searches every position even when it is cached
+	Bypass bool
+
+	// Only never searches: positions that aren't cached go without an
+	// evaluation. It takes precedence over Bypass.
+	Only bool
+
+	// NoStore leaves the request's searches out of the cache
+	NoStore bool
+
+	// Stale accepts a cached search up to the stale margin shallower than
+	// asked for while it is refreshed, when the analyzer has one (see
+	// SetStaleWhileRevalidate). It is ignored with Only or NoStore.
+	Stale bool
+}
+
+// Policy returns the CachePolicy constant the options are counted under.
+// NoStore only counts when the cache is read: CachePolicyNoStore.
+func (c CacheOptions) Policy() string {
+	switch {
+	case c.Only:
+		return CachePolicyOnly
+	case c.Bypass:
+		return CachePolicyBypass
+	case c.NoStore:
+		return CachePolicyNoStore
+	}
+	return CachePolicyDefault
+}
+
+// reads reports whether cached evaluations may be used
+func (c CacheOptions) reads() bool {
+	return c.Only || !c.Bypass
+}
+
+// cachePolicyCounts counts requests by cache policy. The zero value is
+// ready to use.
+type cachePolicyCounts struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *cachePolicyCounts) add(policy string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	c.counts[policy]++
+}
+
+func (c *cachePolicyCounts) snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[string]int64, len(c.counts))
+	for policy, n := range c.counts {
+		counts[policy] = n
+	}
+	return counts
+}
+
+// CachePolicyCounts returns the position and game requests made under each
+// cache policy
+func (a *Analyzer) CachePolicyCounts() map[string]int64 {
+	return a.cachePolicies.snapshot()
+}
+
+// AnalyzePositionWithCache is AnalyzePosition under a cache policy. With
+// cache.Only it returns ErrNotCached unless the position is cached at the
+// depth with at least multiPV PVs, and never waits for an engine.
+func (a *Analyzer) AnalyzePositionWithCache(ctx context.Context, fen string, depth int, multiPV int, cache CacheOptions) (*engine.AnalysisResult, error) {
+	if err := engine.ValidateFEN(fen); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFEN, err)
+	}
+	a.cachePolicies.add(cache.Policy())
+
+	depth = a.ClampDepth(depth)
+
+	// Mate and stalemate need no engine, and the engine has no move to give
+	if reason := gameOver(fen); reason != "" {
+		return gameOverResult(fen, reason), nil
+	}
+
+	query := CacheQuery{Depth: depth, MultiPV: multiPV}
+	if cache.reads() {
+		if cached, found := a.posCache.getWithin(PrimaryEngine, fen, query, a.staleMargin(cache)); found {
+			result := cached.answer(query)
+			if cached.depth < depth {
+				result.Stale = true
+				a.refreshStale(fen, depth, multiPV)
+			}
+			fillPonderMove(result)
+			a.prefetchPonder(fen, depth, result)
+			return result, nil
+		}
+	}
+	if cache.Only {
+		return nil, fmt.Errorf("%w: %d PV at depth %d", ErrNotCached, multiPV, depth)
+	}
+
+	var result *engine.AnalysisResult
+	var err error
+	if a.cloud != nil && multiPV == 1 && depth <= a.cloudMaxDepth {
+		result, err = a.searchOrCloud(ctx, fen, depth)
+	} else {
+		result, err = a.search(ctx, fen, depth, multiPV)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache complete results
+	if !result.Stopped && len(result.Evaluations) > 0 && !cache.NoStore && a.cacheable(depth, result.Evaluations[0], result.BestMove) {
+		a.posCache.SetResult(PrimaryEngine, fen, depth, multiPV, result)
+	}
+	fillPonderMove(result)
+	a.prefetchPonder(fen, depth, result)
+
+	return result, nil
+}
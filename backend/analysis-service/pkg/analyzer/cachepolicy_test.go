@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzePositionWithCache(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	ctx := context.Background()
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+
+	if _, err := a.AnalyzePositionWithCache(ctx, fen, 12, 1, CacheOptions{Only: true}); !errors.Is(err, ErrNotCached) {
+		t.Fatalf("cache only before any search: err = %v, want ErrNotCached", err)
+	}
+
+	// Not stored, so still not cached
+	if _, err := a.AnalyzePositionWithCache(ctx, fen, 12, 1, CacheOptions{NoStore: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AnalyzePositionWithCache(ctx, fen, 12, 1, CacheOptions{Only: true}); !errors.Is(err, ErrNotCached) {
+		t.Fatalf("cache only after a no-store search: err = %v, want ErrNotCached", err)
+	}
+
+	// A bypassing search doesn't read the cache but still fills it
+	if _, err := a.AnalyzePositionWithCache(ctx, fen, 12, 1, CacheOptions{Bypass: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := a.posCache.Get(PrimaryEngine, fen, CacheQuery{Depth: 12}); !found {
+		t.Fatal("bypassing search wasn't cached")
+	}
+	hits := a.CacheStats().Hits
+	if _, err := a.AnalyzePositionWithCache(ctx, fen, 12, 1, CacheOptions{Bypass: true}); err != nil {
+		t.Fatal(err)
+	}
+	if after := a.CacheStats().Hits; after != hits {
+		t.Errorf("bypassing search read the cache: hits %d -> %d", hits, after)
+	}
+
+	if _, err := a.AnalyzePositionWithCache(ctx, fen, 12, 1, CacheOptions{Only: true}); err != nil {
+		t.Errorf("cache only after caching: %v", err)
+	}
+	if _, err := a.AnalyzePositionWithCache(ctx, fen, 12, 3, CacheOptions{Only: true}); !errors.Is(err, ErrNotCached) {
+		t.Errorf("cache only with multi-PV: err = %v, want ErrNotCached", err)
+	}
+
+	// Multi-PV searches are cached with their PVs
+	searched, err := a.AnalyzePositionWithCache(ctx, fen, 12, 3, CacheOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, err := a.AnalyzePositionWithCache(ctx, fen, 12, 3, CacheOptions{Only: true})
+	if err != nil {
+		t.Fatalf("cache only after a multi-PV search: %v", err)
+	}
+	if cached.PVCount != searched.PVCount || !reflect.DeepEqual(cached.Evaluations, searched.Evaluations) {
+		t.Errorf("cached %d PVs %+v, searched %d %+v", cached.PVCount, cached.Evaluations, searched.PVCount, searched.Evaluations)
+	}
+
+	want := map[string]int64{CachePolicyOnly: 5, CachePolicyNoStore: 1, CachePolicyBypass: 2, CachePolicyDefault: 1}
+	if got := a.CachePolicyCounts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("policy counts = %v, want %v", got, want)
+	}
+}
+
+func TestAnalyzeGame_CacheOptions(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	ctx := context.Background()
+	pgn := "1. e4 e5 2. Nf3 *"
+
+	analysis, err := a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{Cache: CacheOptions{Bypass: true, NoStore: true}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Moves) != 3 || analysis.CacheCoverage != 0 {
+		t.Fatalf("bypassing analysis: %d moves, coverage %v", len(analysis.Moves), analysis.CacheCoverage)
+	}
+
+	// Nothing was stored: a cache-only analysis has no moves
+	analysis, err = a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{Cache: CacheOptions{Only: true}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Moves) != 0 || analysis.CacheCoverage != 0 || analysis.EngineVersion != "" {
+		t.Fatalf("cache-only analysis of an uncached game: %d moves, coverage %v, engine %q", len(analysis.Moves), analysis.CacheCoverage, analysis.EngineVersion)
+	}
+
+	// The first three positions cached, the fourth not
+	if _, err := a.AnalyzeGame(ctx, "g1", "1. e4 e5 *", 12, GameOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	analysis, err = a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{Cache: CacheOptions{Only: true}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Moves) != 2 || analysis.CacheCoverage != 75 || analysis.TotalMoves != 3 {
+		t.Errorf("cache-only analysis: %d of %d moves, coverage %v; want 2 of 3, 75", len(analysis.Moves), analysis.TotalMoves, analysis.CacheCoverage)
+	}
+}
@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+// ChecksumVersion is the version of the field list GameAnalysis.Checksum
+// covers. It is raised when a covered field is added, removed or changes
+// format, never for fields outside the list, so adding an optional field
+// doesn't invalidate stored checksums.
+const ChecksumVersion = 1
+
+// checksumHeader starts the canonical form, so it can't collide with a
+// payload of another kind
+const checksumHeader = "eloinsight-analysis-checksum"
+
+// Checksum returns the checksum of g, "v1:" and the hex SHA-256 of its
+// canonical form. Version 1 covers, one line each, ending in "\n":
+//
+//	eloinsight-analysis-checksum v1
+//	game_id=<game ID>
+//	total_moves=<plies in the game>
+//	white=<accuracy>,<acpl>,<blunders>,<mistakes>,<inaccuracies>,<total moves>
+//	black=<the same for black>
+//	move=<ply>,<played move UCI>,<best move UCI>,<centipawn loss>,<classification>
+//
+// with a move line per analyzed move, in order. Accuracy and ACPL are
+// rounded to one decimal as in the proto and written with one decimal,
+// e.g. "91.5" or "0.0"; classifications are lowercase, e.g. "blunder".
+// Nothing else, like evaluations, timings or clocks, is covered.
+func Checksum(g *GameAnalysis) string {
+	sum := sha256.Sum256([]byte(canonicalForm(g)))
+	return fmt.Sprintf("v%d:%s", ChecksumVersion, hex.EncodeToString(sum[:]))
+}
+
+// VerifyGameAnalysis checks that g's Checksum matches its content, to
+// catch payloads truncated or altered between the analyzer and storage
+func VerifyGameAnalysis(g *GameAnalysis) error {
+	if g.Checksum == "" {
+		return fmt.Errorf("%w: analysis has no checksum", ErrChecksumMismatch)
+	}
+	version, _, _ := strings.Cut(g.Checksum, ":")
+	if version != fmt.Sprintf("v%d", ChecksumVersion) {
+		return fmt.Errorf("%w: unknown checksum version %q", ErrChecksumMismatch, version)
+	}
+	if want := Checksum(g); g.Checksum != want {
+		return fmt.Errorf("%w: analysis of game %s has %d moves and checksum %s, content gives %s",
+			ErrChecksumMismatch, g.GameID, len(g.Moves), g.Checksum, want)
+	}
+	return nil
+}
+
+// canonicalForm returns the version 1 text Checksum hashes
+func canonicalForm(g *GameAnalysis) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s v%d\n", checksumHeader, ChecksumVersion)
+	fmt.Fprintf(&b, "game_id=%s\n", g.GameID)
+	fmt.Fprintf(&b, "total_moves=%d\n", g.TotalMoves)
+	for _, side := range []struct {
+		name    string
+		metrics *GameMetrics
+	}{
+		{"white", &g.WhiteMetrics},
+		{"black", &g.BlackMetrics},
+	} {
+		m := side.metrics
+		fmt.Fprintf(&b, "%s=%.1f,%.1f,%d,%d,%d,%d\n", side.name,
+			evaluation.RoundAccuracy(m.Accuracy), evaluation.RoundACPL(m.ACPL),
+			m.Blunders, m.Mistakes, m.Inaccuracies, m.TotalMoves)
+	}
+	for i := range g.Moves {
+		m := &g.Moves[i]
+		fmt.Fprintf(&b, "move=%d,%s,%s,%d,%s\n", m.Ply, m.PlayedMoveUCI, m.BestMoveUCI, m.CentipawnLoss, m.Classification)
+	}
+	return b.String()
+}
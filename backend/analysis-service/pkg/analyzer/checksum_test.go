@@ -0,0 +1,120 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+)
+
+func checksumAnalysis() *GameAnalysis {
+	return &GameAnalysis{
+		GameID:     "g1",
+		TotalMoves: 3,
+		Moves: []MoveAnalysis{
+			{Ply: 0, PlayedMoveUCI: "e2e4", BestMoveUCI: "e2e4", Classification: ClassBest},
+			{Ply: 1, PlayedMoveUCI: "f7f6", BestMoveUCI: "e7e5", CentipawnLoss: 30, Classification: ClassExcellent},
+			{Ply: 2, PlayedMoveUCI: "d1h5", BestMoveUCI: "d2d4", CentipawnLoss: 85, Classification: ClassMistake},
+		},
+		WhiteMetrics: GameMetrics{Accuracy: 91.46, ACPL: 42.5, Mistakes: 1, TotalMoves: 2},
+		BlackMetrics: GameMetrics{Accuracy: 100, TotalMoves: 1},
+	}
+}
+
+func TestChecksum_CanonicalForm(t *testing.T) {
+	want := "eloinsight-analysis-checksum v1\n" +
+		"game_id=g1\n" +
+		"total_moves=3\n" +
+		"white=91.5,42.5,0,1,0,2\n" +
+		"black=100.0,0.0,0,0,0,1\n" +
+		"move=0,e2e4,e2e4,0,best\n" +
+		"move=1,f7f6,e7e5,30,excellent\n" +
+		"move=2,d1h5,d2d4,85,mistake\n"
+	if got := canonicalForm(checksumAnalysis()); got != want {
+		t.Errorf("canonical form =\n%s\nwant\n%s", got, want)
+	}
+	// Pinned: a change here invalidates every stored version 1 checksum
+	want = "v1:bf1c99dc2242f0f83b6986a7f18e817f936f2796864409658155796e349f2ecf"
+	if got := Checksum(checksumAnalysis()); got != want {
+		t.Errorf("checksum = %s, want %s", got, want)
+	}
+}
+
+func TestChecksum_CoveredFields(t *testing.T) {
+	base := Checksum(checksumAnalysis())
+
+	uncovered := map[string]func(g *GameAnalysis){
+		"evaluation":        func(g *GameAnalysis) { g.Moves[0].EvalBefore = engine.Evaluation{Centipawns: 40} },
+		"timing":            func(g *GameAnalysis) { g.TotalTimeMs = 5000; g.Moves[1].EngineTimeMs = 900 },
+		"fast mode":         func(g *GameAnalysis) { g.FastMode = true },
+		"accuracy rounding": func(g *GameAnalysis) { g.WhiteMetrics.Accuracy = 91.5 },
+	}
+	for name, change := range uncovered {
+		g := checksumAnalysis()
+		change(g)
+		if got := Checksum(g); got != base {
+			t.Errorf("%s changed the checksum", name)
+		}
+	}
+
+	covered := map[string]func(g *GameAnalysis){
+		"truncated moves": func(g *GameAnalysis) { g.Moves = g.Moves[:2] },
+		"classification":  func(g *GameAnalysis) { g.Moves[2].Classification = ClassBlunder },
+		"best move":       func(g *GameAnalysis) { g.Moves[1].BestMoveUCI = "d7d5" },
+		"accuracy":        func(g *GameAnalysis) { g.WhiteMetrics.Accuracy = 91.3 },
+		"blunders":        func(g *GameAnalysis) { g.BlackMetrics.Blunders = 1 },
+		"game":            func(g *GameAnalysis) { g.GameID = "g2" },
+	}
+	for name, change := range covered {
+		g := checksumAnalysis()
+		change(g)
+		if got := Checksum(g); got == base {
+			t.Errorf("%s left the checksum unchanged", name)
+		}
+	}
+}
+
+func TestVerifyGameAnalysis(t *testing.T) {
+	g := checksumAnalysis()
+	g.Checksum = Checksum(g)
+	if err := VerifyGameAnalysis(g); err != nil {
+		t.Errorf("intact analysis: %v", err)
+	}
+
+	truncated := *g
+	truncated.Moves = g.Moves[:1]
+	unknown := *g
+	unknown.Checksum = "v9" + g.Checksum[2:]
+	missing := *g
+	missing.Checksum = ""
+	for name, bad := range map[string]*GameAnalysis{"truncated": &truncated, "unknown version": &unknown, "missing": &missing} {
+		if err := VerifyGameAnalysis(bad); !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("%s: err = %v, want ErrChecksumMismatch", name, err)
+		}
+	}
+}
+
+func TestAnalyzeGame_Checksum(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", "1. e4 e5 2. Nf3 Nc6 *", 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyGameAnalysis(analysis); err != nil {
+		t.Fatal(err)
+	}
+
+	// The JSON form carries it through storage
+	data, err := analysis.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored GameAnalysis
+	if err := stored.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyGameAnalysis(&stored); err != nil {
+		t.Errorf("after a JSON round trip: %v", err)
+	}
+}
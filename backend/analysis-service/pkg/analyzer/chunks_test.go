@@ -0,0 +1,200 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"go.uber.org/zap"
+)
+
+// newLoggingAnalyzer returns an analyzer with a pool of size fake engines
+// and the log of their commands
+func newLoggingAnalyzer(t *testing.T, size, multiPV int) (*Analyzer, string) {
+	t.Helper()
+	fake := fakeEngine
+	fake.Log = filepath.Join(t.TempDir(), "uci")
+	p, err := pool.NewPool(size, engine.Config{BinaryPath: fake.Binary(t), Threads: 1, Hash: 16, MultiPV: multiPV}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return NewAnalyzer(p, zap.NewNop(), 1, 20, 30, time.Minute), fake.Log
+}
+
+// loggedCommands returns the commands each engine logged that start with
+// prefix
+func loggedCommands(t *testing.T, log, prefix string) [][]string {
+	t.Helper()
+	var engines [][]string
+	for _, logged := range enginetest.Commands(t, log) {
+		var commands []string
+		for _, command := range logged {
+			if strings.HasPrefix(command, prefix) {
+				commands = append(commands, command)
+			}
+		}
+		engines = append(engines, commands)
+	}
+	return engines
+}
+
+func TestAnalyzeGame_SendsMovesFromStart(t *testing.T) {
+	a, log := newLoggingAnalyzer(t, 1, 3)
+
+	if _, err := a.AnalyzeGame(context.Background(), "g1", "1. e4 e5 2. Nf3 *", 12, GameOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	start := "position fen rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	want := [][]string{{start, start + " moves e2e4", start + " moves e2e4 e7e5", start + " moves e2e4 e7e5 g1f3"}}
+	if got := loggedCommands(t, log, "position "); !reflect.DeepEqual(got, want) {
+		t.Errorf("positions sent:\n%v\nwant:\n%v", got, want)
+	}
+	// Set to 3 at startup, then to 1 once for the whole game
+	if got := loggedCommands(t, log, "setoption name MultiPV"); len(got) != 1 || len(got[0]) != 2 {
+		t.Errorf("MultiPV set %v, want twice", got)
+	}
+}
+
+func TestAnalyzeGame_ChunksPerEngine(t *testing.T) {
+	a, log := newLoggingAnalyzer(t, 2, 1)
+
+	if _, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, GameOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each engine searches runs of consecutive positions, starting at
+	// chunk boundaries
+	seen := make(map[int]bool)
+	for _, commands := range loggedCommands(t, log, "position ") {
+		prev := -1
+		for _, command := range commands {
+			ply := 0
+			if _, moves, ok := strings.Cut(command, " moves "); ok {
+				ply = len(strings.Fields(moves))
+			}
+			if seen[ply] {
+				t.Errorf("position %d searched twice", ply)
+			}
+			seen[ply] = true
+			if ply != prev+1 && ply%gameChunkSize != 0 {
+				t.Errorf("engine jumped from position %d to %d, inside a chunk", prev, ply)
+			}
+			prev = ply
+		}
+	}
+	if len(seen) != 15 {
+		t.Errorf("%d positions searched, want 15", len(seen))
+	}
+}
+
+func TestAnalyzeGame_ChunkProgress(t *testing.T) {
+	a := newFakeAnalyzer(t)
+
+	var events []string
+	opts := GameOptions{OnChunk: func(completed, total int) {
+		events = append(events, fmt.Sprintf("chunk %d/%d", completed, total))
+	}}
+	_, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, opts, func(current, total int, move *MoveAnalysis) {
+		if move == nil {
+			events = append(events, fmt.Sprintf("position %d", current))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One engine: 15 positions in chunks of 8 and 7, the progress capped
+	// at the 14 moves
+	var want []string
+	for i := 1; i <= 15; i++ {
+		want = append(want, fmt.Sprintf("position %d", min(i, 14)))
+		if i == 8 {
+			want = append(want, "chunk 1/2")
+		}
+	}
+	want = append(want, "chunk 2/2")
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("callbacks:\n%v\nwant:\n%v", events, want)
+	}
+}
+
+// BenchmarkGamePositions searches every position of a 60-move game on one
+// Stockfish, set by STOCKFISH_PATH: in order as moves from the start, as
+// game analysis workers do, and as lone FENs in strides of four, the way
+// each of four workers used to take them from a shared queue
+func BenchmarkGamePositions(b *testing.B) {
+	binary := os.Getenv("STOCKFISH_PATH")
+	if binary == "" {
+		b.Skip("STOCKFISH_PATH not set")
+	}
+	pgn, err := os.ReadFile("testdata/sixty_moves.pgn")
+	if err != nil {
+		b.Fatal(err)
+	}
+	positions, err := ParsePGN(string(pgn))
+	if err != nil {
+		b.Fatal(err)
+	}
+	moves := make([]string, len(positions))
+	for i := 1; i < len(positions); i++ {
+		moves[i-1] = positions[i].MoveUCI
+	}
+
+	const depth = 14
+	run := func(b *testing.B, search func(eng *engine.Engine, i int) (*engine.AnalysisResult, error), order []int) {
+		eng, err := engine.NewEngine(engine.Config{BinaryPath: binary, Threads: 1, Hash: 64, MultiPV: 1}, zap.NewNop())
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer eng.Close()
+
+		var nodes int64
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			if err := eng.Reset(); err != nil {
+				b.Fatal(err)
+			}
+			for _, i := range order {
+				result, err := search(eng, i)
+				if err != nil {
+					b.Fatal(err)
+				}
+				nodes += result.Evaluations[0].Nodes
+			}
+		}
+		b.ReportMetric(float64(nodes)/float64(b.N*len(order)), "nodes/position")
+	}
+
+	inOrder := make([]int, len(positions))
+	for i := range inOrder {
+		inOrder[i] = i
+	}
+	b.Run("moves", func(b *testing.B) {
+		run(b, func(eng *engine.Engine, i int) (*engine.AnalysisResult, error) {
+			pos := engine.GamePosition{StartFEN: positions[0].FEN, Moves: moves[:i], FEN: positions[i].FEN}
+			return eng.AnalyzeGamePositionContext(context.Background(), pos, depth, 1)
+		}, inOrder)
+	})
+
+	var strided []int
+	for worker := 0; worker < 4; worker++ {
+		for i := worker; i < len(positions); i += 4 {
+			strided = append(strided, i)
+		}
+	}
+	b.Run("strided-fens", func(b *testing.B) {
+		run(b, func(eng *engine.Engine, i int) (*engine.AnalysisResult, error) {
+			return eng.AnalyzePositionContext(context.Background(), positions[i].FEN, depth, 1)
+		}, strided)
+	})
+}
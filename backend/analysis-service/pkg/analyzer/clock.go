@@ -0,0 +1,199 @@
+package analyzer
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time classes, by estimated game duration as Lichess does
+const (
+	TimeClassBullet    = "bullet"
+	TimeClassBlitz     = "blitz"
+	TimeClassRapid     = "rapid"
+	TimeClassClassical = "classical"
+)
+
+// scrambleIncrementMoves is how many moves of increment count towards a
+// player's effective time: 20 seconds left at +2 is no scramble
+const scrambleIncrementMoves = 10
+
+// DefaultScrambleThresholds returns the effective time left, by time class,
+// below which a player is in a time scramble
+func DefaultScrambleThresholds() map[string]time.Duration {
+	return map[string]time.Duration{
+		TimeClassBullet:    10 * time.Second,
+		TimeClassBlitz:     30 * time.Second,
+		TimeClassRapid:     60 * time.Second,
+		TimeClassClassical: 5 * time.Minute,
+	}
+}
+
+// TimeControl is the base time and increment of a game
+type TimeControl struct {
+	Base      time.Duration
+	Increment time.Duration
+}
+
+// ParseTimeControl reads a PGN TimeControl tag: "180+2", "600", or
+// "40/7200:3600" of which the first period is used. Unknown ("-", "?")
+// and sandclock controls are not ok.
+func ParseTimeControl(tag string) (TimeControl, bool) {
+	period, _, _ := strings.Cut(strings.TrimSpace(tag), ":")
+	if _, perMoves, ok := strings.Cut(period, "/"); ok {
+		period = perMoves
+	}
+	baseField, incField, hasInc := strings.Cut(period, "+")
+
+	base, err := strconv.Atoi(baseField)
+	if err != nil || base <= 0 {
+		return TimeControl{}, false
+	}
+	tc := TimeControl{Base: time.Duration(base) * time.Second}
+	if hasInc {
+		inc, err := strconv.Atoi(incField)
+		if err != nil || inc < 0 {
+			return TimeControl{}, false
+		}
+		tc.Increment = time.Duration(inc) * time.Second
+	}
+	return tc, true
+}
+
+// Class returns the time class of the estimated game duration, the base
+// time plus 40 increments
+func (tc TimeControl) Class() string {
+	switch estimate := tc.Base + 40*tc.Increment; {
+	case estimate < 3*time.Minute:
+		return TimeClassBullet
+	case estimate < 8*time.Minute:
+		return TimeClassBlitz
+	case estimate < 25*time.Minute:
+		return TimeClassRapid
+	default:
+		return TimeClassClassical
+	}
+}
+
+// TimeManagement relates a player's clock to their errors. Moves are timed
+// from the [%clk] comments; moves without one are left out.
+type TimeManagement struct {
+	TimeClass           string
+	ScrambleThresholdMs int64
+
+	// Moves made with less effective time than the threshold, the clock
+	// plus 10 increments, are in the time scramble
+	ScrambleStartPly int // First of them, -1 if none
+	ScrambleMoves    int
+
+	// Shares of the moves in and outside the scramble that were
+	// inaccuracies, mistakes, blunders or missed wins
+	ScrambleErrorRate float64
+	OtherErrorRate    float64
+
+	ThinkingTimeMs  int64   // Spent on the timed moves
+	CPLossPerSecond float64 // Centipawn loss of the timed moves per second of it
+
+	ScrambleBlunders []int // Plies of the blunders made in the scramble
+}
+
+// SetScrambleThresholds sets the effective time left, by time class, below
+// which a player is in a time scramble. Missing classes use the defaults.
+func (a *Analyzer) SetScrambleThresholds(thresholds map[string]time.Duration) {
+	merged := DefaultScrambleThresholds()
+	for class, threshold := range thresholds {
+		merged[class] = threshold
+	}
+	a.scrambleThresholds = merged
+}
+
+// timeManagement returns color's time management over the analyzed moves,
+// or nil when the game has no clock readings. The time control comes from
+// the TimeControl tag; without one the first clock reading is taken as the
+// base time and there is no increment.
+func (a *Analyzer) timeManagement(moves []MoveAnalysis, positions []Position, timeControl, color string) *TimeManagement {
+	tc, ok := ParseTimeControl(timeControl)
+	if !ok {
+		for _, pos := range positions {
+			if pos.HasClock {
+				tc, ok = TimeControl{Base: pos.Clock}, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil
+	}
+
+	class := tc.Class()
+	threshold := a.scrambleThresholds[class]
+	if threshold == 0 {
+		threshold = DefaultScrambleThresholds()[class]
+	}
+	tm := &TimeManagement{
+		TimeClass:           class,
+		ScrambleThresholdMs: threshold.Milliseconds(),
+		ScrambleStartPly:    -1,
+	}
+
+	var timed, scrambleErrors, other, otherErrors, cpLoss int
+	var thinking time.Duration
+	for _, move := range moves {
+		if move.Color != color || move.Ply+1 >= len(positions) || !positions[move.Ply+1].HasClock {
+			continue
+		}
+
+		// Time left before the move: after the player's previous one, or
+		// the base time on their first
+		before := tc.Base
+		if move.Ply >= 2 {
+			prev := positions[move.Ply-1]
+			if !prev.HasClock {
+				continue
+			}
+			before = prev.Clock
+		}
+		after := positions[move.Ply+1].Clock
+		timed++
+		thinking += max(before+tc.Increment-after, 0)
+		cpLoss += move.CentipawnLoss
+
+		isError := false
+		switch move.Classification {
+		case ClassInaccuracy, ClassMistake, ClassBlunder, ClassMissedWin:
+			isError = true
+		}
+		if before+scrambleIncrementMoves*tc.Increment < threshold {
+			if tm.ScrambleStartPly < 0 {
+				tm.ScrambleStartPly = move.Ply
+			}
+			tm.ScrambleMoves++
+			if isError {
+				scrambleErrors++
+			}
+			if move.Classification == ClassBlunder {
+				tm.ScrambleBlunders = append(tm.ScrambleBlunders, move.Ply)
+			}
+		} else {
+			other++
+			if isError {
+				otherErrors++
+			}
+		}
+	}
+	if timed == 0 {
+		return nil
+	}
+
+	if tm.ScrambleMoves > 0 {
+		tm.ScrambleErrorRate = float64(scrambleErrors) / float64(tm.ScrambleMoves)
+	}
+	if other > 0 {
+		tm.OtherErrorRate = float64(otherErrors) / float64(other)
+	}
+	tm.ThinkingTimeMs = thinking.Milliseconds()
+	if thinking > 0 {
+		tm.CPLossPerSecond = float64(cpLoss) / thinking.Seconds()
+	}
+	return tm
+}
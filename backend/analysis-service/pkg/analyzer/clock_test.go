@@ -0,0 +1,146 @@
+package analyzer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		tag   string
+		want  TimeControl
+		ok    bool
+		class string
+	}{
+		{"180+2", TimeControl{180 * time.Second, 2 * time.Second}, true, TimeClassBlitz},
+		{"60", TimeControl{Base: time.Minute}, true, TimeClassBullet},
+		{"120+1", TimeControl{120 * time.Second, time.Second}, true, TimeClassBullet},
+		{"600+5", TimeControl{10 * time.Minute, 5 * time.Second}, true, TimeClassRapid},
+		{"40/7200:3600", TimeControl{Base: 2 * time.Hour}, true, TimeClassClassical},
+		{"-", TimeControl{}, false, ""},
+		{"?", TimeControl{}, false, ""},
+		{"*60", TimeControl{}, false, ""},
+		{"180+x", TimeControl{}, false, ""},
+	}
+	for _, tt := range tests {
+		got, ok := ParseTimeControl(tt.tag)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseTimeControl(%q) = %+v, %v; want %+v, %v", tt.tag, got, ok, tt.want, tt.ok)
+		}
+		if ok && got.Class() != tt.class {
+			t.Errorf("%q: class %s, want %s", tt.tag, got.Class(), tt.class)
+		}
+	}
+}
+
+func TestParsePGN_Clocks(t *testing.T) {
+	positions, err := ParsePGN(`1. e4 { [%eval 0.3] [%clk 0:03:00] } 1... e5 {[%clk 0:02:58.5]} 2. Nf3 (2. f4 {[%clk 0:00:01]}) *`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		clock    time.Duration
+		hasClock bool
+	}{{0, false}, {3 * time.Minute, true}, {178500 * time.Millisecond, true}, {0, false}}
+	for i, pos := range positions {
+		if pos.Clock != want[i].clock || pos.HasClock != want[i].hasClock {
+			t.Errorf("position %d: clock %v (%v), want %v (%v)", i, pos.Clock, pos.HasClock, want[i].clock, want[i].hasClock)
+		}
+	}
+}
+
+// clockedGame returns the moves of a game and positions with the clock
+// after each move
+func clockedGame(clocks []time.Duration, classes []MoveClassification) ([]MoveAnalysis, []Position) {
+	positions := []Position{{}}
+	var moves []MoveAnalysis
+	for ply, clock := range clocks {
+		positions = append(positions, Position{Clock: clock, HasClock: true})
+		color := "white"
+		if ply%2 == 1 {
+			color = "black"
+		}
+		moves = append(moves, MoveAnalysis{Ply: ply, Color: color, Classification: classes[ply], CentipawnLoss: 10})
+	}
+	return moves, positions
+}
+
+func TestTimeManagement(t *testing.T) {
+	a := NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	s := time.Second
+
+	// White spends 50s and 80s, blunders with 50s left and makes the last
+	// move with 25s left, in a 3 minute game
+	moves, positions := clockedGame(
+		[]time.Duration{130 * s, 175 * s, 50 * s, 170 * s, 25 * s, 168 * s, 20 * s, 160 * s},
+		[]MoveClassification{ClassGood, ClassGood, ClassInaccuracy, ClassGood, ClassBlunder, ClassGood, ClassGood, ClassMistake},
+	)
+
+	white := a.timeManagement(moves, positions, "180", "white")
+	want := &TimeManagement{
+		TimeClass:           TimeClassBlitz,
+		ScrambleThresholdMs: 30000,
+		ScrambleStartPly:    6,
+		ScrambleMoves:       1,
+		ScrambleErrorRate:   0,
+		OtherErrorRate:      2.0 / 3,
+		ThinkingTimeMs:      160000,
+		CPLossPerSecond:     40.0 / 160,
+	}
+	// The blunder at ply 4 was made with 50s left, before the scramble
+	if !reflect.DeepEqual(white, want) {
+		t.Errorf("white = %+v\nwant %+v", white, want)
+	}
+
+	// With +2 the same clocks are 20s more effective time
+	if inc := a.timeManagement(moves, positions, "180+2", "white"); inc.ScrambleMoves != 0 || inc.ThinkingTimeMs != 168000 {
+		t.Errorf("with increment = %+v, want no scramble and 168s thinking", inc)
+	}
+
+	// A higher threshold takes the blunder into the scramble
+	a.SetScrambleThresholds(map[string]time.Duration{TimeClassBlitz: 60 * s})
+	if tm := a.timeManagement(moves, positions, "180", "white"); tm.ScrambleStartPly != 4 || !reflect.DeepEqual(tm.ScrambleBlunders, []int{4}) || tm.ScrambleErrorRate != 0.5 {
+		t.Errorf("60s threshold = %+v", tm)
+	}
+
+	// Without a TimeControl tag the first clock is the base
+	if tm := a.timeManagement(moves, positions, "", "black"); tm == nil || tm.TimeClass != TimeClassBullet || tm.ThinkingTimeMs != 15000 {
+		t.Errorf("black without time control = %+v", tm)
+	}
+
+	// No clocks, no time management
+	for i := range positions {
+		positions[i].HasClock = false
+	}
+	if tm := a.timeManagement(moves, positions, "180", "white"); tm != nil {
+		t.Errorf("without clocks = %+v, want nil", tm)
+	}
+}
+
+func TestAnalyzeGame_TimeManagement(t *testing.T) {
+	a := newFakeAnalyzer(t)
+
+	clocked := "[TimeControl \"60+0\"]\n\n1. e4 {[%clk 0:00:59]} e5 {[%clk 0:00:58]} 2. Nf3 {[%clk 0:00:05]} Nc6 {[%clk 0:00:50]} *"
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", clocked, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.WhiteTime == nil || analysis.WhiteTime.TimeClass != TimeClassBullet || analysis.WhiteTime.ThinkingTimeMs != 55000 {
+		t.Errorf("white time = %+v", analysis.WhiteTime)
+	}
+	if analysis.BlackTime == nil || analysis.BlackTime.ScrambleStartPly != -1 {
+		t.Errorf("black time = %+v", analysis.BlackTime)
+	}
+
+	analysis, err = a.AnalyzeGame(context.Background(), "g1", "1. e4 e5 2. Nf3 Nc6 *", 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.WhiteTime != nil || analysis.BlackTime != nil {
+		t.Errorf("without clocks: %+v and %+v, want nil", analysis.WhiteTime, analysis.BlackTime)
+	}
+}
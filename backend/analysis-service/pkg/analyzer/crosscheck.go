@@ -0,0 +1,197 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// CrossCheckCPLossThreshold is the centipawn-loss difference above which
+// two analyses of a move disagree even with the same classification
+const CrossCheckCPLossThreshold = 50
+
+// CrossCheck is one game analyzed by two engine profiles
+type CrossCheck struct {
+	Primary   *GameAnalysis
+	Secondary *GameAnalysis
+	Diff      AnalysisDiff
+}
+
+// AnalysisDiff lists where two analyses of the same game disagree
+type AnalysisDiff struct {
+	EngineA string // Engine profiles of the analyses compared
+	EngineB string
+
+	DepthA int // Depths of the analyses compared
+	DepthB int
+
+	ComparedMoves       int        // Moves present in both analyses
+	Moves               []MoveDiff // Disagreements, in game order
+	ClassificationDiffs int        // Moves classified differently
+	CentipawnLossDiffs  int        // Moves whose loss differs by more than the threshold
+	BestMoveDiffs       int        // Moves with a different best move (DiffAnalyses only)
+
+	// Accuracy of analysis B minus that of analysis A
+	WhiteAccuracyDelta float64
+	BlackAccuracyDelta float64
+
+	// Metrics of analysis B minus those of analysis A
+	White MetricsDelta
+	Black MetricsDelta
+}
+
+// MetricsDelta is the change in a player's metrics from one analysis to
+// another
+type MetricsDelta struct {
+	Accuracy          float64
+	ACPL              float64
+	Blunders          int
+	Mistakes          int
+	Inaccuracies      int
+	PerformanceRating int
+}
+
+// MoveDiff is a move two analyses disagree on
+type MoveDiff struct {
+	Ply        int
+	MoveNumber int
+	Color      string
+	PlayedMove string // SAN
+
+	ClassificationA MoveClassification
+	ClassificationB MoveClassification
+	CentipawnLossA  int
+	CentipawnLossB  int
+	BestMoveA       string // SAN
+	BestMoveB       string
+}
+
+// CompareAnalyses compares two analyses of the same game move by move.
+// Moves only one of them analyzed (after a timeout) are skipped. A move
+// is listed when its classification differs or its centipawn loss differs
+// by more than CrossCheckCPLossThreshold.
+func CompareAnalyses(a, b *GameAnalysis) AnalysisDiff {
+	return compareAnalyses(a, b, CrossCheckCPLossThreshold, false)
+}
+
+// compareAnalyses lists the moves whose classification differs, whose
+// centipawn loss differs by more than cpLossThreshold or, with bestMoves,
+// whose best move differs
+func compareAnalyses(a, b *GameAnalysis, cpLossThreshold int, bestMoves bool) AnalysisDiff {
+	diff := AnalysisDiff{
+		EngineA:            a.EngineProfile,
+		EngineB:            b.EngineProfile,
+		DepthA:             a.Depth,
+		DepthB:             b.Depth,
+		WhiteAccuracyDelta: b.WhiteMetrics.Accuracy - a.WhiteMetrics.Accuracy,
+		BlackAccuracyDelta: b.BlackMetrics.Accuracy - a.BlackMetrics.Accuracy,
+		White:              metricsDelta(a.WhiteMetrics, b.WhiteMetrics),
+		Black:              metricsDelta(a.BlackMetrics, b.BlackMetrics),
+	}
+
+	byPly := make(map[int]*MoveAnalysis, len(b.Moves))
+	for i := range b.Moves {
+		byPly[b.Moves[i].Ply] = &b.Moves[i]
+	}
+
+	for i := range a.Moves {
+		moveA := &a.Moves[i]
+		moveB, ok := byPly[moveA.Ply]
+		if !ok {
+			continue
+		}
+		diff.ComparedMoves++
+
+		classDiffers := moveA.Classification != moveB.Classification
+		lossDiffers := abs(moveA.CentipawnLoss-moveB.CentipawnLoss) > cpLossThreshold
+		bestDiffers := bestMoves && NormalizeUCI(moveA.BestMoveUCI) != NormalizeUCI(moveB.BestMoveUCI)
+		if classDiffers {
+			diff.ClassificationDiffs++
+		}
+		if lossDiffers {
+			diff.CentipawnLossDiffs++
+		}
+		if bestDiffers {
+			diff.BestMoveDiffs++
+		}
+		if !classDiffers && !lossDiffers && !bestDiffers {
+			continue
+		}
+
+		diff.Moves = append(diff.Moves, MoveDiff{
+			Ply:             moveA.Ply,
+			MoveNumber:      moveA.MoveNumber,
+			Color:           moveA.Color,
+			PlayedMove:      moveA.PlayedMove,
+			ClassificationA: moveA.Classification,
+			ClassificationB: moveB.Classification,
+			CentipawnLossA:  moveA.CentipawnLoss,
+			CentipawnLossB:  moveB.CentipawnLoss,
+			BestMoveA:       moveA.BestMove,
+			BestMoveB:       moveB.BestMove,
+		})
+	}
+
+	return diff
+}
+
+func metricsDelta(a, b GameMetrics) MetricsDelta {
+	return MetricsDelta{
+		Accuracy:          b.Accuracy - a.Accuracy,
+		ACPL:              b.ACPL - a.ACPL,
+		Blunders:          b.Blunders - a.Blunders,
+		Mistakes:          b.Mistakes - a.Mistakes,
+		Inaccuracies:      b.Inaccuracies - a.Inaccuracies,
+		PerformanceRating: b.PerformanceRating - a.PerformanceRating,
+	}
+}
+
+// AnalyzeGameWithEngines analyzes a game with the engine of opts and with
+// the secondary engine profile at the same time, and compares the two.
+// Each analysis has its own budget and cache entries.
+func (a *Analyzer) AnalyzeGameWithEngines(ctx context.Context, gameID string, pgn string, depth int, opts GameOptions, secondary string) (*CrossCheck, error) {
+	primary, _, _, err := a.engineProfile(opts.EngineProfile, depth)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, _, err := a.engineProfile(secondary, depth); err != nil {
+		return nil, err
+	}
+	if secondary == "" || secondary == primary {
+		return nil, fmt.Errorf("%w: cross-check needs a second engine profile, got %q", ErrUnknownEngineProfile, secondary)
+	}
+
+	secondaryOpts := opts
+	secondaryOpts.EngineProfile = secondary
+	secondaryOpts.OnMetrics = nil // Progress is reported for the primary only
+	secondaryOpts.OnEstimate = nil
+	secondaryOpts.OnEngineOutput = nil
+
+	var check CrossCheck
+	var secondaryErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		check.Secondary, secondaryErr = a.AnalyzeGame(ctx, gameID, pgn, depth, secondaryOpts, nil)
+	}()
+	check.Primary, err = a.AnalyzeGame(ctx, gameID, pgn, depth, opts, nil)
+	wg.Wait()
+
+	if err != nil {
+		return nil, err
+	}
+	if secondaryErr != nil {
+		return nil, fmt.Errorf("engine profile %q: %w", secondary, secondaryErr)
+	}
+
+	check.Diff = CompareAnalyses(check.Primary, check.Secondary)
+	return &check, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
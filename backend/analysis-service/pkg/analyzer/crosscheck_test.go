@@ -0,0 +1,96 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCompareAnalyses(t *testing.T) {
+	a := &GameAnalysis{
+		EngineProfile: PrimaryEngine,
+		WhiteMetrics:  GameMetrics{Accuracy: 90},
+		BlackMetrics:  GameMetrics{Accuracy: 70},
+		Moves: []MoveAnalysis{
+			{Ply: 0, Color: "white", PlayedMove: "e4", Classification: ClassBest, CentipawnLoss: 0},
+			{Ply: 1, Color: "black", PlayedMove: "e5", Classification: ClassGood, CentipawnLoss: 40},
+			{Ply: 2, Color: "white", PlayedMove: "Qh5", Classification: ClassMistake, CentipawnLoss: 150},
+			{Ply: 3, Color: "black", PlayedMove: "Nc6", Classification: ClassBest, CentipawnLoss: 0},
+			{Ply: 4, Color: "white", PlayedMove: "Bc4", Classification: ClassBest, CentipawnLoss: 0},
+		},
+	}
+	b := &GameAnalysis{
+		EngineProfile: "deep",
+		WhiteMetrics:  GameMetrics{Accuracy: 85},
+		BlackMetrics:  GameMetrics{Accuracy: 75.5},
+		Moves: []MoveAnalysis{
+			{Ply: 0, Color: "white", Classification: ClassBest, CentipawnLoss: 5},
+			{Ply: 1, Color: "black", Classification: ClassInaccuracy, CentipawnLoss: 60}, // Class differs
+			{Ply: 2, Color: "white", Classification: ClassMistake, CentipawnLoss: 260},   // Loss differs
+			{Ply: 3, Color: "black", Classification: ClassBest, CentipawnLoss: 50},       // At the threshold
+			// Ply 4 not analyzed
+		},
+	}
+
+	diff := CompareAnalyses(a, b)
+
+	if diff.EngineA != PrimaryEngine || diff.EngineB != "deep" || diff.ComparedMoves != 4 {
+		t.Errorf("engines = %q/%q, compared = %d", diff.EngineA, diff.EngineB, diff.ComparedMoves)
+	}
+	if diff.ClassificationDiffs != 1 || diff.CentipawnLossDiffs != 1 || len(diff.Moves) != 2 {
+		t.Fatalf("diffs = %d/%d, moves = %+v", diff.ClassificationDiffs, diff.CentipawnLossDiffs, diff.Moves)
+	}
+	if m := diff.Moves[0]; m.Ply != 1 || m.PlayedMove != "e5" || m.ClassificationA != ClassGood || m.ClassificationB != ClassInaccuracy {
+		t.Errorf("first diff = %+v", m)
+	}
+	if m := diff.Moves[1]; m.Ply != 2 || m.CentipawnLossA != 150 || m.CentipawnLossB != 260 {
+		t.Errorf("second diff = %+v", m)
+	}
+	if diff.WhiteAccuracyDelta != -5 || diff.BlackAccuracyDelta != 5.5 {
+		t.Errorf("accuracy deltas = %v/%v, want -5/5.5", diff.WhiteAccuracyDelta, diff.BlackAccuracyDelta)
+	}
+}
+
+func TestAnalyzeGameWithEngines(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	if err := a.AddEngineProfile(EngineProfile{Name: "shallow", Pool: newFakePool(t), Depth: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddEngineProfile(EngineProfile{Name: PrimaryEngine}); err == nil {
+		t.Error("AddEngineProfile(primary) = nil, want error")
+	}
+
+	check, err := a.AnalyzeGameWithEngines(context.Background(), "g1", testPGN, 12, GameOptions{}, "shallow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Primary.EngineProfile != PrimaryEngine || check.Primary.Depth != 12 {
+		t.Errorf("primary = %s at depth %d", check.Primary.EngineProfile, check.Primary.Depth)
+	}
+	if check.Secondary.EngineProfile != "shallow" || check.Secondary.Depth != 5 {
+		t.Errorf("secondary = %s at depth %d", check.Secondary.EngineProfile, check.Secondary.Depth)
+	}
+	// The fake engines agree on everything
+	if check.Diff.ComparedMoves != len(check.Primary.Moves) || len(check.Diff.Moves) != 0 {
+		t.Errorf("diff = %+v", check.Diff)
+	}
+
+	// Each profile has its own cache entries
+	fen := check.Primary.Moves[0].FENBefore
+	if _, ok := a.posCache.Get("shallow", fen, CacheQuery{Depth: 5}); !ok {
+		t.Error("secondary evaluation not cached under its profile")
+	}
+	if _, ok := a.posCache.Get("shallow", fen, CacheQuery{Depth: 6}); ok {
+		t.Error("secondary evaluation answered a deeper request")
+	}
+	if _, ok := a.posCache.Get(PrimaryEngine, fen, CacheQuery{Depth: 12}); !ok {
+		t.Error("primary evaluation not cached under its profile")
+	}
+
+	for _, secondary := range []string{"", PrimaryEngine, "missing"} {
+		_, err := a.AnalyzeGameWithEngines(context.Background(), "g1", testPGN, 12, GameOptions{}, secondary)
+		if !errors.Is(err, ErrUnknownEngineProfile) {
+			t.Errorf("secondary %q: err = %v, want ErrUnknownEngineProfile", secondary, err)
+		}
+	}
+}
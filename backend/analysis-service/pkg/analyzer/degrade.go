@@ -0,0 +1,225 @@
+package analyzer
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"go.uber.org/zap"
+)
+
+// DegradeOptions configure the degradation of game analyses while the
+// engine pool is starved
+type DegradeOptions struct {
+	// PoolWait is the 95th percentile wait for an engine over Window
+	// above which new game analyses are degraded; they return to normal
+	// once it drops below RecoverWait
+	PoolWait    time.Duration
+	RecoverWait time.Duration
+	Window      time.Duration
+
+	// Depth caps the depth of degraded game analyses
+	Depth int
+}
+
+// DegradationState is what the degradation controller last decided, and
+// on what
+type DegradationState struct {
+	Enabled bool
+	Active  bool
+	Since   time.Time // When Active last changed, zero if it never has
+
+	PoolWaitP95 time.Duration // Over the window, 0 below degradeMinSamples waits
+	Samples     int           // Engine waits in the window, those still waiting included
+	Depth       int           // Depth cap while active
+
+	Activations   int64 // Times degradation started
+	DegradedGames int64 // Game analyses degraded
+}
+
+const (
+	// degradeMinSamples is how many engine waits the window needs before
+	// their percentile counts: a few slow waits aren't starvation
+	degradeMinSamples = 20
+
+	// degradeMaxSamples bounds the waits kept, the newest
+	degradeMaxSamples = 4096
+)
+
+// waitSample is the wait for an engine of one Get of the primary pool
+type waitSample struct {
+	at   time.Time
+	wait time.Duration
+}
+
+// degrader is the degradation controller. It is told every wait for an
+// engine of the primary pool and decides, as each game analysis starts,
+// whether the pool is starved. Waits still going on count for as long as
+// they have lasted, so a queue building up is noticed before the engine
+// it waits for is free. The zero value is disabled.
+type degrader struct {
+	mu         sync.Mutex
+	enabled    bool
+	opts       DegradeOptions
+	minSamples int
+	samples    []waitSample // Oldest first
+	waiting    map[int64]time.Time
+	nextWait   int64
+	active     bool
+	since      time.Time
+	p95        time.Duration
+
+	activations int64
+	games       int64
+}
+
+// SetDegradation turns on degrading game analyses while the engine pool
+// is starved: while the 95th percentile wait for an engine of the primary
+// pool is high, new game analyses are searched to at most opts.Depth,
+// skip the quick pre-pass of a time budget and the search of alternatives
+// for book detection, and are marked Degraded. Analyses already running
+// keep their depth.
+func (a *Analyzer) SetDegradation(opts DegradeOptions) {
+	a.degrade.mu.Lock()
+	defer a.degrade.mu.Unlock()
+	a.degrade.enabled = true
+	a.degrade.opts = opts
+	a.degrade.minSamples = degradeMinSamples
+}
+
+// Degradation returns the state of the degradation controller, as of now
+func (a *Analyzer) Degradation() DegradationState {
+	a.degrade.update(time.Now(), a.logger)
+	return a.degrade.state()
+}
+
+// getEngine gets an engine of enginePool, timing the wait for the
+// degradation controller when it is the primary pool
+func (a *Analyzer) getEngine(ctx context.Context, enginePool *pool.Pool) (*engine.Engine, error) {
+	if enginePool != a.pool {
+		return enginePool.Get(ctx)
+	}
+	id := a.degrade.begin()
+	eng, err := enginePool.Get(ctx)
+	a.degrade.end(id)
+	return eng, err
+}
+
+// begin records the start of a wait for an engine, ended by end with the
+// id returned
+func (d *degrader) begin() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.enabled {
+		return 0
+	}
+	if d.waiting == nil {
+		d.waiting = make(map[int64]time.Time)
+	}
+	d.nextWait++
+	d.waiting[d.nextWait] = time.Now()
+	return d.nextWait
+}
+
+func (d *degrader) end(id int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	start, ok := d.waiting[id]
+	if !ok {
+		return
+	}
+	delete(d.waiting, id)
+	d.observeLocked(time.Since(start))
+}
+
+// observe records a wait for an engine of the primary pool
+func (d *degrader) observe(wait time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.enabled {
+		d.observeLocked(wait)
+	}
+}
+
+func (d *degrader) observeLocked(wait time.Duration) {
+	if len(d.samples) == degradeMaxSamples {
+		d.samples = d.samples[1:]
+	}
+	d.samples = append(d.samples, waitSample{at: time.Now(), wait: wait})
+}
+
+// update drops the waits that left the window and starts or ends
+// degradation on the percentile of the rest, and returns the depth cap of
+// a game analysis starting now, 0 when not degraded
+func (d *degrader) update(now time.Time, logger *zap.Logger) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.enabled {
+		return 0
+	}
+
+	cutoff := now.Add(-d.opts.Window)
+	first := sort.Search(len(d.samples), func(i int) bool { return d.samples[i].at.After(cutoff) })
+	d.samples = append(d.samples[:0], d.samples[first:]...)
+
+	d.p95 = 0
+	if len(d.samples)+len(d.waiting) >= d.minSamples {
+		waits := make([]time.Duration, 0, len(d.samples)+len(d.waiting))
+		for _, s := range d.samples {
+			waits = append(waits, s.wait)
+		}
+		for _, start := range d.waiting {
+			waits = append(waits, now.Sub(start))
+		}
+		sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+		d.p95 = waits[(len(waits)*95+99)/100-1]
+	}
+
+	switch {
+	case !d.active && d.p95 > d.opts.PoolWait:
+		d.active, d.since = true, now
+		d.activations++
+		logger.Warn("Engine pool starved, degrading game analyses",
+			zap.Duration("poolWaitP95", d.p95),
+			zap.Int("depth", d.opts.Depth))
+	case d.active && d.p95 < d.opts.RecoverWait:
+		d.active, d.since = false, now
+		logger.Info("Engine pool recovered, game analyses back to normal",
+			zap.Duration("poolWaitP95", d.p95))
+	}
+	if !d.active {
+		return 0
+	}
+	return d.opts.Depth
+}
+
+// degradeGame returns the depth a game analysis starting now at depth is
+// searched to, and whether that is degraded
+func (a *Analyzer) degradeGame(depth int) (int, bool) {
+	limit := a.degrade.update(time.Now(), a.logger)
+	if limit == 0 || depth <= limit {
+		return depth, false
+	}
+	a.degrade.mu.Lock()
+	a.degrade.games++
+	a.degrade.mu.Unlock()
+	return limit, true
+}
+
+func (d *degrader) state() DegradationState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return DegradationState{
+		Enabled:       d.enabled,
+		Active:        d.active,
+		Since:         d.since,
+		PoolWaitP95:   d.p95,
+		Samples:       len(d.samples) + len(d.waiting),
+		Depth:         d.opts.Depth,
+		Activations:   d.activations,
+		DegradedGames: d.games,
+	}
+}
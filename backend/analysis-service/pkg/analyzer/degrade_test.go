@@ -0,0 +1,180 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"go.uber.org/zap"
+)
+
+func TestDegradation_EntersAndRecovers(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	a.SetDegradation(DegradeOptions{PoolWait: 100 * time.Millisecond, RecoverWait: 10 * time.Millisecond, Window: time.Minute, Depth: 8})
+	a.degrade.minSamples = 3
+	ctx := context.Background()
+	opts := GameOptions{Cache: CacheOptions{Bypass: true, NoStore: true}}
+
+	// Too few waits to tell, however long
+	a.degrade.observe(time.Second)
+	a.degrade.observe(time.Second)
+	if state := a.Degradation(); state.Active || state.PoolWaitP95 != 0 || state.Samples != 2 {
+		t.Fatalf("with 2 waits: state = %+v, want inactive without a percentile", state)
+	}
+
+	a.degrade.observe(200 * time.Millisecond)
+	analysis, err := a.AnalyzeGame(ctx, "g1", testPGN, 12, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !analysis.Degraded || analysis.Depth != 8 {
+		t.Errorf("starved: degraded = %v, depth = %d, want degraded at 8", analysis.Degraded, analysis.Depth)
+	}
+	for _, move := range analysis.Moves {
+		if move.RequestedDepth != 8 {
+			t.Fatalf("ply %d requested depth = %d, want 8", move.Ply, move.RequestedDepth)
+		}
+	}
+
+	// A game no deeper than the cap isn't degraded
+	shallow, err := a.AnalyzeGame(ctx, "g2", testPGN, 6, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shallow.Degraded || shallow.Depth != 6 {
+		t.Errorf("at depth 6: degraded = %v, depth = %d, want normal at 6", shallow.Degraded, shallow.Depth)
+	}
+
+	state := a.Degradation()
+	if !state.Active || state.Since.IsZero() || state.Activations != 1 || state.DegradedGames != 1 {
+		t.Errorf("starved: state = %+v, want active once with 1 degraded game", state)
+	}
+
+	// Fast waits bring the percentile under the recovery wait
+	for i := 0; i < 100; i++ {
+		a.degrade.observe(time.Millisecond)
+	}
+	analysis, err = a.AnalyzeGame(ctx, "g3", testPGN, 12, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.Degraded || analysis.Depth != 12 {
+		t.Errorf("recovered: degraded = %v, depth = %d, want normal at 12", analysis.Degraded, analysis.Depth)
+	}
+	if state := a.Degradation(); state.Active || state.Activations != 1 || state.PoolWaitP95 >= 10*time.Millisecond {
+		t.Errorf("recovered: state = %+v", state)
+	}
+}
+
+func TestDegradation_WindowExpires(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	a.SetDegradation(DegradeOptions{PoolWait: 100 * time.Millisecond, RecoverWait: 10 * time.Millisecond, Window: time.Minute, Depth: 8})
+	a.degrade.minSamples = 1
+
+	a.degrade.observe(time.Second)
+	if depth := a.degrade.update(time.Now(), zap.NewNop()); depth != 8 {
+		t.Fatalf("starved: depth cap = %d, want 8", depth)
+	}
+	// Once the slow wait leaves the window nothing says the pool is busy
+	if depth := a.degrade.update(time.Now().Add(2*time.Minute), zap.NewNop()); depth != 0 {
+		t.Errorf("after the window: depth cap = %d, want 0", depth)
+	}
+	if state := a.degrade.state(); state.Active || state.Samples != 0 {
+		t.Errorf("after the window: state = %+v", state)
+	}
+}
+
+func TestDegradation_Disabled(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	for i := 0; i < 50; i++ {
+		a.degrade.observe(time.Minute)
+	}
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.Degraded {
+		t.Error("degraded without SetDegradation")
+	}
+	if state := a.Degradation(); state.Enabled || state.Samples != 0 {
+		t.Errorf("state = %+v, want disabled and empty", state)
+	}
+}
+
+// sleepingEngine answers every search at the depth asked for, after
+// depth²/20 ms, so deeper searches cost disproportionately more
+var sleepingEngine = enginetest.Engine{
+	DepthLatency: time.Millisecond / 20,
+	Default:      enginetest.Eval{Centipawns: 20, BestMove: "e2e4"},
+}
+
+// burstLatencies starts games at depth 20 on one engine every 100ms,
+// faster than it can search them, and returns how long each took and how
+// many were degraded
+func burstLatencies(t *testing.T, degrade bool) ([]time.Duration, int) {
+	a := NewAnalyzer(newEnginePool(t, sleepingEngine), zap.NewNop(), 1, 12, 20, time.Minute)
+	if degrade {
+		a.SetDegradation(DegradeOptions{PoolWait: 50 * time.Millisecond, RecoverWait: 10 * time.Millisecond, Window: time.Minute, Depth: 8})
+		a.degrade.minSamples = 2
+	}
+
+	const games = 8
+	latencies := make([]time.Duration, games)
+	var degraded int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < games; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			start := time.Now()
+			analysis, err := a.AnalyzeGame(context.Background(), fmt.Sprintf("g%d", i), testPGN, 20,
+				GameOptions{Cache: CacheOptions{Bypass: true, NoStore: true}}, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			latencies[i] = time.Since(start)
+			if analysis.Degraded {
+				degraded++
+			}
+		}(i)
+		time.Sleep(100 * time.Millisecond)
+	}
+	wg.Wait()
+	return latencies, degraded
+}
+
+func TestDegradation_BurstLatency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("load test")
+	}
+
+	slowest := func(latencies []time.Duration) time.Duration {
+		var longest time.Duration
+		for _, latency := range latencies {
+			longest = max(longest, latency)
+		}
+		return longest
+	}
+
+	normal, degradedNormal := burstLatencies(t, false)
+	if degradedNormal != 0 {
+		t.Errorf("without degradation: %d games degraded", degradedNormal)
+	}
+	withDegradation, degraded := burstLatencies(t, true)
+	if degraded == 0 {
+		t.Error("with degradation: no game degraded during the burst")
+	}
+
+	t.Logf("slowest game: %v normally, %v with %d of %d games degraded",
+		slowest(normal), slowest(withDegradation), degraded, len(withDegradation))
+	if slowest(withDegradation) > slowest(normal)*4/5 {
+		t.Errorf("slowest game with degradation = %v, want well under %v without", slowest(withDegradation), slowest(normal))
+	}
+}
@@ -0,0 +1,49 @@
+package analyzer
+
+import "fmt"
+
+// DiffAnalyses compares two analyses of the same game, typically a quick
+// one (a) and a deeper re-analysis (b), and lists the moves whose
+// judgment changed: a different classification, a centipawn loss that
+// moved by more than cpLossThreshold (0 = CrossCheckCPLossThreshold) or a
+// different best move. Metric deltas are b minus a.
+//
+// Both analyses must have reached the same positions with the same moves
+// over the moves both analyzed; otherwise an ErrAnalysesMismatch error
+// names the first ply that differs. Unless allowConfigMismatch is set
+// their Config snapshots must not differ materially either, see
+// ConfigDifferences; an ErrConfigMismatch error lists the differences.
+func DiffAnalyses(a, b *GameAnalysis, cpLossThreshold int, allowConfigMismatch bool) (AnalysisDiff, error) {
+	if err := checkSameMoves(a, b); err != nil {
+		return AnalysisDiff{}, err
+	}
+	if !allowConfigMismatch {
+		if err := checkSameConfig(a, b); err != nil {
+			return AnalysisDiff{}, err
+		}
+	}
+	if cpLossThreshold <= 0 {
+		cpLossThreshold = CrossCheckCPLossThreshold
+	}
+	return compareAnalyses(a, b, cpLossThreshold, true), nil
+}
+
+// checkSameMoves checks that a and b played the same moves from the same
+// positions, up to the shorter of the two
+func checkSameMoves(a, b *GameAnalysis) error {
+	n := min(len(a.Moves), len(b.Moves))
+	for i := 0; i < n; i++ {
+		moveA, moveB := &a.Moves[i], &b.Moves[i]
+		switch {
+		case moveA.Ply != moveB.Ply:
+			return fmt.Errorf("%w: move %d is ply %d in one analysis and ply %d in the other",
+				ErrAnalysesMismatch, i, moveA.Ply, moveB.Ply)
+		case moveA.FENBefore != moveB.FENBefore:
+			return fmt.Errorf("%w: positions differ at ply %d", ErrAnalysesMismatch, moveA.Ply)
+		case NormalizeUCI(moveA.PlayedMoveUCI) != NormalizeUCI(moveB.PlayedMoveUCI):
+			return fmt.Errorf("%w: moves differ at ply %d (%s, %s)",
+				ErrAnalysesMismatch, moveA.Ply, moveA.PlayedMoveUCI, moveB.PlayedMoveUCI)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// reanalysis returns the moves of a short game as analyzed at depth
+func reanalysis(depth int) *GameAnalysis {
+	return &GameAnalysis{
+		Depth:        depth,
+		WhiteMetrics: GameMetrics{Accuracy: 90, ACPL: 20, Mistakes: 1},
+		BlackMetrics: GameMetrics{Accuracy: 80, ACPL: 30},
+		Moves: []MoveAnalysis{
+			{Ply: 0, Color: "white", PlayedMove: "e4", PlayedMoveUCI: "e2e4", FENBefore: "fen0", BestMove: "e4", BestMoveUCI: "e2e4", Classification: ClassBest},
+			{Ply: 1, Color: "black", PlayedMove: "e5", PlayedMoveUCI: "e7e5", FENBefore: "fen1", BestMove: "c5", BestMoveUCI: "c7c5", Classification: ClassExcellent, CentipawnLoss: 10},
+			{Ply: 2, Color: "white", PlayedMove: "Qh5", PlayedMoveUCI: "d1h5", FENBefore: "fen2", BestMove: "Nf3", BestMoveUCI: "g1f3", Classification: ClassMistake, CentipawnLoss: 150},
+		},
+	}
+}
+
+func TestDiffAnalyses(t *testing.T) {
+	quick, deep := reanalysis(14), reanalysis(24)
+	deep.WhiteMetrics = GameMetrics{Accuracy: 85, ACPL: 35, Blunders: 1}
+	deep.Moves[1].BestMove, deep.Moves[1].BestMoveUCI = "e5", "e7e5"              // Best move changed
+	deep.Moves[2].Classification, deep.Moves[2].CentipawnLoss = ClassBlunder, 320 // Judgment changed
+
+	diff, err := DiffAnalyses(quick, deep, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.DepthA != 14 || diff.DepthB != 24 || diff.ComparedMoves != 3 {
+		t.Errorf("depths = %d/%d, compared = %d", diff.DepthA, diff.DepthB, diff.ComparedMoves)
+	}
+	if diff.BestMoveDiffs != 1 || diff.ClassificationDiffs != 1 || diff.CentipawnLossDiffs != 1 || len(diff.Moves) != 2 {
+		t.Fatalf("diffs = %d/%d/%d, moves = %+v", diff.BestMoveDiffs, diff.ClassificationDiffs, diff.CentipawnLossDiffs, diff.Moves)
+	}
+	if m := diff.Moves[0]; m.Ply != 1 || m.BestMoveA != "c5" || m.BestMoveB != "e5" {
+		t.Errorf("first diff = %+v", m)
+	}
+	want := MetricsDelta{Accuracy: -5, ACPL: 15, Blunders: 1, Mistakes: -1}
+	if diff.White != want || diff.Black != (MetricsDelta{}) {
+		t.Errorf("deltas = %+v / %+v, want %+v / zero", diff.White, diff.Black, want)
+	}
+
+	// A higher threshold hides the loss change, not the classification
+	diff, _ = DiffAnalyses(quick, deep, 200, false)
+	if diff.CentipawnLossDiffs != 0 || len(diff.Moves) != 2 {
+		t.Errorf("threshold 200: loss diffs = %d, moves = %d", diff.CentipawnLossDiffs, len(diff.Moves))
+	}
+}
+
+func TestDiffAnalyses_Mismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*GameAnalysis)
+		want   string
+	}{
+		{"other position", func(g *GameAnalysis) { g.Moves[2].FENBefore = "other" }, "positions differ at ply 2"},
+		{"other move", func(g *GameAnalysis) { g.Moves[1].PlayedMoveUCI = "c7c5" }, "moves differ at ply 1"},
+		{"shifted plies", func(g *GameAnalysis) { g.Moves = g.Moves[1:] }, "move 0 is ply 0 in one analysis and ply 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := reanalysis(24)
+			tt.modify(b)
+			_, err := DiffAnalyses(reanalysis(14), b, 0, false)
+			if !errors.Is(err, ErrAnalysesMismatch) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want ErrAnalysesMismatch with %q", err, tt.want)
+			}
+		})
+	}
+
+	// A timed-out analysis is compared over the moves it has
+	short := reanalysis(24)
+	short.Moves = short.Moves[:2]
+	if diff, err := DiffAnalyses(reanalysis(14), short, 0, false); err != nil || diff.ComparedMoves != 2 {
+		t.Errorf("shorter analysis: compared %d, err %v", diff.ComparedMoves, err)
+	}
+}
+
+func TestDiffAnalyses_ConfigMismatch(t *testing.T) {
+	snapshot := func(threads int, net string, depth int) *AnalysisConfigSnapshot {
+		return &AnalysisConfigSnapshot{Threads: threads, HashMB: 256, MultiPV: 1, Depth: depth, NNUENet: net, Deterministic: threads == 1}
+	}
+	quick, deep := reanalysis(14), reanalysis(24)
+
+	// Depth and cache hits differ by design
+	quick.Config, deep.Config = snapshot(1, "nn-a.nnue", 14), snapshot(1, "nn-a.nnue", 24)
+	deep.Config.CacheHitPercent = 60
+	if _, err := DiffAnalyses(quick, deep, 0, false); err != nil {
+		t.Errorf("deeper re-analysis: %v", err)
+	}
+
+	deep.Config = snapshot(4, "nn-b.nnue", 24)
+	_, err := DiffAnalyses(quick, deep, 0, false)
+	if !errors.Is(err, ErrConfigMismatch) {
+		t.Fatalf("err = %v, want ErrConfigMismatch", err)
+	}
+	for _, want := range []string{"threads 1 vs 4", "nnue net nn-a.nnue vs nn-b.nnue", "deterministic true vs false"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want it to list %q", err, want)
+		}
+	}
+	if _, err := DiffAnalyses(quick, deep, 0, true); err != nil {
+		t.Errorf("with the override: %v", err)
+	}
+
+	// Analyses stored before snapshots were recorded are compared
+	deep.Config = nil
+	if _, err := DiffAnalyses(quick, deep, 0, false); err != nil {
+		t.Errorf("without a snapshot: %v", err)
+	}
+}
+
+func TestAnalyzeGame_ConfigSnapshot(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", "1. e4 e5 2. Nf3 Nc6 *", 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AnalysisConfigSnapshot{Threads: 1, HashMB: 16, MultiPV: 1, Depth: 12, Deterministic: true}
+	if analysis.Config == nil || *analysis.Config != want {
+		t.Fatalf("config = %+v, want %+v", analysis.Config, want)
+	}
+
+	// A second run finds every position cached
+	again, err := a.AnalyzeGame(context.Background(), "g1", "1. e4 e5 2. Nf3 Nc6 *", 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Config.CacheHitPercent != 100 {
+		t.Errorf("cache hits %.1f%%, want 100%%", again.Config.CacheHitPercent)
+	}
+}
@@ -0,0 +1,296 @@
+package analyzer
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"go.uber.org/zap"
+)
+
+// DriftOptions configure the drift sampler, which searches cached
+// positions again to tell whether the engines still agree with the cache,
+// as after a Stockfish or network upgrade
+type DriftOptions struct {
+	// Every Interval, up to Samples engine entries of the cache picked at
+	// random are searched again to the depth they were cached at
+	Interval time.Duration
+	Samples  int
+
+	// MaxP95 is the 95th percentile difference, in centipawns, above which
+	// the cache's engine entries are flushed (0 = never flush)
+	MaxP95 int
+}
+
+// DriftState is what the drift sampler measured of the cache: the
+// differences between cached and fresh evaluations since the last flush,
+// the newest driftMaxSamples of them
+type DriftState struct {
+	Enabled bool
+	MaxP95  int // Flush ceiling, 0 for none
+
+	P50     int // Centipawns, 0 below driftMinSamples differences
+	P95     int
+	Samples int // Differences measured
+
+	Sampled   int64 // Entries searched again
+	Failed    int64 // Searches that failed or weren't plausible
+	BackedOff int64 // Rounds cut short as the pool was busy
+
+	Flushes   int64
+	Flushed   int64     // Entries flushed
+	LastFlush time.Time // Zero if the cache was never flushed
+}
+
+const (
+	// driftMinSamples is how many differences the percentiles need
+	// before they are reported or flush the cache
+	driftMinSamples = 20
+
+	// driftMaxSamples bounds the differences kept, the newest
+	driftMaxSamples = 1000
+
+	// driftEngineWait is how long a round waits for an idle engine before
+	// it backs off
+	driftEngineWait = time.Second
+)
+
+// driftSampler keeps the differences the drift sampler measured. The zero
+// value is disabled.
+type driftSampler struct {
+	mu         sync.Mutex
+	enabled    bool
+	opts       DriftOptions
+	minSamples int
+	diffs      []int // Oldest first
+
+	sampled   int64
+	failed    int64
+	backedOff int64
+	flushes   int64
+	flushed   int64
+	lastFlush time.Time
+}
+
+// driftEntry is a cached search the drift sampler searches again
+type driftEntry struct {
+	fen   string
+	depth int
+	eval  engine.Evaluation
+}
+
+// SetDriftSampling turns on the drift sampler: every opts.Interval it
+// searches up to opts.Samples random entries of the position cache from
+// the primary engine again, to the depth they were cached at, and records
+// how far the fresh evaluations are from the cached ones. Its searches are
+// background work, one at a time, and a round stops as soon as no engine
+// is free, a request waits for one or game analyses are degraded. It
+// starts the sampler, so it is set once, at startup.
+func (a *Analyzer) SetDriftSampling(opts DriftOptions) {
+	a.drift.mu.Lock()
+	a.drift.enabled = true
+	a.drift.opts = opts
+	a.drift.minSamples = driftMinSamples
+	a.drift.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			a.sampleDrift(context.Background())
+		}
+	}()
+}
+
+// Drift returns what the drift sampler measured so far
+func (a *Analyzer) Drift() DriftState {
+	return a.drift.state()
+}
+
+// sampleDrift runs a round of the drift sampler, then flushes the cache's
+// engine entries if the drift is above the ceiling
+func (a *Analyzer) sampleDrift(ctx context.Context) {
+	ctx = pool.WithTag(ctx, DriftSampleTag)
+	a.drift.mu.Lock()
+	samples := a.drift.opts.Samples
+	a.drift.mu.Unlock()
+
+	for _, entry := range a.posCache.sample(PrimaryEngine, engine.SourceEngine, samples) {
+		if a.poolBusy() {
+			a.drift.backOff()
+			break
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, driftEngineWait)
+		eng, err := a.pool.GetBackground(waitCtx)
+		cancel()
+		if err != nil {
+			a.drift.backOff()
+			break
+		}
+		fresh, err := a.searchBackground(ctx, eng, entry.fen, entry.depth, 1)
+		if err != nil {
+			a.logger.Debug("Drift sample failed", zap.String("fen", entry.fen), zap.Error(err))
+			a.drift.fail()
+			continue
+		}
+		a.drift.record(driftCentipawns(entry.eval, fresh.Evaluations[0]))
+	}
+
+	if !a.drift.overCeiling() {
+		return
+	}
+	state := a.drift.state()
+	flushed := a.posCache.flush(PrimaryEngine, engine.SourceEngine)
+	a.drift.flush(flushed)
+	a.logger.Warn("Cached evaluations drifted from the engines', flushing them from the position cache",
+		zap.Int("p50", state.P50),
+		zap.Int("p95", state.P95),
+		zap.Int("maxP95", state.MaxP95),
+		zap.Int("samples", state.Samples),
+		zap.Int("flushed", flushed))
+}
+
+// poolBusy reports whether the pool has no idle capacity for background
+// work: no free engine, a request waiting for one or game analyses
+// degraded
+func (a *Analyzer) poolBusy() bool {
+	return a.pool.Available() == 0 || a.pool.Waiting() > 0 || a.Degradation().Active
+}
+
+// driftCentipawns is how far apart two evaluations of a position are, in
+// centipawns with mates normalized, at most evaluation.MaxCPLossPerMove:
+// a mate one search found and the other didn't is a large difference,
+// not one that outweighs all others
+func driftCentipawns(cached, fresh engine.Evaluation) int {
+	return min(abs(centipawns(cached)-centipawns(fresh)), int(evaluation.MaxCPLossPerMove))
+}
+
+func (d *driftSampler) record(diff int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.diffs) == driftMaxSamples {
+		d.diffs = d.diffs[1:]
+	}
+	d.diffs = append(d.diffs, diff)
+	d.sampled++
+}
+
+func (d *driftSampler) fail() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sampled++
+	d.failed++
+}
+
+func (d *driftSampler) backOff() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.backedOff++
+}
+
+// overCeiling reports whether the 95th percentile difference is above
+// the flush ceiling, once there are enough differences
+func (d *driftSampler) overCeiling() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.opts.MaxP95 <= 0 || len(d.diffs) < d.minSamples {
+		return false
+	}
+	_, p95 := d.percentiles()
+	return p95 > d.opts.MaxP95
+}
+
+// flush records a flush of n entries; the differences measured against
+// them no longer say anything of the cache
+func (d *driftSampler) flush(n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.diffs = nil
+	d.flushes++
+	d.flushed += int64(n)
+	d.lastFlush = time.Now()
+}
+
+// percentiles returns the median and 95th percentile differences, 0 below
+// minSamples (must be called with lock held)
+func (d *driftSampler) percentiles() (int, int) {
+	if len(d.diffs) == 0 || len(d.diffs) < d.minSamples {
+		return 0, 0
+	}
+	diffs := append([]int(nil), d.diffs...)
+	sort.Ints(diffs)
+	return diffs[(len(diffs)*50+99)/100-1], diffs[(len(diffs)*95+99)/100-1]
+}
+
+func (d *driftSampler) state() DriftState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p50, p95 := d.percentiles()
+	return DriftState{
+		Enabled:   d.enabled,
+		MaxP95:    d.opts.MaxP95,
+		P50:       p50,
+		P95:       p95,
+		Samples:   len(d.diffs),
+		Sampled:   d.sampled,
+		Failed:    d.failed,
+		BackedOff: d.backedOff,
+		Flushes:   d.flushes,
+		Flushed:   d.flushed,
+		LastFlush: d.lastFlush,
+	}
+}
+
+// sample returns up to n entries of an engine profile from source, picked
+// at random. Keys leave out the clocks, so their FENs have fresh ones.
+func (c *PositionCache) sample(engineProfile, source string, n int) []driftEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	prefix := engineProfile + "|"
+	var picked []driftEntry
+	seen := 0
+	for key, cached := range c.cache {
+		if cached.source != source || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		entry := driftEntry{
+			fen:   strings.TrimPrefix(key, prefix) + " 0 1",
+			depth: cached.depth,
+			eval:  cached.evaluation(),
+		}
+		// Reservoir sampling: every entry is as likely to be picked
+		seen++
+		switch {
+		case len(picked) < n:
+			picked = append(picked, entry)
+		case rand.Intn(seen) < n:
+			picked[rand.Intn(n)] = entry
+		}
+	}
+	return picked
+}
+
+// flush removes the entries of an engine profile from source and returns
+// how many there were
+func (c *PositionCache) flush(engineProfile, source string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := engineProfile + "|"
+	n := 0
+	for key, cached := range c.cache {
+		if cached.source == source && strings.HasPrefix(key, prefix) {
+			delete(c.cache, key)
+			n++
+		}
+	}
+	c.bySource[source] -= n
+	return n
+}
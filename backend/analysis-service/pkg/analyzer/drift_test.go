@@ -0,0 +1,169 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"go.uber.org/zap"
+)
+
+// driftFENs are positions the line engine scores cp 20 like any other
+var driftFENs = []string{
+	"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+	"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+	"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2",
+	"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+}
+
+// newDriftAnalyzer returns an analyzer on the line engine, its drift
+// sampler on with opts but left to the test to run, reporting from the
+// first difference, and its cache holding driftFENs searched by an older
+// engine that scored them cached, alongside entries never sampled
+func newDriftAnalyzer(t *testing.T, opts DriftOptions, cached ...int) *Analyzer {
+	t.Helper()
+	a := NewAnalyzer(newEnginePool(t, lineEngine), zap.NewNop(), 1, 12, 20, time.Minute)
+	opts.Interval = time.Hour
+	a.SetDriftSampling(opts)
+	a.drift.mu.Lock()
+	a.drift.minSamples = 1
+	a.drift.mu.Unlock()
+
+	for i, cp := range cached {
+		a.posCache.Set(PrimaryEngine, driftFENs[i], 12, engine.Evaluation{Depth: 12, Centipawns: cp}, "e2e4", engine.SourceEngine)
+	}
+	a.posCache.Import(PrimaryEngine, startFEN, 30, engine.Evaluation{Depth: 30, Centipawns: 900}, "e2e4")
+	a.posCache.Set("secondary", startFEN, 12, engine.Evaluation{Depth: 12, Centipawns: 900}, "e2e4", engine.SourceEngine)
+	return a
+}
+
+func TestSampleDrift_Distribution(t *testing.T) {
+	a := newDriftAnalyzer(t, DriftOptions{Samples: 10}, 20, 30, 60, 220)
+	a.sampleDrift(context.Background())
+
+	// Differences 0, 10, 40 and 200 from the fresh cp 20; the imported
+	// entry and the other engine's are left alone
+	state := a.Drift()
+	if !state.Enabled || state.Sampled != 4 || state.Samples != 4 || state.Failed != 0 || state.BackedOff != 0 {
+		t.Fatalf("state = %+v, want 4 entries sampled", state)
+	}
+	if state.P50 != 10 || state.P95 != 200 {
+		t.Errorf("p50 %d p95 %d, want 10 and 200", state.P50, state.P95)
+	}
+	if state.Flushes != 0 || a.CacheStats().Size != 6 {
+		t.Errorf("cache of %d entries flushed %d times without a ceiling", a.CacheStats().Size, state.Flushes)
+	}
+
+	// A round samples no more than asked
+	a.drift.mu.Lock()
+	a.drift.opts.Samples = 2
+	a.drift.mu.Unlock()
+	a.sampleDrift(context.Background())
+	if state := a.Drift(); state.Sampled != 6 {
+		t.Errorf("sampled %d after a round of 2, want 6", state.Sampled)
+	}
+}
+
+func TestSampleDrift_FlushesAboveCeiling(t *testing.T) {
+	// Under the ceiling the cache stays
+	a := newDriftAnalyzer(t, DriftOptions{Samples: 10, MaxP95: 200}, 20, 30, 60, 220)
+	a.sampleDrift(context.Background())
+	if state := a.Drift(); state.Flushes != 0 || state.MaxP95 != 200 {
+		t.Errorf("state = %+v, want no flush at p95 200", state)
+	}
+
+	a = newDriftAnalyzer(t, DriftOptions{Samples: 10, MaxP95: 100}, 20, 30, 60, 220)
+	a.sampleDrift(context.Background())
+	state := a.Drift()
+	if state.Flushes != 1 || state.Flushed != 4 || state.LastFlush.IsZero() {
+		t.Fatalf("state = %+v, want the 4 engine entries flushed", state)
+	}
+	if state.Samples != 0 || state.P95 != 0 {
+		t.Errorf("p95 %d of %d differences after the flush, want none", state.P95, state.Samples)
+	}
+	sources := a.CacheSizeBySource()
+	if sources[engine.SourceEngine] != 1 || sources[engine.SourceImported] != 1 {
+		t.Errorf("cache by source = %v, want the imported and other engine's entries", sources)
+	}
+	for _, fen := range driftFENs {
+		if a.posCache.has(PrimaryEngine, fen, CacheQuery{Depth: 12}) {
+			t.Errorf("%s still cached", fen)
+		}
+	}
+
+	// Nothing left to sample, nothing more to flush
+	a.sampleDrift(context.Background())
+	if state := a.Drift(); state.Sampled != 4 || state.Flushes != 1 {
+		t.Errorf("state = %+v after a round on the flushed cache", state)
+	}
+}
+
+func TestSampleDrift_BacksOffBusyPool(t *testing.T) {
+	a := newDriftAnalyzer(t, DriftOptions{Samples: 10}, 20, 30)
+
+	// A request holds the pool's only engine
+	eng, err := a.pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.sampleDrift(context.Background())
+	if state := a.Drift(); state.Sampled != 0 || state.BackedOff != 1 {
+		t.Errorf("state = %+v, want the round backed off", state)
+	}
+
+	// Once it is given back the next round samples
+	a.pool.Put(eng)
+	a.sampleDrift(context.Background())
+	if state := a.Drift(); state.Sampled != 2 || state.BackedOff != 1 {
+		t.Errorf("state = %+v, want both entries sampled", state)
+	}
+	if stats := a.PoolStats(); len(stats.Tags) != 2 {
+		t.Errorf("pool tags = %+v, want the request's and the sampler's", stats.Tags)
+	}
+}
+
+func TestDriftCentipawns(t *testing.T) {
+	mate := func(n int) engine.Evaluation { return engine.Evaluation{IsMate: true, MateIn: &n} }
+	tests := []struct {
+		name          string
+		cached, fresh engine.Evaluation
+		want          int
+	}{
+		{"scores", engine.Evaluation{Centipawns: -50}, engine.Evaluation{Centipawns: 30}, 80},
+		{"same score", engine.Evaluation{Centipawns: 30}, engine.Evaluation{Centipawns: 30}, 0},
+		{"mates", mate(3), mate(4), 1},
+		{"mate found", engine.Evaluation{Centipawns: 20}, mate(3), 500},
+		{"mate reversed", mate(-2), mate(2), 500},
+	}
+	for _, tt := range tests {
+		if got := driftCentipawns(tt.cached, tt.fresh); got != tt.want {
+			t.Errorf("%s: %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPositionCache_Sample(t *testing.T) {
+	c := NewPositionCache(100)
+	keys := make(map[string]bool)
+	for _, fen := range append([]string{startFEN}, driftFENs...) {
+		c.Set(PrimaryEngine, fen, 12, engine.Evaluation{Depth: 12}, "e2e4", engine.SourceEngine)
+		keys[c.cacheKey(PrimaryEngine, fen)] = true
+	}
+
+	picked := c.sample(PrimaryEngine, engine.SourceEngine, 3)
+	if len(picked) != 3 {
+		t.Fatalf("picked %d entries, want 3", len(picked))
+	}
+	seen := make(map[string]bool)
+	for _, entry := range picked {
+		key := c.cacheKey(PrimaryEngine, entry.fen)
+		if !keys[key] || seen[key] || entry.depth != 12 || engine.ValidateFEN(entry.fen) != nil {
+			t.Errorf("picked %+v, want a distinct cached entry", entry)
+		}
+		seen[key] = true
+	}
+	if all := c.sample(PrimaryEngine, engine.SourceEngine, 10); len(all) != len(keys) {
+		t.Errorf("picked %d of %d entries", len(all), len(keys))
+	}
+}
@@ -0,0 +1,66 @@
+package analyzer
+
+import "errors"
+
+// Error classes returned by the analyzer. Callers should match them with
+// errors.Is; the wrapped message carries the underlying detail.
+var (
+	// ErrInvalidPGN means the PGN could not be parsed or replayed
+	ErrInvalidPGN = errors.New("invalid PGN")
+
+	// ErrEmptyGame means the PGN parsed but has no moves, like an aborted
+	// game that only has headers
+	ErrEmptyGame = errors.New("game has no moves")
+
+	// ErrGameTooLong means the game has more plies than the analyzer's
+	// limit
+	ErrGameTooLong = errors.New("game too long")
+
+	// ErrUnsupportedVariant means the PGN is of a chess variant the
+	// analyzer doesn't evaluate; see VariantError
+	ErrUnsupportedVariant = errors.New("unsupported chess variant")
+
+	// ErrInvalidFEN means the FEN failed validation
+	ErrInvalidFEN = errors.New("invalid FEN")
+
+	// ErrEngineFailure means the engine crashed or returned unusable output
+	ErrEngineFailure = errors.New("engine failure")
+
+	// ErrUnknownThresholdProfile means the requested threshold profile
+	// is not configured
+	ErrUnknownThresholdProfile = errors.New("unknown threshold profile")
+
+	// ErrUnknownAccuracyModel means the requested accuracy model is not
+	// one of the evaluation.AccuracyModel values
+	ErrUnknownAccuracyModel = errors.New("unknown accuracy model")
+
+	// ErrUnknownEngineProfile means the requested engine profile is not
+	// configured
+	ErrUnknownEngineProfile = errors.New("unknown engine profile")
+
+	// ErrNotCached means a cache-only request's position isn't cached
+	ErrNotCached = errors.New("position not cached")
+
+	// ErrTimeout means the analysis ran past its deadline
+	ErrTimeout = errors.New("analysis timed out")
+
+	// ErrUnsupportedSchema means stored analysis JSON has a schema version
+	// this build can't read
+	ErrUnsupportedSchema = errors.New("unsupported analysis schema")
+
+	// ErrChecksumMismatch means a GameAnalysis doesn't match its checksum,
+	// or has none
+	ErrChecksumMismatch = errors.New("analysis checksum mismatch")
+
+	// ErrAnalysesMismatch means two analyses compared by DiffAnalyses are
+	// not of the same moves
+	ErrAnalysesMismatch = errors.New("analyses are of different moves")
+
+	// ErrConfigMismatch means two analyses compared by DiffAnalyses were
+	// searched with materially different engine settings
+	ErrConfigMismatch = errors.New("analyses were searched with different engine settings")
+
+	// ErrPrefixMismatch means the evaluations supplied for the positions
+	// before GameOptions.StartPly don't line up with the game
+	ErrPrefixMismatch = errors.New("prefix evaluations don't match the game")
+)
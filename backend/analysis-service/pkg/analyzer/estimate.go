@@ -0,0 +1,102 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/pool"
+)
+
+// maxGameWorkers is the most engines one game analysis searches on at once
+const maxGameWorkers = 4
+
+// GameEstimate is what a game analysis expects to take, worked out once
+// its positions have been looked up in the cache and before any is
+// searched, then updated as the searches finish
+type GameEstimate struct {
+	Positions int // Positions of the game, the final one included
+	CacheHits int // Positions the cache had at the depth
+	Searches  int // Positions to search
+	Searched  int // Of Searches, finished so far, failed ones included
+
+	EnginesAvailable int // Idle engines of the pool as the searches started
+	PoolSize         int
+	Workers          int // Engines the searches run on at once
+
+	// ColdStart is set when no search at the game's depth had finished
+	// before the game's, as on a newly started instance or a depth nobody
+	// asked for yet, so the ETA waits for the game's own first search
+	ColdStart bool
+
+	// ETA is the wall clock time left, from the engine time per position
+	// at the depth until the game's first search finishes and from the
+	// game's own pace after it. ETAKnown is false while there is nothing
+	// to go on.
+	ETA      time.Duration
+	ETAKnown bool
+
+	timeBudget time.Duration
+	started    time.Time
+}
+
+// EstimateCallback receives a game analysis's estimate before its searches
+// start and again as each finishes
+type EstimateCallback func(estimate GameEstimate)
+
+// gameWorkers returns the number of engines of enginePool a game analysis
+// searches on: those idle, at least 1 and at most maxGameWorkers
+func gameWorkers(enginePool *pool.Pool) int {
+	return min(max(enginePool.Available(), 1), maxGameWorkers)
+}
+
+// newGameEstimate estimates a game analysis of positions positions at
+// depth, cacheHits of them cached and searches to search on enginePool
+// from now. The timings only cover the primary engine, so an analysis on
+// another engine profile always starts cold.
+func (a *Analyzer) newGameEstimate(enginePool *pool.Pool, engineProfile string, depth, positions, cacheHits, searches int, timeBudget time.Duration) GameEstimate {
+	est := GameEstimate{
+		Positions:        positions,
+		CacheHits:        cacheHits,
+		Searches:         searches,
+		EnginesAvailable: enginePool.Available(),
+		PoolSize:         enginePool.Size(),
+		Workers:          min(gameWorkers(enginePool), max(searches, 1)),
+		timeBudget:       timeBudget,
+		started:          time.Now(),
+	}
+	var perPosition time.Duration
+	warm := false
+	if engineProfile == PrimaryEngine {
+		perPosition, warm = a.timings.Estimate(depth)
+	}
+	est.ColdStart = !warm
+
+	switch {
+	case searches == 0:
+		est.ETAKnown = true
+	case timeBudget > 0:
+		// The budget is split between the positions, searched side by side
+		est.ETA, est.ETAKnown = timeBudget/time.Duration(est.Workers), true
+	case !est.ColdStart:
+		rounds := (searches + est.Workers - 1) / est.Workers
+		est.ETA, est.ETAKnown = perPosition*time.Duration(rounds), true
+	}
+	return est
+}
+
+// searched counts a finished search and projects the game's pace so far
+// over the searches left
+func (e *GameEstimate) searched() {
+	e.Searched++
+	left := e.Searches - e.Searched
+	if left <= 0 {
+		e.ETA, e.ETAKnown = 0, true
+		return
+	}
+	elapsed := time.Since(e.started)
+	e.ETA = elapsed * time.Duration(left) / time.Duration(e.Searched)
+	if e.timeBudget > 0 {
+		// Searches stop once the budget is spent
+		e.ETA = min(e.ETA, max(e.timeBudget/time.Duration(e.Workers)-elapsed, 0))
+	}
+	e.ETAKnown = true
+}
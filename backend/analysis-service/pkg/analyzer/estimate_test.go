@@ -0,0 +1,73 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeGame_Estimate(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	var estimates []GameEstimate
+	opts := GameOptions{OnEstimate: func(e GameEstimate) { estimates = append(estimates, e) }}
+
+	// A new instance has no timings to go on
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := estimates[0]
+	if !first.ColdStart || first.ETAKnown || first.Searched != 0 {
+		t.Errorf("cold start: first estimate = %+v, want cold without an ETA", first)
+	}
+	if first.Positions != analysis.TotalMoves+1 || first.CacheHits != 0 || first.Searches != first.Positions {
+		t.Errorf("cold start: %d positions, %d cached, %d searches, want %d all searched",
+			first.Positions, first.CacheHits, first.Searches, analysis.TotalMoves+1)
+	}
+	if len(estimates) != first.Searches+1 {
+		t.Fatalf("%d estimates, want one before and one per search", len(estimates))
+	}
+	for i, e := range estimates[1:] {
+		if e.Searched != i+1 || !e.ETAKnown {
+			t.Fatalf("estimate %d = %+v, want %d searched with an ETA", i+1, e, i+1)
+		}
+	}
+	if last := estimates[len(estimates)-1]; last.ETA != 0 {
+		t.Errorf("last estimate ETA = %v, want 0", last.ETA)
+	}
+
+	// The game's searches warm the timings for the next, whose ETA is the
+	// time per position over the searches each worker makes
+	estimates = nil
+	opts.Cache = CacheOptions{Bypass: true, NoStore: true}
+	if _, err := a.AnalyzeGame(context.Background(), "g2", testPGN, 12, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	warm := estimates[0]
+	perPosition, _ := a.EstimatePositionTime(12)
+	rounds := (warm.Searches + warm.Workers - 1) / warm.Workers
+	if warm.ColdStart || !warm.ETAKnown || warm.ETA != perPosition*time.Duration(rounds) {
+		t.Errorf("warm: first estimate = %+v, want an ETA of %d × %v", warm, rounds, perPosition)
+	}
+
+	// Everything cached: nothing to wait for
+	estimates = nil
+	opts.Cache = CacheOptions{}
+	if _, err := a.AnalyzeGame(context.Background(), "g3", testPGN, 12, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(estimates) != 1 {
+		t.Fatalf("cached: %d estimates, want 1", len(estimates))
+	}
+	if cached := estimates[0]; cached.CacheHits != cached.Positions || cached.Searches != 0 || !cached.ETAKnown || cached.ETA != 0 {
+		t.Errorf("cached: estimate = %+v, want every position cached and no ETA left", cached)
+	}
+}
+
+func TestGameEstimate_TimeBudget(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	est := a.newGameEstimate(a.pool, PrimaryEngine, 12, 20, 0, 19, 6*time.Second)
+	if !est.ETAKnown || est.ETA != 6*time.Second/time.Duration(est.Workers) {
+		t.Errorf("with a budget: ETA %v, known %v, want the budget over %d workers", est.ETA, est.ETAKnown, est.Workers)
+	}
+}
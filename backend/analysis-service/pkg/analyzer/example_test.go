@@ -0,0 +1,46 @@
+package analyzer_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"go.uber.org/zap"
+)
+
+func ExampleParsePGN() {
+	positions, err := analyzer.ParsePGN("1. e4 e5 2. Nf3 *")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, pos := range positions[1:] {
+		fmt.Println(pos.MoveSAN, pos.MoveUCI)
+	}
+	// Output:
+	// e4 e2e4
+	// e5 e7e5
+	// Nf3 g1f3
+}
+
+func ExampleAnalyzer_AnalyzeGame() {
+	logger := zap.NewNop()
+	engines, err := pool.NewPool(2, engine.Config{BinaryPath: "/usr/local/bin/stockfish", Threads: 1, Hash: 64}, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer engines.Close()
+
+	a := analyzer.NewAnalyzer(engines, logger, 10, 16, 22, time.Minute)
+	analysis, err := a.AnalyzeGame(context.Background(), "game-1", "1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 *", 16, analyzer.GameOptions{}, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, move := range analysis.Moves {
+		fmt.Println(move.MoveNumber, move.PlayedMove, move.Classification, move.CentipawnLoss)
+	}
+	fmt.Printf("White %.1f%%, Black %.1f%%\n", analysis.WhiteMetrics.Accuracy, analysis.BlackMetrics.Accuracy)
+}
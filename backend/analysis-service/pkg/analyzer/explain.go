@@ -0,0 +1,190 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/notnil/chess"
+)
+
+// ExplanationKind is what a move's explanation says about it, and the key
+// of its template
+type ExplanationKind string
+
+const (
+	ExplainBook           ExplanationKind = "book"
+	ExplainForced         ExplanationKind = "forced"
+	ExplainBest           ExplanationKind = "best"
+	ExplainBrilliant      ExplanationKind = "brilliant"
+	ExplainGood           ExplanationKind = "good"
+	ExplainMissedMate     ExplanationKind = "missed_mate"
+	ExplainAllowsMate     ExplanationKind = "allows_mate"
+	ExplainLosesMaterial  ExplanationKind = "loses_material"
+	ExplainMissesMaterial ExplanationKind = "misses_material"
+	ExplainLosesEval      ExplanationKind = "loses_eval"
+)
+
+// ExplanationInputs are the facts a move's explanation is written from.
+// They hold no text of their own but moves in SAN, so a translation only
+// needs templates of its own keyed by Kind.
+type ExplanationInputs struct {
+	Kind           ExplanationKind
+	Classification MoveClassification
+	CentipawnLoss  int
+	MateIn         int    // Moves to the mate missed or allowed
+	BestMove       string // SAN
+	Refutation     string // SAN of the opponent's best reply to the move
+
+	// MaterialLost is the material, in pawns, the mover is down along the
+	// move and the refutation; MaterialWon what they were up along the
+	// best line. Both look sacrificeHorizon plies past the move.
+	MaterialLost int
+	MaterialWon  int
+}
+
+// explanationTemplates are the English explanations by kind
+var explanationTemplates = map[ExplanationKind]string{
+	ExplainBook:           "A book move.",
+	ExplainForced:         "The only legal move.",
+	ExplainBest:           "The best move.",
+	ExplainBrilliant:      "A brilliant move.",
+	ExplainGood:           "A solid move{{with .BestMove}}, though {{.}} was slightly better{{end}}.",
+	ExplainMissedMate:     "Missed mate in {{.MateIn}}{{with .BestMove}} starting with {{.}}{{end}}.",
+	ExplainAllowsMate:     "This allows mate in {{.MateIn}}{{with .Refutation}} starting with {{.}}{{end}}.",
+	ExplainLosesMaterial:  "This drops {{material .MaterialLost}}{{with .Refutation}} to {{.}}{{end}}{{if .BestMove}}{{if gt .MaterialWon 0}} — {{.BestMove}} was winning material{{else}}; {{.BestMove}} was better{{end}}{{end}}.",
+	ExplainMissesMaterial: "{{.BestMove}} was winning {{material .MaterialWon}}.",
+	ExplainLosesEval:      "This gives up {{pawns .CentipawnLoss}} pawns{{with .BestMove}}; {{.}} was better{{end}}.",
+}
+
+// explanations are the parsed explanationTemplates
+var explanations = func() map[ExplanationKind]*template.Template {
+	funcs := template.FuncMap{"material": materialPhrase, "pawns": pawnsPhrase}
+	parsed := make(map[ExplanationKind]*template.Template, len(explanationTemplates))
+	for kind, text := range explanationTemplates {
+		parsed[kind] = template.Must(template.New(string(kind)).Funcs(funcs).Parse(text))
+	}
+	return parsed
+}()
+
+// Explain returns the English explanation of in
+func (in ExplanationInputs) Explain() string {
+	tmpl, ok := explanations[in.Kind]
+	if !ok {
+		return ""
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, in); err != nil {
+		return ""
+	}
+	return b.String()
+}
+
+// ExplainMove returns the facts explaining an analyzed and classified
+// move: its classification for a good move, else the first that applies
+// of a mate missed or allowed, material lost or missed, and the
+// evaluation given up
+func ExplainMove(move *MoveAnalysis) ExplanationInputs {
+	in := ExplanationInputs{
+		Classification: move.Classification,
+		CentipawnLoss:  move.CentipawnLoss,
+	}
+	if !SameMoveFrom(move.FENBefore, move.PlayedMoveUCI, move.BestMoveUCI) {
+		in.BestMove = move.BestMove
+	}
+
+	switch move.Classification {
+	case ClassBook:
+		in.Kind = ExplainBook
+		return in
+	case ClassBrilliant, ClassGreat:
+		in.Kind = ExplainBrilliant
+		return in
+	case ClassBest, ClassExcellent, ClassGood, ClassNormal:
+		switch {
+		case move.Forced:
+			in.Kind = ExplainForced
+		case in.BestMove == "":
+			in.Kind = ExplainBest
+		default:
+			in.Kind = ExplainGood
+		}
+		return in
+	}
+
+	if reply := move.EvalAfter.PV; len(reply) > 0 {
+		in.Refutation, _ = UCIToSAN(move.FENAfter, reply[0])
+	}
+	before := material(move.FENBefore).Balance(move.Color)
+	afterRefutation := append([]string{move.PlayedMoveUCI}, move.EvalAfter.PV...)
+	in.MaterialLost = before - material(playLine(move.FENBefore, afterRefutation, 1+sacrificeHorizon)).Balance(move.Color)
+	if in.BestMove != "" {
+		in.MaterialWon = material(playLine(move.FENBefore, move.PV, 1+sacrificeHorizon)).Balance(move.Color) - before
+	}
+
+	switch {
+	case mateFor(move.EvalBefore) > 0 && mateFor(move.EvalAfter) >= 0:
+		in.Kind = ExplainMissedMate
+		in.MateIn = mateFor(move.EvalBefore)
+	case mateFor(move.EvalAfter) > 0 && mateFor(move.EvalBefore) >= 0:
+		in.Kind = ExplainAllowsMate
+		in.MateIn = mateFor(move.EvalAfter)
+	case in.MaterialLost > 0:
+		in.Kind = ExplainLosesMaterial
+	case in.MaterialWon > 0 && in.BestMove != "":
+		in.Kind = ExplainMissesMaterial
+	default:
+		in.Kind = ExplainLosesEval
+	}
+	return in
+}
+
+// mateFor returns the moves to mate of eval for its side to move,
+// negative when they are mated and 0 without a mate
+func mateFor(eval engine.Evaluation) int {
+	if !eval.IsMate || eval.MateIn == nil {
+		return 0
+	}
+	return *eval.MateIn
+}
+
+// playLine returns the FEN after up to plies moves of line, in UCI, from
+// fen, stopping at the first that isn't legal
+func playLine(fen string, line []string, plies int) string {
+	fenOpt, err := chess.FEN(fen)
+	if err != nil {
+		return fen
+	}
+	position := chess.NewGame(fenOpt).Position()
+	for _, move := range line[:min(plies, len(line))] {
+		legal, ok := legalUCI(position, move)
+		if !ok {
+			break
+		}
+		position = position.Update(legal)
+	}
+	return position.String()
+}
+
+// materialPhrase names an amount of material in pawns
+func materialPhrase(pawns int) string {
+	switch pawns {
+	case 1:
+		return "a pawn"
+	case 2:
+		return "two pawns"
+	case 3:
+		return "a piece"
+	case 5:
+		return "a rook"
+	case 9:
+		return "the queen"
+	}
+	return fmt.Sprintf("%d pawns of material", pawns)
+}
+
+// pawnsPhrase writes centipawns as pawns to one decimal
+func pawnsPhrase(cp int) string {
+	return fmt.Sprintf("%.1f", float64(cp)/100)
+}
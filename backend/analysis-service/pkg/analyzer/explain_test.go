@@ -0,0 +1,173 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+)
+
+// explainFixture is a classified move from fen, its evaluations given
+// with their PVs
+type explainFixture struct {
+	name           string
+	fen            string
+	played, best   string // UCI
+	before, after  engine.Evaluation
+	cpLoss         int
+	classification MoveClassification
+	forced         bool
+}
+
+func mate(n int) engine.Evaluation {
+	return engine.Evaluation{IsMate: true, MateIn: &n}
+}
+
+func withPV(eval engine.Evaluation, pv ...string) engine.Evaluation {
+	eval.PV = pv
+	return eval
+}
+
+var explainFixtures = []explainFixture{
+	{
+		name: "missed mate", fen: "r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4",
+		played: "d2d3", best: "h5f7",
+		before: withPV(mate(1), "h5f7"), after: withPV(engine.Evaluation{Centipawns: 850}, "f6h5"),
+		cpLoss: 500, classification: ClassMissedWin,
+	},
+	{
+		name: "allows mate", fen: "rnbqkbnr/pppp1ppp/8/4p3/8/5P2/PPPPP1PP/RNBQKBNR w KQkq - 0 2",
+		played: "g2g4", best: "b1c3",
+		before: withPV(engine.Evaluation{Centipawns: -60}, "b1c3"), after: withPV(mate(1), "d8h4"),
+		cpLoss: 500, classification: ClassBlunder,
+	},
+	{
+		name: "drops a pawn", fen: "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+		played: "g1f3", best: "e4d5",
+		before: withPV(engine.Evaluation{Centipawns: 40}, "e4d5", "d8d5", "b1c3"), after: withPV(engine.Evaluation{Centipawns: 70}, "d5e4", "f3g5"),
+		cpLoss: 110, classification: ClassInaccuracy,
+	},
+	{
+		name: "drops a pawn, best wins one", fen: "4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1",
+		played: "e1d2", best: "e4d5",
+		before: withPV(engine.Evaluation{Centipawns: 120}, "e4d5", "e8d7", "e1d2"), after: withPV(engine.Evaluation{Centipawns: 90}, "d5e4", "d2e3"),
+		cpLoss: 210, classification: ClassMistake,
+	},
+	{
+		name: "misses a piece", fen: "4k3/8/8/3n4/8/8/8/3RK3 w - - 0 1",
+		played: "e1e2", best: "d1d5",
+		before: withPV(engine.Evaluation{Centipawns: 350}, "d1d5", "e8e7", "e1e2"), after: withPV(engine.Evaluation{Centipawns: -20}, "d5f4", "e2e3"),
+		cpLoss: 330, classification: ClassBlunder,
+	},
+	{
+		name: "gives up the edge", fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		played: "f2f3", best: "e2e4",
+		before: withPV(engine.Evaluation{Centipawns: 30}, "e2e4", "e7e5", "g1f3"), after: withPV(engine.Evaluation{Centipawns: 30}, "e7e5", "e2e4"),
+		cpLoss: 60, classification: ClassInaccuracy,
+	},
+	{
+		name: "second best", fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		played: "d2d4", best: "e2e4",
+		before: engine.Evaluation{Centipawns: 30}, after: engine.Evaluation{Centipawns: -20},
+		cpLoss: 10, classification: ClassExcellent,
+	},
+	{
+		name: "best", fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		played: "e2e4", best: "e2e4",
+		before: engine.Evaluation{Centipawns: 30}, after: engine.Evaluation{Centipawns: -30},
+		classification: ClassBest,
+	},
+	{
+		name: "forced", fen: "4k3/8/8/8/8/8/3q4/K7 w - - 0 1",
+		played: "a1b1", best: "a1b1",
+		before: engine.Evaluation{Centipawns: -900}, after: engine.Evaluation{Centipawns: 900},
+		classification: ClassBest, forced: true,
+	},
+	{
+		name: "book", fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		played: "c2c4", best: "e2e4",
+		before: engine.Evaluation{Centipawns: 30}, after: engine.Evaluation{Centipawns: -15},
+		cpLoss: 15, classification: ClassBook,
+	},
+}
+
+// move builds the fixture's move analysis
+func (f explainFixture) move(t *testing.T) *MoveAnalysis {
+	t.Helper()
+	after, err := PlayUCI(f.fen, f.played)
+	if err != nil {
+		t.Fatal(err)
+	}
+	played, _ := UCIToSAN(f.fen, f.played)
+	best, _ := UCIToSAN(f.fen, f.best)
+	color := "white"
+	if strings.Fields(f.fen)[1] == "b" {
+		color = "black"
+	}
+	return &MoveAnalysis{
+		Color:          color,
+		PlayedMove:     played,
+		PlayedMoveUCI:  f.played,
+		BestMove:       best,
+		BestMoveUCI:    f.best,
+		FENBefore:      f.fen,
+		FENAfter:       after,
+		EvalBefore:     f.before,
+		EvalAfter:      f.after,
+		PV:             f.before.PV,
+		CentipawnLoss:  f.cpLoss,
+		Classification: f.classification,
+		Forced:         f.forced,
+	}
+}
+
+func TestExplainMove_Golden(t *testing.T) {
+	var b strings.Builder
+	for _, f := range explainFixtures {
+		fmt.Fprintf(&b, "%s: %s\n", f.name, ExplainMove(f.move(t)).Explain())
+	}
+
+	// The fixture game on the fake engine, explained as AnalyzeGame does
+	a := newFakeAnalyzer(t)
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, move := range analysis.Moves {
+		fmt.Fprintf(&b, "ply %d %s: %s\n", move.Ply, move.PlayedMove, move.Explanation)
+	}
+
+	const golden = "testdata/explanations.golden"
+	if *update {
+		if err := os.WriteFile(golden, []byte(b.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != string(want) {
+		t.Errorf("explanations changed; run go test -update if intended\ngot:\n%s", b.String())
+	}
+}
+
+func TestExplainMove_Inputs(t *testing.T) {
+	// The drop of a pawn with a better capture available
+	in := ExplainMove(explainFixtures[3].move(t))
+	want := ExplanationInputs{
+		Kind: ExplainLosesMaterial, Classification: ClassMistake, CentipawnLoss: 210,
+		BestMove: "exd5", Refutation: "dxe4", MaterialLost: 1, MaterialWon: 1,
+	}
+	if in != want {
+		t.Errorf("ExplainMove() = %+v, want %+v", in, want)
+	}
+
+	// Only the template depends on the language
+	if got := (ExplanationInputs{Kind: "unknown"}).Explain(); got != "" {
+		t.Errorf("unknown kind explained as %q", got)
+	}
+}
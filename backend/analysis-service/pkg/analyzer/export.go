@@ -0,0 +1,267 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	"github.com/notnil/chess"
+)
+
+// pgnTagPattern matches a tag pair line; the value keeps its escapes
+var pgnTagPattern = regexp.MustCompile(`^\[\s*(\w+)\s+"((?:[^"\\]|\\.)*)"\s*\]$`)
+
+// sevenTagRoster is the tag order PGN requires at the top of a game
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// criticalMoments are the classifications whose comment shows the best line
+var criticalMoments = map[MoveClassification]bool{
+	ClassMistake:   true,
+	ClassBlunder:   true,
+	ClassMissedWin: true,
+}
+
+// bestLinePlies limits the best line shown on a critical moment
+const bestLinePlies = 8
+
+// ExportAnnotatedPGN merges an analysis into the PGN it was made from. The
+// original tags are kept, an Annotator tag is added and every analyzed move
+// gets an [%eval] comment and the NAG of its classification's glyph, see
+// evaluation.ClassificationDisplay; mistakes, blunders and missed wins also
+// get the engine's best line in SAN. Comments and variations of the
+// original movetext are not carried over.
+//
+// Moves missing from a partial analysis are exported without annotations;
+// a truncated analysis exports only the moves before the invalid one.
+// An ErrInvalidPGN error is returned when the PGN doesn't parse or doesn't
+// match the analysis. Games of any variant are read as standard chess, as
+// their analysis was.
+func ExportAnnotatedPGN(analysis *GameAnalysis, originalPGN string) (string, error) {
+	positions, err := parsePGN(originalPGN)
+	var moveErr *PGNMoveError
+	if err != nil && !(analysis.Truncated && errors.As(err, &moveErr)) {
+		return "", err
+	}
+	tags := parsePGNTags(originalPGN)
+
+	byPly := make(map[int]*MoveAnalysis, len(analysis.Moves))
+	for i := range analysis.Moves {
+		move := &analysis.Moves[i]
+		if move.Ply < 0 || move.Ply+1 >= len(positions) {
+			return "", fmt.Errorf("%w: analysis has ply %d but the game has %d moves", ErrInvalidPGN, move.Ply, len(positions)-1)
+		}
+		if played := positions[move.Ply+1].MoveUCI; move.PlayedMoveUCI != "" && move.PlayedMoveUCI != played {
+			return "", fmt.Errorf("%w: analysis move %s at ply %d does not match %s in the PGN", ErrInvalidPGN, move.PlayedMoveUCI, move.Ply, played)
+		}
+		byPly[move.Ply] = move
+	}
+
+	var b strings.Builder
+	for _, name := range sevenTagRoster {
+		value := tagValue(tags, name)
+		if value == "" {
+			value = "?"
+			if name == "Result" {
+				value = "*"
+			}
+		}
+		fmt.Fprintf(&b, "[%s \"%s\"]\n", name, value)
+	}
+	for _, tag := range tags {
+		if !isRosterTag(tag[0]) && tag[0] != "Annotator" {
+			fmt.Fprintf(&b, "[%s \"%s\"]\n", tag[0], tag[1])
+		}
+	}
+	annotator := "EloInsight"
+	if analysis.EngineVersion != "" {
+		annotator += fmt.Sprintf(" (%s, depth %d)", analysis.EngineVersion, analysis.Depth)
+	}
+	fmt.Fprintf(&b, "[Annotator \"%s\"]\n\n", escapePGN(annotator))
+
+	var tokens []string
+	afterComment := false
+	for ply := 0; ply+1 < len(positions); ply++ {
+		color, moveNumber := PlyMove(positions[0].FEN, ply)
+		switch {
+		case color == "white":
+			tokens = append(tokens, fmt.Sprintf("%d.", moveNumber))
+		case afterComment || ply == 0:
+			tokens = append(tokens, fmt.Sprintf("%d...", moveNumber))
+		}
+		tokens = append(tokens, positions[ply+1].MoveSAN)
+		afterComment = false
+
+		move, ok := byPly[ply]
+		if !ok {
+			continue
+		}
+		if nag := evaluation.ClassificationNAG(evaluation.MoveClassification(move.Classification)); nag != "" {
+			tokens = append(tokens, nag)
+		}
+		if words := moveComment(move); len(words) > 0 {
+			words[0] = "{" + words[0]
+			words[len(words)-1] += "}"
+			tokens = append(tokens, words...)
+			afterComment = true
+		}
+	}
+	result := tagValue(tags, "Result")
+	if result == "" {
+		result = "*"
+	}
+	tokens = append(tokens, result)
+
+	writeWrapped(&b, tokens, 79)
+	return b.String(), nil
+}
+
+// FormatEval returns the evaluation after move in pawns from White's point
+// of view, or "#n" for a forced mate, as used by [%eval] comments. It is
+// empty once the game is over.
+func FormatEval(move *MoveAnalysis) string {
+	eval := move.EvalAfter
+
+	// Engine scores are for the side to move after the move
+	sign := 1
+	if move.Color == "white" {
+		sign = -1
+	}
+
+	if eval.IsMate && eval.MateIn != nil {
+		if *eval.MateIn == 0 {
+			return ""
+		}
+		return fmt.Sprintf("#%d", sign*(*eval.MateIn))
+	}
+	return strconv.FormatFloat(float64(sign*eval.Centipawns)/100, 'f', 2, 64)
+}
+
+// moveComment builds the comment for an analyzed move as words to wrap
+// between, keeping the [%eval] command on one line
+func moveComment(move *MoveAnalysis) []string {
+	var words []string
+	if eval := FormatEval(move); eval != "" {
+		words = append(words, fmt.Sprintf("[%%eval %s]", eval))
+	}
+	if criticalMoments[move.Classification] && !SameMove(move.BestMoveUCI, move.PlayedMoveUCI) {
+		_, _, label := evaluation.ClassificationDisplay(evaluation.MoveClassification(move.Classification))
+		text := label + "."
+		if line := bestLineSAN(move.FENBefore, move.PV, bestLinePlies); line != "" {
+			text += " Best line: " + line
+		} else if move.BestMove != "" {
+			text += " " + move.BestMove + " was best."
+		}
+		words = append(words, strings.Fields(text)...)
+	}
+	return words
+}
+
+// bestLineSAN converts a UCI principal variation from fen into numbered
+// SAN, e.g. "3. Nf3 Nc6 4. Bb5". It stops at the first illegal move.
+func bestLineSAN(fen string, pv []string, maxPlies int) string {
+	if len(pv) == 0 {
+		return ""
+	}
+	start := decodePosition(fen)
+	if start.err != nil {
+		return ""
+	}
+	pos := start.position
+
+	moveNumber := 1
+	if fields := strings.Fields(fen); len(fields) == 6 {
+		if n, err := strconv.Atoi(fields[5]); err == nil {
+			moveNumber = n
+		}
+	}
+
+	var out []string
+	for i, uci := range pv {
+		if i == maxPlies {
+			break
+		}
+		move, ok := legalUCI(pos, uci)
+		if !ok {
+			break
+		}
+		white := pos.Turn() == chess.White
+		switch {
+		case white:
+			out = append(out, fmt.Sprintf("%d.", moveNumber))
+		case i == 0:
+			out = append(out, fmt.Sprintf("%d...", moveNumber))
+		}
+		out = append(out, chess.AlgebraicNotation{}.Encode(pos, move))
+		// Updating the position alone skips the game's repetition count
+		pos = pos.Update(move)
+		if !white {
+			moveNumber++
+		}
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	return strings.Join(out, " ")
+}
+
+// parsePGNTags returns the tag pairs at the top of a PGN, in order
+func parsePGNTags(pgn string) [][2]string {
+	var tags [][2]string
+	for _, line := range strings.Split(pgn, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		m := pgnTagPattern.FindStringSubmatch(line)
+		if m == nil {
+			break
+		}
+		tags = append(tags, [2]string{m[1], m[2]})
+	}
+	return tags
+}
+
+func tagValue(tags [][2]string, name string) string {
+	for _, tag := range tags {
+		if tag[0] == name {
+			return tag[1]
+		}
+	}
+	return ""
+}
+
+func isRosterTag(name string) bool {
+	for _, n := range sevenTagRoster {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// escapePGN escapes a tag value
+func escapePGN(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// writeWrapped writes space-separated tokens in lines of at most width
+// characters, as the PGN export format asks for
+func writeWrapped(b *strings.Builder, tokens []string, width int) {
+	lineLen := 0
+	for _, token := range tokens {
+		if lineLen > 0 && lineLen+1+len(token) > width {
+			b.WriteString("\n")
+			lineLen = 0
+		}
+		if lineLen > 0 {
+			b.WriteString(" ")
+			lineLen++
+		}
+		b.WriteString(token)
+		lineLen += len(token)
+	}
+	b.WriteString("\n")
+}
@@ -0,0 +1,152 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/notnil/chess"
+)
+
+const testPGNWithTags = `[Event "Casual Game"]
+[White "Alice"]
+[Black "Bob"]
+[Result "1-0"]
+[ECO "C78"]
+[Annotator "Someone"]
+
+` + "1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 Nf6 5. O-O Be7 6. Re1 b5 7. Bb3 d6 1-0"
+
+func TestExportAnnotatedPGN_RoundTrip(t *testing.T) {
+	a := newFakeAnalyzer(t)
+
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", testPGNWithTags, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 3...a6 becomes a blunder with 3...Nf6 4. O-O as the best line
+	blunder := &analysis.Moves[5]
+	blunder.Classification = ClassBlunder
+	blunder.BestMove = "Nf6"
+	blunder.BestMoveUCI = "g8f6"
+	blunder.PV = []string{"g8f6", "e1g1"}
+	analysis.Moves[2].Classification = ClassBrilliant
+
+	out, err := ExportAnnotatedPGN(analysis, testPGNWithTags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flat := strings.Join(strings.Fields(out), " ")
+	for _, want := range []string{
+		`[Event "Casual Game"]`,
+		`[Site "?"]`,
+		`[ECO "C78"]`,
+		`[Annotator "EloInsight (FakeFish, depth 12)"]`,
+		"Nf3 $3 {[%eval ",
+		"a6 $4 {[%eval ",
+		"Blunder. Best line: 3... Nf6 4. O-O}",
+	} {
+		if !strings.Contains(flat, want) {
+			t.Errorf("export is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Someone") {
+		t.Errorf("original annotator was kept:\n%s", out)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) > 79 {
+			t.Errorf("line longer than 79 characters: %q", line)
+		}
+	}
+
+	pgn, err := chess.PGN(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("exported PGN does not parse: %v\n%s", err, out)
+	}
+	game := chess.NewGame(pgn)
+	if got := len(game.Moves()); got != 14 {
+		t.Errorf("moves = %d, want 14", got)
+	}
+	if got := game.GetTagPair("White"); got == nil || got.Value != "Alice" {
+		t.Errorf("White tag = %v, want Alice", got)
+	}
+	if game.Outcome() != chess.WhiteWon {
+		t.Errorf("outcome = %s, want 1-0", game.Outcome())
+	}
+}
+
+func TestExportAnnotatedPGN_Mismatch(t *testing.T) {
+	analysis := &GameAnalysis{Moves: []MoveAnalysis{{Ply: 0, PlayedMoveUCI: "d2d4"}}}
+	if _, err := ExportAnnotatedPGN(analysis, testPGN); !errors.Is(err, ErrInvalidPGN) {
+		t.Errorf("err = %v, want ErrInvalidPGN", err)
+	}
+
+	analysis = &GameAnalysis{Moves: []MoveAnalysis{{Ply: 40}}}
+	if _, err := ExportAnnotatedPGN(analysis, testPGN); !errors.Is(err, ErrInvalidPGN) {
+		t.Errorf("err = %v, want ErrInvalidPGN", err)
+	}
+}
+
+func TestExportAnnotatedPGN_PartialAnalysis(t *testing.T) {
+	analysis := &GameAnalysis{Moves: []MoveAnalysis{
+		{Ply: 0, Color: "white", PlayedMoveUCI: "e2e4", EvalAfter: engine.Evaluation{Centipawns: -30}},
+	}}
+	out, err := ExportAnnotatedPGN(analysis, testPGN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "1. e4 {[%eval 0.30]} 1... e5 2. Nf3 Nc6") {
+		t.Errorf("unexpected movetext:\n%s", out)
+	}
+	if !strings.HasSuffix(out, "d6 *\n") {
+		t.Errorf("export should end with the result:\n%s", out)
+	}
+}
+
+func TestFormatEval(t *testing.T) {
+	mate := func(n int) *int { return &n }
+	tests := []struct {
+		name string
+		move MoveAnalysis
+		want string
+	}{
+		{"white move", MoveAnalysis{Color: "white", EvalAfter: engine.Evaluation{Centipawns: -45}}, "0.45"},
+		{"black move", MoveAnalysis{Color: "black", EvalAfter: engine.Evaluation{Centipawns: -120}}, "-1.20"},
+		{"mate for white", MoveAnalysis{Color: "white", EvalAfter: engine.Evaluation{IsMate: true, MateIn: mate(-2)}}, "#2"},
+		{"mate for black", MoveAnalysis{Color: "white", EvalAfter: engine.Evaluation{IsMate: true, MateIn: mate(3)}}, "#-3"},
+		{"checkmate", MoveAnalysis{Color: "black", EvalAfter: engine.Evaluation{IsMate: true, MateIn: mate(0)}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatEval(&tt.move); got != tt.want {
+				t.Errorf("FormatEval() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestLineSAN(t *testing.T) {
+	fen := "r1bqkbnr/1ppp1ppp/p1n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 0 4"
+	tests := []struct {
+		name string
+		pv   []string
+		max  int
+		want string
+	}{
+		{"numbered", []string{"b5a4", "g8f6", "e1g1"}, 8, "4. Ba4 Nf6 5. O-O"},
+		{"limited", []string{"b5a4", "g8f6", "e1g1"}, 2, "4. Ba4 Nf6"},
+		{"stops at illegal move", []string{"b5c6", "e2e4"}, 8, "4. Bxc6"},
+		{"empty", nil, 8, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bestLineSAN(fen, tt.pv, tt.max); got != tt.want {
+				t.Errorf("bestLineSAN() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+)
+
+// maxSearchRetries is how many more times a game position whose search
+// failed is searched, each time on another engine, before it is given up
+const maxSearchRetries = 2
+
+// FailureKind classifies a failed engine search
+type FailureKind string
+
+const (
+	FailureEngineDied    FailureKind = "engine_died"
+	FailureTimeout       FailureKind = "timeout"
+	FailureInvalidOutput FailureKind = "invalid_output"
+	FailureOther         FailureKind = "other"
+)
+
+// classifyFailure returns the FailureKind of a search error
+func classifyFailure(err error) FailureKind {
+	switch {
+	case errors.Is(err, engine.ErrEngineDied):
+		return FailureEngineDied
+	case errors.Is(err, engine.ErrInvalidOutput):
+		return FailureInvalidOutput
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, ErrTimeout):
+		return FailureTimeout
+	}
+	return FailureOther
+}
+
+// Diagnostics records the engine failures of a game analysis. Searches
+// stopped because the game's budget ran out are not failures; TimedOut
+// covers them.
+type Diagnostics struct {
+	Retries  int                 // Searches repeated after a failure
+	Failures map[FailureKind]int // Failed searches by kind, retried or not
+
+	// FailedPositions are the indexes of the positions (0 = the starting
+	// position) still failing after every retry; the moves into and out
+	// of them are left out of the analysis
+	FailedPositions []int
+}
+
+// add counts the retries and failures of a position's searches
+func (d *Diagnostics) add(result positionResult) {
+	d.Retries += result.retries
+	for _, kind := range result.failures {
+		if d.Failures == nil {
+			d.Failures = make(map[FailureKind]int)
+		}
+		d.Failures[kind]++
+	}
+}
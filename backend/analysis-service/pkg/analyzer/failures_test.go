@@ -0,0 +1,172 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"go.uber.org/zap"
+)
+
+// e4FEN is the position after 1. e4
+const e4FEN = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+
+// newFailingAnalyzer returns an analyzer on a pool of two engines that
+// fail on the position after 1. e4, as mode: the first engine asked
+// crashing once, or every engine answering it with garbage
+func newFailingAnalyzer(t *testing.T, mode enginetest.FailureMode) (*Analyzer, *pool.Pool) {
+	t.Helper()
+	failure := enginetest.Failure{Mode: mode, FEN: e4FEN}
+	if mode == enginetest.Crash {
+		failure.Times = 1
+	}
+	fake := enginetest.Engine{Default: enginetest.Eval{Centipawns: 25}, Failures: []enginetest.Failure{failure}}
+	p, err := pool.NewPool(2, engine.Config{BinaryPath: fake.Binary(t), Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return NewAnalyzer(p, zap.NewNop(), 1, 20, 30, time.Minute), p
+}
+
+func TestAnalyzeGame_RetriesDeadEngine(t *testing.T) {
+	a, p := newFailingAnalyzer(t, enginetest.Crash)
+
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", "1. e4 e5 2. Nf3 *", 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Moves) != 3 {
+		t.Fatalf("got %d moves, want all 3 after the retry", len(analysis.Moves))
+	}
+	want := Diagnostics{Retries: 1, Failures: map[FailureKind]int{FailureEngineDied: 1}}
+	if !reflect.DeepEqual(analysis.Diagnostics, want) {
+		t.Errorf("diagnostics = %+v, want %+v", analysis.Diagnostics, want)
+	}
+
+	// The dead engine was replaced, not returned to the pool
+	if stats := p.GetStats(); stats.Available != 2 || stats.InUse != 0 {
+		t.Errorf("pool after the game: %d available, %d in use; want 2, 0", stats.Available, stats.InUse)
+	}
+	if _, err := a.AnalyzePosition(context.Background(), "rnbqkbnr/pppp1ppp/8/4p3/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 2", 12, 1); err != nil {
+		t.Errorf("pool unusable after the game: %v", err)
+	}
+}
+
+func TestAnalyzeGame_GivesUpAfterRetries(t *testing.T) {
+	a, _ := newFailingAnalyzer(t, enginetest.Garbage)
+
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", "1. e4 e5 2. Nf3 *", 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Diagnostics{
+		Retries:         maxSearchRetries,
+		Failures:        map[FailureKind]int{FailureInvalidOutput: maxSearchRetries + 1},
+		FailedPositions: []int{1},
+	}
+	if !reflect.DeepEqual(analysis.Diagnostics, want) {
+		t.Errorf("diagnostics = %+v, want %+v", analysis.Diagnostics, want)
+	}
+	// Both moves around the position after 1. e4 are left out
+	if len(analysis.Moves) != 1 || analysis.Moves[0].Ply != 2 {
+		t.Errorf("moves = %d, want only 2. Nf3", len(analysis.Moves))
+	}
+}
+
+func TestAnalyzeGame_ConcurrentCrashes(t *testing.T) {
+	// Every engine started crashes on its second search, twice in all;
+	// four games share the pool's two engines meanwhile
+	fake := enginetest.Engine{Failures: []enginetest.Failure{{Mode: enginetest.Crash, Search: 2, Times: 2}}}
+	p, err := pool.NewPool(2, engine.Config{BinaryPath: fake.Binary(t), Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	a := NewAnalyzer(p, zap.NewNop(), 1, 20, 30, time.Minute)
+
+	pgns := []string{
+		"1. e4 e5 2. Nf3 Nc6 3. Bb5 *",
+		"1. d4 d5 2. c4 e6 3. Nc3 *",
+		"1. c4 e5 2. Nc3 Nf6 3. g3 *",
+		"1. Nf3 d5 2. g3 Nf6 3. Bg2 *",
+	}
+	analyses := make([]*GameAnalysis, len(pgns))
+	errs := make([]error, len(pgns))
+	var wg sync.WaitGroup
+	for i, pgn := range pgns {
+		wg.Add(1)
+		go func(i int, pgn string) {
+			defer wg.Done()
+			analyses[i], errs[i] = a.AnalyzeGame(context.Background(), fmt.Sprintf("g%d", i), pgn, 12, GameOptions{}, nil)
+		}(i, pgn)
+	}
+	wg.Wait()
+
+	retries, died := 0, 0
+	for i, analysis := range analyses {
+		if errs[i] != nil {
+			t.Fatalf("game %d: %v", i, errs[i])
+		}
+		if len(analysis.Moves) != 5 || len(analysis.Diagnostics.FailedPositions) != 0 {
+			t.Errorf("game %d: %d moves, failed positions %v; want all 5 after retries", i, len(analysis.Moves), analysis.Diagnostics.FailedPositions)
+		}
+		retries += analysis.Diagnostics.Retries
+		died += analysis.Diagnostics.Failures[FailureEngineDied]
+	}
+	if retries != 2 || died != 2 {
+		t.Errorf("%d retries of %d dead engines, want 2 of 2", retries, died)
+	}
+	if stats := p.GetStats(); p.Engines() != 2 || stats.Available != 2 || stats.InUse != 0 {
+		t.Errorf("pool after the games: %d engines, %d available, %d in use", p.Engines(), stats.Available, stats.InUse)
+	}
+}
+
+func TestAnalyzePosition_HungEngine(t *testing.T) {
+	fake := enginetest.Engine{Failures: []enginetest.Failure{{Mode: enginetest.Hang, FEN: e4FEN, Times: 1}}}
+	p, err := pool.NewPool(1, engine.Config{BinaryPath: fake.Binary(t), Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	a := NewAnalyzer(p, zap.NewNop(), 1, 20, 30, 100*time.Millisecond)
+
+	// The engine ignores "stop" at the deadline, so it is killed and
+	// replaced rather than holding the pool's only engine
+	start := time.Now()
+	if _, err := a.AnalyzePosition(context.Background(), e4FEN, 12, 1); !errors.Is(err, ErrEngineFailure) {
+		t.Fatalf("hung search = %v, want ErrEngineFailure", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("hung search returned after %v", elapsed)
+	}
+	if result, err := a.AnalyzePosition(context.Background(), e4FEN, 12, 1); err != nil || result.Source != engine.SourceEngine {
+		t.Errorf("search on the replacement = %+v, %v", result, err)
+	}
+	if p.Engines() != 1 {
+		t.Errorf("pool of %d engines, want 1", p.Engines())
+	}
+}
+
+func TestClassifyFailure(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want FailureKind
+	}{
+		{fmt.Errorf("%w: output ended before bestmove", engine.ErrEngineDied), FailureEngineDied},
+		{fmt.Errorf("%w: no evaluation before bestmove", engine.ErrInvalidOutput), FailureInvalidOutput},
+		{context.DeadlineExceeded, FailureTimeout},
+		{fmt.Errorf("boom"), FailureOther},
+	} {
+		if got := classifyFailure(tt.err); got != tt.want {
+			t.Errorf("classifyFailure(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
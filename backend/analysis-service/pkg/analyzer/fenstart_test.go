@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Games set up from a FEN: a study with Black to move first and a rook
+// ending taken up at move 27
+const (
+	blackToMoveStudyPGN = `[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1"]
+
+1... Kd7 2. e4 Ke6 3. Ke2 *`
+	move27FragmentPGN = `[Variant "From Position"]
+[FEN "8/5pk1/6p1/8/3R4/6P1/r4PK1/8 b - - 0 27"]
+
+27... Kf6 28. Rd7 Ke6 29. Rd1 *`
+)
+
+func TestPlyMove(t *testing.T) {
+	for _, tt := range []struct {
+		fen        string
+		ply        int
+		color      string
+		moveNumber int
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 0, "white", 1},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 3, "black", 2},
+		{"4k3/8/8/8/8/8/4P3/4K3 b - - 0 1", 0, "black", 1},
+		{"4k3/8/8/8/8/8/4P3/4K3 b - - 0 1", 1, "white", 2},
+		{"8/5pk1/6p1/8/3R4/6P1/r4PK1/8 w - - 0 27", 1, "black", 27},
+		{"8/5pk1/6p1/8/3R4/6P1/r4PK1/8 b - - 0 27", 2, "black", 28},
+		{"8/5pk1/6p1/8/3R4/6P1/r4PK1/8 b - -", 0, "black", 1}, // No counters
+	} {
+		color, moveNumber := PlyMove(tt.fen, tt.ply)
+		if color != tt.color || moveNumber != tt.moveNumber {
+			t.Errorf("PlyMove(%s, %d) = %s %d, want %s %d", tt.fen, tt.ply, color, moveNumber, tt.color, tt.moveNumber)
+		}
+	}
+}
+
+func TestParsePGN_FENStart(t *testing.T) {
+	positions, err := ParsePGN(move27FragmentPGN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 5 || positions[0].FEN != "8/5pk1/6p1/8/3R4/6P1/r4PK1/8 b - - 0 27" {
+		t.Fatalf("positions = %+v, want the FEN and 4 moves", positions)
+	}
+	if got := StartingFEN(move27FragmentPGN); got != positions[0].FEN {
+		t.Errorf("StartingFEN = %q", got)
+	}
+
+	// A SetUp of 0 ignores the FEN
+	if positions, err := ParsePGN("[SetUp \"0\"]\n[FEN \"4k3/8/8/8/8/8/4P3/4K3 b - - 0 1\"]\n\n1. e4 *"); err != nil || len(positions) != 2 {
+		t.Errorf("SetUp 0: %d positions, %v", len(positions), err)
+	}
+	if _, err := ParsePGN("[FEN \"not a fen\"]\n\n1. e4 *"); err == nil || !strings.Contains(err.Error(), "FEN tag") {
+		t.Errorf("bad FEN tag: err = %v", err)
+	}
+
+	// A bad move is numbered from the FEN
+	_, err = ParsePGN(`[FEN "8/5pk1/6p1/8/3R4/6P1/r4PK1/8 b - - 0 27"]
+
+27... Kf6 28. Rd8 Ke7 29. Rd9 *`)
+	var moveErr *PGNMoveError
+	if !errors.As(err, &moveErr) || moveErr.MoveNumber != 29 || moveErr.Color != "white" {
+		t.Errorf("err = %v, want a PGNMoveError at 29. Rd9", err)
+	}
+}
+
+func TestAnalyzeGame_FENStart(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	for _, tt := range []struct {
+		name  string
+		pgn   string
+		moves []string // Numbered as counted by hand
+		white int      // Moves of each color
+		black int
+	}{
+		{"black-to-move study", blackToMoveStudyPGN, []string{"1... Kd7", "2. e4", "2... Ke6", "3. Ke2"}, 2, 2},
+		{"fragment from move 27", move27FragmentPGN, []string{"27... Kf6", "28. Rd7", "28... Ke6", "29. Rd1"}, 2, 2},
+		{"fragment, White first", strings.Replace(move27FragmentPGN, " b - - 0 27", " w - - 0 27", 1), nil, 0, 0},
+	} {
+		if tt.moves == nil {
+			// White to move from the same position: 27... Kf6 is now White's turn
+			if _, err := a.AnalyzeGame(context.Background(), "g1", tt.pgn, 12, GameOptions{}, nil); err == nil {
+				t.Errorf("%s: analyzed Black's move as White's", tt.name)
+			}
+			continue
+		}
+		analysis, err := a.AnalyzeGame(context.Background(), "g1", tt.pgn, 12, GameOptions{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(analysis.Moves) != len(tt.moves) {
+			t.Fatalf("%s: %d moves, want %d", tt.name, len(analysis.Moves), len(tt.moves))
+		}
+		white, black := 0, 0
+		for i, move := range analysis.Moves {
+			label := (&PGNMoveError{MoveNumber: move.MoveNumber, Color: move.Color, SAN: move.PlayedMove}).label()
+			if label != tt.moves[i] {
+				t.Errorf("%s: move %d is %q, want %q", tt.name, i, label, tt.moves[i])
+			}
+			if move.Color == "white" {
+				white++
+			} else {
+				black++
+			}
+		}
+		if white != tt.white || black != tt.black {
+			t.Errorf("%s: %d white and %d black moves, want %d and %d", tt.name, white, black, tt.white, tt.black)
+		}
+		if analysis.WhiteMetrics.TotalMoves != tt.white || analysis.BlackMetrics.TotalMoves != tt.black {
+			t.Errorf("%s: metrics count %d white and %d black moves, want %d and %d",
+				tt.name, analysis.WhiteMetrics.TotalMoves, analysis.BlackMetrics.TotalMoves, tt.white, tt.black)
+		}
+		out, err := ExportAnnotatedPGN(analysis, tt.pgn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		flat := strings.Join(strings.Fields(out), " ")
+		if !strings.Contains(flat, tt.moves[0]+" ") {
+			t.Errorf("%s: export doesn't start with %q:\n%s", tt.name, tt.moves[0], out)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+func cp(n int) engine.Evaluation {
+	return engine.Evaluation{Centipawns: n}
+}
+
+func TestCentipawnLoss_Mates(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after engine.Evaluation // Mover's, then opponent's point of view
+		want          int
+	}{
+		{"kept the mate", mate(3), mate(-2), 0},
+		{"lengthened the mate", mate(2), mate(-4), 2},
+		{"delivered mate", mate(1), mate(0), 0},
+		{"let the mate go", mate(2), cp(-300), 500},
+		{"had mate, allowed mate", mate(2), mate(1), 500},
+		{"allowed mate", cp(-30), mate(1), 500},
+		{"escaped the mate", mate(-3), cp(-50), 0},
+		{"shortened the mate against them", mate(-5), mate(1), 4},
+		{"held out against the mate", mate(-3), mate(2), 1},
+		{"no mate", cp(60), cp(40), 100},
+	}
+	for _, tt := range tests {
+		if got := centipawnLoss(tt.before, tt.after); got != tt.want {
+			t.Errorf("%s: loss = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// gameEndFixture is a game with the evaluation and best move of each
+// position but the last, from the side to move's point of view
+type gameEndFixture struct {
+	name  string
+	pgn   string
+	evals []engine.Evaluation
+	best  []string // UCI
+
+	// The classifications and losses of the last moves, in order
+	last   []MoveClassification
+	losses []int
+}
+
+var gameEndFixtures = []gameEndFixture{
+	{
+		// Scholar's mate: 3...Nf6 lets Qxf7 mate
+		name:   "checkmate",
+		pgn:    "1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7# 1-0",
+		evals:  []engine.Evaluation{cp(30), cp(-30), cp(30), cp(-30), cp(30), cp(-30), mate(1)},
+		best:   []string{"e2e4", "e7e5", "g1f3", "b8c6", "d1h5", "g7g6", "h5f7"},
+		last:   []MoveClassification{ClassBest, ClassBlunder, ClassBest},
+		losses: []int{0, 500, 0},
+	},
+	{
+		// The search before the mating move missed it: mating is still best
+		name:   "checkmate missed by the search",
+		pgn:    "1. f3 e5 2. g4 Qh4# 0-1",
+		evals:  []engine.Evaluation{cp(30), cp(-40), cp(-60), cp(60)},
+		best:   []string{"e2e4", "d7d5", "d2d4", "d7d5"},
+		last:   []MoveClassification{ClassBest, ClassBest},
+		losses: []int{0, 0},
+	},
+	{
+		// Sam Loyd's ten move stalemate: stalemating a won game throws it away
+		name: "stalemate",
+		pgn:  "1. e3 a5 2. Qh5 Ra6 3. Qxa5 h5 4. h4 Rah6 5. Qxc7 f6 6. Qxd7+ Kf7 7. Qxb7 Qd3 8. Qxb8 Qh7 9. Qxc8 Kg6 10. Qe6 1/2-1/2",
+		evals: []engine.Evaluation{
+			cp(30), cp(-30), cp(30), cp(-30), cp(30), cp(-330), cp(330), cp(-330), cp(330), cp(-430),
+			cp(430), cp(-430), cp(430), cp(-730), cp(730), cp(-730), cp(730), cp(-750), cp(750),
+		},
+		last:   []MoveClassification{ClassBest, ClassBest, ClassBlunder},
+		losses: []int{0, 0, 750},
+	},
+	{
+		// White resigns facing Nf3 mate after 7. Be2: the last position
+		// has moves but nobody played one
+		name:   "resignation",
+		pgn:    "1. e4 e5 2. Nf3 Nc6 3. Bc4 Nd4 4. Nxe5 Qg5 5. Nxf7 Qxg2 6. Rf1 Qxe4+ 7. Be2 0-1",
+		evals:  []engine.Evaluation{cp(30), cp(-30), cp(30), cp(-30), cp(30), cp(-30), cp(200), cp(-250), cp(200), cp(-600), cp(600), cp(-600), cp(-650), mate(1)},
+		best:   []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1c4", "f8c5", "e5d4", "g8f6", "e5f7", "g5g2", "h1f1", "g2e4", "d1e2", "d4f3"},
+		last:   []MoveClassification{ClassBest, ClassBlunder},
+		losses: []int{0, 500},
+	},
+}
+
+// analyzeFixture analyzes fx's game from its evaluations, cached deeper
+// than the analysis asks for, and returns it with the game's positions
+func analyzeFixture(t *testing.T, fx gameEndFixture) (*GameAnalysis, []Position) {
+	t.Helper()
+	a := newFakeAnalyzer(t)
+	positions, err := ParsePGN(fx.pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, eval := range fx.evals {
+		eval.Depth = 20
+		var best string
+		if i < len(fx.best) {
+			best = fx.best[i]
+		}
+		a.posCache.Set(PrimaryEngine, positions[i].FEN, 20, eval, best, engine.SourceEngine)
+	}
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", fx.pgn, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return analysis, positions
+}
+
+func TestAnalyzeGame_GameEnds(t *testing.T) {
+	for _, fx := range gameEndFixtures {
+		t.Run(fx.name, func(t *testing.T) {
+			analysis, positions := analyzeFixture(t, fx)
+
+			// No move is analyzed from the final position, however the game ended
+			if len(analysis.Moves) != len(positions)-1 {
+				t.Fatalf("%d moves analyzed, want %d", len(analysis.Moves), len(positions)-1)
+			}
+			tail := analysis.Moves[len(analysis.Moves)-len(fx.last):]
+			for i, move := range tail {
+				if move.Classification != fx.last[i] || move.CentipawnLoss != fx.losses[i] {
+					t.Errorf("%s: %s, loss %d, want %s, loss %d", move.PlayedMove,
+						move.Classification, move.CentipawnLoss, fx.last[i], fx.losses[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCalculateMetrics_MatedSideNotCredited(t *testing.T) {
+	analysis, _ := analyzeFixture(t, gameEndFixtures[0])
+	if analysis.BlackMetrics.Blunders != 1 || analysis.WhiteMetrics.Blunders != 0 {
+		t.Errorf("blunders: white %d, black %d, want 0 and 1",
+			analysis.WhiteMetrics.Blunders, analysis.BlackMetrics.Blunders)
+	}
+	if analysis.WhiteMetrics.Accuracy <= analysis.BlackMetrics.Accuracy {
+		t.Errorf("accuracy: white %.1f, black %.1f, want the mating side ahead",
+			analysis.WhiteMetrics.Accuracy, analysis.BlackMetrics.Accuracy)
+	}
+	if got := analysis.Moves[len(analysis.Moves)-1].MoveAccuracy; got != 100 {
+		t.Errorf("mating move accuracy = %v, want 100", got)
+	}
+	if analysis.Moves[len(analysis.Moves)-1].CentipawnLoss > evaluation.DefaultThresholds.Best {
+		t.Error("mating move lost centipawns")
+	}
+}
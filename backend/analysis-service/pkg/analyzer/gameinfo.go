@@ -0,0 +1,85 @@
+package analyzer
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+// GameInfo is what a PGN's tag pairs say about the game. A tag that is
+// missing, "?" or, for ratings, not a positive number is left unset: ""
+// or 0.
+type GameInfo struct {
+	White       string
+	Black       string
+	WhiteElo    int
+	BlackElo    int
+	Result      string // "1-0", "0-1" or "1/2-1/2"; unset for "*"
+	TimeControl string // As in the tag, e.g. "180+2", see ParseTimeControl
+	Date        string // As in the tag, e.g. "2024.03.01"; parts may be "??"
+}
+
+// ParseGameInfo reads the players, ratings, result, time control and date
+// from pgn's tag pairs. It doesn't look at the movetext, so it never fails.
+func ParseGameInfo(pgn string) GameInfo {
+	tags := parsePGNTags(pgn)
+	info := GameInfo{
+		White:       knownTag(tags, "White"),
+		Black:       knownTag(tags, "Black"),
+		WhiteElo:    parseElo(tagValue(tags, "WhiteElo")),
+		BlackElo:    parseElo(tagValue(tags, "BlackElo")),
+		TimeControl: knownTag(tags, "TimeControl"),
+	}
+	if result := tagValue(tags, "Result"); result != "*" && pgnResults[result] {
+		info.Result = result
+	}
+	if date := knownTag(tags, "Date"); date != "????.??.??" {
+		info.Date = date
+	}
+	return info
+}
+
+// knownTag returns the value of tags' name tag, "" when it is missing,
+// empty or "?", PGN's unknown value; "-" is the TimeControl tag's
+func knownTag(tags [][2]string, name string) string {
+	value := strings.TrimSpace(tagValue(tags, name))
+	if value == "?" || value == "-" {
+		return ""
+	}
+	return value
+}
+
+// parseElo reads a rating tag, 0 unless it is a positive number
+func parseElo(tag string) int {
+	elo, err := strconv.Atoi(strings.TrimSpace(tag))
+	if err != nil || elo <= 0 {
+		return 0
+	}
+	return elo
+}
+
+// playerResult converts a result in PGN notation to color's: unknown for
+// "*", an empty or a malformed result
+func playerResult(result, color string) evaluation.GameResult {
+	switch result {
+	case "1-0", "0-1":
+		if (result == "1-0") == (color == "white") {
+			return evaluation.ResultWin
+		}
+		return evaluation.ResultLoss
+	case "1/2-1/2":
+		return evaluation.ResultDraw
+	}
+	return evaluation.ResultUnknown
+}
+
+// performanceRating returns the performance rating of metrics' player
+// against an opponent rated opponentElo, 0 when the rating or the result,
+// in PGN notation, isn't known or the player made no move
+func performanceRating(metrics GameMetrics, color string, opponentElo int, result string) int {
+	if metrics.TotalMoves == 0 {
+		return 0
+	}
+	return evaluation.CalculatePerformanceRating(opponentElo, metrics.Accuracy, playerResult(result, color))
+}
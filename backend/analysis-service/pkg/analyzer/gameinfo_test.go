@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+func TestParseGameInfo(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		pgn  string
+		want GameInfo
+	}{
+		{
+			"every tag",
+			"[White \"Alice\"]\n[Black \"Bob\"]\n[WhiteElo \"1850\"]\n[BlackElo \"1720\"]\n" +
+				"[Result \"1/2-1/2\"]\n[TimeControl \"180+2\"]\n[Date \"2024.03.01\"]\n\n1. e4 e5 1/2-1/2",
+			GameInfo{White: "Alice", Black: "Bob", WhiteElo: 1850, BlackElo: 1720,
+				Result: "1/2-1/2", TimeControl: "180+2", Date: "2024.03.01"},
+		},
+		{
+			"unknown values",
+			"[White \"?\"]\n[Black \"Bob\"]\n[WhiteElo \"?\"]\n[BlackElo \"-\"]\n" +
+				"[Result \"*\"]\n[TimeControl \"-\"]\n[Date \"????.??.??\"]\n\n1. e4 *",
+			GameInfo{Black: "Bob"},
+		},
+		{
+			"malformed ratings",
+			"[WhiteElo \"1850a\"]\n[BlackElo \"0\"]\n[Result \"2-0\"]\n\n1. e4",
+			GameInfo{},
+		},
+		{"no tags", "1. e4 e5", GameInfo{}},
+		{"partial date", "[Date \"2024.??.??\"]\n\n1. e4", GameInfo{Date: "2024.??.??"}},
+	} {
+		if got := ParseGameInfo(tt.pgn); got != tt.want {
+			t.Errorf("%s: ParseGameInfo = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeGame_PerformanceRating(t *testing.T) {
+	a := newFakeAnalyzer(t)
+
+	for _, tt := range []struct {
+		name         string
+		tags         string
+		white, black bool // Performance rating expected
+	}{
+		{"both rated", "[WhiteElo \"1800\"]\n[BlackElo \"1600\"]\n[Result \"0-1\"]\n\n", true, true},
+		{"white unrated", "[WhiteElo \"?\"]\n[BlackElo \"1600\"]\n[Result \"0-1\"]\n\n", true, false},
+		{"unfinished", "[WhiteElo \"1800\"]\n[BlackElo \"1600\"]\n[Result \"*\"]\n\n", false, false},
+		{"no result tag", "[WhiteElo \"1800\"]\n[BlackElo \"1600\"]\n\n", false, false},
+		{"malformed result", "[WhiteElo \"1800\"]\n[BlackElo \"1600\"]\n[Result \"1:0\"]\n\n", false, false},
+		{"no tags", "", false, false},
+	} {
+		analysis, err := a.AnalyzeGame(context.Background(), "g1", tt.tags+testPGN, 12, GameOptions{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		white, black := analysis.WhiteMetrics, analysis.BlackMetrics
+
+		wantWhite, wantBlack := 0, 0
+		if tt.white {
+			// White's is against Black's rating
+			wantWhite = evaluation.CalculatePerformanceRating(1600, white.Accuracy, evaluation.ResultLoss)
+		}
+		if tt.black {
+			wantBlack = evaluation.CalculatePerformanceRating(1800, black.Accuracy, evaluation.ResultWin)
+		}
+		if white.PerformanceRating != wantWhite || black.PerformanceRating != wantBlack {
+			t.Errorf("%s: performance ratings %d and %d, want %d and %d",
+				tt.name, white.PerformanceRating, black.PerformanceRating, wantWhite, wantBlack)
+		}
+		// An unknown result isn't a draw, and leaves the rest of the metrics
+		if !tt.white && !tt.black && (white.Resilience != nil || white.TotalMoves != 7 || white.Accuracy == 0) {
+			t.Errorf("%s: white metrics %+v, want accuracy and moves without resilience", tt.name, white)
+		}
+		if tt.black && wantBlack == 0 {
+			t.Errorf("%s: want a non-zero performance rating", tt.name)
+		}
+		if tt.name == "both rated" && (analysis.Info.WhiteElo != 1800 || analysis.Info.Result != "0-1") {
+			t.Errorf("%s: game info %+v", tt.name, analysis.Info)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package analyzer
+
+import "github.com/notnil/chess"
+
+// MoveSquares are the squares of a move for the board to highlight
+type MoveSquares struct {
+	From      string // e.g. "e1"
+	To        string // For castling the king's destination, g or c file
+	Promotion string // "q", "r", "b" or "n"; empty if not a promotion
+
+	// RookFrom and RookTo are the rook's squares when the move castles
+	RookFrom string
+	RookTo   string
+
+	// Captured is the square of the piece the move takes, the passed
+	// pawn's for en passant; empty if it takes nothing
+	Captured string
+}
+
+// Highlights are the squares of an analyzed move for the board to show:
+// where the best move went, how the opponent refutes the move played and
+// which of the mover's pieces that wins
+type Highlights struct {
+	BestMove   *MoveSquares // nil without a legal best move
+	Refutation *MoveSquares // The opponent's best reply; nil without one
+
+	// Hanging is the square of the mover's piece the refutation takes
+	// when the move loses material, empty otherwise
+	Hanging string
+}
+
+// MoveHighlights returns the squares to highlight for move. The hanging
+// piece is the one the refutation captures when ExplainMove finds the
+// move loses material; a refutation that wins it later in the line, as a
+// fork does, leaves none.
+func MoveHighlights(move *MoveAnalysis) Highlights {
+	var h Highlights
+	if best, ok := UCISquares(move.FENBefore, move.BestMoveUCI); ok {
+		h.BestMove = &best
+	}
+	if reply := move.EvalAfter.PV; len(reply) > 0 {
+		if refutation, ok := UCISquares(move.FENAfter, reply[0]); ok {
+			h.Refutation = &refutation
+			if refutation.Captured != "" && ExplainMove(move).Kind == ExplainLosesMaterial {
+				h.Hanging = refutation.Captured
+			}
+		}
+	}
+	return h
+}
+
+// UCISquares returns the squares of move, in UCI notation, played from
+// fen, and false when it isn't a legal move there. Castling, whether
+// written as the king's two-square move or as the king taking its rook,
+// has the king's squares and the rook's.
+func UCISquares(fen, move string) (MoveSquares, bool) {
+	fenOpt, err := chess.FEN(fen)
+	if err != nil {
+		return MoveSquares{}, false
+	}
+	legal, ok := legalUCI(chess.NewGame(fenOpt).Position(), move)
+	if !ok {
+		return MoveSquares{}, false
+	}
+
+	squares := MoveSquares{From: legal.S1().String(), To: legal.S2().String()}
+	if legal.Promo() != chess.NoPieceType {
+		squares.Promotion = legal.Promo().String()
+	}
+	rank := legal.S1().Rank()
+	switch {
+	case legal.HasTag(chess.KingSideCastle):
+		squares.RookFrom = chess.NewSquare(chess.FileH, rank).String()
+		squares.RookTo = chess.NewSquare(chess.FileF, rank).String()
+	case legal.HasTag(chess.QueenSideCastle):
+		squares.RookFrom = chess.NewSquare(chess.FileA, rank).String()
+		squares.RookTo = chess.NewSquare(chess.FileD, rank).String()
+	case legal.HasTag(chess.EnPassant):
+		// The passed pawn stands beside the capturing one
+		squares.Captured = chess.NewSquare(legal.S2().File(), rank).String()
+	case legal.HasTag(chess.Capture):
+		squares.Captured = squares.To
+	}
+	return squares, true
+}
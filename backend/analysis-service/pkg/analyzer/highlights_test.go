@@ -0,0 +1,87 @@
+package analyzer
+
+import "testing"
+
+func TestUCISquares(t *testing.T) {
+	const (
+		castlingWhite = "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1"
+		castlingBlack = "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1"
+		promotion     = "1n2k3/P7/8/8/8/8/8/4K3 w - - 0 1"
+	)
+	tests := []struct {
+		name string
+		fen  string
+		move string
+		want MoveSquares
+	}{
+		{"quiet", startFEN, "e2e4", MoveSquares{From: "e2", To: "e4"}},
+		{"capture", "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2", "e4d5",
+			MoveSquares{From: "e4", To: "d5", Captured: "d5"}},
+		{"en passant, white", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6",
+			MoveSquares{From: "e5", To: "d6", Captured: "d5"}},
+		{"en passant, black", "4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1", "d4e3",
+			MoveSquares{From: "d4", To: "e3", Captured: "e4"}},
+		{"promotion", promotion, "a7a8q", MoveSquares{From: "a7", To: "a8", Promotion: "q"}},
+		{"underpromotion, SAN-style suffix", promotion, "a7a8=N", MoveSquares{From: "a7", To: "a8", Promotion: "n"}},
+		{"capturing promotion", promotion, "a7b8r", MoveSquares{From: "a7", To: "b8", Promotion: "r", Captured: "b8"}},
+		{"white castles short", castlingWhite, "e1g1", MoveSquares{From: "e1", To: "g1", RookFrom: "h1", RookTo: "f1"}},
+		{"white castles long", castlingWhite, "e1c1", MoveSquares{From: "e1", To: "c1", RookFrom: "a1", RookTo: "d1"}},
+		{"black castles short", castlingBlack, "e8g8", MoveSquares{From: "e8", To: "g8", RookFrom: "h8", RookTo: "f8"}},
+		{"black castles long", castlingBlack, "e8c8", MoveSquares{From: "e8", To: "c8", RookFrom: "a8", RookTo: "d8"}},
+		{"king takes rook, short", castlingWhite, "e1h1", MoveSquares{From: "e1", To: "g1", RookFrom: "h1", RookTo: "f1"}},
+		{"king takes rook, long", castlingBlack, "e8a8", MoveSquares{From: "e8", To: "c8", RookFrom: "a8", RookTo: "d8"}},
+		{"king steps", castlingWhite, "e1f1", MoveSquares{From: "e1", To: "f1"}},
+	}
+	for _, tt := range tests {
+		got, ok := UCISquares(tt.fen, tt.move)
+		if !ok || got != tt.want {
+			t.Errorf("%s: %s = %+v, %v; want %+v", tt.name, tt.move, got, ok, tt.want)
+		}
+	}
+
+	for _, bad := range []struct{ fen, move string }{
+		{startFEN, "e2e5"},
+		{startFEN, ""},
+		{"not a fen", "e2e4"},
+	} {
+		if got, ok := UCISquares(bad.fen, bad.move); ok {
+			t.Errorf("%q from %q = %+v, want not legal", bad.move, bad.fen, got)
+		}
+	}
+}
+
+func TestMoveHighlights(t *testing.T) {
+	fixture := func(name string) *MoveAnalysis {
+		for _, f := range explainFixtures {
+			if f.name == name {
+				return f.move(t)
+			}
+		}
+		t.Fatalf("no fixture %q", name)
+		return nil
+	}
+
+	// 2. Nf3 leaves e4 to ...dxe4; exd5 was best
+	h := MoveHighlights(fixture("drops a pawn"))
+	if h.BestMove == nil || *h.BestMove != (MoveSquares{From: "e4", To: "d5", Captured: "d5"}) {
+		t.Errorf("best move = %+v", h.BestMove)
+	}
+	if h.Refutation == nil || *h.Refutation != (MoveSquares{From: "d5", To: "e4", Captured: "e4"}) {
+		t.Errorf("refutation = %+v", h.Refutation)
+	}
+	if h.Hanging != "e4" {
+		t.Errorf("hanging = %q, want e4", h.Hanging)
+	}
+
+	// A reply that takes nothing leaves nothing hanging
+	h = MoveHighlights(fixture("gives up the edge"))
+	if h.Refutation == nil || h.Refutation.To != "e5" || h.Hanging != "" {
+		t.Errorf("gives up the edge: refutation %+v, hanging %q", h.Refutation, h.Hanging)
+	}
+
+	// Without a PV after the move there is no refutation
+	h = MoveHighlights(fixture("second best"))
+	if h.BestMove == nil || h.BestMove.To != "e4" || h.Refutation != nil || h.Hanging != "" {
+		t.Errorf("second best: %+v", h)
+	}
+}
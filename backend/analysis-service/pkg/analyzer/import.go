@@ -0,0 +1,182 @@
+package analyzer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/notnil/chess"
+	"go.uber.org/zap"
+)
+
+// maxImportErrors is how many invalid rows an ImportReport describes
+const maxImportErrors = 20
+
+// ImportReport counts the rows of an evaluation import
+type ImportReport struct {
+	Imported int
+	Skipped  int            // The cache held an evaluation at least as deep
+	Invalid  int            // Rows that couldn't be parsed or aren't legal
+	Sources  map[string]int // Imported rows by their source column
+	Errors   []string       // The first invalid rows, by line number
+}
+
+// ImportEvaluations loads precomputed evaluations into the position cache
+// for the primary engine, so common positions never take engine time.
+//
+// The input is CSV with one position per row:
+//
+//	fen,depth,cp,mate,best_move,source
+//
+// fen may have 4 or 6 fields. Exactly one of cp and mate is set, from
+// White's point of view as in public evaluation databases; they are
+// stored from the side to move like engine results. best_move is in UCI
+// notation and must be legal. source names the database and is only
+// counted. A header row starting with "fen" and lines starting with '#'
+// are ignored.
+//
+// Imported entries have source engine.SourceImported, never replace a
+// cached evaluation at least as deep, and are not evicted. Bad rows are
+// counted and skipped; only a read error stops the import.
+func (a *Analyzer) ImportEvaluations(r io.Reader) (ImportReport, error) {
+	report, err := a.importCSV(r, engine.SourceImported)
+	if err != nil {
+		return report, err
+	}
+	a.logger.Info("Imported evaluations",
+		zap.Int("imported", report.Imported),
+		zap.Int("skipped", report.Skipped),
+		zap.Int("invalid", report.Invalid),
+		zap.Any("sources", report.Sources))
+	return report, nil
+}
+
+// importCSV loads the evaluations of r into the cache as from cacheSource
+func (a *Analyzer) importCSV(r io.Reader, cacheSource string) (ImportReport, error) {
+	report := ImportReport{Sources: make(map[string]int)}
+	invalid := func(line int, format string, args ...interface{}) {
+		report.Invalid++
+		if len(report.Errors) < maxImportErrors {
+			report.Errors = append(report.Errors, fmt.Sprintf("line %d: %s", line, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			invalid(parseErr.Line, "%v", parseErr.Err)
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("read evaluations: %w", err)
+		}
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "fen") {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		row, err := parseImportRow(record)
+		if err != nil {
+			invalid(line, "%v", err)
+			continue
+		}
+		if !a.posCache.importAs(PrimaryEngine, row.fen, row.eval.Depth, row.eval, row.bestMove, cacheSource) {
+			report.Skipped++
+			continue
+		}
+		report.Imported++
+		report.Sources[row.source]++
+	}
+	return report, nil
+}
+
+type importRow struct {
+	fen      string
+	eval     engine.Evaluation
+	bestMove string
+	source   string
+}
+
+// parseImportRow validates one CSV row and converts its score to the side
+// to move
+func parseImportRow(record []string) (importRow, error) {
+	if len(record) != 6 {
+		return importRow{}, fmt.Errorf("has %d fields, want fen,depth,cp,mate,best_move,source", len(record))
+	}
+	for i := range record {
+		record[i] = strings.TrimSpace(record[i])
+	}
+	fen, depthField, cpField, mateField, bestMove, source := record[0], record[1], record[2], record[3], record[4], record[5]
+	bestMove = NormalizeUCI(bestMove)
+
+	// Evaluation databases often leave out the move counters
+	if len(strings.Fields(fen)) == 4 {
+		fen += " 0 1"
+	}
+	if err := engine.ValidateFEN(fen); err != nil {
+		return importRow{}, err
+	}
+	fenOpt, err := chess.FEN(fen)
+	if err != nil {
+		return importRow{}, fmt.Errorf("invalid FEN: %v", err)
+	}
+	position := chess.NewGame(fenOpt).Position()
+	if !isLegalUCI(position, bestMove) {
+		return importRow{}, fmt.Errorf("best move %q is not legal", bestMove)
+	}
+
+	depth, err := strconv.Atoi(depthField)
+	if err != nil || depth < 1 {
+		return importRow{}, fmt.Errorf("depth %q must be a positive integer", depthField)
+	}
+
+	sign := 1
+	if position.Turn() == chess.Black {
+		sign = -1
+	}
+	eval := engine.Evaluation{Depth: depth, PV: []string{bestMove}, MultiPV: 1}
+	switch {
+	case cpField != "" && mateField != "":
+		return importRow{}, errors.New("has both cp and mate")
+	case mateField != "":
+		mate, err := strconv.Atoi(mateField)
+		if err != nil || mate == 0 {
+			return importRow{}, fmt.Errorf("mate %q must be a non-zero integer", mateField)
+		}
+		mateIn := sign * mate
+		eval.IsMate = true
+		eval.MateIn = &mateIn
+	case cpField != "":
+		cp, err := strconv.Atoi(cpField)
+		if err != nil {
+			return importRow{}, fmt.Errorf("cp %q must be an integer", cpField)
+		}
+		eval.Centipawns = sign * cp
+	default:
+		return importRow{}, errors.New("has neither cp nor mate")
+	}
+
+	if source == "" {
+		source = "unknown"
+	}
+	return importRow{fen: fen, eval: eval, bestMove: bestMove, source: source}, nil
+}
+
+// isLegalUCI reports whether move, in UCI notation, is legal in position
+func isLegalUCI(position *chess.Position, move string) bool {
+	_, ok := legalUCI(position, move)
+	return ok
+}
@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"go.uber.org/zap"
+)
+
+const (
+	startFEN   = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	afterE4FEN = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	afterE5FEN = "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2"
+	// Black to move and mate with Qh4#
+	foolsMateFEN = "rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq - 0 2"
+)
+
+func TestImportEvaluations(t *testing.T) {
+	a := NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+
+	// A deeper local result isn't replaced
+	a.posCache.Set(PrimaryEngine, afterE5FEN, 40, engine.Evaluation{Depth: 40, Centipawns: 25}, "g1f3", engine.SourceEngine)
+
+	csv := strings.Join([]string{
+		"fen,depth,cp,mate,best_move,source",
+		"# 4-field FENs, White's point of view",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -,36,18,,e2e4,lichess",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -,34,30,,c7c5,lichess",
+		foolsMateFEN + ",30,,-1,d8h4,tablebase",
+		afterE5FEN + ",32,40,,g1f3,lichess",
+		afterE5FEN + ",32,40,,e2e5,lichess",
+		afterE5FEN + ",deep,40,,g1f3,lichess",
+		afterE5FEN + ",32,40,2,g1f3,lichess",
+		afterE5FEN + ",32,,,g1f3,lichess",
+		"not a fen,32,40,,g1f3,lichess",
+		afterE5FEN + ",32",
+		`"unterminated,32,40,,g1f3,lichess`,
+	}, "\n")
+
+	report, err := a.ImportEvaluations(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 3 || report.Skipped != 1 || report.Invalid != 7 {
+		t.Errorf("imported %d, skipped %d, invalid %d; want 3, 1, 7", report.Imported, report.Skipped, report.Invalid)
+	}
+	if report.Sources["lichess"] != 2 || report.Sources["tablebase"] != 1 {
+		t.Errorf("sources = %v", report.Sources)
+	}
+	if len(report.Errors) != report.Invalid || !strings.HasPrefix(report.Errors[0], "line 7: ") {
+		t.Errorf("errors = %q", report.Errors)
+	}
+
+	// Scores are stored from the side to move, and served from the cache
+	for _, tt := range []struct {
+		fen      string
+		cp, mate int
+		bestMove string
+		source   string
+	}{
+		{startFEN, 18, 0, "e2e4", engine.SourceImported},
+		{afterE4FEN, -30, 0, "c7c5", engine.SourceImported},
+		{foolsMateFEN, 0, 1, "d8h4", engine.SourceImported},
+		{afterE5FEN, 25, 0, "g1f3", engine.SourceEngine},
+	} {
+		result, err := a.AnalyzePosition(context.Background(), tt.fen, 20, 1)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.fen, err)
+		}
+		eval := result.Evaluations[0]
+		mate := 0
+		if eval.IsMate {
+			mate = *eval.MateIn
+		}
+		if eval.Centipawns != tt.cp || mate != tt.mate || result.BestMove != tt.bestMove || result.Source != tt.source {
+			t.Errorf("%s: cp %d mate %d best %s from %s; want cp %d mate %d best %s from %s",
+				tt.fen, eval.Centipawns, mate, result.BestMove, result.Source, tt.cp, tt.mate, tt.bestMove, tt.source)
+		}
+	}
+
+	bySource := a.CacheSizeBySource()
+	if bySource[engine.SourceImported] != 3 || bySource[engine.SourceEngine] != 1 {
+		t.Errorf("cache by source = %v", bySource)
+	}
+}
+
+func TestPositionCache_ImportedNotEvicted(t *testing.T) {
+	c := NewPositionCache(10)
+	// 40 distinct positions of a lone king
+	positions := make([]string, 0, 40)
+	for file := 0; file < 8; file++ {
+		for rank := 1; rank <= 5; rank++ {
+			row := make([]string, 8)
+			for r := range row {
+				row[r] = "8"
+			}
+			row[8-rank] = strings.Repeat("1", file) + "K" + strings.Repeat("1", 7-file)
+			positions = append(positions, strings.Join(row, "/")+" w - - 0 1")
+		}
+	}
+
+	for _, p := range positions[:20] {
+		if !c.Import(PrimaryEngine, p, 30, engine.Evaluation{Depth: 30}, "a1a2") {
+			t.Fatalf("Import(%s) = false, want true", p)
+		}
+	}
+	for _, p := range positions[20:] {
+		c.Set(PrimaryEngine, p, 20, engine.Evaluation{Depth: 20}, "a1a2", engine.SourceEngine)
+	}
+
+	bySource := c.SizeBySource()
+	if bySource[engine.SourceImported] != 20 {
+		t.Errorf("imported entries = %d, want all 20 kept", bySource[engine.SourceImported])
+	}
+	if n := bySource[engine.SourceEngine]; n == 0 || n > 10 {
+		t.Errorf("engine entries = %d, want 1 to 10", n)
+	}
+	if stats := c.Stats(); stats.Size != bySource[engine.SourceImported]+bySource[engine.SourceEngine] {
+		t.Errorf("size = %d, by source %v", stats.Size, bySource)
+	}
+
+	// A deeper local result replaces an imported one
+	c.Set(PrimaryEngine, positions[0], 32, engine.Evaluation{Depth: 32}, "a1b1", engine.SourceEngine)
+	if got := c.SizeBySource()[engine.SourceImported]; got != 19 {
+		t.Errorf("imported entries after replacement = %d, want 19", got)
+	}
+}
@@ -0,0 +1,386 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+// JSONSchemaVersion is the version of the JSON form of GameAnalysis. It is
+// raised whenever a field is renamed, removed or changes meaning; adding a
+// field doesn't raise it.
+const JSONSchemaVersion = 1
+
+// jsonGameAnalysis is the JSON form of GameAnalysis. Keys are snake_case,
+// evaluations are from the side to move as in MoveAnalysis.
+type jsonGameAnalysis struct {
+	SchemaVersion int          `json:"schema_version"`
+	GameID        string       `json:"game_id"`
+	Info          jsonGameInfo `json:"game_info"`
+	EngineVersion string       `json:"engine_version"`
+	EngineProfile string       `json:"engine_profile"`
+	Depth         int          `json:"depth"`
+	TotalTimeMs   int64        `json:"total_time_ms"`
+	StartedAt     int64        `json:"started_at"`
+	CompletedAt   int64        `json:"completed_at"`
+
+	CacheCoverage float64 `json:"cache_coverage"`
+	Preliminary   bool    `json:"preliminary"`
+	PreviewKept   int     `json:"preview_kept"`
+
+	TimeBudgetMs      int64   `json:"time_budget_ms"`
+	BudgetUsedMs      int64   `json:"budget_used_ms"`
+	BudgetUtilization float64 `json:"budget_utilization"`
+
+	TotalMoves      int    `json:"total_moves"`
+	TimedOut        bool   `json:"timed_out"`
+	FastMode        bool   `json:"fast_mode"`
+	Degraded        bool   `json:"degraded"`
+	Truncated       bool   `json:"truncated"`
+	TruncatedAtPly  int    `json:"truncated_at_ply"`
+	TruncationError string `json:"truncation_error"`
+
+	Termination            Termination `json:"termination"`
+	WinnerColor            string      `json:"winner_color"`
+	ResultAgainstRunOfPlay bool        `json:"result_against_run_of_play"`
+
+	ThresholdProfile string                `json:"threshold_profile"`
+	Thresholds       evaluation.Thresholds `json:"thresholds"`
+
+	WhiteMetrics jsonGameMetrics `json:"white_metrics"`
+	BlackMetrics jsonGameMetrics `json:"black_metrics"`
+	Moves        []jsonMove      `json:"moves"`
+
+	WhiteTime *jsonTimeManagement `json:"white_time,omitempty"`
+	BlackTime *jsonTimeManagement `json:"black_time,omitempty"`
+
+	Diagnostics jsonDiagnostics `json:"diagnostics"`
+
+	Config *jsonConfigSnapshot `json:"config,omitempty"`
+
+	SourceCounts map[AnalysisSource]int `json:"source_counts,omitempty"`
+
+	Summary  string `json:"summary"`
+	Checksum string `json:"checksum"`
+}
+
+type jsonGameInfo struct {
+	White       string `json:"white,omitempty"`
+	Black       string `json:"black,omitempty"`
+	WhiteElo    int    `json:"white_elo,omitempty"`
+	BlackElo    int    `json:"black_elo,omitempty"`
+	Result      string `json:"result,omitempty"`
+	TimeControl string `json:"time_control,omitempty"`
+	Date        string `json:"date,omitempty"`
+}
+
+type jsonMove struct {
+	Ply            int                `json:"ply"`
+	MoveNumber     int                `json:"move_number"`
+	Color          string             `json:"color"`
+	PlayedMove     string             `json:"played_move"`
+	PlayedMoveUCI  string             `json:"played_move_uci"`
+	BestMove       string             `json:"best_move"`
+	BestMoveUCI    string             `json:"best_move_uci"`
+	FENBefore      string             `json:"fen_before"`
+	FENAfter       string             `json:"fen_after"`
+	EvalBefore     jsonScore          `json:"eval_before"`
+	EvalAfter      jsonScore          `json:"eval_after"`
+	CentipawnLoss  int                `json:"centipawn_loss"`
+	Classification MoveClassification `json:"classification"`
+	PV             []string           `json:"pv"`
+	AchievedDepth  int                `json:"achieved_depth"`
+	RequestedDepth int                `json:"requested_depth"`
+	FromCache      bool               `json:"from_cache"`
+	Source         AnalysisSource     `json:"source"`
+	MoveAccuracy   float64            `json:"move_accuracy"`
+	Forced         bool               `json:"forced"`
+	GarbageTime    bool               `json:"garbage_time"`
+
+	ExpectedPointsLoss float64 `json:"expected_points_loss"`
+
+	MissedRepetition  bool `json:"missed_repetition"`
+	AllowedRepetition bool `json:"allowed_repetition"`
+
+	MaterialBefore     jsonMaterial `json:"material_before"`
+	MaterialAfter      jsonMaterial `json:"material_after"`
+	MaterialSacrificed int          `json:"material_sacrificed"`
+	Explanation        string       `json:"explanation"`
+
+	AnalyzedAt   int64 `json:"analyzed_at"`
+	EngineTimeMs int64 `json:"engine_time_ms"`
+	QueueTimeMs  int64 `json:"queue_time_ms"`
+	SearchTimeMs int64 `json:"search_time_ms"`
+	MovetimeMs   int64 `json:"movetime_ms"`
+}
+
+type jsonMaterial struct {
+	White int `json:"white"`
+	Black int `json:"black"`
+}
+
+// jsonScore is {"cp": 34} or {"mate": -3}
+type jsonScore struct {
+	CP   *int `json:"cp,omitempty"`
+	Mate *int `json:"mate,omitempty"`
+}
+
+type jsonGameMetrics struct {
+	Accuracy          float64 `json:"accuracy"`
+	ACPL              float64 `json:"acpl"`
+	Blunders          int     `json:"blunders"`
+	Mistakes          int     `json:"mistakes"`
+	Inaccuracies      int     `json:"inaccuracies"`
+	GoodMoves         int     `json:"good_moves"`
+	ExcellentMoves    int     `json:"excellent_moves"`
+	BestMoves         int     `json:"best_moves"`
+	BrilliantMoves    int     `json:"brilliant_moves"`
+	BookMoves         int     `json:"book_moves"`
+	TotalMoves        int     `json:"total_moves"`
+	PerformanceRating int     `json:"performance_rating"`
+	GarbageTimeMoves  int     `json:"garbage_time_moves"`
+
+	ExpectedPointsLost float64 `json:"expected_points_lost"`
+
+	AccuracyStddev float64 `json:"accuracy_stddev"`
+	ACPLStddev     float64 `json:"acpl_stddev"`
+
+	AccuracyModel evaluation.AccuracyModel `json:"accuracy_model,omitempty"`
+	Resilience    *evaluation.Resilience   `json:"resilience,omitempty"`
+}
+
+type jsonTimeManagement struct {
+	TimeClass           string  `json:"time_class"`
+	ScrambleThresholdMs int64   `json:"scramble_threshold_ms"`
+	ScrambleStartPly    int     `json:"scramble_start_ply"`
+	ScrambleMoves       int     `json:"scramble_moves"`
+	ScrambleErrorRate   float64 `json:"scramble_error_rate"`
+	OtherErrorRate      float64 `json:"other_error_rate"`
+	ThinkingTimeMs      int64   `json:"thinking_time_ms"`
+	CPLossPerSecond     float64 `json:"cp_loss_per_second"`
+	ScrambleBlunders    []int   `json:"scramble_blunders"`
+}
+
+type jsonDiagnostics struct {
+	Retries         int                 `json:"retries"`
+	Failures        map[FailureKind]int `json:"failures,omitempty"`
+	FailedPositions []int               `json:"failed_positions,omitempty"`
+}
+
+type jsonConfigSnapshot struct {
+	Threads         int     `json:"threads"`
+	HashMB          int     `json:"hash_mb"`
+	MultiPV         int     `json:"multi_pv"`
+	Depth           int     `json:"depth"`
+	NodesLimit      int64   `json:"nodes_limit"`
+	MovetimeMs      int64   `json:"movetime_ms"`
+	NNUENet         string  `json:"nnue_net"`
+	CacheHitPercent float64 `json:"cache_hit_percent"`
+	Deterministic   bool    `json:"deterministic"`
+}
+
+// MarshalJSON encodes the analysis in the versioned JSON schema meant for
+// storing results. Evaluations keep only their score.
+func (g GameAnalysis) MarshalJSON() ([]byte, error) {
+	out := jsonGameAnalysis{
+		SchemaVersion:          JSONSchemaVersion,
+		GameID:                 g.GameID,
+		Info:                   jsonGameInfo(g.Info),
+		EngineVersion:          g.EngineVersion,
+		EngineProfile:          g.EngineProfile,
+		Depth:                  g.Depth,
+		TotalTimeMs:            g.TotalTimeMs,
+		StartedAt:              g.StartedAt,
+		CompletedAt:            g.CompletedAt,
+		CacheCoverage:          g.CacheCoverage,
+		Preliminary:            g.Preliminary,
+		PreviewKept:            g.PreviewKept,
+		TimeBudgetMs:           g.TimeBudgetMs,
+		BudgetUsedMs:           g.BudgetUsedMs,
+		BudgetUtilization:      g.BudgetUtilization,
+		TotalMoves:             g.TotalMoves,
+		TimedOut:               g.TimedOut,
+		FastMode:               g.FastMode,
+		Degraded:               g.Degraded,
+		Truncated:              g.Truncated,
+		TruncatedAtPly:         g.TruncatedAtPly,
+		TruncationError:        g.TruncationError,
+		Termination:            g.Termination,
+		WinnerColor:            g.WinnerColor,
+		ResultAgainstRunOfPlay: g.ResultAgainstRunOfPlay,
+		ThresholdProfile:       g.ThresholdProfile,
+		Thresholds:             g.Thresholds,
+		WhiteMetrics:           jsonGameMetrics(g.WhiteMetrics),
+		BlackMetrics:           jsonGameMetrics(g.BlackMetrics),
+		Moves:                  make([]jsonMove, len(g.Moves)),
+		WhiteTime:              (*jsonTimeManagement)(g.WhiteTime),
+		BlackTime:              (*jsonTimeManagement)(g.BlackTime),
+		Diagnostics:            jsonDiagnostics(g.Diagnostics),
+		Config:                 (*jsonConfigSnapshot)(g.Config),
+		SourceCounts:           g.SourceCounts,
+		Summary:                g.Summary,
+		Checksum:               g.Checksum,
+	}
+	for i, move := range g.Moves {
+		out.Moves[i] = jsonMove{
+			Ply:            move.Ply,
+			MoveNumber:     move.MoveNumber,
+			Color:          move.Color,
+			PlayedMove:     move.PlayedMove,
+			PlayedMoveUCI:  move.PlayedMoveUCI,
+			BestMove:       move.BestMove,
+			BestMoveUCI:    move.BestMoveUCI,
+			FENBefore:      move.FENBefore,
+			FENAfter:       move.FENAfter,
+			EvalBefore:     toJSONScore(move.EvalBefore),
+			EvalAfter:      toJSONScore(move.EvalAfter),
+			CentipawnLoss:  move.CentipawnLoss,
+			Classification: move.Classification,
+			PV:             move.PV,
+			AchievedDepth:  move.AchievedDepth,
+			RequestedDepth: move.RequestedDepth,
+			FromCache:      move.FromCache,
+			Source:         move.Source,
+			MoveAccuracy:   move.MoveAccuracy,
+			Forced:         move.Forced,
+			GarbageTime:    move.GarbageTime,
+
+			ExpectedPointsLoss: move.ExpectedPointsLoss,
+
+			MissedRepetition:  move.MissedRepetition,
+			AllowedRepetition: move.AllowedRepetition,
+
+			MaterialBefore:     jsonMaterial(move.MaterialBefore),
+			MaterialAfter:      jsonMaterial(move.MaterialAfter),
+			MaterialSacrificed: move.MaterialSacrificed,
+			Explanation:        move.Explanation,
+
+			AnalyzedAt:   move.AnalyzedAt,
+			EngineTimeMs: move.EngineTimeMs,
+			QueueTimeMs:  move.QueueTimeMs,
+			SearchTimeMs: move.SearchTimeMs,
+			MovetimeMs:   move.MovetimeMs,
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes an analysis stored by MarshalJSON. Versions newer
+// than JSONSchemaVersion are rejected with ErrUnsupportedSchema.
+func (g *GameAnalysis) UnmarshalJSON(data []byte) error {
+	var in jsonGameAnalysis
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.SchemaVersion < 1 || in.SchemaVersion > JSONSchemaVersion {
+		return fmt.Errorf("%w: schema_version %d, want 1 to %d", ErrUnsupportedSchema, in.SchemaVersion, JSONSchemaVersion)
+	}
+
+	*g = GameAnalysis{
+		GameID:                 in.GameID,
+		Info:                   GameInfo(in.Info),
+		EngineVersion:          in.EngineVersion,
+		EngineProfile:          in.EngineProfile,
+		Depth:                  in.Depth,
+		TotalTimeMs:            in.TotalTimeMs,
+		StartedAt:              in.StartedAt,
+		CompletedAt:            in.CompletedAt,
+		CacheCoverage:          in.CacheCoverage,
+		Preliminary:            in.Preliminary,
+		PreviewKept:            in.PreviewKept,
+		TimeBudgetMs:           in.TimeBudgetMs,
+		BudgetUsedMs:           in.BudgetUsedMs,
+		BudgetUtilization:      in.BudgetUtilization,
+		TotalMoves:             in.TotalMoves,
+		TimedOut:               in.TimedOut,
+		FastMode:               in.FastMode,
+		Degraded:               in.Degraded,
+		Truncated:              in.Truncated,
+		TruncatedAtPly:         in.TruncatedAtPly,
+		TruncationError:        in.TruncationError,
+		Termination:            in.Termination,
+		WinnerColor:            in.WinnerColor,
+		ResultAgainstRunOfPlay: in.ResultAgainstRunOfPlay,
+		ThresholdProfile:       in.ThresholdProfile,
+		Thresholds:             in.Thresholds,
+		WhiteMetrics:           GameMetrics(in.WhiteMetrics),
+		BlackMetrics:           GameMetrics(in.BlackMetrics),
+		Moves:                  make([]MoveAnalysis, len(in.Moves)),
+		WhiteTime:              (*TimeManagement)(in.WhiteTime),
+		BlackTime:              (*TimeManagement)(in.BlackTime),
+		Diagnostics:            Diagnostics(in.Diagnostics),
+		Config:                 (*AnalysisConfigSnapshot)(in.Config),
+		SourceCounts:           in.SourceCounts,
+		Summary:                in.Summary,
+		Checksum:               in.Checksum,
+	}
+	for i, move := range in.Moves {
+		g.Moves[i] = MoveAnalysis{
+			Ply:            move.Ply,
+			MoveNumber:     move.MoveNumber,
+			Color:          move.Color,
+			PlayedMove:     move.PlayedMove,
+			PlayedMoveUCI:  move.PlayedMoveUCI,
+			BestMove:       move.BestMove,
+			BestMoveUCI:    move.BestMoveUCI,
+			FENBefore:      move.FENBefore,
+			FENAfter:       move.FENAfter,
+			EvalBefore:     move.EvalBefore.evaluation(),
+			EvalAfter:      move.EvalAfter.evaluation(),
+			CentipawnLoss:  move.CentipawnLoss,
+			Classification: move.Classification,
+			PV:             move.PV,
+			AchievedDepth:  move.AchievedDepth,
+			RequestedDepth: move.RequestedDepth,
+			FromCache:      move.FromCache,
+			Source:         move.Source,
+			MoveAccuracy:   move.MoveAccuracy,
+			Forced:         move.Forced,
+			GarbageTime:    move.GarbageTime,
+
+			ExpectedPointsLoss: move.ExpectedPointsLoss,
+
+			MissedRepetition:  move.MissedRepetition,
+			AllowedRepetition: move.AllowedRepetition,
+
+			MaterialBefore:     Material(move.MaterialBefore),
+			MaterialAfter:      Material(move.MaterialAfter),
+			MaterialSacrificed: move.MaterialSacrificed,
+			Explanation:        move.Explanation,
+
+			AnalyzedAt:   move.AnalyzedAt,
+			EngineTimeMs: move.EngineTimeMs,
+			QueueTimeMs:  move.QueueTimeMs,
+			SearchTimeMs: move.SearchTimeMs,
+			MovetimeMs:   move.MovetimeMs,
+		}
+		if g.Moves[i].Source == "" {
+			g.Moves[i].Source = LegacySource(&g.Moves[i])
+		}
+	}
+	shareFENs(g.Moves)
+	return nil
+}
+
+func toJSONScore(eval engine.Evaluation) jsonScore {
+	if eval.IsMate && eval.MateIn != nil {
+		mate := *eval.MateIn
+		return jsonScore{Mate: &mate}
+	}
+	cp := eval.Centipawns
+	return jsonScore{CP: &cp}
+}
+
+func (s jsonScore) evaluation() engine.Evaluation {
+	if s.Mate != nil {
+		mate := *s.Mate
+		return engine.Evaluation{IsMate: true, MateIn: &mate}
+	}
+	var eval engine.Evaluation
+	if s.CP != nil {
+		eval.Centipawns = *s.CP
+	}
+	return eval
+}
@@ -0,0 +1,166 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// jsonGoldenAnalysis sets every field of the JSON schema
+func jsonGoldenAnalysis() *GameAnalysis {
+	mateIn := -2
+	return &GameAnalysis{
+		GameID: "game-1",
+		Info: GameInfo{
+			White: "Carlsen, Magnus", Black: "Nakamura, Hikaru", WhiteElo: 2830, BlackElo: 2790,
+			Result: "1-0", TimeControl: "180+2", Date: "2026.01.01",
+		},
+		Termination: TerminationTimeForfeit, WinnerColor: "white", ResultAgainstRunOfPlay: true,
+		EngineVersion: "Stockfish 17",
+		EngineProfile: PrimaryEngine,
+		Depth:         18,
+		TotalTimeMs:   5400,
+		StartedAt:     1767225600000,
+		CompletedAt:   1767225605400,
+		CacheCoverage: 25,
+		TimeBudgetMs:  10000, BudgetUsedMs: 9500, BudgetUtilization: 95,
+		Diagnostics: Diagnostics{
+			Retries:         3,
+			Failures:        map[FailureKind]int{FailureEngineDied: 1, FailureInvalidOutput: 3},
+			FailedPositions: []int{4},
+		},
+		Config: &AnalysisConfigSnapshot{
+			Threads: 1, HashMB: 256, MultiPV: 1, Depth: 18, MovetimeMs: 10000,
+			NNUENet: "nn-1111cefa1111.nnue", CacheHitPercent: 25,
+		},
+		SourceCounts:     map[AnalysisSource]int{PlyCache: 1, PlyEngine: 1, PlyForcedSkipped: 1},
+		Summary:          "White 95.0%, Black 83.0% (1 blunder) — turning point: 2...g5?? allows mate in 1",
+		TotalMoves:       3,
+		TimedOut:         true,
+		Truncated:        true,
+		TruncatedAtPly:   3,
+		TruncationError:  "invalid PGN: 2... Ke7 at ply 3: illegal move",
+		ThresholdProfile: evaluation.ProfileStandard,
+		Thresholds:       evaluation.DefaultThresholds,
+		WhiteMetrics: GameMetrics{
+			Accuracy: 91.5, ACPL: 42.5, Mistakes: 1, BestMoves: 1, TotalMoves: 2,
+			PerformanceRating: 1650, GarbageTimeMoves: 1, AccuracyStddev: 1.25, ACPLStddev: 9.5, AccuracyModel: evaluation.AccuracyModelLichess,
+			Resilience: &evaluation.Resilience{Swindles: 1, Gifts: 1, GiftConversion: 12.5},
+		},
+		BlackMetrics: GameMetrics{
+			Accuracy: 100, ExcellentMoves: 1, TotalMoves: 1,
+		},
+		WhiteTime: &TimeManagement{
+			TimeClass: TimeClassBlitz, ScrambleThresholdMs: 30000,
+			ScrambleStartPly: 2, ScrambleMoves: 1, ScrambleErrorRate: 1,
+			ThinkingTimeMs: 12500, CPLossPerSecond: 6.8, ScrambleBlunders: []int{2},
+		},
+		BlackTime: &TimeManagement{
+			TimeClass: TimeClassBlitz, ScrambleThresholdMs: 30000, ScrambleStartPly: -1,
+			ThinkingTimeMs: 4000, CPLossPerSecond: 7.5,
+		},
+		Moves: []MoveAnalysis{
+			{
+				MoveNumber: 1, Ply: 0, Color: "white",
+				PlayedMove: "e4", PlayedMoveUCI: "e2e4", BestMove: "e4", BestMoveUCI: "e2e4",
+				FENBefore:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+				FENAfter:       "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+				EvalBefore:     engine.Evaluation{Centipawns: 34},
+				EvalAfter:      engine.Evaluation{Centipawns: -30},
+				Classification: ClassBest,
+				PV:             []string{"e2e4", "e7e5"},
+				AchievedDepth:  22, RequestedDepth: 18, FromCache: true,
+				Source:         PlyCache,
+				MoveAccuracy:   100,
+				MaterialBefore: Material{39, 39}, MaterialAfter: Material{39, 39},
+				AnalyzedAt: 1767225600000,
+			},
+			{
+				MoveNumber: 1, Ply: 1, Color: "black",
+				PlayedMove: "f6", PlayedMoveUCI: "f7f6", BestMove: "e5", BestMoveUCI: "e7e5",
+				FENBefore:      "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+				FENAfter:       "rnbqkbnr/ppppp1pp/5p2/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+				EvalBefore:     engine.Evaluation{Centipawns: -30},
+				EvalAfter:      engine.Evaluation{Centipawns: 0},
+				CentipawnLoss:  30,
+				Classification: ClassExcellent,
+				PV:             []string{"e7e5"},
+				AchievedDepth:  18, RequestedDepth: 18,
+				Source:       PlyEngine,
+				MoveAccuracy: 97.25,
+				AnalyzedAt:   1767225603100, EngineTimeMs: 1850, QueueTimeMs: 120, SearchTimeMs: 1870,
+				MovetimeMs: 1900,
+			},
+			{
+				MoveNumber: 2, Ply: 2, Color: "white",
+				PlayedMove: "Qh5+", PlayedMoveUCI: "d1h5", BestMove: "d4", BestMoveUCI: "d2d4",
+				FENBefore:      "rnbqkbnr/ppppp1pp/5p2/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+				FENAfter:       "rnbqkbnr/ppppp1pp/5p2/7Q/4P3/8/PPPP1PPP/RNB1KBNR b KQkq - 1 2",
+				EvalBefore:     engine.Evaluation{Centipawns: 900},
+				EvalAfter:      engine.Evaluation{IsMate: true, MateIn: &mateIn},
+				CentipawnLoss:  85,
+				Classification: ClassMistake,
+				AchievedDepth:  18, RequestedDepth: 18,
+				MoveAccuracy: 83,
+				Forced:       true,
+				Source:       PlyForcedSkipped,
+				GarbageTime:  true,
+
+				AllowedRepetition: true,
+				MaterialBefore:    Material{39, 39}, MaterialAfter: Material{39, 39}, MaterialSacrificed: 9,
+				Explanation: "This allows mate in 1.",
+				AnalyzedAt:  1767225605400, EngineTimeMs: 2240,
+			},
+		},
+	}
+}
+
+func TestGameAnalysisJSON_Golden(t *testing.T) {
+	data, err := json.MarshalIndent(jsonGoldenAnalysis(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, '\n')
+
+	const golden = "testdata/game_analysis.json"
+	if *update {
+		if err := os.WriteFile(golden, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A change here breaks stored results: add fields, or raise
+	// JSONSchemaVersion for anything else
+	if !bytes.Equal(data, want) {
+		t.Errorf("JSON schema changed; run go test -update if intended\ngot:\n%s", data)
+	}
+
+	var decoded GameAnalysis
+	if err := json.Unmarshal(want, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, jsonGoldenAnalysis()) {
+		t.Errorf("golden file decodes to %+v", decoded)
+	}
+}
+
+func TestGameAnalysisJSON_SchemaVersion(t *testing.T) {
+	for _, data := range []string{`{"game_id":"g1"}`, `{"schema_version":2,"game_id":"g1"}`} {
+		var analysis GameAnalysis
+		if err := json.Unmarshal([]byte(data), &analysis); !errors.Is(err, ErrUnsupportedSchema) {
+			t.Errorf("Unmarshal(%s) = %v, want ErrUnsupportedSchema", data, err)
+		}
+	}
+}
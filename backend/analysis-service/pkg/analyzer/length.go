@@ -0,0 +1,39 @@
+package analyzer
+
+import "fmt"
+
+// SetGameLengthLimits bounds the games AnalyzeGame takes. Games of more
+// than maxPlies plies are refused with ErrGameTooLong; those of more than
+// fastPlies are searched in fast mode, at the minimum depth, unless
+// GameOptions.FullDepth asks otherwise. 0 turns either limit off.
+func (a *Analyzer) SetGameLengthLimits(maxPlies, fastPlies int) {
+	a.maxPlies = maxPlies
+	a.fastPlies = fastPlies
+}
+
+// GameLengthLimits returns the limits of SetGameLengthLimits and the
+// depth of fast mode
+func (a *Analyzer) GameLengthLimits() (maxPlies, fastPlies, fastDepth int) {
+	return a.maxPlies, a.fastPlies, a.minDepth
+}
+
+// CheckGameLength returns ErrGameTooLong when a game of plies plies is
+// over the limit, so callers can refuse it before streaming anything
+func (a *Analyzer) CheckGameLength(plies int) error {
+	if a.maxPlies > 0 && plies > a.maxPlies {
+		return fmt.Errorf("%w: %d plies, at most %d are analyzed", ErrGameTooLong, plies, a.maxPlies)
+	}
+	return nil
+}
+
+// gameDepth returns the depth a game of plies plies is searched to for a
+// requested depth, and whether that is fast mode's, or ErrGameTooLong
+func (a *Analyzer) gameDepth(plies, depth int, opts GameOptions) (int, bool, error) {
+	if err := a.CheckGameLength(plies); err != nil {
+		return 0, false, err
+	}
+	if a.fastPlies > 0 && plies > a.fastPlies && !opts.FullDepth && depth > a.minDepth {
+		return a.minDepth, true, nil
+	}
+	return depth, false, nil
+}
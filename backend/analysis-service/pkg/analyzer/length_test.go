@@ -0,0 +1,73 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// shufflePGN returns a legal game of plies plies, both sides moving a
+// knight out and back
+func shufflePGN(plies int) string {
+	cycle := []string{"Nf3", "Nf6", "Ng1", "Ng8"}
+	var b strings.Builder
+	for ply := 0; ply < plies; ply++ {
+		if ply%2 == 0 {
+			fmt.Fprintf(&b, "%d. ", ply/2+1)
+		}
+		b.WriteString(cycle[ply%4] + " ")
+	}
+	b.WriteString("*")
+	return b.String()
+}
+
+func TestAnalyzeGame_TooLong(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	a.SetGameLengthLimits(600, 300)
+
+	_, err := a.AnalyzeGame(context.Background(), "g1", shufflePGN(1000), 12, GameOptions{}, nil)
+	if !errors.Is(err, ErrGameTooLong) || !strings.Contains(err.Error(), "1000 plies") {
+		t.Errorf("err = %v, want ErrGameTooLong naming 1000 plies", err)
+	}
+}
+
+func TestAnalyzeGame_FastMode(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	a.SetGameLengthLimits(20, 8)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		plies     int
+		fullDepth bool
+		depth     int
+		fast      bool
+	}{
+		{"at the limit", 8, false, 12, false},
+		{"over the limit", 10, false, 1, true},
+		{"over the limit at full depth", 10, true, 12, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, err := a.AnalyzeGame(ctx, "g1", shufflePGN(tt.plies), 12, GameOptions{FullDepth: tt.fullDepth}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if analysis.Depth != tt.depth || analysis.FastMode != tt.fast {
+				t.Errorf("depth %d, fast mode %v; want %d, %v", analysis.Depth, analysis.FastMode, tt.depth, tt.fast)
+			}
+		})
+	}
+}
+
+func TestGameLengthLimits_Off(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	if err := a.CheckGameLength(1000); err != nil {
+		t.Errorf("CheckGameLength() without limits = %v", err)
+	}
+	if depth, fast, err := a.gameDepth(1000, 12, GameOptions{}); depth != 12 || fast || err != nil {
+		t.Errorf("gameDepth() without limits = %d, %v, %v; want 12", depth, fast, err)
+	}
+}
@@ -0,0 +1,51 @@
+package analyzer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// sacrificeHorizon is how many plies after a move MaterialSacrificed
+// looks, so a capture answered by a recapture is no sacrifice
+const sacrificeHorizon = 2
+
+// Material is each side's material in pawns: pawn 1, knight and bishop 3,
+// rook 5, queen 9
+type Material struct {
+	White int
+	Black int
+}
+
+// Balance returns color's material minus the opponent's
+func (m Material) Balance(color string) int {
+	if color == "black" {
+		return m.Black - m.White
+	}
+	return m.White - m.Black
+}
+
+// pieceValues are the material values of the FEN piece letters
+var pieceValues = map[rune]int{'p': 1, 'n': 3, 'b': 3, 'r': 5, 'q': 9}
+
+// material counts the material of a FEN's piece placement
+func material(fen string) Material {
+	placement, _, _ := strings.Cut(fen, " ")
+	var m Material
+	for _, c := range placement {
+		value := pieceValues[unicode.ToLower(c)]
+		if unicode.IsUpper(c) {
+			m.White += value
+		} else {
+			m.Black += value
+		}
+	}
+	return m
+}
+
+// materialSacrificed returns the material the mover at ply gave up, net,
+// from before the move to sacrificeHorizon plies after it or the end of
+// the game. It is negative when they won material.
+func materialSacrificed(positions []Position, ply int, color string) int {
+	end := min(ply+1+sacrificeHorizon, len(positions)-1)
+	return material(positions[ply].FEN).Balance(color) - material(positions[end].FEN).Balance(color)
+}
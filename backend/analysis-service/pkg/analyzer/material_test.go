@@ -0,0 +1,80 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMaterial(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want Material
+	}{
+		{"start", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", Material{39, 39}},
+		{"promoted queen", "Q7/8/8/8/8/8/8/k6K b - - 0 1", Material{9, 0}},
+		{"underpromotion", "k7/8/8/8/8/8/8/1n5K w - - 0 1", Material{0, 3}},
+		{"kings only, no fields", "k7/8/8/8/8/8/8/7K", Material{}},
+	}
+	for _, tt := range tests {
+		if got := material(tt.fen); got != tt.want {
+			t.Errorf("%s: material = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMaterialSacrificed(t *testing.T) {
+	tests := []struct {
+		name  string
+		pgn   string
+		ply   int
+		after Material
+		want  int
+	}{
+		// The bishop for a pawn, not recaptured
+		{"sacrifice", "1. e4 e5 2. Bc4 Nc6 3. Bxf7+ Kxf7 4. d3 *", 4, Material{39, 38}, 2},
+		// The pawn taken en passant isn't on the square moved to, and is
+		// won back
+		{"en passant", "1. e4 a6 2. e5 d5 3. exd6 cxd6 *", 4, Material{39, 38}, 0},
+		// A capture answered by a recapture is an exchange
+		{"exchange", "1. e4 d5 2. exd5 Qxd5 3. Nc3 *", 2, Material{39, 38}, 0},
+		// Near the end of the game the horizon is cut short
+		{"last move", "1. e4 d5 2. exd5 *", 2, Material{39, 38}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positions, err := ParsePGN(tt.pgn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := material(positions[tt.ply+1].FEN); got != tt.after {
+				t.Errorf("material after = %+v, want %+v", got, tt.after)
+			}
+			if got := materialSacrificed(positions, tt.ply, "white"); got != tt.want {
+				t.Errorf("sacrificed = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// Promoting wins the difference between the piece and the pawn
+	positions := []Position{{FEN: "8/P7/8/8/8/8/8/k6K w - - 0 1"}, {FEN: "Q7/8/8/8/8/8/8/k6K b - - 0 1"}}
+	if got := materialSacrificed(positions, 0, "white"); got != -8 {
+		t.Errorf("promotion: sacrificed = %d, want -8", got)
+	}
+}
+
+func TestAnalyzeGame_Material(t *testing.T) {
+	a := newFakeAnalyzer(t)
+
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", "1. e4 e5 2. Bc4 Nc6 3. Bxf7+ Kxf7 4. d3 *", 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sac, recapture := analysis.Moves[4], analysis.Moves[5]
+	if sac.MaterialBefore != (Material{39, 39}) || sac.MaterialAfter != (Material{39, 38}) || sac.MaterialSacrificed != 2 {
+		t.Errorf("Bxf7+: before %+v, after %+v, sacrificed %d", sac.MaterialBefore, sac.MaterialAfter, sac.MaterialSacrificed)
+	}
+	if recapture.MaterialSacrificed != -3 {
+		t.Errorf("Kxf7 sacrificed = %d, want -3", recapture.MaterialSacrificed)
+	}
+}
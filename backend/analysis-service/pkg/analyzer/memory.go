@@ -0,0 +1,90 @@
+package analyzer
+
+import (
+	"unsafe"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+)
+
+// SetMaxPVLength caps the plies of each move's PV kept in game analyses
+// (0 = whole lines). The cap is applied as the moves are built, so long
+// engine lines are never held for the rest of the game.
+func (a *Analyzer) SetMaxPVLength(plies int) {
+	a.maxPVLength = plies
+}
+
+// capPV cuts the PV of eval to at most plies plies (0 = no cap). The cut
+// line is a copy so the engine's full line can be freed.
+func capPV(eval *engine.Evaluation, plies int) {
+	if plies > 0 && len(eval.PV) > plies {
+		eval.PV = append([]string(nil), eval.PV[:plies]...)
+	}
+}
+
+// shareFENs makes each move's FENBefore the previous move's FENAfter when
+// they are equal, so a game decoded from storage holds each position once
+// as AnalyzeGame's results do
+func shareFENs(moves []MoveAnalysis) {
+	for i := 1; i < len(moves); i++ {
+		if moves[i].FENBefore == moves[i-1].FENAfter {
+			moves[i].FENBefore = moves[i-1].FENAfter
+		}
+	}
+}
+
+// MemoryEstimate returns roughly how many bytes g holds: its moves and
+// their strings, counting the FEN and PV a move shares with the next once
+func (g *GameAnalysis) MemoryEstimate() int64 {
+	n := int64(unsafe.Sizeof(*g))
+	for i := range g.Moves {
+		m := &g.Moves[i]
+		n += MoveMemoryEstimate(m)
+		if i == 0 {
+			continue
+		}
+		prev := &g.Moves[i-1]
+		if sameString(m.FENBefore, prev.FENAfter) {
+			n -= int64(len(m.FENBefore))
+		}
+		if samePV(prev.EvalAfter.PV, m.EvalBefore.PV) && !samePV(prev.EvalAfter.PV, prev.PV) {
+			n -= pvMemory(prev.EvalAfter.PV)
+		}
+	}
+	return n
+}
+
+// MoveMemoryEstimate returns roughly how many bytes m holds. Its PV and
+// the PVs of its evaluations are counted once when they share their moves.
+func MoveMemoryEstimate(m *MoveAnalysis) int64 {
+	n := int64(unsafe.Sizeof(*m))
+	for _, s := range []string{m.PlayedMove, m.PlayedMoveUCI, m.BestMove, m.BestMoveUCI, m.FENBefore, m.FENAfter} {
+		n += int64(len(s))
+	}
+	n += pvMemory(m.PV)
+	if !samePV(m.EvalBefore.PV, m.PV) {
+		n += pvMemory(m.EvalBefore.PV)
+	}
+	if !samePV(m.EvalAfter.PV, m.PV) && !samePV(m.EvalAfter.PV, m.EvalBefore.PV) {
+		n += pvMemory(m.EvalAfter.PV)
+	}
+	return n
+}
+
+// pvMemory is the bytes of a PV's slice and moves
+func pvMemory(pv []string) int64 {
+	n := int64(cap(pv)) * int64(unsafe.Sizeof(""))
+	for _, move := range pv {
+		n += int64(len(move))
+	}
+	return n
+}
+
+// samePV reports whether a and b are the same non-empty slice
+func samePV(a, b []string) bool {
+	return len(a) > 0 && len(b) > 0 && &a[0] == &b[0]
+}
+
+// sameString reports whether a and b are the same non-empty bytes
+func sameString(a, b string) bool {
+	return a != "" && len(a) == len(b) && unsafe.StringData(a) == unsafe.StringData(b)
+}
@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"go.uber.org/zap"
+)
+
+// longPVEngine is fakeEngine answering every search with a 30-ply PV
+var longPVEngine = enginetest.Engine{Default: enginetest.Eval{
+	Centipawns: 25,
+	BestMove:   "e2e4",
+	PV:         strings.Fields("e7e5" + strings.Repeat(" e2e4 e7e5", 14)),
+}}
+
+func TestAnalyzeGame_CapsPV(t *testing.T) {
+	a := NewAnalyzer(newEnginePool(t, longPVEngine), zap.NewNop(), 1, 12, 20, time.Minute)
+	whole, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.SetMaxPVLength(8)
+	capped, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range capped.Moves {
+		m := &capped.Moves[i]
+		if len(m.PV) != 8 || len(whole.Moves[i].PV) != 30 {
+			t.Fatalf("ply %d: PV of %d plies capped, %d whole; want 8 and 30", i, len(m.PV), len(whole.Moves[i].PV))
+		}
+		if !samePV(m.PV, m.EvalBefore.PV) {
+			t.Errorf("ply %d: PV and EvalBefore.PV are separate copies", i)
+		}
+		if i > 0 && !samePV(capped.Moves[i-1].EvalAfter.PV, m.PV) {
+			t.Errorf("ply %d: previous EvalAfter.PV is a separate copy", i)
+		}
+		if i > 0 && !sameString(capped.Moves[i-1].FENAfter, m.FENBefore) {
+			t.Errorf("ply %d: FENBefore is a separate copy of the previous FENAfter", i)
+		}
+	}
+	if capped.MemoryEstimate() >= whole.MemoryEstimate() {
+		t.Errorf("capped analysis holds %d bytes, whole %d", capped.MemoryEstimate(), whole.MemoryEstimate())
+	}
+}
+
+func TestUnmarshalJSON_SharesFENs(t *testing.T) {
+	data, err := json.Marshal(jsonGoldenAnalysis())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g GameAnalysis
+	if err := json.Unmarshal(data, &g); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(g.Moves); i++ {
+		if g.Moves[i].FENBefore == g.Moves[i-1].FENAfter && !sameString(g.Moves[i].FENBefore, g.Moves[i-1].FENAfter) {
+			t.Errorf("ply %d: FENBefore decoded as a separate copy", g.Moves[i].Ply)
+		}
+	}
+}
+
+// BenchmarkAnalyzeGame_PVStorage builds the moves of a 100-move game from
+// cached 30-ply PVs, keeping them whole and capped at the default 20
+// plies. held-B/op is the memory the analysis holds by MemoryEstimate.
+func BenchmarkAnalyzeGame_PVStorage(b *testing.B) {
+	pgn, err := os.ReadFile("testdata/hundred_moves.pgn")
+	if err != nil {
+		b.Fatal(err)
+	}
+	a := NewAnalyzer(newEnginePool(b, longPVEngine), zap.NewNop(), 1, 12, 20, time.Minute)
+	// Searched once; the runs below find every position cached
+	if _, err := a.AnalyzeGame(context.Background(), "g1", string(pgn), 12, GameOptions{}, nil); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bench := range []struct {
+		name  string
+		plies int
+	}{
+		{"whole", 0},
+		{"capped", 20},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			a.SetMaxPVLength(bench.plies)
+			b.ReportAllocs()
+			var held int64
+			for i := 0; i < b.N; i++ {
+				analysis, err := a.AnalyzeGame(context.Background(), "g1", string(pgn), 12, GameOptions{}, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				held = analysis.MemoryEstimate()
+			}
+			b.ReportMetric(float64(held), "held-B/op")
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"context"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/pool"
+)
+
+// SearchMeter is told the wall clock time of each engine search made for
+// a call, failed and retried ones included. Answers from the cache or the
+// cloud are free and never reach it.
+type SearchMeter func(d time.Duration)
+
+type searchMeterKey struct{}
+
+// WithSearchMeter returns a copy of ctx whose analyses report their engine
+// searches to meter
+func WithSearchMeter(ctx context.Context, meter SearchMeter) context.Context {
+	return context.WithValue(ctx, searchMeterKey{}, meter)
+}
+
+// meterSearch reports a search of d to ctx's meter, if it has one
+func meterSearch(ctx context.Context, d time.Duration) {
+	if meter, ok := ctx.Value(searchMeterKey{}).(SearchMeter); ok {
+		meter(d)
+	}
+}
+
+// Pool tags of the analyzer's own background searches, see pool.WithTag.
+// The searches of a call, those of its game's workers included, are lent
+// for the tag of the call's context.
+const (
+	PonderPrefetchTag = "ponder_prefetch"
+	StaleRefreshTag   = "stale_refresh"
+	DriftSampleTag    = "drift_sample"
+)
+
+// PoolStats returns the stats of the analyzer's engine pool, with its busy
+// time by tag; they are zero without a pool
+func (a *Analyzer) PoolStats() pool.Stats {
+	if a.pool == nil {
+		return pool.Stats{}
+	}
+	return a.pool.GetStats()
+}
+
+// EstimateGameTime returns the engine time AnalyzeGame is expected to
+// spend on pgn: its time budget, or else the positions it would search
+// times the recent time per position at depth, and at the preview depth
+// when it has one. It is false when there is
+// nothing to go on, either because the game doesn't parse or no search at
+// the depth has finished yet.
+func (a *Analyzer) EstimateGameTime(pgn string, depth int, opts GameOptions) (time.Duration, bool) {
+	engineProfile, _, depth, err := a.engineProfile(opts.EngineProfile, depth)
+	if err != nil {
+		return 0, false
+	}
+	positions, err := a.ParsePGN(pgn)
+	if err != nil && len(positions) == 0 {
+		return 0, false
+	}
+	if opts.Cache.Only {
+		return 0, true
+	}
+	depth, _, err = a.gameDepth(len(positions)-1, depth, opts)
+	if err != nil {
+		return 0, false
+	}
+
+	searches := 0
+	for i, pos := range positions {
+		if i < len(opts.Prefix) || pos.LegalMoves == 0 {
+			continue
+		}
+		if opts.Cache.reads() {
+			if a.posCache.has(engineProfile, pos.FEN, CacheQuery{Depth: depth}) {
+				continue
+			}
+		}
+		searches++
+	}
+	if searches == 0 {
+		return 0, true
+	}
+	if opts.TimeBudget > 0 {
+		return opts.TimeBudget, true
+	}
+	perPosition, ok := a.EstimatePositionTime(depth)
+	if !ok {
+		return 0, false
+	}
+	estimate := perPosition * time.Duration(searches)
+	// A preview searches the same positions, more quickly, first
+	if previewDepth, ok := a.previewDepth(opts, depth); ok {
+		if perPreview, ok := a.EstimatePositionTime(previewDepth); ok {
+			estimate += perPreview * time.Duration(searches)
+		}
+	}
+	return estimate, true
+}
@@ -0,0 +1,184 @@
+package analyzer
+
+import (
+	"math"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+// gameMetrics builds both players' metrics as a game's moves are analyzed,
+// so neither the running metrics nor the final ones take another pass over
+// the moves. It belongs to one analysis and isn't shared between them.
+type gameMetrics struct {
+	model   evaluation.AccuracyModel
+	players map[string]*metricsAccumulator // By color
+
+	// evals are the moves so far from White's point of view, for the
+	// accuracy models and resilience
+	evals []evaluation.MoveEvaluation
+}
+
+func newGameMetrics(method evaluation.AccuracyMethod, model evaluation.AccuracyModel, plies int) *gameMetrics {
+	return &gameMetrics{
+		model: model,
+		players: map[string]*metricsAccumulator{
+			"white": newMetricsAccumulator(method),
+			"black": newMetricsAccumulator(method),
+		},
+		evals: make([]evaluation.MoveEvaluation, 0, plies),
+	}
+}
+
+// add counts the game's next analyzed move
+func (g *gameMetrics) add(move *MoveAnalysis) {
+	g.players[move.Color].add(move)
+	g.evals = append(g.evals, whiteEvaluation(move))
+}
+
+// result returns color's metrics of the moves added so far, with accuracy
+// scored by the game's model
+func (g *gameMetrics) result(color string) GameMetrics {
+	player := g.players[color]
+	metrics := player.result()
+	switch g.model {
+	case evaluation.AccuracyModelLichess:
+		metrics.Accuracy = evaluation.LichessAccuracy(g.evals, color)
+		metrics.AccuracyStddev = player.moveMeanStddev()
+	case evaluation.AccuracyModelChessComApprox:
+		metrics.Accuracy = evaluation.ChessComApproxAccuracy(g.evals, color)
+		metrics.AccuracyStddev = player.moveMeanStddev()
+	}
+	metrics.AccuracyModel = g.model
+	return metrics
+}
+
+// metricsAccumulator builds a player's GameMetrics one move at a time, so
+// metrics so far are available while a game is still being analyzed
+type metricsAccumulator struct {
+	method  evaluation.AccuracyMethod
+	metrics GameMetrics
+
+	losses               evaluation.LossTotals // Of the moves outside garbage time
+	totalMoveAccuracy    float64
+	moveAccuracyVariance float64 // Of totalMoveAccuracy, from the noise of the evaluations
+	accuracyMoves        int
+}
+
+func newMetricsAccumulator(method evaluation.AccuracyMethod) *metricsAccumulator {
+	return &metricsAccumulator{method: method, metrics: GameMetrics{AccuracyModel: evaluation.AccuracyModelEloInsight}}
+}
+
+// add counts one of the player's moves
+func (m *metricsAccumulator) add(move *MoveAnalysis) {
+	m.metrics.TotalMoves++
+	m.metrics.ExpectedPointsLost += move.ExpectedPointsLoss
+	if move.GarbageTime {
+		m.metrics.GarbageTimeMoves++
+	} else if move.Source != PlyBookSkipped {
+		noise := evaluation.LossNoise(move.EvalBefore.Depth, move.EvalAfter.Depth)
+		m.losses.AddUncertain(move.CentipawnLoss, noise)
+		if move.Source != PlyForcedSkipped {
+			m.totalMoveAccuracy += move.MoveAccuracy
+			accuracyNoise := evaluation.MoveAccuracyNoise(centipawns(move.EvalBefore), -centipawns(move.EvalAfter), noise)
+			m.moveAccuracyVariance += accuracyNoise * accuracyNoise
+			m.accuracyMoves++
+		}
+	}
+
+	switch move.Classification {
+	case ClassBrilliant:
+		m.metrics.BrilliantMoves++
+	case ClassBest:
+		m.metrics.BestMoves++
+	case ClassExcellent:
+		m.metrics.ExcellentMoves++
+	case ClassGood:
+		m.metrics.GoodMoves++
+	case ClassBook:
+		m.metrics.BookMoves++
+	case ClassInaccuracy:
+		m.metrics.Inaccuracies++
+	case ClassMistake:
+		m.metrics.Mistakes++
+	case ClassBlunder:
+		m.metrics.Blunders++
+	}
+}
+
+// result returns the metrics of the moves added so far
+func (m *metricsAccumulator) result() GameMetrics {
+	metrics := m.metrics
+	metrics.ACPL = m.losses.ACPL()
+	metrics.ACPLStddev = m.losses.ACPLStddev()
+	metrics.Accuracy = m.losses.Accuracy()
+	metrics.AccuracyStddev = m.losses.AccuracyStddev()
+
+	if m.method == evaluation.AccuracyMoveMean {
+		metrics.Accuracy = 100
+		metrics.AccuracyStddev = m.moveMeanStddev()
+		if m.accuracyMoves > 0 {
+			metrics.Accuracy = m.totalMoveAccuracy / float64(m.accuracyMoves)
+		}
+	}
+
+	return metrics
+}
+
+// moveMeanStddev returns the standard deviation of the mean of the
+// player's move accuracies, which the accuracy models that average move
+// accuracies are taken to share
+func (m *metricsAccumulator) moveMeanStddev() float64 {
+	if m.accuracyMoves == 0 {
+		return 0
+	}
+	return math.Sqrt(m.moveAccuracyVariance) / float64(m.accuracyMoves)
+}
+
+// resilience returns color's resilience over the analyzed moves, from
+// White's point of view, or nil when result, in PGN notation, isn't a
+// finished game
+func resilience(evals []evaluation.MoveEvaluation, color, result string, t evaluation.Thresholds) *evaluation.Resilience {
+	return evaluation.CalculateResilience(evals, color, playerResult(result, color), t)
+}
+
+// whiteEvaluations converts moves for the evaluation package, with
+// evaluations from White's point of view
+func whiteEvaluations(moves []MoveAnalysis) []evaluation.MoveEvaluation {
+	evals := make([]evaluation.MoveEvaluation, len(moves))
+	for i := range moves {
+		evals[i] = whiteEvaluation(&moves[i])
+	}
+	return evals
+}
+
+// whiteEvaluation converts a move for the evaluation package, with
+// evaluations from White's point of view
+func whiteEvaluation(move *MoveAnalysis) evaluation.MoveEvaluation {
+	// Scores before the move are the mover's, after it the opponent's
+	sign := 1
+	if move.Color == "black" {
+		sign = -1
+	}
+	return evaluation.MoveEvaluation{
+		Ply:            move.Ply,
+		Color:          move.Color,
+		EvalBefore:     sign * centipawns(move.EvalBefore),
+		EvalAfter:      -sign * centipawns(move.EvalAfter),
+		CentipawnLoss:  move.CentipawnLoss,
+		Classification: evaluation.MoveClassification(move.Classification),
+		DepthBefore:    move.EvalBefore.Depth,
+		DepthAfter:     move.EvalAfter.Depth,
+	}
+}
+
+// ExpectedPointsLoss returns the expected points move gave away: the drop
+// in the mover's expected points from its evaluation before the move to
+// the one after, mates normalized, see evaluation.ExpectedPointsLoss. A
+// forced move gives nothing away, having no alternative, nor does mating.
+func ExpectedPointsLoss(move *MoveAnalysis) float64 {
+	if move.Forced || gameOver(move.FENAfter) == engine.GameOverCheckmate {
+		return 0
+	}
+	return evaluation.ExpectedPointsLoss(centipawns(move.EvalBefore), -centipawns(move.EvalAfter))
+}
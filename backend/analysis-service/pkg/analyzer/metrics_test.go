@@ -0,0 +1,486 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	"go.uber.org/zap"
+)
+
+// calculateMetrics is a full pass over moves for color's metrics, which the
+// running metrics of AnalyzeGame must match
+func (a *Analyzer) calculateMetrics(moves []MoveAnalysis, color string) GameMetrics {
+	acc := newMetricsAccumulator(a.accuracyMethod)
+	for i := range moves {
+		if moves[i].Color == color {
+			acc.add(&moves[i])
+		}
+	}
+	return acc.result()
+}
+
+func TestAnalyzeGame_RunningMetricsMatchFullPass(t *testing.T) {
+	games := []string{
+		testPGN,
+		"1. e4 f6 2. d4 g5 3. Qh5# 1-0",
+		"1. d4 d5 2. c4 e6 3. Nc3 Nf6 4. Bg5 Be7 5. e3 O-O 6. Nf3 h6 7. Bh4 b6 8. cxd5 Nxd5 *",
+	}
+
+	for _, method := range []evaluation.AccuracyMethod{evaluation.AccuracyCappedLoss, evaluation.AccuracyMoveMean} {
+		a := newFakeAnalyzer(t)
+		if err := a.SetAccuracyMethod(method); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, pgn := range games {
+			type snapshot struct {
+				analyzed     int
+				white, black GameMetrics
+			}
+			var snapshots []snapshot
+			opts := GameOptions{
+				MetricsInterval: 2,
+				OnMetrics: func(analyzed int, white, black GameMetrics) {
+					snapshots = append(snapshots, snapshot{analyzed, white, black})
+				},
+			}
+
+			analysis, err := a.AnalyzeGame(context.Background(), "g1", pgn, 12, opts, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := len(analysis.Moves) / 2; len(snapshots) != want {
+				t.Fatalf("%s: %d snapshots, want %d", method, len(snapshots), want)
+			}
+
+			// Every snapshot equals a full pass over the moves so far
+			for _, s := range snapshots {
+				moves := analysis.Moves[:s.analyzed]
+				if white := a.calculateMetrics(moves, "white"); s.white != white {
+					t.Errorf("%s after %d moves: white %+v, full pass %+v", method, s.analyzed, s.white, white)
+				}
+				if black := a.calculateMetrics(moves, "black"); s.black != black {
+					t.Errorf("%s after %d moves: black %+v, full pass %+v", method, s.analyzed, s.black, black)
+				}
+			}
+			if white := a.calculateMetrics(analysis.Moves, "white"); analysis.WhiteMetrics != white {
+				t.Errorf("%s: white %+v, full pass %+v", method, analysis.WhiteMetrics, white)
+			}
+			if black := a.calculateMetrics(analysis.Moves, "black"); analysis.BlackMetrics != black {
+				t.Errorf("%s: black %+v, full pass %+v", method, analysis.BlackMetrics, black)
+			}
+		}
+	}
+}
+
+// TestAnalyzeGame_MetricsGolden pins the metrics, running and final, of
+// fixture games under every accuracy method and model
+func TestAnalyzeGame_MetricsGolden(t *testing.T) {
+	games := []string{
+		testPGN,
+		"[Result \"1-0\"]\n\n1. e4 f6 2. d4 g5 3. Qh5# 1-0",
+		"[Result \"1/2-1/2\"]\n\n1. d4 d5 2. c4 e6 3. Nc3 Nf6 4. Bg5 Be7 5. e3 O-O 6. Nf3 h6 7. Bh4 b6 8. cxd5 Nxd5 1/2-1/2",
+		"[Result \"0-1\"]\n\n1. e4 f5 2. Qh5+ g6 3. Nf3 gxh5 0-1",
+	}
+	narrow := evaluation.DefaultThresholds
+	narrow.GarbageWin, narrow.GarbageLoss = 100, -50
+
+	var b strings.Builder
+	for _, method := range []evaluation.AccuracyMethod{evaluation.AccuracyCappedLoss, evaluation.AccuracyMoveMean} {
+		for _, model := range []evaluation.AccuracyModel{evaluation.AccuracyModelEloInsight, evaluation.AccuracyModelLichess, evaluation.AccuracyModelChessComApprox} {
+			for _, profile := range []string{"standard", "narrow"} {
+				a := newFakeAnalyzer(t)
+				a.SetBookDetector(plyBook{0: true, 1: true})
+				if err := a.SetAccuracyMethod(method); err != nil {
+					t.Fatal(err)
+				}
+				if err := a.SetThresholdProfiles(map[string]evaluation.Thresholds{"standard": evaluation.DefaultThresholds, "narrow": narrow}, profile); err != nil {
+					t.Fatal(err)
+				}
+
+				for i, pgn := range games {
+					fmt.Fprintf(&b, "%s %s %s game %d\n", method, model, profile, i)
+					opts := GameOptions{
+						AccuracyModel:   model,
+						MetricsInterval: 3,
+						OnMetrics: func(analyzed int, white, black GameMetrics) {
+							fmt.Fprintf(&b, "  after %d: %s %s\n", analyzed, metricsJSON(t, white), metricsJSON(t, black))
+						},
+					}
+					analysis, err := a.AnalyzeGame(context.Background(), "g1", pgn, 12, opts, nil)
+					if err != nil {
+						t.Fatal(err)
+					}
+					fmt.Fprintf(&b, "  white: %s\n  black: %s\n", metricsJSON(t, analysis.WhiteMetrics), metricsJSON(t, analysis.BlackMetrics))
+				}
+			}
+		}
+	}
+
+	const golden = "testdata/metrics.golden"
+	if *update {
+		if err := os.WriteFile(golden, []byte(b.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != string(want) {
+		t.Errorf("metrics changed; run go test -update if intended\ngot:\n%s", b.String())
+	}
+}
+
+// metricsJSON writes metrics with every float in full precision
+func metricsJSON(t *testing.T, metrics GameMetrics) string {
+	t.Helper()
+	data, err := json.Marshal(metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestAnalyzeGame_GarbageTime(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	// The fake engine scores -120 to 240, so a narrow window makes some
+	// positions decided
+	narrow := evaluation.DefaultThresholds
+	narrow.GarbageWin, narrow.GarbageLoss = 100, -50
+	if err := a.SetThresholdProfiles(map[string]evaluation.Thresholds{"narrow": narrow}, "narrow"); err != nil {
+		t.Fatal(err)
+	}
+
+	excluded, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := false
+	included, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, GameOptions{ExcludeGarbageTime: &off}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	garbage := 0
+	var loss, counted int
+	for i, move := range excluded.Moves {
+		before := centipawns(move.EvalBefore)
+		if want := before > 100 || before < -50; move.GarbageTime != want {
+			t.Errorf("ply %d at %d: garbage time = %v, want %v", move.Ply, before, move.GarbageTime, want)
+		}
+		// Garbage time moves are still classified
+		if move.Classification != included.Moves[i].Classification || included.Moves[i].GarbageTime {
+			t.Errorf("ply %d: %s excluded, %s included", move.Ply, move.Classification, included.Moves[i].Classification)
+		}
+		if move.GarbageTime {
+			garbage++
+		} else if move.Color == "white" {
+			loss += move.CentipawnLoss
+			counted++
+		}
+	}
+	if garbage == 0 || garbage == len(excluded.Moves) {
+		t.Fatalf("%d of %d moves in garbage time, want some", garbage, len(excluded.Moves))
+	}
+
+	white, black := excluded.WhiteMetrics, excluded.BlackMetrics
+	if white.GarbageTimeMoves+black.GarbageTimeMoves != garbage {
+		t.Errorf("garbage time moves = %d + %d, want %d", white.GarbageTimeMoves, black.GarbageTimeMoves, garbage)
+	}
+	if counted > 0 && white.ACPL != float64(loss)/float64(counted) {
+		t.Errorf("white ACPL = %v, want %v", white.ACPL, float64(loss)/float64(counted))
+	}
+	if white.TotalMoves != included.WhiteMetrics.TotalMoves || included.WhiteMetrics.GarbageTimeMoves != 0 {
+		t.Errorf("white moves = %d excluded, %+v included", white.TotalMoves, included.WhiteMetrics)
+	}
+
+	// The window used is recorded, and zero when it was off
+	if excluded.Thresholds.GarbageWin != 100 || included.Thresholds.GarbageWin != 0 || included.Thresholds.GarbageLoss != 0 {
+		t.Errorf("thresholds = %+v excluded, %+v included", excluded.Thresholds, included.Thresholds)
+	}
+}
+
+func TestAnalyzeGame_Resilience(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	tagged := "[Result \"0-1\"]\n\n" + testPGN
+
+	for _, tt := range []struct {
+		name   string
+		pgn    string
+		result string
+		used   string // Result the resilience is of, "" for none
+	}{
+		{"result tag", tagged, "", "0-1"},
+		{"request overrides tag", tagged, "1/2-1/2", "1/2-1/2"},
+		{"unfinished", tagged, "*", ""},
+		{"no result", testPGN, "", ""},
+	} {
+		analysis, err := a.AnalyzeGame(context.Background(), "g1", tt.pgn, 12, GameOptions{Result: tt.result}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		white, black := analysis.WhiteMetrics.Resilience, analysis.BlackMetrics.Resilience
+		if tt.used == "" {
+			if white != nil || black != nil {
+				t.Errorf("%s: resilience %+v and %+v, want none", tt.name, white, black)
+			}
+			continue
+		}
+		for _, side := range []struct {
+			color string
+			got   *evaluation.Resilience
+		}{{"white", white}, {"black", black}} {
+			want := resilience(whiteEvaluations(analysis.Moves), side.color, tt.used, analysis.Thresholds)
+			if side.got == nil || *side.got != *want {
+				t.Errorf("%s: %s %+v, want %+v", tt.name, side.color, side.got, want)
+			}
+		}
+	}
+}
+
+func TestResilience_Result(t *testing.T) {
+	// White goes from even to lost and the game is drawn
+	moves := []MoveAnalysis{
+		{Ply: 0, Color: "white", CentipawnLoss: 900, EvalBefore: engine.Evaluation{}, EvalAfter: engine.Evaluation{Centipawns: 900}},
+		{Ply: 1, Color: "black", EvalBefore: engine.Evaluation{Centipawns: 900}, EvalAfter: engine.Evaluation{Centipawns: -900}},
+	}
+
+	for _, tt := range []struct {
+		result        string
+		whiteSwindles int
+		blackBotched  int
+	}{
+		{"1/2-1/2", 1, 1},
+		{"1-0", 1, 1},
+		{"0-1", 0, 0},
+	} {
+		white := resilience(whiteEvaluations(moves), "white", tt.result, evaluation.DefaultThresholds)
+		black := resilience(whiteEvaluations(moves), "black", tt.result, evaluation.DefaultThresholds)
+		if white.Swindles != tt.whiteSwindles || black.BotchedWins != tt.blackBotched {
+			t.Errorf("%s: white %+v, black %+v", tt.result, white, black)
+		}
+	}
+	if r := resilience(whiteEvaluations(moves), "white", "*", evaluation.DefaultThresholds); r != nil {
+		t.Errorf("unfinished game: %+v, want nil", r)
+	}
+}
+
+func TestAnalyzeGame_AccuracyModel(t *testing.T) {
+	a := newFakeAnalyzer(t)
+
+	for _, tt := range []struct {
+		model, used evaluation.AccuracyModel
+	}{
+		{"", evaluation.AccuracyModelEloInsight},
+		{evaluation.AccuracyModelLichess, evaluation.AccuracyModelLichess},
+		{evaluation.AccuracyModelChessComApprox, evaluation.AccuracyModelChessComApprox},
+	} {
+		opts := GameOptions{
+			AccuracyModel:   tt.model,
+			MetricsInterval: 2,
+			OnMetrics: func(analyzed int, white, black GameMetrics) {
+				if white.AccuracyModel != tt.used || black.AccuracyModel != tt.used {
+					t.Errorf("%q after %d moves: models %q and %q", tt.model, analyzed, white.AccuracyModel, black.AccuracyModel)
+				}
+			},
+		}
+		analysis, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, opts, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if analysis.WhiteMetrics.AccuracyModel != tt.used || analysis.BlackMetrics.AccuracyModel != tt.used {
+			t.Errorf("%q: models %q and %q", tt.model, analysis.WhiteMetrics.AccuracyModel, analysis.BlackMetrics.AccuracyModel)
+		}
+
+		var want float64
+		switch tt.used {
+		case evaluation.AccuracyModelEloInsight:
+			want = a.calculateMetrics(analysis.Moves, "white").Accuracy
+		case evaluation.AccuracyModelLichess:
+			want = evaluation.LichessAccuracy(whiteEvaluations(analysis.Moves), "white")
+		case evaluation.AccuracyModelChessComApprox:
+			want = evaluation.ChessComApproxAccuracy(whiteEvaluations(analysis.Moves), "white")
+		}
+		if analysis.WhiteMetrics.Accuracy != want {
+			t.Errorf("%q: white accuracy %.2f, want %.2f", tt.model, analysis.WhiteMetrics.Accuracy, want)
+		}
+	}
+
+	_, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, GameOptions{AccuracyModel: "chesscom"}, nil)
+	if !errors.Is(err, ErrUnknownAccuracyModel) {
+		t.Errorf("unknown model: err = %v, want ErrUnknownAccuracyModel", err)
+	}
+}
+
+func TestMetrics_MatchEvaluationPackage(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	thresholds := evaluation.DefaultThresholds
+
+	for game := 0; game < 200; game++ {
+		moves := make([]MoveAnalysis, 1+rng.Intn(80))
+		for i := range moves {
+			color := "white"
+			if i%2 == 1 {
+				color = "black"
+			}
+			// Mostly small losses, with blunders past the per-move cap
+			loss := rng.Intn(60)
+			if rng.Intn(8) == 0 {
+				loss = rng.Intn(1500)
+			}
+			before := rng.Intn(2400) - 1200
+			moves[i] = MoveAnalysis{
+				Ply:           i,
+				Color:         color,
+				EvalBefore:    engine.Evaluation{Centipawns: before},
+				EvalAfter:     engine.Evaluation{Centipawns: -(before - loss)},
+				CentipawnLoss: loss,
+				GarbageTime:   thresholds.IsGarbageTime(before),
+			}
+			if i < 10 && rng.Intn(2) == 0 {
+				moves[i].Classification = ClassBook
+				moves[i].Source = PlyBookSkipped
+			}
+		}
+
+		evals := whiteEvaluations(moves)
+		for _, color := range []string{"white", "black"} {
+			acc := newMetricsAccumulator(evaluation.AccuracyCappedLoss)
+			for i := range moves {
+				if moves[i].Color == color {
+					acc.add(&moves[i])
+				}
+			}
+			got := acc.result()
+			want := evaluation.CalculatePlayerMetrics(evals, color, 0, evaluation.ResultDraw, thresholds)
+
+			if got.Accuracy != want.Accuracy || got.ACPL != want.ACPL || got.BookMoves != want.BookMoves {
+				t.Fatalf("game %d %s: analyzer accuracy %v ACPL %v book %d, evaluation accuracy %v ACPL %v book %d",
+					game, color, got.Accuracy, got.ACPL, got.BookMoves, want.Accuracy, want.ACPL, want.BookMoves)
+			}
+			if evaluation.RoundAccuracy(got.Accuracy) != evaluation.RoundAccuracy(want.Accuracy) ||
+				evaluation.RoundACPL(got.ACPL) != evaluation.RoundACPL(want.ACPL) {
+				t.Fatalf("game %d %s: rounded metrics differ", game, color)
+			}
+		}
+	}
+}
+
+func TestGameMetrics_StddevShrinksWithDepth(t *testing.T) {
+	// The same moves searched ever deeper
+	moves := func(depth int) []MoveAnalysis {
+		evals := []int{30, -20, 60, -80, 120, -100}
+		var moves []MoveAnalysis
+		for i := 0; i+1 < len(evals); i++ {
+			color := "white"
+			if i%2 == 1 {
+				color = "black"
+			}
+			before := engine.Evaluation{Depth: depth, Centipawns: evals[i]}
+			after := engine.Evaluation{Depth: depth, Centipawns: evals[i+1]}
+			moves = append(moves, MoveAnalysis{
+				Ply:           i,
+				Color:         color,
+				EvalBefore:    before,
+				EvalAfter:     after,
+				CentipawnLoss: max(0, evals[i]+evals[i+1]),
+				MoveAccuracy:  evaluation.CalculateMoveAccuracy(evals[i], -evals[i+1]),
+				Source:        PlyEngine,
+			})
+		}
+		return moves
+	}
+
+	for _, method := range []evaluation.AccuracyMethod{evaluation.AccuracyCappedLoss, evaluation.AccuracyMoveMean} {
+		for _, model := range []evaluation.AccuracyModel{evaluation.AccuracyModelEloInsight, evaluation.AccuracyModelLichess, evaluation.AccuracyModelChessComApprox} {
+			var previous GameMetrics
+			for i, depth := range []int{10, 14, 18, 22, 26} {
+				g := newGameMetrics(method, model, 6)
+				game := moves(depth)
+				for j := range game {
+					g.add(&game[j])
+				}
+				metrics := g.result("white")
+				if metrics.AccuracyStddev <= 0 || metrics.ACPLStddev <= 0 {
+					t.Fatalf("%s/%s at depth %d: stddevs %v, %v, want positive", method, model, depth, metrics.AccuracyStddev, metrics.ACPLStddev)
+				}
+				if i > 0 && (metrics.AccuracyStddev >= previous.AccuracyStddev || metrics.ACPLStddev >= previous.ACPLStddev) {
+					t.Errorf("%s/%s at depth %d: stddevs %v, %v, want below %v, %v", method, model, depth,
+						metrics.AccuracyStddev, metrics.ACPLStddev, previous.AccuracyStddev, previous.ACPLStddev)
+				}
+				previous = metrics
+			}
+		}
+	}
+}
+
+func TestExpectedPointsLoss(t *testing.T) {
+	mate := func(n int) engine.Evaluation { return engine.Evaluation{IsMate: true, MateIn: &n} }
+	cp := func(n int) engine.Evaluation { return engine.Evaluation{Centipawns: n} }
+	const foolsMate = "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"
+	tests := []struct {
+		name string
+		move MoveAnalysis
+		want float64
+	}{
+		// Before the move the mover's evaluation, after it the opponent's
+		{"won to equal", MoveAnalysis{EvalBefore: cp(300), EvalAfter: cp(0)}, 0.3490},
+		{"better to worse", MoveAnalysis{EvalBefore: cp(100), EvalAfter: cp(100)}, 0.2801},
+		{"mate let go", MoveAnalysis{EvalBefore: mate(3), EvalAfter: cp(0)}, 0.5},
+		{"mate kept", MoveAnalysis{EvalBefore: mate(3), EvalAfter: mate(-2)}, 0},
+		{"improved", MoveAnalysis{EvalBefore: cp(-50), EvalAfter: cp(20)}, 0},
+		{"forced", MoveAnalysis{EvalBefore: cp(300), EvalAfter: cp(0), Forced: true}, 0},
+		{"mates", MoveAnalysis{EvalBefore: mate(1), EvalAfter: mate(0), FENAfter: foolsMate}, 0},
+	}
+	for _, tt := range tests {
+		if got := ExpectedPointsLoss(&tt.move); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("%s: %v expected points lost, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeGame_ExpectedPointsLost(t *testing.T) {
+	// White gives away its queen: black is 900 up after 3. Nf3
+	pgn := "[Result \"0-1\"]\n\n1. e4 f5 2. Qh5+ g6 3. Nf3 gxh5 0-1"
+	fens, err := PGNPositions(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := enginetest.Engine{Evals: map[string]enginetest.Eval{fens[5]: {Centipawns: 900, BestMove: "g6h5"}}}
+	a := NewAnalyzer(newEnginePool(t, fake), zap.NewNop(), 1, 12, 20, time.Minute)
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", pgn, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var white, black float64
+	for i := range analysis.Moves {
+		move := &analysis.Moves[i]
+		if move.ExpectedPointsLoss != ExpectedPointsLoss(move) {
+			t.Errorf("ply %d: %v expected points lost, want %v", move.Ply, move.ExpectedPointsLoss, ExpectedPointsLoss(move))
+		}
+		if move.Color == "white" {
+			white += move.ExpectedPointsLoss
+		} else {
+			black += move.ExpectedPointsLoss
+		}
+	}
+	if white <= black {
+		t.Errorf("white lost %v expected points, black %v", white, black)
+	}
+	if analysis.WhiteMetrics.ExpectedPointsLost != white || analysis.BlackMetrics.ExpectedPointsLost != black {
+		t.Errorf("players lost %v and %v expected points, moves %v and %v",
+			analysis.WhiteMetrics.ExpectedPointsLost, analysis.BlackMetrics.ExpectedPointsLost, white, black)
+	}
+}
@@ -0,0 +1,216 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/notnil/chess"
+)
+
+// NormalizeUCI returns move in the canonical UCI form engines and the
+// chess package use: lowercase, without check or annotation marks, and
+// with a promotion written "e7e8q" rather than "e7e8=Q"
+func NormalizeUCI(move string) string {
+	move = strings.TrimRight(strings.TrimSpace(move), "+#!?")
+	return strings.ToLower(strings.ReplaceAll(move, "=", ""))
+}
+
+// SameMove reports whether two UCI moves are the same move once
+// normalized. Empty moves never match.
+func SameMove(a, b string) bool {
+	a, b = NormalizeUCI(a), NormalizeUCI(b)
+	return a != "" && a == b
+}
+
+// UCIToSAN converts a UCI move played from fen to SAN. The move is matched
+// against the legal moves, so a check or mate is marked as in the played
+// moves of a game, e.g. "e8=Q+" or "e8=Q#". Conversions are memoized, see
+// sanMemo, and a panic in the chess package is returned as an error.
+func UCIToSAN(fen, move string) (string, error) {
+	if san, ok := sanMemo.get(fen, move); ok {
+		return san, nil
+	}
+	return decodePosition(fen).san(move)
+}
+
+// fenPosition is a FEN decoded once, to convert several moves played from
+// it without decoding it again for each
+type fenPosition struct {
+	fen      string
+	position *chess.Position
+	err      error // Why the FEN doesn't decode; position is nil then
+}
+
+func decodePosition(fen string) (p fenPosition) {
+	p.fen = fen
+	defer func() {
+		if r := recover(); r != nil {
+			p.position, p.err = nil, fmt.Errorf("decode FEN %q: %v", fen, r)
+		}
+	}()
+	fenOpt, err := chess.FEN(fen)
+	if err != nil {
+		p.err = err
+		return p
+	}
+	p.position = chess.NewGame(fenOpt).Position()
+	return p
+}
+
+// san converts move to SAN as UCIToSAN does
+func (p fenPosition) san(move string) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	if san, ok := sanMemo.get(p.fen, move); ok {
+		return san, nil
+	}
+	san, err := sanFrom(p.position, move)
+	if err != nil {
+		return "", err
+	}
+	sanMemo.put(p.fen, move, san)
+	return san, nil
+}
+
+// canonical returns move as canonicalUCI does
+func (p fenPosition) canonical(move string) string {
+	if p.err == nil {
+		if legal, ok := legalUCI(p.position, move); ok {
+			return (chess.UCINotation{}).Encode(p.position, legal)
+		}
+	}
+	return NormalizeUCI(move)
+}
+
+// same reports whether a and b are the same move as SameMoveFrom does
+func (p fenPosition) same(a, b string) bool {
+	if p.err != nil {
+		return SameMove(a, b)
+	}
+	moveA, okA := legalUCI(p.position, a)
+	moveB, okB := legalUCI(p.position, b)
+	if !okA || !okB {
+		return SameMove(a, b)
+	}
+	return p.position.Update(moveA).String() == p.position.Update(moveB).String()
+}
+
+// sanFrom converts move, played in position, to SAN. A panic on the way,
+// as a malformed move from a broken engine build once caused, is returned
+// as an error so the caller can fall back to the UCI.
+func sanFrom(position *chess.Position, move string) (san string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			san, err = "", fmt.Errorf("convert %q to SAN: %v", move, r)
+		}
+	}()
+	legal, ok := legalUCI(position, move)
+	if !ok {
+		return "", fmt.Errorf("%q is not a legal move", move)
+	}
+	return chess.AlgebraicNotation{}.Encode(position, legal), nil
+}
+
+// sanMemoSize bounds sanMemo. Openings repeat the same few hundred
+// conversions across games; past the bound the memo starts over.
+const sanMemoSize = 4096
+
+// sanMemo memoizes UCI to SAN conversions by FEN and move
+var sanMemo = &sanCache{entries: make(map[sanKey]string, sanMemoSize)}
+
+type sanKey struct{ fen, move string }
+
+// sanCache is a bounded memo of conversions, emptied when it fills up
+type sanCache struct {
+	mu      sync.Mutex
+	entries map[sanKey]string
+}
+
+func (c *sanCache) get(fen, move string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	san, ok := c.entries[sanKey{fen, move}]
+	return san, ok
+}
+
+func (c *sanCache) put(fen, move, san string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= sanMemoSize {
+		clear(c.entries)
+	}
+	c.entries[sanKey{fen, move}] = san
+}
+
+// PlayUCI returns the FEN after playing moves, in UCI notation, from fen.
+// Every move must be legal in turn.
+func PlayUCI(fen string, moves ...string) (string, error) {
+	fenOpt, err := chess.FEN(fen)
+	if err != nil {
+		return "", err
+	}
+	position := chess.NewGame(fenOpt).Position()
+	for _, move := range moves {
+		legal, ok := legalUCI(position, move)
+		if !ok {
+			return "", fmt.Errorf("%q is not a legal move", move)
+		}
+		position = position.Update(legal)
+	}
+	return position.String(), nil
+}
+
+// SameMoveFrom reports whether UCI moves a and b, played from fen, are the
+// same move: they are decoded against the position and compared by the
+// positions they lead to, so encodings differing only in spelling, like
+// castling as e1g1 or as the king taking its rook (e1h1), match. Moves that
+// can't be decoded there are compared as SameMove does.
+func SameMoveFrom(fen, a, b string) bool {
+	return decodePosition(fen).same(a, b)
+}
+
+// canonicalUCI returns move, played from fen, in the UCI the chess package
+// encodes the legal move it stands for with, e.g. e1g1 for e1h1 castling.
+// A move that isn't legal there is only normalized.
+func canonicalUCI(fen, move string) string {
+	return decodePosition(fen).canonical(move)
+}
+
+// legalUCI returns the legal move of position that move, in UCI notation,
+// stands for. Castling written as the king taking its own rook, as engines
+// do in Chess960 mode, is read as the king's two-square move.
+func legalUCI(position *chess.Position, move string) (*chess.Move, bool) {
+	move = NormalizeUCI(move)
+	if castling, ok := kingTakesRook(position, move); ok {
+		move = castling
+	}
+	for _, m := range position.ValidMoves() {
+		if (chess.UCINotation{}).Encode(position, m) == move {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// kingTakesRook returns the standard UCI of move when it is castling
+// encoded as the king moving onto a rook of its own color on its rank
+func kingTakesRook(position *chess.Position, move string) (string, bool) {
+	if len(move) != 4 || move[1] != move[3] {
+		return "", false
+	}
+	m, err := (chess.UCINotation{}).Decode(nil, move)
+	if err != nil {
+		return "", false
+	}
+	king, rook := position.Board().Piece(m.S1()), position.Board().Piece(m.S2())
+	if king.Type() != chess.King || rook.Type() != chess.Rook || king.Color() != rook.Color() {
+		return "", false
+	}
+	file := "g"
+	if move[2] < move[0] {
+		file = "c"
+	}
+	return move[:2] + file + move[3:], true
+}
@@ -0,0 +1,258 @@
+package analyzer
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+	"github.com/notnil/chess"
+	"go.uber.org/zap"
+)
+
+// promotionTests cover each promotion piece pushing to e8 or capturing on
+// d8, quietly, with check and with mate
+var promotionTests = []struct {
+	name string
+	fen  string
+	uci  string
+	san  string
+}{
+	{"queen", "8/4P3/8/8/7k/8/8/K7 w - - 0 1", "e7e8q", "e8=Q"},
+	{"rook", "8/4P3/8/8/7k/8/8/K7 w - - 0 1", "e7e8r", "e8=R"},
+	{"bishop", "8/4P3/8/8/7k/8/8/K7 w - - 0 1", "e7e8b", "e8=B"},
+	{"knight", "8/4P3/8/8/7k/8/8/K7 w - - 0 1", "e7e8n", "e8=N"},
+	{"queen check", "8/4P3/8/7k/8/8/8/K7 w - - 0 1", "e7e8q", "e8=Q+"},
+	{"rook check", "7k/4P3/8/8/8/8/8/K7 w - - 0 1", "e7e8r", "e8=R+"},
+	{"bishop check", "8/4P3/8/7k/8/8/8/K7 w - - 0 1", "e7e8b", "e8=B+"},
+	{"knight check", "8/4P1k1/8/8/8/8/8/K7 w - - 0 1", "e7e8n", "e8=N+"},
+	{"queen mate", "7k/4P1pp/8/8/8/8/8/K7 w - - 0 1", "e7e8q", "e8=Q#"},
+	{"rook mate", "7k/4P1pp/8/8/8/8/8/K7 w - - 0 1", "e7e8r", "e8=R#"},
+	{"queen capture", "3r4/4P3/8/8/8/7k/8/K7 w - - 0 1", "e7d8q", "exd8=Q"},
+	{"rook capture", "3r4/4P3/8/8/8/7k/8/K7 w - - 0 1", "e7d8r", "exd8=R"},
+	{"bishop capture", "3r4/4P3/8/8/8/7k/8/K7 w - - 0 1", "e7d8b", "exd8=B"},
+	{"knight capture", "3r4/4P3/8/8/8/7k/8/K7 w - - 0 1", "e7d8n", "exd8=N"},
+	{"queen capture check", "3r4/4P3/8/8/7k/8/8/K7 w - - 0 1", "e7d8q", "exd8=Q+"},
+	{"rook capture check", "3r3k/4P3/8/8/8/8/8/K7 w - - 0 1", "e7d8r", "exd8=R+"},
+	{"bishop capture check", "3r4/4P3/8/8/7k/8/8/K7 w - - 0 1", "e7d8b", "exd8=B+"},
+	{"knight capture check", "3r4/4Pk2/8/8/8/8/8/K7 w - - 0 1", "e7d8n", "exd8=N+"},
+	{"black knight capture", "k7/8/8/8/8/8/3p4/4R2K b - - 0 1", "d2e1n", "dxe1=N"},
+}
+
+func TestUCIToSAN_Promotions(t *testing.T) {
+	for _, tt := range promotionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, uci := range spellings(tt.uci) {
+				san, err := UCIToSAN(tt.fen, uci)
+				if err != nil || san != tt.san {
+					t.Errorf("UCIToSAN(%q) = %q, %v; want %q", uci, san, err, tt.san)
+				}
+			}
+		})
+	}
+}
+
+// spellings returns a promotion in UCI as engines and clients have sent it
+func spellings(uci string) []string {
+	promo := strings.ToUpper(uci[4:])
+	return []string{uci, uci[:4] + promo, uci[:4] + "=" + promo + "+"}
+}
+
+func TestUCIToSAN_Illegal(t *testing.T) {
+	fen := "8/4P3/8/8/7k/8/8/K7 w - - 0 1"
+	for _, uci := range []string{"e7e8", "e7e8k", "e7d8q", "e2e4", ""} {
+		if san, err := UCIToSAN(fen, uci); err == nil {
+			t.Errorf("UCIToSAN(%q) = %q, want an error", uci, san)
+		}
+	}
+}
+
+func TestSANFrom_RecoversPanic(t *testing.T) {
+	// A nil position panics in the chess package
+	if san, err := sanFrom(nil, "e2e4"); err == nil {
+		t.Errorf("sanFrom(nil) = %q, want an error", san)
+	}
+}
+
+func TestUCIToSAN_FallsBackToUCI(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	for _, fen := range []string{"not a fen", startFEN} {
+		if got := a.uciToSAN(decodePosition(fen), "e2e5"); got != "e2e5" {
+			t.Errorf("uciToSAN(%q, e2e5) = %q, want the UCI", fen, got)
+		}
+	}
+}
+
+func TestSANMemo(t *testing.T) {
+	memo := &sanCache{entries: make(map[sanKey]string)}
+	if _, ok := memo.get(startFEN, "e2e4"); ok {
+		t.Fatal("hit in an empty memo")
+	}
+	memo.put(startFEN, "e2e4", "e4")
+	if san, ok := memo.get(startFEN, "e2e4"); !ok || san != "e4" {
+		t.Errorf("get = %q, %v; want e4", san, ok)
+	}
+	for i := 0; len(memo.entries) < sanMemoSize; i++ {
+		memo.put(startFEN, strings.Repeat("x", i), "")
+	}
+	memo.put(startFEN, "g1f3", "Nf3")
+	if len(memo.entries) != 1 {
+		t.Errorf("%d entries after filling up, want 1", len(memo.entries))
+	}
+
+	// Conversions land in the shared memo, failures don't
+	fen := "8/4P3/8/8/7k/8/8/K7 w - - 0 1"
+	if _, err := UCIToSAN(fen, "e7e8q"); err != nil {
+		t.Fatal(err)
+	}
+	if san, ok := sanMemo.get(fen, "e7e8q"); !ok || san != "e8=Q" {
+		t.Errorf("memo has %q, %v; want e8=Q", san, ok)
+	}
+	UCIToSAN(fen, "e7e8")
+	if _, ok := sanMemo.get(fen, "e7e8"); ok {
+		t.Error("memoized an illegal move")
+	}
+}
+
+func TestSameMove(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"e7e8q", "e7e8q", true},
+		{"e7e8q", "e7e8Q", true},
+		{"e7e8q", "e7e8=Q+", true},
+		{"e7e8q", " e7e8q# ", true},
+		{"e7e8q", "e7e8n", false},
+		{"e7e8q", "e7e8", false},
+		{"e2e4", "e2e4!?", true},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := SameMove(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameMove(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCreateMoveAnalysis_PromotionIsBest(t *testing.T) {
+	a := &Analyzer{logger: zap.NewNop()}
+	eval := engine.Evaluation{Centipawns: 900}
+	// More loss than the best threshold allows: only matching the engine's
+	// move makes it best
+	after := engine.Evaluation{Centipawns: -850}
+
+	for _, tt := range promotionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos := Position{FEN: tt.fen, LegalMoves: 10}
+			next := Position{MoveSAN: tt.san, MoveUCI: tt.uci}
+			for _, bestMove := range spellings(tt.uci) {
+				move := a.createMoveAnalysis(0, pos, next, &eval, &after, bestMove, evaluation.DefaultThresholds)
+				if move.Classification != ClassBest || move.BestMove != tt.san || move.BestMoveUCI != tt.uci {
+					t.Errorf("best move %q: %s, best %q (%q), want best %q (%q)",
+						bestMove, move.Classification, move.BestMove, move.BestMoveUCI, tt.san, tt.uci)
+				}
+			}
+		})
+	}
+}
+
+// castlingFEN has both sides free to castle either way
+const castlingFEN = "r3k2r/pppq1ppp/2npbn2/2b1p3/2B1P3/2NPBN2/PPPQ1PPP/R3K2R w KQkq - 0 1"
+
+func TestSameMoveFrom(t *testing.T) {
+	black := strings.Replace(castlingFEN, " w ", " b ", 1)
+	enPassant := "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3"
+	tests := []struct {
+		name string
+		fen  string
+		a, b string
+		want bool
+	}{
+		{"white kingside", castlingFEN, "e1g1", "e1h1", true},
+		{"white queenside", castlingFEN, "e1c1", "e1a1", true},
+		{"black kingside", black, "e8g8", "e8h8", true},
+		{"black queenside", black, "e8c8", "e8a8", true},
+		{"kingside is not queenside", castlingFEN, "e1g1", "e1a1", false},
+		{"king step is not castling", castlingFEN, "e1f1", "e1g1", false},
+		{"rook move is not castling", castlingFEN, "h1f1", "e1h1", false},
+		{"en passant", enPassant, "e5f6", "E5F6", true},
+		{"en passant is not the other capture", enPassant, "e5f6", "e5d6", false},
+		{"illegal falls back to spelling", castlingFEN, "e1e3", "e1e3", true},
+		{"bad FEN falls back to spelling", "not a fen", "e1g1", "e1h1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SameMoveFrom(tt.fen, tt.a, tt.b); got != tt.want {
+				t.Errorf("SameMoveFrom(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateMoveAnalysis_CastlingIsBest(t *testing.T) {
+	a := &Analyzer{logger: zap.NewNop()}
+	eval := engine.Evaluation{Centipawns: 100}
+	after := engine.Evaluation{Centipawns: -50}
+
+	tests := []struct {
+		played, best, san string
+	}{
+		{"e1g1", "e1h1", "O-O"},
+		{"e1c1", "e1a1", "O-O-O"},
+		{"e1g1", "e1g1", "O-O"},
+	}
+	for _, tt := range tests {
+		pos := Position{FEN: castlingFEN, LegalMoves: 40}
+		next := Position{MoveSAN: tt.san, MoveUCI: tt.played}
+		move := a.createMoveAnalysis(0, pos, next, &eval, &after, tt.best, evaluation.DefaultThresholds)
+		if move.Classification != ClassBest || move.BestMoveUCI != tt.played || move.BestMove != tt.san {
+			t.Errorf("played %s, engine %s: %s, best %q (%q)", tt.played, tt.best, move.Classification, move.BestMove, move.BestMoveUCI)
+		}
+	}
+}
+
+// BenchmarkMoveNotation converts to SAN what building and exporting the
+// analysis of a 100-move game does for each move: the best move, a 10-ply
+// best line and, as with alternatives enabled, the first moves of three
+// more lines. The best line is the game's own continuation.
+func BenchmarkMoveNotation(b *testing.B) {
+	pgn, err := os.ReadFile("testdata/hundred_moves.pgn")
+	if err != nil {
+		b.Fatal(err)
+	}
+	positions, err := ParsePGN(string(pgn))
+	if err != nil {
+		b.Fatal(err)
+	}
+	a := NewAnalyzer(nil, zap.NewNop(), 1, 12, 20, time.Minute)
+	pvs := make([][]string, len(positions)-1)
+	alternatives := make([][]string, len(positions)-1)
+	for i := range pvs {
+		for j := i + 1; j < len(positions) && len(pvs[i]) < 10; j++ {
+			pvs[i] = append(pvs[i], positions[j].MoveUCI)
+		}
+		position := decodePosition(positions[i].FEN).position
+		for _, m := range position.ValidMoves() {
+			if len(alternatives[i]) == 3 {
+				break
+			}
+			alternatives[i] = append(alternatives[i], (chess.UCINotation{}).Encode(position, m))
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i, pv := range pvs {
+			eval := engine.Evaluation{Depth: 12, Centipawns: 20, PV: pv}
+			move := a.createMoveAnalysis(i, positions[i], positions[i+1], &eval, &eval, pv[0], evaluation.DefaultThresholds)
+			bestLineSAN(move.FENBefore, move.PV, bestLinePlies)
+			for _, alternative := range alternatives[i] {
+				UCIToSAN(move.FENBefore, alternative)
+			}
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// PGNMoveError reports a move of the movetext that is illegal, ambiguous
+// or unreadable in its position. It matches ErrInvalidPGN.
+type PGNMoveError struct {
+	Ply        int    // 0-indexed, like MoveAnalysis.Ply
+	MoveNumber int    // 1-indexed
+	Color      string // "white" or "black"
+	SAN        string // Token as written in the PGN
+	Context    string // Movetext around the token
+	Reason     string
+}
+
+func (e *PGNMoveError) Error() string {
+	return fmt.Sprintf("%s: %s at ply %d: %s (near %q)", ErrInvalidPGN, e.label(), e.Ply, e.Reason, e.Context)
+}
+
+// Unwrap makes the error match ErrInvalidPGN
+func (e *PGNMoveError) Unwrap() error {
+	return ErrInvalidPGN
+}
+
+// label formats the move with its number, "12. Nf3" or "12... Nf6"
+func (e *PGNMoveError) label() string {
+	if e.Color == "black" {
+		return fmt.Sprintf("%d... %s", e.MoveNumber, e.SAN)
+	}
+	return fmt.Sprintf("%d. %s", e.MoveNumber, e.SAN)
+}
+
+// pgnContextChars is how much movetext around a bad move an error shows
+const pgnContextChars = 24
+
+// pgnToken is a move of the movetext and where it starts
+type pgnToken struct {
+	text   string
+	offset int
+
+	// clock is the mover's time left from a [%clk] comment after the move
+	clock    time.Duration
+	hasClock bool
+}
+
+var (
+	// moveNumberPattern matches a move number prefix, "12." or "12..."
+	moveNumberPattern = regexp.MustCompile(`^\d+\.+`)
+
+	// clockPattern matches a [%clk h:mm:ss] command, seconds may have a
+	// fraction
+	clockPattern = regexp.MustCompile(`\[%clk\s+(\d+):(\d{1,2}):(\d{1,2}(?:\.\d+)?)\s*\]`)
+
+	// sanPattern splits a piece move or pawn move into piece, origin
+	// hints, destination and promotion; capture marks are optional
+	sanPattern = regexp.MustCompile(`^([NBRQKP])?([a-h])?([1-8])?[x:]?([a-h][1-8])(?:=?([NBRQ]))?$`)
+)
+
+// pgnResults end the movetext
+var pgnResults = map[string]bool{"1-0": true, "0-1": true, "1/2-1/2": true, "*": true}
+
+// pgnMovetext returns the PGN without its tag pair lines
+func pgnMovetext(pgn string) string {
+	var lines []string
+	for _, line := range strings.Split(pgn, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "%") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// startingFEN returns the FEN tag of a game set up from a position, false
+// for a game from the standard starting position
+func startingFEN(tags [][2]string) (string, bool) {
+	fen := strings.TrimSpace(tagValue(tags, "FEN"))
+	if fen == "" || strings.TrimSpace(tagValue(tags, "SetUp")) == "0" {
+		return "", false
+	}
+	return fen, true
+}
+
+// StartingFEN returns the FEN of the position pgn's game starts from, its
+// FEN tag or the standard starting position
+func StartingFEN(pgn string) string {
+	if fen, ok := startingFEN(parsePGNTags(pgn)); ok {
+		return fen
+	}
+	return chess.StartingPosition().String()
+}
+
+// PlyMove returns the color and move number of the move at ply, 0-indexed,
+// of a game starting from startFEN: ply 0 is the move of the side to move
+// there, numbered with the FEN's fullmove number
+func PlyMove(startFEN string, ply int) (color string, moveNumber int) {
+	fields := strings.Fields(startFEN)
+	if len(fields) > 1 && fields[1] == "b" {
+		ply++
+	}
+	moveNumber = 1
+	if len(fields) > 5 {
+		if n, err := strconv.Atoi(fields[5]); err == nil && n > 0 {
+			moveNumber = n
+		}
+	}
+	color = "white"
+	if ply%2 == 1 {
+		color = "black"
+	}
+	return color, moveNumber + ply/2
+}
+
+// PGNGame is one game of a PGN of one or more games
+type PGNGame struct {
+	Text string
+	Tags [][2]string // Name and value, in file order; values keep their escapes
+}
+
+// Tag returns the value of the tag name, "" if the game has none
+func (g PGNGame) Tag(name string) string {
+	return tagValue(g.Tags, name)
+}
+
+// SplitPGN splits a PGN of one or more games into its games. A tag line
+// after movetext starts the next game; a PGN without tags is read as a
+// single game. Blank and escape lines are dropped, as are games with no
+// movetext.
+func SplitPGN(pgn string) []PGNGame {
+	pgn = strings.TrimPrefix(pgn, "\ufeff")
+
+	var games []PGNGame
+	var current PGNGame
+	var lines []string
+	inMovetext := false
+
+	flush := func() {
+		if inMovetext {
+			current.Text = strings.Join(lines, "\n")
+			games = append(games, current)
+		}
+		current, lines, inMovetext = PGNGame{}, nil, false
+	}
+
+	for _, line := range strings.Split(pgn, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "%"):
+			continue
+		case strings.HasPrefix(trimmed, "["):
+			if inMovetext {
+				flush()
+			}
+			if m := pgnTagPattern.FindStringSubmatch(trimmed); m != nil {
+				current.Tags = append(current.Tags, [2]string{m[1], m[2]})
+			}
+		default:
+			inMovetext = true
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return games
+}
+
+// tokenizeMovetext returns the moves of the main line. Comments,
+// variations, NAGs, move numbers and the result are skipped, except for a
+// [%clk] command in a comment after a move; movetext after the result is
+// ignored.
+func tokenizeMovetext(movetext string) ([]pgnToken, error) {
+	var tokens []pgnToken
+	for i := 0; i < len(movetext); {
+		switch c := movetext[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '{':
+			end := strings.IndexByte(movetext[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated comment at offset %d", ErrInvalidPGN, i)
+			}
+			if clock, ok := parseClock(movetext[i : i+end]); ok && len(tokens) > 0 {
+				tokens[len(tokens)-1].clock, tokens[len(tokens)-1].hasClock = clock, true
+			}
+			i += end + 1
+		case c == ';':
+			end := strings.IndexByte(movetext[i:], '\n')
+			if end < 0 {
+				return tokens, nil
+			}
+			i += end + 1
+		case c == '(':
+			end, err := skipVariation(movetext, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case c == ')' || c == '}':
+			return nil, fmt.Errorf("%w: unbalanced %q at offset %d", ErrInvalidPGN, c, i)
+		default:
+			start := i
+			for i < len(movetext) && !strings.ContainsRune(" \t\n\r{};()", rune(movetext[i])) {
+				i++
+			}
+			text := movetext[start:i]
+			if pgnResults[text] {
+				return tokens, nil
+			}
+			if strings.HasPrefix(text, "$") {
+				continue
+			}
+			number := moveNumberPattern.FindString(text)
+			if text = text[len(number):]; text == "" {
+				continue
+			}
+			tokens = append(tokens, pgnToken{text: text, offset: start + len(number)})
+		}
+	}
+	return tokens, nil
+}
+
+// parseClock reads the [%clk] command of a comment
+func parseClock(comment string) (time.Duration, bool) {
+	m := clockPattern.FindStringSubmatch(comment)
+	if m == nil {
+		return 0, false
+	}
+	hours, _ := strconv.Atoi(m[1])
+	minutes, _ := strconv.Atoi(m[2])
+	seconds, _ := strconv.ParseFloat(m[3], 64)
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(math.Round(seconds*1000))*time.Millisecond, true
+}
+
+// skipVariation returns the offset just past the variation opened at
+// start, skipping nested variations and comments
+func skipVariation(movetext string, start int) (int, error) {
+	depth := 0
+	for i := start; i < len(movetext); i++ {
+		switch movetext[i] {
+		case '{':
+			end := strings.IndexByte(movetext[i:], '}')
+			if end < 0 {
+				return 0, fmt.Errorf("%w: unterminated comment at offset %d", ErrInvalidPGN, i)
+			}
+			i += end
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%w: unterminated variation at offset %d", ErrInvalidPGN, start)
+}
+
+// movetextContext returns the movetext around a token on one line
+func movetextContext(movetext string, token pgnToken) string {
+	start := max(token.offset-pgnContextChars, 0)
+	end := min(token.offset+len(token.text)+pgnContextChars, len(movetext))
+	return strings.Join(strings.Fields(movetext[start:end]), " ")
+}
+
+// decodeMove reads a move in SAN, long algebraic or UCI notation. SAN that
+// the strict decoder rejects, like a missing or superfluous disambiguation,
+// a wrong capture or check mark or 0-0 castling, is accepted when exactly
+// one legal move fits it.
+func decodeMove(pos *chess.Position, text string) (*chess.Move, error) {
+	decoders := []chess.Decoder{chess.AlgebraicNotation{}, chess.LongAlgebraicNotation{}, chess.UCINotation{}}
+	for _, decoder := range decoders {
+		if move, err := decoder.Decode(pos, text); err == nil {
+			return move, nil
+		}
+	}
+	return resolveSAN(pos, text)
+}
+
+// resolveSAN finds the legal moves text can mean and returns the move
+// when there is exactly one
+func resolveSAN(pos *chess.Position, text string) (*chess.Move, error) {
+	san := strings.TrimRight(strings.TrimSuffix(text, "e.p."), "+#!?")
+	san = strings.ReplaceAll(san, "0", "O")
+
+	var matches []*chess.Move
+	switch san {
+	case "O-O", "O-O-O":
+		tag := chess.KingSideCastle
+		if san == "O-O-O" {
+			tag = chess.QueenSideCastle
+		}
+		for _, move := range pos.ValidMoves() {
+			if move.HasTag(tag) {
+				matches = append(matches, move)
+			}
+		}
+	default:
+		parts := sanPattern.FindStringSubmatch(san)
+		if parts == nil {
+			return nil, errors.New("not a move")
+		}
+		piece, file, rank, to, promo := strings.TrimPrefix(parts[1], "P"), parts[2], parts[3], parts[4], parts[5]
+		for _, move := range pos.ValidMoves() {
+			if pieceLetter(pos.Board().Piece(move.S1()).Type()) != piece ||
+				move.S2().String() != to ||
+				(file != "" && move.S1().File().String() != file) ||
+				(rank != "" && move.S1().Rank().String() != rank) ||
+				pieceLetter(move.Promo()) != promo {
+				continue
+			}
+			matches = append(matches, move)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, errors.New("illegal move")
+	case 1:
+		return matches[0], nil
+	default:
+		candidates := make([]string, len(matches))
+		for i, move := range matches {
+			candidates[i] = chess.AlgebraicNotation{}.Encode(pos, move)
+		}
+		return nil, fmt.Errorf("ambiguous move, could be %s", strings.Join(candidates, " or "))
+	}
+}
+
+// pieceLetter returns the SAN letter of a piece type; empty for pawns
+func pieceLetter(t chess.PieceType) string {
+	switch t {
+	case chess.King:
+		return "K"
+	case chess.Queen:
+		return "Q"
+	case chess.Rook:
+		return "R"
+	case chess.Bishop:
+		return "B"
+	case chess.Knight:
+		return "N"
+	}
+	return ""
+}
@@ -0,0 +1,219 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Games with one bad move: the first, one in the middle and the last
+const (
+	badFirstMovePGN = "1. Ke3 e5 2. Nf3 Nc6 *"
+	badMidGamePGN   = `[Event "Club championship"]
+[White "A"]
+[Black "B"]
+
+1. e4 e5 2. Nf3 {main line} Nc6 3. Bb5 Nf6?? 4. Qxf7 Bc5 5. O-O *`
+	badLastMovePGN = "1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 Nf6 5. O-O Be7 6. Re1 b5 7. Bb3 Kd6 *"
+)
+
+func TestParsePGN_InvalidMove(t *testing.T) {
+	tests := []struct {
+		name       string
+		pgn        string
+		ply        int
+		moveNumber int
+		color      string
+		san        string
+		context    string
+		reason     string
+	}{
+		{"first move", badFirstMovePGN, 0, 1, "white", "Ke3", "1. Ke3 e5 2. Nf3", "illegal move"},
+		{"mid-game", badMidGamePGN, 6, 4, "white", "Qxf7", "Bb5 Nf6?? 4. Qxf7 Bc5 5. O-O", "illegal move"},
+		{"last move", badLastMovePGN, 13, 7, "black", "Kd6", "7. Bb3 Kd6 *", "illegal move"},
+		{"ambiguous", "1. Nf3 Nf6 2. d3 d6 3. Nd2 e5 *", 4, 3, "white", "Nd2", "3. Nd2 e5", "ambiguous move"},
+		{"not a move", "1. e4 e5 2. Nf3 Zz9 *", 3, 2, "black", "Zz9", "Nf3 Zz9", "not a move"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positions, err := ParsePGN(tt.pgn)
+
+			var moveErr *PGNMoveError
+			if !errors.As(err, &moveErr) || !errors.Is(err, ErrInvalidPGN) {
+				t.Fatalf("err = %v, want a PGNMoveError matching ErrInvalidPGN", err)
+			}
+			if moveErr.Ply != tt.ply || moveErr.MoveNumber != tt.moveNumber || moveErr.Color != tt.color || moveErr.SAN != tt.san {
+				t.Errorf("error at ply %d, move %d %s %q", moveErr.Ply, moveErr.MoveNumber, moveErr.Color, moveErr.SAN)
+			}
+			if !strings.Contains(moveErr.Context, tt.context) || !strings.HasPrefix(moveErr.Reason, tt.reason) {
+				t.Errorf("context = %q, reason = %q", moveErr.Context, moveErr.Reason)
+			}
+			// The positions before the bad move come back with the error
+			if len(positions) != tt.ply+1 {
+				t.Errorf("positions = %d, want %d", len(positions), tt.ply+1)
+			}
+		})
+	}
+}
+
+func TestParsePGN_Lenient(t *testing.T) {
+	tests := []struct {
+		name string
+		pgn  string
+		uci  []string
+	}{
+		{
+			name: "comments, variations and NAGs",
+			pgn:  "1. e4 (1. d4 d5 (1... Nf6 2. c4)) e5 {best by test} 2. Nf3 $1 Nc6; line comment\n3. Bb5 1-0",
+			uci:  []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5"},
+		},
+		{
+			name: "moves only",
+			pgn:  "e4 e5 Nf3",
+			uci:  []string{"e2e4", "e7e5", "g1f3"},
+		},
+		{
+			name: "move numbers without tag pairs",
+			pgn:  "1.e4 e5 2.Nf3 2... Nc6",
+			uci:  []string{"e2e4", "e7e5", "g1f3", "b8c6"},
+		},
+		{
+			name: "moves only with a result",
+			pgn:  "e4 e5 Nf3 1/2-1/2",
+			uci:  []string{"e2e4", "e7e5", "g1f3"},
+		},
+		{
+			// The c3 knight is pinned, so only the g1 knight can go to e2
+			name: "missing disambiguation with one legal move",
+			pgn:  "1. d4 e6 2. e4 Bb4+ 3. Nc3 Nf6 4. Ne2 *",
+			uci:  []string{"d2d4", "e7e6", "e2e4", "f8b4", "b1c3", "g8f6", "g1e2"},
+		},
+		{
+			name: "superfluous disambiguation, capture and check marks",
+			pgn:  "1. e4 e5 2. Ngf3 Nc6 3. Bc4 Bc5 4. 0-0 Nxf6 5. Nbc3+ *",
+			uci:  []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1c4", "f8c5", "e1g1", "g8f6", "b1c3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positions, err := ParsePGN(tt.pgn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, pos := range positions[1:] {
+				got = append(got, pos.MoveUCI)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.uci, " ") {
+				t.Errorf("moves = %v, want %v", got, tt.uci)
+			}
+		})
+	}
+}
+
+func TestParsePGN_Unbalanced(t *testing.T) {
+	for _, pgn := range []string{"1. e4 {unterminated", "1. e4 (1. d4 d5", "1. e4 e5 ) 2. Nf3"} {
+		if _, err := ParsePGN(pgn); !errors.Is(err, ErrInvalidPGN) {
+			t.Errorf("ParsePGN(%q) = %v, want ErrInvalidPGN", pgn, err)
+		}
+	}
+}
+
+func TestSplitPGN(t *testing.T) {
+	twoGames := `[Event "Club"]
+[White "Alice"]
+[ECO "C60"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 1-0
+
+% escaped line
+[Event "Club"]
+[White "Bob"]
+
+1. d4 d5 2. c4 e6 0-1
+`
+	games := SplitPGN("\ufeff" + strings.ReplaceAll(twoGames, "\n", "\r\n"))
+	if len(games) != 2 {
+		t.Fatalf("got %d games, want 2", len(games))
+	}
+	if games[0].Tag("White") != "Alice" || games[1].Tag("White") != "Bob" || games[0].Tag("ECO") != "C60" || games[1].Tag("ECO") != "" {
+		t.Errorf("tags = %v / %v", games[0].Tags, games[1].Tags)
+	}
+	if !strings.Contains(games[1].Text, "1. d4 d5") || strings.Contains(games[1].Text, "e4") || strings.ContainsAny(games[1].Text, "\r%") {
+		t.Errorf("second game text = %q", games[1].Text)
+	}
+
+	if games := SplitPGN("e4 e5 Nf3 Nc6"); len(games) != 1 || games[0].Tags != nil {
+		t.Errorf("headerless game = %+v", games)
+	}
+	if games := SplitPGN("\n\n[Event \"No moves\"]\n"); len(games) != 0 {
+		t.Errorf("input without movetext gave %d games", len(games))
+	}
+}
+
+func TestParsePGN_MoveCounters(t *testing.T) {
+	// The engine is sent these FENs, so they carry the game's real clock
+	positions, err := ParsePGN("1. Nf3 Nf6 2. Ng1 Ng8 3. e4 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"w KQkq - 0 1", "b KQkq - 1 1", "w KQkq - 2 2", "b KQkq - 3 2", "w KQkq - 4 3", "b KQkq e3 0 3"} {
+		if !strings.HasSuffix(positions[i].FEN, want) {
+			t.Errorf("position %d: FEN %q, want it to end in %q", i, positions[i].FEN, want)
+		}
+	}
+}
+
+func TestParsePGN_Underpromotion(t *testing.T) {
+	// En passant to e6, then the pawn takes the queen promoting to a knight
+	pgn := "1. e4 d5 2. exd5 e5 3. dxe6 Nf6 4. e7 Nc6 5. exd8=N Kxd8 *"
+	positions, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := positions[5]; got.MoveSAN != "dxe6" || got.MoveUCI != "d5e6" {
+		t.Errorf("en passant: %s (%s), want dxe6 (d5e6)", got.MoveSAN, got.MoveUCI)
+	}
+	if got := positions[9]; got.MoveSAN != "exd8=N" || got.MoveUCI != "e7d8n" {
+		t.Errorf("underpromotion: %s (%s), want exd8=N (e7d8n)", got.MoveSAN, got.MoveUCI)
+	}
+
+	// The SAN read back is the SAN written: parsing it again gives the same game
+	var movetext []string
+	for _, pos := range positions[1:] {
+		movetext = append(movetext, pos.MoveSAN)
+	}
+	again, err := ParsePGN(strings.Join(movetext, " "))
+	if err != nil || len(again) != len(positions) || again[len(again)-1].FEN != positions[len(positions)-1].FEN {
+		t.Errorf("reparsing %q: %d positions, %v", movetext, len(again), err)
+	}
+}
+
+func TestAnalyzeGame_AnalyzeUntilError(t *testing.T) {
+	a := newFakeAnalyzer(t)
+
+	if _, err := a.AnalyzeGame(context.Background(), "g1", badMidGamePGN, 12, GameOptions{}, nil); !errors.Is(err, ErrInvalidPGN) {
+		t.Fatalf("err = %v, want ErrInvalidPGN", err)
+	}
+
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", badMidGamePGN, 12, GameOptions{AnalyzeUntilError: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !analysis.Truncated || analysis.TruncatedAtPly != 6 || analysis.TotalMoves != 6 || len(analysis.Moves) != 6 {
+		t.Errorf("truncated = %v at ply %d, %d of %d moves", analysis.Truncated, analysis.TruncatedAtPly, len(analysis.Moves), analysis.TotalMoves)
+	}
+	if !strings.Contains(analysis.TruncationError, "4. Qxf7") {
+		t.Errorf("truncation error = %q", analysis.TruncationError)
+	}
+
+	// The legal part of the game can still be exported
+	pgn, err := ExportAnnotatedPGN(analysis, badMidGamePGN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(pgn, "Qxf7") || !strings.Contains(pgn, "Nf6") {
+		t.Errorf("exported PGN = %q", pgn)
+	}
+}
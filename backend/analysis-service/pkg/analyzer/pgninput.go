@@ -0,0 +1,135 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// PGNFormat is how a PGN's moves are written
+type PGNFormat string
+
+const (
+	PGNHeadered PGNFormat = "headered" // Tag pairs before the movetext
+	PGNNumbered PGNFormat = "numbered" // Movetext with move numbers, no tag pairs
+	PGNBareSAN  PGNFormat = "bare_san" // Moves alone, "e4 e5 Nf3"
+)
+
+// PGNInput classifies a PGN as given, to tell which kinds of input fail
+// to parse
+type PGNInput struct {
+	Format   PGNFormat
+	Result   bool // The movetext has a result: 1-0, 0-1, 1/2-1/2 or *
+	Comments bool // The movetext has {} or ; comments
+}
+
+// String names the class, its format followed by +result and +comments
+// when they apply: "numbered+result"
+func (in PGNInput) String() string {
+	s := string(in.Format)
+	if in.Result {
+		s += "+result"
+	}
+	if in.Comments {
+		s += "+comments"
+	}
+	return s
+}
+
+// moveNumberTokenPattern finds a move number in movetext, "12." or "12..."
+// starting a token
+var moveNumberTokenPattern = regexp.MustCompile(`(?:^|\s)\d+\.`)
+
+// ClassifyPGN returns the class of pgn, a single game
+func ClassifyPGN(pgn string) PGNInput {
+	var in PGNInput
+	movetext := pgnMovetext(pgn)
+	switch {
+	case len(parsePGNTags(pgn)) > 0:
+		in.Format = PGNHeadered
+	case moveNumberTokenPattern.MatchString(movetext):
+		in.Format = PGNNumbered
+	default:
+		in.Format = PGNBareSAN
+	}
+	for _, field := range strings.Fields(movetext) {
+		if pgnResults[field] {
+			in.Result = true
+			break
+		}
+	}
+	in.Comments = strings.ContainsAny(movetext, "{;")
+	return in
+}
+
+// PGNParseError is a parse error of a PGN with the class of the input,
+// which it wraps: a *PGNMoveError or an error matching ErrInvalidPGN
+type PGNParseError struct {
+	Input PGNInput
+	Err   error
+}
+
+func (e *PGNParseError) Error() string {
+	return fmt.Sprintf("%v (%s input)", e.Err, e.Input)
+}
+
+func (e *PGNParseError) Unwrap() error {
+	return e.Err
+}
+
+// PGNParseCounts are the games of a PGN class that parsed and that didn't
+type PGNParseCounts struct {
+	Parsed int64 `json:"parsed"`
+	Failed int64 `json:"failed"`
+}
+
+// pgnInputCounts counts games parsed for analysis by PGN class. The zero
+// value is ready to use.
+type pgnInputCounts struct {
+	mu     sync.Mutex
+	counts map[string]PGNParseCounts
+}
+
+func (c *pgnInputCounts) add(in PGNInput, parsed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]PGNParseCounts)
+	}
+	counts := c.counts[in.String()]
+	if parsed {
+		counts.Parsed++
+	} else {
+		counts.Failed++
+	}
+	c.counts[in.String()] = counts
+}
+
+func (c *pgnInputCounts) snapshot() map[string]PGNParseCounts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[string]PGNParseCounts, len(c.counts))
+	for class, n := range c.counts {
+		counts[class] = n
+	}
+	return counts
+}
+
+// RecordPGNParse counts a game given for analysis under its PGN class, as
+// parsed when err is nil and failed when it matches ErrInvalidPGN. Other
+// errors, refused variants and empty games, aren't parse failures and
+// aren't counted. AnalyzeGame records its games; callers that reject a
+// PGN before handing it to AnalyzeGame record it themselves.
+func (a *Analyzer) RecordPGNParse(pgn string, err error) {
+	if err != nil && !errors.Is(err, ErrInvalidPGN) {
+		return
+	}
+	a.pgnInputs.add(ClassifyPGN(pgn), err == nil)
+}
+
+// PGNParseCounts returns the games recorded by RecordPGNParse by PGN class
+func (a *Analyzer) PGNParseCounts() map[string]PGNParseCounts {
+	return a.pgnInputs.snapshot()
+}
@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestClassifyPGN(t *testing.T) {
+	tests := []struct {
+		pgn  string
+		want string
+	}{
+		{"e4 e5 Nf3", "bare_san"},
+		{"e4 e5 Nf3 1-0", "bare_san+result"},
+		{"e4 {the king's pawn} e5; a comment", "bare_san+comments"},
+		{"1.e4 e5 2.Nf3", "numbered"},
+		{"1. e4 1... e5 0-1", "numbered+result"},
+		{"[Event \"Casual\"]\n\n1. e4 e5 {main line} *", "headered+result+comments"},
+		{"[Event \"Casual\"]\n\ne4 e5", "headered"},
+	}
+	for _, tt := range tests {
+		if got := ClassifyPGN(tt.pgn).String(); got != tt.want {
+			t.Errorf("ClassifyPGN(%q) = %s, want %s", tt.pgn, got, tt.want)
+		}
+	}
+}
+
+func TestParsePGN_ErrorHasInputClass(t *testing.T) {
+	for _, tt := range []struct {
+		pgn  string
+		want PGNInput
+	}{
+		{"1. e4 e5 2. Ke3 *", PGNInput{Format: PGNNumbered, Result: true}},
+		{"e4 {unterminated", PGNInput{Format: PGNBareSAN, Comments: true}},
+	} {
+		_, err := ParsePGN(tt.pgn)
+		var parseErr *PGNParseError
+		if !errors.As(err, &parseErr) || !errors.Is(err, ErrInvalidPGN) {
+			t.Fatalf("ParsePGN(%q) = %v, want a PGNParseError matching ErrInvalidPGN", tt.pgn, err)
+		}
+		if parseErr.Input != tt.want {
+			t.Errorf("ParsePGN(%q) input %+v, want %+v", tt.pgn, parseErr.Input, tt.want)
+		}
+	}
+
+	// The bad move is still there to find
+	var moveErr *PGNMoveError
+	if _, err := ParsePGN("1. e4 e5 2. Ke3 *"); !errors.As(err, &moveErr) || moveErr.SAN != "Ke3" {
+		t.Errorf("err = %v, want the PGNMoveError of Ke3", err)
+	}
+}
+
+func TestAnalyzeGame_PGNParseCounts(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	ctx := context.Background()
+	for _, pgn := range []string{"e4 e5", "1. e4 e5 *", "1. e4 e5 2. Ke3 *", "[Variant \"Atomic\"]\n\n1. e4 *"} {
+		a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{}, nil)
+	}
+	// The variant was refused, not misparsed
+	want := map[string]PGNParseCounts{
+		"bare_san":        {Parsed: 1},
+		"numbered+result": {Parsed: 1, Failed: 1},
+	}
+	if got := a.PGNParseCounts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("counts = %v, want %v", got, want)
+	}
+}
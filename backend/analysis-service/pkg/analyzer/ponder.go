@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"context"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"go.uber.org/zap"
+)
+
+// fillPonderMove sets result's PonderMove, when the engine's bestmove line
+// didn't give one, to the reply its best line expects, as for answers
+// from the cache or the cloud
+func fillPonderMove(result *engine.AnalysisResult) {
+	if result.PonderMove != "" || result.BestMove == "" || len(result.Evaluations) == 0 {
+		return
+	}
+	if pv := result.Evaluations[0].PV; len(pv) >= 2 && SameMove(pv[0], result.BestMove) {
+		result.PonderMove = pv[1]
+	}
+}
+
+// SetPonderPrefetch makes position analyses, once answered, search the
+// position after the best move and the ponder move into the cache, so a
+// user stepping through the engine's line finds it there. Prefetches are
+// background work: one at a time, started only while the pool is idle.
+func (a *Analyzer) SetPonderPrefetch(on bool) {
+	a.ponderPrefetch = on
+}
+
+// prefetchPonder starts a ponder prefetch of the position after result,
+// an answer for fen at depth, if one is on and worth it
+func (a *Analyzer) prefetchPonder(fen string, depth int, result *engine.AnalysisResult) {
+	if !a.ponderPrefetch || result.Stopped || result.BestMove == "" || result.PonderMove == "" {
+		return
+	}
+	next, err := PlayUCI(fen, result.BestMove, result.PonderMove)
+	if err != nil || gameOver(next) != "" || a.posCache.has(PrimaryEngine, next, CacheQuery{Depth: depth}) {
+		return
+	}
+	if a.pool.Available() == 0 || a.pool.Waiting() > 0 || !a.prefetching.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer a.prefetching.Store(false)
+		ctx, cancel := a.withTimeout(pool.WithTag(context.Background(), PonderPrefetchTag))
+		defer cancel()
+		if err := a.warmPosition(ctx, next, depth, 1); err != nil {
+			a.logger.Debug("Ponder prefetch failed", zap.String("fen", next), zap.Error(err))
+		}
+	}()
+}
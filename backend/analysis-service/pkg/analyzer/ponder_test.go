@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"go.uber.org/zap"
+)
+
+// lineEngine plays 1. e4 e5 2. Nf3 Nc6 3. Bc4 Bc5 4. c3 Nf6: from each
+// position of it the engine's PV is the rest of the line
+var lineEngine = enginetest.Engine{
+	Evals: map[string]enginetest.Eval{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1":          {Centipawns: 20, BestMove: "e2e4", PV: []string{"e7e5", "g1f3"}},
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1":        {Centipawns: 20, BestMove: "e7e5", PV: []string{"g1f3", "b8c6"}},
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2":      {Centipawns: 20, BestMove: "g1f3", PV: []string{"b8c6", "f1c4"}},
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2":    {Centipawns: 20, BestMove: "b8c6", PV: []string{"f1c4", "f8c5"}},
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3":  {Centipawns: 20, BestMove: "f1c4", PV: []string{"f8c5", "c2c3"}},
+		"r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3": {Centipawns: 20, BestMove: "f8c5", PV: []string{"c2c3", "g8f6"}},
+	},
+	Default: enginetest.Eval{Centipawns: 20, BestMove: "a2a3", PV: []string{"a7a6"}},
+}
+
+func TestFillPonderMove(t *testing.T) {
+	tests := []struct {
+		name   string
+		result engine.AnalysisResult
+		want   string
+	}{
+		{"from the engine", engine.AnalysisResult{BestMove: "e2e4", PonderMove: "c7c5", Evaluations: []engine.Evaluation{{PV: []string{"e2e4", "e7e5"}}}}, "c7c5"},
+		{"from the PV", engine.AnalysisResult{BestMove: "e2e4", Evaluations: []engine.Evaluation{{PV: []string{"e2e4", "e7e5"}}}}, "e7e5"},
+		{"PV of another move", engine.AnalysisResult{BestMove: "d2d4", Evaluations: []engine.Evaluation{{PV: []string{"e2e4", "e7e5"}}}}, ""},
+		{"PV too short", engine.AnalysisResult{BestMove: "e2e4", Evaluations: []engine.Evaluation{{PV: []string{"e2e4"}}}}, ""},
+	}
+	for _, tt := range tests {
+		fillPonderMove(&tt.result)
+		if tt.result.PonderMove != tt.want {
+			t.Errorf("%s: ponder move %q, want %q", tt.name, tt.result.PonderMove, tt.want)
+		}
+	}
+}
+
+// TestPonderPrefetch_SteppingThroughLine has a user step through the
+// engine's line a position at a time, thinking long enough between steps
+// for a prefetch to finish, and compares the cache hit rates
+func TestPonderPrefetch_SteppingThroughLine(t *testing.T) {
+	line := []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1c4"}
+	steps := []string{startFEN}
+	for _, move := range line {
+		next, err := PlayUCI(steps[len(steps)-1], move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		steps = append(steps, next)
+	}
+
+	hitRate := func(prefetch bool) float64 {
+		a := NewAnalyzer(newEnginePool(t, lineEngine), zap.NewNop(), 1, 12, 20, time.Minute)
+		a.SetPonderPrefetch(prefetch)
+		for i, fen := range steps {
+			result, err := a.AnalyzePosition(context.Background(), fen, 12, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i < len(line)-1 && (result.BestMove != line[i] || result.PonderMove != line[i+1]) {
+				t.Fatalf("step %d: best %s ponder %s, want %s %s", i, result.BestMove, result.PonderMove, line[i], line[i+1])
+			}
+			for a.prefetching.Load() {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		stats := a.CacheStats()
+		return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+	}
+
+	without, with := hitRate(false), hitRate(true)
+	t.Logf("cache hit rate stepping through %d positions: %.0f%% without prefetch, %.0f%% with", len(steps), without*100, with*100)
+	// Every position from the third on was prefetched two steps before
+	if without != 0 || with != 4.0/6 {
+		t.Errorf("hit rate %.2f without prefetch and %.2f with, want 0 and %.2f", without, with, 4.0/6)
+	}
+}
+
+func TestPonderPrefetch_YieldsToRequests(t *testing.T) {
+	a := NewAnalyzer(newEnginePool(t, lineEngine), zap.NewNop(), 1, 12, 20, time.Minute)
+	a.SetPonderPrefetch(true)
+
+	// The only engine is lent out: nothing to prefetch with
+	eng, err := a.pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.prefetchPonder(startFEN, 12, &engine.AnalysisResult{BestMove: "e2e4", PonderMove: "e7e5"})
+	if a.prefetching.Load() {
+		t.Error("prefetch started without a free engine")
+	}
+	a.pool.Put(eng)
+}
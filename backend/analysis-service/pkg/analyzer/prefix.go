@@ -0,0 +1,67 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/notnil/chess"
+)
+
+// PrefixEvaluation is an earlier evaluation of a position before
+// GameOptions.StartPly, reused instead of searching it again
+type PrefixEvaluation struct {
+	Ply      int               // Position before this ply's move
+	Eval     engine.Evaluation // From the side to move, with the depth it was searched to
+	BestMove string            // UCI, optional
+	FEN      string            // Optional, checked against the game
+}
+
+// seedPrefix checks that prefix holds exactly one evaluation for each
+// position before startPly and returns them by ply. A mismatch with the
+// positions is an ErrPrefixMismatch.
+func seedPrefix(positions []Position, startPly int, prefix []PrefixEvaluation) ([]*PrefixEvaluation, error) {
+	if startPly == 0 && len(prefix) == 0 {
+		return nil, nil
+	}
+	if startPly < 0 || startPly >= len(positions)-1 {
+		return nil, fmt.Errorf("%w: start ply %d is not a move of the game's %d", ErrPrefixMismatch, startPly, len(positions)-1)
+	}
+
+	byPly := make([]*PrefixEvaluation, startPly)
+	for i := range prefix {
+		p := &prefix[i]
+		if p.Ply < 0 || p.Ply >= startPly {
+			return nil, fmt.Errorf("%w: ply %d is not before start ply %d", ErrPrefixMismatch, p.Ply, startPly)
+		}
+		if byPly[p.Ply] != nil {
+			return nil, fmt.Errorf("%w: ply %d is given twice", ErrPrefixMismatch, p.Ply)
+		}
+		pos := positions[p.Ply]
+		if p.FEN != "" && !samePosition(p.FEN, pos.FEN) {
+			return nil, fmt.Errorf("%w: ply %d is %q, not %q", ErrPrefixMismatch, p.Ply, p.FEN, pos.FEN)
+		}
+		if p.BestMove != "" {
+			fenOpt, err := chess.FEN(pos.FEN)
+			if err != nil || !isLegalUCI(chess.NewGame(fenOpt).Position(), p.BestMove) {
+				return nil, fmt.Errorf("%w: best move %s is not legal at ply %d", ErrPrefixMismatch, p.BestMove, p.Ply)
+			}
+		}
+		byPly[p.Ply] = p
+	}
+	for ply, p := range byPly {
+		if p == nil {
+			return nil, fmt.Errorf("%w: ply %d has no evaluation", ErrPrefixMismatch, ply)
+		}
+	}
+	return byPly, nil
+}
+
+// samePosition compares FENs without their move counters
+func samePosition(a, b string) bool {
+	fieldsA, fieldsB := strings.Fields(a), strings.Fields(b)
+	if len(fieldsA) < 4 || len(fieldsB) < 4 {
+		return false
+	}
+	return strings.Join(fieldsA[:4], " ") == strings.Join(fieldsB[:4], " ")
+}
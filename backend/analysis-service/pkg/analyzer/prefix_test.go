@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+)
+
+const prefixGame = "1. e4 e5 2. Nf3 Nc6 *"
+
+func TestAnalyzeGame_Prefix(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	positions, err := ParsePGN(prefixGame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := GameOptions{
+		StartPly: 2,
+		Prefix: []PrefixEvaluation{
+			{Ply: 1, Eval: engine.Evaluation{Centipawns: -30, Depth: 30}, BestMove: "e7e5", FEN: positions[1].FEN},
+			{Ply: 0, Eval: engine.Evaluation{Centipawns: 25, Depth: 30}, BestMove: "e2e4"},
+		},
+	}
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", prefixGame, 12, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Moves) != 4 {
+		t.Fatalf("got %d moves, want 4", len(analysis.Moves))
+	}
+
+	first, second := analysis.Moves[0], analysis.Moves[1]
+	if first.EvalBefore.Centipawns != 25 || first.AchievedDepth != 30 || first.BestMoveUCI != "e2e4" {
+		t.Errorf("ply 0 = %+v, want the supplied eval and best move", first)
+	}
+	// The move into the first searched position is scored against the
+	// supplied eval before it
+	if second.EvalBefore.Centipawns != -30 || second.EvalAfter.Depth != 12 {
+		t.Errorf("ply 1: before %+v, after %+v", second.EvalBefore, second.EvalAfter)
+	}
+
+	// Only the searched positions are cached
+	for i, pos := range positions {
+		_, found := a.posCache.Get(PrimaryEngine, pos.FEN, CacheQuery{Depth: 12})
+		if found != (i >= opts.StartPly) {
+			t.Errorf("position %d cached = %v", i, found)
+		}
+	}
+}
+
+func TestAnalyzeGame_PrefixMismatch(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	eval := engine.Evaluation{Centipawns: 20, Depth: 20}
+
+	tests := []struct {
+		name     string
+		startPly int
+		prefix   []PrefixEvaluation
+	}{
+		{"start past the last move", 4, []PrefixEvaluation{{Ply: 0, Eval: eval}, {Ply: 1, Eval: eval}, {Ply: 2, Eval: eval}, {Ply: 3, Eval: eval}}},
+		{"negative start", -1, nil},
+		{"missing ply", 2, []PrefixEvaluation{{Ply: 0, Eval: eval}}},
+		{"duplicate ply", 1, []PrefixEvaluation{{Ply: 0, Eval: eval}, {Ply: 0, Eval: eval}}},
+		{"ply at start", 1, []PrefixEvaluation{{Ply: 0, Eval: eval}, {Ply: 1, Eval: eval}}},
+		{"prefix without start", 0, []PrefixEvaluation{{Ply: 0, Eval: eval}}},
+		{"other position", 1, []PrefixEvaluation{{Ply: 0, Eval: eval, FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"}}},
+		{"illegal best move", 1, []PrefixEvaluation{{Ply: 0, Eval: eval, BestMove: "e7e5"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := GameOptions{StartPly: tt.startPly, Prefix: tt.prefix}
+			_, err := a.AnalyzeGame(context.Background(), "g1", prefixGame, 12, opts, nil)
+			if !errors.Is(err, ErrPrefixMismatch) {
+				t.Errorf("err = %v, want ErrPrefixMismatch", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package analyzer
+
+import (
+	"context"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"go.uber.org/zap"
+)
+
+// PreviewCallback receives the complete quick analysis of a game, before
+// it is searched to depth
+type PreviewCallback func(preview *GameAnalysis)
+
+// positionPass is the evaluation of each of a game's positions by one
+// analysis, indexed by ply as in AnalyzeGame
+type positionPass struct {
+	evaluations []engine.Evaluation
+	bestMoves   []string
+	evaluated   []bool
+	fromCache   []bool
+	sources     []AnalysisSource
+}
+
+// SetPreviewTolerance lets the deep pass of a game analyzed with a
+// PreviewDepth keep the preview evaluation of a position that agrees,
+// within tolerance centipawns, with the preview evaluations of the
+// positions before and after it: neither move around it changed the
+// balance, so a deeper search is unlikely to. Mate scores are always
+// searched again. 0, the default, searches every position again.
+func (a *Analyzer) SetPreviewTolerance(tolerance int) {
+	a.previewTolerance = max(tolerance, 0)
+}
+
+// previewDepth returns the depth the preview of a game searched to depth
+// is analyzed at, and false when it has none: no PreviewDepth, one no
+// shallower than depth once clamped, or a game not searched to a depth
+func (a *Analyzer) previewDepth(opts GameOptions, depth int) (int, bool) {
+	if opts.PreviewDepth <= 0 || opts.Cache.Only || opts.TimeBudget > 0 {
+		return 0, false
+	}
+	_, _, previewDepth, err := a.engineProfile(opts.EngineProfile, opts.PreviewDepth)
+	if err != nil || previewDepth >= depth {
+		return 0, false
+	}
+	return previewDepth, true
+}
+
+// analyzePreview analyzes a game at the preview depth, without reporting
+// progress, and hands the result to opts.OnPreview
+func (a *Analyzer) analyzePreview(ctx context.Context, gameID, pgn string, depth int, opts GameOptions) (*GameAnalysis, error) {
+	previewOpts := opts
+	previewOpts.PreviewDepth = 0
+	previewOpts.OnPreview = nil
+	previewOpts.OnMetrics = nil
+	previewOpts.OnChunk = nil
+	previewOpts.OnEstimate = nil
+	previewOpts.OnEngineOutput = nil
+	previewOpts.preview = true
+
+	preview, err := a.AnalyzeGame(ctx, gameID, pgn, depth, previewOpts, nil)
+	if err != nil {
+		return nil, err
+	}
+	a.logger.Info("Game preview completed",
+		zap.String("gameId", gameID),
+		zap.Int("depth", preview.Depth),
+		zap.Int64("totalTimeMs", preview.TotalTimeMs))
+	if opts.OnPreview != nil {
+		opts.OnPreview(preview)
+	}
+	return preview, nil
+}
+
+// previewOnly returns what a game analysis whose deep pass the caller
+// ended comes to: its preview, a successful partial analysis, when it had
+// one, and the caller's error otherwise
+func (a *Analyzer) previewOnly(ctx context.Context, gameID string, preview *GameAnalysis) (*GameAnalysis, error) {
+	if preview == nil {
+		return nil, callerError(ctx.Err())
+	}
+	a.logger.Info("Game analysis canceled after its preview, returning the preview",
+		zap.String("gameId", gameID),
+		zap.Int("depth", preview.Depth),
+		zap.Error(ctx.Err()))
+	return preview, nil
+}
+
+// keeps reports whether a deep pass may keep the evaluation of position i
+// instead of searching it again, see SetPreviewTolerance
+func (p *positionPass) keeps(i, tolerance int) bool {
+	if tolerance <= 0 || !p.evaluated[i] || p.evaluations[i].IsMate {
+		return false
+	}
+	for _, j := range []int{i - 1, i + 1} {
+		if j < 0 || j >= len(p.evaluations) {
+			continue
+		}
+		if !p.evaluated[j] || p.evaluations[j].IsMate {
+			return false
+		}
+		// Both are from the side to move, which alternates: the positions
+		// agree when their evaluations cancel out
+		if abs(centipawns(p.evaluations[i])+centipawns(p.evaluations[j])) > tolerance {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,201 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"go.uber.org/zap"
+)
+
+// previewPGN's 2. Nf3 drops 300 centipawns, and 2...Nc6 gives them back
+const previewPGN = "1. e4 e5 2. Nf3 Nc6 3. Bb5 *"
+
+// newPreviewAnalyzer returns an analyzer on two fake engines scoring
+// every position of previewPGN 20 for the side to move but the one after
+// 2. Nf3, and the game's positions
+func newPreviewAnalyzer(t *testing.T) (*Analyzer, []string) {
+	t.Helper()
+	fens, err := PGNPositions(previewPGN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := enginetest.Engine{
+		Evals:   map[string]enginetest.Eval{fens[3]: {Centipawns: 300}},
+		Default: enginetest.Eval{Centipawns: 20},
+	}
+	p, err := pool.NewPool(2, engine.Config{BinaryPath: fake.Binary(t), Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return NewAnalyzer(p, zap.NewNop(), 1, 20, 30, time.Minute), fens
+}
+
+// checkConsistent checks the metrics of analysis count its moves
+func checkConsistent(t *testing.T, name string, analysis *GameAnalysis) {
+	t.Helper()
+	if err := VerifyGameAnalysis(analysis); err != nil {
+		t.Errorf("%s: %v", name, err)
+	}
+	for _, side := range []struct {
+		color   string
+		metrics GameMetrics
+	}{{"white", analysis.WhiteMetrics}, {"black", analysis.BlackMetrics}} {
+		moves, blunders := 0, 0
+		for _, move := range analysis.Moves {
+			if move.Color != side.color {
+				continue
+			}
+			moves++
+			if move.Classification == ClassBlunder {
+				blunders++
+			}
+		}
+		if side.metrics.TotalMoves != moves || side.metrics.Blunders != blunders {
+			t.Errorf("%s: %s metrics count %d moves and %d blunders, the moves %d and %d",
+				name, side.color, side.metrics.TotalMoves, side.metrics.Blunders, moves, blunders)
+		}
+	}
+}
+
+func TestAnalyzeGame_Preview(t *testing.T) {
+	a, fens := newPreviewAnalyzer(t)
+
+	var preview *GameAnalysis
+	var moves []int
+	opts := GameOptions{
+		PreviewDepth: 8,
+		OnPreview: func(p *GameAnalysis) {
+			if moves != nil {
+				t.Error("preview after the deep pass's progress")
+			}
+			preview = p
+		},
+	}
+	final, err := a.AnalyzeGame(context.Background(), "g1", previewPGN, 16, opts, func(current, total int, move *MoveAnalysis) {
+		if move != nil {
+			moves = append(moves, move.Ply)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview == nil {
+		t.Fatal("no preview")
+	}
+
+	// Both are complete, at their depths, with metrics of their own moves
+	for _, tt := range []struct {
+		name        string
+		analysis    *GameAnalysis
+		depth       int
+		preliminary bool
+	}{{"preview", preview, 8, true}, {"final", final, 16, false}} {
+		if tt.analysis.Depth != tt.depth || tt.analysis.Preliminary != tt.preliminary || len(tt.analysis.Moves) != 5 {
+			t.Errorf("%s: depth %d, preliminary %v, %d moves; want %d, %v, 5",
+				tt.name, tt.analysis.Depth, tt.analysis.Preliminary, len(tt.analysis.Moves), tt.depth, tt.preliminary)
+		}
+		for _, move := range tt.analysis.Moves {
+			if move.AchievedDepth != tt.depth {
+				t.Errorf("%s: ply %d searched to %d", tt.name, move.Ply, move.AchievedDepth)
+			}
+		}
+		if tt.analysis.WhiteMetrics.Blunders != 1 || tt.analysis.BlackMetrics.Blunders != 1 {
+			t.Errorf("%s: blunders %d and %d, want 2. Nf3 and 2...Nc6",
+				tt.name, tt.analysis.WhiteMetrics.Blunders, tt.analysis.BlackMetrics.Blunders)
+		}
+		checkConsistent(t, tt.name, tt.analysis)
+	}
+	// The same evaluations, searched deeper, are less noisy
+	if final.WhiteMetrics.Accuracy != preview.WhiteMetrics.Accuracy || final.WhiteMetrics.AccuracyStddev >= preview.WhiteMetrics.AccuracyStddev {
+		t.Errorf("white accuracy %.1f±%.2f after %.1f±%.2f in the preview", final.WhiteMetrics.Accuracy,
+			final.WhiteMetrics.AccuracyStddev, preview.WhiteMetrics.Accuracy, preview.WhiteMetrics.AccuracyStddev)
+	}
+	// Progress follows the deep pass only
+	if len(moves) != 5 {
+		t.Errorf("progress reported plies %v, want the deep pass's 5", moves)
+	}
+
+	// The cache keeps the deeper searches
+	for _, fen := range fens[:5] {
+		if cached, _ := a.posCache.Get(PrimaryEngine, fen, CacheQuery{Depth: 16}); cached == nil {
+			t.Errorf("%s not cached at depth 16", fen)
+		}
+	}
+
+	// A preview no shallower than the game isn't made
+	preview = nil
+	if _, err := a.AnalyzeGame(context.Background(), "g1", previewPGN, 8, opts, nil); err != nil || preview != nil {
+		t.Errorf("preview at the game's depth: %v, %v", preview, err)
+	}
+}
+
+func TestAnalyzeGame_PreviewTolerance(t *testing.T) {
+	a, fens := newPreviewAnalyzer(t)
+	a.SetPreviewTolerance(50)
+
+	var preview *GameAnalysis
+	opts := GameOptions{
+		PreviewDepth: 8,
+		OnPreview:    func(p *GameAnalysis) { preview = p },
+	}
+	final, err := a.AnalyzeGame(context.Background(), "g1", previewPGN, 16, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The positions either side of the 300 swing are searched again; the
+	// others agree with their neighbors within 40 centipawns and are kept
+	if final.PreviewKept != 3 || preview.PreviewKept != 0 {
+		t.Errorf("kept %d positions, the preview %d; want 3 and 0", final.PreviewKept, preview.PreviewKept)
+	}
+	searched := map[int]bool{2: true, 3: true, 4: true}
+	for i, fen := range fens {
+		cached, _ := a.posCache.Get(PrimaryEngine, fen, CacheQuery{Depth: 16})
+		if (cached != nil) != searched[i] {
+			t.Errorf("position %d cached at depth 16: %v, want %v", i, cached != nil, searched[i])
+		}
+	}
+	for _, move := range final.Moves {
+		if want := map[bool]int{false: 8, true: 16}[searched[move.Ply]]; move.AchievedDepth != want {
+			t.Errorf("ply %d searched to %d, want %d", move.Ply, move.AchievedDepth, want)
+		}
+	}
+	checkConsistent(t, "final", final)
+	if final.WhiteMetrics.Blunders != 1 || final.BlackMetrics.Blunders != 1 {
+		t.Errorf("blunders %d and %d, want 2. Nf3 and 2...Nc6", final.WhiteMetrics.Blunders, final.BlackMetrics.Blunders)
+	}
+}
+
+func TestAnalyzeGame_CanceledAfterPreview(t *testing.T) {
+	a, _ := newPreviewAnalyzer(t)
+
+	// Canceled as the preview arrives: the preview is the result
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var preview *GameAnalysis
+	opts := GameOptions{
+		PreviewDepth: 8,
+		OnPreview: func(p *GameAnalysis) {
+			preview = p
+			cancel()
+		},
+	}
+	analysis, err := a.AnalyzeGame(ctx, "g1", previewPGN, 16, opts, nil)
+	if err != nil {
+		t.Fatalf("canceled after the preview: %v", err)
+	}
+	if analysis != preview || !analysis.Preliminary || analysis.Depth != 8 || len(analysis.Moves) != 5 {
+		t.Errorf("result = %+v, want the preview", analysis)
+	}
+
+	// Canceled before it, on an empty cache, the analysis fails
+	a, _ = newPreviewAnalyzer(t)
+	if _, err := a.AnalyzeGame(ctx, "g1", previewPGN, 16, opts, nil); err == nil {
+		t.Error("canceled before the preview: no error")
+	}
+}
@@ -0,0 +1,146 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+// ReclassifyOptions picks the scoring Reclassify applies
+type ReclassifyOptions struct {
+	// ThresholdProfile is the profile to classify with, "" for the one the
+	// analysis was stored with. The profile's current thresholds are used,
+	// not those recorded in the analysis.
+	ThresholdProfile string
+
+	// AccuracyModel is the model to score accuracy with, "" for the one
+	// the analysis was stored with
+	AccuracyModel evaluation.AccuracyModel
+}
+
+// Reclassify scores a stored analysis again from its evaluations with the
+// analyzer's current thresholds and accuracy model, without searching: the
+// centipawn loss, accuracy, garbage time, classification and explanation
+// of each move, then both players' metrics, resilience and performance
+// rating, and the checksum. Book moves, and classes the thresholds don't
+// assign such as brilliant, are kept. Garbage time stays off for an
+// analysis stored without it. Resilience and performance ratings need the
+// game's result and are kept as stored when the analysis doesn't record
+// it; the clock figures of WhiteTime and BlackTime need the PGN and are
+// always kept.
+//
+// g is not changed. Reclassifying the result again under the same options
+// gives the same analysis.
+func (a *Analyzer) Reclassify(g *GameAnalysis, opts ReclassifyOptions) (*GameAnalysis, error) {
+	profile := opts.ThresholdProfile
+	if profile == "" {
+		profile = g.ThresholdProfile
+	}
+	profile, thresholds, err := a.Thresholds(profile)
+	if err != nil {
+		return nil, err
+	}
+	if g.Thresholds.GarbageWin == 0 && g.Thresholds.GarbageLoss == 0 {
+		thresholds = thresholds.WithoutGarbageTime()
+	}
+	model := opts.AccuracyModel
+	if model == "" {
+		model = g.WhiteMetrics.AccuracyModel
+	}
+	if model == "" {
+		model = evaluation.AccuracyModelEloInsight
+	}
+	if !model.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAccuracyModel, model)
+	}
+
+	out := *g
+	out.Moves = make([]MoveAnalysis, len(g.Moves))
+	copy(out.Moves, g.Moves)
+	out.ThresholdProfile = profile
+	out.Thresholds = thresholds
+
+	metrics := newGameMetrics(a.accuracyMethod, model, len(out.Moves))
+	for i := range out.Moves {
+		move := &out.Moves[i]
+		restoreEvalDepths(out.Moves, i)
+		a.rescoreMove(move, thresholds)
+		metrics.add(move)
+	}
+
+	result := storedResult(g)
+	out.WhiteMetrics = metrics.result("white")
+	out.BlackMetrics = metrics.result("black")
+	if result == "" {
+		out.WhiteMetrics.Resilience, out.WhiteMetrics.PerformanceRating = g.WhiteMetrics.Resilience, g.WhiteMetrics.PerformanceRating
+		out.BlackMetrics.Resilience, out.BlackMetrics.PerformanceRating = g.BlackMetrics.Resilience, g.BlackMetrics.PerformanceRating
+	} else {
+		out.WhiteMetrics.Resilience = resilience(metrics.evals, "white", result, thresholds)
+		out.BlackMetrics.Resilience = resilience(metrics.evals, "black", result, thresholds)
+		out.WhiteMetrics.PerformanceRating = performanceRating(out.WhiteMetrics, "white", out.Info.BlackElo, result)
+		out.BlackMetrics.PerformanceRating = performanceRating(out.BlackMetrics, "black", out.Info.WhiteElo, result)
+	}
+	out.Summary = Summarize(&out)
+	out.Checksum = Checksum(&out)
+	return &out, nil
+}
+
+// rescoreMove recomputes what createMoveAnalysis derives from a move's
+// evaluations under thresholds
+func (a *Analyzer) rescoreMove(move *MoveAnalysis, thresholds evaluation.Thresholds) {
+	move.CentipawnLoss = centipawnLoss(move.EvalBefore, move.EvalAfter)
+	if gameOver(move.FENAfter) == engine.GameOverCheckmate {
+		move.CentipawnLoss = 0
+	}
+	move.MoveAccuracy = 100
+	if !move.Forced {
+		move.MoveAccuracy = evaluation.CalculateMoveAccuracy(centipawns(move.EvalBefore), -centipawns(move.EvalAfter))
+	}
+	move.ExpectedPointsLoss = ExpectedPointsLoss(move)
+	move.GarbageTime = thresholds.IsGarbageTime(centipawns(move.EvalBefore))
+
+	switch move.Classification {
+	case ClassBook, ClassBrilliant, ClassGreat, ClassMissedWin:
+	default:
+		isBest := SameMoveFrom(move.FENBefore, move.PlayedMoveUCI, move.BestMoveUCI)
+		move.Classification = a.classifyMove(move.CentipawnLoss, isBest, thresholds)
+	}
+	move.Explanation = ExplainMove(move).Explain()
+}
+
+// restoreEvalDepths fills in the depths of moves[i]'s evaluations where
+// the stored form dropped them, as the JSON schema does: the move's own
+// achieved depth before it, and the next move's after it
+func restoreEvalDepths(moves []MoveAnalysis, i int) {
+	move := &moves[i]
+	if move.EvalBefore.Depth == 0 {
+		move.EvalBefore.Depth = move.AchievedDepth
+	}
+	if move.EvalAfter.Depth == 0 {
+		move.EvalAfter.Depth = move.AchievedDepth
+		if i+1 < len(moves) && moves[i+1].FENBefore == move.FENAfter {
+			move.EvalAfter.Depth = moves[i+1].AchievedDepth
+		}
+	}
+}
+
+// storedResult returns the result, in PGN notation, a stored analysis is
+// scored with: its game info's, or else the one its ending records
+func storedResult(g *GameAnalysis) string {
+	if whiteResult(g.Info.Result).Known() {
+		return g.Info.Result
+	}
+	switch g.WinnerColor {
+	case "white":
+		return "1-0"
+	case "black":
+		return "0-1"
+	}
+	switch g.Termination {
+	case TerminationStalemate, TerminationInsufficientMaterial, TerminationRepetition,
+		TerminationFiftyMoveRule, TerminationAgreement:
+		return "1/2-1/2"
+	}
+	return ""
+}
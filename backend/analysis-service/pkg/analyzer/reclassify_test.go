@@ -0,0 +1,171 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+// roundTrip returns g as stored in the JSON schema and read back
+func roundTrip(t *testing.T, g *GameAnalysis) *GameAnalysis {
+	t.Helper()
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored GameAnalysis
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatal(err)
+	}
+	return &stored
+}
+
+func TestReclassify(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	ctx := context.Background()
+	pgn := "[WhiteElo \"1800\"]\n[BlackElo \"1750\"]\n[Result \"1-0\"]\n\n" + testPGN[:len(testPGN)-1] + "1-0"
+
+	standard, err := a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strict, err := a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{ThresholdProfile: evaluation.ProfileStrict}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := roundTrip(t, standard)
+
+	// Under its own profile a stored analysis scores as it did
+	same, err := a.Reclassify(stored, ReclassifyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same.Checksum != standard.Checksum {
+		t.Errorf("checksum %s after reclassifying under the same profile, want %s", same.Checksum, standard.Checksum)
+	}
+	if diff := CompareAnalyses(stored, same); diff.ClassificationDiffs != 0 || diff.WhiteAccuracyDelta != 0 {
+		t.Errorf("same profile: %+v", diff)
+	}
+
+	// Under another it scores as a fresh analysis would, without a search
+	before := a.CacheStats()
+	reclassified, err := a.Reclassify(stored, ReclassifyOptions{ThresholdProfile: evaluation.ProfileStrict})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after := a.CacheStats(); after.Hits != before.Hits || after.Misses != before.Misses {
+		t.Errorf("reclassifying used the cache: %+v then %+v", before, after)
+	}
+	if reclassified.ThresholdProfile != evaluation.ProfileStrict || reclassified.Thresholds != strict.Thresholds {
+		t.Errorf("recorded %q %+v", reclassified.ThresholdProfile, reclassified.Thresholds)
+	}
+	if reclassified.Checksum != strict.Checksum {
+		t.Errorf("checksum %s, want the strict analysis's %s", reclassified.Checksum, strict.Checksum)
+	}
+	for i := range strict.Moves {
+		got, want := reclassified.Moves[i], strict.Moves[i]
+		if got.Classification != want.Classification || got.Explanation != want.Explanation || got.GarbageTime != want.GarbageTime {
+			t.Errorf("ply %d: %s %q, want %s %q", got.Ply, got.Classification, got.Explanation, want.Classification, want.Explanation)
+		}
+	}
+	for _, side := range []struct{ got, want GameMetrics }{
+		{reclassified.WhiteMetrics, strict.WhiteMetrics},
+		{reclassified.BlackMetrics, strict.BlackMetrics},
+	} {
+		got, want := side.got, side.want
+		if got.Mistakes != want.Mistakes || got.Inaccuracies != want.Inaccuracies || got.GoodMoves != want.GoodMoves ||
+			math.Abs(got.Accuracy-want.Accuracy) > 1e-9 || math.Abs(got.AccuracyStddev-want.AccuracyStddev) > 1e-9 ||
+			got.PerformanceRating != want.PerformanceRating || (got.Resilience == nil) != (want.Resilience == nil) {
+			t.Errorf("metrics = %+v, want %+v", got, want)
+		}
+	}
+	if diff := CompareAnalyses(stored, reclassified); diff.ClassificationDiffs == 0 {
+		t.Error("the strict profile changed no classification")
+	}
+	if stored.Checksum != standard.Checksum || VerifyGameAnalysis(stored) != nil {
+		t.Error("Reclassify changed its input")
+	}
+
+	// Reclassifying again changes nothing
+	again, err := a.Reclassify(roundTrip(t, reclassified), ReclassifyOptions{ThresholdProfile: evaluation.ProfileStrict})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := json.Marshal(reclassified)
+	second, _ := json.Marshal(again)
+	if !bytes.Equal(first, second) {
+		t.Errorf("not idempotent:\n%s\n%s", first, second)
+	}
+}
+
+func TestReclassify_KeepsBookAndGarbageTimeOff(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	off := false
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, GameOptions{ExcludeGarbageTime: &off}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis.Moves[0].Classification = ClassBook
+	analysis.Moves[0].Source = PlyBookSkipped
+
+	reclassified, err := a.Reclassify(analysis, ReclassifyOptions{ThresholdProfile: evaluation.ProfileLenient})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reclassified.Moves[0].Classification != ClassBook || reclassified.WhiteMetrics.BookMoves != 1 {
+		t.Errorf("book move reclassified as %s", reclassified.Moves[0].Classification)
+	}
+	if reclassified.Thresholds.GarbageWin != 0 || reclassified.Thresholds.GarbageLoss != 0 {
+		t.Errorf("garbage time turned on: %+v", reclassified.Thresholds)
+	}
+}
+
+func TestReclassify_KeepsRatingWithoutResult(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", testPGN, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// As loaded from the database, which doesn't store the result
+	analysis.WhiteMetrics.PerformanceRating = 1900
+	reclassified, err := a.Reclassify(analysis, ReclassifyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reclassified.WhiteMetrics.PerformanceRating != 1900 {
+		t.Errorf("performance rating = %d, want the stored 1900", reclassified.WhiteMetrics.PerformanceRating)
+	}
+}
+
+func TestReclassify_Errors(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	stored := &GameAnalysis{GameID: "g1", ThresholdProfile: evaluation.ProfileStandard}
+	if _, err := a.Reclassify(stored, ReclassifyOptions{ThresholdProfile: "nope"}); !errors.Is(err, ErrUnknownThresholdProfile) {
+		t.Errorf("unknown profile: err = %v", err)
+	}
+	if _, err := a.Reclassify(stored, ReclassifyOptions{AccuracyModel: "nope"}); !errors.Is(err, ErrUnknownAccuracyModel) {
+		t.Errorf("unknown model: err = %v", err)
+	}
+}
+
+func TestStoredResult(t *testing.T) {
+	for _, tt := range []struct {
+		g    GameAnalysis
+		want string
+	}{
+		{GameAnalysis{Info: GameInfo{Result: "0-1"}, WinnerColor: "white"}, "0-1"},
+		{GameAnalysis{WinnerColor: "white"}, "1-0"},
+		{GameAnalysis{Termination: TerminationRepetition}, "1/2-1/2"},
+		{GameAnalysis{Termination: TerminationResignation}, ""},
+		{GameAnalysis{}, ""},
+	} {
+		if got := storedResult(&tt.g); got != tt.want {
+			t.Errorf("storedResult(%+v) = %q, want %q", tt.g, got, tt.want)
+		}
+	}
+}
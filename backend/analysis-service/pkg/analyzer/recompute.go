@@ -0,0 +1,89 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+// StoredMove is a move of an earlier analysis, as much of it as scoring
+// the game again needs
+type StoredMove struct {
+	Ply        int
+	Color      string
+	EvalBefore engine.Evaluation // The mover's
+	EvalAfter  engine.Evaluation // The opponent's
+	BestMove   bool              // The move played was the engine's best
+	Book       bool              // Kept out of accuracy and ACPL
+}
+
+// RecomputedMetrics are a game's metrics scored again from its stored
+// moves
+type RecomputedMetrics struct {
+	White, Black     evaluation.PlayerMetrics
+	AccuracyModel    evaluation.AccuracyModel
+	ThresholdProfile string
+	Thresholds       evaluation.Thresholds
+}
+
+// RecomputeMetrics scores moves, a game's stored moves in ply order, with
+// evaluation.CalculateGameMetrics under opts' ThresholdProfile,
+// ExcludeGarbageTime, AccuracyModel and Result, defaulting as AnalyzeGame
+// does; the rest of opts is ignored. Centipawn losses
+// come from the evaluations, so a change of thresholds or model needs no
+// search, and the engine pool is never touched.
+func (a *Analyzer) RecomputeMetrics(moves []StoredMove, opts GameOptions) (*RecomputedMetrics, error) {
+	profile, thresholds, err := a.Thresholds(opts.ThresholdProfile)
+	if err != nil {
+		return nil, err
+	}
+	excludeGarbage := a.excludeGarbage
+	if opts.ExcludeGarbageTime != nil {
+		excludeGarbage = *opts.ExcludeGarbageTime
+	}
+	if !excludeGarbage {
+		thresholds = thresholds.WithoutGarbageTime()
+	}
+	model := opts.AccuracyModel
+	if model == "" {
+		model = evaluation.AccuracyModelEloInsight
+	}
+	if !model.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAccuracyModel, model)
+	}
+
+	evals := make([]evaluation.MoveEvaluation, len(moves))
+	for i, move := range moves {
+		evals[i] = whiteEvaluation(&MoveAnalysis{
+			Ply:           move.Ply,
+			Color:         move.Color,
+			EvalBefore:    move.EvalBefore,
+			EvalAfter:     move.EvalAfter,
+			CentipawnLoss: centipawnLoss(move.EvalBefore, move.EvalAfter),
+		})
+		evals[i].WasBestMove = move.BestMove
+		evals[i].IsMateScore = move.EvalAfter.IsMate
+		if move.Book {
+			evals[i].Classification = evaluation.ClassBook
+		}
+	}
+
+	recomputed := &RecomputedMetrics{
+		AccuracyModel:    model,
+		ThresholdProfile: profile,
+		Thresholds:       thresholds,
+	}
+	recomputed.White, recomputed.Black = evaluation.CalculateGameMetrics(evals, evaluation.GameMetricsOptions{
+		Thresholds: thresholds,
+		Method:     a.accuracyMethod,
+		Model:      model,
+		Result:     whiteResult(opts.Result),
+	})
+	return recomputed, nil
+}
+
+// whiteResult converts a result in PGN notation to White's
+func whiteResult(result string) evaluation.GameResult {
+	return playerResult(result, "white")
+}
@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// RepetitionAdvantage is the mover's evaluation, in centipawns, from which
+// they are winning, or negated losing, for the repetition flags
+const RepetitionAdvantage = 200
+
+// repetitionDraws returns, for each position, the legal moves that repeat
+// an earlier position for the third time, allowing a draw claim.
+// Positions are the same when placement, side to move, castling rights and
+// en passant square are, as in the position cache.
+func repetitionDraws(positions []Position) [][]string {
+	draws := make([][]string, len(positions))
+	seen := make(map[string]int, len(positions))
+	twice := false // Some position was seen twice
+	for i, pos := range positions {
+		key := positionKey(pos.FEN)
+		seen[key]++
+		twice = twice || seen[key] >= 2
+		if !twice || pos.LegalMoves == 0 {
+			continue
+		}
+
+		fenOpt, err := chess.FEN(pos.FEN)
+		if err != nil {
+			continue
+		}
+		position := chess.NewGame(fenOpt).Position()
+		for _, move := range position.ValidMoves() {
+			if seen[positionKey(position.Update(move).String())] >= 2 {
+				draws[i] = append(draws[i], chess.UCINotation{}.Encode(position, move))
+			}
+		}
+	}
+	return draws
+}
+
+// flagRepetition flags a losing move that passed up a repetition draw, and
+// a winning one that repeated or let the opponent repeat. before and after
+// are the repetition draws of the positions around the move.
+func flagRepetition(move *MoveAnalysis, before, after []string) {
+	repeated := slices.Contains(before, move.PlayedMoveUCI)
+	switch eval := centipawns(move.EvalBefore); {
+	case eval <= -RepetitionAdvantage:
+		move.MissedRepetition = len(before) > 0 && !repeated
+	case eval >= RepetitionAdvantage:
+		move.AllowedRepetition = repeated || len(after) > 0
+	}
+}
+
+// positionKey is the FEN without its move counters
+func positionKey(fen string) string {
+	parts := strings.Fields(fen)
+	if len(parts) > 4 {
+		parts = parts[:4]
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,56 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+)
+
+func TestRepetitionDraws(t *testing.T) {
+	// The knights go out and back twice; after 4. Ng1 Black can repeat
+	// the starting position a third time
+	positions, err := ParsePGN("1. Nf3 Nf6 2. Ng1 Ng8 3. Nf3 Nf6 4. Ng1 e5 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	draws := repetitionDraws(positions)
+	for i, moves := range draws {
+		var want []string
+		if i == 7 {
+			want = []string{"f6g8"}
+		}
+		if !reflect.DeepEqual(moves, want) {
+			t.Errorf("position %d: repetition draws %v, want %v", i, moves, want)
+		}
+	}
+}
+
+func TestFlagRepetition(t *testing.T) {
+	draw := []string{"f6g8"}
+	tests := []struct {
+		name          string
+		eval          int
+		played        string
+		before, after []string
+		missed        bool
+		allowed       bool
+	}{
+		{"losing, passed up the draw", -300, "e7e5", draw, nil, true, false},
+		{"losing, took the draw", -300, "f6g8", draw, nil, false, false},
+		{"winning, repeated", 300, "f6g8", draw, nil, false, true},
+		{"winning, let the opponent repeat", 300, "g1f3", nil, draw, false, true},
+		{"winning, no repetition", 300, "e7e5", nil, nil, false, false},
+		{"level", 50, "e7e5", draw, draw, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			move := MoveAnalysis{PlayedMoveUCI: tt.played, EvalBefore: engine.Evaluation{Centipawns: tt.eval}}
+			flagRepetition(&move, tt.before, tt.after)
+			if move.MissedRepetition != tt.missed || move.AllowedRepetition != tt.allowed {
+				t.Errorf("missed = %v, allowed = %v; want %v, %v", move.MissedRepetition, move.AllowedRepetition, tt.missed, tt.allowed)
+			}
+		})
+	}
+}
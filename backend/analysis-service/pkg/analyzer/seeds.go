@@ -0,0 +1,30 @@
+package analyzer
+
+import (
+	_ "embed"
+	"io"
+	"strings"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+)
+
+// seedEvaluations are the evaluations of the starting position and the
+// most common positions of the first plies, searched when the service is
+// built. Regenerate them with make seeds.
+//
+//go:embed seeds.csv
+var seedEvaluations string
+
+// LoadSeeds loads the built-in seed evaluations into the position cache
+// for the primary engine, tagged engine.SourceSeed, and returns how many
+// were loaded. Seeds are evicted like searched evaluations and replaced by
+// deeper ones; they don't replace a cached evaluation at least as deep.
+func (a *Analyzer) LoadSeeds() (int, error) {
+	return a.loadSeeds(strings.NewReader(seedEvaluations))
+}
+
+// loadSeeds loads seed evaluations in the import format from r
+func (a *Analyzer) loadSeeds(r io.Reader) (int, error) {
+	report, err := a.importCSV(r, engine.SourceSeed)
+	return report.Imported, err
+}
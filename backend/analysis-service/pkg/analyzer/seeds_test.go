@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"go.uber.org/zap"
+)
+
+func TestLoadSeeds(t *testing.T) {
+	a := NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	a.posCache.Set(PrimaryEngine, afterE5FEN, 30, engine.Evaluation{Depth: 30, Centipawns: 25}, "g1f3", engine.SourceEngine)
+
+	seeds := strings.Join([]string{
+		"fen,depth,cp,mate,best_move,source",
+		startFEN + ",20,18,,e2e4,seedgen",
+		afterE4FEN + ",20,30,,c7c5,seedgen",
+		afterE5FEN + ",20,40,,g1f3,seedgen",
+	}, "\n")
+	n, err := a.loadSeeds(strings.NewReader(seeds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("loaded %d seeds, want 2 besides the deeper search", n)
+	}
+	if cached, _ := a.posCache.get(PrimaryEngine, startFEN, CacheQuery{Depth: 20}); cached.source != engine.SourceSeed {
+		t.Errorf("seed cached from %q", cached.source)
+	}
+	if cached, _ := a.posCache.get(PrimaryEngine, afterE5FEN, CacheQuery{Depth: 20}); cached.source != engine.SourceEngine {
+		t.Errorf("deeper search replaced by a seed from %q", cached.source)
+	}
+	if got := cacheSource(engine.SourceSeed); got != PlyImported {
+		t.Errorf("plies from seeds analyzed from %s", got)
+	}
+
+	// A shallower search leaves the seed, a deeper one supersedes it
+	a.posCache.Set(PrimaryEngine, startFEN, 12, engine.Evaluation{Depth: 12}, "d2d4", engine.SourceEngine)
+	if cached, _ := a.posCache.get(PrimaryEngine, startFEN, CacheQuery{Depth: 20}); cached.source != engine.SourceSeed {
+		t.Errorf("shallower search replaced the seed: %q", cached.source)
+	}
+	a.posCache.Set(PrimaryEngine, startFEN, 24, engine.Evaluation{Depth: 24}, "d2d4", engine.SourceEngine)
+	if cached, _ := a.posCache.get(PrimaryEngine, startFEN, CacheQuery{Depth: 24}); cached.source != engine.SourceEngine || cached.result.BestMove != "d2d4" {
+		t.Errorf("deeper search didn't supersede the seed: %q %s", cached.source, cached.result.BestMove)
+	}
+}
+
+func TestLoadSeeds_Embedded(t *testing.T) {
+	rows := 0
+	for _, line := range strings.Split(seedEvaluations, "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "fen,") {
+			rows++
+		}
+	}
+
+	a := NewAnalyzer(nil, zap.NewNop(), 1, 20, 30, time.Minute)
+	n, err := a.LoadSeeds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != rows {
+		t.Errorf("loaded %d of %d seeds", n, rows)
+	}
+	if got := a.CacheSizeBySource()[engine.SourceSeed]; got != n {
+		t.Errorf("%d seed entries cached, want %d", got, n)
+	}
+}
@@ -0,0 +1,82 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eloinsight/analysis-service/pkg/pool"
+)
+
+// AnalysisConfigSnapshot records how a game was searched, so that stored
+// analyses can be told comparable or not
+type AnalysisConfigSnapshot struct {
+	Threads int // Per engine
+	HashMB  int // Per engine
+	MultiPV int
+
+	// Search limits: the depth, the node limit (0 = none; the analyzer
+	// never sets one) and the time budget of a game searched by movetime
+	// instead of depth (0 = none)
+	Depth      int
+	NodesLimit int64
+	MovetimeMs int64
+
+	NNUENet string
+
+	// CacheHitPercent is the percentage of positions evaluated without a
+	// search, GameAnalysis.CacheCoverage
+	CacheHitPercent float64
+
+	// Deterministic is set when the searches were reproducible: one thread
+	// and limited by depth rather than time
+	Deterministic bool
+}
+
+// configSnapshot returns the snapshot of a game about to be searched on
+// enginePool, whose engines run netName
+func configSnapshot(enginePool *pool.Pool, netName string, depth int, opts GameOptions) *AnalysisConfigSnapshot {
+	config := enginePool.Config()
+	movetime := opts.TimeBudget.Milliseconds()
+	return &AnalysisConfigSnapshot{
+		Threads:       config.Threads,
+		HashMB:        config.Hash,
+		MultiPV:       config.MultiPV,
+		Depth:         depth,
+		MovetimeMs:    movetime,
+		NNUENet:       netName,
+		Deterministic: config.Threads == 1 && movetime == 0,
+	}
+}
+
+// ConfigDifferences lists the material differences between two snapshots,
+// those that make their analyses incomparable: threads, hash, MultiPV,
+// node limit, network and determinism. Depth, time budget and cache hits
+// differ between an analysis and its re-analysis by design and aren't
+// listed. Nothing is when either snapshot is missing, as in analyses
+// stored before snapshots were recorded.
+func ConfigDifferences(a, b *AnalysisConfigSnapshot) []string {
+	if a == nil || b == nil {
+		return nil
+	}
+	var diffs []string
+	differ := func(name string, x, y interface{}) {
+		if x != y {
+			diffs = append(diffs, fmt.Sprintf("%s %v vs %v", name, x, y))
+		}
+	}
+	differ("threads", a.Threads, b.Threads)
+	differ("hash", a.HashMB, b.HashMB)
+	differ("multipv", a.MultiPV, b.MultiPV)
+	differ("nodes limit", a.NodesLimit, b.NodesLimit)
+	differ("nnue net", a.NNUENet, b.NNUENet)
+	differ("deterministic", a.Deterministic, b.Deterministic)
+	return diffs
+}
+
+// checkSameConfig checks that a and b were searched comparably
+func checkSameConfig(a, b *GameAnalysis) error {
+	if diffs := ConfigDifferences(a.Config, b.Config); len(diffs) > 0 {
+		return fmt.Errorf("%w: %s", ErrConfigMismatch, strings.Join(diffs, ", "))
+	}
+	return nil
+}
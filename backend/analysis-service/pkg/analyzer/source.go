@@ -0,0 +1,70 @@
+package analyzer
+
+import "github.com/eloinsight/analysis-service/pkg/engine"
+
+// AnalysisSource is how a ply of a game was analyzed: where the
+// evaluation of the position before it came from, or why the move doesn't
+// count toward the metrics or wasn't analyzed at all
+type AnalysisSource string
+
+const (
+	PlyEngine   AnalysisSource = "engine"   // Searched for this analysis
+	PlyCache    AnalysisSource = "cache"    // From the position cache
+	PlyImported AnalysisSource = "imported" // Seeded from GameOptions.Prefix, an imported evaluation database or the built-in seeds
+
+	// Evaluated but left out of the metrics: book moves of accuracy and
+	// ACPL, forced moves of move-mean accuracy
+	PlyBookSkipped   AnalysisSource = "book_skipped"
+	PlyForcedSkipped AnalysisSource = "forced_skipped"
+
+	// Not in Moves: the search of a position of the move failed after
+	// every retry, or the move was outside what the analysis reached, cut
+	// off by the timeout or time budget or uncached under Cache.Only
+	PlyFailed     AnalysisSource = "failed"
+	PlyOutOfRange AnalysisSource = "out_of_range"
+)
+
+// AnalysisSources lists the sources in the order they are reported
+var AnalysisSources = []AnalysisSource{
+	PlyEngine, PlyCache, PlyImported, PlyBookSkipped, PlyForcedSkipped, PlyFailed, PlyOutOfRange,
+}
+
+// cacheSource returns the source of a ply whose position was in the cache
+// with cacheEntrySource
+func cacheSource(cacheEntrySource string) AnalysisSource {
+	switch cacheEntrySource {
+	case engine.SourceImported, engine.SourceSeed:
+		return PlyImported
+	}
+	return PlyCache
+}
+
+// moveSource returns the source of an analyzed and classified move whose
+// position before it was evaluated from evaluated
+func moveSource(move *MoveAnalysis, evaluated AnalysisSource) AnalysisSource {
+	switch {
+	case move.Classification == ClassBook:
+		return PlyBookSkipped
+	case move.Forced:
+		return PlyForcedSkipped
+	}
+	return evaluated
+}
+
+// missingSource returns the source of a move left out of the analysis
+func missingSource(failed bool) AnalysisSource {
+	if failed {
+		return PlyFailed
+	}
+	return PlyOutOfRange
+}
+
+// LegacySource returns the source of a move stored before sources were
+// recorded, as near as its other fields tell: a move from an imported
+// evaluation passes for one from the cache
+func LegacySource(move *MoveAnalysis) AnalysisSource {
+	if move.FromCache {
+		return moveSource(move, PlyCache)
+	}
+	return moveSource(move, PlyEngine)
+}
@@ -0,0 +1,158 @@
+package analyzer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+// checkSources checks that analysis's source counts add up to its plies
+// and match the sources of its moves, and returns them
+func checkSources(t *testing.T, analysis *GameAnalysis) map[AnalysisSource]int {
+	t.Helper()
+	total := 0
+	for _, n := range analysis.SourceCounts {
+		total += n
+	}
+	if total != analysis.TotalMoves {
+		t.Errorf("source counts %v add up to %d, want %d plies", analysis.SourceCounts, total, analysis.TotalMoves)
+	}
+	inMoves := make(map[AnalysisSource]int)
+	for _, move := range analysis.Moves {
+		inMoves[move.Source]++
+	}
+	for _, source := range AnalysisSources {
+		want := analysis.SourceCounts[source]
+		if source == PlyFailed || source == PlyOutOfRange {
+			want = 0
+		}
+		if inMoves[source] != want {
+			t.Errorf("%d moves from %s, counted %d", inMoves[source], source, analysis.SourceCounts[source])
+		}
+	}
+	return analysis.SourceCounts
+}
+
+func TestAnalyzeGame_SourceCounts(t *testing.T) {
+	ctx := context.Background()
+	const pgn = "1. e4 e5 2. Nf3 Nc6 *"
+
+	t.Run("engine then cache", func(t *testing.T) {
+		a := newFakeAnalyzer(t)
+		first, err := a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := checkSources(t, first); !reflect.DeepEqual(got, map[AnalysisSource]int{PlyEngine: 4}) {
+			t.Errorf("first analysis: %v", got)
+		}
+		second, err := a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := checkSources(t, second); !reflect.DeepEqual(got, map[AnalysisSource]int{PlyCache: 4}) {
+			t.Errorf("second analysis: %v", got)
+		}
+	})
+
+	t.Run("imported", func(t *testing.T) {
+		a := newFakeAnalyzer(t)
+		positions, err := ParsePGN(pgn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.posCache.Import(PrimaryEngine, positions[1].FEN, 30, engine.Evaluation{Depth: 30, Centipawns: -30}, "e7e5")
+		prefix := []PrefixEvaluation{{Ply: 0, Eval: engine.Evaluation{Depth: 20, Centipawns: 20}}}
+		analysis, err := a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{StartPly: 1, Prefix: prefix}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := checkSources(t, analysis); !reflect.DeepEqual(got, map[AnalysisSource]int{PlyImported: 2, PlyEngine: 2}) {
+			t.Errorf("sources: %v", got)
+		}
+	})
+
+	t.Run("book and forced", func(t *testing.T) {
+		a := newFakeAnalyzer(t)
+		a.SetBookDetector(plyBook{0: true, 1: true})
+		// 2... g6 is the only answer to the check
+		analysis, err := a.AnalyzeGame(ctx, "g1", "1. e4 f5 2. Qh5+ g6 3. Nf3 *", 12, GameOptions{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[AnalysisSource]int{PlyBookSkipped: 2, PlyForcedSkipped: 1, PlyEngine: 2}
+		if got := checkSources(t, analysis); !reflect.DeepEqual(got, want) {
+			t.Errorf("sources: %v, want %v", got, want)
+		}
+		if analysis.Moves[3].Source != PlyForcedSkipped {
+			t.Errorf("2... g6 from %s", analysis.Moves[3].Source)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		a, _ := newFailingAnalyzer(t, "garbage")
+		analysis, err := a.AnalyzeGame(ctx, "g1", "1. e4 e5 2. Nf3 *", 12, GameOptions{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := checkSources(t, analysis); !reflect.DeepEqual(got, map[AnalysisSource]int{PlyFailed: 2, PlyEngine: 1}) {
+			t.Errorf("sources: %v", got)
+		}
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		a := newFakeAnalyzer(t)
+		if _, err := a.AnalyzeGame(ctx, "g1", "1. e4 e5 *", 12, GameOptions{}, nil); err != nil {
+			t.Fatal(err)
+		}
+		analysis, err := a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{Cache: CacheOptions{Only: true}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := checkSources(t, analysis); !reflect.DeepEqual(got, map[AnalysisSource]int{PlyCache: 2, PlyOutOfRange: 2}) {
+			t.Errorf("sources: %v", got)
+		}
+	})
+}
+
+func TestMetrics_FollowSources(t *testing.T) {
+	// The same moves count differently by source alone
+	moves := []MoveAnalysis{
+		{Color: "white", CentipawnLoss: 100, MoveAccuracy: 50, Classification: ClassMistake, Source: PlyEngine},
+		{Color: "white", CentipawnLoss: 100, MoveAccuracy: 50, Classification: ClassMistake, Source: PlyBookSkipped},
+		{Color: "white", CentipawnLoss: 100, MoveAccuracy: 50, Classification: ClassMistake, Source: PlyForcedSkipped},
+	}
+	acc := newMetricsAccumulator(evaluation.AccuracyMoveMean)
+	for i := range moves {
+		acc.add(&moves[i])
+	}
+	if acc.losses.Moves != 2 || acc.accuracyMoves != 1 {
+		t.Errorf("%d moves in the loss and %d in the accuracy, want 2 and 1", acc.losses.Moves, acc.accuracyMoves)
+	}
+}
+
+func TestUnmarshalJSON_LegacySources(t *testing.T) {
+	stored := &GameAnalysis{Moves: []MoveAnalysis{
+		{Ply: 0, Classification: ClassBook},
+		{Ply: 1, Forced: true, Classification: ClassBest},
+		{Ply: 2, FromCache: true, Classification: ClassGood},
+		{Ply: 3, Classification: ClassMistake},
+	}}
+	data, err := stored.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded GameAnalysis
+	if err := decoded.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	want := []AnalysisSource{PlyBookSkipped, PlyForcedSkipped, PlyCache, PlyEngine}
+	for i, move := range decoded.Moves {
+		if move.Source != want[i] {
+			t.Errorf("ply %d decoded from %q, want %q", i, move.Source, want[i])
+		}
+	}
+}
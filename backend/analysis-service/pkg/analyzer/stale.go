@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	"go.uber.org/zap"
+)
+
+// staleRefresher searches positions that were answered stale to the depth
+// they were asked at, one at a time on background engines. Each position
+// is queued once until its refresh is done, so a burst of requests for it
+// costs one search.
+type staleRefresher struct {
+	margin int // Depths a cached search may be short of a request
+	queue  chan staleRefresh
+
+	mu      sync.Mutex
+	pending map[string]bool // Cache keys queued or being searched
+}
+
+// staleRefresh is a position to search into the cache
+type staleRefresh struct {
+	key     string
+	fen     string
+	depth   int
+	multiPV int
+}
+
+// SetStaleWhileRevalidate lets position requests that allow it (see
+// CacheOptions.Stale) be answered from a cached search up to margin depths
+// shallower than asked for, marked Stale, while a background search to the
+// requested depth refreshes the cache for later requests. At most
+// queueSize positions wait for a refresh; more are dropped until there is
+// room. A margin of 0 or less turns it off. It starts the refresh worker,
+// so it is set once, at startup.
+func (a *Analyzer) SetStaleWhileRevalidate(margin, queueSize int) {
+	if margin <= 0 {
+		a.stale = nil
+		return
+	}
+	a.stale = &staleRefresher{
+		margin:  margin,
+		queue:   make(chan staleRefresh, max(queueSize, 1)),
+		pending: make(map[string]bool),
+	}
+	go a.runStaleRefreshes(a.stale)
+}
+
+// staleMargin returns how many depths short of a request a cached search
+// may be to answer it under cache
+func (a *Analyzer) staleMargin(cache CacheOptions) int {
+	if a.stale == nil || !cache.Stale || cache.Only || cache.NoStore {
+		return 0
+	}
+	return a.stale.margin
+}
+
+// refreshStale queues a refresh of fen to depth unless one is already
+// queued or under way, or the queue is full
+func (a *Analyzer) refreshStale(fen string, depth, multiPV int) {
+	r := a.stale
+	key := a.posCache.cacheKey(PrimaryEngine, fen)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending[key] {
+		return
+	}
+	select {
+	case r.queue <- staleRefresh{key: key, fen: fen, depth: depth, multiPV: multiPV}:
+		r.pending[key] = true
+	default:
+		a.logger.Debug("Stale refresh queue full", zap.String("fen", fen), zap.Int("depth", depth))
+	}
+}
+
+// runStaleRefreshes searches the positions queued on r into the cache
+func (a *Analyzer) runStaleRefreshes(r *staleRefresher) {
+	for refresh := range r.queue {
+		// A search since it was queued may have done it already
+		if !a.posCache.has(PrimaryEngine, refresh.fen, CacheQuery{Depth: refresh.depth, MultiPV: refresh.multiPV, NeedPV: true}) {
+			ctx, cancel := a.withTimeout(pool.WithTag(context.Background(), StaleRefreshTag))
+			if err := a.warmPosition(ctx, refresh.fen, refresh.depth, refresh.multiPV); err != nil {
+				a.logger.Debug("Stale refresh failed", zap.String("fen", refresh.fen), zap.Error(err))
+			}
+			cancel()
+		}
+
+		r.mu.Lock()
+		delete(r.pending, refresh.key)
+		r.mu.Unlock()
+	}
+}
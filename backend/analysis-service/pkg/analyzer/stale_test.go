@@ -0,0 +1,112 @@
+package analyzer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"go.uber.org/zap"
+)
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (r *staleRefresher) pendingCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pending)
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	a := NewAnalyzer(newEnginePool(t, lineEngine), zap.NewNop(), 1, 12, 20, time.Minute)
+	a.SetStaleWhileRevalidate(2, 1)
+	ctx := context.Background()
+
+	afterE4 := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	afterE5 := "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2"
+	for _, fen := range []string{startFEN, afterE4, afterE5} {
+		a.posCache.Set(PrimaryEngine, fen, 10, engine.Evaluation{Depth: 10, Centipawns: 15, PV: []string{"a2a3"}}, "a2a3", engine.SourceEngine)
+	}
+	if _, ok := a.posCache.getWithin(PrimaryEngine, startFEN, CacheQuery{Depth: 13}, 2); ok {
+		t.Error("a depth 10 entry answered depth 13 within 2")
+	}
+
+	// With the only engine lent out, stale answers come at once
+	eng, err := a.pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := a.AnalyzePositionWithCache(ctx, startFEN, 12, 1, CacheOptions{Stale: true})
+			if err != nil || !result.Stale || result.Depth != 10 {
+				t.Errorf("herd answer = %+v, %v; want stale at depth 10", result, err)
+			}
+		}()
+	}
+	wg.Wait()
+	// The herd queued one refresh, which the worker takes off the queue
+	// and holds until an engine is free
+	if n := a.stale.pendingCount(); n != 1 {
+		t.Fatalf("%d refreshes pending, want 1", n)
+	}
+	waitFor(t, "the refresh to be taken", func() bool { return len(a.stale.queue) == 0 })
+
+	// One more fills the queue; beyond it refreshes are dropped, but the
+	// answer is still stale
+	for _, fen := range []string{afterE4, afterE5} {
+		result, err := a.AnalyzePositionWithCache(ctx, fen, 12, 1, CacheOptions{Stale: true})
+		if err != nil || !result.Stale {
+			t.Errorf("%s: %+v, %v; want a stale answer", fen, result, err)
+		}
+	}
+	if n := a.stale.pendingCount(); n != 2 {
+		t.Errorf("%d refreshes pending, want 2 with the queue full", n)
+	}
+
+	// Only requests allowing it get stale answers
+	for _, cache := range []CacheOptions{{Stale: true, Only: true}, {Stale: true, NoStore: true}, {}} {
+		if margin := a.staleMargin(cache); margin != 0 {
+			t.Errorf("%+v: stale margin %d", cache, margin)
+		}
+	}
+	if _, err := a.AnalyzePositionWithCache(ctx, startFEN, 12, 1, CacheOptions{Stale: true, Only: true}); err == nil {
+		t.Error("cache_only: a depth 10 entry answered depth 12")
+	}
+
+	a.pool.Put(eng)
+	waitFor(t, "the refreshes", func() bool { return a.stale.pendingCount() == 0 })
+	result, err := a.AnalyzePositionWithCache(ctx, startFEN, 12, 1, CacheOptions{Stale: true, Only: true})
+	if err != nil || result.Stale || result.Depth != 12 || result.BestMove != "e2e4" {
+		t.Errorf("after the refresh: %+v, %v; want the depth 12 search", result, err)
+	}
+	if _, err := a.AnalyzePositionWithCache(ctx, afterE5, 12, 1, CacheOptions{Only: true}); err == nil {
+		t.Error("the dropped refresh was searched")
+	}
+}
+
+func TestStaleWhileRevalidate_Off(t *testing.T) {
+	a := NewAnalyzer(nil, zap.NewNop(), 1, 12, 20, time.Minute)
+	a.SetStaleWhileRevalidate(0, 10)
+	a.posCache.Set(PrimaryEngine, startFEN, 10, engine.Evaluation{Depth: 10}, "e2e4", engine.SourceEngine)
+	if margin := a.staleMargin(CacheOptions{Stale: true}); margin != 0 {
+		t.Errorf("margin = %d with stale answers off", margin)
+	}
+	if _, err := a.AnalyzePositionWithCache(context.Background(), startFEN, 12, 1, CacheOptions{Stale: true, Only: true}); err == nil {
+		t.Error("a depth 10 entry answered depth 12 with stale answers off")
+	}
+}
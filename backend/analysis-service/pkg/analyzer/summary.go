@@ -0,0 +1,145 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+// SummaryPart writes one part of a game's summary from an analysis, or ""
+// when the analysis lacks what the part is written from, like the metrics
+// of a game without analyzed moves. A summary is made of the parts written.
+type SummaryPart interface {
+	SummarizeGame(g *GameAnalysis) string
+}
+
+// summarySeparator joins the parts of a summary
+const summarySeparator = " — "
+
+// DefaultSummaryParts make up GameAnalysis.Summary: the players' accuracy
+// and errors, then the turning point of the game with the mate missed or
+// allowed on it
+var DefaultSummaryParts = []SummaryPart{
+	PlayerSummary{},
+	TurningPointSummary{Detail: MateDetail},
+}
+
+// Summarize returns the summary of g made of parts, the default parts when
+// none are given, e.g. "White 92.3% (2 inaccuracies), Black 78.1% (1
+// mistake, 2 blunders) — turning point: 24...Qxb2?? missed mate in 4". It
+// is the same for the same analysis.
+func Summarize(g *GameAnalysis, parts ...SummaryPart) string {
+	if len(parts) == 0 {
+		parts = DefaultSummaryParts
+	}
+	var written []string
+	for _, part := range parts {
+		if text := part.SummarizeGame(g); text != "" {
+			written = append(written, text)
+		}
+	}
+	return strings.Join(written, summarySeparator)
+}
+
+// PlayerSummary writes each player's accuracy and errors, leaving out a
+// player without analyzed moves
+type PlayerSummary struct{}
+
+func (PlayerSummary) SummarizeGame(g *GameAnalysis) string {
+	var players []string
+	for _, p := range []struct {
+		name    string
+		metrics GameMetrics
+	}{{"White", g.WhiteMetrics}, {"Black", g.BlackMetrics}} {
+		if p.metrics.TotalMoves == 0 {
+			continue
+		}
+		text := fmt.Sprintf("%s %.1f%%", p.name, p.metrics.Accuracy)
+		if counts := errorCounts(p.metrics); counts != "" {
+			text += " (" + counts + ")"
+		}
+		players = append(players, text)
+	}
+	return strings.Join(players, ", ")
+}
+
+// errorCounts writes the inaccuracies, mistakes and blunders of m that
+// there are, e.g. "1 mistake, 2 blunders"
+func errorCounts(m GameMetrics) string {
+	var counts []string
+	for _, c := range []struct {
+		n              int
+		singular, many string
+	}{
+		{m.Inaccuracies, "inaccuracy", "inaccuracies"},
+		{m.Mistakes, "mistake", "mistakes"},
+		{m.Blunders, "blunder", "blunders"},
+	} {
+		switch {
+		case c.n == 1:
+			counts = append(counts, "1 "+c.singular)
+		case c.n > 1:
+			counts = append(counts, fmt.Sprintf("%d %s", c.n, c.many))
+		}
+	}
+	return strings.Join(counts, ", ")
+}
+
+// TurningPointSummary writes the turning point of a game: of its critical
+// moments, the mistakes, blunders and missed wins, the one that cost the
+// most win probability, the earliest of equals. Detail, when set, adds to
+// the move what happened on it. A game without critical moments has no
+// turning point.
+type TurningPointSummary struct {
+	Detail func(move *MoveAnalysis) string
+}
+
+func (s TurningPointSummary) SummarizeGame(g *GameAnalysis) string {
+	move := turningPoint(g.Moves)
+	if move == nil {
+		return ""
+	}
+	glyph, _, _ := evaluation.ClassificationDisplay(evaluation.MoveClassification(move.Classification))
+	dots := "."
+	if move.Color == "black" {
+		dots = "..."
+	}
+	text := fmt.Sprintf("turning point: %d%s%s%s", move.MoveNumber, dots, move.PlayedMove, glyph)
+	if s.Detail != nil {
+		if detail := s.Detail(move); detail != "" {
+			text += " " + detail
+		}
+	}
+	return text
+}
+
+// turningPoint returns the critical moment of moves with the lowest move
+// accuracy, then the highest centipawn loss, or nil when there is none
+func turningPoint(moves []MoveAnalysis) *MoveAnalysis {
+	var worst *MoveAnalysis
+	for i := range moves {
+		move := &moves[i]
+		if !criticalMoments[move.Classification] {
+			continue
+		}
+		if worst == nil || move.MoveAccuracy < worst.MoveAccuracy ||
+			move.MoveAccuracy == worst.MoveAccuracy && move.CentipawnLoss > worst.CentipawnLoss {
+			worst = move
+		}
+	}
+	return worst
+}
+
+// MateDetail writes the mate a move missed or allowed, e.g. "missed mate
+// in 4", or "" when it did neither
+func MateDetail(move *MoveAnalysis) string {
+	in := ExplainMove(move)
+	switch in.Kind {
+	case ExplainMissedMate:
+		return fmt.Sprintf("missed mate in %d", in.MateIn)
+	case ExplainAllowsMate:
+		return fmt.Sprintf("allows mate in %d", in.MateIn)
+	}
+	return ""
+}
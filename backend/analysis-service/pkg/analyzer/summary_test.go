@@ -0,0 +1,108 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fixtureMoveGame is a game of the explain fixtures' moves, each played as
+// move 24 by its side, with the metrics AnalyzeGame would give them
+func fixtureMoveGame(t *testing.T, fixtures ...explainFixture) *GameAnalysis {
+	t.Helper()
+	metrics := newGameMetrics("", "", len(fixtures))
+	g := &GameAnalysis{}
+	for _, f := range fixtures {
+		move := f.move(t)
+		move.MoveNumber = 24
+		g.Moves = append(g.Moves, *move)
+		metrics.add(move)
+	}
+	g.WhiteMetrics = metrics.result("white")
+	g.BlackMetrics = metrics.result("black")
+	return g
+}
+
+func TestSummarize_Golden(t *testing.T) {
+	var b strings.Builder
+
+	// The fixture games on the fake engine, summarized as AnalyzeGame does
+	games := []string{
+		testPGN,
+		"[Result \"1-0\"]\n\n1. e4 f6 2. d4 g5 3. Qh5# 1-0",
+		"[Result \"1/2-1/2\"]\n\n1. d4 d5 2. c4 e6 3. Nc3 Nf6 4. Bg5 Be7 5. e3 O-O 6. Nf3 h6 7. Bh4 b6 8. cxd5 Nxd5 1/2-1/2",
+		"[Result \"0-1\"]\n\n1. e4 f5 2. Qh5+ g6 3. Nf3 gxh5 0-1",
+	}
+	a := newFakeAnalyzer(t)
+	a.SetBookDetector(plyBook{0: true, 1: true})
+	for i, pgn := range games {
+		analysis, err := a.AnalyzeGame(context.Background(), "g1", pgn, 12, GameOptions{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if analysis.Summary != Summarize(analysis) {
+			t.Errorf("game %d: Summary %q isn't Summarize's %q", i, analysis.Summary, Summarize(analysis))
+		}
+		fmt.Fprintf(&b, "game %d: %s\n", i, analysis.Summary)
+	}
+
+	// Turning points on a mate missed and allowed, and without either
+	fmt.Fprintf(&b, "missed mate: %s\n", Summarize(fixtureMoveGame(t, explainFixtures[0])))
+	fmt.Fprintf(&b, "allows mate: %s\n", Summarize(fixtureMoveGame(t, explainFixtures[1], explainFixtures[2])))
+	fmt.Fprintf(&b, "drops a pawn: %s\n", Summarize(fixtureMoveGame(t, explainFixtures[3])))
+
+	const golden = "testdata/summaries.golden"
+	if *update {
+		if err := os.WriteFile(golden, []byte(b.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != string(want) {
+		t.Errorf("summaries changed; run go test -update if intended\ngot:\n%s", b.String())
+	}
+}
+
+func TestSummarize_Degrades(t *testing.T) {
+	// Without analyzed moves there is nothing to summarize
+	if got := Summarize(&GameAnalysis{TotalMoves: 40, TimedOut: true}); got != "" {
+		t.Errorf("empty analysis summary = %q, want empty", got)
+	}
+
+	// Without critical moments there is no turning point
+	g := fixtureMoveGame(t, explainFixtures[len(explainFixtures)-1])
+	if got := Summarize(g); !strings.HasPrefix(got, "White ") || strings.Contains(got, summarySeparator) {
+		t.Errorf("summary = %q, want White's accuracy alone", got)
+	}
+
+	// Without a detail the turning point is the move alone
+	g = fixtureMoveGame(t, explainFixtures[0])
+	if got, want := Summarize(g, TurningPointSummary{}), "turning point: 24.d3?"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
+
+func TestTurningPoint(t *testing.T) {
+	moves := []MoveAnalysis{
+		{Ply: 0, Classification: ClassBlunder, MoveAccuracy: 20, CentipawnLoss: 500},
+		{Ply: 1, Classification: ClassInaccuracy, MoveAccuracy: 10, CentipawnLoss: 80},
+		{Ply: 2, Classification: ClassMistake, MoveAccuracy: 20, CentipawnLoss: 500},
+		{Ply: 3, Classification: ClassMissedWin, MoveAccuracy: 20, CentipawnLoss: 600},
+	}
+	// Inaccuracies aren't critical; of equal accuracies the bigger loss wins
+	if got := turningPoint(moves); got == nil || got.Ply != 3 {
+		t.Errorf("turning point = %+v, want ply 3", got)
+	}
+	// Of equal moves the earliest
+	if got := turningPoint(moves[:3]); got == nil || got.Ply != 0 {
+		t.Errorf("turning point = %+v, want ply 0", got)
+	}
+	if got := turningPoint(moves[1:2]); got != nil {
+		t.Errorf("turning point = %+v, want none", got)
+	}
+}
@@ -0,0 +1,214 @@
+package analyzer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// Termination is how a game ended
+type Termination string
+
+const (
+	TerminationUnknown              Termination = ""
+	TerminationCheckmate            Termination = "checkmate"
+	TerminationResignation          Termination = "resignation"
+	TerminationTimeForfeit          Termination = "time_forfeit"
+	TerminationAbandonment          Termination = "abandonment"
+	TerminationStalemate            Termination = "stalemate"
+	TerminationInsufficientMaterial Termination = "insufficient_material"
+	TerminationRepetition           Termination = "repetition"
+	TerminationFiftyMoveRule        Termination = "fifty_move_rule"
+	TerminationAgreement            Termination = "agreement"
+)
+
+// terminationTag reads a Termination tag, chess.com's "X won by
+// checkmate" or "Game drawn by repetition" and Lichess's "Time forfeit".
+// normal is set for Lichess's "Normal", a game ended on the board, by
+// resignation or by agreement.
+func terminationTag(tag string) (termination Termination, normal bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	switch {
+	case tag == "normal":
+		return TerminationUnknown, true
+	case strings.Contains(tag, "checkmate"):
+		return TerminationCheckmate, false
+	case strings.Contains(tag, "resign"):
+		return TerminationResignation, false
+	// Before insufficient material: "drawn by timeout vs insufficient material"
+	case strings.Contains(tag, "on time"), strings.Contains(tag, "time forfeit"), strings.Contains(tag, "timeout"):
+		return TerminationTimeForfeit, false
+	case strings.Contains(tag, "abandon"):
+		return TerminationAbandonment, false
+	case strings.Contains(tag, "stalemate"):
+		return TerminationStalemate, false
+	case strings.Contains(tag, "insufficient material"):
+		return TerminationInsufficientMaterial, false
+	case strings.Contains(tag, "repetition"):
+		return TerminationRepetition, false
+	case strings.Contains(tag, "50-move"), strings.Contains(tag, "fifty"):
+		return TerminationFiftyMoveRule, false
+	case strings.Contains(tag, "agree"):
+		return TerminationAgreement, false
+	}
+	return TerminationUnknown, false
+}
+
+// winnerColor returns the winner of a result in PGN notation, "" for a
+// draw or an unfinished game
+func winnerColor(result string) string {
+	switch result {
+	case "1-0":
+		return "white"
+	case "0-1":
+		return "black"
+	}
+	return ""
+}
+
+// gameEnding is how a game ended, as in GameAnalysis
+type gameEnding struct {
+	termination      Termination
+	winnerColor      string
+	againstRunOfPlay bool
+}
+
+// endGame works out how a game ended from result, in PGN notation,
+// the Termination tag and, when final isn't "", the final position's FEN
+// with positions, all of the game's, and finalEval, the side to move's
+// evaluation of it when evaluated is set
+func endGame(result, tag, final string, positions []Position, finalEval int, evaluated bool) gameEnding {
+	ending := gameEnding{winnerColor: winnerColor(result)}
+	termination, normal := terminationTag(tag)
+
+	var method chess.Method
+	var halfMoves int
+	repeated := false
+	if final != "" {
+		if fenOpt, err := chess.FEN(final); err == nil {
+			method = chess.NewGame(fenOpt).Method()
+		}
+		if fields := strings.Fields(final); len(fields) > 4 {
+			halfMoves, _ = strconv.Atoi(fields[4])
+		}
+		key, seen := positionKey(final), 0
+		for _, pos := range positions {
+			if positionKey(pos.FEN) == key {
+				seen++
+			}
+		}
+		repeated = seen >= 3
+	}
+	sideToMove := ""
+	if fields := strings.Fields(final); len(fields) > 1 {
+		sideToMove = map[string]string{"w": "white", "b": "black"}[fields[1]]
+	}
+
+	// The board has the last word on mate and stalemate, whatever the tags
+	switch method {
+	case chess.Checkmate:
+		ending.termination = TerminationCheckmate
+		ending.winnerColor = opponent(sideToMove)
+		return ending
+	case chess.Stalemate:
+		ending.termination = TerminationStalemate
+		ending.winnerColor = ""
+		ending.againstRunOfPlay = result == "1-0" || result == "0-1"
+		return ending
+	}
+
+	decisive := ending.winnerColor != ""
+	switch {
+	case termination != TerminationUnknown:
+		ending.termination = termination
+	case result == "1/2-1/2" && method == chess.InsufficientMaterial:
+		ending.termination = TerminationInsufficientMaterial
+	case result == "1/2-1/2" && repeated:
+		ending.termination = TerminationRepetition
+	case result == "1/2-1/2" && halfMoves >= 100:
+		ending.termination = TerminationFiftyMoveRule
+	case normal && decisive:
+		ending.termination = TerminationResignation
+	case normal && result == "1/2-1/2":
+		ending.termination = TerminationAgreement
+	}
+
+	if decisive && ending.termination != TerminationCheckmate {
+		loserEval := finalEval
+		if sideToMove != opponent(ending.winnerColor) {
+			loserEval = -finalEval
+		}
+		ending.againstRunOfPlay = method == chess.InsufficientMaterial ||
+			(evaluated && sideToMove != "" && loserEval >= RepetitionAdvantage)
+	}
+	return ending
+}
+
+// endGame sets how g ended from its positions, result, in PGN notation,
+// and Termination tag. A truncated game's final position isn't the
+// game's, so only the tags count.
+func (g *GameAnalysis) endGame(positions []Position, result, tag string) {
+	var final string
+	var finalEval int
+	evaluated := false
+	if !g.Truncated && len(positions) > 0 {
+		final = positions[len(positions)-1].FEN
+		if n := len(g.Moves); n > 0 && g.Moves[n-1].FENAfter == final {
+			finalEval, evaluated = centipawns(g.Moves[n-1].EvalAfter), true
+		}
+	}
+	ending := endGame(result, tag, final, positions, finalEval, evaluated)
+	g.Termination, g.WinnerColor, g.ResultAgainstRunOfPlay = ending.termination, ending.winnerColor, ending.againstRunOfPlay
+}
+
+// opponent returns the other color, "" for ""
+func opponent(color string) string {
+	switch color {
+	case "white":
+		return "black"
+	case "black":
+		return "white"
+	}
+	return ""
+}
+
+// ResultSummary describes how the game ended, as "White won by checkmate on move
+// 34" or "Draw by repetition", "" when the result isn't known
+func (g *GameAnalysis) ResultSummary() string {
+	var summary string
+	winner := map[string]string{"white": "White", "black": "Black"}[g.WinnerColor]
+	switch {
+	case winner != "" && g.Termination == TerminationTimeForfeit:
+		summary = winner + " won on time"
+	case winner != "" && g.Termination != TerminationUnknown:
+		summary = winner + " won by " + terminationNames[g.Termination]
+	case winner != "":
+		summary = winner + " won"
+	case g.Termination == TerminationTimeForfeit:
+		summary = "Draw by timeout vs insufficient material"
+	case g.Termination != TerminationUnknown:
+		summary = "Draw by " + terminationNames[g.Termination]
+	case g.Info.Result == "1/2-1/2":
+		summary = "Draw"
+	default:
+		return ""
+	}
+	if len(g.Moves) > 0 && !g.TimedOut && !g.Truncated && len(g.Moves) == g.TotalMoves {
+		summary += fmt.Sprintf(" on move %d", g.Moves[len(g.Moves)-1].MoveNumber)
+	}
+	return summary
+}
+
+// terminationNames are the terminations as ResultSummary words them
+var terminationNames = map[Termination]string{
+	TerminationCheckmate:            "checkmate",
+	TerminationResignation:          "resignation",
+	TerminationAbandonment:          "abandonment",
+	TerminationStalemate:            "stalemate",
+	TerminationInsufficientMaterial: "insufficient material",
+	TerminationRepetition:           "repetition",
+	TerminationFiftyMoveRule:        "the fifty-move rule",
+	TerminationAgreement:            "agreement",
+}
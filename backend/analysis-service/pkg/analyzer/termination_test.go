@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEndGame(t *testing.T) {
+	const (
+		foolsMate = "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"
+		stalemate = "7k/5Q2/6K1/8/8/8/8/8 b - - 0 60"
+		bareKings = "8/8/4k3/8/8/4K3/8/8 w - - 0 60"
+		middle    = "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+		fifty     = "8/8/4k3/8/8/4K3/4Q3/8 w - - 100 120"
+	)
+	for _, tt := range []struct {
+		name        string
+		result, tag string
+		final       string
+		finalEval   int // Side to move's
+		evaluated   bool
+		want        gameEnding
+	}{
+		{"checkmate", "0-1", "", foolsMate, 0, false, gameEnding{TerminationCheckmate, "black", false}},
+		{"board overrides the tags", "1-0", "White won on time", foolsMate, 0, false, gameEnding{TerminationCheckmate, "black", false}},
+		{"stalemate", "1/2-1/2", "", stalemate, 0, false, gameEnding{TerminationStalemate, "", false}},
+		{"decisive stalemate", "1-0", "", stalemate, 0, false, gameEnding{TerminationStalemate, "", true}},
+		{"insufficient material", "1/2-1/2", "", bareKings, 0, false, gameEnding{TerminationInsufficientMaterial, "", false}},
+		{"flag with bare kings", "1-0", "White won on time", bareKings, 0, false, gameEnding{TerminationTimeForfeit, "white", true}},
+		{"draw on time", "1/2-1/2", "Game drawn by timeout vs insufficient material", middle, 0, false, gameEnding{TerminationTimeForfeit, "", false}},
+		{"fifty moves", "1/2-1/2", "", fifty, 0, false, gameEnding{TerminationFiftyMoveRule, "", false}},
+		{"chess.com repetition", "1/2-1/2", "Game drawn by repetition", middle, 0, false, gameEnding{TerminationRepetition, "", false}},
+		{"lichess draw", "1/2-1/2", "Normal", middle, 0, false, gameEnding{TerminationAgreement, "", false}},
+		{"lichess resignation", "1-0", "Normal", middle, 500, true, gameEnding{TerminationResignation, "white", false}},
+		{"resigned a winning position", "0-1", "Normal", middle, 300, true, gameEnding{TerminationResignation, "black", true}},
+		{"no final evaluation", "1-0", "Time forfeit", middle, -300, false, gameEnding{TerminationTimeForfeit, "white", false}},
+		{"flagged a winning position, loser to move", "0-1", "Black won on time", middle, 300, true, gameEnding{TerminationTimeForfeit, "black", true}},
+		{"winner ahead", "1-0", "Magnus won by resignation", middle, 300, true, gameEnding{TerminationResignation, "white", false}},
+		{"no tags", "1-0", "", middle, 0, false, gameEnding{TerminationUnknown, "white", false}},
+		{"unfinished", "*", "", middle, 0, false, gameEnding{}},
+		{"no final position", "1-0", "Time forfeit", "", 0, false, gameEnding{TerminationTimeForfeit, "white", false}},
+	} {
+		if got := endGame(tt.result, tt.tag, tt.final, nil, tt.finalEval, tt.evaluated); got != tt.want {
+			t.Errorf("%s: %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEndGame_Repetition(t *testing.T) {
+	positions := []Position{
+		{FEN: "8/8/4k3/8/8/4K3/4Q3/8 w - - 0 50"},
+		{FEN: "8/8/4k3/8/8/4K3/4Q3/8 w - - 4 52"},
+		{FEN: "8/8/4k3/8/8/4K3/4Q3/8 w - - 8 54"},
+	}
+	got := endGame("1/2-1/2", "", positions[2].FEN, positions, 0, false)
+	if got.termination != TerminationRepetition {
+		t.Errorf("termination = %q, want repetition", got.termination)
+	}
+}
+
+func TestGameAnalysis_ResultSummary(t *testing.T) {
+	moves := []MoveAnalysis{{MoveNumber: 33, Color: "black"}, {MoveNumber: 34, Color: "white"}}
+	for _, tt := range []struct {
+		analysis GameAnalysis
+		want     string
+	}{
+		{GameAnalysis{Termination: TerminationCheckmate, WinnerColor: "white", Moves: moves, TotalMoves: 2}, "White won by checkmate on move 34"},
+		{GameAnalysis{Termination: TerminationTimeForfeit, WinnerColor: "white", Moves: moves, TotalMoves: 3}, "White won on time"},
+		{GameAnalysis{Termination: TerminationRepetition}, "Draw by repetition"},
+		{GameAnalysis{Termination: TerminationTimeForfeit}, "Draw by timeout vs insufficient material"},
+		{GameAnalysis{WinnerColor: "black"}, "Black won"},
+		{GameAnalysis{Info: GameInfo{Result: "1/2-1/2"}}, "Draw"},
+		{GameAnalysis{}, ""},
+	} {
+		if got := tt.analysis.ResultSummary(); got != tt.want {
+			t.Errorf("Summary = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeGame_Termination(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	pgn := "[Result \"0-1\"]\n[Termination \"Time forfeit\"]\n\n" + testPGN
+
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", pgn, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.Termination != TerminationTimeForfeit || analysis.WinnerColor != "black" {
+		t.Errorf("termination %q, winner %q; want time_forfeit, black", analysis.Termination, analysis.WinnerColor)
+	}
+	if got, want := analysis.ResultSummary(), "Black won on time on move 7"; got != want {
+		t.Errorf("Summary = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/timing"
+)
+
+// DepthTiming is the rolling estimate of the engine time one single-PV
+// search of a position takes at depths MinDepth to MaxDepth, as reported by
+// the engine, so without the wait for a free engine
+type DepthTiming = timing.Stats
+
+// CapacityEstimate is the game analyses per hour the primary pool can
+// finish at a depth, see EstimateCapacity
+type CapacityEstimate = timing.Capacity
+
+// TimingHistogramBounds are the upper bounds, in milliseconds, of the bins
+// of DepthTiming.Histogram
+var TimingHistogramBounds = timing.HistogramBounds
+
+// recordTiming adds a completed single-PV search of fen to depth by the
+// primary engine to the rolling engine times
+func (a *Analyzer) recordTiming(fen string, depth int, timeMs int64) {
+	a.timings.Record(depth, timing.PhaseOf(fen), timeMs)
+}
+
+// DepthTimings returns the rolling engine time per position of each depth
+// bucket the primary engine has searched
+func (a *Analyzer) DepthTimings() []DepthTiming {
+	return a.timings.Snapshot()
+}
+
+// EstimatePositionTime returns the expected engine time of a single-PV
+// search at depth, from recent searches at depths of the same bucket. It
+// is false until there has been one.
+func (a *Analyzer) EstimatePositionTime(depth int) (time.Duration, bool) {
+	return a.timings.Estimate(depth)
+}
+
+// EstimateCapacity estimates how many game analyses of moves moves at
+// depth the primary pool can finish per hour at its current size
+func (a *Analyzer) EstimateCapacity(depth, moves int) CapacityEstimate {
+	engines := 0
+	if a.pool != nil {
+		engines = a.pool.Size()
+	}
+	return a.timings.Capacity(depth, moves, engines)
+}
@@ -0,0 +1,104 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAnalyzeGame_Timing(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	pgn := "1. e4 e5 2. Nf3 *"
+
+	analysis, err := a.AnalyzeGame(context.Background(), "g1", pgn, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.StartedAt == 0 || analysis.CompletedAt < analysis.StartedAt {
+		t.Fatalf("started at %d, completed at %d", analysis.StartedAt, analysis.CompletedAt)
+	}
+	for _, move := range analysis.Moves {
+		if move.AnalyzedAt < analysis.StartedAt || move.AnalyzedAt > analysis.CompletedAt {
+			t.Errorf("ply %d analyzed at %d, outside the analysis", move.Ply, move.AnalyzedAt)
+		}
+		// The fake engine reports 10ms for every search
+		if move.EngineTimeMs != 10 {
+			t.Errorf("ply %d engine time = %d, want 10", move.Ply, move.EngineTimeMs)
+		}
+	}
+	timings := a.DepthTimings()
+	if len(timings) != 1 || timings[0].MinDepth != 9 || timings[0].MsPerPosition != 10 || timings[0].Searches != 4 {
+		t.Errorf("depth timings = %+v, want 4 searches of 10ms at 9-12", timings)
+	}
+
+	// Cached positions took no engine time
+	analysis, err = a.AnalyzeGame(context.Background(), "g1", pgn, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, move := range analysis.Moves {
+		if !move.FromCache || move.EngineTimeMs != 0 || move.QueueTimeMs != 0 || move.SearchTimeMs != 0 {
+			t.Errorf("ply %d: from cache %v, engine time %d, queue %d, search %d; want cached with none", move.Ply, move.FromCache, move.EngineTimeMs, move.QueueTimeMs, move.SearchTimeMs)
+		}
+	}
+	if estimate, ok := a.EstimatePositionTime(12); !ok || estimate != 10*time.Millisecond {
+		t.Errorf("EstimatePositionTime(12) = %v, %v; want 10ms", estimate, ok)
+	}
+}
+
+func TestAnalyzePosition_QueueAndSearchTime(t *testing.T) {
+	p := newFakePool(t)
+	a := NewAnalyzer(p, zap.NewNop(), 1, 12, 20, time.Minute)
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+
+	// Hold the only engine so the request has to queue for it
+	eng, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		p.Put(eng)
+	}()
+
+	result, err := a.AnalyzePosition(context.Background(), fen, 12, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.QueueTimeMs < 50 || result.TimeMs != result.QueueTimeMs+result.SearchTimeMs {
+		t.Errorf("queue %dms, search %dms, total %dms; want at least 50ms queued and the sum as total", result.QueueTimeMs, result.SearchTimeMs, result.TimeMs)
+	}
+	if result.PoolAvailable != 0 || result.PoolWaiting != 0 {
+		t.Errorf("pool at dispatch: %d available, %d waiting; want 0, 0", result.PoolAvailable, result.PoolWaiting)
+	}
+
+	// Answered from the cache: no time at all
+	result, err = a.AnalyzePosition(context.Background(), fen, 12, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.QueueTimeMs != 0 || result.SearchTimeMs != 0 || result.TimeMs != 0 {
+		t.Errorf("cached answer: queue %dms, search %dms, total %dms; want 0", result.QueueTimeMs, result.SearchTimeMs, result.TimeMs)
+	}
+}
+
+func TestEstimateCapacity(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	if capacity := a.EstimateCapacity(12, 40); capacity.Known {
+		t.Fatalf("capacity before any search = %+v", capacity)
+	}
+	if _, err := a.AnalyzeGame(context.Background(), "g1", "1. e4 e5 2. Nf3 *", 12, GameOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The fake engine reports 10ms for every search
+	capacity := a.EstimateCapacity(12, 40)
+	if !capacity.Known || capacity.MsPerPosition != 10 || capacity.Engines != a.pool.Size() {
+		t.Fatalf("capacity = %+v, want 10ms per position on %d engines", capacity, a.pool.Size())
+	}
+	if want := float64(a.pool.Size()) * 3600000 / (81 * 10); capacity.GamesPerHour != want {
+		t.Errorf("games per hour = %v, want %v", capacity.GamesPerHour, want)
+	}
+}
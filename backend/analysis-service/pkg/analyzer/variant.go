@@ -0,0 +1,57 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VariantError reports a game of a chess variant the analyzer doesn't
+// evaluate, whose moves standard rules would misread. It matches
+// ErrUnsupportedVariant.
+type VariantError struct {
+	Variant string // As in the PGN's Variant tag
+}
+
+func (e *VariantError) Error() string {
+	return fmt.Sprintf("%s: %q", ErrUnsupportedVariant, e.Variant)
+}
+
+// Unwrap makes the error match ErrUnsupportedVariant
+func (e *VariantError) Unwrap() error {
+	return ErrUnsupportedVariant
+}
+
+// standardVariants are the Variant tag values, lowercased, of games played
+// under standard rules. Without the tag a game is standard.
+var standardVariants = map[string]bool{
+	"":              true,
+	"standard":      true,
+	"from position": true,
+}
+
+// CheckVariant returns a *VariantError when the Variant tag of pgn, a
+// single game, names anything but standard chess
+func CheckVariant(pgn string) error {
+	variant := tagValue(parsePGNTags(pgn), "Variant")
+	if standardVariants[strings.ToLower(strings.TrimSpace(variant))] {
+		return nil
+	}
+	return &VariantError{Variant: variant}
+}
+
+// SetAnalyzeVariants makes the analyzer take games of any variant and
+// analyze them as standard chess, for experimenting. Their moves stop
+// being legal wherever the variant's rules differ, and the evaluations
+// before that mean little.
+func (a *Analyzer) SetAnalyzeVariants(on bool) {
+	a.analyzeVariants = on
+}
+
+// ParsePGN parses pgn like the package's ParsePGN, but accepts games of
+// any variant when SetAnalyzeVariants is on
+func (a *Analyzer) ParsePGN(pgn string) ([]Position, error) {
+	if a.analyzeVariants {
+		return parsePGN(pgn)
+	}
+	return ParsePGN(pgn)
+}
@@ -0,0 +1,67 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCheckVariant(t *testing.T) {
+	tests := []struct {
+		variant string // "" for no tag
+		ok      bool
+	}{
+		{"", true},
+		{"Standard", true},
+		{"standard", true},
+		{"From Position", true},
+		{"Atomic", false},
+		{"Antichess", false},
+		{"Crazyhouse", false},
+		{"Chess960", false},
+	}
+	for _, tt := range tests {
+		pgn := "[Event \"Casual game\"]\n"
+		if tt.variant != "" {
+			pgn += "[Variant \"" + tt.variant + "\"]\n"
+		}
+		pgn += "\n1. e4 e5 *"
+
+		err := CheckVariant(pgn)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("%q: %v", tt.variant, err)
+			}
+			continue
+		}
+		var variantErr *VariantError
+		if !errors.As(err, &variantErr) || variantErr.Variant != tt.variant || !errors.Is(err, ErrUnsupportedVariant) {
+			t.Errorf("%q: %v, want a VariantError", tt.variant, err)
+		}
+	}
+}
+
+func TestAnalyzeGame_Variant(t *testing.T) {
+	// Legal in standard chess, but in Atomic 2. exd5 blows up both pieces
+	const pgn = "[Variant \"Atomic\"]\n\n1. e4 d5 2. exd5 *"
+
+	positions, err := ParsePGN(pgn)
+	if !errors.Is(err, ErrUnsupportedVariant) || positions != nil {
+		t.Errorf("ParsePGN() = %d positions, %v; want ErrUnsupportedVariant", len(positions), err)
+	}
+
+	a := newFakeAnalyzer(t)
+	ctx := context.Background()
+	if _, err := a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{AnalyzeUntilError: true}, nil); !errors.Is(err, ErrUnsupportedVariant) {
+		t.Errorf("AnalyzeGame() error = %v, want ErrUnsupportedVariant", err)
+	}
+
+	a.SetAnalyzeVariants(true)
+	analysis, err := a.AnalyzeGame(ctx, "g1", pgn, 12, GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Moves) != 3 {
+		t.Errorf("analyzed %d moves as standard chess, want 3", len(analysis.Moves))
+	}
+}
@@ -0,0 +1,170 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"go.uber.org/zap"
+)
+
+// WarmReport counts the positions of a cache warming as it goes. Every
+// position given is a duplicate, cached, game over or pending until it is
+// analyzed or fails.
+type WarmReport struct {
+	Depth      int
+	Positions  int // Given, duplicates included
+	Duplicates int // Given again after their first time
+	Cached     int // Already cached at the depth
+	GameOver   int // Mate or stalemate, nothing to search
+	Pending    int // Left to search
+	Analyzed   int // Searched and cached
+	Failed     int
+
+	// EstimatedTime is the engine time the pending searches are expected
+	// to take, known once a search at the depth has finished
+	EstimatedTime time.Duration
+	Estimated     bool
+}
+
+// PGNPositions returns the FEN of every position of the games of pgn, one
+// or more games, in order and starting positions included
+func PGNPositions(pgn string) ([]string, error) {
+	games := SplitPGN(pgn)
+	if len(games) == 0 {
+		return nil, fmt.Errorf("%w: no games found", ErrInvalidPGN)
+	}
+	var fens []string
+	for i, game := range games {
+		positions, err := ParsePGN(game.Text)
+		if err != nil {
+			return nil, fmt.Errorf("game %d: %w", i+1, err)
+		}
+		for _, pos := range positions {
+			fens = append(fens, pos.FEN)
+		}
+	}
+	return fens, nil
+}
+
+// WarmCache searches fens to depth with the primary engine and caches
+// them, ahead of games expected to reach them. Duplicates, as the cache
+// keys positions, and positions cached at the depth are skipped. The
+// searches are background work: one engine at a time, taken only while no
+// other request waits for one. progress, if set, is called after each
+// search. With dryRun nothing is searched and the report says what would
+// be. Canceling ctx stops the warming; what was searched stays cached.
+func (a *Analyzer) WarmCache(ctx context.Context, fens []string, depth int, dryRun bool, progress func(WarmReport)) (WarmReport, error) {
+	for i, fen := range fens {
+		if err := engine.ValidateFEN(fen); err != nil {
+			return WarmReport{}, fmt.Errorf("%w: position %d: %v", ErrInvalidFEN, i+1, err)
+		}
+	}
+	depth = a.ClampDepth(depth)
+
+	report := WarmReport{Depth: depth, Positions: len(fens)}
+	seen := make(map[string]bool, len(fens))
+	var pending []string
+	for _, fen := range fens {
+		key := a.posCache.cacheKey(PrimaryEngine, fen)
+		switch {
+		case seen[key]:
+			report.Duplicates++
+		case a.posCache.has(PrimaryEngine, fen, CacheQuery{Depth: depth}):
+			report.Cached++
+		case gameOver(fen) != "":
+			report.GameOver++
+		default:
+			pending = append(pending, fen)
+		}
+		seen[key] = true
+	}
+	report.Pending = len(pending)
+	a.estimateWarming(&report)
+	if dryRun {
+		return report, nil
+	}
+
+	a.logger.Info("Warming position cache",
+		zap.Int("positions", report.Positions),
+		zap.Int("toAnalyze", report.Pending),
+		zap.Int("depth", depth))
+
+	for _, fen := range pending {
+		if err := a.warmPosition(ctx, fen, depth, 1); err != nil {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			a.logger.Warn("Failed to warm position", zap.String("fen", fen), zap.Error(err))
+			report.Failed++
+		} else {
+			report.Analyzed++
+		}
+		report.Pending--
+		a.estimateWarming(&report)
+		if progress != nil {
+			progress(report)
+		}
+	}
+	return report, nil
+}
+
+// estimateWarming sets report's estimate of its pending searches
+func (a *Analyzer) estimateWarming(report *WarmReport) {
+	perPosition, ok := a.EstimatePositionTime(report.Depth)
+	report.EstimatedTime = perPosition * time.Duration(report.Pending)
+	report.Estimated = ok || report.Pending == 0
+}
+
+// warmPosition searches fen to depth for multiPV PVs on a background
+// engine and caches it, whatever the admission policy
+func (a *Analyzer) warmPosition(ctx context.Context, fen string, depth, multiPV int) error {
+	eng, err := a.pool.GetBackground(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := a.searchBackground(ctx, eng, fen, depth, multiPV)
+	if err != nil {
+		return err
+	}
+	a.posCache.setResult(PrimaryEngine, fen, depth, multiPV, result, true)
+	return nil
+}
+
+// searchBackground searches fen to depth for multiPV PVs on eng, a
+// background engine of the pool it gives back, and returns the result if
+// it may be cached
+func (a *Analyzer) searchBackground(ctx context.Context, eng *engine.Engine, fen string, depth, multiPV int) (*engine.AnalysisResult, error) {
+	searchCtx, cancel := a.withTimeout(ctx)
+	defer cancel()
+	searchStart := time.Now()
+	result, err := eng.AnalyzePositionContext(searchCtx, fen, depth, multiPV)
+	meterSearch(ctx, time.Since(searchStart))
+	if err != nil {
+		if classifyFailure(err) == FailureEngineDied {
+			a.pool.Discard(eng)
+		} else {
+			a.pool.Put(eng)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrEngineFailure, err)
+	}
+	if result.Stopped || len(result.Evaluations) == 0 {
+		a.pool.Put(eng)
+		return nil, fmt.Errorf("%w: search of %s stopped before depth %d", ErrTimeout, fen, depth)
+	}
+	if a.judgeEngine(eng, depth, result.Evaluations[0], result.BestMove) {
+		a.pool.Discard(eng)
+	} else {
+		a.pool.Put(eng)
+	}
+
+	if multiPV == 1 {
+		a.recordTiming(fen, depth, result.Evaluations[0].TimeMs)
+	}
+	if !a.cacheable(depth, result.Evaluations[0], result.BestMove) {
+		return nil, fmt.Errorf("%w: implausible search of %s not cached", ErrEngineFailure, fen)
+	}
+	result.Source = engine.SourceEngine
+	return result, nil
+}
@@ -0,0 +1,147 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+)
+
+func TestWarmCache(t *testing.T) {
+	a := newFakeAnalyzer(t)
+	ctx := context.Background()
+	a.posCache.Set(PrimaryEngine, afterE5FEN, 14, engine.Evaluation{Depth: 14}, "g1f3", engine.SourceEngine)
+
+	const matedFEN = "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"
+	fens := []string{
+		startFEN,
+		afterE4FEN,
+		strings.Replace(startFEN, " 0 1", " 4 7", 1), // The same position later in a game
+		afterE5FEN,
+		matedFEN,
+		foolsMateFEN,
+	}
+
+	dry, err := a.WarmCache(ctx, fens, 12, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := WarmReport{Depth: 12, Positions: 6, Duplicates: 1, Cached: 1, GameOver: 1, Pending: 3}
+	if dry != want {
+		t.Errorf("dry run = %+v, want %+v", dry, want)
+	}
+	if a.posCache.has(PrimaryEngine, startFEN, CacheQuery{Depth: 12}) {
+		t.Fatal("dry run searched")
+	}
+
+	var reports []WarmReport
+	report, err := a.WarmCache(ctx, fens, 12, false, func(r WarmReport) { reports = append(reports, r) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Analyzed != 3 || report.Pending != 0 || report.Failed != 0 || !report.Estimated {
+		t.Errorf("report = %+v, want 3 positions analyzed", report)
+	}
+	if len(reports) != 3 || reports[0].Pending != 2 || reports[0].Analyzed != 1 {
+		t.Errorf("progress = %+v, want a report per search", reports)
+	}
+	for _, fen := range []string{startFEN, afterE4FEN, foolsMateFEN} {
+		if !a.posCache.has(PrimaryEngine, fen, CacheQuery{Depth: 12}) {
+			t.Errorf("%s not cached", fen)
+		}
+	}
+
+	// Now everything is cached, and the dry run has timings to go on
+	again, err := a.WarmCache(ctx, fens, 12, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Cached != 4 || again.Pending != 0 {
+		t.Errorf("second dry run = %+v, want 4 cached", again)
+	}
+	const afterD4FEN = "rnbqkbnr/pppp1ppp/8/4p3/3PP3/8/PPP2PPP/RNBQKBNR b KQkq - 0 2"
+	more, err := a.WarmCache(ctx, append(fens, afterD4FEN), 12, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if more.Pending != 1 || !more.Estimated || more.EstimatedTime <= 0 {
+		t.Errorf("dry run with a new position = %+v, want 1 pending with an estimate", more)
+	}
+
+	if _, err := a.WarmCache(ctx, []string{startFEN, "not a fen"}, 12, false, nil); !errors.Is(err, ErrInvalidFEN) {
+		t.Errorf("invalid FEN: err = %v, want ErrInvalidFEN", err)
+	}
+}
+
+func TestPGNPositions(t *testing.T) {
+	pgn := `[Event "Round 1"]
+[White "A"]
+
+1. e4 e5 1-0
+
+[Event "Round 2"]
+
+1. d4 d5 2. c4 *
+`
+	fens, err := PGNPositions(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fens) != 7 || fens[0] != startFEN || fens[3] != startFEN {
+		t.Errorf("positions = %v, want both games' from their start", fens)
+	}
+
+	_, err = PGNPositions(pgn + "\n[Event \"Round 3\"]\n\n1. e5 *\n")
+	if !errors.Is(err, ErrInvalidPGN) || !strings.Contains(err.Error(), "game 3") {
+		t.Errorf("err = %v, want ErrInvalidPGN naming game 3", err)
+	}
+}
+
+func TestGetBackground_YieldsToGet(t *testing.T) {
+	p := newFakePool(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	held, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan string, 2)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		eng, err := p.Get(ctx)
+		if err != nil {
+			order <- err.Error()
+			return
+		}
+		order <- "interactive"
+		p.Put(eng)
+	}()
+	for p.Waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		defer wg.Done()
+		eng, err := p.GetBackground(ctx)
+		if err != nil {
+			order <- err.Error()
+			return
+		}
+		order <- "background"
+		p.Put(eng)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	p.Put(held)
+	wg.Wait()
+	if first, second := <-order, <-order; first != "interactive" || second != "background" {
+		t.Errorf("engine went to %s, then %s; want interactive first", first, second)
+	}
+}
@@ -0,0 +1,278 @@
+// Package client is a Go client for the analysis service. It wraps the
+// generated gRPC stubs with keepalive, API key metadata, retries on
+// Unavailable and ResourceExhausted that honor the server's RetryInfo,
+// and helpers that pick between the unary and streaming calls.
+//
+//	c, err := client.New("localhost:50051", client.Options{APIKey: key})
+//	if err != nil { ... }
+//	defer c.Close()
+//	analysis, err := c.AnalyzeGamePGN(ctx, pgn, client.GameOptions{Depth: 18})
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	pb "github.com/eloinsight/analysis-service/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// Defaults of the zero Options
+const (
+	DefaultKeepaliveTime    = 30 * time.Second
+	DefaultKeepaliveTimeout = 10 * time.Second
+	DefaultMaxRetries       = 3
+	DefaultInitialBackoff   = 250 * time.Millisecond
+	DefaultMaxBackoff       = 10 * time.Second
+)
+
+// Options configures a Client. Zero fields take the defaults above.
+type Options struct {
+	// APIKey is sent as x-api-key on every call; UserID as x-user-id.
+	// The server charges engine time quotas to the key, else the user.
+	APIKey string
+	UserID string
+
+	// AdminToken is sent as x-admin-token, which AdminService calls
+	// and record_engine_output need
+	AdminToken string
+
+	// TransportCredentials secure the connection; nil connects in
+	// plaintext
+	TransportCredentials credentials.TransportCredentials
+
+	// Pings on idle connections keep long game streams alive through
+	// proxies. The server refuses pings more often than its
+	// GRPC_KEEPALIVE_MIN_TIME_SECONDS (10s by default).
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+
+	// Calls failing with Unavailable or ResourceExhausted are retried up
+	// to MaxRetries times (negative = never), waiting the server's
+	// RetryInfo delay or else a backoff doubling from InitialBackoff up
+	// to MaxBackoff
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// DialOptions are added after the client's own
+	DialOptions []grpc.DialOption
+}
+
+// withDefaults returns o with its zero fields set to the defaults
+func (o Options) withDefaults() Options {
+	if o.KeepaliveTime == 0 {
+		o.KeepaliveTime = DefaultKeepaliveTime
+	}
+	if o.KeepaliveTimeout == 0 {
+		o.KeepaliveTimeout = DefaultKeepaliveTimeout
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = DefaultMaxRetries
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+	if o.InitialBackoff == 0 {
+		o.InitialBackoff = DefaultInitialBackoff
+	}
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = DefaultMaxBackoff
+	}
+	return o
+}
+
+// Client is a connection to the analysis service. It is safe for
+// concurrent use.
+type Client struct {
+	conn     *grpc.ClientConn
+	analysis pb.AnalysisServiceClient
+	admin    pb.AdminServiceClient
+	retry    retryPolicy
+}
+
+// New connects to the analysis service at target, e.g. "localhost:50051".
+// The connection is made lazily, on the first call.
+func New(target string, opts Options) (*Client, error) {
+	opts = opts.withDefaults()
+	creds := opts.TransportCredentials
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	retry := retryPolicy{maxRetries: opts.MaxRetries, initial: opts.InitialBackoff, max: opts.MaxBackoff}
+
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    opts.KeepaliveTime,
+			Timeout: opts.KeepaliveTimeout,
+		}),
+		grpc.WithChainUnaryInterceptor(identityUnary(opts), retry.unary),
+		grpc.WithChainStreamInterceptor(identityStream(opts)),
+	}, opts.DialOptions...)
+
+	conn, err := grpc.NewClient(target, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", target, err)
+	}
+	return &Client{
+		conn:     conn,
+		analysis: pb.NewAnalysisServiceClient(conn),
+		admin:    pb.NewAdminServiceClient(conn),
+		retry:    retry,
+	}, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Analysis returns the AnalysisService stub, for calls without a helper.
+// Its unary calls are retried and carry the client's identity.
+func (c *Client) Analysis() pb.AnalysisServiceClient {
+	return c.analysis
+}
+
+// Admin returns the AdminService stub
+func (c *Client) Admin() pb.AdminServiceClient {
+	return c.admin
+}
+
+// AnalyzePosition analyzes fen to depth (0 = the server default)
+func (c *Client) AnalyzePosition(ctx context.Context, fen string, depth int) (*pb.PositionAnalysis, error) {
+	return c.analysis.AnalyzePosition(ctx, &pb.AnalyzePositionRequest{Fen: fen, Depth: int32(depth)})
+}
+
+// GameOptions configures AnalyzeGamePGN
+type GameOptions struct {
+	GameID           string
+	Depth            int    // 0 = the server default
+	ThresholdProfile string // Empty = the server default
+	EngineProfile    string // Empty = the primary engine
+
+	// Request, when set, is the request sent, for the fields not above;
+	// the PGN and the fields set above are filled in on a copy
+	Request *pb.AnalyzeGameRequest
+
+	// Progress, when set, streams the analysis and is called with every
+	// progress message, heartbeats included
+	Progress func(*pb.GameAnalysisProgress)
+}
+
+// AnalyzeGamePGN analyzes the game of pgn. Without a Progress callback it
+// makes one AnalyzeGame call; with one it streams AnalyzeGameStream and
+// builds the result from the streamed moves and final metrics, so only
+// the game ID, moves, metrics, total moves and timed out are set. A
+// stream failing before its first message is retried as unary calls are.
+func (c *Client) AnalyzeGamePGN(ctx context.Context, pgn string, opts GameOptions) (*pb.GameAnalysis, error) {
+	req := &pb.AnalyzeGameRequest{}
+	if opts.Request != nil {
+		req = proto.Clone(opts.Request).(*pb.AnalyzeGameRequest)
+	}
+	req.Pgn = pgn
+	if opts.GameID != "" {
+		req.GameId = opts.GameID
+	}
+	if opts.Depth != 0 {
+		req.Depth = int32(opts.Depth)
+	}
+	if opts.ThresholdProfile != "" {
+		req.ThresholdProfile = opts.ThresholdProfile
+	}
+	if opts.EngineProfile != "" {
+		req.EngineProfile = opts.EngineProfile
+	}
+
+	if opts.Progress == nil {
+		return c.analysis.AnalyzeGame(ctx, req)
+	}
+	return c.streamGame(ctx, req, opts.Progress)
+}
+
+// streamGame runs AnalyzeGameStream, retrying while it fails before its
+// first message
+func (c *Client) streamGame(ctx context.Context, req *pb.AnalyzeGameRequest, progress func(*pb.GameAnalysisProgress)) (*pb.GameAnalysis, error) {
+	for attempt := 0; ; attempt++ {
+		stream, err := c.analysis.AnalyzeGameStream(ctx, req)
+		var first *pb.GameAnalysisProgress
+		if err == nil {
+			first, err = stream.Recv()
+		}
+		if err == nil {
+			return collectGame(req.GameId, first, stream, progress)
+		}
+		if !c.retry.wait(ctx, attempt, err) {
+			return nil, err
+		}
+	}
+}
+
+// collectGame reads a game stream whose first message is first to its
+// end, building the analysis from its moves and final metrics
+func collectGame(gameID string, first *pb.GameAnalysisProgress, stream pb.AnalysisService_AnalyzeGameStreamClient, progress func(*pb.GameAnalysisProgress)) (*pb.GameAnalysis, error) {
+	analysis := &pb.GameAnalysis{GameId: gameID}
+	for msg := first; ; {
+		progress(msg)
+		analysis.TotalMoves = msg.TotalMoves
+		// Coalesced messages carry the moves held back before them in
+		// batched_moves; the completed message repeats the last move
+		for _, move := range append(msg.BatchedMoves, msg.MoveAnalysis) {
+			if move == nil {
+				continue
+			}
+			if n := len(analysis.Moves); n == 0 || move.Ply > analysis.Moves[n-1].Ply {
+				analysis.Moves = append(analysis.Moves, move)
+			}
+		}
+		if msg.Status == "completed" {
+			analysis.WhiteMetrics = msg.WhiteMetrics
+			analysis.BlackMetrics = msg.BlackMetrics
+			analysis.TimedOut = msg.TimedOut
+		}
+
+		var err error
+		msg, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return analysis, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// identityUnary and identityStream add the API key, user ID and admin
+// token metadata of opts
+func identityUnary(opts Options) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		return invoker(withIdentity(ctx, opts), method, req, reply, cc, callOpts...)
+	}
+}
+
+func identityStream(opts Options) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(withIdentity(ctx, opts), desc, cc, method, callOpts...)
+	}
+}
+
+func withIdentity(ctx context.Context, opts Options) context.Context {
+	if opts.APIKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", opts.APIKey)
+	}
+	if opts.UserID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-user-id", opts.UserID)
+	}
+	if opts.AdminToken != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-admin-token", opts.AdminToken)
+	}
+	return ctx
+}
@@ -0,0 +1,307 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	grpcserver "github.com/eloinsight/analysis-service/internal/grpc"
+	"github.com/eloinsight/analysis-service/pkg/analyzer"
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"github.com/eloinsight/analysis-service/pkg/pool"
+	pb "github.com/eloinsight/analysis-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestMain(m *testing.M) {
+	enginetest.Main()
+	os.Exit(m.Run())
+}
+
+// fakeServer fails its calls with failures, in order, then answers them
+type fakeServer struct {
+	pb.UnimplementedAnalysisServiceServer
+
+	mu       sync.Mutex
+	failures []error
+	calls    int
+	times    []time.Time
+	md       metadata.MD
+	block    bool // Answers only once the call is canceled
+}
+
+func (s *fakeServer) next(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	s.times = append(s.times, time.Now())
+	s.md, _ = metadata.FromIncomingContext(ctx)
+	if len(s.failures) > 0 {
+		err := s.failures[0]
+		s.failures = s.failures[1:]
+		return err
+	}
+	return nil
+}
+
+func (s *fakeServer) AnalyzePosition(ctx context.Context, req *pb.AnalyzePositionRequest) (*pb.PositionAnalysis, error) {
+	if err := s.next(ctx); err != nil {
+		return nil, err
+	}
+	if s.block {
+		<-ctx.Done()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	return &pb.PositionAnalysis{Fen: req.Fen, Depth: req.Depth}, nil
+}
+
+func (s *fakeServer) AnalyzeGame(ctx context.Context, req *pb.AnalyzeGameRequest) (*pb.GameAnalysis, error) {
+	if err := s.next(ctx); err != nil {
+		return nil, err
+	}
+	return &pb.GameAnalysis{GameId: req.GameId, TotalMoves: 2}, nil
+}
+
+func (s *fakeServer) AnalyzeGameStream(req *pb.AnalyzeGameRequest, stream pb.AnalysisService_AnalyzeGameStreamServer) error {
+	if err := s.next(stream.Context()); err != nil {
+		return err
+	}
+	moves := []*pb.MoveAnalysis{{Ply: 0, PlayedMoveUci: "e2e4"}, {Ply: 1, PlayedMoveUci: "e7e5"}}
+	for i, move := range moves {
+		if err := stream.Send(&pb.GameAnalysisProgress{GameId: req.GameId, Status: "analyzing", CurrentMove: int32(i + 1), TotalMoves: 2, MoveAnalysis: move}); err != nil {
+			return err
+		}
+	}
+	return stream.Send(&pb.GameAnalysisProgress{
+		GameId: req.GameId, Status: "completed", CurrentMove: 2, TotalMoves: 2, MoveAnalysis: moves[1],
+		WhiteMetrics: &pb.GameMetrics{Accuracy: 97}, BlackMetrics: &pb.GameMetrics{Accuracy: 95},
+	})
+}
+
+// newTestClient serves s over bufconn and returns a client of it with
+// short backoffs
+func newTestClient(t *testing.T, s pb.AnalysisServiceServer, opts Options) *Client {
+	t.Helper()
+	server := grpc.NewServer()
+	pb.RegisterAnalysisServiceServer(server, s)
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = time.Millisecond
+	}
+	opts.DialOptions = append(opts.DialOptions, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}))
+	c, err := New("passthrough:///bufnet", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func unavailable(t *testing.T, retryAfter time.Duration) error {
+	t.Helper()
+	st, err := status.New(codes.Unavailable, "engines starting").WithDetails(
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st.Err()
+}
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+func TestAnalyzePosition_RetriesHonoringRetryInfo(t *testing.T) {
+	s := &fakeServer{failures: []error{unavailable(t, 100*time.Millisecond), status.Error(codes.ResourceExhausted, "busy")}}
+	c := newTestClient(t, s, Options{})
+
+	analysis, err := c.AnalyzePosition(context.Background(), startFEN, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.Depth != 12 || s.calls != 3 {
+		t.Fatalf("depth %d after %d calls, want 12 after 3", analysis.Depth, s.calls)
+	}
+	if wait := s.times[1].Sub(s.times[0]); wait < 100*time.Millisecond {
+		t.Errorf("retried after %v, RetryInfo asked for 100ms", wait)
+	}
+}
+
+func TestAnalyzePosition_NotRetried(t *testing.T) {
+	quota, err := status.New(codes.ResourceExhausted, "quota used up").WithDetails(
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{Subject: "user:alice"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		opts Options
+		errs []error
+		want codes.Code
+	}{
+		{"quota used up", Options{}, []error{quota.Err()}, codes.ResourceExhausted},
+		{"invalid argument", Options{}, []error{status.Error(codes.InvalidArgument, "bad FEN")}, codes.InvalidArgument},
+		{"retries disabled", Options{MaxRetries: -1}, []error{unavailable(t, 0)}, codes.Unavailable},
+		{"retries used up", Options{MaxRetries: 2}, []error{unavailable(t, 0), unavailable(t, 0), unavailable(t, 0)}, codes.Unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &fakeServer{failures: tt.errs}
+			c := newTestClient(t, s, tt.opts)
+			_, err := c.AnalyzePosition(context.Background(), startFEN, 12)
+			if status.Code(err) != tt.want || s.calls != len(tt.errs) {
+				t.Errorf("err = %v after %d calls, want %v after %d", err, s.calls, tt.want, len(tt.errs))
+			}
+		})
+	}
+}
+
+func TestClient_SendsIdentity(t *testing.T) {
+	s := &fakeServer{}
+	c := newTestClient(t, s, Options{APIKey: "key-1", UserID: "alice", AdminToken: "admin-1"})
+
+	if _, err := c.AnalyzePosition(context.Background(), startFEN, 12); err != nil {
+		t.Fatal(err)
+	}
+	if key, user := s.md.Get("x-api-key"), s.md.Get("x-user-id"); len(key) != 1 || key[0] != "key-1" || len(user) != 1 || user[0] != "alice" {
+		t.Errorf("unary call sent x-api-key %v, x-user-id %v", key, user)
+	}
+	if token := s.md.Get("x-admin-token"); len(token) != 1 || token[0] != "admin-1" {
+		t.Errorf("unary call sent x-admin-token %v", token)
+	}
+
+	if _, err := c.AnalyzeGamePGN(context.Background(), "1. e4 e5 *", GameOptions{Progress: func(*pb.GameAnalysisProgress) {}}); err != nil {
+		t.Fatal(err)
+	}
+	if key := s.md.Get("x-api-key"); len(key) != 1 || key[0] != "key-1" {
+		t.Errorf("stream sent x-api-key %v", key)
+	}
+}
+
+func TestClient_Cancellation(t *testing.T) {
+	t.Run("during a call", func(t *testing.T) {
+		s := &fakeServer{block: true}
+		c := newTestClient(t, s, Options{})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := c.AnalyzePosition(ctx, startFEN, 12); status.Code(err) != codes.DeadlineExceeded {
+			t.Errorf("err = %v, want DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("during a backoff", func(t *testing.T) {
+		s := &fakeServer{failures: []error{unavailable(t, time.Hour)}}
+		c := newTestClient(t, s, Options{})
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		start := time.Now()
+		_, err := c.AnalyzePosition(ctx, startFEN, 12)
+		if status.Code(err) != codes.Unavailable || time.Since(start) > 5*time.Second {
+			t.Errorf("err = %v after %v, want the Unavailable error once canceled", err, time.Since(start))
+		}
+	})
+}
+
+func TestAnalyzeGamePGN(t *testing.T) {
+	t.Run("unary", func(t *testing.T) {
+		s := &fakeServer{failures: []error{unavailable(t, 0)}}
+		c := newTestClient(t, s, Options{})
+		analysis, err := c.AnalyzeGamePGN(context.Background(), "1. e4 e5 *", GameOptions{GameID: "g1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if analysis.GameId != "g1" || s.calls != 2 {
+			t.Errorf("game %q after %d calls", analysis.GameId, s.calls)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		s := &fakeServer{failures: []error{unavailable(t, 0)}}
+		c := newTestClient(t, s, Options{})
+		var statuses []string
+		analysis, err := c.AnalyzeGamePGN(context.Background(), "1. e4 e5 *", GameOptions{
+			GameID:   "g1",
+			Progress: func(p *pb.GameAnalysisProgress) { statuses = append(statuses, p.Status) },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.calls != 2 || len(statuses) != 3 || statuses[2] != "completed" {
+			t.Errorf("progress %v after %d calls", statuses, s.calls)
+		}
+		if len(analysis.Moves) != 2 || analysis.Moves[1].PlayedMoveUci != "e7e5" || analysis.TotalMoves != 2 {
+			t.Errorf("moves %v of %d", analysis.Moves, analysis.TotalMoves)
+		}
+		if analysis.WhiteMetrics.GetAccuracy() != 97 || analysis.BlackMetrics.GetAccuracy() != 95 {
+			t.Errorf("metrics %v and %v", analysis.WhiteMetrics, analysis.BlackMetrics)
+		}
+	})
+	t.Run("coalesced", func(t *testing.T) {
+		// A server holding back every progress message it can, on a fake
+		// engine finding no mistakes to send at once
+		fake := enginetest.Engine{}
+		p, err := pool.NewPool(1, engine.Config{BinaryPath: fake.Binary(t), Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { p.Close() })
+		s := grpcserver.NewServer(analyzer.NewAnalyzer(p, zap.NewNop(), 1, 20, 30, time.Minute), p, zap.NewNop(), 0)
+		s.SetProgressInterval(time.Hour)
+		c := newTestClient(t, s, Options{})
+
+		var batched, coalesced int
+		analysis, err := c.AnalyzeGamePGN(context.Background(), "1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 *", GameOptions{
+			GameID: "g1",
+			Depth:  10,
+			Progress: func(p *pb.GameAnalysisProgress) {
+				batched += len(p.BatchedMoves)
+				coalesced += int(p.CoalescedUpdates)
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if batched == 0 || coalesced == 0 {
+			t.Fatalf("%d batched moves, %d coalesced updates: the stream wasn't coalesced", batched, coalesced)
+		}
+		if len(analysis.Moves) != 6 || analysis.TotalMoves != 6 {
+			t.Fatalf("%d moves of %d, want all 6", len(analysis.Moves), analysis.TotalMoves)
+		}
+		for i, move := range analysis.Moves {
+			if move.Ply != int32(i) {
+				t.Errorf("move %d is ply %d, want the moves in order", i, move.Ply)
+			}
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	p := retryPolicy{maxRetries: 10, initial: 100 * time.Millisecond, max: time.Second}
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		want *= time.Millisecond
+		if got := p.backoff(attempt); got > want || got < want*4/5 {
+			t.Errorf("backoff(%d) = %v, want within a fifth under %v", attempt, got, want)
+		}
+	}
+}
+
+func TestRetryDelay_NotStatus(t *testing.T) {
+	if _, ok := retryDelay(errors.New("dial failed")); ok {
+		t.Error("plain error retried")
+	}
+}
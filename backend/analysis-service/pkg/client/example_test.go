@@ -0,0 +1,61 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/client"
+	pb "github.com/eloinsight/analysis-service/proto"
+)
+
+func Example() {
+	c, err := client.New("localhost:50051", client.Options{APIKey: "my-key"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	analysis, err := c.AnalyzeGamePGN(ctx, "1. e4 e5 2. Nf3 Nc6 *", client.GameOptions{GameID: "g1", Depth: 18})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("white %.1f%%, black %.1f%%\n", analysis.WhiteMetrics.Accuracy, analysis.BlackMetrics.Accuracy)
+}
+
+func ExampleClient_AnalyzeGamePGN_progress() {
+	c, err := client.New("localhost:50051", client.Options{UserID: "alice"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+
+	analysis, err := c.AnalyzeGamePGN(context.Background(), "1. d4 d5 2. c4 *", client.GameOptions{
+		Progress: func(p *pb.GameAnalysisProgress) {
+			fmt.Printf("%s %d/%d\n", p.Status, p.CurrentMove, p.TotalMoves)
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, move := range analysis.Moves {
+		fmt.Println(move.PlayedMove, move.Classification)
+	}
+}
+
+func ExampleClient_AnalyzePosition() {
+	c, err := client.New("localhost:50051", client.Options{MaxRetries: 5, MaxBackoff: 30 * time.Second})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+
+	position, err := c.AnalyzePosition(context.Background(), "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", 20)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(position.BestMove)
+}
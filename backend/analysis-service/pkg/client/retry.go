@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retryPolicy decides whether and when a failed call is tried again
+type retryPolicy struct {
+	maxRetries int
+	initial    time.Duration
+	max        time.Duration
+}
+
+// unary is the interceptor retrying unary calls
+func (p retryPolicy) unary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	for attempt := 0; ; attempt++ {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil || !p.wait(ctx, attempt, err) {
+			return err
+		}
+	}
+}
+
+// wait sleeps before retry attempt+1 of a call that failed with err and
+// reports whether to make it: not when err isn't retryable, the retries
+// are used up or ctx ends first
+func (p retryPolicy) wait(ctx context.Context, attempt int, err error) bool {
+	if attempt >= p.maxRetries {
+		return false
+	}
+	delay, ok := retryDelay(err)
+	if !ok {
+		return false
+	}
+	if delay == 0 {
+		delay = p.backoff(attempt)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// backoff is the wait before retry attempt+1 without a server hint: the
+// initial backoff doubled per attempt, up to the maximum, less up to a
+// fifth at random so that clients turned away together spread out
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	d := p.initial
+	for i := 0; i < attempt && d < p.max; i++ {
+		d *= 2
+	}
+	d = min(d, p.max)
+	return d - time.Duration(rand.Int63n(int64(d)/5+1))
+}
+
+// retryDelay reports whether err is worth retrying and the delay its
+// RetryInfo asks for, 0 when it has none. Unavailable and
+// ResourceExhausted are, except a used-up quota, which a retry can't fix.
+func retryDelay(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.Unavailable && st.Code() != codes.ResourceExhausted {
+		return 0, false
+	}
+	var delay time.Duration
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.QuotaFailure:
+			return 0, false
+		case *errdetails.RetryInfo:
+			delay = d.GetRetryDelay().AsDuration()
+		}
+	}
+	return delay, true
+}
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FallbackBinary is looked up on PATH when no configured candidate works
+const FallbackBinary = "stockfish"
+
+// ProbeTimeout is how long a candidate binary has to answer uci with uciok
+const ProbeTimeout = 2 * time.Second
+
+// SplitPaths splits a list of candidate binaries separated like PATH,
+// by colons on Unix, dropping empty entries
+func SplitPaths(list string) []string {
+	var paths []string
+	for _, path := range filepath.SplitList(list) {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// ProbeFailure is a candidate binary Discover rejected and why
+type ProbeFailure struct {
+	Path string
+	Err  error
+}
+
+// DiscoveryError lists every candidate Discover tried, in order, with the
+// reason each was rejected
+type DiscoveryError struct {
+	Tried []ProbeFailure
+}
+
+func (e *DiscoveryError) Error() string {
+	tried := make([]string, len(e.Tried))
+	for i, failure := range e.Tried {
+		tried[i] = fmt.Sprintf("%s: %v", failure.Path, failure.Err)
+	}
+	return "no working UCI engine: " + strings.Join(tried, "; ")
+}
+
+// Discover returns the absolute path of the first of candidates, then of
+// FallbackBinary on PATH, that exists, is executable and answers uci
+// within ProbeTimeout. It logs the binary selected and why the ones before
+// it were rejected; when none works, the error is a *DiscoveryError.
+func Discover(candidates []string, logger *zap.Logger) (string, error) {
+	return discover(candidates, ProbeTimeout, logger)
+}
+
+func discover(candidates []string, timeout time.Duration, logger *zap.Logger) (string, error) {
+	var tried []ProbeFailure
+	reject := func(path string, err error) {
+		tried = append(tried, ProbeFailure{Path: path, Err: err})
+		logger.Warn("Engine binary rejected", zap.String("path", path), zap.Error(err))
+	}
+
+	for _, candidate := range candidates {
+		path, err := probeBinary(candidate, timeout)
+		if err != nil {
+			reject(candidate, err)
+			continue
+		}
+		logger.Info("Engine binary selected", zap.String("path", path),
+			zap.String("reason", "configured"), zap.Int("rejected", len(tried)))
+		return path, nil
+	}
+
+	fallback := FallbackBinary + " on PATH"
+	found, err := exec.LookPath(FallbackBinary)
+	if err != nil {
+		reject(fallback, errors.New("not found"))
+		return "", &DiscoveryError{Tried: tried}
+	}
+	path, err := probeBinary(found, timeout)
+	if err != nil {
+		reject(fallback+" ("+found+")", err)
+		return "", &DiscoveryError{Tried: tried}
+	}
+	logger.Info("Engine binary selected", zap.String("path", path),
+		zap.String("reason", "found on PATH"), zap.Int("rejected", len(tried)))
+	return path, nil
+}
+
+// probeBinary checks that path is an executable file that answers uci
+// with uciok within timeout, and returns it made absolute
+func probeBinary(path string, timeout time.Duration) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(abs)
+	switch {
+	case os.IsNotExist(err):
+		return "", errors.New("does not exist")
+	case err != nil:
+		return "", fmt.Errorf("cannot be accessed: %v", err)
+	case info.IsDir():
+		return "", errors.New("is a directory, not a binary")
+	case info.Mode().Perm()&0o111 == 0:
+		return "", errors.New("is not executable")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, abs)
+	cmd.WaitDelay = 100 * time.Millisecond
+	cmd.Stdin = strings.NewReader("uci\nquit\n")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start: %v", err)
+	}
+	defer func() {
+		cancel()
+		cmd.Wait()
+	}()
+
+	// Read on the side: a process that ignores the kill can keep stdout open
+	answered := make(chan bool, 1)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if strings.TrimSpace(scanner.Text()) == "uciok" {
+				answered <- true
+				return
+			}
+		}
+		answered <- false
+	}()
+	select {
+	case ok := <-answered:
+		if ok {
+			return abs, nil
+		}
+		return "", errors.New("exited without answering uci")
+	case <-ctx.Done():
+		return "", fmt.Errorf("did not answer uci within %s", timeout)
+	}
+}
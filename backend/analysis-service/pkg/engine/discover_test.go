@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"go.uber.org/zap"
+)
+
+// writeBinary writes an executable script named name into dir
+func writeBinary(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// fakeEngineScript returns the script of a fake engine, for writeBinary to
+// put where a test needs it
+func fakeEngineScript(t *testing.T, fake enginetest.Engine) string {
+	t.Helper()
+	script, err := os.ReadFile(fake.Binary(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(script)
+}
+
+func TestSplitPaths(t *testing.T) {
+	got := SplitPaths(" /opt/sf/stockfish::/usr/games/stockfish ")
+	want := []string{"/opt/sf/stockfish", "/usr/games/stockfish"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitPaths = %q, want %q", got, want)
+	}
+	if got := SplitPaths(""); got != nil {
+		t.Errorf("SplitPaths(\"\") = %q, want none", got)
+	}
+}
+
+func TestDiscover_FirstWorkingCandidate(t *testing.T) {
+	dir := t.TempDir()
+
+	silent := writeBinary(t, dir, "silent", fakeEngineScript(t, enginetest.Engine{Startup: 10 * time.Second}))
+	crashes := writeBinary(t, dir, "crashes", "#!/bin/sh\nexit 1\n")
+	notExecutable := filepath.Join(dir, "plain")
+	if err := os.WriteFile(notExecutable, []byte(fakeEngineScript(t, enginetest.Engine{})), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	working := writeBinary(t, dir, "fakefish", fakeEngineScript(t, enginetest.Engine{}))
+
+	start := time.Now()
+	candidates := []string{filepath.Join(dir, "missing"), dir, notExecutable, crashes, silent, working}
+	got, err := discover(candidates, 200*time.Millisecond, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != working {
+		t.Errorf("selected %s, want %s", got, working)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("discovery took %s, want the silent binary cut off after 200ms", elapsed)
+	}
+}
+
+func TestDiscover_RelativePathMadeAbsolute(t *testing.T) {
+	dir := t.TempDir()
+	writeBinary(t, dir, "fakefish", fakeEngineScript(t, enginetest.Engine{}))
+	t.Chdir(dir)
+
+	got, err := discover([]string{"./fakefish"}, time.Second, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filepath.IsAbs(got) || filepath.Base(got) != "fakefish" {
+		t.Errorf("selected %s, want the absolute path of ./fakefish", got)
+	}
+}
+
+func TestDiscover_FallsBackToPATH(t *testing.T) {
+	bin := t.TempDir()
+	fallback := writeBinary(t, bin, FallbackBinary, fakeEngineScript(t, enginetest.Engine{}))
+	t.Setenv("PATH", bin)
+
+	got, err := discover([]string{"/nonexistent/stockfish"}, time.Second, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != fallback {
+		t.Errorf("selected %s, want %s from PATH", got, fallback)
+	}
+}
+
+func TestDiscover_ListsEveryFailure(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", t.TempDir())
+	crashes := writeBinary(t, dir, "crashes", "#!/bin/sh\nexit 1\n")
+
+	_, err := discover([]string{"/nonexistent/stockfish", crashes}, time.Second, zap.NewNop())
+	var discoveryErr *DiscoveryError
+	if !errors.As(err, &discoveryErr) {
+		t.Fatalf("error = %v, want a *DiscoveryError", err)
+	}
+	if len(discoveryErr.Tried) != 3 {
+		t.Fatalf("tried %d binaries, want 3: %v", len(discoveryErr.Tried), err)
+	}
+	for _, want := range []string{
+		"/nonexistent/stockfish: does not exist",
+		crashes + ": exited without answering uci",
+		"stockfish on PATH: not found",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,757 @@
+// Package engine runs a UCI engine process, normally Stockfish, and
+// searches positions with it.
+//
+// Evaluation and AnalysisResult are part of the stable API; fields are
+// only ever added. Evaluations are from the side to move's point of view.
+package engine
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/uci"
+	"go.uber.org/zap"
+)
+
+// uciDebug gates the per-line UCI debug logs, which are too voluminous to
+// follow the log level alone
+var uciDebug atomic.Bool
+
+// SetUCIDebug enables or disables logging of every UCI line sent to and
+// received from engines. Lines are logged at debug level.
+func SetUCIDebug(enabled bool) {
+	uciDebug.Store(enabled)
+}
+
+// Search failures, matched with errors.Is
+var (
+	// ErrEngineDied means the engine process stopped taking commands or
+	// ended its output mid-search; the engine can't be used again
+	ErrEngineDied = errors.New("engine process died")
+
+	// ErrInvalidOutput means the engine answered with output that can't be
+	// used; the process itself may still be fine
+	ErrInvalidOutput = errors.New("invalid engine output")
+)
+
+// Engine represents a Stockfish process
+type Engine struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *bufio.Scanner
+	mu      sync.Mutex
+	logger  *zap.Logger
+	config  Config
+	ready   bool
+	version string
+	netName string
+
+	// options are the analysis defaults and overrides set, "name=value"
+	options []string
+
+	// multiPV is the MultiPV option the engine has, so it's only sent
+	// when a search needs another
+	multiPV int
+
+	// game is the StartFEN of the last search's game, empty when it was a
+	// lone FEN or the engine was reset since
+	game string
+
+	// recorder receives the UCI lines of the running search, if its
+	// context has one; guarded by mu
+	recorder Recorder
+}
+
+// Recorder receives every UCI line of a search: commands sent prefixed
+// with "> ", engine output with "< "
+type Recorder func(line string)
+
+type recorderKey struct{}
+
+// WithRecorder returns a copy of ctx whose searches send their UCI lines
+// to rec, regardless of the debug log level
+func WithRecorder(ctx context.Context, rec Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, rec)
+}
+
+// GamePosition is a position of a game: Moves, in UCI notation, played
+// from StartFEN, which reach FEN. Searching positions of the same game one
+// after the other lets the engine keep what it learned between them.
+type GamePosition struct {
+	StartFEN string
+	Moves    []string
+	FEN      string
+}
+
+// Config holds engine configuration
+type Config struct {
+	BinaryPath string
+	Threads    int
+	Hash       int
+	MultiPV    int
+
+	// Options are UCI options by name, set after the analysis defaults
+	// and overriding them
+	Options map[string]string
+}
+
+// analysisOptions are set, when the engine has them, so its evaluations
+// are objective: no pondering, no contempt favoring the side to move and
+// analysis mode
+var analysisOptions = []struct{ name, value string }{
+	{"Ponder", "false"},
+	{"Contempt", "0"},
+	{"UCI_AnalyseMode", "true"},
+}
+
+// Evaluation represents position evaluation
+type Evaluation struct {
+	Centipawns int
+	MateIn     *int
+	IsMate     bool
+	Depth      int
+	SelDepth   int
+	Nodes      int64
+	NPS        int64
+	TimeMs     int64
+	PV         []string
+	MultiPV    int
+
+	// TransposesTo is the MultiPV of a better line this one reaches the
+	// same position as, set by the analyzer's GetBestMoves; 0 for none
+	TransposesTo int
+}
+
+// AnalysisResult holds the complete analysis result
+type AnalysisResult struct {
+	// Evaluations holds the PVs the engine reported, ordered by MultiPV
+	// index. PV 1 is always first; the engine may report fewer PVs than
+	// requested, so PVCount can be below the MultiPV of the search.
+	Evaluations []Evaluation
+	PVCount     int
+	Notes       []Note // Why the result differs from what was asked for
+	BestMove    string
+	PonderMove  string
+	FEN         string
+	Depth       int
+	TimeMs      int64
+	Stopped     bool   // Search was stopped before reaching the requested depth
+	Source      string // SourceEngine, SourceCloud or SourceImported
+
+	// Set by the analyzer, which then makes TimeMs their sum: the wait for
+	// a free engine and the search itself, both wall clock, and the
+	// engine pool's callers waiting and free engines when the search was
+	// dispatched
+	QueueTimeMs   int64
+	SearchTimeMs  int64
+	PoolWaiting   int
+	PoolAvailable int
+
+	// GameOver is GameOverCheckmate or GameOverStalemate when the side to
+	// move has no legal move; there is then no best move and no search
+	GameOver string
+
+	// Stale is set for an answer from a cached search shallower than
+	// requested, given while a search to the requested depth refreshes
+	// the cache; Depth is the cached search's
+	Stale bool
+}
+
+// Why a position has no legal moves
+const (
+	GameOverCheckmate = "checkmate"
+	GameOverStalemate = "stalemate"
+)
+
+// Note explains an analysis result that differs from the search asked for
+type Note string
+
+// NoteFewerLegalMoves means the position has fewer legal moves than the
+// PVs requested, so there are fewer PVs
+const NoteFewerLegalMoves Note = "fewer_legal_moves"
+
+// Where an analysis result came from
+const (
+	SourceEngine   = "engine"   // A local Stockfish
+	SourceCloud    = "cloud"    // The Lichess cloud evaluation API
+	SourceImported = "imported" // An evaluation database loaded into the cache
+	SourceSeed     = "seed"     // The seed evaluations built into the analyzer
+)
+
+// NewEngine creates and initializes a new Stockfish engine
+func NewEngine(config Config, logger *zap.Logger) (*Engine, error) {
+	cmd := exec.Command(config.BinaryPath)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		stdin.Close()
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		stdin.Close()
+		return nil, fmt.Errorf("failed to start stockfish: %w", err)
+	}
+
+	engine := &Engine{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewScanner(stdout),
+		logger: logger,
+		config: config,
+	}
+
+	if err := engine.initialize(); err != nil {
+		engine.Close()
+		return nil, fmt.Errorf("failed to initialize engine: %w", err)
+	}
+
+	return engine, nil
+}
+
+// initialize sets up the UCI protocol and options
+func (e *Engine) initialize() error {
+	// Send UCI command
+	if err := e.sendCommand("uci"); err != nil {
+		return err
+	}
+
+	// Wait for uciok
+	advertised := make(map[string]bool) // Option names, lowercased
+	for e.stdout.Scan() {
+		line := e.stdout.Text()
+
+		if strings.HasPrefix(line, "id name") {
+			e.version = strings.TrimPrefix(line, "id name ")
+		}
+		if name, ok := optionName(line); ok {
+			advertised[strings.ToLower(name)] = true
+		}
+
+		// The default EvalFile option names the embedded NNUE network
+		if strings.HasPrefix(line, "option name EvalFile ") {
+			if idx := strings.Index(line, " default "); idx >= 0 {
+				e.netName = strings.TrimSpace(line[idx+len(" default "):])
+			}
+		}
+
+		if line == "uciok" {
+			break
+		}
+	}
+
+	if e.stdout.Err() != nil {
+		return e.stdout.Err()
+	}
+
+	// Set options
+	if err := e.sendCommand(fmt.Sprintf("setoption name Threads value %d", e.config.Threads)); err != nil {
+		return err
+	}
+	if err := e.sendCommand(fmt.Sprintf("setoption name Hash value %d", e.config.Hash)); err != nil {
+		return err
+	}
+	e.multiPV = 1
+	if e.config.MultiPV > 1 {
+		if err := e.SetMultiPV(e.config.MultiPV); err != nil {
+			return err
+		}
+	}
+	if err := e.setAnalysisOptions(advertised); err != nil {
+		return err
+	}
+
+	// Check if ready
+	if err := e.sendCommand("isready"); err != nil {
+		return err
+	}
+
+	for e.stdout.Scan() {
+		if e.stdout.Text() == "readyok" {
+			break
+		}
+	}
+
+	e.ready = true
+	e.logger.Info("Stockfish initialized",
+		zap.String("version", e.version),
+		zap.String("nnueNet", e.netName),
+		zap.Strings("options", e.options))
+	return nil
+}
+
+// optionName returns the name of the option an "option name ... type ..."
+// line of the uci answer declares
+func optionName(line string) (string, bool) {
+	rest, ok := strings.CutPrefix(line, "option name ")
+	if !ok {
+		return "", false
+	}
+	name, _, _ := strings.Cut(rest, " type ")
+	return strings.TrimSpace(name), name != ""
+}
+
+// setAnalysisOptions sets the analysis options the engine advertised,
+// with the values of Config.Options where it has them, then the rest of
+// Config.Options in name order
+func (e *Engine) setAnalysisOptions(advertised map[string]bool) error {
+	overrides := make(map[string]string, len(e.config.Options))
+	names := make([]string, 0, len(e.config.Options))
+	for name, value := range e.config.Options {
+		overrides[strings.ToLower(name)] = value
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	set := func(name, value string) error {
+		if err := e.sendCommand(fmt.Sprintf("setoption name %s value %s", name, value)); err != nil {
+			return err
+		}
+		e.options = append(e.options, name+"="+value)
+		return nil
+	}
+	for _, option := range analysisOptions {
+		key := strings.ToLower(option.name)
+		value, overridden := overrides[key]
+		if !overridden {
+			if !advertised[key] {
+				continue
+			}
+			value = option.value
+		}
+		if err := set(option.name, value); err != nil {
+			return err
+		}
+		delete(overrides, key)
+	}
+	for _, name := range names {
+		if value, ok := overrides[strings.ToLower(name)]; ok {
+			if err := set(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sendCommand sends a command to the engine
+func (e *Engine) sendCommand(cmd string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, err := e.stdin.Write([]byte(cmd + "\n"))
+	if err != nil {
+		e.ready = false
+		return fmt.Errorf("%w: failed to send command '%s': %v", ErrEngineDied, cmd, err)
+	}
+	if e.recorder != nil {
+		e.recorder("> " + cmd)
+	}
+
+	if uciDebug.Load() {
+		e.logger.Debug("Sent command", zap.String("cmd", cmd))
+	}
+	return nil
+}
+
+// SetMultiPV changes the number of principal variations
+func (e *Engine) SetMultiPV(count int) error {
+	if count < 1 || count > 10 {
+		return errors.New("MultiPV must be between 1 and 10")
+	}
+	if err := e.sendCommand(fmt.Sprintf("setoption name MultiPV value %d", count)); err != nil {
+		return err
+	}
+	e.multiPV = count
+	return nil
+}
+
+// AnalyzePosition analyzes a FEN position to a given depth
+func (e *Engine) AnalyzePosition(fen string, depth int, multiPV int) (*AnalysisResult, error) {
+	return e.AnalyzePositionContext(context.Background(), fen, depth, multiPV)
+}
+
+// AnalyzePositionContext analyzes a FEN position to a given depth. If ctx
+// ends first the search is stopped and the best line found so far is
+// returned with Stopped set.
+func (e *Engine) AnalyzePositionContext(ctx context.Context, fen string, depth int, multiPV int) (*AnalysisResult, error) {
+	return e.searchDepth(ctx, GamePosition{FEN: fen}, depth, multiPV)
+}
+
+// AnalyzeGamePositionContext is AnalyzePositionContext for a position of a
+// game, sent as the game's moves from its start. The engine keeps its
+// hash between positions of the same game.
+func (e *Engine) AnalyzeGamePositionContext(ctx context.Context, pos GamePosition, depth int, multiPV int) (*AnalysisResult, error) {
+	return e.searchDepth(ctx, pos, depth, multiPV)
+}
+
+// searchDepth searches pos to depth
+func (e *Engine) searchDepth(ctx context.Context, pos GamePosition, depth int, multiPV int) (*AnalysisResult, error) {
+	result, stopped, err := e.search(ctx, pos, fmt.Sprintf("go depth %d", depth), multiPV)
+	if err != nil {
+		return nil, err
+	}
+	result.Stopped = stopped && result.Depth < depth
+	return result, nil
+}
+
+// search sets up pos and runs goCmd, reporting whether ctx stopped it
+func (e *Engine) search(ctx context.Context, pos GamePosition, goCmd string, multiPV int) (*AnalysisResult, bool, error) {
+	if !e.ready {
+		return nil, false, fmt.Errorf("%w: engine not ready", ErrEngineDied)
+	}
+
+	if rec, ok := ctx.Value(recorderKey{}).(Recorder); ok {
+		e.setRecorder(rec)
+		defer e.setRecorder(nil)
+	}
+
+	if multiPV > 0 && multiPV != e.multiPV {
+		if err := e.SetMultiPV(multiPV); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := e.setPosition(pos); err != nil {
+		return nil, false, err
+	}
+
+	// Start analysis
+	if err := e.sendCommand(goCmd); err != nil {
+		return nil, false, err
+	}
+
+	finish := e.stopOnDone(ctx)
+	result, err := e.readAnalysisResult(pos.FEN, multiPV)
+	stopped := finish()
+	if err != nil {
+		return nil, false, err
+	}
+	return result, stopped, nil
+}
+
+// setRecorder sets the recorder of the running search, nil after it
+func (e *Engine) setRecorder(rec Recorder) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recorder = rec
+}
+
+// received passes a line read from the engine to the search's recorder.
+// Lines are only read by the searching goroutine, which also sets the
+// recorder, so it needs no lock.
+func (e *Engine) received(line string) {
+	if e.recorder != nil {
+		e.recorder("< " + line)
+	}
+}
+
+// setPosition sends pos, as a FEN or as moves from its game's start. A
+// game from another start than the last search's starts a new game: its
+// hash entries would be of no use.
+func (e *Engine) setPosition(pos GamePosition) error {
+	if pos.StartFEN == "" {
+		e.game = ""
+		return e.sendCommand(fmt.Sprintf("position fen %s", pos.FEN))
+	}
+
+	if e.game != "" && e.game != pos.StartFEN {
+		if err := e.Reset(); err != nil {
+			return err
+		}
+	}
+	e.game = pos.StartFEN
+	if len(pos.Moves) == 0 {
+		return e.sendCommand(fmt.Sprintf("position fen %s", pos.StartFEN))
+	}
+	return e.sendCommand(fmt.Sprintf("position fen %s moves %s", pos.StartFEN, strings.Join(pos.Moves, " ")))
+}
+
+// stopGrace is how long a search may take to end after "stop" before the
+// engine is taken for hung and killed
+const stopGrace = time.Second
+
+// stopOnDone sends "stop" if ctx ends while a search is running, and kills
+// the engine if the search still runs stopGrace later, so its output ends
+// with ErrEngineDied. The returned func must be called once the search
+// finished; it reports whether "stop" was sent and guarantees none is sent
+// afterwards, where it would cut the next search short.
+func (e *Engine) stopOnDone(ctx context.Context) func() bool {
+	if ctx.Done() == nil {
+		return func() bool { return false }
+	}
+
+	done := make(chan struct{})
+	exited := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			e.Stop()
+			select {
+			case <-done:
+			case <-time.After(stopGrace):
+				e.logger.Warn("Engine ignored stop, killing it", zap.Duration("grace", stopGrace))
+				e.cmd.Process.Kill()
+			}
+			exited <- true
+		case <-done:
+			exited <- false
+		}
+	}()
+
+	return func() bool {
+		close(done)
+		return <-exited
+	}
+}
+
+// AnalyzePositionWithTime analyzes with a time limit
+func (e *Engine) AnalyzePositionWithTime(fen string, timeMs int, multiPV int) (*AnalysisResult, error) {
+	return e.AnalyzePositionWithTimeContext(context.Background(), fen, timeMs, multiPV)
+}
+
+// AnalyzePositionWithTimeContext analyzes for timeMs milliseconds. If ctx
+// ends first the search is stopped early and returned with Stopped set.
+func (e *Engine) AnalyzePositionWithTimeContext(ctx context.Context, fen string, timeMs int, multiPV int) (*AnalysisResult, error) {
+	return e.AnalyzeGamePositionWithTimeContext(ctx, GamePosition{FEN: fen}, timeMs, multiPV)
+}
+
+// AnalyzeGamePositionWithTimeContext is AnalyzePositionWithTimeContext for
+// a position of a game, as AnalyzeGamePositionContext sends it
+func (e *Engine) AnalyzeGamePositionWithTimeContext(ctx context.Context, pos GamePosition, timeMs int, multiPV int) (*AnalysisResult, error) {
+	result, stopped, err := e.search(ctx, pos, fmt.Sprintf("go movetime %d", timeMs), multiPV)
+	if err != nil {
+		return nil, err
+	}
+	result.Stopped = stopped
+	return result, nil
+}
+
+// readAnalysisResult reads and parses the engine output
+func (e *Engine) readAnalysisResult(fen string, multiPV int) (*AnalysisResult, error) {
+	result := &AnalysisResult{
+		FEN:         fen,
+		Evaluations: make([]Evaluation, 0),
+	}
+
+	evalMap := make(map[int]*Evaluation) // Track evaluations by MultiPV number
+	finished := false
+
+	for e.stdout.Scan() {
+		line := e.stdout.Text()
+		if uciDebug.Load() {
+			e.logger.Debug("Engine output", zap.String("line", line))
+		}
+		e.received(line)
+
+		if uci.IsInfo(line) {
+			eval := evaluationFromInfo(uci.ParseInfo(line))
+			pvNum := eval.MultiPV
+			if pvNum == 0 {
+				pvNum = 1
+			}
+			evalMap[pvNum] = &eval
+		}
+
+		if best, ponder, ok := uci.ParseBestMove(line); ok {
+			result.BestMove = best
+			result.PonderMove = ponder
+			finished = true
+			break
+		}
+	}
+
+	if err := e.stdout.Err(); err != nil {
+		e.markDead()
+		return nil, fmt.Errorf("%w: %v", ErrEngineDied, err)
+	}
+	if !finished {
+		e.markDead()
+		return nil, fmt.Errorf("%w: output ended before bestmove", ErrEngineDied)
+	}
+
+	// Convert map to slice, ordered by MultiPV number. Callers take the
+	// first evaluation as the best line, so PVs without PV 1 are unusable.
+	maxPV := multiPV
+	if maxPV == 0 {
+		maxPV = 1
+	}
+	for i := 1; i <= maxPV; i++ {
+		if eval, ok := evalMap[i]; ok {
+			result.Evaluations = append(result.Evaluations, *eval)
+		}
+	}
+	if len(result.Evaluations) == 0 {
+		return nil, fmt.Errorf("%w: no evaluation before bestmove", ErrInvalidOutput)
+	}
+	if _, ok := evalMap[1]; !ok {
+		return nil, fmt.Errorf("%w: engine reported %d PVs but not PV 1", ErrInvalidOutput, len(result.Evaluations))
+	}
+	result.Depth = result.Evaluations[0].Depth
+	result.TimeMs = result.Evaluations[0].TimeMs
+	result.PVCount = len(result.Evaluations)
+
+	return result, nil
+}
+
+// evaluationFromInfo converts a parsed info line to an Evaluation
+func evaluationFromInfo(info uci.Info) Evaluation {
+	return Evaluation{
+		Centipawns: info.Centipawns,
+		MateIn:     info.MateIn,
+		IsMate:     info.IsMate,
+		Depth:      info.Depth,
+		SelDepth:   info.SelDepth,
+		Nodes:      info.Nodes,
+		NPS:        info.NPS,
+		TimeMs:     info.TimeMs,
+		PV:         info.PV,
+		MultiPV:    info.MultiPV,
+	}
+}
+
+// Reset prepares the engine for a new game
+func (e *Engine) Reset() error {
+	e.game = ""
+	if err := e.sendCommand("ucinewgame"); err != nil {
+		return err
+	}
+	if err := e.sendCommand("isready"); err != nil {
+		return err
+	}
+
+	for e.stdout.Scan() {
+		e.received(e.stdout.Text())
+		if e.stdout.Text() == "readyok" {
+			break
+		}
+	}
+
+	return e.stdout.Err()
+}
+
+// Stop stops the current analysis
+func (e *Engine) Stop() error {
+	return e.sendCommand("stop")
+}
+
+// Close shuts down the engine
+func (e *Engine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.ready = false
+
+	if e.stdin != nil {
+		e.stdin.Write([]byte("quit\n"))
+		e.stdin.Close()
+	}
+
+	if e.cmd != nil && e.cmd.Process != nil {
+		// Give it time to quit gracefully
+		done := make(chan error, 1)
+		go func() {
+			done <- e.cmd.Wait()
+		}()
+
+		select {
+		case <-done:
+			// Process exited
+		case <-time.After(2 * time.Second):
+			// Force kill if it doesn't exit
+			e.cmd.Process.Kill()
+		}
+	}
+
+	e.logger.Info("Engine closed")
+	return nil
+}
+
+// markDead keeps a dead engine from being searched with again
+func (e *Engine) markDead() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ready = false
+}
+
+// IsReady returns whether the engine is ready
+func (e *Engine) IsReady() bool {
+	return e.ready
+}
+
+// Version returns the Stockfish version string, followed by the analysis
+// options set, as in "Stockfish 16.1 (Ponder=false, UCI_AnalyseMode=true)"
+func (e *Engine) Version() string {
+	if len(e.options) == 0 {
+		return e.version
+	}
+	return fmt.Sprintf("%s (%s)", e.version, strings.Join(e.options, ", "))
+}
+
+// Options returns the analysis options set on the engine, "name=value"
+// in the order they were sent
+func (e *Engine) Options() []string {
+	return e.options
+}
+
+// NetName returns the NNUE network file the engine evaluates with
+func (e *Engine) NetName() string {
+	return e.netName
+}
+
+// ValidateFEN checks if a FEN string is valid
+func ValidateFEN(fen string) error {
+	parts := strings.Fields(fen)
+	if len(parts) < 4 {
+		return errors.New("invalid FEN: too few parts")
+	}
+
+	// Validate piece placement
+	ranks := strings.Split(parts[0], "/")
+	if len(ranks) != 8 {
+		return errors.New("invalid FEN: must have 8 ranks")
+	}
+
+	// Validate each rank
+	pieceRegex := regexp.MustCompile(`^[kqrbnpKQRBNP1-8]+$`)
+	for _, rank := range ranks {
+		if !pieceRegex.MatchString(rank) {
+			return fmt.Errorf("invalid FEN: invalid characters in rank '%s'", rank)
+		}
+
+		// Count squares in rank
+		count := 0
+		for _, c := range rank {
+			if c >= '1' && c <= '8' {
+				count += int(c - '0')
+			} else {
+				count++
+			}
+		}
+		if count != 8 {
+			return fmt.Errorf("invalid FEN: rank '%s' does not have 8 squares", rank)
+		}
+	}
+
+	// Validate side to move
+	if parts[1] != "w" && parts[1] != "b" {
+		return errors.New("invalid FEN: side to move must be 'w' or 'b'")
+	}
+
+	return nil
+}
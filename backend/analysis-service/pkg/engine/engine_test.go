@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	enginetest.Main()
+	os.Exit(m.Run())
+}
+
+// startOptionsEngine starts a fake engine advertising options and returns
+// it with the setoption commands it was sent
+func startOptionsEngine(t *testing.T, options string, overrides map[string]string) (*Engine, []string) {
+	t.Helper()
+	log := filepath.Join(t.TempDir(), "uci")
+	fake := enginetest.Engine{Name: "FakeFish 16", Options: strings.Fields(options), Log: log}
+
+	e, err := NewEngine(Config{BinaryPath: fake.Binary(t), Threads: 1, Hash: 16, MultiPV: 1, Options: overrides}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { e.Close() })
+
+	var setoptions []string
+	for _, commands := range enginetest.Commands(t, log) {
+		for _, command := range commands {
+			if strings.HasPrefix(command, "setoption ") {
+				setoptions = append(setoptions, command)
+			}
+		}
+	}
+	return e, setoptions
+}
+
+func TestNewEngine_AnalysisOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   string // Advertised, space-separated
+		overrides map[string]string
+		want      []string // setoption commands
+		version   string
+	}{
+		{
+			name:    "all advertised",
+			options: "Threads Hash Ponder Contempt UCI_AnalyseMode",
+			want: []string{
+				"setoption name Threads value 1",
+				"setoption name Hash value 16",
+				"setoption name Ponder value false",
+				"setoption name Contempt value 0",
+				"setoption name UCI_AnalyseMode value true",
+			},
+			version: "FakeFish 16 (Ponder=false, Contempt=0, UCI_AnalyseMode=true)",
+		},
+		{
+			name:    "no contempt",
+			options: "Threads Hash Ponder UCI_AnalyseMode",
+			want: []string{
+				"setoption name Threads value 1",
+				"setoption name Hash value 16",
+				"setoption name Ponder value false",
+				"setoption name UCI_AnalyseMode value true",
+			},
+			version: "FakeFish 16 (Ponder=false, UCI_AnalyseMode=true)",
+		},
+		{
+			name:    "none advertised",
+			options: "Threads Hash",
+			want: []string{
+				"setoption name Threads value 1",
+				"setoption name Hash value 16",
+			},
+			version: "FakeFish 16",
+		},
+		{
+			name:      "overridden",
+			options:   "Threads Hash Ponder Contempt UCI_AnalyseMode Move_Overhead",
+			overrides: map[string]string{"contempt": "20", "Move_Overhead": "50", "Analysis_Contempt": "Both"},
+			want: []string{
+				"setoption name Threads value 1",
+				"setoption name Hash value 16",
+				"setoption name Ponder value false",
+				"setoption name Contempt value 20",
+				"setoption name UCI_AnalyseMode value true",
+				"setoption name Analysis_Contempt value Both",
+				"setoption name Move_Overhead value 50",
+			},
+			version: "FakeFish 16 (Ponder=false, Contempt=20, UCI_AnalyseMode=true, Analysis_Contempt=Both, Move_Overhead=50)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, setoptions := startOptionsEngine(t, tt.options, tt.overrides)
+			if !reflect.DeepEqual(setoptions, tt.want) {
+				t.Errorf("setoption sequence:\n%s\nwant:\n%s", strings.Join(setoptions, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if got := e.Version(); got != tt.version {
+				t.Errorf("Version() = %q, want %q", got, tt.version)
+			}
+		})
+	}
+}
+
+func TestOptionName(t *testing.T) {
+	for line, want := range map[string]string{
+		"option name Skill Level type spin default 20 min 0 max 20": "Skill Level",
+		"option name UCI_AnalyseMode type check default false":      "UCI_AnalyseMode",
+		"id name Stockfish 16": "",
+	} {
+		if got, _ := optionName(line); got != want {
+			t.Errorf("optionName(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestAnalyzePositionContext_KillsHungEngine(t *testing.T) {
+	const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	fake := enginetest.Engine{Failures: []enginetest.Failure{{Mode: enginetest.Hang, Search: 2}}}
+	e, err := NewEngine(Config{BinaryPath: fake.Binary(t), Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { e.Close() })
+
+	if _, err := e.AnalyzePosition(startFEN, 12, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second search ignores "stop": stopGrace after the deadline the
+	// engine is killed rather than the caller left waiting
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = e.AnalyzePositionContext(ctx, startFEN, 12, 1)
+	if elapsed := time.Since(start); elapsed > stopGrace+time.Second {
+		t.Errorf("hung search returned after %v", elapsed)
+	}
+	if !errors.Is(err, ErrEngineDied) || e.IsReady() {
+		t.Errorf("hung search: err %v, ready %v; want the engine dead", err, e.IsReady())
+	}
+}
+
+func TestAnalyzePositionContext_StopsInTime(t *testing.T) {
+	const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	fake := enginetest.Engine{Latency: time.Minute}
+	e, err := NewEngine(Config{BinaryPath: fake.Binary(t), Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { e.Close() })
+
+	// An engine that answers "stop" is left alone and searches again
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		result, err := e.AnalyzePositionContext(ctx, startFEN, 12, 1)
+		cancel()
+		if err != nil || !result.Stopped || !e.IsReady() {
+			t.Fatalf("search %d: %+v, %v, ready %v", i, result, err, e.IsReady())
+		}
+	}
+}
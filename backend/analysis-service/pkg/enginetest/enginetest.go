@@ -0,0 +1,209 @@
+// Package enginetest runs fake UCI engines for tests, in place of
+// Stockfish: with canned evaluations by position, deterministic best
+// moves, a set search time and failures on demand.
+//
+// A fake engine is the test binary itself, started again by the script
+// Binary writes, so the test binary's TestMain must call Main first:
+//
+//	func TestMain(m *testing.M) {
+//		enginetest.Main()
+//		os.Exit(m.Run())
+//	}
+package enginetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// configEnv names the file of the Engine a fake engine process runs
+const configEnv = "ENGINETEST_CONFIG"
+
+// Engine is a fake UCI engine. The zero value answers every search at
+// once, scoring every position 0 with the first legal move in UCI order.
+type Engine struct {
+	Name string // Its "id name", "FakeFish" if empty
+
+	// Options are the names of the options "uci" advertises, Threads,
+	// Hash and MultiPV if nil
+	Options []string
+
+	// Log is where every process of the engine appends the commands it
+	// reads, to Log.<pid>, for Commands; nowhere if empty
+	Log string
+
+	// Startup is how long it takes to answer "uci", as Stockfish does
+	// while it loads its network
+	Startup time.Duration
+
+	// Latency is how long every search takes, whatever its limit; "stop"
+	// ends it early, at depth 1
+	Latency time.Duration
+
+	// DepthLatency is added to Latency for each depth squared a search
+	// reaches, so deeper searches cost disproportionately more
+	DepthLatency time.Duration
+
+	// ReportedTime is the time every search reports instead of the time
+	// it took, so timings are the same across runs
+	ReportedTime time.Duration
+
+	// Depth is the depth "go movetime" searches reach, 20 if 0; "go depth"
+	// searches reach theirs
+	Depth int
+
+	// Evals are canned evaluations by FEN, compared on the board, side to
+	// move and castling rights, whether the position was sent as a FEN or
+	// as moves from another. Other positions get Default.
+	Evals   map[string]Eval
+	Default Eval
+
+	// Spread varies the scores of positions without a canned evaluation:
+	// each scores Default's plus a multiple of Spread/8 up to Spread,
+	// picked by the position, so evaluations differ between moves yet
+	// stay the same across runs
+	Spread int
+
+	// ReverseLines reports MultiPV lines last first, as engines may while
+	// lines change order
+	ReverseLines bool
+
+	// Failures make searches fail; the first that applies to a search does
+	Failures []Failure
+}
+
+// Eval is a canned evaluation, from the side to move's point of view
+type Eval struct {
+	Centipawns int
+	MateIn     int      // Moves to mate, negative when getting mated; 0 for a score
+	BestMove   string   // UCI; the first legal move in UCI order if empty
+	PV         []string // Moves after the best move
+
+	// Lines are the MultiPV lines after the first, in order; without them
+	// the other legal moves in UCI order score 10cp less each line
+	Lines []Line
+}
+
+// Line is a MultiPV line of an Eval
+type Line struct {
+	Depth      int // The search's depth if 0
+	Centipawns int
+	PV         []string
+}
+
+// FailureMode is how a fake engine fails a search
+type FailureMode string
+
+const (
+	// Hang stops answering anything, "stop" and "quit" included, until
+	// the engine is killed or its input closed
+	Hang FailureMode = "hang"
+
+	// Crash reports a depth, then exits with status 1
+	Crash FailureMode = "crash"
+
+	// Garbage answers with lines that aren't UCI and a best move without
+	// an evaluation
+	Garbage FailureMode = "garbage"
+
+	// Stall searches until "stop", whatever the limit, then answers at
+	// depth 1 as a stopped search with Latency does
+	Stall FailureMode = "stall"
+)
+
+// Failure makes searches fail
+type Failure struct {
+	Mode FailureMode
+
+	// FEN is the position whose searches fail, compared as Evals are;
+	// every position if empty
+	FEN string
+
+	// Search is the search of each engine process that fails, from 1;
+	// every search if 0
+	Search int
+
+	// Times is how many searches fail, counted across every process of
+	// the engine; no limit if 0
+	Times int
+}
+
+// Binary writes the executable of the fake engine to a temporary
+// directory of t and returns its path, for engine.Config's BinaryPath.
+// Every process started from it is a new engine, sharing only the count
+// of Failures' Times.
+func (e Engine) Binary(t testing.TB) string {
+	t.Helper()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "engine.json")
+	if err := os.WriteFile(configPath, config, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Under the race detector a process waits a second before it exits,
+	// which every engine closed would
+	binary := filepath.Join(dir, "fakefish")
+	script := fmt.Sprintf("#!/bin/sh\nGORACE=\"${GORACE:+$GORACE }atexit_sleep_ms=0\" %s=%s exec %s\n",
+		configEnv, shellQuote(configPath), shellQuote(exe))
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return binary
+}
+
+// Main runs the fake engine and exits when the test binary was started as
+// one by Binary's executable, and returns at once otherwise
+func Main() {
+	configPath := os.Getenv(configEnv)
+	if configPath == "" {
+		return
+	}
+
+	var e Engine
+	config, err := os.ReadFile(configPath)
+	if err == nil {
+		err = json.Unmarshal(config, &e)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "enginetest: %v\n", err)
+		os.Exit(2)
+	}
+	os.Exit(newFakeEngine(e, filepath.Dir(configPath), os.Stdout).run(os.Stdin))
+}
+
+// Commands returns the commands each process of an engine logged to
+// log, by process, in the order they were read
+func Commands(t testing.TB, log string) [][]string {
+	t.Helper()
+	files, err := filepath.Glob(log + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var processes [][]string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		processes = append(processes, strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"))
+	}
+	return processes
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
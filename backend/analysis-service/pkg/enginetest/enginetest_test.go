@@ -0,0 +1,239 @@
+package enginetest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	enginetest.Main()
+	os.Exit(m.Run())
+}
+
+const (
+	startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	e4FEN    = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+)
+
+func startEngine(t *testing.T, fake enginetest.Engine, multiPV int) *engine.Engine {
+	t.Helper()
+	eng, err := engine.NewEngine(engine.Config{BinaryPath: fake.Binary(t), Threads: 1, Hash: 16, MultiPV: multiPV}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { eng.Close() })
+	return eng
+}
+
+func TestEngine_CannedEvaluations(t *testing.T) {
+	eng := startEngine(t, enginetest.Engine{
+		Name: "CannedFish",
+		Evals: map[string]enginetest.Eval{
+			e4FEN: {Centipawns: -35, BestMove: "c7c5", PV: []string{"g1f3", "d7d6"}},
+		},
+		Default: enginetest.Eval{Centipawns: 15},
+	}, 1)
+	if eng.Version() != "CannedFish" {
+		t.Errorf("version = %q", eng.Version())
+	}
+
+	// The position after 1. e4, whether sent as a FEN, with other clocks,
+	// or as moves
+	for _, pos := range []engine.GamePosition{
+		{FEN: e4FEN},
+		{FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 3 9"},
+		{StartFEN: startFEN, Moves: []string{"e2e4"}, FEN: e4FEN},
+	} {
+		result, err := eng.AnalyzeGamePositionContext(context.Background(), pos, 14, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		eval := result.Evaluations[0]
+		if result.BestMove != "c7c5" || result.PonderMove != "g1f3" || eval.Centipawns != -35 || eval.Depth != 14 || len(eval.PV) != 3 {
+			t.Errorf("%+v: best %s ponder %s, eval %+v", pos, result.BestMove, result.PonderMove, eval)
+		}
+	}
+
+	// Other positions get the default and their first legal move
+	result, err := eng.AnalyzePosition(startFEN, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BestMove != "a2a3" || result.Evaluations[0].Centipawns != 15 {
+		t.Errorf("start: best %s, eval %+v", result.BestMove, result.Evaluations[0])
+	}
+}
+
+func TestEngine_MatesAndMultiPV(t *testing.T) {
+	const foolsMate = "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"
+	const backRank = "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1"
+	eng := startEngine(t, enginetest.Engine{Evals: map[string]enginetest.Eval{
+		backRank: {MateIn: 1, BestMove: "a1a8"},
+	}}, 1)
+
+	result, err := eng.AnalyzePosition(backRank, 12, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PVCount != 3 || result.BestMove != "a1a8" || !result.Evaluations[0].IsMate || *result.Evaluations[0].MateIn != 1 {
+		t.Fatalf("back rank mate: %+v", result)
+	}
+	if second := result.Evaluations[1]; second.IsMate || second.Centipawns != -10 || second.PV[0] == "a1a8" {
+		t.Errorf("second PV = %+v, want another move 10cp worse", second)
+	}
+
+	// A mated side has no move, as Stockfish answers
+	result, err = eng.AnalyzePosition(foolsMate, 12, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BestMove != "" || !result.Evaluations[0].IsMate || *result.Evaluations[0].MateIn != 0 {
+		t.Errorf("mated: %+v", result)
+	}
+}
+
+func TestEngine_LatencyAndStop(t *testing.T) {
+	eng := startEngine(t, enginetest.Engine{Latency: 100 * time.Millisecond, Depth: 18}, 1)
+
+	start := time.Now()
+	result, err := eng.AnalyzePositionWithTime(startFEN, 5000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || result.Depth != 18 {
+		t.Errorf("search took %v to depth %d, want 100ms to depth 18", elapsed, result.Depth)
+	}
+
+	// Stopped early it reports depth 1
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	result, err = eng.AnalyzePositionContext(ctx, startFEN, 18, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Stopped || result.Depth != 1 {
+		t.Errorf("stopped search: stopped %v at depth %d", result.Stopped, result.Depth)
+	}
+}
+
+func TestEngine_Failures(t *testing.T) {
+	// The first process to search the position after 1. e4 crashes; every
+	// process garbles its second search
+	fake := enginetest.Engine{Failures: []enginetest.Failure{
+		{Mode: enginetest.Crash, FEN: e4FEN, Times: 1},
+		{Mode: enginetest.Garbage, Search: 2},
+	}}
+	binary := fake.Binary(t)
+	start := func() *engine.Engine {
+		eng, err := engine.NewEngine(engine.Config{BinaryPath: binary, Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { eng.Close() })
+		return eng
+	}
+
+	first := start()
+	if _, err := first.AnalyzePosition(e4FEN, 12, 1); !errors.Is(err, engine.ErrEngineDied) || first.IsReady() {
+		t.Fatalf("crash: err %v, ready %v", err, first.IsReady())
+	}
+
+	second := start()
+	if _, err := second.AnalyzePosition(e4FEN, 12, 1); err != nil {
+		t.Fatalf("the crash happened again: %v", err)
+	}
+	if _, err := second.AnalyzePosition(startFEN, 12, 1); !errors.Is(err, engine.ErrInvalidOutput) || !second.IsReady() {
+		t.Fatalf("garbage: err %v, ready %v", err, second.IsReady())
+	}
+	if _, err := second.AnalyzePosition(startFEN, 12, 1); err != nil {
+		t.Errorf("third search: %v", err)
+	}
+}
+
+func TestEngine_OptionsAndLog(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "uci")
+	eng := startEngine(t, enginetest.Engine{Name: "FakeFish 16", Options: []string{"Threads", "Ponder"}, Log: log}, 1)
+	if _, err := eng.AnalyzePosition(e4FEN, 8, 1); err != nil {
+		t.Fatal(err)
+	}
+	eng.Close()
+
+	// Of the analysis options only Ponder is advertised
+	processes := enginetest.Commands(t, log)
+	if len(processes) != 1 {
+		t.Fatalf("%d processes logged, want 1", len(processes))
+	}
+	var setoptions []string
+	for _, command := range processes[0] {
+		if strings.HasPrefix(command, "setoption ") {
+			setoptions = append(setoptions, command)
+		}
+	}
+	if want := []string{"setoption name Threads value 1", "setoption name Hash value 16", "setoption name Ponder value false"}; !reflect.DeepEqual(setoptions, want) {
+		t.Errorf("setoption commands = %v, want %v", setoptions, want)
+	}
+	if commands := strings.Join(processes[0], "\n"); !strings.Contains(commands, "position fen "+e4FEN+"\ngo depth 8") {
+		t.Errorf("commands logged:\n%s\nwant the search", commands)
+	}
+}
+
+func TestEngine_Lines(t *testing.T) {
+	eng := startEngine(t, enginetest.Engine{
+		Default: enginetest.Eval{Centipawns: 30, BestMove: "g1f3", PV: []string{"d7d5"}, Lines: []enginetest.Line{
+			{Centipawns: 28, PV: []string{"d2d4", "d7d5"}},
+			{Depth: 10, Centipawns: 20, PV: []string{"g1f3", "g8f6"}},
+		}},
+		ReverseLines: true,
+	}, 1)
+
+	result, err := eng.AnalyzePosition(startFEN, 12, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, eval := range result.Evaluations {
+		got = append(got, fmt.Sprintf("%d: %s at %d, %d", eval.MultiPV, strings.Join(eval.PV, " "), eval.Depth, eval.Centipawns))
+	}
+	want := []string{"1: g1f3 d7d5 at 12, 30", "2: d2d4 d7d5 at 12, 28", "3: g1f3 g8f6 at 10, 20"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lines = %v, want %v", got, want)
+	}
+}
+
+func TestEngine_Stall(t *testing.T) {
+	eng := startEngine(t, enginetest.Engine{
+		DepthLatency: 100 * time.Microsecond,
+		Failures:     []enginetest.Failure{{Mode: enginetest.Stall, Search: 2}},
+	}, 1)
+
+	// depth² × 100µs: 40ms at depth 20
+	start := time.Now()
+	if result, err := eng.AnalyzePosition(startFEN, 20, 1); err != nil || result.Depth != 20 {
+		t.Fatalf("first search: %+v, %v", result, err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("depth 20 search took %v, want at least 40ms", elapsed)
+	}
+
+	// The second search runs until stopped, however shallow its depth
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	result, err := eng.AnalyzePositionContext(ctx, startFEN, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Stopped || result.Depth != 1 {
+		t.Errorf("stalled search: stopped %v at depth %d", result.Stopped, result.Depth)
+	}
+}
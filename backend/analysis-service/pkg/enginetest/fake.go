@@ -0,0 +1,420 @@
+package enginetest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// defaultDepth is the depth of searches without one
+const defaultDepth = 20
+
+// fakeEngine is a running fake engine, answering UCI commands
+type fakeEngine struct {
+	Engine
+	out      *bufio.Writer
+	log      io.Writer // Where commands are logged, nil for nowhere
+	stateDir string    // Where Failures' Times are counted
+	evals    map[string]Eval
+
+	multiPV  int
+	position *chess.Position // nil when the last position sent was invalid
+	searches int
+}
+
+func newFakeEngine(e Engine, stateDir string, out io.Writer) *fakeEngine {
+	f := &fakeEngine{
+		Engine:   e,
+		out:      bufio.NewWriter(out),
+		stateDir: stateDir,
+		evals:    make(map[string]Eval, len(e.Evals)),
+		multiPV:  1,
+		position: chess.StartingPosition(),
+	}
+	for fen, eval := range e.Evals {
+		f.evals[positionKey(fen)] = eval
+	}
+	return f
+}
+
+// run answers the commands read from in until "quit" or the end of in, and
+// returns the exit status
+func (f *fakeEngine) run(in io.Reader) int {
+	commands := make(chan string)
+	go func() {
+		defer close(commands)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			commands <- scanner.Text()
+		}
+	}()
+
+	if f.Log != "" {
+		file, err := os.OpenFile(fmt.Sprintf("%s.%d", f.Log, os.Getpid()), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "enginetest: %v\n", err)
+			return 2
+		}
+		defer file.Close()
+		f.log = file
+	}
+
+	for line := range commands {
+		if f.log != nil {
+			fmt.Fprintln(f.log, line)
+		}
+		cmd, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch cmd {
+		case "uci":
+			time.Sleep(f.Startup)
+			f.println("id name " + f.name())
+			f.println("id author enginetest")
+			for _, option := range f.options() {
+				f.println(option)
+			}
+			f.println("uciok")
+		case "isready":
+			f.println("readyok")
+		case "setoption":
+			if value, ok := strings.CutPrefix(args, "name MultiPV value "); ok {
+				if n, err := strconv.Atoi(value); err == nil && n > 0 {
+					f.multiPV = n
+				}
+			}
+		case "position":
+			f.position, _ = parsePosition(args)
+		case "go":
+			if status, exit := f.search(args, commands); exit {
+				return status
+			}
+		case "quit":
+			return 0
+		}
+		f.out.Flush()
+	}
+	return 0
+}
+
+// search answers "go args", reading commands while it runs, and reports
+// whether the engine exits, with which status
+func (f *fakeEngine) search(args string, commands <-chan string) (int, bool) {
+	f.searches++
+	switch f.failure() {
+	case Hang:
+		// Until killed, or the input closes so the process doesn't outlive
+		// a test that never killed it
+		for range commands {
+		}
+		return 0, true
+	case Crash:
+		f.println("info depth 1 seldepth 1 multipv 1 score cp 0 nodes 10 nps 1000 time 1")
+		f.out.Flush()
+		return 1, true
+	case Garbage:
+		f.println("info string ¿que?")
+		f.println("score 9000 depth banana")
+		f.println("bestmove " + f.bestMove())
+		return 0, false
+	case Stall:
+		started := time.Now()
+		if _, exit := f.wait(nil, commands); exit {
+			return 0, true
+		}
+		f.report(1, time.Since(started))
+		return 0, false
+	}
+
+	depth := f.Depth
+	if depth == 0 {
+		depth = defaultDepth
+	}
+	if limit, ok := goArgument(args, "depth"); ok {
+		depth = limit
+	}
+
+	started := time.Now()
+	if latency := f.Latency + f.DepthLatency*time.Duration(depth*depth); latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		stopped, exit := f.wait(timer.C, commands)
+		if exit {
+			return 0, true
+		}
+		if stopped {
+			depth = 1
+		}
+	}
+	f.report(depth, time.Since(started))
+	return 0, false
+}
+
+// wait reads commands until done, "stop" or the engine exiting, reporting
+// whether the search was stopped or the engine exits. A nil done never
+// comes.
+func (f *fakeEngine) wait(done <-chan time.Time, commands <-chan string) (stopped, exit bool) {
+	f.out.Flush()
+	for {
+		select {
+		case <-done:
+			return false, false
+		case line, ok := <-commands:
+			if ok && f.log != nil {
+				fmt.Fprintln(f.log, line)
+			}
+			switch {
+			case !ok || line == "quit":
+				return false, true
+			case line == "stop":
+				return true, false
+			case line == "isready":
+				f.println("readyok")
+				f.out.Flush()
+			}
+		}
+	}
+}
+
+// report prints the evaluations and best move of the position searched
+// to depth
+func (f *fakeEngine) report(depth int, elapsed time.Duration) {
+	if f.position == nil {
+		f.println("info string invalid position")
+		f.println("bestmove (none)")
+		return
+	}
+	switch f.position.Status() {
+	case chess.Checkmate:
+		f.println("info depth 0 score mate 0")
+		f.println("bestmove (none)")
+		return
+	case chess.Stalemate:
+		f.println("info depth 0 score cp 0")
+		f.println("bestmove (none)")
+		return
+	}
+
+	eval := f.eval()
+	best := f.bestMove()
+	first := Line{Depth: depth, Centipawns: eval.Centipawns, PV: append([]string{best}, eval.PV...)}
+	lines := []Line{first}
+	if eval.Lines != nil {
+		for _, line := range eval.Lines {
+			if len(lines) == f.multiPV {
+				break
+			}
+			if line.Depth == 0 {
+				line.Depth = depth
+			}
+			lines = append(lines, line)
+		}
+	} else {
+		for _, move := range legalMoves(f.position) {
+			if len(lines) == f.multiPV {
+				break
+			}
+			// Alternatives score 10cp less each than the line before
+			if move != best {
+				lines = append(lines, Line{Depth: depth, Centipawns: eval.Centipawns - 10*len(lines), PV: []string{move}})
+			}
+		}
+	}
+
+	timeMs := elapsed.Milliseconds()
+	if f.ReportedTime > 0 {
+		timeMs = f.ReportedTime.Milliseconds()
+	}
+	for i := range lines {
+		if f.ReverseLines {
+			i = len(lines) - 1 - i
+		}
+		line := lines[i]
+		score := fmt.Sprintf("cp %d", line.Centipawns)
+		if i == 0 && eval.MateIn != 0 {
+			score = fmt.Sprintf("mate %d", eval.MateIn)
+		}
+		f.println(fmt.Sprintf("info depth %d seldepth %d multipv %d score %s nodes %d nps 1000000 time %d pv %s",
+			line.Depth, line.Depth, i+1, score, 1000*line.Depth, timeMs, strings.Join(line.PV, " ")))
+	}
+	if len(eval.PV) > 0 {
+		f.println(fmt.Sprintf("bestmove %s ponder %s", best, eval.PV[0]))
+	} else {
+		f.println("bestmove " + best)
+	}
+}
+
+// failure returns the mode of the first of Failures that applies to the
+// search, "" for none
+func (f *fakeEngine) failure() FailureMode {
+	for i, failure := range f.Failures {
+		if failure.Search != 0 && failure.Search != f.searches {
+			continue
+		}
+		if failure.FEN != "" && (f.position == nil || positionKey(failure.FEN) != positionKey(f.position.String())) {
+			continue
+		}
+		if failure.Times != 0 && !f.claim(i, failure.Times) {
+			continue
+		}
+		return failure.Mode
+	}
+	return ""
+}
+
+// claim counts a search toward the Times of Failures[i], reporting false
+// when they are used up. Every process of the engine counts in the same
+// files, each failure creating one.
+func (f *fakeEngine) claim(i, times int) bool {
+	for n := 1; n <= times; n++ {
+		name := filepath.Join(f.stateDir, fmt.Sprintf("failure-%d-%d", i, n))
+		file, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			file.Close()
+			return true
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return false
+		}
+	}
+	return false
+}
+
+// eval returns the canned evaluation of the position
+func (f *fakeEngine) eval() Eval {
+	key := positionKey(f.position.String())
+	if eval, ok := f.evals[key]; ok {
+		return eval
+	}
+	eval := f.Default
+	if f.Spread != 0 {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		eval.Centipawns += int(h.Sum32()%9) * f.Spread / 8
+	}
+	return eval
+}
+
+// bestMove returns the canned best move of the position, or its first
+// legal move in UCI order
+func (f *fakeEngine) bestMove() string {
+	if f.position == nil {
+		return "(none)"
+	}
+	if best := f.eval().BestMove; best != "" {
+		return best
+	}
+	if moves := legalMoves(f.position); len(moves) > 0 {
+		return moves[0]
+	}
+	return "(none)"
+}
+
+// options returns the option lines "uci" answers with
+func (f *fakeEngine) options() []string {
+	names := f.Options
+	if names == nil {
+		names = []string{"Threads", "Hash", "MultiPV"}
+	}
+	lines := make([]string, len(names))
+	for i, name := range names {
+		switch name {
+		case "Threads":
+			lines[i] = "option name Threads type spin default 1 min 1 max 1024"
+		case "Hash":
+			lines[i] = "option name Hash type spin default 16 min 1 max 33554432"
+		case "MultiPV":
+			lines[i] = "option name MultiPV type spin default 1 min 1 max 500"
+		default:
+			lines[i] = "option name " + name + " type string default"
+		}
+	}
+	return lines
+}
+
+func (f *fakeEngine) name() string {
+	if f.Name == "" {
+		return "FakeFish"
+	}
+	return f.Name
+}
+
+func (f *fakeEngine) println(line string) {
+	f.out.WriteString(line + "\n")
+}
+
+// parsePosition parses the arguments of "position": "startpos" or
+// "fen <FEN>", then optionally "moves" and moves in UCI notation
+func parsePosition(args string) (*chess.Position, error) {
+	setup, moves, _ := strings.Cut(args, " moves ")
+	setup = strings.TrimSuffix(setup, " moves")
+
+	position := chess.StartingPosition()
+	if fen, ok := strings.CutPrefix(setup, "fen "); ok {
+		fenOpt, err := chess.FEN(fen)
+		if err != nil {
+			return nil, err
+		}
+		position = chess.NewGame(fenOpt).Position()
+	} else if setup != "startpos" {
+		return nil, fmt.Errorf("invalid position %q", args)
+	}
+
+	for _, move := range strings.Fields(moves) {
+		legal, ok := legalMove(position, move)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a legal move", move)
+		}
+		position = position.Update(legal)
+	}
+	return position, nil
+}
+
+// legalMove returns the legal move of position written move in UCI
+func legalMove(position *chess.Position, move string) (*chess.Move, bool) {
+	for _, m := range position.ValidMoves() {
+		if (chess.UCINotation{}).Encode(position, m) == move {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// legalMoves returns the legal moves of position in UCI, in order
+func legalMoves(position *chess.Position) []string {
+	var moves []string
+	for _, m := range position.ValidMoves() {
+		moves = append(moves, (chess.UCINotation{}).Encode(position, m))
+	}
+	sort.Strings(moves)
+	return moves
+}
+
+// positionKey is the board, side to move and castling rights of fen
+func positionKey(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) > 3 {
+		fields = fields[:3]
+	}
+	return strings.Join(fields, " ")
+}
+
+// goArgument returns the value of name in the arguments of "go"
+func goArgument(args, name string) (int, bool) {
+	fields := strings.Fields(args)
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == name {
+			n, err := strconv.Atoi(fields[i+1])
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
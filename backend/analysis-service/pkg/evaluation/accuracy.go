@@ -0,0 +1,139 @@
+package evaluation
+
+import "math"
+
+// AccuracyModel selects whose scoring a game's accuracy follows, so the
+// number can be compared with another site's
+type AccuracyModel string
+
+const (
+	// AccuracyModelEloInsight is the service's own accuracy, calculated
+	// with its AccuracyMethod
+	AccuracyModelEloInsight AccuracyModel = "eloinsight"
+
+	// AccuracyModelLichess is lichess's game accuracy: the mean of the
+	// volatility-weighted mean and the harmonic mean of per-move accuracies
+	AccuracyModelLichess AccuracyModel = "lichess"
+
+	// AccuracyModelChessComApprox approximates chess.com's CAPS accuracy,
+	// whose formula isn't public, as the mean of per-move accuracies from
+	// win probability deltas, leaving out book moves
+	AccuracyModelChessComApprox AccuracyModel = "chesscom_approx"
+)
+
+// Valid reports whether m is a known model
+func (m AccuracyModel) Valid() bool {
+	return m == AccuracyModelEloInsight || m == AccuracyModelLichess || m == AccuracyModelChessComApprox
+}
+
+// Constants of lichess's win percentage and move accuracy curves
+const (
+	lichessWinCoefficient = 0.00368208
+	lichessMaxCentipawns  = 1000
+	lichessMinWindow      = 2
+	lichessMaxWindow      = 8
+	lichessMinWeight      = 0.5
+	lichessMaxWeight      = 12
+)
+
+// LichessWinPercent converts a centipawn evaluation to lichess's win
+// percentage, 0-100, from the same point of view
+func LichessWinPercent(centipawns int) float64 {
+	cp := float64(max(-lichessMaxCentipawns, min(lichessMaxCentipawns, centipawns)))
+	return 50 + 50*(2/(1+math.Exp(-lichessWinCoefficient*cp))-1)
+}
+
+// LichessMoveAccuracy returns lichess's accuracy of a move, 0-100, from the
+// mover's win percentages before and after it
+func LichessMoveAccuracy(before, after float64) float64 {
+	if after >= before {
+		return 100
+	}
+	// The +1 is lichess's allowance for engine uncertainty
+	accuracy := 103.1668100711649*math.Exp(-0.04354415386753951*(before-after)) - 3.166924740191411 + 1
+	return math.Max(0, math.Min(100, accuracy))
+}
+
+// LichessAccuracy returns color's game accuracy the way lichess calculates
+// it. Moves are the game's moves of both players, in order, with
+// evaluations from White's point of view. Each move's accuracy is weighted
+// by the standard deviation of the win percentages in a window around it,
+// so moves in sharp positions count more.
+func LichessAccuracy(moves []MoveEvaluation, color string) float64 {
+	if len(moves) == 0 {
+		return 100
+	}
+
+	// White's win percentages before the first move and after each move
+	winPercents := make([]float64, 0, len(moves)+1)
+	winPercents = append(winPercents, LichessWinPercent(moves[0].EvalBefore))
+	for _, move := range moves {
+		winPercents = append(winPercents, LichessWinPercent(move.EvalAfter))
+	}
+
+	// Windows end at each move; the first moves share the first window
+	size := max(lichessMinWindow, min(lichessMaxWindow, len(moves)/10))
+	size = min(size, len(winPercents))
+	var weightedSum, weights, inverseSum float64
+	var count int
+	for i, move := range moves {
+		if move.Color != color {
+			continue
+		}
+		start := max(0, i+2-size)
+		end := min(len(winPercents), start+size)
+		weight := math.Max(lichessMinWeight, math.Min(lichessMaxWeight, standardDeviation(winPercents[start:end])))
+
+		before, after := winPercents[i], winPercents[i+1]
+		if color == "black" {
+			before, after = 100-before, 100-after
+		}
+		accuracy := LichessMoveAccuracy(before, after)
+
+		weightedSum += accuracy * weight
+		weights += weight
+		inverseSum += 1 / math.Max(1, accuracy)
+		count++
+	}
+	if count == 0 {
+		return 100
+	}
+
+	harmonic := float64(count) / inverseSum
+	return (weightedSum/weights + harmonic) / 2
+}
+
+// ChessComApproxAccuracy approximates color's chess.com game accuracy: the
+// mean of CalculateMoveAccuracy over their moves, leaving out book moves.
+// Moves are as for LichessAccuracy. Chess.com's own numbers can differ by
+// a few points.
+func ChessComApproxAccuracy(moves []MoveEvaluation, color string) float64 {
+	var total float64
+	var count int
+	for _, move := range moves {
+		if move.Color != color || move.Classification == ClassBook {
+			continue
+		}
+		total += CalculateMoveAccuracy(moverEval(move.EvalBefore, color), moverEval(move.EvalAfter, color))
+		count++
+	}
+	if count == 0 {
+		return 100
+	}
+	return total / float64(count)
+}
+
+// standardDeviation is the population standard deviation of xs
+func standardDeviation(xs []float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	mean := sum / float64(len(xs))
+
+	var squares float64
+	for _, x := range xs {
+		squares += (x - mean) * (x - mean)
+	}
+	return math.Sqrt(squares / float64(len(xs)))
+}
@@ -0,0 +1,85 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"testing"
+)
+
+// accuracyFixture is a game as White's evaluation of each position, from
+// the starting one, with each model's expected accuracies. The lichess
+// values follow lichess's published algorithm; the chess.com bands are
+// wider as that model is only an approximation.
+type accuracyFixture struct {
+	Name      string `json:"name"`
+	BookPlies int    `json:"book_plies"`
+	Evals     []int  `json:"evals"`
+	Want      map[AccuracyModel]struct {
+		White     float64 `json:"white"`
+		Black     float64 `json:"black"`
+		Tolerance float64 `json:"tolerance"`
+	} `json:"want"`
+}
+
+func (f accuracyFixture) moves() []MoveEvaluation {
+	moves := make([]MoveEvaluation, len(f.Evals)-1)
+	for i := range moves {
+		moves[i] = MoveEvaluation{Ply: i, Color: "white", EvalBefore: f.Evals[i], EvalAfter: f.Evals[i+1]}
+		if i%2 == 1 {
+			moves[i].Color = "black"
+		}
+		if i < f.BookPlies {
+			moves[i].Classification = ClassBook
+		}
+	}
+	return moves
+}
+
+func TestAccuracyModels(t *testing.T) {
+	data, err := os.ReadFile("testdata/accuracy_models.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixtures []accuracyFixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		t.Fatal(err)
+	}
+
+	models := map[AccuracyModel]func([]MoveEvaluation, string) float64{
+		AccuracyModelLichess:        LichessAccuracy,
+		AccuracyModelChessComApprox: ChessComApproxAccuracy,
+	}
+	for _, f := range fixtures {
+		moves := f.moves()
+		for model, want := range f.Want {
+			white, black := models[model](moves, "white"), models[model](moves, "black")
+			if math.Abs(white-want.White) > want.Tolerance || math.Abs(black-want.Black) > want.Tolerance {
+				t.Errorf("%s, %s: accuracy %.2f/%.2f, want %.2f/%.2f ±%.1f", f.Name, model, white, black, want.White, want.Black, want.Tolerance)
+			}
+		}
+	}
+}
+
+func TestLichessMoveAccuracy(t *testing.T) {
+	if got := LichessMoveAccuracy(50, 60); got != 100 {
+		t.Errorf("improving move = %.2f, want 100", got)
+	}
+	if got := LichessMoveAccuracy(100, 0); got != 0 {
+		t.Errorf("100 point drop = %.2f, want 0", got)
+	}
+	if got := LichessWinPercent(5000); got != LichessWinPercent(1000) {
+		t.Errorf("win percent not capped at 1000cp: %.2f", got)
+	}
+}
+
+func TestAccuracyModel_Valid(t *testing.T) {
+	for _, m := range []AccuracyModel{AccuracyModelEloInsight, AccuracyModelLichess, AccuracyModelChessComApprox} {
+		if !m.Valid() {
+			t.Errorf("%q not valid", m)
+		}
+	}
+	if AccuracyModel("chesscom").Valid() {
+		t.Error(`"chesscom" valid`)
+	}
+}
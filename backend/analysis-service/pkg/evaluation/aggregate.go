@@ -0,0 +1,421 @@
+package evaluation
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// === MULTI-GAME AGGREGATION ===
+
+// GamePhase is the stage of the game a move was played in
+type GamePhase string
+
+const (
+	PhaseOpening    GamePhase = "opening"
+	PhaseMiddlegame GamePhase = "middlegame"
+	PhaseEndgame    GamePhase = "endgame"
+)
+
+// Phase boundaries
+const (
+	// OpeningMoves is the last move number that can count as opening
+	OpeningMoves = 10
+
+	// EndgamePieces is the number of queens, rooks, bishops and knights
+	// left on the board (both sides) at or below which the endgame starts
+	EndgamePieces = 6
+
+	// MiddlegamePieces ends the opening early once trades bring the
+	// count of queens, rooks, bishops and knights down to it
+	MiddlegamePieces = 10
+)
+
+// AccuracyBucketWidth is the width of the accuracy distribution buckets
+const AccuracyBucketWidth = 10
+
+// PlayerReport aggregates a player's analyzed games
+type PlayerReport struct {
+	Player string
+
+	Games        int // Games the player took part in
+	Wins         int
+	Draws        int
+	Losses       int
+	GamesAsWhite int
+	GamesAsBlack int
+	SkippedGames int // Games given that the player didn't take part in
+
+	// UnknownResults are games without a known result, still being
+	// played for one. They count towards Games and the move and accuracy
+	// figures, not towards results, scores or streaks.
+	UnknownResults int
+
+	AverageAccuracy float64 // Mean of per-game accuracy
+	ACPL            float64 // Average centipawn loss over all moves
+	TotalMoves      int
+	Blunders        int
+	BlunderRate     float64 // Blunders per 100 moves
+
+	TimeClasses     []TimeClassStats // Most played first
+	Openings        []OpeningRecord  // Worst score first
+	AccuracyBuckets []AccuracyBucket // Ascending, AccuracyBucketWidth wide
+	Phases          []PhaseStats     // Opening, middlegame, endgame
+	Trend           []GameTrendPoint // Chronological
+
+	LongestWinStreak  int
+	LongestLossStreak int
+	CurrentStreak     int // Positive for wins, negative for losses, 0 after a draw
+}
+
+// TimeClassStats holds results for one time class
+type TimeClassStats struct {
+	TimeClass       string
+	Games           int
+	Wins            int
+	Draws           int
+	Losses          int
+	AverageAccuracy float64
+}
+
+// OpeningRecord holds results for one opening
+type OpeningRecord struct {
+	ECO             string
+	Name            string
+	Games           int
+	Wins            int
+	Draws           int
+	Losses          int
+	Score           float64 // Points per game with a known result, 0-1
+	AverageAccuracy float64
+}
+
+// AccuracyBucket counts games with accuracy in [Min, Max)
+// (the last bucket includes 100)
+type AccuracyBucket struct {
+	Min   int
+	Max   int
+	Games int
+}
+
+// PhaseStats holds move quality in one phase of the game
+type PhaseStats struct {
+	Phase        GamePhase
+	Moves        int
+	Blunders     int
+	Mistakes     int
+	Inaccuracies int
+	BlunderRate  float64 // Blunders per 100 moves
+	ACPL         float64
+}
+
+// GameTrendPoint is one game of the report's trend
+type GameTrendPoint struct {
+	GameID      string
+	PlayedAt    time.Time
+	Accuracy    float64
+	Blunders    int
+	BlunderRate float64 // Blunders per 100 moves
+	Result      GameResult
+}
+
+// AggregatePlayerReport aggregates the games player took part in into a
+// report. The player is matched case-insensitively against WhitePlayer and
+// BlackPlayer, so games played as either color are combined; games without
+// the player are counted as skipped. Games are ordered by PlayedAt for the
+// trend and streaks, keeping input order when it is unknown.
+//
+// Per-game accuracy is taken from the game's metrics, or calculated from
+// the moves when the metrics are empty.
+func AggregatePlayerReport(analyses []GameEvaluation, player string) PlayerReport {
+	report := PlayerReport{Player: player}
+
+	games := make([]GameEvaluation, 0, len(analyses))
+	for _, game := range analyses {
+		if playerColor(game, player) == "" {
+			report.SkippedGames++
+			continue
+		}
+		games = append(games, game)
+	}
+	sort.SliceStable(games, func(i, j int) bool {
+		return games[i].PlayedAt.Before(games[j].PlayedAt)
+	})
+
+	timeClasses := make(map[string]*TimeClassStats)
+	openings := make(map[string]*OpeningRecord)
+	tcAccuracy := make(map[string]*mean)
+	openingAccuracy := make(map[string]*mean)
+	phases := map[GamePhase]*PhaseStats{
+		PhaseOpening:    {Phase: PhaseOpening},
+		PhaseMiddlegame: {Phase: PhaseMiddlegame},
+		PhaseEndgame:    {Phase: PhaseEndgame},
+	}
+	phaseLoss := make(map[GamePhase]int)
+	for lo := 0; lo < 100; lo += AccuracyBucketWidth {
+		report.AccuracyBuckets = append(report.AccuracyBuckets, AccuracyBucket{Min: lo, Max: lo + AccuracyBucketWidth})
+	}
+
+	var accuracy mean
+	var totalLoss, winStreak, lossStreak int
+
+	for _, game := range games {
+		color := playerColor(game, player)
+		result := playerResult(game.Result, color)
+		if color == "black" {
+			report.GamesAsBlack++
+		} else {
+			report.GamesAsWhite++
+		}
+		report.Games++
+
+		tc := game.TimeClass
+		if tc == "" {
+			tc = "unknown"
+		}
+		if timeClasses[tc] == nil {
+			timeClasses[tc] = &TimeClassStats{TimeClass: tc}
+		}
+		eco := game.ECO
+		if eco == "" {
+			eco = "?"
+		}
+		if openings[eco] == nil {
+			openings[eco] = &OpeningRecord{ECO: eco}
+		}
+		if openings[eco].Name == "" {
+			openings[eco].Name = game.OpeningName
+		}
+		if tcAccuracy[tc] == nil {
+			tcAccuracy[tc] = &mean{}
+		}
+		if openingAccuracy[eco] == nil {
+			openingAccuracy[eco] = &mean{}
+		}
+		tcStats, opening := timeClasses[tc], openings[eco]
+		tcStats.Games++
+		opening.Games++
+
+		switch result {
+		case ResultWin:
+			report.Wins++
+			tcStats.Wins++
+			opening.Wins++
+			winStreak, lossStreak = winStreak+1, 0
+		case ResultLoss:
+			report.Losses++
+			tcStats.Losses++
+			opening.Losses++
+			winStreak, lossStreak = 0, lossStreak+1
+		case ResultDraw:
+			report.Draws++
+			tcStats.Draws++
+			opening.Draws++
+			winStreak, lossStreak = 0, 0
+		default:
+			// Neither ends nor extends a streak
+			report.UnknownResults++
+		}
+		report.LongestWinStreak = max(report.LongestWinStreak, winStreak)
+		report.LongestLossStreak = max(report.LongestLossStreak, lossStreak)
+		report.CurrentStreak = winStreak - lossStreak
+
+		// Move quality
+		var moves, blunders int
+		for _, move := range game.Moves {
+			if move.Color != color {
+				continue
+			}
+			class := move.Classification
+			if class == "" {
+				class = ClassifyMove(move.CentipawnLoss, move.WasBestMove, move.EvalBefore, move.EvalAfter, move.IsMateScore, DefaultThresholds)
+			}
+			phase := phases[MovePhase(move)]
+			phase.Moves++
+			phaseLoss[phase.Phase] += move.CentipawnLoss
+			switch class {
+			case ClassBlunder, ClassMissedWin:
+				phase.Blunders++
+				blunders++
+			case ClassMistake:
+				phase.Mistakes++
+			case ClassInaccuracy:
+				phase.Inaccuracies++
+			}
+			moves++
+			totalLoss += move.CentipawnLoss
+		}
+		report.TotalMoves += moves
+		report.Blunders += blunders
+
+		point := GameTrendPoint{
+			GameID:      game.GameID,
+			PlayedAt:    game.PlayedAt,
+			Blunders:    blunders,
+			BlunderRate: per100(blunders, moves),
+			Result:      result,
+		}
+
+		if gameAccuracy, ok := playerAccuracy(game, color); ok {
+			point.Accuracy = gameAccuracy
+			accuracy.add(gameAccuracy)
+			tcAccuracy[tc].add(gameAccuracy)
+			openingAccuracy[eco].add(gameAccuracy)
+
+			bucket := int(gameAccuracy) / AccuracyBucketWidth
+			bucket = max(0, min(bucket, len(report.AccuracyBuckets)-1))
+			report.AccuracyBuckets[bucket].Games++
+		}
+		report.Trend = append(report.Trend, point)
+	}
+
+	report.AverageAccuracy = accuracy.value()
+	if report.TotalMoves > 0 {
+		report.ACPL = float64(totalLoss) / float64(report.TotalMoves)
+	}
+	report.BlunderRate = per100(report.Blunders, report.TotalMoves)
+
+	for _, phase := range []GamePhase{PhaseOpening, PhaseMiddlegame, PhaseEndgame} {
+		stats := phases[phase]
+		stats.BlunderRate = per100(stats.Blunders, stats.Moves)
+		if stats.Moves > 0 {
+			stats.ACPL = float64(phaseLoss[phase]) / float64(stats.Moves)
+		}
+		report.Phases = append(report.Phases, *stats)
+	}
+
+	for tc, stats := range timeClasses {
+		stats.AverageAccuracy = tcAccuracy[tc].value()
+		report.TimeClasses = append(report.TimeClasses, *stats)
+	}
+	sort.Slice(report.TimeClasses, func(i, j int) bool {
+		a, b := report.TimeClasses[i], report.TimeClasses[j]
+		if a.Games != b.Games {
+			return a.Games > b.Games
+		}
+		return a.TimeClass < b.TimeClass
+	})
+
+	for eco, opening := range openings {
+		opening.Score = score(opening.Wins, opening.Draws, opening.Losses)
+		opening.AverageAccuracy = openingAccuracy[eco].value()
+		report.Openings = append(report.Openings, *opening)
+	}
+	sort.Slice(report.Openings, func(i, j int) bool {
+		a, b := report.Openings[i], report.Openings[j]
+		if a.Score != b.Score {
+			return a.Score < b.Score
+		}
+		if a.Games != b.Games {
+			return a.Games > b.Games
+		}
+		return a.ECO < b.ECO
+	})
+
+	return report
+}
+
+// MovePhase returns the phase a move was played in. Without a FEN, moves
+// after the opening count as middlegame.
+func MovePhase(move MoveEvaluation) GamePhase {
+	pieces := -1
+	if board, _, ok := strings.Cut(move.FEN, " "); ok {
+		pieces = 0
+		for _, c := range strings.ToLower(board) {
+			switch c {
+			case 'q', 'r', 'b', 'n':
+				pieces++
+			}
+		}
+	}
+
+	switch {
+	case pieces >= 0 && pieces <= EndgamePieces:
+		return PhaseEndgame
+	case move.MoveNumber <= OpeningMoves && (pieces < 0 || pieces > MiddlegamePieces):
+		return PhaseOpening
+	default:
+		return PhaseMiddlegame
+	}
+}
+
+// playerColor returns the color player had in game, or "" if they didn't
+// play in it. A game against themselves counts as White.
+func playerColor(game GameEvaluation, player string) string {
+	switch {
+	case strings.EqualFold(game.WhitePlayer, player):
+		return "white"
+	case strings.EqualFold(game.BlackPlayer, player):
+		return "black"
+	default:
+		return ""
+	}
+}
+
+// playerAccuracy returns color's accuracy in game from its metrics, or
+// calculated from the moves when the metrics are empty. Games without
+// moves of the player have no meaningful accuracy.
+func playerAccuracy(game GameEvaluation, color string) (float64, bool) {
+	metrics := game.WhiteMetrics
+	if color == "black" {
+		metrics = game.BlackMetrics
+	}
+	if metrics.TotalMoves > 0 {
+		return metrics.Accuracy, true
+	}
+	for _, move := range game.Moves {
+		if move.Color == color {
+			return CalculateAccuracy(game.Moves, color), true
+		}
+	}
+	return 0, false
+}
+
+// score returns the points per game of a record, 0 without games
+func score(wins, draws, losses int) float64 {
+	games := wins + draws + losses
+	if games == 0 {
+		return 0
+	}
+	return (float64(wins) + float64(draws)/2) / float64(games)
+}
+
+// playerResult converts a result from White's point of view to color's
+func playerResult(result GameResult, color string) GameResult {
+	if color != "black" {
+		return result
+	}
+	switch result {
+	case ResultWin:
+		return ResultLoss
+	case ResultLoss:
+		return ResultWin
+	default:
+		return result
+	}
+}
+
+// mean accumulates an average
+type mean struct {
+	sum float64
+	n   int
+}
+
+func (m *mean) add(v float64) {
+	m.sum += v
+	m.n++
+}
+
+func (m *mean) value() float64 {
+	if m.n == 0 {
+		return 0
+	}
+	return m.sum / float64(m.n)
+}
+
+func per100(count, moves int) float64 {
+	if moves == 0 {
+		return 0
+	}
+	return float64(count) * 100 / float64(moves)
+}
@@ -0,0 +1,221 @@
+package evaluation
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// reportGame builds a game where both sides get the given per-move
+// classifications and accuracy
+func reportGame(id, white, black string, result GameResult, eco, timeClass string, day int, accuracy float64, classes ...MoveClassification) GameEvaluation {
+	game := GameEvaluation{
+		GameID:      id,
+		WhitePlayer: white,
+		BlackPlayer: black,
+		Result:      result,
+		ECO:         eco,
+		TimeClass:   timeClass,
+		PlayedAt:    time.Date(2026, 9, day, 12, 0, 0, 0, time.UTC),
+	}
+	for i, class := range classes {
+		for _, color := range []string{"white", "black"} {
+			game.Moves = append(game.Moves, MoveEvaluation{
+				MoveNumber:     i + 1,
+				Color:          color,
+				Classification: class,
+			})
+		}
+	}
+	metrics := PlayerMetrics{Accuracy: accuracy, TotalMoves: len(classes)}
+	game.WhiteMetrics, game.BlackMetrics = metrics, metrics
+	return game
+}
+
+func TestAggregatePlayerReport_Empty(t *testing.T) {
+	report := AggregatePlayerReport(nil, "alice")
+
+	if report.Games != 0 || report.AverageAccuracy != 0 || report.BlunderRate != 0 {
+		t.Errorf("empty report = %+v", report)
+	}
+	if len(report.Phases) != 3 || len(report.AccuracyBuckets) != 10 {
+		t.Errorf("phases = %d, buckets = %d, want 3 and 10", len(report.Phases), len(report.AccuracyBuckets))
+	}
+	if len(report.Openings) != 0 || len(report.Trend) != 0 {
+		t.Errorf("openings = %v, trend = %v", report.Openings, report.Trend)
+	}
+}
+
+func TestAggregatePlayerReport_BothColors(t *testing.T) {
+	games := []GameEvaluation{
+		// Out of order on purpose
+		reportGame("g3", "Bob", "Alice", ResultLoss, "B20", "blitz", 3, 80, ClassBest, ClassGood),
+		reportGame("g1", "alice", "Bob", ResultWin, "C50", "blitz", 1, 90, ClassBest, ClassBest, ClassInaccuracy, ClassBest),
+		reportGame("g2", "Carol", "ALICE", ResultWin, "B20", "rapid", 2, 40, ClassBlunder, ClassMistake),
+		reportGame("g4", "Bob", "Carol", ResultDraw, "A00", "blitz", 4, 70, ClassBest),
+		reportGame("g5", "Alice", "Dave", ResultDraw, "", "", 5, 65, ClassMissedWin, ClassBest),
+	}
+
+	report := AggregatePlayerReport(games, "Alice")
+
+	if report.Games != 4 || report.SkippedGames != 1 {
+		t.Fatalf("games = %d, skipped = %d, want 4 and 1", report.Games, report.SkippedGames)
+	}
+	if report.GamesAsWhite != 2 || report.GamesAsBlack != 2 {
+		t.Errorf("as white = %d, as black = %d, want 2 and 2", report.GamesAsWhite, report.GamesAsBlack)
+	}
+	// Results are from White's point of view: Alice lost g2 as Black and
+	// won g3 as Black
+	if report.Wins != 2 || report.Losses != 1 || report.Draws != 1 {
+		t.Errorf("W/L/D = %d/%d/%d, want 2/1/1", report.Wins, report.Losses, report.Draws)
+	}
+	if got, want := report.AverageAccuracy, (90+40+80+65)/4.0; math.Abs(got-want) > 1e-9 {
+		t.Errorf("average accuracy = %v, want %v", got, want)
+	}
+	if report.TotalMoves != 10 || report.Blunders != 2 || report.BlunderRate != 20 {
+		t.Errorf("moves = %d, blunders = %d, rate = %v, want 10, 2, 20", report.TotalMoves, report.Blunders, report.BlunderRate)
+	}
+
+	// Chronological trend and streaks: W (g1), L (g2), W (g3), D (g5)
+	var ids []string
+	for _, p := range report.Trend {
+		ids = append(ids, p.GameID)
+	}
+	if got := len(ids); got != 4 || ids[0] != "g1" || ids[1] != "g2" || ids[2] != "g3" || ids[3] != "g5" {
+		t.Errorf("trend order = %v, want [g1 g2 g3 g5]", ids)
+	}
+	if report.Trend[1].BlunderRate != 50 {
+		t.Errorf("g2 blunder rate = %v, want 50", report.Trend[1].BlunderRate)
+	}
+	if report.LongestWinStreak != 1 || report.LongestLossStreak != 1 || report.CurrentStreak != 0 {
+		t.Errorf("streaks = %d/%d/%d, want 1/1/0", report.LongestWinStreak, report.LongestLossStreak, report.CurrentStreak)
+	}
+
+	// Worst opening first
+	if len(report.Openings) != 3 {
+		t.Fatalf("openings = %+v", report.Openings)
+	}
+	if o := report.Openings[0]; o.ECO != "B20" || o.Games != 2 || o.Score != 0.5 || o.AverageAccuracy != 60 {
+		t.Errorf("worst opening = %+v", o)
+	}
+	if o := report.Openings[2]; o.ECO != "C50" || o.Score != 1 {
+		t.Errorf("best opening = %+v", o)
+	}
+
+	if tc := report.TimeClasses[0]; tc.TimeClass != "blitz" || tc.Games != 2 || tc.AverageAccuracy != 85 {
+		t.Errorf("most played time class = %+v", tc)
+	}
+
+	buckets := map[int]int{}
+	for _, b := range report.AccuracyBuckets {
+		buckets[b.Min] = b.Games
+	}
+	if buckets[40] != 1 || buckets[60] != 1 || buckets[80] != 1 || buckets[90] != 1 {
+		t.Errorf("buckets = %v", report.AccuracyBuckets)
+	}
+}
+
+func TestAggregatePlayerReport_UnknownResult(t *testing.T) {
+	games := []GameEvaluation{
+		reportGame("g1", "alice", "bob", ResultWin, "C50", "blitz", 1, 80, ClassBest),
+		reportGame("g2", "alice", "bob", ResultUnknown, "C50", "blitz", 2, 40, ClassBlunder),
+		reportGame("g3", "bob", "alice", ResultLoss, "C50", "blitz", 3, 90, ClassBest),
+	}
+
+	report := AggregatePlayerReport(games, "alice")
+
+	if report.Games != 3 || report.UnknownResults != 1 || report.Wins != 2 || report.Draws != 0 || report.Losses != 0 {
+		t.Errorf("games %d, unknown %d, results %d/%d/%d; want 3, 1, 2/0/0",
+			report.Games, report.UnknownResults, report.Wins, report.Draws, report.Losses)
+	}
+	// The unfinished game counts for accuracy and moves, not for the score
+	if report.AverageAccuracy != 70 || report.TotalMoves != 3 || report.Blunders != 1 {
+		t.Errorf("accuracy %v, moves %d, blunders %d; want 70, 3, 1", report.AverageAccuracy, report.TotalMoves, report.Blunders)
+	}
+	if tc := report.TimeClasses[0]; tc.Games != 3 || tc.Wins != 2 || tc.Draws != 0 {
+		t.Errorf("blitz = %+v", tc)
+	}
+	if opening := report.Openings[0]; opening.Games != 3 || opening.Score != 1 {
+		t.Errorf("opening = %+v, want a score of 1 from the two finished games", opening)
+	}
+	if report.LongestWinStreak != 2 || report.CurrentStreak != 2 {
+		t.Errorf("streaks = %d/%d, want 2/2 across the unfinished game", report.LongestWinStreak, report.CurrentStreak)
+	}
+	if report.Trend[1].Result != ResultUnknown {
+		t.Errorf("trend result = %q, want unknown", report.Trend[1].Result)
+	}
+
+	// An unfinished game has neither a performance rating nor resilience
+	metrics := CalculatePlayerMetrics(games[1].Moves, "white", 1500, ResultUnknown, DefaultThresholds)
+	if metrics.PerformanceRating != 0 || metrics.Resilience != nil || metrics.TotalMoves != 1 {
+		t.Errorf("metrics = %+v, want moves but no rating or resilience", metrics)
+	}
+}
+
+func TestAggregatePlayerReport_Streaks(t *testing.T) {
+	var games []GameEvaluation
+	for i, result := range []GameResult{ResultWin, ResultWin, ResultWin, ResultLoss, ResultLoss, ResultWin, ResultWin} {
+		games = append(games, reportGame("g", "alice", "bob", result, "C50", "blitz", i+1, 80, ClassBest))
+	}
+
+	report := AggregatePlayerReport(games, "alice")
+
+	if report.LongestWinStreak != 3 || report.LongestLossStreak != 2 || report.CurrentStreak != 2 {
+		t.Errorf("streaks = %d/%d/%d, want 3/2/2", report.LongestWinStreak, report.LongestLossStreak, report.CurrentStreak)
+	}
+}
+
+func TestAggregatePlayerReport_Phases(t *testing.T) {
+	const (
+		opening = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+		middle  = "r1bq1rk1/ppp2ppp/2n2n2/8/8/2N2N2/PPP2PPP/R1BQ1RK1 w - - 0 15"
+		endgame = "8/5pk1/8/8/8/8/5PK1/3R4 w - - 0 40"
+	)
+	game := GameEvaluation{
+		WhitePlayer: "alice",
+		Moves: []MoveEvaluation{
+			{MoveNumber: 1, Color: "white", FEN: opening, Classification: ClassBest},
+			{MoveNumber: 15, Color: "white", FEN: middle, Classification: ClassBlunder, CentipawnLoss: 400},
+			{MoveNumber: 16, Color: "white", FEN: middle, Classification: ClassBest},
+			{MoveNumber: 40, Color: "white", FEN: endgame, Classification: ClassMistake, CentipawnLoss: 150},
+		},
+	}
+
+	report := AggregatePlayerReport([]GameEvaluation{game}, "alice")
+
+	want := []PhaseStats{
+		{Phase: PhaseOpening, Moves: 1},
+		{Phase: PhaseMiddlegame, Moves: 2, Blunders: 1, BlunderRate: 50, ACPL: 200},
+		{Phase: PhaseEndgame, Moves: 1, Mistakes: 1, ACPL: 150},
+	}
+	for i, w := range want {
+		if report.Phases[i] != w {
+			t.Errorf("phase %d = %+v, want %+v", i, report.Phases[i], w)
+		}
+	}
+	// Metrics were empty, so accuracy comes from the moves
+	if report.Trend[0].Accuracy == 0 || report.AverageAccuracy != report.Trend[0].Accuracy {
+		t.Errorf("accuracy = %v, trend %v", report.AverageAccuracy, report.Trend[0].Accuracy)
+	}
+}
+
+func TestMovePhase(t *testing.T) {
+	tests := []struct {
+		name string
+		move MoveEvaluation
+		want GamePhase
+	}{
+		{"early without FEN", MoveEvaluation{MoveNumber: 5}, PhaseOpening},
+		{"late without FEN", MoveEvaluation{MoveNumber: 30}, PhaseMiddlegame},
+		{"early trades", MoveEvaluation{MoveNumber: 8, FEN: "r3k2r/ppp2ppp/8/8/8/8/PPP2PPP/R3K2R w KQkq - 0 8"}, PhaseEndgame},
+		{"trades end the opening", MoveEvaluation{MoveNumber: 9, FEN: "r3kb1r/ppp2ppp/2n5/8/8/2N5/PPP2PPP/R3KB1R w KQkq - 0 9"}, PhaseMiddlegame},
+		{"full board", MoveEvaluation{MoveNumber: 3, FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 3"}, PhaseOpening},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MovePhase(tt.move); got != tt.want {
+				t.Errorf("MovePhase() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package evaluation
+
+// === CLASSIFICATION DISPLAY ===
+
+// Classifications lists every MoveClassification, best to worst
+var Classifications = []MoveClassification{
+	ClassBrilliant,
+	ClassGreat,
+	ClassBest,
+	ClassExcellent,
+	ClassGood,
+	ClassBook,
+	ClassNormal,
+	ClassInaccuracy,
+	ClassMistake,
+	ClassBlunder,
+	ClassMissedWin,
+}
+
+// classificationDisplay is how clients show a classification
+type classificationDisplay struct {
+	glyph string // Annotation glyph, empty for moves that get none
+	color string // "#rrggbb"
+	label string
+}
+
+// classificationDisplays is the one table of glyphs, colors and labels;
+// the API and the annotated-PGN export both read it
+var classificationDisplays = map[MoveClassification]classificationDisplay{
+	ClassBrilliant:  {"!!", "#1BACA6", "Brilliant"},
+	ClassGreat:      {"!", "#5C8BB0", "Great"},
+	ClassBest:       {"", "#96BC4B", "Best"},
+	ClassExcellent:  {"", "#96BC4B", "Excellent"},
+	ClassGood:       {"", "#96AF8B", "Good"},
+	ClassBook:       {"", "#A88865", "Book"},
+	ClassNormal:     {"", "#9E9E9E", "Normal"},
+	ClassInaccuracy: {"?!", "#F7C631", "Inaccuracy"},
+	ClassMistake:    {"?", "#E58F2A", "Mistake"},
+	ClassBlunder:    {"??", "#CA3431", "Blunder"},
+	ClassMissedWin:  {"?", "#DBAC16", "Missed win"},
+}
+
+// glyphNAGs maps annotation glyphs to their numeric annotation glyphs
+var glyphNAGs = map[string]string{
+	"!":  "$1",
+	"?":  "$2",
+	"!!": "$3",
+	"??": "$4",
+	"!?": "$5",
+	"?!": "$6",
+}
+
+// ClassificationDisplay returns the glyph, color and label clients show
+// class with, e.g. "??", "#CA3431" and "Blunder". The glyph is empty for
+// classes without one; all three are empty for an unknown class.
+func ClassificationDisplay(class MoveClassification) (glyph string, colorHex string, label string) {
+	d := classificationDisplays[class]
+	return d.glyph, d.color, d.label
+}
+
+// ClassificationNAG returns the numeric annotation glyph, like "$4", for
+// class's glyph, or "" when it has none
+func ClassificationNAG(class MoveClassification) string {
+	return glyphNAGs[classificationDisplays[class].glyph]
+}
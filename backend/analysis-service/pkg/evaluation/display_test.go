@@ -0,0 +1,50 @@
+package evaluation
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestClassificationDisplay(t *testing.T) {
+	color := regexp.MustCompile(`^#[0-9A-F]{6}$`)
+	seen := make(map[MoveClassification]bool)
+	for _, class := range Classifications {
+		if seen[class] {
+			t.Errorf("%s listed twice", class)
+		}
+		seen[class] = true
+
+		glyph, hex, label := ClassificationDisplay(class)
+		if label == "" || !color.MatchString(hex) {
+			t.Errorf("%s: label %q, color %q", class, label, hex)
+		}
+		if nag := ClassificationNAG(class); (nag == "") != (glyph == "") {
+			t.Errorf("%s: glyph %q but NAG %q", class, glyph, nag)
+		}
+	}
+	for class := range classificationDisplays {
+		if !seen[class] {
+			t.Errorf("%s has display data but isn't in Classifications", class)
+		}
+	}
+
+	for _, tt := range []struct {
+		class      MoveClassification
+		glyph, nag string
+	}{
+		{ClassBrilliant, "!!", "$3"},
+		{ClassGreat, "!", "$1"},
+		{ClassBest, "", ""},
+		{ClassInaccuracy, "?!", "$6"},
+		{ClassMistake, "?", "$2"},
+		{ClassBlunder, "??", "$4"},
+		{ClassMissedWin, "?", "$2"},
+	} {
+		if glyph, _, _ := ClassificationDisplay(tt.class); glyph != tt.glyph || ClassificationNAG(tt.class) != tt.nag {
+			t.Errorf("%s: %q %q, want %q %q", tt.class, glyph, ClassificationNAG(tt.class), tt.glyph, tt.nag)
+		}
+	}
+	if glyph, hex, label := ClassificationDisplay("nope"); glyph != "" || hex != "" || label != "" {
+		t.Errorf("unknown class: %q %q %q", glyph, hex, label)
+	}
+}
@@ -0,0 +1,825 @@
+// Package evaluation provides chess game evaluation metrics.
+// It converts Stockfish evaluations into human-readable metrics like
+// accuracy percentage, ACPL, move classifications, and performance rating.
+//
+// PlayerMetrics, MoveEvaluation, GameEvaluation and Thresholds, with
+// CalculatePlayerMetrics, are the stable API: fields are only ever added.
+// It has no dependencies on engines and can be used on stored analyses.
+package evaluation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// === THRESHOLD CONSTANTS ===
+// Based on chess.com and lichess standards for move classification
+
+// Move Classification Thresholds (in centipawns)
+const (
+	// BestMoveThreshold: Move matches or is within 10cp of best move
+	BestMoveThreshold = 10
+
+	// ExcellentMoveThreshold: Very strong move, minimal loss
+	ExcellentMoveThreshold = 25
+
+	// GoodMoveThreshold: Solid move, acceptable loss
+	GoodMoveThreshold = 50
+
+	// InaccuracyThreshold: Small mistake, missed better option
+	InaccuracyThreshold = 100
+
+	// MistakeThreshold: Significant error, loses advantage
+	MistakeThreshold = 300
+
+	// BlunderThreshold: Major mistake, loses game/material
+	BlunderThreshold = 301
+)
+
+// GarbageTimeThreshold is the default evaluation, in centipawns from the
+// mover's point of view, beyond which the game is decided either way
+const GarbageTimeThreshold = 800
+
+// Thresholds holds the maximum centipawn loss for each move classification.
+// A loss above Mistake is a blunder.
+//
+// Moves made with the mover's evaluation above GarbageWin or below
+// GarbageLoss are garbage time: still classified, but left out of
+// accuracy and ACPL when garbage time is excluded. Zero disables a side.
+type Thresholds struct {
+	Best       int `json:"best"`
+	Excellent  int `json:"excellent"`
+	Good       int `json:"good"`
+	Inaccuracy int `json:"inaccuracy"`
+	Mistake    int `json:"mistake"`
+
+	GarbageWin  int `json:"garbage_win"`
+	GarbageLoss int `json:"garbage_loss"` // Negative
+}
+
+// DefaultThresholds are the standard chess.com/lichess-like thresholds
+var DefaultThresholds = Thresholds{
+	Best:       BestMoveThreshold,
+	Excellent:  ExcellentMoveThreshold,
+	Good:       GoodMoveThreshold,
+	Inaccuracy: InaccuracyThreshold,
+	Mistake:    MistakeThreshold,
+
+	GarbageWin:  GarbageTimeThreshold,
+	GarbageLoss: -GarbageTimeThreshold,
+}
+
+// Threshold profile names
+const (
+	ProfileStandard = "standard" // DefaultThresholds
+	ProfileStrict   = "strict"   // For titled and strong club players
+	ProfileLenient  = "lenient"  // For beginners
+)
+
+// DefaultProfiles returns the built-in threshold profiles
+func DefaultProfiles() map[string]Thresholds {
+	return map[string]Thresholds{
+		ProfileStandard: DefaultThresholds,
+		ProfileStrict:   {Best: 5, Excellent: 15, Good: 30, Inaccuracy: 60, Mistake: 200, GarbageWin: GarbageTimeThreshold, GarbageLoss: -GarbageTimeThreshold},
+		ProfileLenient:  {Best: 15, Excellent: 40, Good: 80, Inaccuracy: 150, Mistake: 400, GarbageWin: GarbageTimeThreshold, GarbageLoss: -GarbageTimeThreshold},
+	}
+}
+
+// Validate checks that thresholds are non-negative and strictly increasing
+func (t Thresholds) Validate() error {
+	if t.Best < 0 {
+		return fmt.Errorf("best threshold %d must not be negative", t.Best)
+	}
+	if !(t.Best < t.Excellent && t.Excellent < t.Good && t.Good < t.Inaccuracy && t.Inaccuracy < t.Mistake) {
+		return fmt.Errorf("thresholds %s must be strictly increasing", t)
+	}
+	if t.GarbageWin < 0 || t.GarbageLoss > 0 {
+		return fmt.Errorf("garbage time thresholds %d,%d must be positive and negative", t.GarbageWin, t.GarbageLoss)
+	}
+	return nil
+}
+
+// IsGarbageTime reports whether a move made at evalBefore, centipawns from
+// the mover's point of view, is made in a decided position
+func (t Thresholds) IsGarbageTime(evalBefore int) bool {
+	return (t.GarbageWin != 0 && evalBefore > t.GarbageWin) ||
+		(t.GarbageLoss != 0 && evalBefore < t.GarbageLoss)
+}
+
+// WithoutGarbageTime returns the thresholds with garbage time disabled
+func (t Thresholds) WithoutGarbageTime() Thresholds {
+	t.GarbageWin, t.GarbageLoss = 0, 0
+	return t
+}
+
+// String formats thresholds as "best,excellent,good,inaccuracy,mistake",
+// followed by ",garbage_win,garbage_loss" when those aren't the defaults
+func (t Thresholds) String() string {
+	s := fmt.Sprintf("%d,%d,%d,%d,%d", t.Best, t.Excellent, t.Good, t.Inaccuracy, t.Mistake)
+	if t.GarbageWin != GarbageTimeThreshold || t.GarbageLoss != -GarbageTimeThreshold {
+		s += fmt.Sprintf(",%d,%d", t.GarbageWin, t.GarbageLoss)
+	}
+	return s
+}
+
+// ParseThresholds parses the "best,excellent,good,inaccuracy,mistake"
+// format produced by String. Garbage time thresholds are optional and
+// default to ±GarbageTimeThreshold.
+func ParseThresholds(s string) (Thresholds, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 5 && len(parts) != 7 {
+		return Thresholds{}, fmt.Errorf("thresholds %q must have 5 or 7 comma-separated values", s)
+	}
+
+	values := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return Thresholds{}, fmt.Errorf("thresholds %q: %q is not an integer", s, part)
+		}
+		values[i] = n
+	}
+
+	t := Thresholds{
+		Best:        values[0],
+		Excellent:   values[1],
+		Good:        values[2],
+		Inaccuracy:  values[3],
+		Mistake:     values[4],
+		GarbageWin:  GarbageTimeThreshold,
+		GarbageLoss: -GarbageTimeThreshold,
+	}
+	if len(values) == 7 {
+		t.GarbageWin, t.GarbageLoss = values[5], values[6]
+	}
+	return t, t.Validate()
+}
+
+// Accuracy Calculation Constants
+const (
+	// MaxCPLossPerMove caps the centipawn loss per move for accuracy calculation
+	// This prevents a single catastrophic blunder from destroying the accuracy score
+	MaxCPLossPerMove = 500.0
+
+	// WinningThreshold: Position considered winning (in centipawns)
+	WinningThreshold = 200
+
+	// DeadDrawThreshold: Position is likely a draw (in centipawns)
+	DeadDrawThreshold = 20
+
+	// MateScore: Stockfish returns this when mate is found
+	MateScore = 10000
+)
+
+// AccuracyMethod selects how a player's game accuracy is calculated
+type AccuracyMethod string
+
+const (
+	// AccuracyCappedLoss is 100 minus the average centipawn loss, capped at
+	// MaxCPLossPerMove per move, as a percentage of that cap
+	AccuracyCappedLoss AccuracyMethod = "capped_loss"
+
+	// AccuracyMoveMean is the mean of the per-move accuracies, skipping
+	// forced and book moves
+	AccuracyMoveMean AccuracyMethod = "move_mean"
+)
+
+// Valid reports whether m is a known method
+func (m AccuracyMethod) Valid() bool {
+	return m == AccuracyCappedLoss || m == AccuracyMoveMean
+}
+
+// Performance Rating Constants
+const (
+	// MaxPerformanceDiff bounds the rating difference a score can show,
+	// as FIDE does for perfect and zero scores
+	MaxPerformanceDiff = 400.0
+
+	// AccuracyTiebreakWeight is Elo per accuracy point away from 50%,
+	// so accuracy moves a performance by at most 25
+	AccuracyTiebreakWeight = 0.5
+)
+
+// Linear performance rating constants, used by the deprecated
+// CalculatePerformanceRatingLinear
+const (
+	// BasePerformanceBonus for a win
+	WinBonus = 400
+
+	// BasePerformancePenalty for a loss
+	LossPenalty = -400
+
+	// DrawAdjustment for draws
+	DrawAdjustment = 0
+
+	// AccuracyWeight determines how much accuracy affects performance
+	AccuracyWeight = 8.0
+)
+
+// === TYPES ===
+
+// MoveClassification represents the quality of a chess move
+type MoveClassification string
+
+const (
+	ClassBrilliant  MoveClassification = "brilliant"
+	ClassGreat      MoveClassification = "great"
+	ClassBest       MoveClassification = "best"
+	ClassExcellent  MoveClassification = "excellent"
+	ClassGood       MoveClassification = "good"
+	ClassBook       MoveClassification = "book"
+	ClassNormal     MoveClassification = "normal"
+	ClassInaccuracy MoveClassification = "inaccuracy"
+	ClassMistake    MoveClassification = "mistake"
+	ClassBlunder    MoveClassification = "blunder"
+	ClassMissedWin  MoveClassification = "missed_win"
+)
+
+// GameResult represents the outcome of a game
+type GameResult string
+
+const (
+	ResultWin  GameResult = "win"
+	ResultLoss GameResult = "loss"
+	ResultDraw GameResult = "draw"
+
+	// ResultUnknown is a game still being played or whose result wasn't
+	// given or couldn't be read, like a PGN ending in "*". It is not a
+	// draw: score-based figures leave it out.
+	ResultUnknown GameResult = ""
+)
+
+// Known reports whether the result is a win, loss or draw
+func (r GameResult) Known() bool {
+	return r == ResultWin || r == ResultLoss || r == ResultDraw
+}
+
+// MoveEvaluation contains evaluation data for a single move
+type MoveEvaluation struct {
+	Ply           int    // Half-move number (0-indexed)
+	MoveNumber    int    // Full move number (1-indexed)
+	Color         string // "white" or "black"
+	PlayedMove    string // Move in SAN notation
+	BestMove      string // Best move in SAN notation
+	EvalBefore    int    // Centipawn evaluation before move, from White's point of view
+	EvalAfter     int    // Centipawn evaluation after move, from White's point of view
+	IsMateScore   bool   // True if evaluation is mate score
+	MateIn        *int   // Moves to mate (nil if not mate)
+	CentipawnLoss int    // Loss in centipawns from played move
+	WasBestMove   bool   // True if played move was the best move
+
+	Classification MoveClassification // Classification, if already known
+	FEN            string             // Position before the move (optional)
+
+	// Depths EvalBefore and EvalAfter were searched to, for the
+	// uncertainty of the metrics; 0 when unknown
+	DepthBefore int
+	DepthAfter  int
+}
+
+// PlayerMetrics contains aggregated analysis metrics for one player
+type PlayerMetrics struct {
+	Accuracy          float64 // 0-100 percentage
+	ACPL              float64 // Average Centipawn Loss
+	TotalCPLoss       int     // Sum of all centipawn losses
+	Blunders          int     // Moves with >300cp loss
+	Mistakes          int     // Moves with 101-300cp loss
+	Inaccuracies      int     // Moves with 51-100cp loss
+	GoodMoves         int     // Moves with 26-50cp loss
+	ExcellentMoves    int     // Moves with 11-25cp loss
+	BestMoves         int     // Moves with ≤10cp loss
+	BrilliantMoves    int     // Exceptional moves (sacrifice + advantage)
+	BookMoves         int     // Opening book moves
+	TotalMoves        int     // Total moves analyzed
+	PerformanceRating int     // Estimated performance rating
+	T1Accuracy        float64 // Alternative T1 accuracy calculation
+	GarbageTimeMoves  int     // Moves left out of accuracy and ACPL, decided positions
+
+	// ExpectedPointsLost is the sum of the ExpectedPointsLoss of every
+	// move of the player's: the points they gave away over the game
+	ExpectedPointsLost float64
+
+	// Standard deviations of Accuracy and ACPL from the noise of
+	// evaluations at the depths searched, see EvalNoise
+	AccuracyStddev float64
+	ACPLStddev     float64
+
+	Resilience *Resilience // Nil when the result is unknown
+}
+
+// GameEvaluation contains complete evaluation for a game
+type GameEvaluation struct {
+	GameID       string
+	WhitePlayer  string
+	BlackPlayer  string
+	WhiteRating  int
+	BlackRating  int
+	Result       GameResult // From White's point of view
+	WhiteMetrics PlayerMetrics
+	BlackMetrics PlayerMetrics
+	Moves        []MoveEvaluation
+
+	ECO         string    // Opening ECO code, e.g. "C50"
+	OpeningName string    // Opening name, e.g. "Italian Game"
+	TimeClass   string    // "bullet", "blitz", "rapid", ...
+	PlayedAt    time.Time // Zero if unknown
+}
+
+// === CORE EVALUATION FUNCTIONS ===
+
+// ClassifyMove determines the classification of a move based on centipawn loss
+func ClassifyMove(cpLoss int, wasBestMove bool, evalBefore, evalAfter int, isMateScore bool, t Thresholds) MoveClassification {
+	// Best move gets best classification
+	if wasBestMove {
+		return ClassBest
+	}
+
+	// Check for missed win (was winning, now not)
+	if evalBefore >= WinningThreshold && evalAfter < WinningThreshold/2 {
+		return ClassMissedWin
+	}
+
+	// Classify by centipawn loss
+	switch {
+	case cpLoss <= t.Best:
+		return ClassBest
+	case cpLoss <= t.Excellent:
+		return ClassExcellent
+	case cpLoss <= t.Good:
+		return ClassGood
+	case cpLoss <= t.Inaccuracy:
+		return ClassInaccuracy
+	case cpLoss <= t.Mistake:
+		return ClassMistake
+	default:
+		return ClassBlunder
+	}
+}
+
+// IsBrilliantMove determines if a move qualifies as brilliant
+// A brilliant move is one that sacrifices material BUT leads to a winning position
+func IsBrilliantMove(evalBefore, evalAfter int, materialSacrificed int) bool {
+	// Must sacrifice meaningful material (at least a pawn = 100cp)
+	if materialSacrificed < 100 {
+		return false
+	}
+
+	// The position must improve or stay very strong after the sacrifice
+	// (i.e., the sacrifice works tactically)
+	evalImprovement := evalAfter - evalBefore
+
+	// Must be a good sacrifice: position improves significantly
+	// despite material loss, or maintains winning advantage
+	return evalImprovement >= 100 || evalAfter >= 300
+}
+
+// CalculateCentipawnLoss calculates the loss in centipawns for a move
+// Takes into account the side to move (positive is always good for the player)
+func CalculateCentipawnLoss(evalBefore, evalAfter int, isBlack bool) int {
+	// Adjust perspective: positive should mean good for the player
+	if isBlack {
+		evalBefore = -evalBefore
+		evalAfter = -evalAfter
+	}
+
+	// Centipawn loss is how much the position worsened
+	loss := evalBefore - evalAfter
+
+	// Can't have negative loss (improvement is 0 loss)
+	if loss < 0 {
+		return 0
+	}
+
+	return loss
+}
+
+// LossTotals accumulates the centipawn losses of a player's moves. It is
+// the one place accuracy and ACPL are calculated, for whole games here and
+// move by move by the analyzer, so every surface shows the same numbers.
+// The zero value is ready to use.
+type LossTotals struct {
+	Loss       float64 // Sum of the centipawn losses
+	CappedLoss float64 // Sum of the losses capped at MaxCPLossPerMove
+	Moves      int
+
+	// Variances of Loss and CappedLoss from the noise of the evaluations
+	LossVariance       float64
+	CappedLossVariance float64
+}
+
+// Add counts a move that lost cpLoss centipawns
+func (t *LossTotals) Add(cpLoss int) {
+	t.AddUncertain(cpLoss, 0)
+}
+
+// AddUncertain counts a move that lost cpLoss centipawns, give or take a
+// standard deviation of noise, see LossNoise
+func (t *LossTotals) AddUncertain(cpLoss int, noise float64) {
+	t.Loss += float64(cpLoss)
+	// Cap the loss per move to prevent catastrophic blunders from
+	// completely destroying the accuracy score
+	t.CappedLoss += math.Min(float64(cpLoss), MaxCPLossPerMove)
+	t.Moves++
+
+	// A loss past the cap stays capped whatever the noise
+	t.LossVariance += noise * noise
+	if float64(cpLoss) < MaxCPLossPerMove {
+		t.CappedLossVariance += noise * noise
+	}
+}
+
+// ACPL returns the average centipawn loss, 0 without moves
+func (t LossTotals) ACPL() float64 {
+	if t.Moves == 0 {
+		return 0.0
+	}
+	return t.Loss / float64(t.Moves)
+}
+
+// Accuracy returns 100 - (CappedLoss / MaxPossibleLoss) * 100, in percent,
+// 100 without moves
+func (t LossTotals) Accuracy() float64 {
+	if t.Moves == 0 {
+		return 100.0
+	}
+
+	// Maximum possible loss (if every move was MaxCPLossPerMove)
+	maxPossibleLoss := float64(t.Moves) * MaxCPLossPerMove
+
+	// Calculate accuracy percentage
+	accuracy := 100.0 - (t.CappedLoss/maxPossibleLoss)*100.0
+
+	// Clamp to 0-100 range
+	return math.Max(0, math.Min(100, accuracy))
+}
+
+// ACPLStddev returns the standard deviation of ACPL, 0 without moves
+func (t LossTotals) ACPLStddev() float64 {
+	if t.Moves == 0 {
+		return 0
+	}
+	return math.Sqrt(t.LossVariance) / float64(t.Moves)
+}
+
+// AccuracyStddev returns the standard deviation of Accuracy, in percentage
+// points, 0 without moves
+func (t LossTotals) AccuracyStddev() float64 {
+	if t.Moves == 0 {
+		return 0
+	}
+	return math.Sqrt(t.CappedLossVariance) / (float64(t.Moves) * MaxCPLossPerMove) * 100
+}
+
+// lossTotals adds up color's moves
+func lossTotals(moves []MoveEvaluation, color string) LossTotals {
+	var totals LossTotals
+	for _, move := range moves {
+		if move.Color == color {
+			totals.AddUncertain(move.CentipawnLoss, LossNoise(move.DepthBefore, move.DepthAfter))
+		}
+	}
+	return totals
+}
+
+// CalculateACPL calculates Average Centipawn Loss for a set of moves
+func CalculateACPL(moves []MoveEvaluation, color string) float64 {
+	return lossTotals(moves, color).ACPL()
+}
+
+// CalculateAccuracy calculates the accuracy percentage for a set of moves
+// Uses the formula: Accuracy = 100 - (TotalLoss / MaxPossibleLoss) * 100
+// with a cap on loss per move to prevent single blunders from destroying the score
+func CalculateAccuracy(moves []MoveEvaluation, color string) float64 {
+	return lossTotals(moves, color).Accuracy()
+}
+
+// RoundAccuracy rounds an accuracy, in percent, to the one decimal it is
+// reported with
+func RoundAccuracy(accuracy float64) float64 {
+	return math.Round(accuracy*10) / 10
+}
+
+// RoundACPL rounds an average centipawn loss to the one decimal it is
+// reported with
+func RoundACPL(acpl float64) float64 {
+	return math.Round(acpl*10) / 10
+}
+
+// RoundExpectedPoints rounds expected points to the two decimals they are
+// reported with
+func RoundExpectedPoints(points float64) float64 {
+	return math.Round(points*100) / 100
+}
+
+// CalculateT1Accuracy calculates accuracy using Lichess's T1 formula
+// This provides a different perspective on accuracy that's more forgiving
+// Formula: 103.1668 * exp(-0.04354 * ACPL) - 3.1669
+func CalculateT1Accuracy(acpl float64) float64 {
+	if acpl <= 0 {
+		return 100.0
+	}
+
+	accuracy := 103.1668*math.Exp(-0.04354*acpl) - 3.1669
+
+	return math.Max(0, math.Min(100, accuracy))
+}
+
+// CalculateMoveAccuracy returns the accuracy of a single move, 0-100, from
+// how much it dropped the mover's win probability, using the T1 curve on the
+// drop in percentage points. Evaluations are centipawns from the mover's
+// point of view before and after the move, mates normalized with
+// NormalizeMateScore. A move that keeps or improves the position scores 100.
+func CalculateMoveAccuracy(evalBefore, evalAfter int) float64 {
+	drop := (EvalToWinProbability(evalBefore) - EvalToWinProbability(evalAfter)) * 100
+	if drop <= 0 {
+		return 100.0
+	}
+
+	accuracy := 103.1668*math.Exp(-0.04354*drop) - 3.1669
+
+	return math.Max(0, math.Min(100, accuracy))
+}
+
+// ExpectedPoints returns the points the side to move can expect, a win
+// counting 1 and a draw half, from an evaluation in centipawns from its
+// point of view, mates normalized with NormalizeMateScore. Without the
+// engine's win/draw/loss estimate it is EvalToWinProbability, whose curve
+// is fitted to game results with draws counting half.
+func ExpectedPoints(centipawns int) float64 {
+	return EvalToWinProbability(centipawns)
+}
+
+// ExpectedPointsLoss returns the expected points a move gave away, 0-1,
+// from the mover's evaluations before and after it as for
+// CalculateMoveAccuracy. A move that keeps or improves the position gives
+// nothing away.
+func ExpectedPointsLoss(evalBefore, evalAfter int) float64 {
+	return math.Max(0, ExpectedPoints(evalBefore)-ExpectedPoints(evalAfter))
+}
+
+// CalculatePerformanceRating estimates the player's performance rating for
+// one game: the rating at which the Elo expected score against the
+// opponent equals the score made, bounded to the opponent's rating
+// ±MaxPerformanceDiff. Accuracy only breaks ties between equal results, by
+// at most ±25. Returns 0 when the opponent is unrated (rating 0 or less) or
+// the result isn't known.
+func CalculatePerformanceRating(opponentRating int, accuracy float64, result GameResult) int {
+	if opponentRating <= 0 || !result.Known() {
+		return 0
+	}
+
+	diff := PerformanceDifference(ResultScore(result))
+	tiebreak := (math.Max(0, math.Min(100, accuracy)) - 50.0) * AccuracyTiebreakWeight
+
+	return int(math.Round(float64(opponentRating) + diff + tiebreak))
+}
+
+// ResultScore converts a result to points: 1 for a win, 0.5 for a draw
+// and 0 for a loss. An unknown result scores 0; check Known first.
+func ResultScore(result GameResult) float64 {
+	switch result {
+	case ResultWin:
+		return 1
+	case ResultDraw:
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+// ExpectedScore returns the Elo expected score of a player against an
+// opponent, 0-1
+func ExpectedScore(rating, opponentRating int) float64 {
+	return 1.0 / (1.0 + math.Pow(10, float64(opponentRating-rating)/400.0))
+}
+
+// PerformanceDifference returns the rating difference to the opponents at
+// which score (points per game, 0-1) is the expected score,
+// 400·log10(score/(1-score)), bounded to ±MaxPerformanceDiff
+func PerformanceDifference(score float64) float64 {
+	if score <= 0 {
+		return -MaxPerformanceDiff
+	}
+	if score >= 1 {
+		return MaxPerformanceDiff
+	}
+	diff := 400.0 * math.Log10(score/(1-score))
+	return math.Max(-MaxPerformanceDiff, math.Min(MaxPerformanceDiff, diff))
+}
+
+// CalculatePerformanceRatingLinear is the former performance estimate,
+// opponent rating ±400 for the result plus 8 points per accuracy point
+// away from 50%, which lets accuracy dominate.
+//
+// Deprecated: use CalculatePerformanceRating. This will be removed in the
+// next release.
+func CalculatePerformanceRatingLinear(opponentRating int, accuracy float64, result GameResult) int {
+	baseRating := float64(opponentRating)
+
+	// Accuracy bonus: higher accuracy = higher performance
+	// Scale: accuracy of 50% = 0 bonus, 100% = +400, 0% = -400
+	accuracyBonus := (accuracy - 50.0) * AccuracyWeight
+
+	// Result adjustment
+	var resultBonus float64
+	switch result {
+	case ResultWin:
+		resultBonus = float64(WinBonus)
+	case ResultLoss:
+		resultBonus = float64(LossPenalty)
+	case ResultDraw:
+		resultBonus = float64(DrawAdjustment)
+	}
+
+	performance := baseRating + accuracyBonus + resultBonus
+	return int(math.Round(performance))
+}
+
+// CountMovesByClassification counts moves in each classification category
+func CountMovesByClassification(moves []MoveEvaluation, color string, t Thresholds) map[MoveClassification]int {
+	counts := make(map[MoveClassification]int)
+
+	for _, move := range moves {
+		if move.Color != color {
+			continue
+		}
+
+		if move.Classification == ClassBook {
+			counts[ClassBook]++
+			continue
+		}
+		classification := ClassifyMove(
+			move.CentipawnLoss,
+			move.WasBestMove,
+			move.EvalBefore,
+			move.EvalAfter,
+			move.IsMateScore,
+			t,
+		)
+		counts[classification]++
+	}
+
+	return counts
+}
+
+// CalculatePlayerMetrics calculates all metrics for a player. Moves in
+// garbage time under t are counted and classified but left out of the
+// loss-based metrics; pass t.WithoutGarbageTime() to include them. Moves
+// already classified ClassBook are counted as book moves and left out too.
+func CalculatePlayerMetrics(moves []MoveEvaluation, color string, opponentRating int, result GameResult, t Thresholds) PlayerMetrics {
+	metrics := PlayerMetrics{}
+
+	var totalCPLoss int
+	var moveCount int
+	var counted []MoveEvaluation
+
+	for _, move := range moves {
+		if move.Color != color {
+			continue
+		}
+
+		moveCount++
+		metrics.ExpectedPointsLost += ExpectedPointsLoss(moverEval(move.EvalBefore, color), moverEval(move.EvalAfter, color))
+		book := move.Classification == ClassBook
+		if t.IsGarbageTime(moverEval(move.EvalBefore, color)) {
+			metrics.GarbageTimeMoves++
+		} else if !book {
+			totalCPLoss += move.CentipawnLoss
+			counted = append(counted, move)
+		}
+
+		// Classify and count; book moves keep their classification
+		classification := ClassBook
+		if !book {
+			classification = ClassifyMove(
+				move.CentipawnLoss,
+				move.WasBestMove,
+				move.EvalBefore,
+				move.EvalAfter,
+				move.IsMateScore,
+				t,
+			)
+		}
+
+		switch classification {
+		case ClassBrilliant:
+			metrics.BrilliantMoves++
+		case ClassBest:
+			metrics.BestMoves++
+		case ClassExcellent:
+			metrics.ExcellentMoves++
+		case ClassGood:
+			metrics.GoodMoves++
+		case ClassBook:
+			metrics.BookMoves++
+		case ClassInaccuracy:
+			metrics.Inaccuracies++
+		case ClassMistake:
+			metrics.Mistakes++
+		case ClassBlunder, ClassMissedWin:
+			metrics.Blunders++
+		}
+	}
+
+	metrics.TotalMoves = moveCount
+	metrics.TotalCPLoss = totalCPLoss
+
+	if len(counted) > 0 {
+		totals := lossTotals(counted, color)
+		metrics.ACPL = totals.ACPL()
+		metrics.Accuracy = totals.Accuracy()
+		metrics.ACPLStddev = totals.ACPLStddev()
+		metrics.AccuracyStddev = totals.AccuracyStddev()
+		metrics.T1Accuracy = CalculateT1Accuracy(metrics.ACPL)
+	} else {
+		metrics.Accuracy = 100.0
+		metrics.T1Accuracy = 100.0
+	}
+	if moveCount > 0 {
+		metrics.PerformanceRating = CalculatePerformanceRating(opponentRating, metrics.Accuracy, result)
+	}
+	metrics.Resilience = CalculateResilience(moves, color, result, t)
+
+	return metrics
+}
+
+// === HELPER FUNCTIONS ===
+
+// moverEval converts an evaluation from White's point of view to color's
+func moverEval(eval int, color string) int {
+	if color == "black" {
+		return -eval
+	}
+	return eval
+}
+
+// NormalizeMateScore converts mate scores to a large centipawn value
+// Positive = side to move is mating, Negative = side to move is getting mated,
+// 0 = side to move is checkmated
+func NormalizeMateScore(mateIn int) int {
+	if mateIn > 0 {
+		// Mating: return large positive value, decreasing as mate is further away
+		return MateScore - mateIn
+	}
+	// Getting mated: return large negative value
+	return -MateScore - mateIn
+}
+
+// EvalToWinProbability converts centipawn evaluation to winning probability
+// Uses the logistic function that approximates real game outcomes
+func EvalToWinProbability(centipawns int) float64 {
+	// Logistic function: P(win) = 1 / (1 + 10^(-cp/400))
+	// This is based on empirical chess data
+	exponent := float64(-centipawns) / 400.0
+	return 1.0 / (1.0 + math.Pow(10, exponent))
+}
+
+// WinProbabilityToElo converts win probability difference to Elo difference
+func WinProbabilityToElo(winProbDiff float64) float64 {
+	// Elo formula: difference = 400 * log10(P / (1 - P))
+	if winProbDiff <= 0 {
+		return -400.0
+	}
+	if winProbDiff >= 1 {
+		return 400.0
+	}
+	return 400.0 * math.Log10(winProbDiff/(1-winProbDiff))
+}
+
+// Book move heuristic, until moves are checked against an opening book
+const (
+	BookEvalWindow = 50 // Most a book move changes the mover's evaluation by, in centipawns
+	BookTopMoves   = 3  // A book move is one of the engine's this many best moves
+)
+
+// IsBookMove reports whether the move at ply (0-indexed) can be a book
+// move: one of the first bookPlies plies, changing the mover's evaluation
+// by evalChange centipawns, at most BookEvalWindow either way. The caller
+// also checks that it is one of the engine's BookTopMoves best moves.
+func IsBookMove(ply, bookPlies, evalChange int) bool {
+	return ply < bookPlies && evalChange >= -BookEvalWindow && evalChange <= BookEvalWindow
+}
+
+// CalculateComplexity estimates the complexity of a position
+// based on evaluation variance across top moves
+func CalculateComplexity(topEvals []int) float64 {
+	if len(topEvals) < 2 {
+		return 0.0
+	}
+
+	// Variance in evaluations = complexity
+	var sum, sumSq float64
+	for _, e := range topEvals {
+		sum += float64(e)
+		sumSq += float64(e * e)
+	}
+
+	n := float64(len(topEvals))
+	mean := sum / n
+	variance := (sumSq / n) - (mean * mean)
+
+	return math.Sqrt(variance)
+}
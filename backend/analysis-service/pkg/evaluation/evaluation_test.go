@@ -24,7 +24,7 @@ func TestClassifyMove_Best(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ClassifyMove(tt.cpLoss, tt.wasBest, tt.evalBefore, tt.evalAfter, false)
+			got := ClassifyMove(tt.cpLoss, tt.wasBest, tt.evalBefore, tt.evalAfter, false, DefaultThresholds)
 			if got != tt.want {
 				t.Errorf("ClassifyMove() = %v, want %v", got, tt.want)
 			}
@@ -45,7 +45,7 @@ func TestClassifyMove_Excellent(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ClassifyMove(tt.cpLoss, false, 100, 100-tt.cpLoss, false)
+			got := ClassifyMove(tt.cpLoss, false, 100, 100-tt.cpLoss, false, DefaultThresholds)
 			if got != tt.want {
 				t.Errorf("ClassifyMove() = %v, want %v", got, tt.want)
 			}
@@ -66,7 +66,7 @@ func TestClassifyMove_Good(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ClassifyMove(tt.cpLoss, false, 100, 100-tt.cpLoss, false)
+			got := ClassifyMove(tt.cpLoss, false, 100, 100-tt.cpLoss, false, DefaultThresholds)
 			if got != tt.want {
 				t.Errorf("ClassifyMove() = %v, want %v", got, tt.want)
 			}
@@ -87,7 +87,7 @@ func TestClassifyMove_Inaccuracy(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ClassifyMove(tt.cpLoss, false, 200, 200-tt.cpLoss, false)
+			got := ClassifyMove(tt.cpLoss, false, 200, 200-tt.cpLoss, false, DefaultThresholds)
 			if got != tt.want {
 				t.Errorf("ClassifyMove() = %v, want %v", got, tt.want)
 			}
@@ -108,7 +108,7 @@ func TestClassifyMove_Mistake(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ClassifyMove(tt.cpLoss, false, 400, 400-tt.cpLoss, false)
+			got := ClassifyMove(tt.cpLoss, false, 400, 400-tt.cpLoss, false, DefaultThresholds)
 			if got != tt.want {
 				t.Errorf("ClassifyMove() = %v, want %v", got, tt.want)
 			}
@@ -133,7 +133,7 @@ func TestClassifyMove_Blunder(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ClassifyMove(tt.cpLoss, false, tt.evalBefore, tt.evalAfter, false)
+			got := ClassifyMove(tt.cpLoss, false, tt.evalBefore, tt.evalAfter, false, DefaultThresholds)
 			if got != tt.want {
 				t.Errorf("ClassifyMove() = %v, want %v", got, tt.want)
 			}
@@ -143,7 +143,7 @@ func TestClassifyMove_Blunder(t *testing.T) {
 
 func TestClassifyMove_MissedWin(t *testing.T) {
 	// Missed win: was winning (eval >= 200), now not (eval < 100)
-	got := ClassifyMove(400, false, 300, 50, false)
+	got := ClassifyMove(400, false, 300, 50, false, DefaultThresholds)
 	if got != ClassMissedWin {
 		t.Errorf("ClassifyMove() = %v, want ClassMissedWin", got)
 	}
@@ -325,6 +325,35 @@ func TestCalculateT1Accuracy(t *testing.T) {
 	}
 }
 
+func TestCalculateMoveAccuracy(t *testing.T) {
+	tests := []struct {
+		name       string
+		evalBefore int
+		evalAfter  int
+		want       float64
+	}{
+		{"no change", 0, 0, 100},
+		{"improvement", 50, 100, 100},
+		{"small drop", 0, -20, 87.86},
+		{"one pawn", 0, -100, 52.90},
+		{"three pawns", 100, -200, 14.93},
+		{"winning to losing", 300, -300, 1.77},
+		{"already decided", 2000, 1500, 99.92},
+		{"mate kept", NormalizeMateScore(3), NormalizeMateScore(1), 100},
+		{"mate lost to +3", NormalizeMateScore(2), 300, 50.30},
+		{"getting mated anyway", NormalizeMateScore(-2), NormalizeMateScore(-4), 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateMoveAccuracy(tt.evalBefore, tt.evalAfter)
+			if math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("CalculateMoveAccuracy(%d, %d) = %.2f, want %.2f", tt.evalBefore, tt.evalAfter, got, tt.want)
+			}
+		})
+	}
+}
+
 // === PERFORMANCE RATING TESTS ===
 
 func TestCalculatePerformanceRating(t *testing.T) {
@@ -336,38 +365,14 @@ func TestCalculatePerformanceRating(t *testing.T) {
 		minExpected    int
 		maxExpected    int
 	}{
-		{
-			name:           "high accuracy win",
-			opponentRating: 1500,
-			accuracy:       90.0,
-			result:         ResultWin,
-			minExpected:    2100, // 1500 + 320 (acc bonus) + 400 (win)
-			maxExpected:    2300,
-		},
-		{
-			name:           "average accuracy draw",
-			opponentRating: 1500,
-			accuracy:       50.0,
-			result:         ResultDraw,
-			minExpected:    1400,
-			maxExpected:    1600,
-		},
-		{
-			name:           "low accuracy loss",
-			opponentRating: 1500,
-			accuracy:       30.0,
-			result:         ResultLoss,
-			minExpected:    800, // 1500 - 160 (acc penalty) - 400 (loss)
-			maxExpected:    1100,
-		},
-		{
-			name:           "grandmaster level",
-			opponentRating: 2700,
-			accuracy:       95.0,
-			result:         ResultWin,
-			minExpected:    3300,
-			maxExpected:    3600,
-		},
+		{"high accuracy win", 1500, 90.0, ResultWin, 1915, 1925},      // 1500 + 400 + 20
+		{"average accuracy draw", 1500, 50.0, ResultDraw, 1500, 1500}, // Expected score 0.5
+		{"low accuracy loss", 1500, 30.0, ResultLoss, 1085, 1095},     // 1500 - 400 - 10
+		{"grandmaster level", 2700, 95.0, ResultWin, 3120, 3125},      // 2700 + 400 + 22.5
+		{"perfect loss", 1500, 100.0, ResultLoss, 1125, 1125},         // Accuracy can't undo the result
+		{"sloppy win", 1500, 0.0, ResultWin, 1875, 1875},
+		{"unrated opponent", 0, 90.0, ResultWin, 0, 0},
+		{"unknown result", 1500, 90.0, ResultUnknown, 0, 0},
 	}
 
 	for _, tt := range tests {
@@ -380,6 +385,52 @@ func TestCalculatePerformanceRating(t *testing.T) {
 	}
 }
 
+func TestCalculatePerformanceRating_ResultDominates(t *testing.T) {
+	// Any win performs better than any draw, any draw better than any loss
+	worstWin := CalculatePerformanceRating(1500, 0, ResultWin)
+	bestDraw := CalculatePerformanceRating(1500, 100, ResultDraw)
+	worstDraw := CalculatePerformanceRating(1500, 0, ResultDraw)
+	bestLoss := CalculatePerformanceRating(1500, 100, ResultLoss)
+
+	if worstWin <= bestDraw || worstDraw <= bestLoss {
+		t.Errorf("win %d / draw %d..%d / loss %d overlap", worstWin, worstDraw, bestDraw, bestLoss)
+	}
+}
+
+func TestPerformanceDifference(t *testing.T) {
+	tests := []struct {
+		score float64
+		want  float64
+	}{
+		{0, -400},
+		{0.5, 0},
+		{1, 400},
+		{0.75, 190.85},
+		{0.25, -190.85},
+		{0.99, 400}, // Bounded
+	}
+
+	for _, tt := range tests {
+		got := PerformanceDifference(tt.score)
+		if math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("PerformanceDifference(%v) = %.2f, want %.2f", tt.score, got, tt.want)
+		}
+		// The difference is where the expected score equals the score
+		if tt.score > 0 && tt.score < 1 && math.Abs(got) < MaxPerformanceDiff {
+			if e := ExpectedScore(1500+int(math.Round(got)), 1500); math.Abs(e-tt.score) > 0.001 {
+				t.Errorf("ExpectedScore at %+.0f = %v, want %v", got, e, tt.score)
+			}
+		}
+	}
+}
+
+func TestCalculatePerformanceRatingLinear(t *testing.T) {
+	// The deprecated formula is kept as it was
+	if got := CalculatePerformanceRatingLinear(1500, 90, ResultWin); got != 2220 {
+		t.Errorf("CalculatePerformanceRatingLinear() = %d, want 2220", got)
+	}
+}
+
 // === BRILLIANT MOVE TESTS ===
 
 func TestIsBrilliantMove(t *testing.T) {
@@ -479,6 +530,158 @@ func TestCalculateComplexity(t *testing.T) {
 	}
 }
 
+// === THRESHOLD TESTS ===
+
+func TestClassifyMove_Profiles(t *testing.T) {
+	profiles := DefaultProfiles()
+
+	tests := []struct {
+		profile string
+		cpLoss  int
+		want    MoveClassification
+	}{
+		{ProfileStandard, 40, ClassGood},
+		{ProfileStrict, 40, ClassInaccuracy},
+		{ProfileLenient, 40, ClassExcellent},
+		{ProfileStandard, 250, ClassMistake},
+		{ProfileStrict, 250, ClassBlunder},
+		{ProfileLenient, 120, ClassInaccuracy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			got := ClassifyMove(tt.cpLoss, false, 0, -tt.cpLoss, false, profiles[tt.profile])
+			if got != tt.want {
+				t.Errorf("ClassifyMove(%d) with %s = %v, want %v", tt.cpLoss, tt.profile, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultProfiles_Valid(t *testing.T) {
+	for name, th := range DefaultProfiles() {
+		if err := th.Validate(); err != nil {
+			t.Errorf("profile %s: %v", name, err)
+		}
+	}
+	if DefaultProfiles()[ProfileStandard] != DefaultThresholds {
+		t.Error("standard profile should equal DefaultThresholds")
+	}
+}
+
+func TestParseThresholds(t *testing.T) {
+	got, err := ParseThresholds("5, 15,30,60,200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != DefaultProfiles()[ProfileStrict] {
+		t.Errorf("ParseThresholds() = %+v", got)
+	}
+	if got.String() != "5,15,30,60,200" {
+		t.Errorf("String() = %q", got.String())
+	}
+
+	got, err = ParseThresholds("5,15,30,60,200,600,-1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GarbageWin != 600 || got.GarbageLoss != -1000 || got.String() != "5,15,30,60,200,600,-1000" {
+		t.Errorf("ParseThresholds() = %+v", got)
+	}
+
+	for _, bad := range []string{"", "10,25,50,100", "10,25,x,100,300", "10,25,25,100,300", "-1,25,50,100,300", "10,25,50,100,300,800", "10,25,50,100,300,-800,800"} {
+		if _, err := ParseThresholds(bad); err == nil {
+			t.Errorf("ParseThresholds(%q) should fail", bad)
+		}
+	}
+}
+
+func TestCalculatePlayerMetrics_Thresholds(t *testing.T) {
+	moves := []MoveEvaluation{
+		{Color: "white", CentipawnLoss: 40, EvalBefore: 0, EvalAfter: -40},
+		{Color: "white", CentipawnLoss: 250, EvalBefore: 0, EvalAfter: -250},
+	}
+
+	standard := CalculatePlayerMetrics(moves, "white", 1500, ResultDraw, DefaultThresholds)
+	strict := CalculatePlayerMetrics(moves, "white", 1500, ResultDraw, DefaultProfiles()[ProfileStrict])
+
+	if standard.GoodMoves != 1 || standard.Mistakes != 1 {
+		t.Errorf("standard: good=%d mistakes=%d, want 1 and 1", standard.GoodMoves, standard.Mistakes)
+	}
+	if strict.Inaccuracies != 1 || strict.Blunders != 1 {
+		t.Errorf("strict: inaccuracies=%d blunders=%d, want 1 and 1", strict.Inaccuracies, strict.Blunders)
+	}
+	// Loss-based metrics don't depend on classification
+	if standard.ACPL != strict.ACPL || standard.Accuracy != strict.Accuracy {
+		t.Error("ACPL and accuracy should not depend on thresholds")
+	}
+}
+
+func TestCalculatePlayerMetrics_GarbageTime(t *testing.T) {
+	// Evaluations are White's; White is up a queen from the third move
+	moves := []MoveEvaluation{
+		{Color: "white", CentipawnLoss: 20, EvalBefore: 50, EvalAfter: 30},
+		{Color: "black", CentipawnLoss: 900, EvalBefore: 30, EvalAfter: 930},
+		{Color: "white", CentipawnLoss: 400, EvalBefore: 930, EvalAfter: 530},
+		{Color: "black", CentipawnLoss: 60, EvalBefore: 530, EvalAfter: 590},
+		{Color: "white", CentipawnLoss: 300, EvalBefore: 1000, EvalAfter: 700},
+		{Color: "black", CentipawnLoss: 80, EvalBefore: 900, EvalAfter: 980},
+	}
+
+	white := CalculatePlayerMetrics(moves, "white", 1500, ResultWin, DefaultThresholds)
+	black := CalculatePlayerMetrics(moves, "black", 1500, ResultLoss, DefaultThresholds)
+
+	// Taking a slower win is still classified but doesn't cost accuracy
+	if white.GarbageTimeMoves != 2 || white.TotalMoves != 3 || white.ACPL != 20 || white.Mistakes != 1 || white.Blunders != 1 {
+		t.Errorf("white = %+v", white)
+	}
+	// Black's moves after the blunder are made from -930 and -900
+	if black.GarbageTimeMoves != 1 || black.ACPL != 480 {
+		t.Errorf("black = %+v", black)
+	}
+
+	all := CalculatePlayerMetrics(moves, "white", 1500, ResultWin, DefaultThresholds.WithoutGarbageTime())
+	if all.GarbageTimeMoves != 0 || all.ACPL != 240 || all.Accuracy >= white.Accuracy {
+		t.Errorf("without garbage time = %+v", all)
+	}
+}
+
+func TestCalculatePlayerMetrics_BookMoves(t *testing.T) {
+	moves := []MoveEvaluation{
+		{Color: "white", CentipawnLoss: 40, EvalBefore: 30, EvalAfter: -10, Classification: ClassBook},
+		{Color: "black", CentipawnLoss: 0, EvalBefore: -10, EvalAfter: -10, Classification: ClassBook},
+		{Color: "white", CentipawnLoss: 20, EvalBefore: -10, EvalAfter: -30},
+		{Color: "black", CentipawnLoss: 10, EvalBefore: -30, EvalAfter: -20},
+	}
+
+	white := CalculatePlayerMetrics(moves, "white", 1500, ResultDraw, DefaultThresholds)
+	if white.BookMoves != 1 || white.TotalMoves != 2 || white.ACPL != 20 || white.TotalCPLoss != 20 {
+		t.Errorf("white = %+v, want one book move left out of ACPL", white)
+	}
+	if counts := CountMovesByClassification(moves, "black", DefaultThresholds); counts[ClassBook] != 1 {
+		t.Errorf("black counts = %v, want one book move", counts)
+	}
+}
+
+func TestIsBookMove(t *testing.T) {
+	tests := []struct {
+		ply, bookPlies, change int
+		want                   bool
+	}{
+		{0, 20, 0, true},
+		{19, 20, -50, true},
+		{20, 20, 0, false},
+		{4, 20, 51, false},
+		{4, 20, -80, false},
+		{0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		if got := IsBookMove(tt.ply, tt.bookPlies, tt.change); got != tt.want {
+			t.Errorf("IsBookMove(%d, %d, %d) = %v, want %v", tt.ply, tt.bookPlies, tt.change, got, tt.want)
+		}
+	}
+}
+
 // === INTEGRATION TESTS ===
 
 func TestCalculatePlayerMetrics(t *testing.T) {
@@ -491,8 +694,8 @@ func TestCalculatePlayerMetrics(t *testing.T) {
 		{Color: "black", CentipawnLoss: 500, WasBestMove: false, EvalBefore: 50, EvalAfter: -450},
 	}
 
-	whiteMetrics := CalculatePlayerMetrics(moves, "white", 1500, ResultWin)
-	blackMetrics := CalculatePlayerMetrics(moves, "black", 1500, ResultLoss)
+	whiteMetrics := CalculatePlayerMetrics(moves, "white", 1500, ResultWin, DefaultThresholds)
+	blackMetrics := CalculatePlayerMetrics(moves, "black", 1500, ResultLoss, DefaultThresholds)
 
 	// White should have much better metrics
 	if whiteMetrics.Accuracy <= blackMetrics.Accuracy {
@@ -555,7 +758,7 @@ func BenchmarkCalculateAccuracy(b *testing.B) {
 func BenchmarkClassifyMove(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		ClassifyMove(150, false, 200, 50, false)
+		ClassifyMove(150, false, 200, 50, false, DefaultThresholds)
 	}
 }
 
@@ -576,6 +779,52 @@ func BenchmarkCalculatePlayerMetrics(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		CalculatePlayerMetrics(moves, "white", 1500, ResultWin)
+		CalculatePlayerMetrics(moves, "white", 1500, ResultWin, DefaultThresholds)
+	}
+}
+
+func TestExpectedPointsLoss(t *testing.T) {
+	// Expected points are 1/(1+10^(-cp/400)), a win counting 1
+	tests := []struct {
+		name          string
+		before, after int
+		want          float64
+	}{
+		{"won to equal", 300, 0, 0.3490},
+		{"better to worse", 100, -100, 0.2801},
+		{"mate let go", NormalizeMateScore(3), 0, 0.5},
+		{"mated instead of worse", -200, NormalizeMateScore(-2), 0.2403},
+		{"improved", 50, 80, 0},
+		{"kept", 120, 120, 0},
+	}
+	for _, tt := range tests {
+		if got := ExpectedPointsLoss(tt.before, tt.after); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("%s: ExpectedPointsLoss(%d, %d) = %v, want %v", tt.name, tt.before, tt.after, got, tt.want)
+		}
+	}
+	if got := ExpectedPoints(0); got != 0.5 {
+		t.Errorf("ExpectedPoints(0) = %v, want 0.5", got)
+	}
+	if got := RoundExpectedPoints(0.3490204); got != 0.35 {
+		t.Errorf("RoundExpectedPoints = %v, want 0.35", got)
+	}
+}
+
+func TestCalculatePlayerMetrics_ExpectedPointsLost(t *testing.T) {
+	// Evaluations are White's: White throws away +300, Black then +100
+	moves := []MoveEvaluation{
+		{Color: "white", CentipawnLoss: 300, EvalBefore: 300, EvalAfter: 0},
+		{Color: "black", CentipawnLoss: 0, EvalBefore: 0, EvalAfter: -100},
+		{Color: "white", CentipawnLoss: 0, EvalBefore: -100, EvalAfter: -90},
+		{Color: "black", CentipawnLoss: 200, EvalBefore: -100, EvalAfter: 100},
+	}
+
+	white := CalculatePlayerMetrics(moves, "white", 0, ResultDraw, DefaultThresholds)
+	black := CalculatePlayerMetrics(moves, "black", 0, ResultDraw, DefaultThresholds)
+	if math.Abs(white.ExpectedPointsLost-0.3490) > 1e-4 {
+		t.Errorf("white lost %v expected points, want 0.3490", white.ExpectedPointsLost)
+	}
+	if math.Abs(black.ExpectedPointsLost-0.2801) > 1e-4 {
+		t.Errorf("black lost %v expected points, want 0.2801", black.ExpectedPointsLost)
 	}
 }
@@ -0,0 +1,25 @@
+package evaluation_test
+
+import (
+	"fmt"
+
+	"github.com/eloinsight/analysis-service/pkg/evaluation"
+)
+
+func ExampleCalculatePlayerMetrics() {
+	// Evaluations are from White's point of view
+	moves := []evaluation.MoveEvaluation{
+		{Color: "white", CentipawnLoss: 0, WasBestMove: true, EvalBefore: 30, EvalAfter: 30},
+		{Color: "black", CentipawnLoss: 120, EvalBefore: 30, EvalAfter: 150},
+		{Color: "white", CentipawnLoss: 40, EvalBefore: 150, EvalAfter: 110},
+	}
+
+	white := evaluation.CalculatePlayerMetrics(moves, "white", 1600, evaluation.ResultWin, evaluation.DefaultThresholds)
+	fmt.Printf("accuracy %.0f%%, ACPL %.0f, %d best, %d good\n", white.Accuracy, white.ACPL, white.BestMoves, white.GoodMoves)
+	// Output: accuracy 96%, ACPL 20, 1 best, 1 good
+}
+
+func ExampleClassifyMove() {
+	fmt.Println(evaluation.ClassifyMove(150, false, 20, -130, false, evaluation.DefaultThresholds))
+	// Output: mistake
+}
@@ -0,0 +1,191 @@
+package evaluation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// === OPENING PERFORMANCE ===
+
+// UnknownOpening is the family of games without a usable ECO code
+const UnknownOpening = "unknown"
+
+// DefaultOpeningMinGames is the number of games an opening needs to be
+// reported, so single games don't surface as trends
+const DefaultOpeningMinGames = 3
+
+// OpeningOptions configures AggregateOpenings
+type OpeningOptions struct {
+	MinGames  int // Openings with fewer games are left out (0 or less = all)
+	LastGames int // Only the player's most recent games (0 or less = all)
+}
+
+// OpeningsReport is a player's results by opening family and color
+type OpeningsReport struct {
+	Player   string
+	Games    int            // Games of the player considered
+	Openings []OpeningStats // Most played first
+	Hidden   int            // Openings left out for having fewer than MinGames
+}
+
+// OpeningStats holds a player's results in one ECO family with one color
+type OpeningStats struct {
+	Family string // e.g. "B10-B19", or UnknownOpening
+	Name   string // Most common opening name, without the variation
+	Color  string // "white" or "black"
+
+	Games           int // Unknown results included, see PlayerReport.UnknownResults
+	Wins            int
+	Draws           int
+	Losses          int
+	ScorePercent    float64 // Points per game with a known result, 0-100
+	AverageAccuracy float64
+	OutOfBookACPL   float64 // Average centipawn loss of the player's non-book moves
+
+	// TopDeviation is the player's most common move leaving book, like
+	// "5... Nf6", counted only in games where the player left book first;
+	// empty without book moves to tell where theory ends
+	TopDeviation      string
+	TopDeviationGames int
+}
+
+// AggregateOpenings groups the games player took part in by ECO family and
+// the player's color. Games without an ECO code are grouped under
+// UnknownOpening. Book moves are the leading moves classified ClassBook;
+// games without any count all moves as out of book.
+func AggregateOpenings(analyses []GameEvaluation, player string, opts OpeningOptions) OpeningsReport {
+	report := OpeningsReport{Player: player}
+
+	games := make([]GameEvaluation, 0, len(analyses))
+	for _, game := range analyses {
+		if playerColor(game, player) != "" {
+			games = append(games, game)
+		}
+	}
+	sort.SliceStable(games, func(i, j int) bool {
+		return games[i].PlayedAt.Before(games[j].PlayedAt)
+	})
+	if opts.LastGames > 0 && len(games) > opts.LastGames {
+		games = games[len(games)-opts.LastGames:]
+	}
+
+	type openingKey struct{ family, color string }
+	type openingTotals struct {
+		stats      *OpeningStats
+		accuracy   mean
+		loss       int
+		moves      int
+		names      map[string]int
+		deviations map[string]int
+	}
+	openings := make(map[openingKey]*openingTotals)
+
+	for _, game := range games {
+		color := playerColor(game, player)
+		key := openingKey{ECOFamily(game.ECO), color}
+		totals := openings[key]
+		if totals == nil {
+			totals = &openingTotals{
+				stats:      &OpeningStats{Family: key.family, Color: color},
+				names:      make(map[string]int),
+				deviations: make(map[string]int),
+			}
+			openings[key] = totals
+		}
+		report.Games++
+
+		stats := totals.stats
+		stats.Games++
+		switch playerResult(game.Result, color) {
+		case ResultWin:
+			stats.Wins++
+		case ResultLoss:
+			stats.Losses++
+		case ResultDraw:
+			stats.Draws++
+		}
+
+		if name, _, _ := strings.Cut(game.OpeningName, ":"); strings.TrimSpace(name) != "" {
+			totals.names[strings.TrimSpace(name)]++
+		}
+		if accuracy, ok := playerAccuracy(game, color); ok {
+			totals.accuracy.add(accuracy)
+		}
+
+		inBook, sawBook := true, false
+		for _, move := range game.Moves {
+			if inBook && move.Classification == ClassBook {
+				sawBook = true
+				continue
+			}
+			if inBook && sawBook && move.Color == color {
+				totals.deviations[deviationLabel(move)]++
+			}
+			inBook = false
+			if move.Color == color {
+				totals.loss += move.CentipawnLoss
+				totals.moves++
+			}
+		}
+	}
+
+	for _, totals := range openings {
+		stats := totals.stats
+		if stats.Games < opts.MinGames {
+			report.Hidden++
+			continue
+		}
+		stats.ScorePercent = score(stats.Wins, stats.Draws, stats.Losses) * 100
+		stats.AverageAccuracy = totals.accuracy.value()
+		if totals.moves > 0 {
+			stats.OutOfBookACPL = float64(totals.loss) / float64(totals.moves)
+		}
+		stats.Name, _ = mostCommon(totals.names)
+		stats.TopDeviation, stats.TopDeviationGames = mostCommon(totals.deviations)
+		report.Openings = append(report.Openings, *stats)
+	}
+	sort.Slice(report.Openings, func(i, j int) bool {
+		a, b := report.Openings[i], report.Openings[j]
+		if a.Games != b.Games {
+			return a.Games > b.Games
+		}
+		if a.Family != b.Family {
+			return a.Family < b.Family
+		}
+		return a.Color < b.Color
+	})
+
+	return report
+}
+
+// ECOFamily returns the family of an ECO code, the ten codes sharing its
+// letter and first digit ("B12" is in "B10-B19"), or UnknownOpening
+func ECOFamily(eco string) string {
+	eco = strings.ToUpper(strings.TrimSpace(eco))
+	if len(eco) != 3 || eco[0] < 'A' || eco[0] > 'E' ||
+		eco[1] < '0' || eco[1] > '9' || eco[2] < '0' || eco[2] > '9' {
+		return UnknownOpening
+	}
+	return fmt.Sprintf("%s0-%s9", eco[:2], eco[:2])
+}
+
+// deviationLabel formats a move with its number, "5. Nf3" or "5... Nf6"
+func deviationLabel(move MoveEvaluation) string {
+	if move.Color == "black" {
+		return fmt.Sprintf("%d... %s", move.MoveNumber, move.PlayedMove)
+	}
+	return fmt.Sprintf("%d. %s", move.MoveNumber, move.PlayedMove)
+}
+
+// mostCommon returns the most counted key, the first in order on ties
+func mostCommon(counts map[string]int) (string, int) {
+	var best string
+	var bestCount int
+	for key, count := range counts {
+		if count > bestCount || (count == bestCount && key < best) {
+			best, bestCount = key, count
+		}
+	}
+	return best, bestCount
+}
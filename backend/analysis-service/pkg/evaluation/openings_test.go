@@ -0,0 +1,136 @@
+package evaluation
+
+import "testing"
+
+// bookGame builds a Caro-Kann whose first bookPlies moves are book and
+// the rest lose the given centipawns
+func bookGame(white, black string, result GameResult, eco, name string, day, bookPlies, loss int) GameEvaluation {
+	game := reportGame("g", white, black, result, eco, "blitz", day, 80)
+	game.OpeningName = name
+	game.WhiteMetrics = PlayerMetrics{Accuracy: 80, TotalMoves: 4}
+	game.BlackMetrics = game.WhiteMetrics
+
+	sans := []string{"e4", "c6", "d4", "d5", "Nc3", "dxe4", "Nxe4", "Bf5"}
+	for ply, san := range sans {
+		move := MoveEvaluation{
+			Ply:        ply,
+			MoveNumber: ply/2 + 1,
+			Color:      "white",
+			PlayedMove: san,
+		}
+		if ply%2 == 1 {
+			move.Color = "black"
+		}
+		if ply < bookPlies {
+			move.Classification = ClassBook
+		} else {
+			move.Classification = ClassGood
+			move.CentipawnLoss = loss
+		}
+		game.Moves = append(game.Moves, move)
+	}
+	return game
+}
+
+func TestAggregateOpenings(t *testing.T) {
+	games := []GameEvaluation{
+		bookGame("bob", "alice", ResultLoss, "B12", "Caro-Kann Defense: Advance Variation", 1, 5, 20),
+		bookGame("carol", "Alice", ResultDraw, "B18", "Caro-Kann Defense: Classical Variation", 2, 5, 40),
+		bookGame("dave", "alice", ResultWin, "B10", "Caro-Kann Defense", 3, 4, 30),
+		bookGame("alice", "bob", ResultWin, "C50", "Italian Game", 4, 4, 10),
+		bookGame("alice", "bob", ResultLoss, "", "", 5, 0, 50),
+		bookGame("bob", "carol", ResultWin, "B12", "Caro-Kann Defense", 6, 5, 0),
+	}
+
+	report := AggregateOpenings(games, "alice", OpeningOptions{})
+
+	if report.Games != 5 || len(report.Openings) != 3 || report.Hidden != 0 {
+		t.Fatalf("games = %d, openings = %+v, hidden = %d", report.Games, report.Openings, report.Hidden)
+	}
+
+	caro := report.Openings[0]
+	if caro.Family != "B10-B19" || caro.Color != "black" || caro.Name != "Caro-Kann Defense" || caro.Games != 3 {
+		t.Errorf("caro-kann = %+v", caro)
+	}
+	// Alice won g1 and drew g2 as Black (results are White's), lost g3
+	if caro.Wins != 1 || caro.Draws != 1 || caro.Losses != 1 || caro.ScorePercent != 50 {
+		t.Errorf("caro-kann results = %d/%d/%d, score %v", caro.Wins, caro.Draws, caro.Losses, caro.ScorePercent)
+	}
+	// Two out of book moves of alice's per game, losing 20, 40 and 30
+	if want := 30.0; caro.OutOfBookACPL != want {
+		t.Errorf("out of book ACPL = %v, want %v", caro.OutOfBookACPL, want)
+	}
+	// Alice left book first with 3... dxe4 twice; in g3 Bob left book first
+	if caro.TopDeviation != "3... dxe4" || caro.TopDeviationGames != 2 {
+		t.Errorf("deviation = %q x%d, want 3... dxe4 x2", caro.TopDeviation, caro.TopDeviationGames)
+	}
+	if caro.AverageAccuracy != 80 {
+		t.Errorf("accuracy = %v, want 80", caro.AverageAccuracy)
+	}
+
+	// Without book moves every move counts and there is no deviation
+	var unknown OpeningStats
+	for _, o := range report.Openings {
+		if o.Family == UnknownOpening {
+			unknown = o
+		}
+	}
+	if unknown.Games != 1 || unknown.TopDeviation != "" || unknown.OutOfBookACPL != 50 {
+		t.Errorf("unknown opening = %+v", unknown)
+	}
+}
+
+func TestAggregateOpenings_UnknownResult(t *testing.T) {
+	games := []GameEvaluation{
+		bookGame("alice", "bob", ResultDraw, "C50", "Italian Game", 1, 4, 0),
+		bookGame("alice", "bob", ResultUnknown, "C50", "Italian Game", 2, 4, 0),
+	}
+
+	report := AggregateOpenings(games, "alice", OpeningOptions{})
+	if o := report.Openings[0]; o.Games != 2 || o.Draws != 1 || o.Wins+o.Losses != 0 || o.ScorePercent != 50 {
+		t.Errorf("italian = %+v, want 2 games scoring 50%% from the draw", o)
+	}
+}
+
+func TestAggregateOpenings_Options(t *testing.T) {
+	games := []GameEvaluation{
+		bookGame("alice", "bob", ResultWin, "C50", "Italian Game", 1, 4, 0),
+		bookGame("alice", "bob", ResultWin, "C55", "Two Knights Defense", 2, 4, 0),
+		bookGame("bob", "alice", ResultWin, "B12", "Caro-Kann Defense", 3, 4, 0),
+		bookGame("alice", "bob", ResultLoss, "C54", "Italian Game", 4, 4, 0),
+	}
+
+	report := AggregateOpenings(games, "alice", OpeningOptions{MinGames: 2})
+	if len(report.Openings) != 1 || report.Openings[0].Family != "C50-C59" || report.Hidden != 1 {
+		t.Fatalf("openings = %+v, hidden = %d", report.Openings, report.Hidden)
+	}
+	if o := report.Openings[0]; o.Games != 3 || o.Name != "Italian Game" {
+		t.Errorf("italian = %+v", o)
+	}
+
+	// The last two games only
+	report = AggregateOpenings(games, "alice", OpeningOptions{LastGames: 2})
+	if report.Games != 2 || len(report.Openings) != 2 {
+		t.Errorf("last games: games = %d, openings = %+v", report.Games, report.Openings)
+	}
+}
+
+func TestECOFamily(t *testing.T) {
+	tests := []struct {
+		eco  string
+		want string
+	}{
+		{"B12", "B10-B19"},
+		{"c65", "C60-C69"},
+		{"A00", "A00-A09"},
+		{"", UnknownOpening},
+		{"F12", UnknownOpening},
+		{"B1", UnknownOpening},
+		{"B1x", UnknownOpening},
+	}
+	for _, tt := range tests {
+		if got := ECOFamily(tt.eco); got != tt.want {
+			t.Errorf("ECOFamily(%q) = %q, want %q", tt.eco, got, tt.want)
+		}
+	}
+}
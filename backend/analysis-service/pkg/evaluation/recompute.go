@@ -0,0 +1,59 @@
+package evaluation
+
+import "math"
+
+// GameMetricsOptions select how CalculateGameMetrics scores a game
+type GameMetricsOptions struct {
+	Thresholds Thresholds
+	Method     AccuracyMethod // Accuracy of AccuracyModelEloInsight, "" = AccuracyCappedLoss
+	Model      AccuracyModel  // "" = AccuracyModelEloInsight
+	Result     GameResult     // From White's point of view, ResultUnknown when unknown
+}
+
+// CalculateGameMetrics scores both players of a game from its moves in
+// ply order, evaluations from White's point of view and centipawn losses
+// set: moves other than book ones are classified afresh by ClassifyMove
+// under opts.Thresholds, and accuracy follows opts.Model. Without the players' ratings there are no performance
+// ratings. It needs no engine.
+func CalculateGameMetrics(moves []MoveEvaluation, opts GameMetricsOptions) (white, black PlayerMetrics) {
+	score := func(color string) PlayerMetrics {
+		metrics := CalculatePlayerMetrics(moves, color, 0, playerResult(opts.Result, color), opts.Thresholds)
+
+		switch opts.Model {
+		case AccuracyModelLichess:
+			metrics.Accuracy = LichessAccuracy(moves, color)
+			_, metrics.AccuracyStddev = moveMeanAccuracy(moves, color, opts.Thresholds)
+		case AccuracyModelChessComApprox:
+			metrics.Accuracy = ChessComApproxAccuracy(moves, color)
+			_, metrics.AccuracyStddev = moveMeanAccuracy(moves, color, opts.Thresholds)
+		default:
+			if opts.Method == AccuracyMoveMean {
+				metrics.Accuracy, metrics.AccuracyStddev = moveMeanAccuracy(moves, color, opts.Thresholds)
+			}
+		}
+		return metrics
+	}
+	return score("white"), score("black")
+}
+
+// moveMeanAccuracy returns the mean of color's move accuracies outside
+// book moves and garbage time, 100 without any, and its standard deviation
+// from the noise of the evaluations
+func moveMeanAccuracy(moves []MoveEvaluation, color string, t Thresholds) (float64, float64) {
+	var total, variance float64
+	var n int
+	for _, move := range moves {
+		if move.Color != color || move.Classification == ClassBook || t.IsGarbageTime(moverEval(move.EvalBefore, color)) {
+			continue
+		}
+		before, after := moverEval(move.EvalBefore, color), moverEval(move.EvalAfter, color)
+		total += CalculateMoveAccuracy(before, after)
+		noise := MoveAccuracyNoise(before, after, LossNoise(move.DepthBefore, move.DepthAfter))
+		variance += noise * noise
+		n++
+	}
+	if n == 0 {
+		return 100, 0
+	}
+	return total / float64(n), math.Sqrt(variance) / float64(n)
+}
@@ -0,0 +1,55 @@
+package evaluation
+
+import "testing"
+
+// recomputeMoves is a short game from White's point of view with
+// centipawn losses set: White loses 120cp on ply 2
+func recomputeMoves() []MoveEvaluation {
+	return []MoveEvaluation{
+		{Ply: 0, Color: "white", EvalBefore: 30, EvalAfter: 20, CentipawnLoss: 10},
+		{Ply: 1, Color: "black", EvalBefore: 20, EvalAfter: 25, CentipawnLoss: 0, WasBestMove: true},
+		{Ply: 2, Color: "white", EvalBefore: 25, EvalAfter: -95, CentipawnLoss: 120},
+		{Ply: 3, Color: "black", EvalBefore: -95, EvalAfter: -90, CentipawnLoss: 5},
+	}
+}
+
+func TestCalculateGameMetrics_Thresholds(t *testing.T) {
+	white, black := CalculateGameMetrics(recomputeMoves(), GameMetricsOptions{Thresholds: DefaultThresholds})
+	if white.TotalMoves != 2 || black.TotalMoves != 2 {
+		t.Fatalf("total moves = %d/%d, want 2/2", white.TotalMoves, black.TotalMoves)
+	}
+	if white.Inaccuracies+white.Mistakes != 1 {
+		t.Errorf("white: %d inaccuracies, %d mistakes, want one of them", white.Inaccuracies, white.Mistakes)
+	}
+
+	// Looser thresholds forgive the loss: the counts change, ACPL doesn't
+	loose := DefaultThresholds
+	loose.Good, loose.Inaccuracy, loose.Mistake = 150, 300, 500
+	lenient, _ := CalculateGameMetrics(recomputeMoves(), GameMetricsOptions{Thresholds: loose})
+	if lenient.Inaccuracies+lenient.Mistakes+lenient.Blunders != 0 {
+		t.Errorf("loose thresholds: %+v, want no errors", lenient)
+	}
+	if lenient.ACPL != white.ACPL {
+		t.Errorf("ACPL = %v, want %v whatever the thresholds", lenient.ACPL, white.ACPL)
+	}
+}
+
+func TestCalculateGameMetrics_Models(t *testing.T) {
+	opts := GameMetricsOptions{Thresholds: DefaultThresholds}
+	white, _ := CalculateGameMetrics(recomputeMoves(), opts)
+
+	opts.Model = AccuracyModelLichess
+	lichess, _ := CalculateGameMetrics(recomputeMoves(), opts)
+	if want := LichessAccuracy(recomputeMoves(), "white"); lichess.Accuracy != want {
+		t.Errorf("lichess accuracy = %v, want %v", lichess.Accuracy, want)
+	}
+	if lichess.Mistakes != white.Mistakes || lichess.ACPL != white.ACPL {
+		t.Errorf("the model changed more than accuracy: %+v, %+v", lichess, white)
+	}
+
+	opts.Model, opts.Method = "", AccuracyMoveMean
+	mean, _ := CalculateGameMetrics(recomputeMoves(), opts)
+	if want, _ := moveMeanAccuracy(recomputeMoves(), "white", DefaultThresholds); mean.Accuracy != want {
+		t.Errorf("move mean accuracy = %v, want %v", mean.Accuracy, want)
+	}
+}
@@ -0,0 +1,108 @@
+package evaluation
+
+// Win probabilities, for the player, below which a position counts as lost
+// and above which it counts as won
+const (
+	LostWinProbability = 0.10
+	WonWinProbability  = 0.90
+)
+
+// Resilience measures how a player did from lost and won positions and with
+// the opponent's mistakes. It needs the game result.
+type Resilience struct {
+	// Swindles counts the times the player's win probability fell below
+	// LostWinProbability in a game they drew or won. A new time starts
+	// only once the position was back to even (50%).
+	Swindles int `json:"swindles"`
+
+	// BotchedWins counts the times it rose above WonWinProbability in a
+	// game they drew or lost, again once per return to even
+	BotchedWins int `json:"botched_wins"`
+
+	// Gifts counts the opponent's mistakes, blunders and missed wins.
+	// GiftConversion is the mean win probability the player gained, in
+	// percentage points, from before each one to after their reply.
+	Gifts          int     `json:"gifts"`
+	GiftConversion float64 `json:"gift_conversion"`
+}
+
+// CalculateResilience returns the resilience of color over the game's moves,
+// in order, from the win probability before each move and after the last.
+// result is from color's point of view; nil is returned when it isn't a
+// win, loss or draw. Opponent moves without a Classification are
+// classified with t.
+func CalculateResilience(moves []MoveEvaluation, color string, result GameResult, t Thresholds) *Resilience {
+	switch result {
+	case ResultWin, ResultLoss, ResultDraw:
+	default:
+		return nil
+	}
+
+	r := &Resilience{}
+	winProbability := func(eval int) float64 {
+		return EvalToWinProbability(moverEval(eval, color))
+	}
+
+	series := make([]float64, 0, len(moves)+1)
+	for _, move := range moves {
+		series = append(series, winProbability(move.EvalBefore))
+	}
+	if len(moves) > 0 {
+		series = append(series, winProbability(moves[len(moves)-1].EvalAfter))
+	}
+
+	lost, won := false, false
+	for _, p := range series {
+		switch {
+		case p < LostWinProbability && !lost:
+			lost = true
+			if result != ResultLoss {
+				r.Swindles++
+			}
+		case p > WonWinProbability && !won:
+			won = true
+			if result != ResultWin {
+				r.BotchedWins++
+			}
+		}
+		if p >= 0.5 {
+			lost = false
+		}
+		if p <= 0.5 {
+			won = false
+		}
+	}
+
+	var gained float64
+	for i, move := range moves {
+		if move.Color == color {
+			continue
+		}
+		switch classifyEvaluation(move, t) {
+		case ClassMistake, ClassBlunder, ClassMissedWin:
+		default:
+			continue
+		}
+
+		after := move.EvalAfter
+		if i+1 < len(moves) && moves[i+1].Color == color && moves[i+1].Ply == move.Ply+1 {
+			after = moves[i+1].EvalAfter
+		}
+		r.Gifts++
+		gained += (winProbability(after) - winProbability(move.EvalBefore)) * 100
+	}
+	if r.Gifts > 0 {
+		r.GiftConversion = gained / float64(r.Gifts)
+	}
+
+	return r
+}
+
+// classifyEvaluation returns the move's classification, classifying it with
+// t when it isn't known
+func classifyEvaluation(move MoveEvaluation, t Thresholds) MoveClassification {
+	if move.Classification != "" {
+		return move.Classification
+	}
+	return ClassifyMove(move.CentipawnLoss, move.WasBestMove, move.EvalBefore, move.EvalAfter, move.IsMateScore, t)
+}
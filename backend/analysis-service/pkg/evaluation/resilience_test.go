@@ -0,0 +1,66 @@
+package evaluation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCalculateResilience(t *testing.T) {
+	// Evaluations are White's. White blunders into a lost position (-900),
+	// Black gives it back, White goes lost again and Black then botches a
+	// second win the same way.
+	moves := []MoveEvaluation{
+		{Ply: 0, Color: "white", CentipawnLoss: 900, EvalBefore: 20, EvalAfter: -900},
+		{Ply: 1, Color: "black", CentipawnLoss: 900, EvalBefore: -900, EvalAfter: 0},
+		{Ply: 2, Color: "white", CentipawnLoss: 0, EvalBefore: 0, EvalAfter: 0},
+		{Ply: 3, Color: "black", CentipawnLoss: 0, EvalBefore: 0, EvalAfter: 0},
+		{Ply: 4, Color: "white", CentipawnLoss: 850, EvalBefore: 0, EvalAfter: -850},
+		{Ply: 5, Color: "black", CentipawnLoss: 0, EvalBefore: -850, EvalAfter: -800},
+		{Ply: 6, Color: "white", CentipawnLoss: 0, EvalBefore: -800, EvalAfter: -800},
+		{Ply: 7, Color: "black", CentipawnLoss: 800, EvalBefore: -800, EvalAfter: 0},
+	}
+
+	white := CalculateResilience(moves, "white", ResultDraw, DefaultThresholds)
+	if white == nil || white.Swindles != 2 || white.BotchedWins != 0 || white.Gifts != 2 {
+		t.Fatalf("white = %+v, want 2 swindles from 2 gifts", white)
+	}
+	// Back to 0 from -900 after the reply, and from -800 after the last move
+	want := ((EvalToWinProbability(0)-EvalToWinProbability(-900))*100 + (EvalToWinProbability(0)-EvalToWinProbability(-800))*100) / 2
+	if math.Abs(white.GiftConversion-want) > 1e-9 {
+		t.Errorf("gift conversion = %.2f, want %.2f", white.GiftConversion, want)
+	}
+
+	black := CalculateResilience(moves, "black", ResultDraw, DefaultThresholds)
+	if black == nil || black.Swindles != 0 || black.BotchedWins != 2 {
+		t.Errorf("black = %+v, want 2 botched wins", black)
+	}
+
+	// Losing a lost game is no swindle; the same game won by White is
+	if lost := CalculateResilience(moves, "white", ResultLoss, DefaultThresholds); lost.Swindles != 0 {
+		t.Errorf("lost game swindles = %d, want 0", lost.Swindles)
+	}
+	if won := CalculateResilience(moves, "black", ResultWin, DefaultThresholds); won.BotchedWins != 0 {
+		t.Errorf("won game botched wins = %d, want 0", won.BotchedWins)
+	}
+
+	if unknown := CalculateResilience(moves, "white", "", DefaultThresholds); unknown != nil {
+		t.Errorf("unknown result = %+v, want nil", unknown)
+	}
+	if m := CalculatePlayerMetrics(moves, "white", 1500, ResultDraw, DefaultThresholds); m.Resilience == nil || *m.Resilience != *white {
+		t.Errorf("player metrics resilience = %+v, want %+v", m.Resilience, white)
+	}
+}
+
+func TestCalculateResilience_GiftGivenBack(t *testing.T) {
+	// Black blunders and White blunders straight back
+	moves := []MoveEvaluation{
+		{Ply: 0, Color: "white", EvalBefore: 0, EvalAfter: 0},
+		{Ply: 1, Color: "black", CentipawnLoss: 500, EvalBefore: 0, EvalAfter: 500},
+		{Ply: 2, Color: "white", CentipawnLoss: 500, EvalBefore: 500, EvalAfter: 0},
+	}
+
+	r := CalculateResilience(moves, "white", ResultDraw, DefaultThresholds)
+	if r.Gifts != 1 || math.Abs(r.GiftConversion) > 1e-9 {
+		t.Errorf("resilience = %+v, want one gift converted to nothing", r)
+	}
+}
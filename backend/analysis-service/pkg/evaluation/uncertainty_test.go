@@ -0,0 +1,90 @@
+package evaluation
+
+import "testing"
+
+func TestEvalNoise_ShrinksWithDepth(t *testing.T) {
+	if got := EvalNoise(0); got != 0 {
+		t.Errorf("EvalNoise(0) = %v, want 0 for an unknown depth", got)
+	}
+	previous := EvalNoise(1)
+	for depth := 2; depth <= 60; depth++ {
+		noise := EvalNoise(depth)
+		if noise > previous {
+			t.Fatalf("EvalNoise(%d) = %v, above EvalNoise(%d) = %v", depth, noise, depth-1, previous)
+		}
+		if noise < EvalNoiseFloor {
+			t.Fatalf("EvalNoise(%d) = %v, below the floor", depth, noise)
+		}
+		previous = noise
+	}
+	if EvalNoise(12) <= EvalNoise(26)*2 {
+		t.Errorf("EvalNoise(12) = %v, want well above EvalNoise(26) = %v", EvalNoise(12), EvalNoise(26))
+	}
+}
+
+func TestMoveAccuracyNoise(t *testing.T) {
+	if got := MoveAccuracyNoise(50, 0, 0); got != 0 {
+		t.Errorf("without noise: %v, want 0", got)
+	}
+	// The accuracy curve is steepest around even positions
+	if even, won := MoveAccuracyNoise(30, 0, 20), MoveAccuracyNoise(930, 900, 20); even <= won {
+		t.Errorf("noise around an even position = %v, want above %v around a won one", even, won)
+	}
+	if shallow, deep := MoveAccuracyNoise(30, 0, LossNoise(12, 12)), MoveAccuracyNoise(30, 0, LossNoise(26, 26)); shallow <= deep {
+		t.Errorf("noise at depth 12 = %v, want above %v at depth 26", shallow, deep)
+	}
+}
+
+func TestLossTotals_Stddev(t *testing.T) {
+	var empty LossTotals
+	if empty.ACPLStddev() != 0 || empty.AccuracyStddev() != 0 {
+		t.Errorf("without moves: stddevs %v, %v, want 0", empty.ACPLStddev(), empty.AccuracyStddev())
+	}
+
+	var exact LossTotals
+	exact.Add(40)
+	if exact.ACPLStddev() != 0 || exact.AccuracyStddev() != 0 {
+		t.Errorf("without noise: stddevs %v, %v, want 0", exact.ACPLStddev(), exact.AccuracyStddev())
+	}
+
+	// A blunder past the cap moves ACPL but not accuracy
+	var capped LossTotals
+	capped.AddUncertain(900, 20)
+	if capped.ACPLStddev() != 20 || capped.AccuracyStddev() != 0 {
+		t.Errorf("capped loss: stddevs %v, %v, want 20 and 0", capped.ACPLStddev(), capped.AccuracyStddev())
+	}
+
+	// More moves average the noise out
+	var one, four LossTotals
+	one.AddUncertain(30, 20)
+	for i := 0; i < 4; i++ {
+		four.AddUncertain(30, 20)
+	}
+	if four.ACPLStddev() != one.ACPLStddev()/2 {
+		t.Errorf("4 moves: ACPL stddev = %v, want half of %v", four.ACPLStddev(), one.ACPLStddev())
+	}
+}
+
+func TestCalculatePlayerMetrics_StddevShrinksWithDepth(t *testing.T) {
+	moves := func(depth int) []MoveEvaluation {
+		return []MoveEvaluation{
+			{Ply: 0, Color: "white", EvalBefore: 30, EvalAfter: 20, CentipawnLoss: 10, DepthBefore: depth, DepthAfter: depth},
+			{Ply: 1, Color: "black", EvalBefore: 20, EvalAfter: 60, CentipawnLoss: 40, DepthBefore: depth, DepthAfter: depth},
+			{Ply: 2, Color: "white", EvalBefore: 60, EvalAfter: -90, CentipawnLoss: 150, DepthBefore: depth, DepthAfter: depth},
+			{Ply: 3, Color: "black", EvalBefore: -90, EvalAfter: -80, CentipawnLoss: 10, DepthBefore: depth, DepthAfter: depth},
+		}
+	}
+
+	var previous PlayerMetrics
+	for i, depth := range []int{8, 12, 16, 20, 26} {
+		metrics := CalculatePlayerMetrics(moves(depth), "white", 0, ResultDraw, DefaultThresholds)
+		if metrics.AccuracyStddev <= 0 || metrics.ACPLStddev <= 0 {
+			t.Fatalf("depth %d: stddevs %v, %v, want positive", depth, metrics.AccuracyStddev, metrics.ACPLStddev)
+		}
+		if i > 0 && (metrics.AccuracyStddev >= previous.AccuracyStddev || metrics.ACPLStddev >= previous.ACPLStddev) {
+			t.Errorf("depth %d: stddevs %v, %v, want below %v, %v", depth,
+				metrics.AccuracyStddev, metrics.ACPLStddev, previous.AccuracyStddev, previous.ACPLStddev)
+		}
+		previous = metrics
+	}
+}
@@ -0,0 +1,500 @@
+// Package pool keeps a fixed number of engines running and lends them out,
+// replacing engines that fail between uses.
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"go.uber.org/zap"
+)
+
+// Errors returned by Get
+var (
+	// ErrPoolExhausted means no engine became free before the caller's deadline
+	ErrPoolExhausted = errors.New("engine pool exhausted")
+
+	// ErrPoolClosed means the pool has been shut down
+	ErrPoolClosed = errors.New("pool is closed")
+)
+
+// replaceRetryDelay is the wait before another try at starting an engine
+// the pool lost
+const replaceRetryDelay = 5 * time.Second
+
+// Pool manages a pool of Stockfish engines
+type Pool struct {
+	engines   chan *engine.Engine
+	config    engine.Config
+	logger    *zap.Logger
+	size      int
+	created   int32
+	available int32
+	inUse     int32
+	waiting   int32
+	mu        sync.Mutex
+	closed    atomic.Bool // Set under mu, which sends to engines hold
+	ready     atomic.Bool
+	startTime time.Time
+
+	// identity is what the last engine started reports, for GetStats to
+	// read without taking an engine; nil before the first starts
+	identity atomic.Pointer[engineIdentity]
+
+	busy busyClock // Engine time lent out, by tag
+
+	subMu       sync.Mutex
+	subscribers []chan int
+}
+
+// engineIdentity is the Stockfish version and network of an engine
+type engineIdentity struct {
+	version string
+	netName string
+}
+
+// NewPool creates a new engine pool and starts its engines
+func NewPool(size int, config engine.Config, logger *zap.Logger) (*Pool, error) {
+	pool, err := New(size, config, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Start(); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+// New creates an engine pool without engines: Get waits until Start has
+// run. It lets a server take connections while its engines start.
+func New(size int, config engine.Config, logger *zap.Logger) (*Pool, error) {
+	if size <= 0 {
+		return nil, errors.New("pool size must be positive")
+	}
+
+	return &Pool{
+		engines:   make(chan *engine.Engine, size),
+		config:    config,
+		logger:    logger,
+		size:      size,
+		startTime: time.Now(),
+	}, nil
+}
+
+// Start starts the pool's engines, all at once so that starting takes
+// about as long as the slowest engine rather than the sum of them. If any
+// fails the others are closed and its error returned; the pool is then
+// left without engines.
+func (p *Pool) Start() error {
+	started := time.Now()
+	engines := make([]*engine.Engine, p.size)
+	errs := make([]error, p.size)
+	var wg sync.WaitGroup
+	for i := range engines {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			engines[i], errs[i] = engine.NewEngine(p.config, p.logger)
+		}(i)
+	}
+	wg.Wait()
+
+	closeAll := func() {
+		for _, eng := range engines {
+			if eng != nil {
+				eng.Close()
+			}
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			closeAll()
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	if p.closed.Load() {
+		p.mu.Unlock()
+		closeAll()
+		return ErrPoolClosed
+	}
+	for _, eng := range engines {
+		p.setIdentity(eng)
+		p.engines <- eng
+		atomic.AddInt32(&p.created, 1)
+		atomic.AddInt32(&p.available, 1)
+	}
+	p.ready.Store(true)
+	p.mu.Unlock()
+	p.notify()
+
+	p.logger.Info("Engine pool created",
+		zap.Int("size", p.size),
+		zap.Duration("startup", time.Since(started)))
+	return nil
+}
+
+// Ready reports whether Start has started all the pool's engines
+func (p *Pool) Ready() bool {
+	return p.ready.Load()
+}
+
+// Get acquires an engine from the pool, lent for the tag of ctx, see
+// WithTag
+func (p *Pool) Get(ctx context.Context) (*engine.Engine, error) {
+	if p.closed.Load() {
+		return nil, ErrPoolClosed
+	}
+
+	// Fast path: an engine is free right now
+	select {
+	case eng, ok := <-p.engines:
+		return p.lend(ctx, eng, ok)
+	default:
+	}
+
+	atomic.AddInt32(&p.waiting, 1)
+	defer atomic.AddInt32(&p.waiting, -1)
+
+	select {
+	case eng, ok := <-p.engines:
+		return p.lend(ctx, eng, ok)
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: %v", ErrPoolExhausted, ctx.Err())
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// backgroundPoll is how often GetBackground checks for an engine no Get
+// caller wants
+const backgroundPoll = 50 * time.Millisecond
+
+// GetBackground acquires an engine for background work, like warming the
+// cache, that must never hold up Get callers: it only takes a free engine
+// while none of them is waiting, checking every backgroundPoll until ctx
+// ends. Its errors are Get's.
+func (p *Pool) GetBackground(ctx context.Context) (*engine.Engine, error) {
+	ticker := time.NewTicker(backgroundPoll)
+	defer ticker.Stop()
+
+	for {
+		if p.closed.Load() {
+			return nil, ErrPoolClosed
+		}
+		if atomic.LoadInt32(&p.waiting) == 0 {
+			select {
+			case eng, ok := <-p.engines:
+				return p.lend(ctx, eng, ok)
+			default:
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w: %v", ErrPoolExhausted, ctx.Err())
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// lend takes eng, just received from the pool, out for the tag of ctx;
+// ok is false when the pool was closed instead
+func (p *Pool) lend(ctx context.Context, eng *engine.Engine, ok bool) (*engine.Engine, error) {
+	if !ok {
+		return nil, ErrPoolClosed
+	}
+	atomic.AddInt32(&p.available, -1)
+	atomic.AddInt32(&p.inUse, 1)
+	p.busy.lend(eng, TagFrom(ctx))
+	return eng, nil
+}
+
+// Put returns an engine to the pool. An engine returned after Close is
+// closed instead.
+func (p *Pool) Put(eng *engine.Engine) {
+	if p.closed.Load() {
+		p.closeReturned(eng)
+		return
+	}
+
+	// Reset engine state
+	if err := eng.Reset(); err != nil {
+		p.logger.Warn("Failed to reset engine, replacing", zap.Error(err))
+		p.busy.giveBack(eng, true)
+		eng.Close()
+		p.replaceEngine()
+		return
+	}
+
+	if !eng.IsReady() {
+		p.logger.Warn("Engine not ready, replacing")
+		p.busy.giveBack(eng, true)
+		eng.Close()
+		p.replaceEngine()
+		return
+	}
+
+	// Close may have run while the engine was reset
+	p.mu.Lock()
+	if p.closed.Load() {
+		p.mu.Unlock()
+		p.closeReturned(eng)
+		return
+	}
+	p.busy.giveBack(eng, false)
+
+	atomic.AddInt32(&p.inUse, -1)
+	atomic.AddInt32(&p.available, 1)
+	p.engines <- eng
+	p.mu.Unlock()
+}
+
+// closeReturned closes an engine returned to the closed pool
+func (p *Pool) closeReturned(eng *engine.Engine) {
+	p.busy.giveBack(eng, true)
+	atomic.AddInt32(&p.inUse, -1)
+	eng.Close()
+}
+
+// Discard closes an engine that failed, without resetting it as Put does,
+// and starts a replacement
+func (p *Pool) Discard(eng *engine.Engine) {
+	p.busy.giveBack(eng, true)
+	eng.Close()
+	atomic.AddInt32(&p.inUse, -1)
+	if p.closed.Load() {
+		return
+	}
+	p.logger.Warn("Discarding failed engine, replacing")
+	p.replaceEngine()
+}
+
+// replaceEngine creates a new engine to replace a failed one. If it can't
+// be started the pool runs an engine short, retrying every
+// replaceRetryDelay until it can.
+func (p *Pool) replaceEngine() {
+	err := p.addEngine()
+	if err == nil {
+		p.logger.Info("Engine replaced successfully")
+		return
+	}
+	if errors.Is(err, ErrPoolClosed) {
+		return
+	}
+	p.logger.Error("Failed to create replacement engine", zap.Error(err))
+	atomic.AddInt32(&p.created, -1)
+	p.notify()
+	time.AfterFunc(replaceRetryDelay, p.restoreEngine)
+}
+
+// restoreEngine retries starting an engine the pool lost
+func (p *Pool) restoreEngine() {
+	err := p.addEngine()
+	if errors.Is(err, ErrPoolClosed) {
+		return
+	}
+	if err != nil {
+		p.logger.Error("Failed to restore engine", zap.Error(err))
+		time.AfterFunc(replaceRetryDelay, p.restoreEngine)
+		return
+	}
+	atomic.AddInt32(&p.created, 1)
+	p.notify()
+	p.logger.Info("Engine restored", zap.Int("engines", p.Engines()))
+}
+
+// addEngine starts an engine and makes it available
+func (p *Pool) addEngine() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed.Load() {
+		return ErrPoolClosed
+	}
+
+	eng, err := engine.NewEngine(p.config, p.logger)
+	if err != nil {
+		return err
+	}
+
+	p.setIdentity(eng)
+	p.engines <- eng
+	atomic.AddInt32(&p.available, 1)
+	return nil
+}
+
+// setIdentity records the version and network eng reports as the pool's
+func (p *Pool) setIdentity(eng *engine.Engine) {
+	p.identity.Store(&engineIdentity{version: eng.Version(), netName: eng.NetName()})
+}
+
+// Subscribe returns a channel that receives the number of engines the pool
+// has, free or lent out, whenever it changes. A reader that falls behind
+// only misses counts a newer one replaced.
+func (p *Pool) Subscribe() <-chan int {
+	ch := make(chan int, 1)
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	p.subscribers = append(p.subscribers, ch)
+	return ch
+}
+
+// notify sends the engine count to every subscriber
+func (p *Pool) notify() {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	n := p.Engines()
+	for _, ch := range p.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- n
+	}
+}
+
+// Stats returns pool statistics
+type Stats struct {
+	Size             int
+	Available        int
+	InUse            int
+	StockfishVersion string
+	NNUENet          string
+	Uptime           time.Duration
+
+	// BusyTime is the time engines have been lent out, loans in progress
+	// included. Tags splits it by the tag engines were lent for, busiest
+	// first, and Engines by engine for those the pool still has.
+	BusyTime time.Duration
+	Tags     []TagStats
+	Engines  []EngineStats
+}
+
+// GetStats returns current pool statistics. It never takes an engine, so
+// polling it doesn't hold up Get; the version and network are "unknown"
+// until the first engine has started.
+func (p *Pool) GetStats() Stats {
+	version, netName := p.Identity()
+	if version == "" {
+		version, netName = "unknown", "unknown"
+	}
+
+	busy, tags, engines := p.busy.stats()
+	return Stats{
+		Size:             p.size,
+		Available:        int(atomic.LoadInt32(&p.available)),
+		InUse:            int(atomic.LoadInt32(&p.inUse)),
+		StockfishVersion: version,
+		NNUENet:          netName,
+		Uptime:           time.Since(p.startTime),
+		BusyTime:         busy,
+		Tags:             tags,
+		Engines:          engines,
+	}
+}
+
+// Identity returns the version and network the last engine started
+// reports, without taking an engine; both are "" until the first started
+func (p *Pool) Identity() (version, netName string) {
+	if identity := p.identity.Load(); identity != nil {
+		return identity.version, identity.netName
+	}
+	return "", ""
+}
+
+// Size returns the pool size
+func (p *Pool) Size() int {
+	return p.size
+}
+
+// Config returns the configuration the pool starts its engines with
+func (p *Pool) Config() engine.Config {
+	return p.config
+}
+
+// Engines returns the number of engines the pool has, free or lent out.
+// It is below Size while failed engines couldn't be replaced.
+func (p *Pool) Engines() int {
+	return int(atomic.LoadInt32(&p.created))
+}
+
+// Available returns the number of available engines
+func (p *Pool) Available() int {
+	return int(atomic.LoadInt32(&p.available))
+}
+
+// Waiting returns the number of callers blocked waiting for an engine
+func (p *Pool) Waiting() int {
+	return int(atomic.LoadInt32(&p.waiting))
+}
+
+// Close shuts down all engines in the pool
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed.Load() {
+		return nil
+	}
+	p.closed.Store(true)
+
+	close(p.engines)
+
+	var firstErr error
+	for eng := range p.engines {
+		if err := eng.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	p.logger.Info("Engine pool closed")
+	return firstErr
+}
+
+// HealthCheck verifies all engines are healthy
+func (p *Pool) HealthCheck(ctx context.Context) error {
+	checkedEngines := make([]*engine.Engine, 0, p.size)
+
+	// Get and check each engine
+	for i := 0; i < p.size; i++ {
+		eng, err := p.Get(ctx)
+		if err != nil {
+			// Put back already checked engines
+			for _, e := range checkedEngines {
+				p.Put(e)
+			}
+			return err
+		}
+
+		if !eng.IsReady() {
+			// Put back already checked engines
+			for _, e := range checkedEngines {
+				p.Put(e)
+			}
+			// Put back this one too (will trigger replacement)
+			p.Put(eng)
+			return errors.New("engine not ready")
+		}
+
+		checkedEngines = append(checkedEngines, eng)
+	}
+
+	// Return all engines
+	for _, eng := range checkedEngines {
+		p.Put(eng)
+	}
+
+	return nil
+}
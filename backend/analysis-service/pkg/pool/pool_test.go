@@ -0,0 +1,295 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	enginetest.Main()
+	os.Exit(m.Run())
+}
+
+// engineStartup is how long slowEngineConfig's engine takes to answer "uci"
+const engineStartup = 300 * time.Millisecond
+
+// slowEngineConfig is an engine that is slow to start, as Stockfish is
+// while it loads its network
+func slowEngineConfig(t *testing.T) engine.Config {
+	t.Helper()
+	binary := enginetest.Engine{Name: "SlowFish", Startup: engineStartup}.Binary(t)
+	return engine.Config{BinaryPath: binary, Threads: 1, Hash: 16, MultiPV: 1}
+}
+
+func TestStart_EnginesStartConcurrently(t *testing.T) {
+	const size = 8
+	p, err := New(size, slowEngineConfig(t), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if p.Ready() || p.Engines() != 0 {
+		t.Fatalf("before Start: ready %v with %d engines", p.Ready(), p.Engines())
+	}
+	updates := p.Subscribe()
+
+	start := time.Now()
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+
+	// One after the other they would take size*engineStartup
+	if elapsed >= 3*engineStartup {
+		t.Errorf("%d engines started in %v, want about %v", size, elapsed, engineStartup)
+	}
+	if !p.Ready() || p.Engines() != size || p.Available() != size {
+		t.Errorf("after Start: ready %v, %d engines, %d available", p.Ready(), p.Engines(), p.Available())
+	}
+	select {
+	case n := <-updates:
+		if n != size {
+			t.Errorf("subscribers told %d engines, want %d", n, size)
+		}
+	default:
+		t.Error("subscribers weren't told the engines started")
+	}
+}
+
+func TestGet_WaitsForStart(t *testing.T) {
+	p, err := New(1, slowEngineConfig(t), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	got := make(chan error, 1)
+	go func() {
+		eng, err := p.Get(context.Background())
+		if err == nil {
+			p.Put(eng)
+		}
+		got <- err
+	}()
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Errorf("Get() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Get didn't return once the pool started")
+	}
+}
+
+func TestNewPool_StartFailure(t *testing.T) {
+	config := slowEngineConfig(t)
+	config.BinaryPath = filepath.Join(t.TempDir(), "missing")
+	if _, err := NewPool(4, config, zap.NewNop()); err == nil {
+		t.Fatal("NewPool() with a missing binary succeeded")
+	}
+}
+
+func TestGetStats_DoesNotTakeEngines(t *testing.T) {
+	p, err := NewPool(2, slowEngineConfig(t), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// One engine busy throughout, the other free for the prober alone
+	busy, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Put(busy)
+
+	stop := make(chan struct{})
+	polled := make(chan []Stats)
+	go func() {
+		var stats []Stats
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				polled <- stats
+				return
+			case <-ticker.C:
+				stats = append(stats, p.GetStats())
+			}
+		}
+	}()
+
+	// With its context already done Get only succeeds on an engine free
+	// right then, so a poll holding the free one would fail it
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	probes := 0
+	for time.Now().Before(deadline) {
+		eng, err := p.Get(done)
+		if err != nil {
+			close(stop)
+			t.Fatalf("Get after %d probes = %v with an engine free", probes, err)
+		}
+		p.Put(eng)
+		probes++
+	}
+	close(stop)
+
+	stats := <-polled
+	if len(stats) < 50 {
+		t.Fatalf("%d polls in a second, want about 100", len(stats))
+	}
+	for _, s := range stats {
+		if s.StockfishVersion != "SlowFish" || s.Available > 1 || s.InUse < 1 {
+			t.Fatalf("stats while saturated = %+v", s)
+		}
+	}
+}
+
+func TestIdentity_AllEnginesBusy(t *testing.T) {
+	p, err := NewPool(1, slowEngineConfig(t), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	eng, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Put(eng)
+
+	if version, netName := p.Identity(); version != eng.Version() || netName != eng.NetName() {
+		t.Errorf("Identity() = %q, %q; want %q, %q", version, netName, eng.Version(), eng.NetName())
+	}
+}
+
+func TestClose_DuringUse(t *testing.T) {
+	const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	binary := enginetest.Engine{Latency: 5 * time.Millisecond}.Binary(t)
+	p, err := NewPool(3, engine.Config{BinaryPath: binary, Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// More callers than engines search until the pool closes under them;
+	// none may get an engine after that, or panic giving one back
+	var wg sync.WaitGroup
+	errs := make(chan error, 6)
+	for w := 0; w < 6; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				eng, err := p.Get(context.Background())
+				if err != nil {
+					errs <- err
+					return
+				}
+				eng.AnalyzePosition(startFEN, 12, 1)
+				p.Put(eng)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := p.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if !errors.Is(err, ErrPoolClosed) {
+			t.Errorf("Get after Close = %v, want ErrPoolClosed", err)
+		}
+	}
+	if stats := p.GetStats(); stats.InUse != 0 {
+		t.Errorf("%d engines in use after every caller gave its engine back", stats.InUse)
+	}
+	if _, err := p.GetBackground(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("GetBackground after Close = %v", err)
+	}
+}
+
+func TestGet_WaitingWhenClosed(t *testing.T) {
+	p, err := NewPool(1, slowEngineConfig(t), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	eng, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A caller waiting for the only engine is told the pool closed
+	got := make(chan error, 1)
+	go func() {
+		_, err := p.Get(context.Background())
+		got <- err
+	}()
+	for p.Waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	p.Close()
+	select {
+	case err := <-got:
+		if !errors.Is(err, ErrPoolClosed) {
+			t.Errorf("waiting Get = %v, want ErrPoolClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting Get didn't return when the pool closed")
+	}
+
+	// The engine given back afterwards is closed, not pooled
+	p.Put(eng)
+	if eng.IsReady() || p.GetStats().InUse != 0 {
+		t.Errorf("engine put back after Close: ready %v, %d in use", eng.IsReady(), p.GetStats().InUse)
+	}
+}
+
+func TestDiscard_ReplacesCrashedEngine(t *testing.T) {
+	const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	fake := enginetest.Engine{Failures: []enginetest.Failure{{Mode: enginetest.Crash, Times: 1}}}
+	p, err := NewPool(1, engine.Config{BinaryPath: fake.Binary(t), Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	eng, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := eng.AnalyzePosition(startFEN, 12, 1); !errors.Is(err, engine.ErrEngineDied) {
+		t.Fatalf("first search = %v, want the engine dead", err)
+	}
+	p.Discard(eng)
+
+	// Its replacement searches
+	eng, err = p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Put(eng)
+	if !eng.IsReady() || p.Engines() != 1 {
+		t.Fatalf("replacement ready %v, pool of %d engines", eng.IsReady(), p.Engines())
+	}
+	if _, err := eng.AnalyzePosition(startFEN, 12, 1); err != nil {
+		t.Errorf("replacement search = %v", err)
+	}
+}
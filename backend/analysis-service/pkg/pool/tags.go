@@ -0,0 +1,160 @@
+package pool
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+)
+
+// UntaggedTag is the tag of engines lent for a context without one
+const UntaggedTag = "untagged"
+
+type tagKey struct{}
+
+// WithTag returns a copy of ctx whose engines, taken with Get or
+// GetBackground, are lent for tag: the RPC or class of work they serve.
+// GetStats attributes their busy time to it. An empty tag is UntaggedTag.
+func WithTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, tagKey{}, tag)
+}
+
+// TagFrom returns the tag of ctx, UntaggedTag if it has none
+func TagFrom(ctx context.Context) string {
+	if tag, _ := ctx.Value(tagKey{}).(string); tag != "" {
+		return tag
+	}
+	return UntaggedTag
+}
+
+// TagStats is the busy time of the pool's engines lent for a tag
+type TagStats struct {
+	Tag   string
+	Busy  time.Duration // Loans in progress included
+	Share float64       // Percentage of the pool's busy time
+	Loans int64
+	InUse int // Engines lent for it now
+}
+
+// EngineStats is the busy time of one of the pool's engines by tag. IDs
+// number the engines in the order they were first lent.
+type EngineStats struct {
+	ID   int
+	Busy map[string]time.Duration
+}
+
+// loan is an engine lent out
+type loan struct {
+	tag   string
+	since time.Time
+}
+
+// busyClock records how long engines were lent out, by tag and by engine.
+// An engine's own record goes when it leaves the pool; the totals by tag
+// keep its time. The zero value is ready to use.
+type busyClock struct {
+	mu      sync.Mutex
+	loans   map[*engine.Engine]loan
+	engines map[*engine.Engine]*EngineStats
+	nextID  int
+	tags    map[string]*TagStats // Finished loans
+}
+
+// lend records eng lent for tag from now
+func (c *busyClock) lend(eng *engine.Engine, tag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loans == nil {
+		c.loans = make(map[*engine.Engine]loan)
+		c.engines = make(map[*engine.Engine]*EngineStats)
+		c.tags = make(map[string]*TagStats)
+	}
+	c.loans[eng] = loan{tag: tag, since: time.Now()}
+	if c.engines[eng] == nil {
+		c.nextID++
+		c.engines[eng] = &EngineStats{ID: c.nextID, Busy: make(map[string]time.Duration)}
+	}
+}
+
+// giveBack ends the loan of eng, if it was lent, and forgets eng when it
+// leaves the pool
+func (c *busyClock) giveBack(eng *engine.Engine, leaves bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if l, ok := c.loans[eng]; ok {
+		delete(c.loans, eng)
+		busy := time.Since(l.since)
+		c.engines[eng].Busy[l.tag] += busy
+		tag := c.tags[l.tag]
+		if tag == nil {
+			tag = &TagStats{Tag: l.tag}
+			c.tags[l.tag] = tag
+		}
+		tag.Busy += busy
+		tag.Loans++
+	}
+	if leaves {
+		delete(c.engines, eng)
+	}
+}
+
+// stats returns the busy time by tag, busiest first, and by engine, in
+// the order they were first lent, with the loans in progress counted up
+// to now, and their sum
+func (c *busyClock) stats() (time.Duration, []TagStats, []EngineStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	tags := make(map[string]*TagStats, len(c.tags))
+	for name, t := range c.tags {
+		copied := *t
+		tags[name] = &copied
+	}
+	engines := make(map[*engine.Engine]*EngineStats, len(c.engines))
+	for eng, e := range c.engines {
+		copied := EngineStats{ID: e.ID, Busy: make(map[string]time.Duration, len(e.Busy))}
+		for tag, busy := range e.Busy {
+			copied.Busy[tag] = busy
+		}
+		engines[eng] = &copied
+	}
+	for eng, l := range c.loans {
+		busy := now.Sub(l.since)
+		engines[eng].Busy[l.tag] += busy
+		tag := tags[l.tag]
+		if tag == nil {
+			tag = &TagStats{Tag: l.tag}
+			tags[l.tag] = tag
+		}
+		tag.Busy += busy
+		tag.Loans++
+		tag.InUse++
+	}
+
+	var total time.Duration
+	byTag := make([]TagStats, 0, len(tags))
+	for _, t := range tags {
+		total += t.Busy
+		byTag = append(byTag, *t)
+	}
+	for i := range byTag {
+		if total > 0 {
+			byTag[i].Share = 100 * float64(byTag[i].Busy) / float64(total)
+		}
+	}
+	sort.Slice(byTag, func(i, j int) bool {
+		if byTag[i].Busy != byTag[j].Busy {
+			return byTag[i].Busy > byTag[j].Busy
+		}
+		return byTag[i].Tag < byTag[j].Tag
+	})
+	byEngine := make([]EngineStats, 0, len(engines))
+	for _, e := range engines {
+		byEngine = append(byEngine, *e)
+	}
+	sort.Slice(byEngine, func(i, j int) bool { return byEngine[i].ID < byEngine[j].ID })
+	return total, byTag, byEngine
+}
@@ -0,0 +1,163 @@
+package pool
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eloinsight/analysis-service/pkg/engine"
+	"github.com/eloinsight/analysis-service/pkg/enginetest"
+	"go.uber.org/zap"
+)
+
+func newQuickPool(t *testing.T, size int) *Pool {
+	t.Helper()
+	binary := enginetest.Engine{Name: "QuickFish"}.Binary(t)
+	p, err := NewPool(size, engine.Config{BinaryPath: binary, Threads: 1, Hash: 16, MultiPV: 1}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+// checkAttribution checks that the busy time of stats adds up the same by
+// tag and, when no engine left the pool, by engine, and the shares to 100%
+func checkAttribution(t *testing.T, stats Stats, enginesLeft bool) {
+	t.Helper()
+	var byTag, byEngine time.Duration
+	var shares float64
+	for _, tag := range stats.Tags {
+		byTag += tag.Busy
+		shares += tag.Share
+	}
+	for _, e := range stats.Engines {
+		for _, busy := range e.Busy {
+			byEngine += busy
+		}
+	}
+	if byTag != stats.BusyTime || !enginesLeft && byEngine != stats.BusyTime {
+		t.Errorf("busy time %v, by tag %v, by engine %v", stats.BusyTime, byTag, byEngine)
+	}
+	if stats.BusyTime > 0 && math.Abs(shares-100) > 1e-9 {
+		t.Errorf("shares add up to %v%%", shares)
+	}
+}
+
+func TestGetStats_TagsUnderMixedLoad(t *testing.T) {
+	p := newQuickPool(t, 3)
+
+	// Interactive calls, a batch import and untagged work compete for the
+	// engines, each holding them for a while
+	load := map[string]struct {
+		workers, loans int
+		hold           time.Duration
+	}{
+		"AnalyzePosition": {workers: 3, loans: 8, hold: 2 * time.Millisecond},
+		"queue":           {workers: 2, loans: 4, hold: 10 * time.Millisecond},
+		UntaggedTag:       {workers: 1, loans: 3, hold: time.Millisecond},
+	}
+	var wg sync.WaitGroup
+	for tag, l := range load {
+		ctx := context.Background()
+		if tag != UntaggedTag {
+			ctx = WithTag(ctx, tag)
+		}
+		for w := 0; w < l.workers; w++ {
+			wg.Add(1)
+			go func(hold time.Duration, loans int) {
+				defer wg.Done()
+				for i := 0; i < loans; i++ {
+					eng, err := p.Get(ctx)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					time.Sleep(hold)
+					p.Put(eng)
+				}
+			}(l.hold, l.loans)
+		}
+	}
+	wg.Wait()
+
+	stats := p.GetStats()
+	checkAttribution(t, stats, false)
+	if len(stats.Tags) != len(load) {
+		t.Fatalf("tags = %+v, want %d", stats.Tags, len(load))
+	}
+	for _, tag := range stats.Tags {
+		l := load[tag.Tag]
+		if want := int64(l.workers * l.loans); tag.Loans != want {
+			t.Errorf("%s: %d loans, want %d", tag.Tag, tag.Loans, want)
+		}
+		if min := time.Duration(l.workers*l.loans) * l.hold; tag.Busy < min {
+			t.Errorf("%s: busy %v, want at least %v", tag.Tag, tag.Busy, min)
+		}
+	}
+	if stats.Tags[0].Tag != "queue" {
+		t.Errorf("busiest tag = %s, want queue", stats.Tags[0].Tag)
+	}
+	if len(stats.Engines) != 3 {
+		t.Errorf("engines = %+v, want 3", stats.Engines)
+	}
+}
+
+func TestGetStats_LoansInProgress(t *testing.T) {
+	p := newQuickPool(t, 2)
+	ctx := WithTag(context.Background(), "AnalyzeGame")
+
+	eng, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	background, err := p.GetBackground(WithTag(context.Background(), "WarmCache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// An engine lent out counts up to now
+	stats := p.GetStats()
+	checkAttribution(t, stats, false)
+	for _, tag := range stats.Tags {
+		if tag.InUse != 1 || tag.Loans != 1 || tag.Busy < 5*time.Millisecond {
+			t.Errorf("%s: %+v, want one loan of 5ms or more in progress", tag.Tag, tag)
+		}
+	}
+
+	// A discarded engine's record goes, its time stays with its tag
+	p.Discard(eng)
+	p.Put(background)
+	stats = p.GetStats()
+	checkAttribution(t, stats, true)
+	if len(stats.Engines) != 1 || len(stats.Engines[0].Busy) != 1 || stats.Engines[0].Busy["WarmCache"] == 0 {
+		t.Errorf("engines = %+v, want the background engine alone", stats.Engines)
+	}
+	var analyzeGame time.Duration
+	for _, tag := range stats.Tags {
+		if tag.InUse != 0 {
+			t.Errorf("%s: %d engines in use after they were given back", tag.Tag, tag.InUse)
+		}
+		if tag.Tag == "AnalyzeGame" {
+			analyzeGame = tag.Busy
+		}
+	}
+	if analyzeGame < 5*time.Millisecond {
+		t.Errorf("AnalyzeGame busy = %v after its engine was discarded", analyzeGame)
+	}
+}
+
+func TestTagFrom(t *testing.T) {
+	if got := TagFrom(context.Background()); got != UntaggedTag {
+		t.Errorf("TagFrom(untagged) = %q", got)
+	}
+	if got := TagFrom(WithTag(context.Background(), "")); got != UntaggedTag {
+		t.Errorf("TagFrom(empty tag) = %q", got)
+	}
+	if got := TagFrom(WithTag(context.Background(), "AnalyzeGame")); got != "AnalyzeGame" {
+		t.Errorf("TagFrom = %q", got)
+	}
+}
@@ -0,0 +1,67 @@
+package timing
+
+import "time"
+
+// Capacity is how many game analyses of Moves moves at Depth a pool of
+// Engines engines can finish in an hour, from the engine time of recent
+// searches at depths of the same range. Queueing, cache hits and book
+// moves aren't counted: every position is taken as searched.
+type Capacity struct {
+	Depth     int
+	MinDepth  int // Depth range the estimate is from
+	MaxDepth  int
+	Moves     int // Full moves of a game
+	Positions int // Positions searched per game, the final one included
+	Engines   int
+
+	MsPerPosition    float64 // Mean engine time per search
+	P90MsPerPosition float64 // 90th percentile of it
+	GamesPerHour     float64 // At the mean
+	GamesPerHourP90  float64 // At the 90th percentile, for a cautious plan
+
+	Samples    float64 // Recent searches the estimate is from, decayed
+	Confidence float64 // From 0 without samples towards 1, see Stats.Confidence
+
+	// Known is false when nothing was searched at depths of the range yet;
+	// the rest is then 0 but for the request's values
+	Known bool
+}
+
+// Capacity estimates the game analyses of moves moves at depth that
+// engines engines can finish per hour
+func (r *Recorder) Capacity(depth, moves, engines int) Capacity {
+	capacity := Capacity{
+		Depth:     depth,
+		Moves:     moves,
+		Positions: 2*moves + 1,
+		Engines:   engines,
+	}
+	if depth < 1 || moves < 1 {
+		return capacity
+	}
+	bucket := (depth - 1) / DepthBucketSize
+	capacity.MinDepth = bucket*DepthBucketSize + 1
+	capacity.MaxDepth = (bucket + 1) * DepthBucketSize
+
+	stats, ok := r.bucket(bucket, r.now())
+	if !ok || stats.Samples <= 0 {
+		return capacity
+	}
+	capacity.Known = true
+	capacity.MsPerPosition = stats.MsPerPosition
+	capacity.P90MsPerPosition = stats.Percentile(0.9)
+	capacity.GamesPerHour = gamesPerHour(capacity.MsPerPosition, capacity.Positions, engines)
+	capacity.GamesPerHourP90 = gamesPerHour(capacity.P90MsPerPosition, capacity.Positions, engines)
+	capacity.Samples = stats.Samples
+	capacity.Confidence = stats.Confidence()
+	return capacity
+}
+
+// gamesPerHour is how many games of positions positions, each searched in
+// msPerPosition, engines engines get through in an hour
+func gamesPerHour(msPerPosition float64, positions, engines int) float64 {
+	if msPerPosition <= 0 || positions <= 0 {
+		return 0
+	}
+	return float64(engines) * float64(time.Hour.Milliseconds()) / (msPerPosition * float64(positions))
+}
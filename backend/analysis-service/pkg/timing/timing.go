@@ -0,0 +1,297 @@
+// Package timing keeps the rolling engine time per position of single-PV
+// searches, by depth range and game phase, for analysis ETAs, time budgets
+// and capacity planning.
+package timing
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DepthBucketSize is the width of the depth ranges engine time is
+	// kept for: 1-4, 5-8 and so on
+	DepthBucketSize = 4
+
+	// DefaultHalfLife is how long it takes a search to count half as much
+	// as a new one
+	DefaultHalfLife = time.Hour
+
+	// confidenceSamples is the number of recent searches, decayed, at
+	// which an estimate has a confidence of 0.5
+	confidenceSamples = 20
+)
+
+// HistogramBounds are the upper bounds, in milliseconds, of the bins of
+// Stats.Histogram; its last bin holds the longer searches
+var HistogramBounds = []int64{10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// Phase is the phase of the game a position is in, by its material
+type Phase string
+
+const (
+	PhaseOpening    Phase = "opening"    // 28 men or more on the board
+	PhaseMiddlegame Phase = "middlegame" // Anything between
+	PhaseEndgame    Phase = "endgame"    // 6 knights, bishops, rooks and queens or fewer
+)
+
+// Phases are the phases of a game, in order
+var Phases = []Phase{PhaseOpening, PhaseMiddlegame, PhaseEndgame}
+
+// PhaseOf returns the phase of the position of a FEN from the pieces on
+// the board
+func PhaseOf(fen string) Phase {
+	placement, _, _ := strings.Cut(fen, " ")
+	men, pieces := 0, 0
+	for _, c := range placement {
+		switch c {
+		case 'n', 'b', 'r', 'q', 'N', 'B', 'R', 'Q':
+			pieces++
+			men++
+		case 'p', 'k', 'P', 'K':
+			men++
+		}
+	}
+	switch {
+	case pieces <= 6:
+		return PhaseEndgame
+	case men >= 28:
+		return PhaseOpening
+	}
+	return PhaseMiddlegame
+}
+
+// Sample is one search: the engine time of a single-PV search to Depth of
+// a position in Phase, finished At
+type Sample struct {
+	Depth  int
+	Phase  Phase
+	TimeMs int64
+	At     time.Time
+}
+
+// Stats is the rolling engine time of the searches at depths MinDepth to
+// MaxDepth, of all phases or, in Phases, of one. Searches count for less
+// the older they are, halving every half-life.
+type Stats struct {
+	MinDepth      int
+	MaxDepth      int
+	Phase         Phase // "" for all phases
+	MsPerPosition float64
+	Samples       float64   // Searches, decayed
+	Searches      int64     // Searches recorded
+	Histogram     []float64 // Searches, decayed, by bin of HistogramBounds
+	Phases        []Stats   // By phase, those searched in order of Phases; only for all phases
+}
+
+// Percentile returns the engine time, in milliseconds, a fraction p of the
+// searches took at most, interpolated within the bins of the histogram.
+// Searches in the last bin count as taking its lower bound.
+func (s Stats) Percentile(p float64) float64 {
+	if s.Samples <= 0 || len(s.Histogram) == 0 {
+		return 0
+	}
+	target := p * s.Samples
+	var seen float64
+	for i, n := range s.Histogram {
+		if i == len(HistogramBounds) {
+			break
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = float64(HistogramBounds[i-1])
+		}
+		if n > 0 && seen+n >= target {
+			return lower + (float64(HistogramBounds[i])-lower)*(target-seen)/n
+		}
+		seen += n
+	}
+	return float64(HistogramBounds[len(HistogramBounds)-1])
+}
+
+// Confidence rates how well Stats is known from its recent searches, from
+// 0 without any towards 1
+func (s Stats) Confidence() float64 {
+	return s.Samples / (s.Samples + confidenceSamples)
+}
+
+// Recorder keeps the Stats of the searches recorded in it. The zero value
+// is ready to use, with DefaultHalfLife and the wall clock.
+type Recorder struct {
+	HalfLife time.Duration    // DefaultHalfLife if 0
+	Now      func() time.Time // time.Now if nil
+
+	mu    sync.Mutex
+	cells map[cell]*cellStats
+}
+
+// cell is a depth bucket and phase of the searches
+type cell struct {
+	bucket int
+	phase  Phase
+}
+
+// cellStats are the decayed sums of a cell, as of updated
+type cellStats struct {
+	sumMs    float64
+	samples  float64
+	bins     []float64
+	searches int64
+	updated  time.Time
+}
+
+func (r *Recorder) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// decay returns the weight of a search that is age old
+func (r *Recorder) decay(age time.Duration) float64 {
+	halfLife := r.HalfLife
+	if halfLife <= 0 {
+		halfLife = DefaultHalfLife
+	}
+	return math.Exp2(-age.Seconds() / halfLife.Seconds())
+}
+
+// decayTo ages c's sums to at
+func (r *Recorder) decayTo(c *cellStats, at time.Time) {
+	if !at.After(c.updated) {
+		return
+	}
+	factor := r.decay(at.Sub(c.updated))
+	c.sumMs *= factor
+	c.samples *= factor
+	for i := range c.bins {
+		c.bins[i] *= factor
+	}
+	c.updated = at
+}
+
+// Record adds a search to depth in phase that just finished
+func (r *Recorder) Record(depth int, phase Phase, timeMs int64) {
+	r.Add(Sample{Depth: depth, Phase: phase, TimeMs: timeMs, At: r.now()})
+}
+
+// Add adds a search. One at depth 0 or less is left out.
+func (r *Recorder) Add(s Sample) {
+	if s.Depth < 1 {
+		return
+	}
+	key := cell{bucket: (s.Depth - 1) / DepthBucketSize, phase: s.Phase}
+	bin := sort.Search(len(HistogramBounds), func(i int) bool { return s.TimeMs <= HistogramBounds[i] })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cells == nil {
+		r.cells = make(map[cell]*cellStats)
+	}
+	c, ok := r.cells[key]
+	if !ok {
+		c = &cellStats{bins: make([]float64, len(HistogramBounds)+1), updated: s.At}
+		r.cells[key] = c
+	}
+	r.decayTo(c, s.At)
+	// A search older than the latest counts as aged already
+	weight := r.decay(c.updated.Sub(s.At))
+	c.sumMs += weight * float64(s.TimeMs)
+	c.samples += weight
+	c.bins[bin] += weight
+	c.searches++
+}
+
+// Estimate returns the mean engine time of a search at depth, from the
+// searches at depths of its range in every phase. It is false until there
+// has been one.
+func (r *Recorder) Estimate(depth int) (time.Duration, bool) {
+	if depth < 1 {
+		return 0, false
+	}
+	stats, ok := r.bucket((depth-1)/DepthBucketSize, r.now())
+	if !ok || stats.Samples <= 0 {
+		return 0, false
+	}
+	return time.Duration(stats.MsPerPosition * float64(time.Millisecond)), true
+}
+
+// Snapshot returns the Stats of each depth range searched so far,
+// shallowest first
+func (r *Recorder) Snapshot() []Stats {
+	now := r.now()
+	r.mu.Lock()
+	buckets := make(map[int]bool)
+	for key := range r.cells {
+		buckets[key.bucket] = true
+	}
+	r.mu.Unlock()
+
+	snapshot := make([]Stats, 0, len(buckets))
+	for bucket := range buckets {
+		if stats, ok := r.bucket(bucket, now); ok {
+			snapshot = append(snapshot, stats)
+		}
+	}
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].MinDepth < snapshot[j].MinDepth })
+	return snapshot
+}
+
+// bucket sums the phases of a depth bucket as of now; false when none was
+// searched
+func (r *Recorder) bucket(bucket int, now time.Time) (Stats, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := Stats{
+		MinDepth:  bucket*DepthBucketSize + 1,
+		MaxDepth:  (bucket + 1) * DepthBucketSize,
+		Histogram: make([]float64, len(HistogramBounds)+1),
+	}
+	var sumMs float64
+	// Searches recorded without a phase count in the total only
+	for _, phase := range append([]Phase{""}, Phases...) {
+		c, ok := r.cells[cell{bucket: bucket, phase: phase}]
+		if !ok {
+			continue
+		}
+		r.decayTo(c, now)
+		sumMs += c.sumMs
+		total.Samples += c.samples
+		total.Searches += c.searches
+		for i, n := range c.bins {
+			total.Histogram[i] += n
+		}
+		if phase == "" {
+			continue
+		}
+		stats := Stats{
+			MinDepth:  total.MinDepth,
+			MaxDepth:  total.MaxDepth,
+			Phase:     phase,
+			Samples:   c.samples,
+			Searches:  c.searches,
+			Histogram: append([]float64(nil), c.bins...),
+		}
+		if c.samples > 0 {
+			stats.MsPerPosition = meanMs(c.sumMs, c.samples)
+		}
+		total.Phases = append(total.Phases, stats)
+	}
+	if total.Searches == 0 {
+		return Stats{}, false
+	}
+	if total.Samples > 0 {
+		total.MsPerPosition = meanMs(sumMs, total.Samples)
+	}
+	return total, true
+}
+
+// meanMs is sumMs over samples to the microsecond, so that the decay's
+// rounding errors don't show
+func meanMs(sumMs, samples float64) float64 {
+	return math.Round(sumMs/samples*1000) / 1000
+}
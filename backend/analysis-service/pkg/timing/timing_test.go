@@ -0,0 +1,143 @@
+package timing
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// clock is a settable time for a Recorder
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newRecorder() (*Recorder, *clock) {
+	c := &clock{t: time.Unix(1700000000, 0)}
+	return &Recorder{HalfLife: time.Hour, Now: c.now}, c
+}
+
+func TestPhaseOf(t *testing.T) {
+	for _, tt := range []struct {
+		fen  string
+		want Phase
+	}{
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", PhaseOpening},
+		{"r2q1rk1/pp2bppp/2n1pn2/3p4/3P4/2N1PN2/PP3PPP/R2Q1RK1 w - - 0 11", PhaseMiddlegame},
+		{"8/5pk1/6p1/8/3R4/6P1/r4PK1/8 w - - 0 40", PhaseEndgame},
+	} {
+		if got := PhaseOf(tt.fen); got != tt.want {
+			t.Errorf("PhaseOf(%s) = %s, want %s", tt.fen, got, tt.want)
+		}
+	}
+}
+
+func TestRecorder_Estimate(t *testing.T) {
+	r, _ := newRecorder()
+	if _, ok := r.Estimate(12); ok {
+		t.Fatal("estimate before any search")
+	}
+
+	r.Record(12, PhaseOpening, 100)
+	r.Record(10, PhaseEndgame, 200)
+	r.Record(2, PhaseOpening, 5)
+	r.Record(0, PhaseOpening, 1000) // No depth: ignored
+
+	// Both phases of 9-12 count
+	if got, ok := r.Estimate(9); !ok || got != 150*time.Millisecond {
+		t.Errorf("Estimate(9) = %v, %v; want 150ms", got, ok)
+	}
+	snapshot := r.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("snapshot = %+v, want two buckets", snapshot)
+	}
+	if first := snapshot[0]; first.MinDepth != 1 || first.MaxDepth != 4 || first.MsPerPosition != 5 || first.Searches != 1 {
+		t.Errorf("first bucket = %+v", first)
+	}
+	second := snapshot[1]
+	if second.MinDepth != 9 || second.MaxDepth != 12 || second.Searches != 2 || len(second.Phases) != 2 {
+		t.Fatalf("second bucket = %+v", second)
+	}
+	if opening := second.Phases[0]; opening.Phase != PhaseOpening || opening.MsPerPosition != 100 {
+		t.Errorf("opening of 9-12 = %+v", opening)
+	}
+	if endgame := second.Phases[1]; endgame.Phase != PhaseEndgame || endgame.MsPerPosition != 200 {
+		t.Errorf("endgame of 9-12 = %+v", endgame)
+	}
+}
+
+func TestRecorder_Decay(t *testing.T) {
+	r, c := newRecorder()
+	start := c.t
+	r.Add(Sample{Depth: 20, Phase: PhaseMiddlegame, TimeMs: 1000, At: start})
+	c.t = start.Add(time.Hour)
+	r.Record(20, PhaseMiddlegame, 400)
+
+	// The older search counts half: (0.5*1000 + 400) / 1.5
+	stats := r.Snapshot()[0]
+	if stats.MsPerPosition != 600 || math.Abs(stats.Samples-1.5) > 1e-9 || stats.Searches != 2 {
+		t.Errorf("after an hour: %+v, want 600ms from 1.5 samples", stats)
+	}
+
+	// A late sample is aged by its own time
+	r.Add(Sample{Depth: 20, Phase: PhaseMiddlegame, TimeMs: 1000, At: start})
+	if stats := r.Snapshot()[0]; math.Abs(stats.Samples-2) > 1e-9 {
+		t.Errorf("samples = %v, want 2", stats.Samples)
+	}
+
+	c.t = start.Add(3 * time.Hour)
+	if stats := r.Snapshot()[0]; math.Abs(stats.Samples-0.5) > 1e-9 {
+		t.Errorf("two hours later: samples = %v, want 0.5", stats.Samples)
+	}
+}
+
+func TestStats_Percentile(t *testing.T) {
+	r, _ := newRecorder()
+	for i := 0; i < 9; i++ {
+		r.Record(16, PhaseMiddlegame, 40) // 25-50 bin
+	}
+	r.Record(16, PhaseMiddlegame, 20000) // Beyond the last bound
+
+	stats := r.Snapshot()[0]
+	if got := stats.Percentile(0.5); got <= 25 || got > 50 {
+		t.Errorf("median = %v, want within 25-50", got)
+	}
+	if got := stats.Percentile(0.9); got != 50 {
+		t.Errorf("p90 = %v, want 50", got)
+	}
+	if got := stats.Percentile(1); got != 10000 {
+		t.Errorf("p100 = %v, want the last bound", got)
+	}
+	if got := (Stats{}).Percentile(0.5); got != 0 {
+		t.Errorf("empty median = %v", got)
+	}
+}
+
+func TestRecorder_Capacity(t *testing.T) {
+	r, _ := newRecorder()
+	if capacity := r.Capacity(22, 40, 4); capacity.Known || capacity.GamesPerHour != 0 || capacity.Positions != 81 {
+		t.Errorf("before any search: %+v", capacity)
+	}
+
+	for i := 0; i < 20; i++ {
+		r.Record(22, PhaseMiddlegame, 450)
+	}
+	capacity := r.Capacity(22, 40, 4)
+	if !capacity.Known || capacity.MinDepth != 21 || capacity.MaxDepth != 24 {
+		t.Fatalf("capacity = %+v", capacity)
+	}
+	// 4 engines * 3,600,000ms / (81 positions * 450ms)
+	if want := 4 * 3600000.0 / (81 * 450); math.Abs(capacity.GamesPerHour-want) > 1e-9 {
+		t.Errorf("games per hour = %v, want %v", capacity.GamesPerHour, want)
+	}
+	if capacity.GamesPerHourP90 <= 0 || capacity.GamesPerHourP90 > capacity.GamesPerHour {
+		t.Errorf("p90 games per hour = %v, want at most %v", capacity.GamesPerHourP90, capacity.GamesPerHour)
+	}
+	if math.Abs(capacity.Confidence-0.5) > 1e-9 {
+		t.Errorf("confidence = %v, want 0.5 from 20 samples", capacity.Confidence)
+	}
+
+	// Another depth range has nothing to go on
+	if capacity := r.Capacity(12, 40, 4); capacity.Known {
+		t.Errorf("depth 12: %+v, want unknown", capacity)
+	}
+}
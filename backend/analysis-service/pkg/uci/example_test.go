@@ -0,0 +1,30 @@
+package uci_test
+
+import (
+	"fmt"
+
+	"github.com/eloinsight/analysis-service/pkg/uci"
+)
+
+func ExampleParseInfo() {
+	info := uci.ParseInfo("info depth 20 seldepth 28 multipv 1 score cp 34 nodes 1480201 nps 987000 time 1500 pv e2e4 e7e5 g1f3")
+	fmt.Println(info.Depth, info.Centipawns, info.PV)
+
+	mate := uci.ParseInfo("info depth 12 score mate -3 pv h7h6")
+	fmt.Println(mate.IsMate, *mate.MateIn)
+	// Output:
+	// 20 34 [e2e4 e7e5 g1f3]
+	// true -3
+}
+
+func ExampleParseBestMove() {
+	best, ponder, ok := uci.ParseBestMove("bestmove e2e4 ponder e7e5")
+	fmt.Println(best, ponder, ok)
+	// Output: e2e4 e7e5 true
+}
+
+func ExampleParseBestMove_gameOver() {
+	best, _, ok := uci.ParseBestMove("bestmove (none)")
+	fmt.Printf("%q %v\n", best, ok)
+	// Output: "" true
+}
@@ -0,0 +1,98 @@
+// Package uci parses the output of engines speaking the Universal Chess
+// Interface. It has no engine process of its own; see package engine.
+package uci
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Info is a parsed "info" line of a search. Scores are from the side to
+// move's point of view.
+type Info struct {
+	Depth      int
+	SelDepth   int
+	MultiPV    int // 1-based; 0 when the engine didn't say
+	Centipawns int
+	MateIn     *int // Moves to mate, negative when getting mated
+	IsMate     bool
+	Nodes      int64
+	NPS        int64
+	TimeMs     int64
+	PV         []string // UCI moves
+}
+
+// IsInfo reports whether line is an info line carrying a search depth,
+// as opposed to strings and currmove updates
+func IsInfo(line string) bool {
+	return strings.HasPrefix(line, "info") && strings.Contains(line, "depth")
+}
+
+// ParseInfo parses an info line. Unknown and malformed fields are left
+// zero.
+func ParseInfo(line string) Info {
+	var info Info
+	parts := strings.Fields(line)
+
+	for i := 0; i < len(parts); i++ {
+		switch parts[i] {
+		case "depth":
+			if i+1 < len(parts) {
+				info.Depth, _ = strconv.Atoi(parts[i+1])
+			}
+		case "seldepth":
+			if i+1 < len(parts) {
+				info.SelDepth, _ = strconv.Atoi(parts[i+1])
+			}
+		case "multipv":
+			if i+1 < len(parts) {
+				info.MultiPV, _ = strconv.Atoi(parts[i+1])
+			}
+		case "score":
+			if i+1 < len(parts) {
+				if parts[i+1] == "cp" && i+2 < len(parts) {
+					info.Centipawns, _ = strconv.Atoi(parts[i+2])
+					info.IsMate = false
+				} else if parts[i+1] == "mate" && i+2 < len(parts) {
+					mateIn, _ := strconv.Atoi(parts[i+2])
+					info.MateIn = &mateIn
+					info.IsMate = true
+				}
+			}
+		case "nodes":
+			if i+1 < len(parts) {
+				info.Nodes, _ = strconv.ParseInt(parts[i+1], 10, 64)
+			}
+		case "nps":
+			if i+1 < len(parts) {
+				info.NPS, _ = strconv.ParseInt(parts[i+1], 10, 64)
+			}
+		case "time":
+			if i+1 < len(parts) {
+				info.TimeMs, _ = strconv.ParseInt(parts[i+1], 10, 64)
+			}
+		case "pv":
+			info.PV = parts[i+1:]
+			return info // PV is always at the end
+		}
+	}
+
+	return info
+}
+
+// ParseBestMove parses a "bestmove" line, which ends a search. ok is
+// false for any other line. best is empty when the engine has no move,
+// reported as "(none)" or "0000" in checkmate and stalemate.
+func ParseBestMove(line string) (best, ponder string, ok bool) {
+	if !strings.HasPrefix(line, "bestmove") {
+		return "", "", false
+	}
+	parts := strings.Fields(line)
+	if len(parts) >= 2 && parts[1] != "(none)" && parts[1] != "0000" {
+		best = parts[1]
+	}
+	if len(parts) >= 4 && parts[2] == "ponder" {
+		ponder = parts[3]
+	}
+	return best, ponder, true
+}
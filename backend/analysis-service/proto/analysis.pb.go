@@ -21,6 +21,142 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// How a ply of a game was analyzed
+type AnalysisSource int32
+
+const (
+	AnalysisSource_ANALYSIS_SOURCE_UNSPECIFIED AnalysisSource = 0 // Stored before sources were recorded
+	AnalysisSource_ENGINE                      AnalysisSource = 1 // Searched for this analysis
+	AnalysisSource_CACHE                       AnalysisSource = 2 // From the position cache
+	AnalysisSource_IMPORTED                    AnalysisSource = 3 // From the request's prefix, an imported evaluation database or the built-in seeds
+	AnalysisSource_BOOK_SKIPPED                AnalysisSource = 4 // Book move, left out of accuracy and ACPL
+	AnalysisSource_FORCED_SKIPPED              AnalysisSource = 5 // Only legal move, left out of move-mean accuracy
+	AnalysisSource_FAILED                      AnalysisSource = 6 // A search of the move's positions failed after every retry; not in moves
+	AnalysisSource_OUT_OF_RANGE                AnalysisSource = 7 // Not reached before the timeout or time budget, or uncached under cache_only; not in moves
+)
+
+// Enum value maps for AnalysisSource.
+var (
+	AnalysisSource_name = map[int32]string{
+		0: "ANALYSIS_SOURCE_UNSPECIFIED",
+		1: "ENGINE",
+		2: "CACHE",
+		3: "IMPORTED",
+		4: "BOOK_SKIPPED",
+		5: "FORCED_SKIPPED",
+		6: "FAILED",
+		7: "OUT_OF_RANGE",
+	}
+	AnalysisSource_value = map[string]int32{
+		"ANALYSIS_SOURCE_UNSPECIFIED": 0,
+		"ENGINE":                      1,
+		"CACHE":                       2,
+		"IMPORTED":                    3,
+		"BOOK_SKIPPED":                4,
+		"FORCED_SKIPPED":              5,
+		"FAILED":                      6,
+		"OUT_OF_RANGE":                7,
+	}
+)
+
+func (x AnalysisSource) Enum() *AnalysisSource {
+	p := new(AnalysisSource)
+	*p = x
+	return p
+}
+
+func (x AnalysisSource) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AnalysisSource) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_analysis_proto_enumTypes[0].Descriptor()
+}
+
+func (AnalysisSource) Type() protoreflect.EnumType {
+	return &file_proto_analysis_proto_enumTypes[0]
+}
+
+func (x AnalysisSource) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AnalysisSource.Descriptor instead.
+func (AnalysisSource) EnumDescriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{0}
+}
+
+// How a game ended
+type Termination int32
+
+const (
+	Termination_TERMINATION_UNKNOWN   Termination = 0 // No Termination tag and nothing on the board to tell
+	Termination_CHECKMATE             Termination = 1
+	Termination_RESIGNATION           Termination = 2
+	Termination_TIME_FORFEIT          Termination = 3 // A flag fell; a draw when the opponent couldn't mate
+	Termination_ABANDONMENT           Termination = 4
+	Termination_STALEMATE             Termination = 5
+	Termination_INSUFFICIENT_MATERIAL Termination = 6
+	Termination_REPETITION            Termination = 7
+	Termination_FIFTY_MOVE_RULE       Termination = 8
+	Termination_AGREEMENT             Termination = 9
+)
+
+// Enum value maps for Termination.
+var (
+	Termination_name = map[int32]string{
+		0: "TERMINATION_UNKNOWN",
+		1: "CHECKMATE",
+		2: "RESIGNATION",
+		3: "TIME_FORFEIT",
+		4: "ABANDONMENT",
+		5: "STALEMATE",
+		6: "INSUFFICIENT_MATERIAL",
+		7: "REPETITION",
+		8: "FIFTY_MOVE_RULE",
+		9: "AGREEMENT",
+	}
+	Termination_value = map[string]int32{
+		"TERMINATION_UNKNOWN":   0,
+		"CHECKMATE":             1,
+		"RESIGNATION":           2,
+		"TIME_FORFEIT":          3,
+		"ABANDONMENT":           4,
+		"STALEMATE":             5,
+		"INSUFFICIENT_MATERIAL": 6,
+		"REPETITION":            7,
+		"FIFTY_MOVE_RULE":       8,
+		"AGREEMENT":             9,
+	}
+)
+
+func (x Termination) Enum() *Termination {
+	p := new(Termination)
+	*p = x
+	return p
+}
+
+func (x Termination) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Termination) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_analysis_proto_enumTypes[1].Descriptor()
+}
+
+func (Termination) Type() protoreflect.EnumType {
+	return &file_proto_analysis_proto_enumTypes[1]
+}
+
+func (x Termination) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Termination.Descriptor instead.
+func (Termination) EnumDescriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{1}
+}
+
 // Move classification enum
 type MoveClassification int32
 
@@ -82,11 +218,11 @@ func (x MoveClassification) String() string {
 }
 
 func (MoveClassification) Descriptor() protoreflect.EnumDescriptor {
-	return file_proto_analysis_proto_enumTypes[0].Descriptor()
+	return file_proto_analysis_proto_enumTypes[2].Descriptor()
 }
 
 func (MoveClassification) Type() protoreflect.EnumType {
-	return &file_proto_analysis_proto_enumTypes[0]
+	return &file_proto_analysis_proto_enumTypes[2]
 }
 
 func (x MoveClassification) Number() protoreflect.EnumNumber {
@@ -95,16 +231,176 @@ func (x MoveClassification) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use MoveClassification.Descriptor instead.
 func (MoveClassification) EnumDescriptor() ([]byte, []int) {
-	return file_proto_analysis_proto_rawDescGZIP(), []int{0}
+	return file_proto_analysis_proto_rawDescGZIP(), []int{2}
+}
+
+// Point of view of move evaluations in a game analysis
+type EvalPerspective int32
+
+const (
+	EvalPerspective_EVAL_PERSPECTIVE_UNSPECIFIED EvalPerspective = 0 // Same as SIDE_TO_MOVE
+	EvalPerspective_SIDE_TO_MOVE                 EvalPerspective = 1 // Positive = good for the side to move in that position, as engines report
+	EvalPerspective_WHITE                        EvalPerspective = 2 // Positive = good for White
+)
+
+// Enum value maps for EvalPerspective.
+var (
+	EvalPerspective_name = map[int32]string{
+		0: "EVAL_PERSPECTIVE_UNSPECIFIED",
+		1: "SIDE_TO_MOVE",
+		2: "WHITE",
+	}
+	EvalPerspective_value = map[string]int32{
+		"EVAL_PERSPECTIVE_UNSPECIFIED": 0,
+		"SIDE_TO_MOVE":                 1,
+		"WHITE":                        2,
+	}
+)
+
+func (x EvalPerspective) Enum() *EvalPerspective {
+	p := new(EvalPerspective)
+	*p = x
+	return p
+}
+
+func (x EvalPerspective) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EvalPerspective) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_analysis_proto_enumTypes[3].Descriptor()
+}
+
+func (EvalPerspective) Type() protoreflect.EnumType {
+	return &file_proto_analysis_proto_enumTypes[3]
+}
+
+func (x EvalPerspective) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EvalPerspective.Descriptor instead.
+func (EvalPerspective) EnumDescriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{3}
+}
+
+// How a player's game accuracy is scored, to compare it with other sites
+type AccuracyModel int32
+
+const (
+	AccuracyModel_ACCURACY_MODEL_UNSPECIFIED AccuracyModel = 0 // Same as ELOINSIGHT
+	AccuracyModel_ELOINSIGHT                 AccuracyModel = 1 // The service's own accuracy, by its ACCURACY_METHOD
+	AccuracyModel_LICHESS                    AccuracyModel = 2 // Lichess's: mean of the volatility-weighted and harmonic means of move accuracies
+	// An approximation of chess.com's CAPS accuracy, whose formula isn't
+	// public: the mean of move accuracies from win probability deltas,
+	// leaving out book moves. Expect it to differ from chess.com's own
+	// number by a few points.
+	AccuracyModel_CHESSCOM_APPROX AccuracyModel = 3
+)
+
+// Enum value maps for AccuracyModel.
+var (
+	AccuracyModel_name = map[int32]string{
+		0: "ACCURACY_MODEL_UNSPECIFIED",
+		1: "ELOINSIGHT",
+		2: "LICHESS",
+		3: "CHESSCOM_APPROX",
+	}
+	AccuracyModel_value = map[string]int32{
+		"ACCURACY_MODEL_UNSPECIFIED": 0,
+		"ELOINSIGHT":                 1,
+		"LICHESS":                    2,
+		"CHESSCOM_APPROX":            3,
+	}
+)
+
+func (x AccuracyModel) Enum() *AccuracyModel {
+	p := new(AccuracyModel)
+	*p = x
+	return p
+}
+
+func (x AccuracyModel) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AccuracyModel) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_analysis_proto_enumTypes[4].Descriptor()
+}
+
+func (AccuracyModel) Type() protoreflect.EnumType {
+	return &file_proto_analysis_proto_enumTypes[4]
+}
+
+func (x AccuracyModel) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AccuracyModel.Descriptor instead.
+func (AccuracyModel) EnumDescriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{4}
+}
+
+// Output format of an exported game analysis
+type ExportFormat int32
+
+const (
+	ExportFormat_EXPORT_FORMAT_UNSPECIFIED ExportFormat = 0 // Same as ANNOTATED_PGN
+	ExportFormat_ANNOTATED_PGN             ExportFormat = 1 // Original PGN with [%eval] comments, NAGs and best lines
+	ExportFormat_JSON                      ExportFormat = 2 // JSON form of the GameAnalysis message
+)
+
+// Enum value maps for ExportFormat.
+var (
+	ExportFormat_name = map[int32]string{
+		0: "EXPORT_FORMAT_UNSPECIFIED",
+		1: "ANNOTATED_PGN",
+		2: "JSON",
+	}
+	ExportFormat_value = map[string]int32{
+		"EXPORT_FORMAT_UNSPECIFIED": 0,
+		"ANNOTATED_PGN":             1,
+		"JSON":                      2,
+	}
+)
+
+func (x ExportFormat) Enum() *ExportFormat {
+	p := new(ExportFormat)
+	*p = x
+	return p
+}
+
+func (x ExportFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ExportFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_analysis_proto_enumTypes[5].Descriptor()
+}
+
+func (ExportFormat) Type() protoreflect.EnumType {
+	return &file_proto_analysis_proto_enumTypes[5]
+}
+
+func (x ExportFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ExportFormat.Descriptor instead.
+func (ExportFormat) EnumDescriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{5}
 }
 
 // Request to analyze a single position
 type AnalyzePositionRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Fen           string                 `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"`                               // FEN string of the position
-	Depth         int32                  `protobuf:"varint,2,opt,name=depth,proto3" json:"depth,omitempty"`                          // Analysis depth (10-30)
-	MultiPv       int32                  `protobuf:"varint,3,opt,name=multi_pv,json=multiPv,proto3" json:"multi_pv,omitempty"`       // Number of principal variations (1-5)
-	TimeoutMs     int32                  `protobuf:"varint,4,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"` // Timeout in milliseconds (optional)
+	Fen           string                 `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"`                                  // FEN string of the position
+	Depth         int32                  `protobuf:"varint,2,opt,name=depth,proto3" json:"depth,omitempty"`                             // Analysis depth (0 = DEFAULT_DEPTH, clamped to MIN_DEPTH-MAX_DEPTH)
+	MultiPv       int32                  `protobuf:"varint,3,opt,name=multi_pv,json=multiPv,proto3" json:"multi_pv,omitempty"`          // Number of principal variations (0 = 1, at most MAX_MULTI_PV)
+	TimeoutMs     int32                  `protobuf:"varint,4,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`    // Timeout in milliseconds (optional)
+	UseCache      *bool                  `protobuf:"varint,5,opt,name=use_cache,json=useCache,proto3,oneof" json:"use_cache,omitempty"` // Answer from the position cache when possible (unset = true)
+	CacheOnly     bool                   `protobuf:"varint,6,opt,name=cache_only,json=cacheOnly,proto3" json:"cache_only,omitempty"`    // Never search: NOT_CACHED unless the position is cached at the depth with at least multi_pv PVs
+	NoStore       bool                   `protobuf:"varint,7,opt,name=no_store,json=noStore,proto3" json:"no_store,omitempty"`          // Don't cache the searches of this request
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -167,19 +463,52 @@ func (x *AnalyzePositionRequest) GetTimeoutMs() int32 {
 	return 0
 }
 
+func (x *AnalyzePositionRequest) GetUseCache() bool {
+	if x != nil && x.UseCache != nil {
+		return *x.UseCache
+	}
+	return false
+}
+
+func (x *AnalyzePositionRequest) GetCacheOnly() bool {
+	if x != nil {
+		return x.CacheOnly
+	}
+	return false
+}
+
+func (x *AnalyzePositionRequest) GetNoStore() bool {
+	if x != nil {
+		return x.NoStore
+	}
+	return false
+}
+
 // Analysis result for a single position
 type PositionAnalysis struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Fen           string                 `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"`                           // FEN of analyzed position
-	Depth         int32                  `protobuf:"varint,2,opt,name=depth,proto3" json:"depth,omitempty"`                      // Depth reached
-	Evaluation    *Evaluation            `protobuf:"bytes,3,opt,name=evaluation,proto3" json:"evaluation,omitempty"`             // Position evaluation
-	BestMove      string                 `protobuf:"bytes,4,opt,name=best_move,json=bestMove,proto3" json:"best_move,omitempty"` // Best move in UCI format
-	Pv            []string               `protobuf:"bytes,5,rep,name=pv,proto3" json:"pv,omitempty"`                             // Principal variation (best line)
-	Nodes         int64                  `protobuf:"varint,6,opt,name=nodes,proto3" json:"nodes,omitempty"`                      // Nodes searched
-	Nps           int64                  `protobuf:"varint,7,opt,name=nps,proto3" json:"nps,omitempty"`                          // Nodes per second
-	TimeMs        int64                  `protobuf:"varint,8,opt,name=time_ms,json=timeMs,proto3" json:"time_ms,omitempty"`      // Time taken in milliseconds
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Fen            string                 `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"`                                                // FEN of analyzed position
+	Depth          int32                  `protobuf:"varint,2,opt,name=depth,proto3" json:"depth,omitempty"`                                           // Depth reached
+	Evaluation     *Evaluation            `protobuf:"bytes,3,opt,name=evaluation,proto3" json:"evaluation,omitempty"`                                  // Position evaluation
+	BestMove       string                 `protobuf:"bytes,4,opt,name=best_move,json=bestMove,proto3" json:"best_move,omitempty"`                      // Best move in UCI format
+	Pv             []string               `protobuf:"bytes,5,rep,name=pv,proto3" json:"pv,omitempty"`                                                  // Principal variation (best line)
+	Nodes          int64                  `protobuf:"varint,6,opt,name=nodes,proto3" json:"nodes,omitempty"`                                           // Nodes searched
+	Nps            int64                  `protobuf:"varint,7,opt,name=nps,proto3" json:"nps,omitempty"`                                               // Nodes per second
+	TimeMs         int64                  `protobuf:"varint,8,opt,name=time_ms,json=timeMs,proto3" json:"time_ms,omitempty"`                           // Time taken in milliseconds: queue_time_ms + search_time_ms
+	TargetDepth    int32                  `protobuf:"varint,9,opt,name=target_depth,json=targetDepth,proto3" json:"target_depth,omitempty"`            // Depth searched for, after clamping to the service limits
+	TimedOut       bool                   `protobuf:"varint,10,opt,name=timed_out,json=timedOut,proto3" json:"timed_out,omitempty"`                    // Search stopped by the analysis timeout before target_depth
+	Source         string                 `protobuf:"bytes,11,opt,name=source,proto3" json:"source,omitempty"`                                         // "engine" (local Stockfish), "cloud" (Lichess cloud eval) or "imported" (evaluation import) or "seed" (built-in opening evaluations)
+	GameOverReason string                 `protobuf:"bytes,12,opt,name=game_over_reason,json=gameOverReason,proto3" json:"game_over_reason,omitempty"` // "checkmate" or "stalemate" when the side to move has no legal move: nothing is searched, evaluation is mate 0 or 0 and best_move is empty
+	MultiPv        int32                  `protobuf:"varint,13,opt,name=multi_pv,json=multiPv,proto3" json:"multi_pv,omitempty"`                       // Principal variations searched, after applying the default and MAX_MULTI_PV
+	QueueTimeMs    int64                  `protobuf:"varint,14,opt,name=queue_time_ms,json=queueTimeMs,proto3" json:"queue_time_ms,omitempty"`         // Waiting for a free engine (or the cloud fallback); time_ms is queue_time_ms + search_time_ms
+	SearchTimeMs   int64                  `protobuf:"varint,15,opt,name=search_time_ms,json=searchTimeMs,proto3" json:"search_time_ms,omitempty"`      // Searching, wall clock; both are 0 for cached answers
+	PoolWaiting    int32                  `protobuf:"varint,16,opt,name=pool_waiting,json=poolWaiting,proto3" json:"pool_waiting,omitempty"`           // Requests already waiting for an engine when this one asked
+	PoolAvailable  int32                  `protobuf:"varint,17,opt,name=pool_available,json=poolAvailable,proto3" json:"pool_available,omitempty"`     // Free engines when this one asked
+	PonderMoveUci  string                 `protobuf:"bytes,18,opt,name=ponder_move_uci,json=ponderMoveUci,proto3" json:"ponder_move_uci,omitempty"`    // Reply the engine expects to best_move, from its bestmove line or else its PV (empty = none)
+	PonderMoveSan  string                 `protobuf:"bytes,19,opt,name=ponder_move_san,json=ponderMoveSan,proto3" json:"ponder_move_san,omitempty"`    // ponder_move_uci in SAN, played after best_move
+	ServedStale    bool                   `protobuf:"varint,20,opt,name=served_stale,json=servedStale,proto3" json:"served_stale,omitempty"`           // Answered from a cached search at depth, up to STALE_DEPTH_MARGIN below target_depth, while a background search to target_depth refreshes the cache
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *PositionAnalysis) Reset() {
@@ -268,9 +597,96 @@ func (x *PositionAnalysis) GetTimeMs() int64 {
 	return 0
 }
 
+func (x *PositionAnalysis) GetTargetDepth() int32 {
+	if x != nil {
+		return x.TargetDepth
+	}
+	return 0
+}
+
+func (x *PositionAnalysis) GetTimedOut() bool {
+	if x != nil {
+		return x.TimedOut
+	}
+	return false
+}
+
+func (x *PositionAnalysis) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *PositionAnalysis) GetGameOverReason() string {
+	if x != nil {
+		return x.GameOverReason
+	}
+	return ""
+}
+
+func (x *PositionAnalysis) GetMultiPv() int32 {
+	if x != nil {
+		return x.MultiPv
+	}
+	return 0
+}
+
+func (x *PositionAnalysis) GetQueueTimeMs() int64 {
+	if x != nil {
+		return x.QueueTimeMs
+	}
+	return 0
+}
+
+func (x *PositionAnalysis) GetSearchTimeMs() int64 {
+	if x != nil {
+		return x.SearchTimeMs
+	}
+	return 0
+}
+
+func (x *PositionAnalysis) GetPoolWaiting() int32 {
+	if x != nil {
+		return x.PoolWaiting
+	}
+	return 0
+}
+
+func (x *PositionAnalysis) GetPoolAvailable() int32 {
+	if x != nil {
+		return x.PoolAvailable
+	}
+	return 0
+}
+
+func (x *PositionAnalysis) GetPonderMoveUci() string {
+	if x != nil {
+		return x.PonderMoveUci
+	}
+	return ""
+}
+
+func (x *PositionAnalysis) GetPonderMoveSan() string {
+	if x != nil {
+		return x.PonderMoveSan
+	}
+	return ""
+}
+
+func (x *PositionAnalysis) GetServedStale() bool {
+	if x != nil {
+		return x.ServedStale
+	}
+	return false
+}
+
 // Position evaluation
 type Evaluation struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
+	// Game analyses use the request's eval_perspective; everything else is
+	// from the side to move
+	//
 	// Types that are valid to be assigned to Score:
 	//
 	//	*Evaluation_Centipawns
@@ -348,11 +764,11 @@ type isEvaluation_Score interface {
 }
 
 type Evaluation_Centipawns struct {
-	Centipawns int32 `protobuf:"varint,1,opt,name=centipawns,proto3,oneof"` // Score in centipawns (positive = white better)
+	Centipawns int32 `protobuf:"varint,1,opt,name=centipawns,proto3,oneof"` // Score in centipawns (positive = better for the perspective's side)
 }
 
 type Evaluation_MateIn struct {
-	MateIn int32 `protobuf:"varint,2,opt,name=mate_in,json=mateIn,proto3,oneof"` // Mate in N moves (positive = white mates, negative = black mates)
+	MateIn int32 `protobuf:"varint,2,opt,name=mate_in,json=mateIn,proto3,oneof"` // Mate in N moves (positive = the perspective's side mates)
 }
 
 func (*Evaluation_Centipawns) isEvaluation_Score() {}
@@ -361,14 +777,36 @@ func (*Evaluation_MateIn) isEvaluation_Score() {}
 
 // Request to analyze a full game
 type AnalyzeGameRequest struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	GameId           string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`                                  // Game identifier
-	Pgn              string                 `protobuf:"bytes,2,opt,name=pgn,proto3" json:"pgn,omitempty"`                                                      // PGN of the game
-	Depth            int32                  `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"`                                                 // Analysis depth per move
-	MultiPv          int32                  `protobuf:"varint,4,opt,name=multi_pv,json=multiPv,proto3" json:"multi_pv,omitempty"`                              // MultiPV for each position
-	IncludeBookMoves bool                   `protobuf:"varint,5,opt,name=include_book_moves,json=includeBookMoves,proto3" json:"include_book_moves,omitempty"` // Analyze opening book moves
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	GameId             string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`                                                            // Game identifier
+	Pgn                string                 `protobuf:"bytes,2,opt,name=pgn,proto3" json:"pgn,omitempty"`                                                                                // PGN of the game
+	Depth              int32                  `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"`                                                                           // Analysis depth per move
+	MultiPv            int32                  `protobuf:"varint,4,opt,name=multi_pv,json=multiPv,proto3" json:"multi_pv,omitempty"`                                                        // MultiPV for each position
+	IncludeBookMoves   bool                   `protobuf:"varint,5,opt,name=include_book_moves,json=includeBookMoves,proto3" json:"include_book_moves,omitempty"`                           // Analyze opening book moves
+	ThresholdProfile   string                 `protobuf:"bytes,6,opt,name=threshold_profile,json=thresholdProfile,proto3" json:"threshold_profile,omitempty"`                              // Classification thresholds: standard, strict, lenient (empty = server default)
+	EngineProfile      string                 `protobuf:"bytes,7,opt,name=engine_profile,json=engineProfile,proto3" json:"engine_profile,omitempty"`                                       // Engine to analyze with (empty = primary)
+	CrossCheckEngine   string                 `protobuf:"bytes,8,opt,name=cross_check_engine,json=crossCheckEngine,proto3" json:"cross_check_engine,omitempty"`                            // Also analyze with this engine profile and compare (AnalyzeGame only)
+	AnalyzeUntilError  bool                   `protobuf:"varint,9,opt,name=analyze_until_error,json=analyzeUntilError,proto3" json:"analyze_until_error,omitempty"`                        // Analyze the moves before an illegal or ambiguous move instead of failing
+	EvalPerspective    EvalPerspective        `protobuf:"varint,10,opt,name=eval_perspective,json=evalPerspective,proto3,enum=analysis.EvalPerspective" json:"eval_perspective,omitempty"` // Sign convention of move evaluations (default SIDE_TO_MOVE)
+	ExcludeGarbageTime *bool                  `protobuf:"varint,11,opt,name=exclude_garbage_time,json=excludeGarbageTime,proto3,oneof" json:"exclude_garbage_time,omitempty"`              // Leave moves in decided positions out of accuracy and ACPL (unset = server default)
+	Persist            bool                   `protobuf:"varint,12,opt,name=persist,proto3" json:"persist,omitempty"`                                                                      // Store the analysis in PostgreSQL and return its row IDs, on the final analysis of a stream (needs the Postgres sink)
+	Result             string                 `protobuf:"bytes,13,opt,name=result,proto3" json:"result,omitempty"`                                                                         // Game result for resilience: 1-0, 0-1, 1/2-1/2 or * (empty = the PGN's Result tag)
+	StartPly           int32                  `protobuf:"varint,14,opt,name=start_ply,json=startPly,proto3" json:"start_ply,omitempty"`                                                    // First move searched; the positions before it take prefix_evaluations
+	PrefixEvaluations  []*PrefixEvaluation    `protobuf:"bytes,15,rep,name=prefix_evaluations,json=prefixEvaluations,proto3" json:"prefix_evaluations,omitempty"`                          // One per ply before start_ply
+	AccuracyModel      AccuracyModel          `protobuf:"varint,16,opt,name=accuracy_model,json=accuracyModel,proto3,enum=analysis.AccuracyModel" json:"accuracy_model,omitempty"`         // How accuracy is scored (default ELOINSIGHT)
+	UseCache           *bool                  `protobuf:"varint,17,opt,name=use_cache,json=useCache,proto3,oneof" json:"use_cache,omitempty"`                                              // Use cached evaluations (unset = true)
+	CacheOnly          bool                   `protobuf:"varint,18,opt,name=cache_only,json=cacheOnly,proto3" json:"cache_only,omitempty"`                                                 // Never search: only moves with both positions cached are analyzed
+	NoStore            bool                   `protobuf:"varint,19,opt,name=no_store,json=noStore,proto3" json:"no_store,omitempty"`                                                       // Don't cache the searches of this request
+	TimeBudgetMs       int64                  `protobuf:"varint,20,opt,name=time_budget_ms,json=timeBudgetMs,proto3" json:"time_budget_ms,omitempty"`                                      // Search the uncached positions by movetime within this budget instead of to depth (0 = none)
+	RecordEngineOutput bool                   `protobuf:"varint,21,opt,name=record_engine_output,json=recordEngineOutput,proto3" json:"record_engine_output,omitempty"`                    // Keep each position's UCI conversation for AdminService.GetEngineTranscript by the response's transcript_job_id (needs ENGINE_TRANSCRIPTS_ENABLED and x-admin-token)
+	FullDepth          bool                   `protobuf:"varint,22,opt,name=full_depth,json=fullDepth,proto3" json:"full_depth,omitempty"`                                                 // Search a game over ServiceInfo.fast_mode_plies to the requested depth anyway
+	// AnalyzeGameStream only, AnalyzeGame rejects it: first analyze the whole
+	// game at this depth and send it as a preliminary progress message, then
+	// search to depth (0 = no preview; ignored unless shallower than the depth
+	// searched, not allowed with cache_only or time_budget_ms)
+	PreviewDepth  int32 `protobuf:"varint,23,opt,name=preview_depth,json=previewDepth,proto3" json:"preview_depth,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *AnalyzeGameRequest) Reset() {
@@ -436,120 +874,5482 @@ func (x *AnalyzeGameRequest) GetIncludeBookMoves() bool {
 	return false
 }
 
-// Full game analysis result
-type GameAnalysis struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	GameId        string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
-	Moves         []*MoveAnalysis        `protobuf:"bytes,2,rep,name=moves,proto3" json:"moves,omitempty"`
-	WhiteMetrics  *GameMetrics           `protobuf:"bytes,3,opt,name=white_metrics,json=whiteMetrics,proto3" json:"white_metrics,omitempty"`
-	BlackMetrics  *GameMetrics           `protobuf:"bytes,4,opt,name=black_metrics,json=blackMetrics,proto3" json:"black_metrics,omitempty"`
-	TotalTimeMs   int64                  `protobuf:"varint,5,opt,name=total_time_ms,json=totalTimeMs,proto3" json:"total_time_ms,omitempty"`
-	EngineVersion string                 `protobuf:"bytes,6,opt,name=engine_version,json=engineVersion,proto3" json:"engine_version,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *AnalyzeGameRequest) GetThresholdProfile() string {
+	if x != nil {
+		return x.ThresholdProfile
+	}
+	return ""
 }
 
-func (x *GameAnalysis) Reset() {
-	*x = GameAnalysis{}
-	mi := &file_proto_analysis_proto_msgTypes[4]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *AnalyzeGameRequest) GetEngineProfile() string {
+	if x != nil {
+		return x.EngineProfile
+	}
+	return ""
 }
 
-func (x *GameAnalysis) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *AnalyzeGameRequest) GetCrossCheckEngine() string {
+	if x != nil {
+		return x.CrossCheckEngine
+	}
+	return ""
 }
 
-func (*GameAnalysis) ProtoMessage() {}
-
-func (x *GameAnalysis) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_analysis_proto_msgTypes[4]
+func (x *AnalyzeGameRequest) GetAnalyzeUntilError() bool {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.AnalyzeUntilError
 	}
-	return mi.MessageOf(x)
+	return false
 }
 
-// Deprecated: Use GameAnalysis.ProtoReflect.Descriptor instead.
-func (*GameAnalysis) Descriptor() ([]byte, []int) {
+func (x *AnalyzeGameRequest) GetEvalPerspective() EvalPerspective {
+	if x != nil {
+		return x.EvalPerspective
+	}
+	return EvalPerspective_EVAL_PERSPECTIVE_UNSPECIFIED
+}
+
+func (x *AnalyzeGameRequest) GetExcludeGarbageTime() bool {
+	if x != nil && x.ExcludeGarbageTime != nil {
+		return *x.ExcludeGarbageTime
+	}
+	return false
+}
+
+func (x *AnalyzeGameRequest) GetPersist() bool {
+	if x != nil {
+		return x.Persist
+	}
+	return false
+}
+
+func (x *AnalyzeGameRequest) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *AnalyzeGameRequest) GetStartPly() int32 {
+	if x != nil {
+		return x.StartPly
+	}
+	return 0
+}
+
+func (x *AnalyzeGameRequest) GetPrefixEvaluations() []*PrefixEvaluation {
+	if x != nil {
+		return x.PrefixEvaluations
+	}
+	return nil
+}
+
+func (x *AnalyzeGameRequest) GetAccuracyModel() AccuracyModel {
+	if x != nil {
+		return x.AccuracyModel
+	}
+	return AccuracyModel_ACCURACY_MODEL_UNSPECIFIED
+}
+
+func (x *AnalyzeGameRequest) GetUseCache() bool {
+	if x != nil && x.UseCache != nil {
+		return *x.UseCache
+	}
+	return false
+}
+
+func (x *AnalyzeGameRequest) GetCacheOnly() bool {
+	if x != nil {
+		return x.CacheOnly
+	}
+	return false
+}
+
+func (x *AnalyzeGameRequest) GetNoStore() bool {
+	if x != nil {
+		return x.NoStore
+	}
+	return false
+}
+
+func (x *AnalyzeGameRequest) GetTimeBudgetMs() int64 {
+	if x != nil {
+		return x.TimeBudgetMs
+	}
+	return 0
+}
+
+func (x *AnalyzeGameRequest) GetRecordEngineOutput() bool {
+	if x != nil {
+		return x.RecordEngineOutput
+	}
+	return false
+}
+
+func (x *AnalyzeGameRequest) GetFullDepth() bool {
+	if x != nil {
+		return x.FullDepth
+	}
+	return false
+}
+
+func (x *AnalyzeGameRequest) GetPreviewDepth() int32 {
+	if x != nil {
+		return x.PreviewDepth
+	}
+	return 0
+}
+
+// An earlier evaluation of a position before start_ply, used instead of
+// searching it again
+type PrefixEvaluation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ply           int32                  `protobuf:"varint,1,opt,name=ply,proto3" json:"ply,omitempty"`                                     // Position before this ply's move (0 = starting position)
+	Evaluation    *Evaluation            `protobuf:"bytes,2,opt,name=evaluation,proto3" json:"evaluation,omitempty"`                        // In the request's eval_perspective
+	BestMoveUci   string                 `protobuf:"bytes,3,opt,name=best_move_uci,json=bestMoveUci,proto3" json:"best_move_uci,omitempty"` // Optional, must be legal in the position
+	Depth         int32                  `protobuf:"varint,4,opt,name=depth,proto3" json:"depth,omitempty"`                                 // Depth it was searched to
+	Fen           string                 `protobuf:"bytes,5,opt,name=fen,proto3" json:"fen,omitempty"`                                      // Optional, must match the game's position
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrefixEvaluation) Reset() {
+	*x = PrefixEvaluation{}
+	mi := &file_proto_analysis_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrefixEvaluation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrefixEvaluation) ProtoMessage() {}
+
+func (x *PrefixEvaluation) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrefixEvaluation.ProtoReflect.Descriptor instead.
+func (*PrefixEvaluation) Descriptor() ([]byte, []int) {
 	return file_proto_analysis_proto_rawDescGZIP(), []int{4}
 }
 
+func (x *PrefixEvaluation) GetPly() int32 {
+	if x != nil {
+		return x.Ply
+	}
+	return 0
+}
+
+func (x *PrefixEvaluation) GetEvaluation() *Evaluation {
+	if x != nil {
+		return x.Evaluation
+	}
+	return nil
+}
+
+func (x *PrefixEvaluation) GetBestMoveUci() string {
+	if x != nil {
+		return x.BestMoveUci
+	}
+	return ""
+}
+
+func (x *PrefixEvaluation) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *PrefixEvaluation) GetFen() string {
+	if x != nil {
+		return x.Fen
+	}
+	return ""
+}
+
+// Full game analysis result
+type GameAnalysis struct {
+	state                  protoimpl.MessageState    `protogen:"open.v1"`
+	GameId                 string                    `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	Moves                  []*MoveAnalysis           `protobuf:"bytes,2,rep,name=moves,proto3" json:"moves,omitempty"`
+	WhiteMetrics           *GameMetrics              `protobuf:"bytes,3,opt,name=white_metrics,json=whiteMetrics,proto3" json:"white_metrics,omitempty"`
+	BlackMetrics           *GameMetrics              `protobuf:"bytes,4,opt,name=black_metrics,json=blackMetrics,proto3" json:"black_metrics,omitempty"`
+	TotalTimeMs            int64                     `protobuf:"varint,5,opt,name=total_time_ms,json=totalTimeMs,proto3" json:"total_time_ms,omitempty"`
+	EngineVersion          string                    `protobuf:"bytes,6,opt,name=engine_version,json=engineVersion,proto3" json:"engine_version,omitempty"`
+	ThresholdProfile       string                    `protobuf:"bytes,7,opt,name=threshold_profile,json=thresholdProfile,proto3" json:"threshold_profile,omitempty"`                              // Threshold profile used for classification
+	Thresholds             *ClassificationThresholds `protobuf:"bytes,8,opt,name=thresholds,proto3" json:"thresholds,omitempty"`                                                                  // Threshold values of that profile
+	Depth                  int32                     `protobuf:"varint,9,opt,name=depth,proto3" json:"depth,omitempty"`                                                                           // Depth searched, after clamping to the service limits
+	TimedOut               bool                      `protobuf:"varint,10,opt,name=timed_out,json=timedOut,proto3" json:"timed_out,omitempty"`                                                    // Analysis timeout hit, moves holds only the analyzed moves
+	TotalMoves             int32                     `protobuf:"varint,11,opt,name=total_moves,json=totalMoves,proto3" json:"total_moves,omitempty"`                                              // Moves in the game, analyzed or not
+	EngineProfile          string                    `protobuf:"bytes,12,opt,name=engine_profile,json=engineProfile,proto3" json:"engine_profile,omitempty"`                                      // Engine profile that analyzed the game
+	CrossCheck             *CrossCheck               `protobuf:"bytes,13,opt,name=cross_check,json=crossCheck,proto3" json:"cross_check,omitempty"`                                               // Second-engine analysis, when requested
+	Truncated              bool                      `protobuf:"varint,14,opt,name=truncated,proto3" json:"truncated,omitempty"`                                                                  // The PGN has an invalid move, only the moves before it were analyzed
+	TruncatedAtPly         int32                     `protobuf:"varint,15,opt,name=truncated_at_ply,json=truncatedAtPly,proto3" json:"truncated_at_ply,omitempty"`                                // Ply of the invalid move (0-indexed)
+	TruncationError        string                    `protobuf:"bytes,16,opt,name=truncation_error,json=truncationError,proto3" json:"truncation_error,omitempty"`                                // Move number, move and context of the invalid move
+	EvalPerspective        EvalPerspective           `protobuf:"varint,17,opt,name=eval_perspective,json=evalPerspective,proto3,enum=analysis.EvalPerspective" json:"eval_perspective,omitempty"` // Sign convention of eval_before and eval_after
+	Stored                 *StoredAnalysis           `protobuf:"bytes,18,opt,name=stored,proto3" json:"stored,omitempty"`                                                                         // Rows the analysis was stored in, when persist was requested
+	WhiteTime              *TimeManagement           `protobuf:"bytes,19,opt,name=white_time,json=whiteTime,proto3" json:"white_time,omitempty"`                                                  // Unset when the PGN has no [%clk] comments
+	BlackTime              *TimeManagement           `protobuf:"bytes,20,opt,name=black_time,json=blackTime,proto3" json:"black_time,omitempty"`
+	StartedAtUnixMs        int64                     `protobuf:"varint,21,opt,name=started_at_unix_ms,json=startedAtUnixMs,proto3" json:"started_at_unix_ms,omitempty"`
+	CompletedAtUnixMs      int64                     `protobuf:"varint,22,opt,name=completed_at_unix_ms,json=completedAtUnixMs,proto3" json:"completed_at_unix_ms,omitempty"`
+	CacheCoverage          float32                   `protobuf:"fixed32,23,opt,name=cache_coverage,json=cacheCoverage,proto3" json:"cache_coverage,omitempty"`                                                                       // Percentage of positions evaluated without a search
+	Diagnostics            *AnalysisDiagnostics      `protobuf:"bytes,24,opt,name=diagnostics,proto3" json:"diagnostics,omitempty"`                                                                                                  // Engine failures and retries
+	TimeBudgetMs           int64                     `protobuf:"varint,25,opt,name=time_budget_ms,json=timeBudgetMs,proto3" json:"time_budget_ms,omitempty"`                                                                         // Requested time budget, 0 for none
+	BudgetUsedMs           int64                     `protobuf:"varint,26,opt,name=budget_used_ms,json=budgetUsedMs,proto3" json:"budget_used_ms,omitempty"`                                                                         // Search time spent of the budget
+	BudgetUtilization      float32                   `protobuf:"fixed32,27,opt,name=budget_utilization,json=budgetUtilization,proto3" json:"budget_utilization,omitempty"`                                                           // budget_used_ms as a percentage of time_budget_ms
+	TranscriptJobId        string                    `protobuf:"bytes,28,opt,name=transcript_job_id,json=transcriptJobId,proto3" json:"transcript_job_id,omitempty"`                                                                 // With record_engine_output: the job AdminService.GetEngineTranscript finds the transcripts by, also sent as x-transcript-job-id response header
+	FastMode               bool                      `protobuf:"varint,29,opt,name=fast_mode,json=fastMode,proto3" json:"fast_mode,omitempty"`                                                                                       // Long game searched at ServiceInfo.fast_mode_depth; depth is the depth used
+	Checksum               string                    `protobuf:"bytes,30,opt,name=checksum,proto3" json:"checksum,omitempty"`                                                                                                        // "v1:" and the SHA-256 of the moves and metrics, see the README for the fields covered
+	Config                 *AnalysisConfigSnapshot   `protobuf:"bytes,31,opt,name=config,proto3" json:"config,omitempty"`                                                                                                            // Engine settings the game was searched with; unset in analyses stored before it was recorded
+	SourceCounts           map[string]int32          `protobuf:"bytes,32,rep,name=source_counts,json=sourceCounts,proto3" json:"source_counts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // Plies by AnalysisSource, lowercase ("engine", "book_skipped", ...); those left out of moves included, so they add up to total_moves
+	Degraded               bool                      `protobuf:"varint,33,opt,name=degraded,proto3" json:"degraded,omitempty"`                                                                                                       // The engine pool was starved as the analysis started: depth capped at the degraded depth, no time-budget pre-pass or book alternatives
+	GameInfo               *GameInfo                 `protobuf:"bytes,34,opt,name=game_info,json=gameInfo,proto3" json:"game_info,omitempty"`                                                                                        // Players, ratings and result from the PGN's tags
+	Termination            Termination               `protobuf:"varint,35,opt,name=termination,proto3,enum=analysis.Termination" json:"termination,omitempty"`                                                                       // How the game ended, from the Result and Termination tags checked against the final position
+	WinnerColor            string                    `protobuf:"bytes,36,opt,name=winner_color,json=winnerColor,proto3" json:"winner_color,omitempty"`                                                                               // "white" or "black", empty for a draw or an unknown result
+	ResultAgainstRunOfPlay bool                      `protobuf:"varint,37,opt,name=result_against_run_of_play,json=resultAgainstRunOfPlay,proto3" json:"result_against_run_of_play,omitempty"`                                       // Won other than by checkmate although the final position is a dead draw or the loser was winning on the board
+	ResultSummary          string                    `protobuf:"bytes,38,opt,name=result_summary,json=resultSummary,proto3" json:"result_summary,omitempty"`                                                                         // How the game ended in words, e.g. "White won by checkmate on move 34"; empty when the result is unknown
+	Summary                string                    `protobuf:"bytes,39,opt,name=summary,proto3" json:"summary,omitempty"`                                                                                                          // One line for sharing, e.g. "White 92.3% (2 inaccuracies), Black 78.1% (1 mistake, 2 blunders) — turning point: 24...Qxb2?? missed mate in 4"
+	Preliminary            bool                      `protobuf:"varint,40,opt,name=preliminary,proto3" json:"preliminary,omitempty"`                                                                                                 // Analyzed at preview_depth: the preview, or the result of a stream canceled after it
+	PreviewKept            int32                     `protobuf:"varint,41,opt,name=preview_kept,json=previewKept,proto3" json:"preview_kept,omitempty"`                                                                              // Positions whose preview evaluation agreed with its neighbors' within PREVIEW_TOLERANCE_CP and wasn't searched again
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *GameAnalysis) Reset() {
+	*x = GameAnalysis{}
+	mi := &file_proto_analysis_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameAnalysis) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameAnalysis) ProtoMessage() {}
+
+func (x *GameAnalysis) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameAnalysis.ProtoReflect.Descriptor instead.
+func (*GameAnalysis) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{5}
+}
+
 func (x *GameAnalysis) GetGameId() string {
 	if x != nil {
-		return x.GameId
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *GameAnalysis) GetMoves() []*MoveAnalysis {
+	if x != nil {
+		return x.Moves
+	}
+	return nil
+}
+
+func (x *GameAnalysis) GetWhiteMetrics() *GameMetrics {
+	if x != nil {
+		return x.WhiteMetrics
+	}
+	return nil
+}
+
+func (x *GameAnalysis) GetBlackMetrics() *GameMetrics {
+	if x != nil {
+		return x.BlackMetrics
+	}
+	return nil
+}
+
+func (x *GameAnalysis) GetTotalTimeMs() int64 {
+	if x != nil {
+		return x.TotalTimeMs
+	}
+	return 0
+}
+
+func (x *GameAnalysis) GetEngineVersion() string {
+	if x != nil {
+		return x.EngineVersion
+	}
+	return ""
+}
+
+func (x *GameAnalysis) GetThresholdProfile() string {
+	if x != nil {
+		return x.ThresholdProfile
+	}
+	return ""
+}
+
+func (x *GameAnalysis) GetThresholds() *ClassificationThresholds {
+	if x != nil {
+		return x.Thresholds
+	}
+	return nil
+}
+
+func (x *GameAnalysis) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *GameAnalysis) GetTimedOut() bool {
+	if x != nil {
+		return x.TimedOut
+	}
+	return false
+}
+
+func (x *GameAnalysis) GetTotalMoves() int32 {
+	if x != nil {
+		return x.TotalMoves
+	}
+	return 0
+}
+
+func (x *GameAnalysis) GetEngineProfile() string {
+	if x != nil {
+		return x.EngineProfile
+	}
+	return ""
+}
+
+func (x *GameAnalysis) GetCrossCheck() *CrossCheck {
+	if x != nil {
+		return x.CrossCheck
+	}
+	return nil
+}
+
+func (x *GameAnalysis) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+func (x *GameAnalysis) GetTruncatedAtPly() int32 {
+	if x != nil {
+		return x.TruncatedAtPly
+	}
+	return 0
+}
+
+func (x *GameAnalysis) GetTruncationError() string {
+	if x != nil {
+		return x.TruncationError
+	}
+	return ""
+}
+
+func (x *GameAnalysis) GetEvalPerspective() EvalPerspective {
+	if x != nil {
+		return x.EvalPerspective
+	}
+	return EvalPerspective_EVAL_PERSPECTIVE_UNSPECIFIED
+}
+
+func (x *GameAnalysis) GetStored() *StoredAnalysis {
+	if x != nil {
+		return x.Stored
+	}
+	return nil
+}
+
+func (x *GameAnalysis) GetWhiteTime() *TimeManagement {
+	if x != nil {
+		return x.WhiteTime
+	}
+	return nil
+}
+
+func (x *GameAnalysis) GetBlackTime() *TimeManagement {
+	if x != nil {
+		return x.BlackTime
+	}
+	return nil
+}
+
+func (x *GameAnalysis) GetStartedAtUnixMs() int64 {
+	if x != nil {
+		return x.StartedAtUnixMs
+	}
+	return 0
+}
+
+func (x *GameAnalysis) GetCompletedAtUnixMs() int64 {
+	if x != nil {
+		return x.CompletedAtUnixMs
+	}
+	return 0
+}
+
+func (x *GameAnalysis) GetCacheCoverage() float32 {
+	if x != nil {
+		return x.CacheCoverage
+	}
+	return 0
+}
+
+func (x *GameAnalysis) GetDiagnostics() *AnalysisDiagnostics {
+	if x != nil {
+		return x.Diagnostics
+	}
+	return nil
+}
+
+func (x *GameAnalysis) GetTimeBudgetMs() int64 {
+	if x != nil {
+		return x.TimeBudgetMs
+	}
+	return 0
+}
+
+func (x *GameAnalysis) GetBudgetUsedMs() int64 {
+	if x != nil {
+		return x.BudgetUsedMs
+	}
+	return 0
+}
+
+func (x *GameAnalysis) GetBudgetUtilization() float32 {
+	if x != nil {
+		return x.BudgetUtilization
+	}
+	return 0
+}
+
+func (x *GameAnalysis) GetTranscriptJobId() string {
+	if x != nil {
+		return x.TranscriptJobId
+	}
+	return ""
+}
+
+func (x *GameAnalysis) GetFastMode() bool {
+	if x != nil {
+		return x.FastMode
+	}
+	return false
+}
+
+func (x *GameAnalysis) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+func (x *GameAnalysis) GetConfig() *AnalysisConfigSnapshot {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *GameAnalysis) GetSourceCounts() map[string]int32 {
+	if x != nil {
+		return x.SourceCounts
+	}
+	return nil
+}
+
+func (x *GameAnalysis) GetDegraded() bool {
+	if x != nil {
+		return x.Degraded
+	}
+	return false
+}
+
+func (x *GameAnalysis) GetGameInfo() *GameInfo {
+	if x != nil {
+		return x.GameInfo
+	}
+	return nil
+}
+
+func (x *GameAnalysis) GetTermination() Termination {
+	if x != nil {
+		return x.Termination
+	}
+	return Termination_TERMINATION_UNKNOWN
+}
+
+func (x *GameAnalysis) GetWinnerColor() string {
+	if x != nil {
+		return x.WinnerColor
+	}
+	return ""
+}
+
+func (x *GameAnalysis) GetResultAgainstRunOfPlay() bool {
+	if x != nil {
+		return x.ResultAgainstRunOfPlay
+	}
+	return false
+}
+
+func (x *GameAnalysis) GetResultSummary() string {
+	if x != nil {
+		return x.ResultSummary
+	}
+	return ""
+}
+
+func (x *GameAnalysis) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+func (x *GameAnalysis) GetPreliminary() bool {
+	if x != nil {
+		return x.Preliminary
+	}
+	return false
+}
+
+func (x *GameAnalysis) GetPreviewKept() int32 {
+	if x != nil {
+		return x.PreviewKept
+	}
+	return 0
+}
+
+// What a game's PGN tags say about it. Tags that are missing or "?" are
+// left unset.
+type GameInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	White         string                 `protobuf:"bytes,1,opt,name=white,proto3" json:"white,omitempty"`
+	Black         string                 `protobuf:"bytes,2,opt,name=black,proto3" json:"black,omitempty"`
+	WhiteElo      *int32                 `protobuf:"varint,3,opt,name=white_elo,json=whiteElo,proto3,oneof" json:"white_elo,omitempty"` // Unset unless the WhiteElo tag is a positive number
+	BlackElo      *int32                 `protobuf:"varint,4,opt,name=black_elo,json=blackElo,proto3,oneof" json:"black_elo,omitempty"`
+	Result        string                 `protobuf:"bytes,5,opt,name=result,proto3" json:"result,omitempty"`                              // "1-0", "0-1" or "1/2-1/2"; empty for "*"
+	TimeControl   string                 `protobuf:"bytes,6,opt,name=time_control,json=timeControl,proto3" json:"time_control,omitempty"` // As in the tag, e.g. "180+2"
+	Date          string                 `protobuf:"bytes,7,opt,name=date,proto3" json:"date,omitempty"`                                  // As in the tag, e.g. "2024.03.01"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GameInfo) Reset() {
+	*x = GameInfo{}
+	mi := &file_proto_analysis_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameInfo) ProtoMessage() {}
+
+func (x *GameInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameInfo.ProtoReflect.Descriptor instead.
+func (*GameInfo) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GameInfo) GetWhite() string {
+	if x != nil {
+		return x.White
+	}
+	return ""
+}
+
+func (x *GameInfo) GetBlack() string {
+	if x != nil {
+		return x.Black
+	}
+	return ""
+}
+
+func (x *GameInfo) GetWhiteElo() int32 {
+	if x != nil && x.WhiteElo != nil {
+		return *x.WhiteElo
+	}
+	return 0
+}
+
+func (x *GameInfo) GetBlackElo() int32 {
+	if x != nil && x.BlackElo != nil {
+		return *x.BlackElo
+	}
+	return 0
+}
+
+func (x *GameInfo) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *GameInfo) GetTimeControl() string {
+	if x != nil {
+		return x.TimeControl
+	}
+	return ""
+}
+
+func (x *GameInfo) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+// The engine settings of a game analysis, to tell whether two stored
+// analyses are comparable
+type AnalysisConfigSnapshot struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Threads         int32                  `protobuf:"varint,1,opt,name=threads,proto3" json:"threads,omitempty"`             // Per engine
+	HashMb          int32                  `protobuf:"varint,2,opt,name=hash_mb,json=hashMb,proto3" json:"hash_mb,omitempty"` // Per engine
+	MultiPv         int32                  `protobuf:"varint,3,opt,name=multi_pv,json=multiPv,proto3" json:"multi_pv,omitempty"`
+	Depth           int32                  `protobuf:"varint,4,opt,name=depth,proto3" json:"depth,omitempty"`                             // Depth searched to
+	NodesLimit      int64                  `protobuf:"varint,5,opt,name=nodes_limit,json=nodesLimit,proto3" json:"nodes_limit,omitempty"` // Node limit, 0 = none
+	MovetimeMs      int64                  `protobuf:"varint,6,opt,name=movetime_ms,json=movetimeMs,proto3" json:"movetime_ms,omitempty"` // Time budget of a game searched by movetime instead of depth, 0 = none
+	NnueNet         string                 `protobuf:"bytes,7,opt,name=nnue_net,json=nnueNet,proto3" json:"nnue_net,omitempty"`
+	CacheHitPercent float32                `protobuf:"fixed32,8,opt,name=cache_hit_percent,json=cacheHitPercent,proto3" json:"cache_hit_percent,omitempty"` // Positions evaluated without a search
+	Deterministic   bool                   `protobuf:"varint,9,opt,name=deterministic,proto3" json:"deterministic,omitempty"`                               // One thread and depth-limited searches
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *AnalysisConfigSnapshot) Reset() {
+	*x = AnalysisConfigSnapshot{}
+	mi := &file_proto_analysis_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnalysisConfigSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalysisConfigSnapshot) ProtoMessage() {}
+
+func (x *AnalysisConfigSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalysisConfigSnapshot.ProtoReflect.Descriptor instead.
+func (*AnalysisConfigSnapshot) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AnalysisConfigSnapshot) GetThreads() int32 {
+	if x != nil {
+		return x.Threads
+	}
+	return 0
+}
+
+func (x *AnalysisConfigSnapshot) GetHashMb() int32 {
+	if x != nil {
+		return x.HashMb
+	}
+	return 0
+}
+
+func (x *AnalysisConfigSnapshot) GetMultiPv() int32 {
+	if x != nil {
+		return x.MultiPv
+	}
+	return 0
+}
+
+func (x *AnalysisConfigSnapshot) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *AnalysisConfigSnapshot) GetNodesLimit() int64 {
+	if x != nil {
+		return x.NodesLimit
+	}
+	return 0
+}
+
+func (x *AnalysisConfigSnapshot) GetMovetimeMs() int64 {
+	if x != nil {
+		return x.MovetimeMs
+	}
+	return 0
+}
+
+func (x *AnalysisConfigSnapshot) GetNnueNet() string {
+	if x != nil {
+		return x.NnueNet
+	}
+	return ""
+}
+
+func (x *AnalysisConfigSnapshot) GetCacheHitPercent() float32 {
+	if x != nil {
+		return x.CacheHitPercent
+	}
+	return 0
+}
+
+func (x *AnalysisConfigSnapshot) GetDeterministic() bool {
+	if x != nil {
+		return x.Deterministic
+	}
+	return false
+}
+
+// Engine failures during a game analysis. Searches stopped by the
+// analysis timeout are not failures; timed_out covers them.
+type AnalysisDiagnostics struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Retries         int32                  `protobuf:"varint,1,opt,name=retries,proto3" json:"retries,omitempty"`                                                                             // Searches repeated, on another engine, after a failure
+	Failures        map[string]int32       `protobuf:"bytes,2,rep,name=failures,proto3" json:"failures,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // Failed searches by kind: engine_died, timeout, invalid_output, other
+	FailedPositions []int32                `protobuf:"varint,3,rep,packed,name=failed_positions,json=failedPositions,proto3" json:"failed_positions,omitempty"`                               // Positions still failing after every retry (0 = starting position)
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *AnalysisDiagnostics) Reset() {
+	*x = AnalysisDiagnostics{}
+	mi := &file_proto_analysis_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnalysisDiagnostics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalysisDiagnostics) ProtoMessage() {}
+
+func (x *AnalysisDiagnostics) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalysisDiagnostics.ProtoReflect.Descriptor instead.
+func (*AnalysisDiagnostics) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AnalysisDiagnostics) GetRetries() int32 {
+	if x != nil {
+		return x.Retries
+	}
+	return 0
+}
+
+func (x *AnalysisDiagnostics) GetFailures() map[string]int32 {
+	if x != nil {
+		return x.Failures
+	}
+	return nil
+}
+
+func (x *AnalysisDiagnostics) GetFailedPositions() []int32 {
+	if x != nil {
+		return x.FailedPositions
+	}
+	return nil
+}
+
+// How a player's clock related to their errors, from [%clk] comments
+type TimeManagement struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	TimeClass           string                 `protobuf:"bytes,1,opt,name=time_class,json=timeClass,proto3" json:"time_class,omitempty"`                                  // bullet, blitz, rapid or classical, from the TimeControl tag
+	ScrambleThresholdMs int64                  `protobuf:"varint,2,opt,name=scramble_threshold_ms,json=scrambleThresholdMs,proto3" json:"scramble_threshold_ms,omitempty"` // Effective time (clock + 10 increments) below which a move is in the time scramble
+	ScrambleStartPly    int32                  `protobuf:"varint,3,opt,name=scramble_start_ply,json=scrambleStartPly,proto3" json:"scramble_start_ply,omitempty"`          // First move in the scramble, -1 if none
+	ScrambleMoves       int32                  `protobuf:"varint,4,opt,name=scramble_moves,json=scrambleMoves,proto3" json:"scramble_moves,omitempty"`
+	ScrambleErrorRate   float32                `protobuf:"fixed32,5,opt,name=scramble_error_rate,json=scrambleErrorRate,proto3" json:"scramble_error_rate,omitempty"`  // Share of scramble moves that were inaccuracies, mistakes, blunders or missed wins
+	OtherErrorRate      float32                `protobuf:"fixed32,6,opt,name=other_error_rate,json=otherErrorRate,proto3" json:"other_error_rate,omitempty"`           // The same outside the scramble
+	ThinkingTimeMs      int64                  `protobuf:"varint,7,opt,name=thinking_time_ms,json=thinkingTimeMs,proto3" json:"thinking_time_ms,omitempty"`            // Spent on the timed moves
+	CpLossPerSecond     float32                `protobuf:"fixed32,8,opt,name=cp_loss_per_second,json=cpLossPerSecond,proto3" json:"cp_loss_per_second,omitempty"`      // Centipawn loss per second of thinking time
+	ScrambleBlunders    []int32                `protobuf:"varint,9,rep,packed,name=scramble_blunders,json=scrambleBlunders,proto3" json:"scramble_blunders,omitempty"` // Plies of blunders made in the scramble
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *TimeManagement) Reset() {
+	*x = TimeManagement{}
+	mi := &file_proto_analysis_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimeManagement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimeManagement) ProtoMessage() {}
+
+func (x *TimeManagement) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimeManagement.ProtoReflect.Descriptor instead.
+func (*TimeManagement) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *TimeManagement) GetTimeClass() string {
+	if x != nil {
+		return x.TimeClass
+	}
+	return ""
+}
+
+func (x *TimeManagement) GetScrambleThresholdMs() int64 {
+	if x != nil {
+		return x.ScrambleThresholdMs
+	}
+	return 0
+}
+
+func (x *TimeManagement) GetScrambleStartPly() int32 {
+	if x != nil {
+		return x.ScrambleStartPly
+	}
+	return 0
+}
+
+func (x *TimeManagement) GetScrambleMoves() int32 {
+	if x != nil {
+		return x.ScrambleMoves
+	}
+	return 0
+}
+
+func (x *TimeManagement) GetScrambleErrorRate() float32 {
+	if x != nil {
+		return x.ScrambleErrorRate
+	}
+	return 0
+}
+
+func (x *TimeManagement) GetOtherErrorRate() float32 {
+	if x != nil {
+		return x.OtherErrorRate
+	}
+	return 0
+}
+
+func (x *TimeManagement) GetThinkingTimeMs() int64 {
+	if x != nil {
+		return x.ThinkingTimeMs
+	}
+	return 0
+}
+
+func (x *TimeManagement) GetCpLossPerSecond() float32 {
+	if x != nil {
+		return x.CpLossPerSecond
+	}
+	return 0
+}
+
+func (x *TimeManagement) GetScrambleBlunders() []int32 {
+	if x != nil {
+		return x.ScrambleBlunders
+	}
+	return nil
+}
+
+// Rows of a persisted game analysis
+type StoredAnalysis struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GameRowId     int64                  `protobuf:"varint,1,opt,name=game_row_id,json=gameRowId,proto3" json:"game_row_id,omitempty"`           // game_analyses row
+	MoveRowIds    []int64                `protobuf:"varint,2,rep,packed,name=move_row_ids,json=moveRowIds,proto3" json:"move_row_ids,omitempty"` // move_analyses rows, in move order
+	KeptExisting  bool                   `protobuf:"varint,3,opt,name=kept_existing,json=keptExisting,proto3" json:"kept_existing,omitempty"`    // A deeper analysis of the game was already stored and was kept
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StoredAnalysis) Reset() {
+	*x = StoredAnalysis{}
+	mi := &file_proto_analysis_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoredAnalysis) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoredAnalysis) ProtoMessage() {}
+
+func (x *StoredAnalysis) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoredAnalysis.ProtoReflect.Descriptor instead.
+func (*StoredAnalysis) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *StoredAnalysis) GetGameRowId() int64 {
+	if x != nil {
+		return x.GameRowId
+	}
+	return 0
+}
+
+func (x *StoredAnalysis) GetMoveRowIds() []int64 {
+	if x != nil {
+		return x.MoveRowIds
+	}
+	return nil
+}
+
+func (x *StoredAnalysis) GetKeptExisting() bool {
+	if x != nil {
+		return x.KeptExisting
+	}
+	return false
+}
+
+// A game analyzed by a second engine profile, compared to the first
+type CrossCheck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Secondary     *GameAnalysis          `protobuf:"bytes,1,opt,name=secondary,proto3" json:"secondary,omitempty"` // Analysis by the cross-check engine
+	Diff          *AnalysisDiff          `protobuf:"bytes,2,opt,name=diff,proto3" json:"diff,omitempty"`           // Where the two analyses disagree
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrossCheck) Reset() {
+	*x = CrossCheck{}
+	mi := &file_proto_analysis_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrossCheck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrossCheck) ProtoMessage() {}
+
+func (x *CrossCheck) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrossCheck.ProtoReflect.Descriptor instead.
+func (*CrossCheck) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CrossCheck) GetSecondary() *GameAnalysis {
+	if x != nil {
+		return x.Secondary
+	}
+	return nil
+}
+
+func (x *CrossCheck) GetDiff() *AnalysisDiff {
+	if x != nil {
+		return x.Diff
+	}
+	return nil
+}
+
+// Disagreements between two analyses of the same game
+type AnalysisDiff struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	EngineA             string                 `protobuf:"bytes,1,opt,name=engine_a,json=engineA,proto3" json:"engine_a,omitempty"`                                      // Engine profile of the first analysis
+	EngineB             string                 `protobuf:"bytes,2,opt,name=engine_b,json=engineB,proto3" json:"engine_b,omitempty"`                                      // Engine profile of the second analysis
+	ComparedMoves       int32                  `protobuf:"varint,3,opt,name=compared_moves,json=comparedMoves,proto3" json:"compared_moves,omitempty"`                   // Moves present in both analyses
+	Moves               []*MoveDiff            `protobuf:"bytes,4,rep,name=moves,proto3" json:"moves,omitempty"`                                                         // Disagreements, in game order
+	ClassificationDiffs int32                  `protobuf:"varint,5,opt,name=classification_diffs,json=classificationDiffs,proto3" json:"classification_diffs,omitempty"` // Moves classified differently
+	CentipawnLossDiffs  int32                  `protobuf:"varint,6,opt,name=centipawn_loss_diffs,json=centipawnLossDiffs,proto3" json:"centipawn_loss_diffs,omitempty"`  // Moves whose loss differs by more than 50 centipawns
+	WhiteAccuracyDelta  float32                `protobuf:"fixed32,7,opt,name=white_accuracy_delta,json=whiteAccuracyDelta,proto3" json:"white_accuracy_delta,omitempty"` // Accuracy of analysis B minus that of analysis A, percentage points to one decimal
+	BlackAccuracyDelta  float32                `protobuf:"fixed32,8,opt,name=black_accuracy_delta,json=blackAccuracyDelta,proto3" json:"black_accuracy_delta,omitempty"`
+	BestMoveDiffs       int32                  `protobuf:"varint,9,opt,name=best_move_diffs,json=bestMoveDiffs,proto3" json:"best_move_diffs,omitempty"` // Moves with a different best move (DiffAnalyses only)
+	DepthA              int32                  `protobuf:"varint,10,opt,name=depth_a,json=depthA,proto3" json:"depth_a,omitempty"`                       // Depths of the analyses compared
+	DepthB              int32                  `protobuf:"varint,11,opt,name=depth_b,json=depthB,proto3" json:"depth_b,omitempty"`
+	WhiteDelta          *MetricsDelta          `protobuf:"bytes,12,opt,name=white_delta,json=whiteDelta,proto3" json:"white_delta,omitempty"` // Metrics of analysis B minus those of analysis A
+	BlackDelta          *MetricsDelta          `protobuf:"bytes,13,opt,name=black_delta,json=blackDelta,proto3" json:"black_delta,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *AnalysisDiff) Reset() {
+	*x = AnalysisDiff{}
+	mi := &file_proto_analysis_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnalysisDiff) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalysisDiff) ProtoMessage() {}
+
+func (x *AnalysisDiff) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalysisDiff.ProtoReflect.Descriptor instead.
+func (*AnalysisDiff) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *AnalysisDiff) GetEngineA() string {
+	if x != nil {
+		return x.EngineA
+	}
+	return ""
+}
+
+func (x *AnalysisDiff) GetEngineB() string {
+	if x != nil {
+		return x.EngineB
+	}
+	return ""
+}
+
+func (x *AnalysisDiff) GetComparedMoves() int32 {
+	if x != nil {
+		return x.ComparedMoves
+	}
+	return 0
+}
+
+func (x *AnalysisDiff) GetMoves() []*MoveDiff {
+	if x != nil {
+		return x.Moves
+	}
+	return nil
+}
+
+func (x *AnalysisDiff) GetClassificationDiffs() int32 {
+	if x != nil {
+		return x.ClassificationDiffs
+	}
+	return 0
+}
+
+func (x *AnalysisDiff) GetCentipawnLossDiffs() int32 {
+	if x != nil {
+		return x.CentipawnLossDiffs
+	}
+	return 0
+}
+
+func (x *AnalysisDiff) GetWhiteAccuracyDelta() float32 {
+	if x != nil {
+		return x.WhiteAccuracyDelta
+	}
+	return 0
+}
+
+func (x *AnalysisDiff) GetBlackAccuracyDelta() float32 {
+	if x != nil {
+		return x.BlackAccuracyDelta
+	}
+	return 0
+}
+
+func (x *AnalysisDiff) GetBestMoveDiffs() int32 {
+	if x != nil {
+		return x.BestMoveDiffs
+	}
+	return 0
+}
+
+func (x *AnalysisDiff) GetDepthA() int32 {
+	if x != nil {
+		return x.DepthA
+	}
+	return 0
+}
+
+func (x *AnalysisDiff) GetDepthB() int32 {
+	if x != nil {
+		return x.DepthB
+	}
+	return 0
+}
+
+func (x *AnalysisDiff) GetWhiteDelta() *MetricsDelta {
+	if x != nil {
+		return x.WhiteDelta
+	}
+	return nil
+}
+
+func (x *AnalysisDiff) GetBlackDelta() *MetricsDelta {
+	if x != nil {
+		return x.BlackDelta
+	}
+	return nil
+}
+
+// Change in a player's metrics from one analysis to another
+type MetricsDelta struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Accuracy          float32                `protobuf:"fixed32,1,opt,name=accuracy,proto3" json:"accuracy,omitempty"` // Percentage points, to one decimal
+	Acpl              float32                `protobuf:"fixed32,2,opt,name=acpl,proto3" json:"acpl,omitempty"`         // Centipawns, to one decimal
+	Blunders          int32                  `protobuf:"varint,3,opt,name=blunders,proto3" json:"blunders,omitempty"`
+	Mistakes          int32                  `protobuf:"varint,4,opt,name=mistakes,proto3" json:"mistakes,omitempty"`
+	Inaccuracies      int32                  `protobuf:"varint,5,opt,name=inaccuracies,proto3" json:"inaccuracies,omitempty"`
+	PerformanceRating int32                  `protobuf:"varint,6,opt,name=performance_rating,json=performanceRating,proto3" json:"performance_rating,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *MetricsDelta) Reset() {
+	*x = MetricsDelta{}
+	mi := &file_proto_analysis_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetricsDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetricsDelta) ProtoMessage() {}
+
+func (x *MetricsDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetricsDelta.ProtoReflect.Descriptor instead.
+func (*MetricsDelta) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *MetricsDelta) GetAccuracy() float32 {
+	if x != nil {
+		return x.Accuracy
+	}
+	return 0
+}
+
+func (x *MetricsDelta) GetAcpl() float32 {
+	if x != nil {
+		return x.Acpl
+	}
+	return 0
+}
+
+func (x *MetricsDelta) GetBlunders() int32 {
+	if x != nil {
+		return x.Blunders
+	}
+	return 0
+}
+
+func (x *MetricsDelta) GetMistakes() int32 {
+	if x != nil {
+		return x.Mistakes
+	}
+	return 0
+}
+
+func (x *MetricsDelta) GetInaccuracies() int32 {
+	if x != nil {
+		return x.Inaccuracies
+	}
+	return 0
+}
+
+func (x *MetricsDelta) GetPerformanceRating() int32 {
+	if x != nil {
+		return x.PerformanceRating
+	}
+	return 0
+}
+
+// Two analyses of the same game to compare
+type DiffAnalysesRequest struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	AnalysisA           *GameAnalysis          `protobuf:"bytes,1,opt,name=analysis_a,json=analysisA,proto3" json:"analysis_a,omitempty"`                      // Earlier analysis, e.g. at a low depth
+	AnalysisB           *GameAnalysis          `protobuf:"bytes,2,opt,name=analysis_b,json=analysisB,proto3" json:"analysis_b,omitempty"`                      // Later analysis; deltas are B minus A
+	CpLossThreshold     int32                  `protobuf:"varint,3,opt,name=cp_loss_threshold,json=cpLossThreshold,proto3" json:"cp_loss_threshold,omitempty"` // Centipawn-loss change that lists a move (0 = 50)
+	JobIdA              string                 `protobuf:"bytes,4,opt,name=job_id_a,json=jobIdA,proto3" json:"job_id_a,omitempty"`                             // Stored analyses to compare instead (needs a job store, not supported yet)
+	JobIdB              string                 `protobuf:"bytes,5,opt,name=job_id_b,json=jobIdB,proto3" json:"job_id_b,omitempty"`
+	AllowConfigMismatch bool                   `protobuf:"varint,6,opt,name=allow_config_mismatch,json=allowConfigMismatch,proto3" json:"allow_config_mismatch,omitempty"` // Compare even when the analyses' config snapshots differ materially
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *DiffAnalysesRequest) Reset() {
+	*x = DiffAnalysesRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiffAnalysesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiffAnalysesRequest) ProtoMessage() {}
+
+func (x *DiffAnalysesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiffAnalysesRequest.ProtoReflect.Descriptor instead.
+func (*DiffAnalysesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DiffAnalysesRequest) GetAnalysisA() *GameAnalysis {
+	if x != nil {
+		return x.AnalysisA
+	}
+	return nil
+}
+
+func (x *DiffAnalysesRequest) GetAnalysisB() *GameAnalysis {
+	if x != nil {
+		return x.AnalysisB
+	}
+	return nil
+}
+
+func (x *DiffAnalysesRequest) GetCpLossThreshold() int32 {
+	if x != nil {
+		return x.CpLossThreshold
+	}
+	return 0
+}
+
+func (x *DiffAnalysesRequest) GetJobIdA() string {
+	if x != nil {
+		return x.JobIdA
+	}
+	return ""
+}
+
+func (x *DiffAnalysesRequest) GetJobIdB() string {
+	if x != nil {
+		return x.JobIdB
+	}
+	return ""
+}
+
+func (x *DiffAnalysesRequest) GetAllowConfigMismatch() bool {
+	if x != nil {
+		return x.AllowConfigMismatch
+	}
+	return false
+}
+
+// A move two analyses disagree on
+type MoveDiff struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Ply             int32                  `protobuf:"varint,1,opt,name=ply,proto3" json:"ply,omitempty"`
+	MoveNumber      int32                  `protobuf:"varint,2,opt,name=move_number,json=moveNumber,proto3" json:"move_number,omitempty"`
+	Color           string                 `protobuf:"bytes,3,opt,name=color,proto3" json:"color,omitempty"`
+	PlayedMove      string                 `protobuf:"bytes,4,opt,name=played_move,json=playedMove,proto3" json:"played_move,omitempty"` // SAN
+	ClassificationA MoveClassification     `protobuf:"varint,5,opt,name=classification_a,json=classificationA,proto3,enum=analysis.MoveClassification" json:"classification_a,omitempty"`
+	ClassificationB MoveClassification     `protobuf:"varint,6,opt,name=classification_b,json=classificationB,proto3,enum=analysis.MoveClassification" json:"classification_b,omitempty"`
+	CentipawnLossA  int32                  `protobuf:"varint,7,opt,name=centipawn_loss_a,json=centipawnLossA,proto3" json:"centipawn_loss_a,omitempty"`
+	CentipawnLossB  int32                  `protobuf:"varint,8,opt,name=centipawn_loss_b,json=centipawnLossB,proto3" json:"centipawn_loss_b,omitempty"`
+	BestMoveA       string                 `protobuf:"bytes,9,opt,name=best_move_a,json=bestMoveA,proto3" json:"best_move_a,omitempty"` // SAN
+	BestMoveB       string                 `protobuf:"bytes,10,opt,name=best_move_b,json=bestMoveB,proto3" json:"best_move_b,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *MoveDiff) Reset() {
+	*x = MoveDiff{}
+	mi := &file_proto_analysis_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MoveDiff) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MoveDiff) ProtoMessage() {}
+
+func (x *MoveDiff) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MoveDiff.ProtoReflect.Descriptor instead.
+func (*MoveDiff) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *MoveDiff) GetPly() int32 {
+	if x != nil {
+		return x.Ply
+	}
+	return 0
+}
+
+func (x *MoveDiff) GetMoveNumber() int32 {
+	if x != nil {
+		return x.MoveNumber
+	}
+	return 0
+}
+
+func (x *MoveDiff) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *MoveDiff) GetPlayedMove() string {
+	if x != nil {
+		return x.PlayedMove
+	}
+	return ""
+}
+
+func (x *MoveDiff) GetClassificationA() MoveClassification {
+	if x != nil {
+		return x.ClassificationA
+	}
+	return MoveClassification_CLASSIFICATION_UNKNOWN
+}
+
+func (x *MoveDiff) GetClassificationB() MoveClassification {
+	if x != nil {
+		return x.ClassificationB
+	}
+	return MoveClassification_CLASSIFICATION_UNKNOWN
+}
+
+func (x *MoveDiff) GetCentipawnLossA() int32 {
+	if x != nil {
+		return x.CentipawnLossA
+	}
+	return 0
+}
+
+func (x *MoveDiff) GetCentipawnLossB() int32 {
+	if x != nil {
+		return x.CentipawnLossB
+	}
+	return 0
+}
+
+func (x *MoveDiff) GetBestMoveA() string {
+	if x != nil {
+		return x.BestMoveA
+	}
+	return ""
+}
+
+func (x *MoveDiff) GetBestMoveB() string {
+	if x != nil {
+		return x.BestMoveB
+	}
+	return ""
+}
+
+// Analysis progress during game analysis
+type GameAnalysisProgress struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	GameId          string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	CurrentMove     int32                  `protobuf:"varint,2,opt,name=current_move,json=currentMove,proto3" json:"current_move,omitempty"`              // Current move being analyzed (1-indexed)
+	TotalMoves      int32                  `protobuf:"varint,3,opt,name=total_moves,json=totalMoves,proto3" json:"total_moves,omitempty"`                 // Total moves in the game
+	ProgressPercent float32                `protobuf:"fixed32,4,opt,name=progress_percent,json=progressPercent,proto3" json:"progress_percent,omitempty"` // Progress percentage (0-100)
+	MoveAnalysis    *MoveAnalysis          `protobuf:"bytes,5,opt,name=move_analysis,json=moveAnalysis,proto3" json:"move_analysis,omitempty"`            // Analysis of current move (if completed)
+	Status          string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`                                            // "analyzing", "completed", "error"
+	ErrorMessage    string                 `protobuf:"bytes,7,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`            // Error message if status is "error"
+	Heartbeat       bool                   `protobuf:"varint,8,opt,name=heartbeat,proto3" json:"heartbeat,omitempty"`                                     // Keepalive message, move counts are unchanged
+	ElapsedMs       int64                  `protobuf:"varint,9,opt,name=elapsed_ms,json=elapsedMs,proto3" json:"elapsed_ms,omitempty"`                    // Milliseconds since the analysis started
+	QueuePosition   int32                  `protobuf:"varint,10,opt,name=queue_position,json=queuePosition,proto3" json:"queue_position,omitempty"`       // Requests waiting for a pool engine
+	TimedOut        bool                   `protobuf:"varint,11,opt,name=timed_out,json=timedOut,proto3" json:"timed_out,omitempty"`                      // Set on the "completed" message of a partial analysis
+	WhiteMetrics    *GameMetrics           `protobuf:"bytes,12,opt,name=white_metrics,json=whiteMetrics,proto3" json:"white_metrics,omitempty"`           // Metrics over the moves analyzed so far, every STREAM_METRICS_INTERVAL moves and on "completed"
+	BlackMetrics    *GameMetrics           `protobuf:"bytes,13,opt,name=black_metrics,json=blackMetrics,proto3" json:"black_metrics,omitempty"`
+	ChunksCompleted int32                  `protobuf:"varint,14,opt,name=chunks_completed,json=chunksCompleted,proto3" json:"chunks_completed,omitempty"`  // Chunks of consecutive positions searched so far; a message is sent as each completes
+	ChunksTotal     int32                  `protobuf:"varint,15,opt,name=chunks_total,json=chunksTotal,proto3" json:"chunks_total,omitempty"`              // Chunks the positions to search were split into, 0 until known
+	TranscriptJobId string                 `protobuf:"bytes,16,opt,name=transcript_job_id,json=transcriptJobId,proto3" json:"transcript_job_id,omitempty"` // On the final message, with record_engine_output: see GameAnalysis.transcript_job_id
+	Estimate        *GameEstimate          `protobuf:"bytes,17,opt,name=estimate,proto3" json:"estimate,omitempty"`                                        // On the first message, sent before any search, and updated as searches finish
+	// Moves of progress messages held back by STREAM_PROGRESS_INTERVAL_SECONDS
+	// and merged into this one, in order, all before move_analysis
+	BatchedMoves     []*MoveAnalysis `protobuf:"bytes,18,rep,name=batched_moves,json=batchedMoves,proto3" json:"batched_moves,omitempty"`
+	CoalescedUpdates int32           `protobuf:"varint,19,opt,name=coalesced_updates,json=coalescedUpdates,proto3" json:"coalesced_updates,omitempty"` // On the final message: progress messages merged into later ones
+	Preliminary      bool            `protobuf:"varint,20,opt,name=preliminary,proto3" json:"preliminary,omitempty"`                                   // The preview_depth analysis, in analysis, before the game is searched to depth
+	Analysis         *GameAnalysis   `protobuf:"bytes,21,opt,name=analysis,proto3" json:"analysis,omitempty"`                                          // The whole analysis: on the preliminary message, and on "completed" when preview_depth or persist is set
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *GameAnalysisProgress) Reset() {
+	*x = GameAnalysisProgress{}
+	mi := &file_proto_analysis_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameAnalysisProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameAnalysisProgress) ProtoMessage() {}
+
+func (x *GameAnalysisProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameAnalysisProgress.ProtoReflect.Descriptor instead.
+func (*GameAnalysisProgress) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GameAnalysisProgress) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *GameAnalysisProgress) GetCurrentMove() int32 {
+	if x != nil {
+		return x.CurrentMove
+	}
+	return 0
+}
+
+func (x *GameAnalysisProgress) GetTotalMoves() int32 {
+	if x != nil {
+		return x.TotalMoves
+	}
+	return 0
+}
+
+func (x *GameAnalysisProgress) GetProgressPercent() float32 {
+	if x != nil {
+		return x.ProgressPercent
+	}
+	return 0
+}
+
+func (x *GameAnalysisProgress) GetMoveAnalysis() *MoveAnalysis {
+	if x != nil {
+		return x.MoveAnalysis
+	}
+	return nil
+}
+
+func (x *GameAnalysisProgress) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *GameAnalysisProgress) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *GameAnalysisProgress) GetHeartbeat() bool {
+	if x != nil {
+		return x.Heartbeat
+	}
+	return false
+}
+
+func (x *GameAnalysisProgress) GetElapsedMs() int64 {
+	if x != nil {
+		return x.ElapsedMs
+	}
+	return 0
+}
+
+func (x *GameAnalysisProgress) GetQueuePosition() int32 {
+	if x != nil {
+		return x.QueuePosition
+	}
+	return 0
+}
+
+func (x *GameAnalysisProgress) GetTimedOut() bool {
+	if x != nil {
+		return x.TimedOut
+	}
+	return false
+}
+
+func (x *GameAnalysisProgress) GetWhiteMetrics() *GameMetrics {
+	if x != nil {
+		return x.WhiteMetrics
+	}
+	return nil
+}
+
+func (x *GameAnalysisProgress) GetBlackMetrics() *GameMetrics {
+	if x != nil {
+		return x.BlackMetrics
+	}
+	return nil
+}
+
+func (x *GameAnalysisProgress) GetChunksCompleted() int32 {
+	if x != nil {
+		return x.ChunksCompleted
+	}
+	return 0
+}
+
+func (x *GameAnalysisProgress) GetChunksTotal() int32 {
+	if x != nil {
+		return x.ChunksTotal
+	}
+	return 0
+}
+
+func (x *GameAnalysisProgress) GetTranscriptJobId() string {
+	if x != nil {
+		return x.TranscriptJobId
+	}
+	return ""
+}
+
+func (x *GameAnalysisProgress) GetEstimate() *GameEstimate {
+	if x != nil {
+		return x.Estimate
+	}
+	return nil
+}
+
+func (x *GameAnalysisProgress) GetBatchedMoves() []*MoveAnalysis {
+	if x != nil {
+		return x.BatchedMoves
+	}
+	return nil
+}
+
+func (x *GameAnalysisProgress) GetCoalescedUpdates() int32 {
+	if x != nil {
+		return x.CoalescedUpdates
+	}
+	return 0
+}
+
+func (x *GameAnalysisProgress) GetPreliminary() bool {
+	if x != nil {
+		return x.Preliminary
+	}
+	return false
+}
+
+func (x *GameAnalysisProgress) GetAnalysis() *GameAnalysis {
+	if x != nil {
+		return x.Analysis
+	}
+	return nil
+}
+
+// What a game analysis expects to take, from the cache and the pool as it
+// starts and from its own searches as they finish
+type GameEstimate struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Positions        int32                  `protobuf:"varint,1,opt,name=positions,proto3" json:"positions,omitempty"`                                       // Positions of the game, the final one included
+	CacheHits        int32                  `protobuf:"varint,2,opt,name=cache_hits,json=cacheHits,proto3" json:"cache_hits,omitempty"`                      // Positions the cache had at the depth
+	Searches         int32                  `protobuf:"varint,3,opt,name=searches,proto3" json:"searches,omitempty"`                                         // Positions to search
+	Searched         int32                  `protobuf:"varint,4,opt,name=searched,proto3" json:"searched,omitempty"`                                         // Of searches, finished so far
+	EnginesAvailable int32                  `protobuf:"varint,5,opt,name=engines_available,json=enginesAvailable,proto3" json:"engines_available,omitempty"` // Idle engines of the pool as the searches started
+	PoolSize         int32                  `protobuf:"varint,6,opt,name=pool_size,json=poolSize,proto3" json:"pool_size,omitempty"`
+	Workers          int32                  `protobuf:"varint,7,opt,name=workers,proto3" json:"workers,omitempty"`                      // Engines the searches run on at once
+	ColdStart        bool                   `protobuf:"varint,8,opt,name=cold_start,json=coldStart,proto3" json:"cold_start,omitempty"` // No search at the depth had finished before this game's
+	EtaMs            int64                  `protobuf:"varint,9,opt,name=eta_ms,json=etaMs,proto3" json:"eta_ms,omitempty"`             // Wall clock time left
+	EtaKnown         bool                   `protobuf:"varint,10,opt,name=eta_known,json=etaKnown,proto3" json:"eta_known,omitempty"`   // False until there is anything to estimate eta_ms from
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *GameEstimate) Reset() {
+	*x = GameEstimate{}
+	mi := &file_proto_analysis_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameEstimate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameEstimate) ProtoMessage() {}
+
+func (x *GameEstimate) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameEstimate.ProtoReflect.Descriptor instead.
+func (*GameEstimate) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *GameEstimate) GetPositions() int32 {
+	if x != nil {
+		return x.Positions
+	}
+	return 0
+}
+
+func (x *GameEstimate) GetCacheHits() int32 {
+	if x != nil {
+		return x.CacheHits
+	}
+	return 0
+}
+
+func (x *GameEstimate) GetSearches() int32 {
+	if x != nil {
+		return x.Searches
+	}
+	return 0
+}
+
+func (x *GameEstimate) GetSearched() int32 {
+	if x != nil {
+		return x.Searched
+	}
+	return 0
+}
+
+func (x *GameEstimate) GetEnginesAvailable() int32 {
+	if x != nil {
+		return x.EnginesAvailable
+	}
+	return 0
+}
+
+func (x *GameEstimate) GetPoolSize() int32 {
+	if x != nil {
+		return x.PoolSize
+	}
+	return 0
+}
+
+func (x *GameEstimate) GetWorkers() int32 {
+	if x != nil {
+		return x.Workers
+	}
+	return 0
+}
+
+func (x *GameEstimate) GetColdStart() bool {
+	if x != nil {
+		return x.ColdStart
+	}
+	return false
+}
+
+func (x *GameEstimate) GetEtaMs() int64 {
+	if x != nil {
+		return x.EtaMs
+	}
+	return 0
+}
+
+func (x *GameEstimate) GetEtaKnown() bool {
+	if x != nil {
+		return x.EtaKnown
+	}
+	return false
+}
+
+// Analysis for a single move in a game
+type MoveAnalysis struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	MoveNumber         int32                  `protobuf:"varint,1,opt,name=move_number,json=moveNumber,proto3" json:"move_number,omitempty"`                         // Move number (1-indexed)
+	Ply                int32                  `protobuf:"varint,2,opt,name=ply,proto3" json:"ply,omitempty"`                                                         // Ply (half-move, 0-indexed)
+	Color              string                 `protobuf:"bytes,3,opt,name=color,proto3" json:"color,omitempty"`                                                      // "white" or "black"
+	PlayedMove         string                 `protobuf:"bytes,4,opt,name=played_move,json=playedMove,proto3" json:"played_move,omitempty"`                          // Move played in SAN format
+	PlayedMoveUci      string                 `protobuf:"bytes,5,opt,name=played_move_uci,json=playedMoveUci,proto3" json:"played_move_uci,omitempty"`               // Move played in UCI format
+	BestMove           string                 `protobuf:"bytes,6,opt,name=best_move,json=bestMove,proto3" json:"best_move,omitempty"`                                // Best move in SAN format
+	BestMoveUci        string                 `protobuf:"bytes,7,opt,name=best_move_uci,json=bestMoveUci,proto3" json:"best_move_uci,omitempty"`                     // Best move in UCI format
+	FenBefore          string                 `protobuf:"bytes,8,opt,name=fen_before,json=fenBefore,proto3" json:"fen_before,omitempty"`                             // FEN before the move
+	FenAfter           string                 `protobuf:"bytes,9,opt,name=fen_after,json=fenAfter,proto3" json:"fen_after,omitempty"`                                // FEN after the move
+	EvalBefore         *Evaluation            `protobuf:"bytes,10,opt,name=eval_before,json=evalBefore,proto3" json:"eval_before,omitempty"`                         // Evaluation before the move
+	EvalAfter          *Evaluation            `protobuf:"bytes,11,opt,name=eval_after,json=evalAfter,proto3" json:"eval_after,omitempty"`                            // Evaluation after the move
+	CentipawnLoss      int32                  `protobuf:"varint,12,opt,name=centipawn_loss,json=centipawnLoss,proto3" json:"centipawn_loss,omitempty"`               // Centipawn loss for this move
+	Classification     MoveClassification     `protobuf:"varint,13,opt,name=classification,proto3,enum=analysis.MoveClassification" json:"classification,omitempty"` // Move classification
+	Pv                 []string               `protobuf:"bytes,14,rep,name=pv,proto3" json:"pv,omitempty"`                                                           // Principal variation of the search that reached depth
+	Depth              int32                  `protobuf:"varint,15,opt,name=depth,proto3" json:"depth,omitempty"`                                                    // Depth reached, above requested_depth when a deeper search was cached
+	MoveAccuracy       float32                `protobuf:"fixed32,16,opt,name=move_accuracy,json=moveAccuracy,proto3" json:"move_accuracy,omitempty"`                 // Percent (0-100) to one decimal, from the drop in the mover's win probability
+	Forced             bool                   `protobuf:"varint,17,opt,name=forced,proto3" json:"forced,omitempty"`                                                  // Only legal move; excluded from move-mean accuracy
+	RequestedDepth     int32                  `protobuf:"varint,18,opt,name=requested_depth,json=requestedDepth,proto3" json:"requested_depth,omitempty"`            // Depth the game was analyzed at
+	FromCache          bool                   `protobuf:"varint,19,opt,name=from_cache,json=fromCache,proto3" json:"from_cache,omitempty"`                           // Evaluation before the move came from the cache
+	GarbageTime        bool                   `protobuf:"varint,20,opt,name=garbage_time,json=garbageTime,proto3" json:"garbage_time,omitempty"`                     // Made in a decided position; classified but left out of accuracy and ACPL
+	MissedRepetition   bool                   `protobuf:"varint,21,opt,name=missed_repetition,json=missedRepetition,proto3" json:"missed_repetition,omitempty"`      // Losing by 200cp or more, passed up a threefold repetition draw
+	AllowedRepetition  bool                   `protobuf:"varint,22,opt,name=allowed_repetition,json=allowedRepetition,proto3" json:"allowed_repetition,omitempty"`   // Winning by 200cp or more, repeated or let the opponent repeat a third time
+	MaterialBefore     *Material              `protobuf:"bytes,23,opt,name=material_before,json=materialBefore,proto3" json:"material_before,omitempty"`
+	MaterialAfter      *Material              `protobuf:"bytes,24,opt,name=material_after,json=materialAfter,proto3" json:"material_after,omitempty"`
+	MaterialSacrificed int32                  `protobuf:"varint,25,opt,name=material_sacrificed,json=materialSacrificed,proto3" json:"material_sacrificed,omitempty"` // Material the mover gave up by two plies after the move, negative when won
+	AnalyzedAtUnixMs   int64                  `protobuf:"varint,26,opt,name=analyzed_at_unix_ms,json=analyzedAtUnixMs,proto3" json:"analyzed_at_unix_ms,omitempty"`   // When both evaluations of the move were available
+	EngineTimeMs       int64                  `protobuf:"varint,27,opt,name=engine_time_ms,json=engineTimeMs,proto3" json:"engine_time_ms,omitempty"`                 // Engine search time of the position before the move, 0 when cached or seeded
+	QueueTimeMs        int64                  `protobuf:"varint,28,opt,name=queue_time_ms,json=queueTimeMs,proto3" json:"queue_time_ms,omitempty"`                    // Wall clock waiting for an engine for that position, retries included
+	SearchTimeMs       int64                  `protobuf:"varint,29,opt,name=search_time_ms,json=searchTimeMs,proto3" json:"search_time_ms,omitempty"`                 // Wall clock searching it, retries included
+	MovetimeMs         int64                  `protobuf:"varint,30,opt,name=movetime_ms,json=movetimeMs,proto3" json:"movetime_ms,omitempty"`                         // Movetime the time budget allotted that position, 0 without a budget
+	Explanation        string                 `protobuf:"bytes,31,opt,name=explanation,proto3" json:"explanation,omitempty"`                                          // A sentence on the move for players, e.g. "This drops a pawn to Bxd5; Nf3 was better."
+	Source             AnalysisSource         `protobuf:"varint,32,opt,name=source,proto3,enum=analysis.AnalysisSource" json:"source,omitempty"`                      // Where the evaluation came from, or why the move doesn't count toward the metrics
+	// How to show the classification, the same for every client and as the
+	// annotated PGN export's NAGs
+	ClassificationGlyph string      `protobuf:"bytes,33,opt,name=classification_glyph,json=classificationGlyph,proto3" json:"classification_glyph,omitempty"`  // "!!", "!", "?!", "?" or "??"; empty for classifications without one
+	ClassificationLabel string      `protobuf:"bytes,34,opt,name=classification_label,json=classificationLabel,proto3" json:"classification_label,omitempty"`  // e.g. "Missed win"
+	ClassificationColor string      `protobuf:"bytes,35,opt,name=classification_color,json=classificationColor,proto3" json:"classification_color,omitempty"`  // "#rrggbb"
+	ExpectedPointsLoss  float32     `protobuf:"fixed32,36,opt,name=expected_points_loss,json=expectedPointsLoss,proto3" json:"expected_points_loss,omitempty"` // Points (0-1) of the game's result the mover gave up, to two decimals; 0 when forced or mating
+	Highlights          *Highlights `protobuf:"bytes,37,opt,name=highlights,proto3" json:"highlights,omitempty"`                                               // Squares for the board to highlight with the move
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *MoveAnalysis) Reset() {
+	*x = MoveAnalysis{}
+	mi := &file_proto_analysis_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MoveAnalysis) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MoveAnalysis) ProtoMessage() {}
+
+func (x *MoveAnalysis) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MoveAnalysis.ProtoReflect.Descriptor instead.
+func (*MoveAnalysis) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *MoveAnalysis) GetMoveNumber() int32 {
+	if x != nil {
+		return x.MoveNumber
+	}
+	return 0
+}
+
+func (x *MoveAnalysis) GetPly() int32 {
+	if x != nil {
+		return x.Ply
+	}
+	return 0
+}
+
+func (x *MoveAnalysis) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *MoveAnalysis) GetPlayedMove() string {
+	if x != nil {
+		return x.PlayedMove
+	}
+	return ""
+}
+
+func (x *MoveAnalysis) GetPlayedMoveUci() string {
+	if x != nil {
+		return x.PlayedMoveUci
+	}
+	return ""
+}
+
+func (x *MoveAnalysis) GetBestMove() string {
+	if x != nil {
+		return x.BestMove
+	}
+	return ""
+}
+
+func (x *MoveAnalysis) GetBestMoveUci() string {
+	if x != nil {
+		return x.BestMoveUci
+	}
+	return ""
+}
+
+func (x *MoveAnalysis) GetFenBefore() string {
+	if x != nil {
+		return x.FenBefore
+	}
+	return ""
+}
+
+func (x *MoveAnalysis) GetFenAfter() string {
+	if x != nil {
+		return x.FenAfter
+	}
+	return ""
+}
+
+func (x *MoveAnalysis) GetEvalBefore() *Evaluation {
+	if x != nil {
+		return x.EvalBefore
+	}
+	return nil
+}
+
+func (x *MoveAnalysis) GetEvalAfter() *Evaluation {
+	if x != nil {
+		return x.EvalAfter
+	}
+	return nil
+}
+
+func (x *MoveAnalysis) GetCentipawnLoss() int32 {
+	if x != nil {
+		return x.CentipawnLoss
+	}
+	return 0
+}
+
+func (x *MoveAnalysis) GetClassification() MoveClassification {
+	if x != nil {
+		return x.Classification
+	}
+	return MoveClassification_CLASSIFICATION_UNKNOWN
+}
+
+func (x *MoveAnalysis) GetPv() []string {
+	if x != nil {
+		return x.Pv
+	}
+	return nil
+}
+
+func (x *MoveAnalysis) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *MoveAnalysis) GetMoveAccuracy() float32 {
+	if x != nil {
+		return x.MoveAccuracy
+	}
+	return 0
+}
+
+func (x *MoveAnalysis) GetForced() bool {
+	if x != nil {
+		return x.Forced
+	}
+	return false
+}
+
+func (x *MoveAnalysis) GetRequestedDepth() int32 {
+	if x != nil {
+		return x.RequestedDepth
+	}
+	return 0
+}
+
+func (x *MoveAnalysis) GetFromCache() bool {
+	if x != nil {
+		return x.FromCache
+	}
+	return false
+}
+
+func (x *MoveAnalysis) GetGarbageTime() bool {
+	if x != nil {
+		return x.GarbageTime
+	}
+	return false
+}
+
+func (x *MoveAnalysis) GetMissedRepetition() bool {
+	if x != nil {
+		return x.MissedRepetition
+	}
+	return false
+}
+
+func (x *MoveAnalysis) GetAllowedRepetition() bool {
+	if x != nil {
+		return x.AllowedRepetition
+	}
+	return false
+}
+
+func (x *MoveAnalysis) GetMaterialBefore() *Material {
+	if x != nil {
+		return x.MaterialBefore
+	}
+	return nil
+}
+
+func (x *MoveAnalysis) GetMaterialAfter() *Material {
+	if x != nil {
+		return x.MaterialAfter
+	}
+	return nil
+}
+
+func (x *MoveAnalysis) GetMaterialSacrificed() int32 {
+	if x != nil {
+		return x.MaterialSacrificed
+	}
+	return 0
+}
+
+func (x *MoveAnalysis) GetAnalyzedAtUnixMs() int64 {
+	if x != nil {
+		return x.AnalyzedAtUnixMs
+	}
+	return 0
+}
+
+func (x *MoveAnalysis) GetEngineTimeMs() int64 {
+	if x != nil {
+		return x.EngineTimeMs
+	}
+	return 0
+}
+
+func (x *MoveAnalysis) GetQueueTimeMs() int64 {
+	if x != nil {
+		return x.QueueTimeMs
+	}
+	return 0
+}
+
+func (x *MoveAnalysis) GetSearchTimeMs() int64 {
+	if x != nil {
+		return x.SearchTimeMs
+	}
+	return 0
+}
+
+func (x *MoveAnalysis) GetMovetimeMs() int64 {
+	if x != nil {
+		return x.MovetimeMs
+	}
+	return 0
+}
+
+func (x *MoveAnalysis) GetExplanation() string {
+	if x != nil {
+		return x.Explanation
+	}
+	return ""
+}
+
+func (x *MoveAnalysis) GetSource() AnalysisSource {
+	if x != nil {
+		return x.Source
+	}
+	return AnalysisSource_ANALYSIS_SOURCE_UNSPECIFIED
+}
+
+func (x *MoveAnalysis) GetClassificationGlyph() string {
+	if x != nil {
+		return x.ClassificationGlyph
+	}
+	return ""
+}
+
+func (x *MoveAnalysis) GetClassificationLabel() string {
+	if x != nil {
+		return x.ClassificationLabel
+	}
+	return ""
+}
+
+func (x *MoveAnalysis) GetClassificationColor() string {
+	if x != nil {
+		return x.ClassificationColor
+	}
+	return ""
+}
+
+func (x *MoveAnalysis) GetExpectedPointsLoss() float32 {
+	if x != nil {
+		return x.ExpectedPointsLoss
+	}
+	return 0
+}
+
+func (x *MoveAnalysis) GetHighlights() *Highlights {
+	if x != nil {
+		return x.Highlights
+	}
+	return nil
+}
+
+// Squares of an analyzed move for the board to highlight
+type Highlights struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BestMove      *MoveSquares           `protobuf:"bytes,1,opt,name=best_move,json=bestMove,proto3" json:"best_move,omitempty"`                // Unset without a legal best move
+	Refutation    *MoveSquares           `protobuf:"bytes,2,opt,name=refutation,proto3" json:"refutation,omitempty"`                            // The opponent's best reply to the move played; unset without one
+	HangingSquare string                 `protobuf:"bytes,3,opt,name=hanging_square,json=hangingSquare,proto3" json:"hanging_square,omitempty"` // The mover's piece the refutation takes when the move loses material; empty otherwise
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Highlights) Reset() {
+	*x = Highlights{}
+	mi := &file_proto_analysis_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Highlights) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Highlights) ProtoMessage() {}
+
+func (x *Highlights) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Highlights.ProtoReflect.Descriptor instead.
+func (*Highlights) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Highlights) GetBestMove() *MoveSquares {
+	if x != nil {
+		return x.BestMove
+	}
+	return nil
+}
+
+func (x *Highlights) GetRefutation() *MoveSquares {
+	if x != nil {
+		return x.Refutation
+	}
+	return nil
+}
+
+func (x *Highlights) GetHangingSquare() string {
+	if x != nil {
+		return x.HangingSquare
+	}
+	return ""
+}
+
+// Squares of a move, from its UCI
+type MoveSquares struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`                         // e.g. "e1"
+	To            string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`                             // For castling the king's destination, on the g or c file
+	Promotion     string                 `protobuf:"bytes,3,opt,name=promotion,proto3" json:"promotion,omitempty"`               // "q", "r", "b" or "n"; empty if not a promotion
+	RookFrom      string                 `protobuf:"bytes,4,opt,name=rook_from,json=rookFrom,proto3" json:"rook_from,omitempty"` // The rook's squares when castling; empty otherwise
+	RookTo        string                 `protobuf:"bytes,5,opt,name=rook_to,json=rookTo,proto3" json:"rook_to,omitempty"`
+	Captured      string                 `protobuf:"bytes,6,opt,name=captured,proto3" json:"captured,omitempty"` // The piece the move takes, the passed pawn's for en passant; empty if none
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MoveSquares) Reset() {
+	*x = MoveSquares{}
+	mi := &file_proto_analysis_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MoveSquares) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MoveSquares) ProtoMessage() {}
+
+func (x *MoveSquares) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MoveSquares.ProtoReflect.Descriptor instead.
+func (*MoveSquares) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *MoveSquares) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *MoveSquares) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *MoveSquares) GetPromotion() string {
+	if x != nil {
+		return x.Promotion
+	}
+	return ""
+}
+
+func (x *MoveSquares) GetRookFrom() string {
+	if x != nil {
+		return x.RookFrom
+	}
+	return ""
+}
+
+func (x *MoveSquares) GetRookTo() string {
+	if x != nil {
+		return x.RookTo
+	}
+	return ""
+}
+
+func (x *MoveSquares) GetCaptured() string {
+	if x != nil {
+		return x.Captured
+	}
+	return ""
+}
+
+// Each side's material in pawns: pawn 1, knight and bishop 3, rook 5, queen 9
+type Material struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	White         int32                  `protobuf:"varint,1,opt,name=white,proto3" json:"white,omitempty"`
+	Black         int32                  `protobuf:"varint,2,opt,name=black,proto3" json:"black,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Material) Reset() {
+	*x = Material{}
+	mi := &file_proto_analysis_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Material) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Material) ProtoMessage() {}
+
+func (x *Material) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Material.ProtoReflect.Descriptor instead.
+func (*Material) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Material) GetWhite() int32 {
+	if x != nil {
+		return x.White
+	}
+	return 0
+}
+
+func (x *Material) GetBlack() int32 {
+	if x != nil {
+		return x.Black
+	}
+	return 0
+}
+
+// Aggregated metrics for a player's side. Accuracy and ACPL are
+// calculated the same way as by the evaluation package's reports, and
+// every accuracy and ACPL in this API is rounded to one decimal.
+type GameMetrics struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Accuracy           float32                `protobuf:"fixed32,1,opt,name=accuracy,proto3" json:"accuracy,omitempty"`                                                            // Accuracy in percent (0-100)
+	Acpl               float32                `protobuf:"fixed32,2,opt,name=acpl,proto3" json:"acpl,omitempty"`                                                                    // Average centipawn loss, in centipawns
+	Blunders           int32                  `protobuf:"varint,3,opt,name=blunders,proto3" json:"blunders,omitempty"`                                                             // Number of blunders
+	Mistakes           int32                  `protobuf:"varint,4,opt,name=mistakes,proto3" json:"mistakes,omitempty"`                                                             // Number of mistakes
+	Inaccuracies       int32                  `protobuf:"varint,5,opt,name=inaccuracies,proto3" json:"inaccuracies,omitempty"`                                                     // Number of inaccuracies
+	GoodMoves          int32                  `protobuf:"varint,6,opt,name=good_moves,json=goodMoves,proto3" json:"good_moves,omitempty"`                                          // Number of good moves
+	ExcellentMoves     int32                  `protobuf:"varint,7,opt,name=excellent_moves,json=excellentMoves,proto3" json:"excellent_moves,omitempty"`                           // Number of excellent moves
+	BestMoves          int32                  `protobuf:"varint,8,opt,name=best_moves,json=bestMoves,proto3" json:"best_moves,omitempty"`                                          // Number of best moves
+	BrilliantMoves     int32                  `protobuf:"varint,9,opt,name=brilliant_moves,json=brilliantMoves,proto3" json:"brilliant_moves,omitempty"`                           // Number of brilliant moves
+	BookMoves          int32                  `protobuf:"varint,10,opt,name=book_moves,json=bookMoves,proto3" json:"book_moves,omitempty"`                                         // Number of book moves
+	TotalMoves         int32                  `protobuf:"varint,11,opt,name=total_moves,json=totalMoves,proto3" json:"total_moves,omitempty"`                                      // Total moves analyzed
+	PerformanceRating  int32                  `protobuf:"varint,12,opt,name=performance_rating,json=performanceRating,proto3" json:"performance_rating,omitempty"`                 // Opponent rating ±400 for a win or loss (FIDE single-game bound), ±25 for accuracy; 0 if unrated
+	GarbageTimeMoves   int32                  `protobuf:"varint,13,opt,name=garbage_time_moves,json=garbageTimeMoves,proto3" json:"garbage_time_moves,omitempty"`                  // Of total_moves, left out of accuracy and ACPL
+	Resilience         *Resilience            `protobuf:"bytes,14,opt,name=resilience,proto3" json:"resilience,omitempty"`                                                         // Unset when the game result is unknown
+	AccuracyModel      AccuracyModel          `protobuf:"varint,15,opt,name=accuracy_model,json=accuracyModel,proto3,enum=analysis.AccuracyModel" json:"accuracy_model,omitempty"` // Model accuracy was scored with
+	AccuracyStddev     float32                `protobuf:"fixed32,16,opt,name=accuracy_stddev,json=accuracyStddev,proto3" json:"accuracy_stddev,omitempty"`                         // Standard deviation of accuracy from the noise of evaluations at the depths searched, in points; deeper analyses have smaller ones
+	AcplStddev         float32                `protobuf:"fixed32,17,opt,name=acpl_stddev,json=acplStddev,proto3" json:"acpl_stddev,omitempty"`                                     // Standard deviation of acpl, in centipawns
+	ExpectedPointsLost float32                `protobuf:"fixed32,18,opt,name=expected_points_lost,json=expectedPointsLost,proto3" json:"expected_points_lost,omitempty"`           // Sum of the moves' expected_points_loss, garbage time included
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *GameMetrics) Reset() {
+	*x = GameMetrics{}
+	mi := &file_proto_analysis_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameMetrics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameMetrics) ProtoMessage() {}
+
+func (x *GameMetrics) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameMetrics.ProtoReflect.Descriptor instead.
+func (*GameMetrics) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *GameMetrics) GetAccuracy() float32 {
+	if x != nil {
+		return x.Accuracy
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetAcpl() float32 {
+	if x != nil {
+		return x.Acpl
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetBlunders() int32 {
+	if x != nil {
+		return x.Blunders
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetMistakes() int32 {
+	if x != nil {
+		return x.Mistakes
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetInaccuracies() int32 {
+	if x != nil {
+		return x.Inaccuracies
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetGoodMoves() int32 {
+	if x != nil {
+		return x.GoodMoves
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetExcellentMoves() int32 {
+	if x != nil {
+		return x.ExcellentMoves
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetBestMoves() int32 {
+	if x != nil {
+		return x.BestMoves
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetBrilliantMoves() int32 {
+	if x != nil {
+		return x.BrilliantMoves
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetBookMoves() int32 {
+	if x != nil {
+		return x.BookMoves
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetTotalMoves() int32 {
+	if x != nil {
+		return x.TotalMoves
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetPerformanceRating() int32 {
+	if x != nil {
+		return x.PerformanceRating
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetGarbageTimeMoves() int32 {
+	if x != nil {
+		return x.GarbageTimeMoves
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetResilience() *Resilience {
+	if x != nil {
+		return x.Resilience
+	}
+	return nil
+}
+
+func (x *GameMetrics) GetAccuracyModel() AccuracyModel {
+	if x != nil {
+		return x.AccuracyModel
+	}
+	return AccuracyModel_ACCURACY_MODEL_UNSPECIFIED
+}
+
+func (x *GameMetrics) GetAccuracyStddev() float32 {
+	if x != nil {
+		return x.AccuracyStddev
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetAcplStddev() float32 {
+	if x != nil {
+		return x.AcplStddev
+	}
+	return 0
+}
+
+func (x *GameMetrics) GetExpectedPointsLost() float32 {
+	if x != nil {
+		return x.ExpectedPointsLost
+	}
+	return 0
+}
+
+// How a player did from lost and won positions and with the opponent's mistakes
+type Resilience struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Swindles       int32                  `protobuf:"varint,1,opt,name=swindles,proto3" json:"swindles,omitempty"`                                    // Times below 10% win probability in a game drawn or won
+	BotchedWins    int32                  `protobuf:"varint,2,opt,name=botched_wins,json=botchedWins,proto3" json:"botched_wins,omitempty"`           // Times above 90% win probability in a game drawn or lost
+	Gifts          int32                  `protobuf:"varint,3,opt,name=gifts,proto3" json:"gifts,omitempty"`                                          // Opponent mistakes, blunders and missed wins
+	GiftConversion float32                `protobuf:"fixed32,4,opt,name=gift_conversion,json=giftConversion,proto3" json:"gift_conversion,omitempty"` // Mean win probability gained from before each gift to after the reply, in points
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Resilience) Reset() {
+	*x = Resilience{}
+	mi := &file_proto_analysis_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Resilience) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Resilience) ProtoMessage() {}
+
+func (x *Resilience) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Resilience.ProtoReflect.Descriptor instead.
+func (*Resilience) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *Resilience) GetSwindles() int32 {
+	if x != nil {
+		return x.Swindles
+	}
+	return 0
+}
+
+func (x *Resilience) GetBotchedWins() int32 {
+	if x != nil {
+		return x.BotchedWins
+	}
+	return 0
+}
+
+func (x *Resilience) GetGifts() int32 {
+	if x != nil {
+		return x.Gifts
+	}
+	return 0
+}
+
+func (x *Resilience) GetGiftConversion() float32 {
+	if x != nil {
+		return x.GiftConversion
+	}
+	return 0
+}
+
+// Request for MultiPV best moves
+type GetBestMovesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Fen           string                 `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"`      // FEN string
+	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"` // Number of best moves to return (0 = STOCKFISH_MULTI_PV, at most MAX_MULTI_PV)
+	Depth         int32                  `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"` // Analysis depth
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBestMovesRequest) Reset() {
+	*x = GetBestMovesRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBestMovesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBestMovesRequest) ProtoMessage() {}
+
+func (x *GetBestMovesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBestMovesRequest.ProtoReflect.Descriptor instead.
+func (*GetBestMovesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *GetBestMovesRequest) GetFen() string {
+	if x != nil {
+		return x.Fen
+	}
+	return ""
+}
+
+func (x *GetBestMovesRequest) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *GetBestMovesRequest) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+// Response with multiple best moves
+type BestMovesResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Fen            string                 `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"`
+	Moves          []*BestMove            `protobuf:"bytes,2,rep,name=moves,proto3" json:"moves,omitempty"`
+	Depth          int32                  `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"`
+	TimedOut       bool                   `protobuf:"varint,4,opt,name=timed_out,json=timedOut,proto3" json:"timed_out,omitempty"`                    // Search stopped by the analysis timeout, depth is what was reached
+	PvCount        int32                  `protobuf:"varint,5,opt,name=pv_count,json=pvCount,proto3" json:"pv_count,omitempty"`                       // PVs the engine produced, below count when the position has fewer legal moves
+	Notes          []string               `protobuf:"bytes,6,rep,name=notes,proto3" json:"notes,omitempty"`                                           // Why moves has fewer entries than asked for: fewer_legal_moves
+	GameOverReason string                 `protobuf:"bytes,7,opt,name=game_over_reason,json=gameOverReason,proto3" json:"game_over_reason,omitempty"` // "checkmate" or "stalemate" when there are no moves at all
+	TargetDepth    int32                  `protobuf:"varint,8,opt,name=target_depth,json=targetDepth,proto3" json:"target_depth,omitempty"`           // Depth searched for, after clamping to the service limits
+	Count          int32                  `protobuf:"varint,9,opt,name=count,proto3" json:"count,omitempty"`                                          // Moves asked of the engine, after applying the default and MAX_MULTI_PV
+	PonderMoveUci  string                 `protobuf:"bytes,10,opt,name=ponder_move_uci,json=ponderMoveUci,proto3" json:"ponder_move_uci,omitempty"`   // Reply the engine expects to the first move (empty = none)
+	PonderMoveSan  string                 `protobuf:"bytes,11,opt,name=ponder_move_san,json=ponderMoveSan,proto3" json:"ponder_move_san,omitempty"`   // ponder_move_uci in SAN, played after the first move
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *BestMovesResponse) Reset() {
+	*x = BestMovesResponse{}
+	mi := &file_proto_analysis_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BestMovesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BestMovesResponse) ProtoMessage() {}
+
+func (x *BestMovesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BestMovesResponse.ProtoReflect.Descriptor instead.
+func (*BestMovesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *BestMovesResponse) GetFen() string {
+	if x != nil {
+		return x.Fen
+	}
+	return ""
+}
+
+func (x *BestMovesResponse) GetMoves() []*BestMove {
+	if x != nil {
+		return x.Moves
+	}
+	return nil
+}
+
+func (x *BestMovesResponse) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *BestMovesResponse) GetTimedOut() bool {
+	if x != nil {
+		return x.TimedOut
+	}
+	return false
+}
+
+func (x *BestMovesResponse) GetPvCount() int32 {
+	if x != nil {
+		return x.PvCount
+	}
+	return 0
+}
+
+func (x *BestMovesResponse) GetNotes() []string {
+	if x != nil {
+		return x.Notes
+	}
+	return nil
+}
+
+func (x *BestMovesResponse) GetGameOverReason() string {
+	if x != nil {
+		return x.GameOverReason
+	}
+	return ""
+}
+
+func (x *BestMovesResponse) GetTargetDepth() int32 {
+	if x != nil {
+		return x.TargetDepth
+	}
+	return 0
+}
+
+func (x *BestMovesResponse) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *BestMovesResponse) GetPonderMoveUci() string {
+	if x != nil {
+		return x.PonderMoveUci
+	}
+	return ""
+}
+
+func (x *BestMovesResponse) GetPonderMoveSan() string {
+	if x != nil {
+		return x.PonderMoveSan
+	}
+	return ""
+}
+
+// A single best move with evaluation
+type BestMove struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Rank             int32                  `protobuf:"varint,1,opt,name=rank,proto3" json:"rank,omitempty"`                                                   // Rank (1 = best, 2 = second best, etc.)
+	MoveUci          string                 `protobuf:"bytes,2,opt,name=move_uci,json=moveUci,proto3" json:"move_uci,omitempty"`                               // Move in UCI format
+	MoveSan          string                 `protobuf:"bytes,3,opt,name=move_san,json=moveSan,proto3" json:"move_san,omitempty"`                               // Move in SAN format (if available)
+	Evaluation       *Evaluation            `protobuf:"bytes,4,opt,name=evaluation,proto3" json:"evaluation,omitempty"`                                        // Evaluation after this move
+	Pv               []string               `protobuf:"bytes,5,rep,name=pv,proto3" json:"pv,omitempty"`                                                        // Principal variation
+	TransposesToRank int32                  `protobuf:"varint,6,opt,name=transposes_to_rank,json=transposesToRank,proto3" json:"transposes_to_rank,omitempty"` // Rank of a better move whose line reaches the same position within two moves each, 0 when independent
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *BestMove) Reset() {
+	*x = BestMove{}
+	mi := &file_proto_analysis_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BestMove) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BestMove) ProtoMessage() {}
+
+func (x *BestMove) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BestMove.ProtoReflect.Descriptor instead.
+func (*BestMove) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *BestMove) GetRank() int32 {
+	if x != nil {
+		return x.Rank
+	}
+	return 0
+}
+
+func (x *BestMove) GetMoveUci() string {
+	if x != nil {
+		return x.MoveUci
+	}
+	return ""
+}
+
+func (x *BestMove) GetMoveSan() string {
+	if x != nil {
+		return x.MoveSan
+	}
+	return ""
+}
+
+func (x *BestMove) GetEvaluation() *Evaluation {
+	if x != nil {
+		return x.Evaluation
+	}
+	return nil
+}
+
+func (x *BestMove) GetPv() []string {
+	if x != nil {
+		return x.Pv
+	}
+	return nil
+}
+
+func (x *BestMove) GetTransposesToRank() int32 {
+	if x != nil {
+		return x.TransposesToRank
+	}
+	return 0
+}
+
+// Health check request
+type HealthCheckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthCheckRequest) Reset() {
+	*x = HealthCheckRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthCheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthCheckRequest) ProtoMessage() {}
+
+func (x *HealthCheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthCheckRequest.ProtoReflect.Descriptor instead.
+func (*HealthCheckRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{27}
+}
+
+// Health check response
+type HealthCheckResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Healthy          bool                   `protobuf:"varint,1,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	Status           string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"` // "ok", or "degraded" while game analyses are degraded
+	AvailableWorkers int32                  `protobuf:"varint,3,opt,name=available_workers,json=availableWorkers,proto3" json:"available_workers,omitempty"`
+	TotalWorkers     int32                  `protobuf:"varint,4,opt,name=total_workers,json=totalWorkers,proto3" json:"total_workers,omitempty"`
+	StockfishVersion string                 `protobuf:"bytes,5,opt,name=stockfish_version,json=stockfishVersion,proto3" json:"stockfish_version,omitempty"`
+	UptimeSeconds    int64                  `protobuf:"varint,6,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	Degraded         bool                   `protobuf:"varint,7,opt,name=degraded,proto3" json:"degraded,omitempty"`                                    // The engine pool is starved and new game analyses are degraded
+	PoolWaitP95Ms    int64                  `protobuf:"varint,8,opt,name=pool_wait_p95_ms,json=poolWaitP95Ms,proto3" json:"pool_wait_p95_ms,omitempty"` // 95th percentile wait for an engine over the degradation window
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *HealthCheckResponse) Reset() {
+	*x = HealthCheckResponse{}
+	mi := &file_proto_analysis_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthCheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthCheckResponse) ProtoMessage() {}
+
+func (x *HealthCheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthCheckResponse.ProtoReflect.Descriptor instead.
+func (*HealthCheckResponse) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *HealthCheckResponse) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
+}
+
+func (x *HealthCheckResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *HealthCheckResponse) GetAvailableWorkers() int32 {
+	if x != nil {
+		return x.AvailableWorkers
+	}
+	return 0
+}
+
+func (x *HealthCheckResponse) GetTotalWorkers() int32 {
+	if x != nil {
+		return x.TotalWorkers
+	}
+	return 0
+}
+
+func (x *HealthCheckResponse) GetStockfishVersion() string {
+	if x != nil {
+		return x.StockfishVersion
+	}
+	return ""
+}
+
+func (x *HealthCheckResponse) GetUptimeSeconds() int64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *HealthCheckResponse) GetDegraded() bool {
+	if x != nil {
+		return x.Degraded
+	}
+	return false
+}
+
+func (x *HealthCheckResponse) GetPoolWaitP95Ms() int64 {
+	if x != nil {
+		return x.PoolWaitP95Ms
+	}
+	return 0
+}
+
+// Service info request
+type GetServiceInfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetServiceInfoRequest) Reset() {
+	*x = GetServiceInfoRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetServiceInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetServiceInfoRequest) ProtoMessage() {}
+
+func (x *GetServiceInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetServiceInfoRequest.ProtoReflect.Descriptor instead.
+func (*GetServiceInfoRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{29}
+}
+
+// Build and configuration info of the running service.
+// Field names are consumed by the admin panel - keep them stable.
+type ServiceInfo struct {
+	state                   protoimpl.MessageState               `protogen:"open.v1"`
+	GitSha                  string                               `protobuf:"bytes,1,opt,name=git_sha,json=gitSha,proto3" json:"git_sha,omitempty"`                                                                                                             // Git commit the binary was built from
+	BuildTime               string                               `protobuf:"bytes,2,opt,name=build_time,json=buildTime,proto3" json:"build_time,omitempty"`                                                                                                    // Build timestamp (UTC, RFC 3339)
+	GoVersion               string                               `protobuf:"bytes,3,opt,name=go_version,json=goVersion,proto3" json:"go_version,omitempty"`                                                                                                    // Go toolchain version
+	StockfishVersion        string                               `protobuf:"bytes,4,opt,name=stockfish_version,json=stockfishVersion,proto3" json:"stockfish_version,omitempty"`                                                                               // Stockfish "id name" string
+	NnueNet                 string                               `protobuf:"bytes,5,opt,name=nnue_net,json=nnueNet,proto3" json:"nnue_net,omitempty"`                                                                                                          // NNUE network file in use
+	DefaultDepth            int32                                `protobuf:"varint,6,opt,name=default_depth,json=defaultDepth,proto3" json:"default_depth,omitempty"`                                                                                          // Depth used when a request omits it
+	MaxDepth                int32                                `protobuf:"varint,7,opt,name=max_depth,json=maxDepth,proto3" json:"max_depth,omitempty"`                                                                                                      // Maximum depth a request can ask for
+	Thresholds              *ClassificationThresholds            `protobuf:"bytes,8,opt,name=thresholds,proto3" json:"thresholds,omitempty"`                                                                                                                   // Thresholds of the default profile
+	DefaultThresholdProfile string                               `protobuf:"bytes,9,opt,name=default_threshold_profile,json=defaultThresholdProfile,proto3" json:"default_threshold_profile,omitempty"`                                                        // Profile used when a request omits it
+	ThresholdProfiles       map[string]*ClassificationThresholds `protobuf:"bytes,10,rep,name=threshold_profiles,json=thresholdProfiles,proto3" json:"threshold_profiles,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // All available profiles
+	EngineProfiles          []string                             `protobuf:"bytes,11,rep,name=engine_profiles,json=engineProfiles,proto3" json:"engine_profiles,omitempty"`                                                                                    // Engine profiles besides the primary one
+	MaxGamePlies            int32                                `protobuf:"varint,12,opt,name=max_game_plies,json=maxGamePlies,proto3" json:"max_game_plies,omitempty"`                                                                                       // Longer games are refused with INVALID_ARGUMENT (0 = no limit)
+	FastModePlies           int32                                `protobuf:"varint,13,opt,name=fast_mode_plies,json=fastModePlies,proto3" json:"fast_mode_plies,omitempty"`                                                                                    // Longer games are searched at fast_mode_depth unless full_depth is set (0 = never)
+	FastModeDepth           int32                                `protobuf:"varint,14,opt,name=fast_mode_depth,json=fastModeDepth,proto3" json:"fast_mode_depth,omitempty"`                                                                                    // Depth of fast mode
+	StockfishPath           string                               `protobuf:"bytes,15,opt,name=stockfish_path,json=stockfishPath,proto3" json:"stockfish_path,omitempty"`                                                                                       // Absolute path of the Stockfish binary selected at startup
+	unknownFields           protoimpl.UnknownFields
+	sizeCache               protoimpl.SizeCache
+}
+
+func (x *ServiceInfo) Reset() {
+	*x = ServiceInfo{}
+	mi := &file_proto_analysis_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServiceInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceInfo) ProtoMessage() {}
+
+func (x *ServiceInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceInfo.ProtoReflect.Descriptor instead.
+func (*ServiceInfo) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ServiceInfo) GetGitSha() string {
+	if x != nil {
+		return x.GitSha
+	}
+	return ""
+}
+
+func (x *ServiceInfo) GetBuildTime() string {
+	if x != nil {
+		return x.BuildTime
+	}
+	return ""
+}
+
+func (x *ServiceInfo) GetGoVersion() string {
+	if x != nil {
+		return x.GoVersion
+	}
+	return ""
+}
+
+func (x *ServiceInfo) GetStockfishVersion() string {
+	if x != nil {
+		return x.StockfishVersion
+	}
+	return ""
+}
+
+func (x *ServiceInfo) GetNnueNet() string {
+	if x != nil {
+		return x.NnueNet
+	}
+	return ""
+}
+
+func (x *ServiceInfo) GetDefaultDepth() int32 {
+	if x != nil {
+		return x.DefaultDepth
+	}
+	return 0
+}
+
+func (x *ServiceInfo) GetMaxDepth() int32 {
+	if x != nil {
+		return x.MaxDepth
+	}
+	return 0
+}
+
+func (x *ServiceInfo) GetThresholds() *ClassificationThresholds {
+	if x != nil {
+		return x.Thresholds
+	}
+	return nil
+}
+
+func (x *ServiceInfo) GetDefaultThresholdProfile() string {
+	if x != nil {
+		return x.DefaultThresholdProfile
+	}
+	return ""
+}
+
+func (x *ServiceInfo) GetThresholdProfiles() map[string]*ClassificationThresholds {
+	if x != nil {
+		return x.ThresholdProfiles
+	}
+	return nil
+}
+
+func (x *ServiceInfo) GetEngineProfiles() []string {
+	if x != nil {
+		return x.EngineProfiles
+	}
+	return nil
+}
+
+func (x *ServiceInfo) GetMaxGamePlies() int32 {
+	if x != nil {
+		return x.MaxGamePlies
+	}
+	return 0
+}
+
+func (x *ServiceInfo) GetFastModePlies() int32 {
+	if x != nil {
+		return x.FastModePlies
+	}
+	return 0
+}
+
+func (x *ServiceInfo) GetFastModeDepth() int32 {
+	if x != nil {
+		return x.FastModeDepth
+	}
+	return 0
+}
+
+func (x *ServiceInfo) GetStockfishPath() string {
+	if x != nil {
+		return x.StockfishPath
+	}
+	return ""
+}
+
+// Centipawn-loss upper bounds used for move classification
+type ClassificationThresholds struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Best          int32                  `protobuf:"varint,1,opt,name=best,proto3" json:"best,omitempty"`                                  // Loss <= best is a best move
+	Excellent     int32                  `protobuf:"varint,2,opt,name=excellent,proto3" json:"excellent,omitempty"`                        // Loss <= excellent is an excellent move
+	Good          int32                  `protobuf:"varint,3,opt,name=good,proto3" json:"good,omitempty"`                                  // Loss <= good is a good move
+	Inaccuracy    int32                  `protobuf:"varint,4,opt,name=inaccuracy,proto3" json:"inaccuracy,omitempty"`                      // Loss <= inaccuracy is an inaccuracy
+	Mistake       int32                  `protobuf:"varint,5,opt,name=mistake,proto3" json:"mistake,omitempty"`                            // Loss <= mistake is a mistake, above is a blunder
+	GarbageWin    int32                  `protobuf:"varint,6,opt,name=garbage_win,json=garbageWin,proto3" json:"garbage_win,omitempty"`    // Mover's eval above this is garbage time (0 = off)
+	GarbageLoss   int32                  `protobuf:"varint,7,opt,name=garbage_loss,json=garbageLoss,proto3" json:"garbage_loss,omitempty"` // Mover's eval below this (negative) is garbage time (0 = off)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClassificationThresholds) Reset() {
+	*x = ClassificationThresholds{}
+	mi := &file_proto_analysis_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClassificationThresholds) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClassificationThresholds) ProtoMessage() {}
+
+func (x *ClassificationThresholds) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClassificationThresholds.ProtoReflect.Descriptor instead.
+func (*ClassificationThresholds) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ClassificationThresholds) GetBest() int32 {
+	if x != nil {
+		return x.Best
+	}
+	return 0
+}
+
+func (x *ClassificationThresholds) GetExcellent() int32 {
+	if x != nil {
+		return x.Excellent
+	}
+	return 0
+}
+
+func (x *ClassificationThresholds) GetGood() int32 {
+	if x != nil {
+		return x.Good
+	}
+	return 0
+}
+
+func (x *ClassificationThresholds) GetInaccuracy() int32 {
+	if x != nil {
+		return x.Inaccuracy
+	}
+	return 0
+}
+
+func (x *ClassificationThresholds) GetMistake() int32 {
+	if x != nil {
+		return x.Mistake
+	}
+	return 0
+}
+
+func (x *ClassificationThresholds) GetGarbageWin() int32 {
+	if x != nil {
+		return x.GarbageWin
+	}
+	return 0
+}
+
+func (x *ClassificationThresholds) GetGarbageLoss() int32 {
+	if x != nil {
+		return x.GarbageLoss
+	}
+	return 0
+}
+
+// Request to export a game analysis
+type ExportGameAnalysisRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Analysis      *GameAnalysis          `protobuf:"bytes,1,opt,name=analysis,proto3" json:"analysis,omitempty"`                         // Analysis returned by AnalyzeGame
+	Pgn           string                 `protobuf:"bytes,2,opt,name=pgn,proto3" json:"pgn,omitempty"`                                   // PGN the analysis was made from (required for ANNOTATED_PGN)
+	Format        ExportFormat           `protobuf:"varint,3,opt,name=format,proto3,enum=analysis.ExportFormat" json:"format,omitempty"` // Output format
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportGameAnalysisRequest) Reset() {
+	*x = ExportGameAnalysisRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportGameAnalysisRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportGameAnalysisRequest) ProtoMessage() {}
+
+func (x *ExportGameAnalysisRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportGameAnalysisRequest.ProtoReflect.Descriptor instead.
+func (*ExportGameAnalysisRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ExportGameAnalysisRequest) GetAnalysis() *GameAnalysis {
+	if x != nil {
+		return x.Analysis
+	}
+	return nil
+}
+
+func (x *ExportGameAnalysisRequest) GetPgn() string {
+	if x != nil {
+		return x.Pgn
+	}
+	return ""
+}
+
+func (x *ExportGameAnalysisRequest) GetFormat() ExportFormat {
+	if x != nil {
+		return x.Format
+	}
+	return ExportFormat_EXPORT_FORMAT_UNSPECIFIED
+}
+
+// Exported game analysis
+type ExportGameAnalysisResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Content       string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`                            // Exported document
+	ContentType   string                 `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"` // MIME type of content
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportGameAnalysisResponse) Reset() {
+	*x = ExportGameAnalysisResponse{}
+	mi := &file_proto_analysis_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportGameAnalysisResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportGameAnalysisResponse) ProtoMessage() {}
+
+func (x *ExportGameAnalysisResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportGameAnalysisResponse.ProtoReflect.Descriptor instead.
+func (*ExportGameAnalysisResponse) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ExportGameAnalysisResponse) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *ExportGameAnalysisResponse) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+// Request to score a game's stored moves again
+type RecomputeMetricsRequest struct {
+	state              protoimpl.MessageState  `protogen:"open.v1"`
+	Moves              []*StoredMoveEvaluation `protobuf:"bytes,1,rep,name=moves,proto3" json:"moves,omitempty"`                                                                           // In ply order; plies may be missing, not repeated
+	ThresholdProfile   string                  `protobuf:"bytes,2,opt,name=threshold_profile,json=thresholdProfile,proto3" json:"threshold_profile,omitempty"`                             // Classification thresholds: standard, strict, lenient (empty = server default)
+	AccuracyModel      AccuracyModel           `protobuf:"varint,3,opt,name=accuracy_model,json=accuracyModel,proto3,enum=analysis.AccuracyModel" json:"accuracy_model,omitempty"`         // How accuracy is scored (default ELOINSIGHT)
+	ExcludeGarbageTime *bool                   `protobuf:"varint,4,opt,name=exclude_garbage_time,json=excludeGarbageTime,proto3,oneof" json:"exclude_garbage_time,omitempty"`              // Leave moves in decided positions out of accuracy and ACPL (unset = server default)
+	Result             string                  `protobuf:"bytes,5,opt,name=result,proto3" json:"result,omitempty"`                                                                         // Game result for resilience: 1-0, 0-1, 1/2-1/2 or * (empty = unknown)
+	EvalPerspective    EvalPerspective         `protobuf:"varint,6,opt,name=eval_perspective,json=evalPerspective,proto3,enum=analysis.EvalPerspective" json:"eval_perspective,omitempty"` // Sign convention of the moves' evaluations (default SIDE_TO_MOVE)
+	FenStartOffset     int32                   `protobuf:"varint,7,opt,name=fen_start_offset,json=fenStartOffset,proto3" json:"fen_start_offset,omitempty"`                                // 1 when the game started from a FEN with Black to move, so even plies are Black's
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *RecomputeMetricsRequest) Reset() {
+	*x = RecomputeMetricsRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecomputeMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecomputeMetricsRequest) ProtoMessage() {}
+
+func (x *RecomputeMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecomputeMetricsRequest.ProtoReflect.Descriptor instead.
+func (*RecomputeMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *RecomputeMetricsRequest) GetMoves() []*StoredMoveEvaluation {
+	if x != nil {
+		return x.Moves
+	}
+	return nil
+}
+
+func (x *RecomputeMetricsRequest) GetThresholdProfile() string {
+	if x != nil {
+		return x.ThresholdProfile
+	}
+	return ""
+}
+
+func (x *RecomputeMetricsRequest) GetAccuracyModel() AccuracyModel {
+	if x != nil {
+		return x.AccuracyModel
+	}
+	return AccuracyModel_ACCURACY_MODEL_UNSPECIFIED
+}
+
+func (x *RecomputeMetricsRequest) GetExcludeGarbageTime() bool {
+	if x != nil && x.ExcludeGarbageTime != nil {
+		return *x.ExcludeGarbageTime
+	}
+	return false
+}
+
+func (x *RecomputeMetricsRequest) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *RecomputeMetricsRequest) GetEvalPerspective() EvalPerspective {
+	if x != nil {
+		return x.EvalPerspective
+	}
+	return EvalPerspective_EVAL_PERSPECTIVE_UNSPECIFIED
+}
+
+func (x *RecomputeMetricsRequest) GetFenStartOffset() int32 {
+	if x != nil {
+		return x.FenStartOffset
+	}
+	return 0
+}
+
+// A move of a stored analysis
+type StoredMoveEvaluation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ply           int32                  `protobuf:"varint,1,opt,name=ply,proto3" json:"ply,omitempty"`                                    // Ply (half-move, 0-indexed)
+	Color         string                 `protobuf:"bytes,2,opt,name=color,proto3" json:"color,omitempty"`                                 // "white" or "black", as the ply and fen_start_offset say
+	EvalBefore    *Evaluation            `protobuf:"bytes,3,opt,name=eval_before,json=evalBefore,proto3" json:"eval_before,omitempty"`     // Before the move
+	EvalAfter     *Evaluation            `protobuf:"bytes,4,opt,name=eval_after,json=evalAfter,proto3" json:"eval_after,omitempty"`        // After the move
+	BestMove      bool                   `protobuf:"varint,5,opt,name=best_move,json=bestMove,proto3" json:"best_move,omitempty"`          // The move played was the engine's best
+	Book          bool                   `protobuf:"varint,6,opt,name=book,proto3" json:"book,omitempty"`                                  // Opening book move, kept out of accuracy and ACPL
+	DepthBefore   int32                  `protobuf:"varint,7,opt,name=depth_before,json=depthBefore,proto3" json:"depth_before,omitempty"` // Depths the evaluations were searched to, for the stddevs (0 = unknown)
+	DepthAfter    int32                  `protobuf:"varint,8,opt,name=depth_after,json=depthAfter,proto3" json:"depth_after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StoredMoveEvaluation) Reset() {
+	*x = StoredMoveEvaluation{}
+	mi := &file_proto_analysis_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoredMoveEvaluation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoredMoveEvaluation) ProtoMessage() {}
+
+func (x *StoredMoveEvaluation) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoredMoveEvaluation.ProtoReflect.Descriptor instead.
+func (*StoredMoveEvaluation) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *StoredMoveEvaluation) GetPly() int32 {
+	if x != nil {
+		return x.Ply
+	}
+	return 0
+}
+
+func (x *StoredMoveEvaluation) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *StoredMoveEvaluation) GetEvalBefore() *Evaluation {
+	if x != nil {
+		return x.EvalBefore
+	}
+	return nil
+}
+
+func (x *StoredMoveEvaluation) GetEvalAfter() *Evaluation {
+	if x != nil {
+		return x.EvalAfter
+	}
+	return nil
+}
+
+func (x *StoredMoveEvaluation) GetBestMove() bool {
+	if x != nil {
+		return x.BestMove
+	}
+	return false
+}
+
+func (x *StoredMoveEvaluation) GetBook() bool {
+	if x != nil {
+		return x.Book
+	}
+	return false
+}
+
+func (x *StoredMoveEvaluation) GetDepthBefore() int32 {
+	if x != nil {
+		return x.DepthBefore
+	}
+	return 0
+}
+
+func (x *StoredMoveEvaluation) GetDepthAfter() int32 {
+	if x != nil {
+		return x.DepthAfter
+	}
+	return 0
+}
+
+// Both players' metrics scored again from stored moves
+type RecomputeMetricsResponse struct {
+	state            protoimpl.MessageState    `protogen:"open.v1"`
+	WhiteMetrics     *GameMetrics              `protobuf:"bytes,1,opt,name=white_metrics,json=whiteMetrics,proto3" json:"white_metrics,omitempty"`
+	BlackMetrics     *GameMetrics              `protobuf:"bytes,2,opt,name=black_metrics,json=blackMetrics,proto3" json:"black_metrics,omitempty"`
+	ThresholdProfile string                    `protobuf:"bytes,3,opt,name=threshold_profile,json=thresholdProfile,proto3" json:"threshold_profile,omitempty"` // Threshold profile used for classification
+	Thresholds       *ClassificationThresholds `protobuf:"bytes,4,opt,name=thresholds,proto3" json:"thresholds,omitempty"`                                     // Threshold values of that profile
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *RecomputeMetricsResponse) Reset() {
+	*x = RecomputeMetricsResponse{}
+	mi := &file_proto_analysis_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecomputeMetricsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecomputeMetricsResponse) ProtoMessage() {}
+
+func (x *RecomputeMetricsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecomputeMetricsResponse.ProtoReflect.Descriptor instead.
+func (*RecomputeMetricsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *RecomputeMetricsResponse) GetWhiteMetrics() *GameMetrics {
+	if x != nil {
+		return x.WhiteMetrics
+	}
+	return nil
+}
+
+func (x *RecomputeMetricsResponse) GetBlackMetrics() *GameMetrics {
+	if x != nil {
+		return x.BlackMetrics
+	}
+	return nil
+}
+
+func (x *RecomputeMetricsResponse) GetThresholdProfile() string {
+	if x != nil {
+		return x.ThresholdProfile
+	}
+	return ""
+}
+
+func (x *RecomputeMetricsResponse) GetThresholds() *ClassificationThresholds {
+	if x != nil {
+		return x.Thresholds
+	}
+	return nil
+}
+
+// Request to aggregate a player's analyzed games
+type AggregateAnalysesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Player        string                 `protobuf:"bytes,1,opt,name=player,proto3" json:"player,omitempty"`                  // Player name as in the games, matched case-insensitively
+	Games         []*AnalyzedGame        `protobuf:"bytes,2,rep,name=games,proto3" json:"games,omitempty"`                    // Games to aggregate, as either color
+	GameIds       []string               `protobuf:"bytes,3,rep,name=game_ids,json=gameIds,proto3" json:"game_ids,omitempty"` // Stored analyses to aggregate (needs a job store, not supported yet)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AggregateAnalysesRequest) Reset() {
+	*x = AggregateAnalysesRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregateAnalysesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregateAnalysesRequest) ProtoMessage() {}
+
+func (x *AggregateAnalysesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregateAnalysesRequest.ProtoReflect.Descriptor instead.
+func (*AggregateAnalysesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *AggregateAnalysesRequest) GetPlayer() string {
+	if x != nil {
+		return x.Player
+	}
+	return ""
+}
+
+func (x *AggregateAnalysesRequest) GetGames() []*AnalyzedGame {
+	if x != nil {
+		return x.Games
+	}
+	return nil
+}
+
+func (x *AggregateAnalysesRequest) GetGameIds() []string {
+	if x != nil {
+		return x.GameIds
+	}
+	return nil
+}
+
+// A game analysis with the game details it doesn't carry itself
+type AnalyzedGame struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Analysis       *GameAnalysis          `protobuf:"bytes,1,opt,name=analysis,proto3" json:"analysis,omitempty"` // Analysis returned by AnalyzeGame
+	WhitePlayer    string                 `protobuf:"bytes,2,opt,name=white_player,json=whitePlayer,proto3" json:"white_player,omitempty"`
+	BlackPlayer    string                 `protobuf:"bytes,3,opt,name=black_player,json=blackPlayer,proto3" json:"black_player,omitempty"`
+	Result         string                 `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`                                            // "1-0", "0-1" or "1/2-1/2"; "*" or empty for a game still being played or without a known result
+	Eco            string                 `protobuf:"bytes,5,opt,name=eco,proto3" json:"eco,omitempty"`                                                  // Opening ECO code
+	OpeningName    string                 `protobuf:"bytes,6,opt,name=opening_name,json=openingName,proto3" json:"opening_name,omitempty"`               // Opening name
+	TimeClass      string                 `protobuf:"bytes,7,opt,name=time_class,json=timeClass,proto3" json:"time_class,omitempty"`                     // bullet, blitz, rapid, classical or daily
+	PlayedAtUnixMs int64                  `protobuf:"varint,8,opt,name=played_at_unix_ms,json=playedAtUnixMs,proto3" json:"played_at_unix_ms,omitempty"` // When the game was played (0 = unknown)
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AnalyzedGame) Reset() {
+	*x = AnalyzedGame{}
+	mi := &file_proto_analysis_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnalyzedGame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalyzedGame) ProtoMessage() {}
+
+func (x *AnalyzedGame) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalyzedGame.ProtoReflect.Descriptor instead.
+func (*AnalyzedGame) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *AnalyzedGame) GetAnalysis() *GameAnalysis {
+	if x != nil {
+		return x.Analysis
+	}
+	return nil
+}
+
+func (x *AnalyzedGame) GetWhitePlayer() string {
+	if x != nil {
+		return x.WhitePlayer
+	}
+	return ""
+}
+
+func (x *AnalyzedGame) GetBlackPlayer() string {
+	if x != nil {
+		return x.BlackPlayer
+	}
+	return ""
+}
+
+func (x *AnalyzedGame) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *AnalyzedGame) GetEco() string {
+	if x != nil {
+		return x.Eco
+	}
+	return ""
+}
+
+func (x *AnalyzedGame) GetOpeningName() string {
+	if x != nil {
+		return x.OpeningName
+	}
+	return ""
+}
+
+func (x *AnalyzedGame) GetTimeClass() string {
+	if x != nil {
+		return x.TimeClass
+	}
+	return ""
+}
+
+func (x *AnalyzedGame) GetPlayedAtUnixMs() int64 {
+	if x != nil {
+		return x.PlayedAtUnixMs
+	}
+	return 0
+}
+
+// Aggregated report of a player's games
+type PlayerReport struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Player            string                 `protobuf:"bytes,1,opt,name=player,proto3" json:"player,omitempty"`
+	Games             int32                  `protobuf:"varint,2,opt,name=games,proto3" json:"games,omitempty"` // Games the player took part in
+	Wins              int32                  `protobuf:"varint,3,opt,name=wins,proto3" json:"wins,omitempty"`
+	Draws             int32                  `protobuf:"varint,4,opt,name=draws,proto3" json:"draws,omitempty"`
+	Losses            int32                  `protobuf:"varint,5,opt,name=losses,proto3" json:"losses,omitempty"`
+	GamesAsWhite      int32                  `protobuf:"varint,6,opt,name=games_as_white,json=gamesAsWhite,proto3" json:"games_as_white,omitempty"`
+	GamesAsBlack      int32                  `protobuf:"varint,7,opt,name=games_as_black,json=gamesAsBlack,proto3" json:"games_as_black,omitempty"`
+	SkippedGames      int32                  `protobuf:"varint,8,opt,name=skipped_games,json=skippedGames,proto3" json:"skipped_games,omitempty"`           // Games given that the player didn't take part in
+	AverageAccuracy   float32                `protobuf:"fixed32,9,opt,name=average_accuracy,json=averageAccuracy,proto3" json:"average_accuracy,omitempty"` // Mean of per-game accuracy, in percent
+	Acpl              float32                `protobuf:"fixed32,10,opt,name=acpl,proto3" json:"acpl,omitempty"`                                             // Average centipawn loss over all moves, in centipawns
+	TotalMoves        int32                  `protobuf:"varint,11,opt,name=total_moves,json=totalMoves,proto3" json:"total_moves,omitempty"`
+	Blunders          int32                  `protobuf:"varint,12,opt,name=blunders,proto3" json:"blunders,omitempty"`
+	BlunderRate       float32                `protobuf:"fixed32,13,opt,name=blunder_rate,json=blunderRate,proto3" json:"blunder_rate,omitempty"`           // Blunders per 100 moves
+	TimeClasses       []*TimeClassStats      `protobuf:"bytes,14,rep,name=time_classes,json=timeClasses,proto3" json:"time_classes,omitempty"`             // Most played first
+	Openings          []*OpeningRecord       `protobuf:"bytes,15,rep,name=openings,proto3" json:"openings,omitempty"`                                      // Worst score first
+	AccuracyBuckets   []*AccuracyBucket      `protobuf:"bytes,16,rep,name=accuracy_buckets,json=accuracyBuckets,proto3" json:"accuracy_buckets,omitempty"` // Ascending, 10 points wide
+	Phases            []*PhaseStats          `protobuf:"bytes,17,rep,name=phases,proto3" json:"phases,omitempty"`                                          // Opening, middlegame, endgame
+	Trend             []*GameTrendPoint      `protobuf:"bytes,18,rep,name=trend,proto3" json:"trend,omitempty"`                                            // Chronological
+	LongestWinStreak  int32                  `protobuf:"varint,19,opt,name=longest_win_streak,json=longestWinStreak,proto3" json:"longest_win_streak,omitempty"`
+	LongestLossStreak int32                  `protobuf:"varint,20,opt,name=longest_loss_streak,json=longestLossStreak,proto3" json:"longest_loss_streak,omitempty"`
+	CurrentStreak     int32                  `protobuf:"varint,21,opt,name=current_streak,json=currentStreak,proto3" json:"current_streak,omitempty"`    // Positive for wins, negative for losses, 0 after a draw
+	UnknownResults    int32                  `protobuf:"varint,22,opt,name=unknown_results,json=unknownResults,proto3" json:"unknown_results,omitempty"` // Games with result "*" or empty: counted in games, moves and accuracy, not in results, scores or streaks
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *PlayerReport) Reset() {
+	*x = PlayerReport{}
+	mi := &file_proto_analysis_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlayerReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlayerReport) ProtoMessage() {}
+
+func (x *PlayerReport) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlayerReport.ProtoReflect.Descriptor instead.
+func (*PlayerReport) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *PlayerReport) GetPlayer() string {
+	if x != nil {
+		return x.Player
+	}
+	return ""
+}
+
+func (x *PlayerReport) GetGames() int32 {
+	if x != nil {
+		return x.Games
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetWins() int32 {
+	if x != nil {
+		return x.Wins
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetDraws() int32 {
+	if x != nil {
+		return x.Draws
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetLosses() int32 {
+	if x != nil {
+		return x.Losses
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetGamesAsWhite() int32 {
+	if x != nil {
+		return x.GamesAsWhite
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetGamesAsBlack() int32 {
+	if x != nil {
+		return x.GamesAsBlack
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetSkippedGames() int32 {
+	if x != nil {
+		return x.SkippedGames
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetAverageAccuracy() float32 {
+	if x != nil {
+		return x.AverageAccuracy
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetAcpl() float32 {
+	if x != nil {
+		return x.Acpl
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetTotalMoves() int32 {
+	if x != nil {
+		return x.TotalMoves
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetBlunders() int32 {
+	if x != nil {
+		return x.Blunders
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetBlunderRate() float32 {
+	if x != nil {
+		return x.BlunderRate
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetTimeClasses() []*TimeClassStats {
+	if x != nil {
+		return x.TimeClasses
+	}
+	return nil
+}
+
+func (x *PlayerReport) GetOpenings() []*OpeningRecord {
+	if x != nil {
+		return x.Openings
+	}
+	return nil
+}
+
+func (x *PlayerReport) GetAccuracyBuckets() []*AccuracyBucket {
+	if x != nil {
+		return x.AccuracyBuckets
+	}
+	return nil
+}
+
+func (x *PlayerReport) GetPhases() []*PhaseStats {
+	if x != nil {
+		return x.Phases
+	}
+	return nil
+}
+
+func (x *PlayerReport) GetTrend() []*GameTrendPoint {
+	if x != nil {
+		return x.Trend
+	}
+	return nil
+}
+
+func (x *PlayerReport) GetLongestWinStreak() int32 {
+	if x != nil {
+		return x.LongestWinStreak
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetLongestLossStreak() int32 {
+	if x != nil {
+		return x.LongestLossStreak
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetCurrentStreak() int32 {
+	if x != nil {
+		return x.CurrentStreak
+	}
+	return 0
+}
+
+func (x *PlayerReport) GetUnknownResults() int32 {
+	if x != nil {
+		return x.UnknownResults
+	}
+	return 0
+}
+
+// Results in one time class
+type TimeClassStats struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TimeClass       string                 `protobuf:"bytes,1,opt,name=time_class,json=timeClass,proto3" json:"time_class,omitempty"`
+	Games           int32                  `protobuf:"varint,2,opt,name=games,proto3" json:"games,omitempty"`
+	Wins            int32                  `protobuf:"varint,3,opt,name=wins,proto3" json:"wins,omitempty"`
+	Draws           int32                  `protobuf:"varint,4,opt,name=draws,proto3" json:"draws,omitempty"`
+	Losses          int32                  `protobuf:"varint,5,opt,name=losses,proto3" json:"losses,omitempty"`
+	AverageAccuracy float32                `protobuf:"fixed32,6,opt,name=average_accuracy,json=averageAccuracy,proto3" json:"average_accuracy,omitempty"` // Percent
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *TimeClassStats) Reset() {
+	*x = TimeClassStats{}
+	mi := &file_proto_analysis_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimeClassStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimeClassStats) ProtoMessage() {}
+
+func (x *TimeClassStats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimeClassStats.ProtoReflect.Descriptor instead.
+func (*TimeClassStats) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *TimeClassStats) GetTimeClass() string {
+	if x != nil {
+		return x.TimeClass
+	}
+	return ""
+}
+
+func (x *TimeClassStats) GetGames() int32 {
+	if x != nil {
+		return x.Games
+	}
+	return 0
+}
+
+func (x *TimeClassStats) GetWins() int32 {
+	if x != nil {
+		return x.Wins
+	}
+	return 0
+}
+
+func (x *TimeClassStats) GetDraws() int32 {
+	if x != nil {
+		return x.Draws
+	}
+	return 0
+}
+
+func (x *TimeClassStats) GetLosses() int32 {
+	if x != nil {
+		return x.Losses
+	}
+	return 0
+}
+
+func (x *TimeClassStats) GetAverageAccuracy() float32 {
+	if x != nil {
+		return x.AverageAccuracy
+	}
+	return 0
+}
+
+// Results with one opening
+type OpeningRecord struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Eco             string                 `protobuf:"bytes,1,opt,name=eco,proto3" json:"eco,omitempty"`
+	Name            string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Games           int32                  `protobuf:"varint,3,opt,name=games,proto3" json:"games,omitempty"`
+	Wins            int32                  `protobuf:"varint,4,opt,name=wins,proto3" json:"wins,omitempty"`
+	Draws           int32                  `protobuf:"varint,5,opt,name=draws,proto3" json:"draws,omitempty"`
+	Losses          int32                  `protobuf:"varint,6,opt,name=losses,proto3" json:"losses,omitempty"`
+	Score           float32                `protobuf:"fixed32,7,opt,name=score,proto3" json:"score,omitempty"`                                            // Points per game with a known result (0-1)
+	AverageAccuracy float32                `protobuf:"fixed32,8,opt,name=average_accuracy,json=averageAccuracy,proto3" json:"average_accuracy,omitempty"` // Percent
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *OpeningRecord) Reset() {
+	*x = OpeningRecord{}
+	mi := &file_proto_analysis_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OpeningRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpeningRecord) ProtoMessage() {}
+
+func (x *OpeningRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpeningRecord.ProtoReflect.Descriptor instead.
+func (*OpeningRecord) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *OpeningRecord) GetEco() string {
+	if x != nil {
+		return x.Eco
+	}
+	return ""
+}
+
+func (x *OpeningRecord) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *OpeningRecord) GetGames() int32 {
+	if x != nil {
+		return x.Games
+	}
+	return 0
+}
+
+func (x *OpeningRecord) GetWins() int32 {
+	if x != nil {
+		return x.Wins
+	}
+	return 0
+}
+
+func (x *OpeningRecord) GetDraws() int32 {
+	if x != nil {
+		return x.Draws
+	}
+	return 0
+}
+
+func (x *OpeningRecord) GetLosses() int32 {
+	if x != nil {
+		return x.Losses
+	}
+	return 0
+}
+
+func (x *OpeningRecord) GetScore() float32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *OpeningRecord) GetAverageAccuracy() float32 {
+	if x != nil {
+		return x.AverageAccuracy
+	}
+	return 0
+}
+
+// Games with accuracy in [min, max), the last bucket includes 100
+type AccuracyBucket struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Min           int32                  `protobuf:"varint,1,opt,name=min,proto3" json:"min,omitempty"`
+	Max           int32                  `protobuf:"varint,2,opt,name=max,proto3" json:"max,omitempty"`
+	Games         int32                  `protobuf:"varint,3,opt,name=games,proto3" json:"games,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AccuracyBucket) Reset() {
+	*x = AccuracyBucket{}
+	mi := &file_proto_analysis_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AccuracyBucket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccuracyBucket) ProtoMessage() {}
+
+func (x *AccuracyBucket) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccuracyBucket.ProtoReflect.Descriptor instead.
+func (*AccuracyBucket) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *AccuracyBucket) GetMin() int32 {
+	if x != nil {
+		return x.Min
+	}
+	return 0
+}
+
+func (x *AccuracyBucket) GetMax() int32 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *AccuracyBucket) GetGames() int32 {
+	if x != nil {
+		return x.Games
+	}
+	return 0
+}
+
+// Move quality in one phase of the game
+type PhaseStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Phase         string                 `protobuf:"bytes,1,opt,name=phase,proto3" json:"phase,omitempty"` // "opening", "middlegame" or "endgame"
+	Moves         int32                  `protobuf:"varint,2,opt,name=moves,proto3" json:"moves,omitempty"`
+	Blunders      int32                  `protobuf:"varint,3,opt,name=blunders,proto3" json:"blunders,omitempty"`
+	Mistakes      int32                  `protobuf:"varint,4,opt,name=mistakes,proto3" json:"mistakes,omitempty"`
+	Inaccuracies  int32                  `protobuf:"varint,5,opt,name=inaccuracies,proto3" json:"inaccuracies,omitempty"`
+	BlunderRate   float32                `protobuf:"fixed32,6,opt,name=blunder_rate,json=blunderRate,proto3" json:"blunder_rate,omitempty"` // Blunders per 100 moves
+	Acpl          float32                `protobuf:"fixed32,7,opt,name=acpl,proto3" json:"acpl,omitempty"`                                  // Centipawns
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PhaseStats) Reset() {
+	*x = PhaseStats{}
+	mi := &file_proto_analysis_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PhaseStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PhaseStats) ProtoMessage() {}
+
+func (x *PhaseStats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PhaseStats.ProtoReflect.Descriptor instead.
+func (*PhaseStats) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *PhaseStats) GetPhase() string {
+	if x != nil {
+		return x.Phase
+	}
+	return ""
+}
+
+func (x *PhaseStats) GetMoves() int32 {
+	if x != nil {
+		return x.Moves
+	}
+	return 0
+}
+
+func (x *PhaseStats) GetBlunders() int32 {
+	if x != nil {
+		return x.Blunders
+	}
+	return 0
+}
+
+func (x *PhaseStats) GetMistakes() int32 {
+	if x != nil {
+		return x.Mistakes
+	}
+	return 0
+}
+
+func (x *PhaseStats) GetInaccuracies() int32 {
+	if x != nil {
+		return x.Inaccuracies
+	}
+	return 0
+}
+
+func (x *PhaseStats) GetBlunderRate() float32 {
+	if x != nil {
+		return x.BlunderRate
+	}
+	return 0
+}
+
+func (x *PhaseStats) GetAcpl() float32 {
+	if x != nil {
+		return x.Acpl
+	}
+	return 0
+}
+
+// One game of a report's trend
+type GameTrendPoint struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	GameId         string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	PlayedAtUnixMs int64                  `protobuf:"varint,2,opt,name=played_at_unix_ms,json=playedAtUnixMs,proto3" json:"played_at_unix_ms,omitempty"`
+	Accuracy       float32                `protobuf:"fixed32,3,opt,name=accuracy,proto3" json:"accuracy,omitempty"` // Percent
+	Blunders       int32                  `protobuf:"varint,4,opt,name=blunders,proto3" json:"blunders,omitempty"`
+	BlunderRate    float32                `protobuf:"fixed32,5,opt,name=blunder_rate,json=blunderRate,proto3" json:"blunder_rate,omitempty"` // Blunders per 100 moves
+	Result         string                 `protobuf:"bytes,6,opt,name=result,proto3" json:"result,omitempty"`                                // "win", "loss" or "draw" for the player, empty when unknown
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GameTrendPoint) Reset() {
+	*x = GameTrendPoint{}
+	mi := &file_proto_analysis_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameTrendPoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameTrendPoint) ProtoMessage() {}
+
+func (x *GameTrendPoint) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameTrendPoint.ProtoReflect.Descriptor instead.
+func (*GameTrendPoint) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *GameTrendPoint) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *GameTrendPoint) GetPlayedAtUnixMs() int64 {
+	if x != nil {
+		return x.PlayedAtUnixMs
+	}
+	return 0
+}
+
+func (x *GameTrendPoint) GetAccuracy() float32 {
+	if x != nil {
+		return x.Accuracy
+	}
+	return 0
+}
+
+func (x *GameTrendPoint) GetBlunders() int32 {
+	if x != nil {
+		return x.Blunders
+	}
+	return 0
+}
+
+func (x *GameTrendPoint) GetBlunderRate() float32 {
+	if x != nil {
+		return x.BlunderRate
+	}
+	return 0
+}
+
+func (x *GameTrendPoint) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+// Request to aggregate a player's results by opening
+type AggregateOpeningsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Player        string                 `protobuf:"bytes,1,opt,name=player,proto3" json:"player,omitempty"`                         // Player name, matched case-insensitively
+	Games         []*AnalyzedGame        `protobuf:"bytes,2,rep,name=games,proto3" json:"games,omitempty"`                           // Games with eco set; those without are grouped as "unknown"
+	MinGames      int32                  `protobuf:"varint,3,opt,name=min_games,json=minGames,proto3" json:"min_games,omitempty"`    // Leave out openings with fewer games (0 = server default)
+	LastGames     int32                  `protobuf:"varint,4,opt,name=last_games,json=lastGames,proto3" json:"last_games,omitempty"` // Only the player's most recent games (0 = all)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AggregateOpeningsRequest) Reset() {
+	*x = AggregateOpeningsRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregateOpeningsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregateOpeningsRequest) ProtoMessage() {}
+
+func (x *AggregateOpeningsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregateOpeningsRequest.ProtoReflect.Descriptor instead.
+func (*AggregateOpeningsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *AggregateOpeningsRequest) GetPlayer() string {
+	if x != nil {
+		return x.Player
+	}
+	return ""
+}
+
+func (x *AggregateOpeningsRequest) GetGames() []*AnalyzedGame {
+	if x != nil {
+		return x.Games
+	}
+	return nil
+}
+
+func (x *AggregateOpeningsRequest) GetMinGames() int32 {
+	if x != nil {
+		return x.MinGames
+	}
+	return 0
+}
+
+func (x *AggregateOpeningsRequest) GetLastGames() int32 {
+	if x != nil {
+		return x.LastGames
+	}
+	return 0
+}
+
+// A player's results by opening family and color
+type OpeningsReport struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Player         string                 `protobuf:"bytes,1,opt,name=player,proto3" json:"player,omitempty"`
+	Games          int32                  `protobuf:"varint,2,opt,name=games,proto3" json:"games,omitempty"`                                         // Games of the player considered
+	MinGames       int32                  `protobuf:"varint,3,opt,name=min_games,json=minGames,proto3" json:"min_games,omitempty"`                   // Threshold applied
+	HiddenOpenings int32                  `protobuf:"varint,4,opt,name=hidden_openings,json=hiddenOpenings,proto3" json:"hidden_openings,omitempty"` // Openings left out for having fewer games
+	Openings       []*OpeningStats        `protobuf:"bytes,5,rep,name=openings,proto3" json:"openings,omitempty"`                                    // Most played first
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *OpeningsReport) Reset() {
+	*x = OpeningsReport{}
+	mi := &file_proto_analysis_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OpeningsReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpeningsReport) ProtoMessage() {}
+
+func (x *OpeningsReport) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpeningsReport.ProtoReflect.Descriptor instead.
+func (*OpeningsReport) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *OpeningsReport) GetPlayer() string {
+	if x != nil {
+		return x.Player
+	}
+	return ""
+}
+
+func (x *OpeningsReport) GetGames() int32 {
+	if x != nil {
+		return x.Games
+	}
+	return 0
+}
+
+func (x *OpeningsReport) GetMinGames() int32 {
+	if x != nil {
+		return x.MinGames
+	}
+	return 0
+}
+
+func (x *OpeningsReport) GetHiddenOpenings() int32 {
+	if x != nil {
+		return x.HiddenOpenings
+	}
+	return 0
+}
+
+func (x *OpeningsReport) GetOpenings() []*OpeningStats {
+	if x != nil {
+		return x.Openings
+	}
+	return nil
+}
+
+// A player's results in one ECO family with one color
+type OpeningStats struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Family            string                 `protobuf:"bytes,1,opt,name=family,proto3" json:"family,omitempty"` // e.g. "B10-B19", or "unknown"
+	Name              string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`     // Most common opening name, without the variation
+	Color             string                 `protobuf:"bytes,3,opt,name=color,proto3" json:"color,omitempty"`   // "white" or "black"
+	Games             int32                  `protobuf:"varint,4,opt,name=games,proto3" json:"games,omitempty"`
+	Wins              int32                  `protobuf:"varint,5,opt,name=wins,proto3" json:"wins,omitempty"`
+	Draws             int32                  `protobuf:"varint,6,opt,name=draws,proto3" json:"draws,omitempty"`
+	Losses            int32                  `protobuf:"varint,7,opt,name=losses,proto3" json:"losses,omitempty"`
+	ScorePercent      float32                `protobuf:"fixed32,8,opt,name=score_percent,json=scorePercent,proto3" json:"score_percent,omitempty"`          // Points per game with a known result, 0-100
+	AverageAccuracy   float32                `protobuf:"fixed32,9,opt,name=average_accuracy,json=averageAccuracy,proto3" json:"average_accuracy,omitempty"` // Percent
+	OutOfBookAcpl     float32                `protobuf:"fixed32,10,opt,name=out_of_book_acpl,json=outOfBookAcpl,proto3" json:"out_of_book_acpl,omitempty"`  // Average centipawn loss after the book moves, in centipawns
+	TopDeviation      string                 `protobuf:"bytes,11,opt,name=top_deviation,json=topDeviation,proto3" json:"top_deviation,omitempty"`           // Player's most common move leaving book, e.g. "5... Nf6"
+	TopDeviationGames int32                  `protobuf:"varint,12,opt,name=top_deviation_games,json=topDeviationGames,proto3" json:"top_deviation_games,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *OpeningStats) Reset() {
+	*x = OpeningStats{}
+	mi := &file_proto_analysis_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OpeningStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpeningStats) ProtoMessage() {}
+
+func (x *OpeningStats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpeningStats.ProtoReflect.Descriptor instead.
+func (*OpeningStats) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *OpeningStats) GetFamily() string {
+	if x != nil {
+		return x.Family
+	}
+	return ""
+}
+
+func (x *OpeningStats) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *OpeningStats) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *OpeningStats) GetGames() int32 {
+	if x != nil {
+		return x.Games
+	}
+	return 0
+}
+
+func (x *OpeningStats) GetWins() int32 {
+	if x != nil {
+		return x.Wins
+	}
+	return 0
+}
+
+func (x *OpeningStats) GetDraws() int32 {
+	if x != nil {
+		return x.Draws
+	}
+	return 0
+}
+
+func (x *OpeningStats) GetLosses() int32 {
+	if x != nil {
+		return x.Losses
+	}
+	return 0
+}
+
+func (x *OpeningStats) GetScorePercent() float32 {
+	if x != nil {
+		return x.ScorePercent
+	}
+	return 0
+}
+
+func (x *OpeningStats) GetAverageAccuracy() float32 {
+	if x != nil {
+		return x.AverageAccuracy
+	}
+	return 0
+}
+
+func (x *OpeningStats) GetOutOfBookAcpl() float32 {
+	if x != nil {
+		return x.OutOfBookAcpl
+	}
+	return 0
+}
+
+func (x *OpeningStats) GetTopDeviation() string {
+	if x != nil {
+		return x.TopDeviation
+	}
+	return ""
+}
+
+func (x *OpeningStats) GetTopDeviationGames() int32 {
+	if x != nil {
+		return x.TopDeviationGames
+	}
+	return 0
+}
+
+// The caller is identified by its x-api-key metadata, else x-user-id
+type GetQuotaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetQuotaRequest) Reset() {
+	*x = GetQuotaRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetQuotaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQuotaRequest) ProtoMessage() {}
+
+func (x *GetQuotaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQuotaRequest.ProtoReflect.Descriptor instead.
+func (*GetQuotaRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{48}
+}
+
+// A caller's engine time in the current UTC day. Only searches count:
+// positions answered from the cache or the cloud are free.
+type QuotaUsage struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Principal      string                 `protobuf:"bytes,1,opt,name=principal,proto3" json:"principal,omitempty"` // "key:" and a hash of the API key, "user:" and the user ID, or "anonymous"
+	UsedMs         int64                  `protobuf:"varint,2,opt,name=used_ms,json=usedMs,proto3" json:"used_ms,omitempty"`
+	LimitMs        int64                  `protobuf:"varint,3,opt,name=limit_ms,json=limitMs,proto3" json:"limit_ms,omitempty"`
+	RemainingMs    int64                  `protobuf:"varint,4,opt,name=remaining_ms,json=remainingMs,proto3" json:"remaining_ms,omitempty"`              // Never below 0, though used_ms can pass limit_ms
+	ResetsAtUnixMs int64                  `protobuf:"varint,5,opt,name=resets_at_unix_ms,json=resetsAtUnixMs,proto3" json:"resets_at_unix_ms,omitempty"` // Next UTC midnight
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *QuotaUsage) Reset() {
+	*x = QuotaUsage{}
+	mi := &file_proto_analysis_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuotaUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaUsage) ProtoMessage() {}
+
+func (x *QuotaUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaUsage.ProtoReflect.Descriptor instead.
+func (*QuotaUsage) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *QuotaUsage) GetPrincipal() string {
+	if x != nil {
+		return x.Principal
+	}
+	return ""
+}
+
+func (x *QuotaUsage) GetUsedMs() int64 {
+	if x != nil {
+		return x.UsedMs
+	}
+	return 0
+}
+
+func (x *QuotaUsage) GetLimitMs() int64 {
+	if x != nil {
+		return x.LimitMs
+	}
+	return 0
+}
+
+func (x *QuotaUsage) GetRemainingMs() int64 {
+	if x != nil {
+		return x.RemainingMs
+	}
+	return 0
+}
+
+func (x *QuotaUsage) GetResetsAtUnixMs() int64 {
+	if x != nil {
+		return x.ResetsAtUnixMs
+	}
+	return 0
+}
+
+// Request to change the log level at runtime
+type SetLogLevelRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Level              string                 `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"`                                                        // debug, info, warn or error
+	RevertAfterSeconds int32                  `protobuf:"varint,2,opt,name=revert_after_seconds,json=revertAfterSeconds,proto3" json:"revert_after_seconds,omitempty"` // Revert to the configured level after this long (0 = server default, -1 = never)
+	UciDebug           *bool                  `protobuf:"varint,3,opt,name=uci_debug,json=uciDebug,proto3,oneof" json:"uci_debug,omitempty"`                           // Also log every engine UCI line (unset = unchanged)
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *SetLogLevelRequest) Reset() {
+	*x = SetLogLevelRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLogLevelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLogLevelRequest) ProtoMessage() {}
+
+func (x *SetLogLevelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLogLevelRequest.ProtoReflect.Descriptor instead.
+func (*SetLogLevelRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *SetLogLevelRequest) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *SetLogLevelRequest) GetRevertAfterSeconds() int32 {
+	if x != nil {
+		return x.RevertAfterSeconds
+	}
+	return 0
+}
+
+func (x *SetLogLevelRequest) GetUciDebug() bool {
+	if x != nil && x.UciDebug != nil {
+		return *x.UciDebug
+	}
+	return false
+}
+
+// Log level after a SetLogLevel call
+type SetLogLevelResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PreviousLevel  string                 `protobuf:"bytes,1,opt,name=previous_level,json=previousLevel,proto3" json:"previous_level,omitempty"`         // Level before the change
+	Level          string                 `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`                                              // Level now in effect
+	RevertAtUnixMs int64                  `protobuf:"varint,3,opt,name=revert_at_unix_ms,json=revertAtUnixMs,proto3" json:"revert_at_unix_ms,omitempty"` // When the configured level is restored (0 = no revert pending)
+	UciDebug       bool                   `protobuf:"varint,4,opt,name=uci_debug,json=uciDebug,proto3" json:"uci_debug,omitempty"`                       // Whether engine UCI lines are logged
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SetLogLevelResponse) Reset() {
+	*x = SetLogLevelResponse{}
+	mi := &file_proto_analysis_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLogLevelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLogLevelResponse) ProtoMessage() {}
+
+func (x *SetLogLevelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLogLevelResponse.ProtoReflect.Descriptor instead.
+func (*SetLogLevelResponse) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *SetLogLevelResponse) GetPreviousLevel() string {
+	if x != nil {
+		return x.PreviousLevel
+	}
+	return ""
+}
+
+func (x *SetLogLevelResponse) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *SetLogLevelResponse) GetRevertAtUnixMs() int64 {
+	if x != nil {
+		return x.RevertAtUnixMs
+	}
+	return 0
+}
+
+func (x *SetLogLevelResponse) GetUciDebug() bool {
+	if x != nil {
+		return x.UciDebug
+	}
+	return false
+}
+
+// Precomputed evaluations to load, as CSV rows of
+// fen,depth,cp,mate,best_move,source with White's point of view scores
+type ImportEvaluationsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"` // CSV file in the service's IMPORT_EVALS_DIR, relative to it or absolute
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"` // CSV contents, instead of path (up to the 10MB message limit)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportEvaluationsRequest) Reset() {
+	*x = ImportEvaluationsRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportEvaluationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportEvaluationsRequest) ProtoMessage() {}
+
+func (x *ImportEvaluationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportEvaluationsRequest.ProtoReflect.Descriptor instead.
+func (*ImportEvaluationsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *ImportEvaluationsRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ImportEvaluationsRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// Outcome of an evaluation import
+type ImportEvaluationsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Imported      int32                  `protobuf:"varint,1,opt,name=imported,proto3" json:"imported,omitempty"`                                                                                                            // Rows added to the cache
+	Skipped       int32                  `protobuf:"varint,2,opt,name=skipped,proto3" json:"skipped,omitempty"`                                                                                                              // Rows the cache held at least as deep
+	Invalid       int32                  `protobuf:"varint,3,opt,name=invalid,proto3" json:"invalid,omitempty"`                                                                                                              // Rows that couldn't be parsed or have an illegal best move
+	Sources       map[string]int32       `protobuf:"bytes,4,rep,name=sources,proto3" json:"sources,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`                                    // Imported rows by their source column
+	Errors        []string               `protobuf:"bytes,5,rep,name=errors,proto3" json:"errors,omitempty"`                                                                                                                 // The first invalid rows, by line number
+	CacheBySource map[string]int32       `protobuf:"bytes,6,rep,name=cache_by_source,json=cacheBySource,proto3" json:"cache_by_source,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // Cached positions by source after the import
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportEvaluationsResponse) Reset() {
+	*x = ImportEvaluationsResponse{}
+	mi := &file_proto_analysis_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportEvaluationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportEvaluationsResponse) ProtoMessage() {}
+
+func (x *ImportEvaluationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportEvaluationsResponse.ProtoReflect.Descriptor instead.
+func (*ImportEvaluationsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *ImportEvaluationsResponse) GetImported() int32 {
+	if x != nil {
+		return x.Imported
+	}
+	return 0
+}
+
+func (x *ImportEvaluationsResponse) GetSkipped() int32 {
+	if x != nil {
+		return x.Skipped
+	}
+	return 0
+}
+
+func (x *ImportEvaluationsResponse) GetInvalid() int32 {
+	if x != nil {
+		return x.Invalid
+	}
+	return 0
+}
+
+func (x *ImportEvaluationsResponse) GetSources() map[string]int32 {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+func (x *ImportEvaluationsResponse) GetErrors() []string {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *ImportEvaluationsResponse) GetCacheBySource() map[string]int32 {
+	if x != nil {
+		return x.CacheBySource
+	}
+	return nil
+}
+
+type GetAnalysisStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAnalysisStatsRequest) Reset() {
+	*x = GetAnalysisStatsRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAnalysisStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAnalysisStatsRequest) ProtoMessage() {}
+
+func (x *GetAnalysisStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAnalysisStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetAnalysisStatsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{54}
+}
+
+type AnalysisStats struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	DepthTimings      []*DepthTiming         `protobuf:"bytes,1,rep,name=depth_timings,json=depthTimings,proto3" json:"depth_timings,omitempty"` // Depth ranges searched so far, shallowest first
+	Degradation       *Degradation           `protobuf:"bytes,2,opt,name=degradation,proto3" json:"degradation,omitempty"`
+	HistogramBoundsMs []int64                `protobuf:"varint,3,rep,packed,name=histogram_bounds_ms,json=histogramBoundsMs,proto3" json:"histogram_bounds_ms,omitempty"` // Upper bounds of the bins of DepthTiming.histogram; its last bin holds the longer searches
+	PoolUsage         *EnginePoolUsage       `protobuf:"bytes,4,opt,name=pool_usage,json=poolUsage,proto3" json:"pool_usage,omitempty"`
+	EvalDrift         *EvalDrift             `protobuf:"bytes,5,opt,name=eval_drift,json=evalDrift,proto3" json:"eval_drift,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *AnalysisStats) Reset() {
+	*x = AnalysisStats{}
+	mi := &file_proto_analysis_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnalysisStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalysisStats) ProtoMessage() {}
+
+func (x *AnalysisStats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalysisStats.ProtoReflect.Descriptor instead.
+func (*AnalysisStats) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *AnalysisStats) GetDepthTimings() []*DepthTiming {
+	if x != nil {
+		return x.DepthTimings
+	}
+	return nil
+}
+
+func (x *AnalysisStats) GetDegradation() *Degradation {
+	if x != nil {
+		return x.Degradation
+	}
+	return nil
+}
+
+func (x *AnalysisStats) GetHistogramBoundsMs() []int64 {
+	if x != nil {
+		return x.HistogramBoundsMs
+	}
+	return nil
+}
+
+func (x *AnalysisStats) GetPoolUsage() *EnginePoolUsage {
+	if x != nil {
+		return x.PoolUsage
+	}
+	return nil
+}
+
+func (x *AnalysisStats) GetEvalDrift() *EvalDrift {
+	if x != nil {
+		return x.EvalDrift
+	}
+	return nil
+}
+
+// Time the primary pool's engines were lent out, by the tag they were lent
+// for: the RPC, "queue" for queued jobs, "ponder_prefetch",
+// "stale_refresh" and "drift_sample" for the analyzer's background
+// searches or "untagged"
+type EnginePoolUsage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BusyMs        int64                  `protobuf:"varint,1,opt,name=busy_ms,json=busyMs,proto3" json:"busy_ms,omitempty"` // Loans in progress included; the tags' busy_ms add up to it
+	Tags          []*EngineTagUsage      `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`                    // Busiest first
+	Engines       []*EngineUsage         `protobuf:"bytes,3,rep,name=engines,proto3" json:"engines,omitempty"`              // The pool's engines, in the order they were first lent
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnginePoolUsage) Reset() {
+	*x = EnginePoolUsage{}
+	mi := &file_proto_analysis_proto_msgTypes[56]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnginePoolUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnginePoolUsage) ProtoMessage() {}
+
+func (x *EnginePoolUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[56]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnginePoolUsage.ProtoReflect.Descriptor instead.
+func (*EnginePoolUsage) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{56}
+}
+
+func (x *EnginePoolUsage) GetBusyMs() int64 {
+	if x != nil {
+		return x.BusyMs
+	}
+	return 0
+}
+
+func (x *EnginePoolUsage) GetTags() []*EngineTagUsage {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *EnginePoolUsage) GetEngines() []*EngineUsage {
+	if x != nil {
+		return x.Engines
+	}
+	return nil
+}
+
+type EngineTagUsage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tag           string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	BusyMs        int64                  `protobuf:"varint,2,opt,name=busy_ms,json=busyMs,proto3" json:"busy_ms,omitempty"`
+	Share         float64                `protobuf:"fixed64,3,opt,name=share,proto3" json:"share,omitempty"`             // Percentage of EnginePoolUsage.busy_ms
+	Loans         int64                  `protobuf:"varint,4,opt,name=loans,proto3" json:"loans,omitempty"`              // Times an engine was lent for it
+	InUse         int32                  `protobuf:"varint,5,opt,name=in_use,json=inUse,proto3" json:"in_use,omitempty"` // Engines lent for it now
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EngineTagUsage) Reset() {
+	*x = EngineTagUsage{}
+	mi := &file_proto_analysis_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EngineTagUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EngineTagUsage) ProtoMessage() {}
+
+func (x *EngineTagUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EngineTagUsage.ProtoReflect.Descriptor instead.
+func (*EngineTagUsage) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *EngineTagUsage) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *EngineTagUsage) GetBusyMs() int64 {
+	if x != nil {
+		return x.BusyMs
+	}
+	return 0
+}
+
+func (x *EngineTagUsage) GetShare() float64 {
+	if x != nil {
+		return x.Share
 	}
-	return ""
+	return 0
 }
 
-func (x *GameAnalysis) GetMoves() []*MoveAnalysis {
+func (x *EngineTagUsage) GetLoans() int64 {
 	if x != nil {
-		return x.Moves
+		return x.Loans
 	}
-	return nil
+	return 0
 }
 
-func (x *GameAnalysis) GetWhiteMetrics() *GameMetrics {
+func (x *EngineTagUsage) GetInUse() int32 {
 	if x != nil {
-		return x.WhiteMetrics
+		return x.InUse
 	}
-	return nil
+	return 0
 }
 
-func (x *GameAnalysis) GetBlackMetrics() *GameMetrics {
+// Busy time of one engine; an engine replaced after a failure goes, its
+// time stays with its tags
+type EngineUsage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	BusyMs        map[string]int64       `protobuf:"bytes,2,rep,name=busy_ms,json=busyMs,proto3" json:"busy_ms,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // By tag
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EngineUsage) Reset() {
+	*x = EngineUsage{}
+	mi := &file_proto_analysis_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EngineUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EngineUsage) ProtoMessage() {}
+
+func (x *EngineUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[58]
 	if x != nil {
-		return x.BlackMetrics
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
 }
 
-func (x *GameAnalysis) GetTotalTimeMs() int64 {
+// Deprecated: Use EngineUsage.ProtoReflect.Descriptor instead.
+func (*EngineUsage) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{58}
+}
+
+func (x *EngineUsage) GetId() int32 {
 	if x != nil {
-		return x.TotalTimeMs
+		return x.Id
 	}
 	return 0
 }
 
-func (x *GameAnalysis) GetEngineVersion() string {
+func (x *EngineUsage) GetBusyMs() map[string]int64 {
 	if x != nil {
-		return x.EngineVersion
+		return x.BusyMs
 	}
-	return ""
+	return nil
 }
 
-// Analysis progress during game analysis
-type GameAnalysisProgress struct {
+// State of the controller that degrades game analyses while the engine pool
+// is starved
+// How far fresh searches of cached positions are from the cached
+// evaluations, since the cache was last flushed for drifting
+type EvalDrift struct {
 	state           protoimpl.MessageState `protogen:"open.v1"`
-	GameId          string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
-	CurrentMove     int32                  `protobuf:"varint,2,opt,name=current_move,json=currentMove,proto3" json:"current_move,omitempty"`              // Current move being analyzed (1-indexed)
-	TotalMoves      int32                  `protobuf:"varint,3,opt,name=total_moves,json=totalMoves,proto3" json:"total_moves,omitempty"`                 // Total moves in the game
-	ProgressPercent float32                `protobuf:"fixed32,4,opt,name=progress_percent,json=progressPercent,proto3" json:"progress_percent,omitempty"` // Progress percentage (0-100)
-	MoveAnalysis    *MoveAnalysis          `protobuf:"bytes,5,opt,name=move_analysis,json=moveAnalysis,proto3" json:"move_analysis,omitempty"`            // Analysis of current move (if completed)
-	Status          string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`                                            // "analyzing", "completed", "error"
-	ErrorMessage    string                 `protobuf:"bytes,7,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`            // Error message if status is "error"
+	Enabled         bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	P50Cp           int32                  `protobuf:"varint,2,opt,name=p50_cp,json=p50Cp,proto3" json:"p50_cp,omitempty"` // 0 until there are enough samples
+	P95Cp           int32                  `protobuf:"varint,3,opt,name=p95_cp,json=p95Cp,proto3" json:"p95_cp,omitempty"`
+	Samples         int32                  `protobuf:"varint,4,opt,name=samples,proto3" json:"samples,omitempty"`                      // Differences the percentiles are taken over
+	Sampled         int64                  `protobuf:"varint,5,opt,name=sampled,proto3" json:"sampled,omitempty"`                      // Cached positions searched again
+	Failed          int64                  `protobuf:"varint,6,opt,name=failed,proto3" json:"failed,omitempty"`                        // Searches that failed or weren't plausible
+	BackedOff       int64                  `protobuf:"varint,7,opt,name=backed_off,json=backedOff,proto3" json:"backed_off,omitempty"` // Rounds cut short as the pool was busy
+	MaxP95Cp        int32                  `protobuf:"varint,8,opt,name=max_p95_cp,json=maxP95Cp,proto3" json:"max_p95_cp,omitempty"`  // Ceiling above which the cache is flushed, 0 for none
+	Flushes         int64                  `protobuf:"varint,9,opt,name=flushes,proto3" json:"flushes,omitempty"`
+	FlushedEntries  int64                  `protobuf:"varint,10,opt,name=flushed_entries,json=flushedEntries,proto3" json:"flushed_entries,omitempty"`
+	LastFlushUnixMs int64                  `protobuf:"varint,11,opt,name=last_flush_unix_ms,json=lastFlushUnixMs,proto3" json:"last_flush_unix_ms,omitempty"` // 0 if the cache was never flushed
 	unknownFields   protoimpl.UnknownFields
 	sizeCache       protoimpl.SizeCache
 }
 
-func (x *GameAnalysisProgress) Reset() {
-	*x = GameAnalysisProgress{}
-	mi := &file_proto_analysis_proto_msgTypes[5]
+func (x *EvalDrift) Reset() {
+	*x = EvalDrift{}
+	mi := &file_proto_analysis_proto_msgTypes[59]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GameAnalysisProgress) String() string {
+func (x *EvalDrift) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GameAnalysisProgress) ProtoMessage() {}
+func (*EvalDrift) ProtoMessage() {}
 
-func (x *GameAnalysisProgress) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_analysis_proto_msgTypes[5]
+func (x *EvalDrift) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[59]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -560,97 +6360,117 @@ func (x *GameAnalysisProgress) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GameAnalysisProgress.ProtoReflect.Descriptor instead.
-func (*GameAnalysisProgress) Descriptor() ([]byte, []int) {
-	return file_proto_analysis_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use EvalDrift.ProtoReflect.Descriptor instead.
+func (*EvalDrift) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{59}
 }
 
-func (x *GameAnalysisProgress) GetGameId() string {
+func (x *EvalDrift) GetEnabled() bool {
 	if x != nil {
-		return x.GameId
+		return x.Enabled
 	}
-	return ""
+	return false
 }
 
-func (x *GameAnalysisProgress) GetCurrentMove() int32 {
+func (x *EvalDrift) GetP50Cp() int32 {
 	if x != nil {
-		return x.CurrentMove
+		return x.P50Cp
 	}
 	return 0
 }
 
-func (x *GameAnalysisProgress) GetTotalMoves() int32 {
+func (x *EvalDrift) GetP95Cp() int32 {
 	if x != nil {
-		return x.TotalMoves
+		return x.P95Cp
 	}
 	return 0
 }
 
-func (x *GameAnalysisProgress) GetProgressPercent() float32 {
+func (x *EvalDrift) GetSamples() int32 {
 	if x != nil {
-		return x.ProgressPercent
+		return x.Samples
 	}
 	return 0
 }
 
-func (x *GameAnalysisProgress) GetMoveAnalysis() *MoveAnalysis {
+func (x *EvalDrift) GetSampled() int64 {
 	if x != nil {
-		return x.MoveAnalysis
+		return x.Sampled
 	}
-	return nil
+	return 0
 }
 
-func (x *GameAnalysisProgress) GetStatus() string {
+func (x *EvalDrift) GetFailed() int64 {
 	if x != nil {
-		return x.Status
+		return x.Failed
 	}
-	return ""
+	return 0
 }
 
-func (x *GameAnalysisProgress) GetErrorMessage() string {
+func (x *EvalDrift) GetBackedOff() int64 {
 	if x != nil {
-		return x.ErrorMessage
+		return x.BackedOff
 	}
-	return ""
+	return 0
 }
 
-// Analysis for a single move in a game
-type MoveAnalysis struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	MoveNumber     int32                  `protobuf:"varint,1,opt,name=move_number,json=moveNumber,proto3" json:"move_number,omitempty"`                         // Move number (1-indexed)
-	Ply            int32                  `protobuf:"varint,2,opt,name=ply,proto3" json:"ply,omitempty"`                                                         // Ply (half-move, 0-indexed)
-	Color          string                 `protobuf:"bytes,3,opt,name=color,proto3" json:"color,omitempty"`                                                      // "white" or "black"
-	PlayedMove     string                 `protobuf:"bytes,4,opt,name=played_move,json=playedMove,proto3" json:"played_move,omitempty"`                          // Move played in SAN format
-	PlayedMoveUci  string                 `protobuf:"bytes,5,opt,name=played_move_uci,json=playedMoveUci,proto3" json:"played_move_uci,omitempty"`               // Move played in UCI format
-	BestMove       string                 `protobuf:"bytes,6,opt,name=best_move,json=bestMove,proto3" json:"best_move,omitempty"`                                // Best move in SAN format
-	BestMoveUci    string                 `protobuf:"bytes,7,opt,name=best_move_uci,json=bestMoveUci,proto3" json:"best_move_uci,omitempty"`                     // Best move in UCI format
-	FenBefore      string                 `protobuf:"bytes,8,opt,name=fen_before,json=fenBefore,proto3" json:"fen_before,omitempty"`                             // FEN before the move
-	FenAfter       string                 `protobuf:"bytes,9,opt,name=fen_after,json=fenAfter,proto3" json:"fen_after,omitempty"`                                // FEN after the move
-	EvalBefore     *Evaluation            `protobuf:"bytes,10,opt,name=eval_before,json=evalBefore,proto3" json:"eval_before,omitempty"`                         // Evaluation before the move
-	EvalAfter      *Evaluation            `protobuf:"bytes,11,opt,name=eval_after,json=evalAfter,proto3" json:"eval_after,omitempty"`                            // Evaluation after the move
-	CentipawnLoss  int32                  `protobuf:"varint,12,opt,name=centipawn_loss,json=centipawnLoss,proto3" json:"centipawn_loss,omitempty"`               // Centipawn loss for this move
-	Classification MoveClassification     `protobuf:"varint,13,opt,name=classification,proto3,enum=analysis.MoveClassification" json:"classification,omitempty"` // Move classification
-	Pv             []string               `protobuf:"bytes,14,rep,name=pv,proto3" json:"pv,omitempty"`                                                           // Principal variation from this position
-	Depth          int32                  `protobuf:"varint,15,opt,name=depth,proto3" json:"depth,omitempty"`                                                    // Depth reached
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+func (x *EvalDrift) GetMaxP95Cp() int32 {
+	if x != nil {
+		return x.MaxP95Cp
+	}
+	return 0
 }
 
-func (x *MoveAnalysis) Reset() {
-	*x = MoveAnalysis{}
-	mi := &file_proto_analysis_proto_msgTypes[6]
+func (x *EvalDrift) GetFlushes() int64 {
+	if x != nil {
+		return x.Flushes
+	}
+	return 0
+}
+
+func (x *EvalDrift) GetFlushedEntries() int64 {
+	if x != nil {
+		return x.FlushedEntries
+	}
+	return 0
+}
+
+func (x *EvalDrift) GetLastFlushUnixMs() int64 {
+	if x != nil {
+		return x.LastFlushUnixMs
+	}
+	return 0
+}
+
+type Degradation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Active        bool                   `protobuf:"varint,2,opt,name=active,proto3" json:"active,omitempty"`                                        // New game analyses are degraded
+	SinceUnixMs   int64                  `protobuf:"varint,3,opt,name=since_unix_ms,json=sinceUnixMs,proto3" json:"since_unix_ms,omitempty"`         // When active last changed, 0 if it never has
+	PoolWaitP95Ms int64                  `protobuf:"varint,4,opt,name=pool_wait_p95_ms,json=poolWaitP95Ms,proto3" json:"pool_wait_p95_ms,omitempty"` // Over the window, 0 until it has enough waits
+	Samples       int32                  `protobuf:"varint,5,opt,name=samples,proto3" json:"samples,omitempty"`                                      // Engine waits in the window
+	Depth         int32                  `protobuf:"varint,6,opt,name=depth,proto3" json:"depth,omitempty"`                                          // Depth cap while active
+	Activations   int64                  `protobuf:"varint,7,opt,name=activations,proto3" json:"activations,omitempty"`                              // Times degradation started
+	DegradedGames int64                  `protobuf:"varint,8,opt,name=degraded_games,json=degradedGames,proto3" json:"degraded_games,omitempty"`     // Game analyses degraded
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Degradation) Reset() {
+	*x = Degradation{}
+	mi := &file_proto_analysis_proto_msgTypes[60]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MoveAnalysis) String() string {
+func (x *Degradation) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MoveAnalysis) ProtoMessage() {}
+func (*Degradation) ProtoMessage() {}
 
-func (x *MoveAnalysis) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_analysis_proto_msgTypes[6]
+func (x *Degradation) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[60]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -661,150 +6481,259 @@ func (x *MoveAnalysis) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MoveAnalysis.ProtoReflect.Descriptor instead.
-func (*MoveAnalysis) Descriptor() ([]byte, []int) {
-	return file_proto_analysis_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use Degradation.ProtoReflect.Descriptor instead.
+func (*Degradation) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{60}
 }
 
-func (x *MoveAnalysis) GetMoveNumber() int32 {
+func (x *Degradation) GetEnabled() bool {
 	if x != nil {
-		return x.MoveNumber
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *Degradation) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+func (x *Degradation) GetSinceUnixMs() int64 {
+	if x != nil {
+		return x.SinceUnixMs
 	}
 	return 0
 }
 
-func (x *MoveAnalysis) GetPly() int32 {
+func (x *Degradation) GetPoolWaitP95Ms() int64 {
 	if x != nil {
-		return x.Ply
+		return x.PoolWaitP95Ms
 	}
 	return 0
 }
 
-func (x *MoveAnalysis) GetColor() string {
+func (x *Degradation) GetSamples() int32 {
 	if x != nil {
-		return x.Color
+		return x.Samples
 	}
-	return ""
+	return 0
 }
 
-func (x *MoveAnalysis) GetPlayedMove() string {
+func (x *Degradation) GetDepth() int32 {
 	if x != nil {
-		return x.PlayedMove
+		return x.Depth
 	}
-	return ""
+	return 0
 }
 
-func (x *MoveAnalysis) GetPlayedMoveUci() string {
+func (x *Degradation) GetActivations() int64 {
 	if x != nil {
-		return x.PlayedMoveUci
+		return x.Activations
 	}
-	return ""
+	return 0
 }
 
-func (x *MoveAnalysis) GetBestMove() string {
+func (x *Degradation) GetDegradedGames() int64 {
 	if x != nil {
-		return x.BestMove
+		return x.DegradedGames
 	}
-	return ""
+	return 0
 }
 
-func (x *MoveAnalysis) GetBestMoveUci() string {
+// Rolling average of the engine time of single-PV searches at a range of
+// depths, as reported by the engine so without queueing. Searches count
+// half as much every hour.
+type DepthTiming struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinDepth      int32                  `protobuf:"varint,1,opt,name=min_depth,json=minDepth,proto3" json:"min_depth,omitempty"`
+	MaxDepth      int32                  `protobuf:"varint,2,opt,name=max_depth,json=maxDepth,proto3" json:"max_depth,omitempty"`
+	MsPerPosition float64                `protobuf:"fixed64,3,opt,name=ms_per_position,json=msPerPosition,proto3" json:"ms_per_position,omitempty"`
+	Searches      int64                  `protobuf:"varint,4,opt,name=searches,proto3" json:"searches,omitempty"`
+	Phase         string                 `protobuf:"bytes,5,opt,name=phase,proto3" json:"phase,omitempty"`                  // opening, middlegame or endgame; empty for all phases
+	Samples       float64                `protobuf:"fixed64,6,opt,name=samples,proto3" json:"samples,omitempty"`            // Searches, decayed
+	Histogram     []float64              `protobuf:"fixed64,7,rep,packed,name=histogram,proto3" json:"histogram,omitempty"` // Searches, decayed, by bin of AnalysisStats.histogram_bounds_ms
+	Phases        []*DepthTiming         `protobuf:"bytes,8,rep,name=phases,proto3" json:"phases,omitempty"`                // The range's searches by phase, only for all phases
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DepthTiming) Reset() {
+	*x = DepthTiming{}
+	mi := &file_proto_analysis_proto_msgTypes[61]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DepthTiming) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DepthTiming) ProtoMessage() {}
+
+func (x *DepthTiming) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[61]
 	if x != nil {
-		return x.BestMoveUci
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *MoveAnalysis) GetFenBefore() string {
+// Deprecated: Use DepthTiming.ProtoReflect.Descriptor instead.
+func (*DepthTiming) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{61}
+}
+
+func (x *DepthTiming) GetMinDepth() int32 {
 	if x != nil {
-		return x.FenBefore
+		return x.MinDepth
 	}
-	return ""
+	return 0
 }
 
-func (x *MoveAnalysis) GetFenAfter() string {
+func (x *DepthTiming) GetMaxDepth() int32 {
 	if x != nil {
-		return x.FenAfter
+		return x.MaxDepth
 	}
-	return ""
+	return 0
 }
 
-func (x *MoveAnalysis) GetEvalBefore() *Evaluation {
+func (x *DepthTiming) GetMsPerPosition() float64 {
 	if x != nil {
-		return x.EvalBefore
+		return x.MsPerPosition
 	}
-	return nil
+	return 0
 }
 
-func (x *MoveAnalysis) GetEvalAfter() *Evaluation {
+func (x *DepthTiming) GetSearches() int64 {
 	if x != nil {
-		return x.EvalAfter
+		return x.Searches
 	}
-	return nil
+	return 0
 }
 
-func (x *MoveAnalysis) GetCentipawnLoss() int32 {
+func (x *DepthTiming) GetPhase() string {
 	if x != nil {
-		return x.CentipawnLoss
+		return x.Phase
+	}
+	return ""
+}
+
+func (x *DepthTiming) GetSamples() float64 {
+	if x != nil {
+		return x.Samples
 	}
 	return 0
 }
 
-func (x *MoveAnalysis) GetClassification() MoveClassification {
+func (x *DepthTiming) GetHistogram() []float64 {
 	if x != nil {
-		return x.Classification
+		return x.Histogram
 	}
-	return MoveClassification_CLASSIFICATION_UNKNOWN
+	return nil
 }
 
-func (x *MoveAnalysis) GetPv() []string {
+func (x *DepthTiming) GetPhases() []*DepthTiming {
 	if x != nil {
-		return x.Pv
+		return x.Phases
 	}
 	return nil
 }
 
-func (x *MoveAnalysis) GetDepth() int32 {
+type GetCapacityEstimateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Depth         int32                  `protobuf:"varint,1,opt,name=depth,proto3" json:"depth,omitempty"`                                   // Depth games are analyzed at (required)
+	AverageMoves  int32                  `protobuf:"varint,2,opt,name=average_moves,json=averageMoves,proto3" json:"average_moves,omitempty"` // Full moves of an average game (required)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCapacityEstimateRequest) Reset() {
+	*x = GetCapacityEstimateRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[62]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCapacityEstimateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCapacityEstimateRequest) ProtoMessage() {}
+
+func (x *GetCapacityEstimateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[62]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCapacityEstimateRequest.ProtoReflect.Descriptor instead.
+func (*GetCapacityEstimateRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{62}
+}
+
+func (x *GetCapacityEstimateRequest) GetDepth() int32 {
 	if x != nil {
 		return x.Depth
 	}
 	return 0
 }
 
-// Aggregated metrics for a player's side
-type GameMetrics struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	Accuracy          float32                `protobuf:"fixed32,1,opt,name=accuracy,proto3" json:"accuracy,omitempty"`                                            // Accuracy percentage (0-100)
-	Acpl              float32                `protobuf:"fixed32,2,opt,name=acpl,proto3" json:"acpl,omitempty"`                                                    // Average centipawn loss
-	Blunders          int32                  `protobuf:"varint,3,opt,name=blunders,proto3" json:"blunders,omitempty"`                                             // Number of blunders
-	Mistakes          int32                  `protobuf:"varint,4,opt,name=mistakes,proto3" json:"mistakes,omitempty"`                                             // Number of mistakes
-	Inaccuracies      int32                  `protobuf:"varint,5,opt,name=inaccuracies,proto3" json:"inaccuracies,omitempty"`                                     // Number of inaccuracies
-	GoodMoves         int32                  `protobuf:"varint,6,opt,name=good_moves,json=goodMoves,proto3" json:"good_moves,omitempty"`                          // Number of good moves
-	ExcellentMoves    int32                  `protobuf:"varint,7,opt,name=excellent_moves,json=excellentMoves,proto3" json:"excellent_moves,omitempty"`           // Number of excellent moves
-	BestMoves         int32                  `protobuf:"varint,8,opt,name=best_moves,json=bestMoves,proto3" json:"best_moves,omitempty"`                          // Number of best moves
-	BrilliantMoves    int32                  `protobuf:"varint,9,opt,name=brilliant_moves,json=brilliantMoves,proto3" json:"brilliant_moves,omitempty"`           // Number of brilliant moves
-	BookMoves         int32                  `protobuf:"varint,10,opt,name=book_moves,json=bookMoves,proto3" json:"book_moves,omitempty"`                         // Number of book moves
-	TotalMoves        int32                  `protobuf:"varint,11,opt,name=total_moves,json=totalMoves,proto3" json:"total_moves,omitempty"`                      // Total moves analyzed
-	PerformanceRating int32                  `protobuf:"varint,12,opt,name=performance_rating,json=performanceRating,proto3" json:"performance_rating,omitempty"` // Estimated performance rating
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+func (x *GetCapacityEstimateRequest) GetAverageMoves() int32 {
+	if x != nil {
+		return x.AverageMoves
+	}
+	return 0
 }
 
-func (x *GameMetrics) Reset() {
-	*x = GameMetrics{}
-	mi := &file_proto_analysis_proto_msgTypes[7]
+// Game analyses the primary engine pool can finish per hour, taking every
+// position of a game as searched: cache hits make it faster, waits for
+// busy engines slower
+type CapacityEstimate struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Depth            int32                  `protobuf:"varint,1,opt,name=depth,proto3" json:"depth,omitempty"`
+	MinDepth         int32                  `protobuf:"varint,2,opt,name=min_depth,json=minDepth,proto3" json:"min_depth,omitempty"` // Depth range the estimate is from
+	MaxDepth         int32                  `protobuf:"varint,3,opt,name=max_depth,json=maxDepth,proto3" json:"max_depth,omitempty"`
+	AverageMoves     int32                  `protobuf:"varint,4,opt,name=average_moves,json=averageMoves,proto3" json:"average_moves,omitempty"`
+	PositionsPerGame int32                  `protobuf:"varint,5,opt,name=positions_per_game,json=positionsPerGame,proto3" json:"positions_per_game,omitempty"` // Positions searched per game, the final one included
+	Engines          int32                  `protobuf:"varint,6,opt,name=engines,proto3" json:"engines,omitempty"`                                             // Current size of the pool
+	MsPerPosition    float64                `protobuf:"fixed64,7,opt,name=ms_per_position,json=msPerPosition,proto3" json:"ms_per_position,omitempty"`         // Mean engine time per search
+	P90MsPerPosition float64                `protobuf:"fixed64,8,opt,name=p90_ms_per_position,json=p90MsPerPosition,proto3" json:"p90_ms_per_position,omitempty"`
+	GamesPerHour     float64                `protobuf:"fixed64,9,opt,name=games_per_hour,json=gamesPerHour,proto3" json:"games_per_hour,omitempty"`             // At the mean
+	GamesPerHourP90  float64                `protobuf:"fixed64,10,opt,name=games_per_hour_p90,json=gamesPerHourP90,proto3" json:"games_per_hour_p90,omitempty"` // At the 90th percentile, for a cautious plan
+	Samples          float64                `protobuf:"fixed64,11,opt,name=samples,proto3" json:"samples,omitempty"`                                            // Recent searches the estimate is from, decayed
+	Confidence       float64                `protobuf:"fixed64,12,opt,name=confidence,proto3" json:"confidence,omitempty"`                                      // 0 without samples, 0.5 at 20, towards 1
+	Known            bool                   `protobuf:"varint,13,opt,name=known,proto3" json:"known,omitempty"`                                                 // False until the depth range has been searched; the estimates are then 0
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *CapacityEstimate) Reset() {
+	*x = CapacityEstimate{}
+	mi := &file_proto_analysis_proto_msgTypes[63]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GameMetrics) String() string {
+func (x *CapacityEstimate) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GameMetrics) ProtoMessage() {}
+func (*CapacityEstimate) ProtoMessage() {}
 
-func (x *GameMetrics) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_analysis_proto_msgTypes[7]
+func (x *CapacityEstimate) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[63]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -815,120 +6744,126 @@ func (x *GameMetrics) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GameMetrics.ProtoReflect.Descriptor instead.
-func (*GameMetrics) Descriptor() ([]byte, []int) {
-	return file_proto_analysis_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use CapacityEstimate.ProtoReflect.Descriptor instead.
+func (*CapacityEstimate) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{63}
+}
+
+func (x *CapacityEstimate) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
 }
 
-func (x *GameMetrics) GetAccuracy() float32 {
+func (x *CapacityEstimate) GetMinDepth() int32 {
 	if x != nil {
-		return x.Accuracy
+		return x.MinDepth
 	}
 	return 0
 }
 
-func (x *GameMetrics) GetAcpl() float32 {
+func (x *CapacityEstimate) GetMaxDepth() int32 {
 	if x != nil {
-		return x.Acpl
+		return x.MaxDepth
 	}
 	return 0
 }
 
-func (x *GameMetrics) GetBlunders() int32 {
+func (x *CapacityEstimate) GetAverageMoves() int32 {
 	if x != nil {
-		return x.Blunders
+		return x.AverageMoves
 	}
 	return 0
 }
 
-func (x *GameMetrics) GetMistakes() int32 {
+func (x *CapacityEstimate) GetPositionsPerGame() int32 {
 	if x != nil {
-		return x.Mistakes
+		return x.PositionsPerGame
 	}
 	return 0
 }
 
-func (x *GameMetrics) GetInaccuracies() int32 {
+func (x *CapacityEstimate) GetEngines() int32 {
 	if x != nil {
-		return x.Inaccuracies
+		return x.Engines
 	}
 	return 0
 }
 
-func (x *GameMetrics) GetGoodMoves() int32 {
+func (x *CapacityEstimate) GetMsPerPosition() float64 {
 	if x != nil {
-		return x.GoodMoves
+		return x.MsPerPosition
 	}
 	return 0
 }
 
-func (x *GameMetrics) GetExcellentMoves() int32 {
+func (x *CapacityEstimate) GetP90MsPerPosition() float64 {
 	if x != nil {
-		return x.ExcellentMoves
+		return x.P90MsPerPosition
 	}
 	return 0
 }
 
-func (x *GameMetrics) GetBestMoves() int32 {
+func (x *CapacityEstimate) GetGamesPerHour() float64 {
 	if x != nil {
-		return x.BestMoves
+		return x.GamesPerHour
 	}
 	return 0
 }
 
-func (x *GameMetrics) GetBrilliantMoves() int32 {
+func (x *CapacityEstimate) GetGamesPerHourP90() float64 {
 	if x != nil {
-		return x.BrilliantMoves
+		return x.GamesPerHourP90
 	}
 	return 0
 }
 
-func (x *GameMetrics) GetBookMoves() int32 {
+func (x *CapacityEstimate) GetSamples() float64 {
 	if x != nil {
-		return x.BookMoves
+		return x.Samples
 	}
 	return 0
 }
 
-func (x *GameMetrics) GetTotalMoves() int32 {
+func (x *CapacityEstimate) GetConfidence() float64 {
 	if x != nil {
-		return x.TotalMoves
+		return x.Confidence
 	}
 	return 0
 }
 
-func (x *GameMetrics) GetPerformanceRating() int32 {
+func (x *CapacityEstimate) GetKnown() bool {
 	if x != nil {
-		return x.PerformanceRating
+		return x.Known
 	}
-	return 0
+	return false
 }
 
-// Request for MultiPV best moves
-type GetBestMovesRequest struct {
+// A position of a game analyzed with record_engine_output, kept for an hour
+type GetEngineTranscriptRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Fen           string                 `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"`      // FEN string
-	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"` // Number of best moves to return (1-10)
-	Depth         int32                  `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"` // Analysis depth
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"` // transcript_job_id of the analysis
+	Ply           int32                  `protobuf:"varint,2,opt,name=ply,proto3" json:"ply,omitempty"`                 // Position searched, by the half-moves played to reach it (0 = the start)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetBestMovesRequest) Reset() {
-	*x = GetBestMovesRequest{}
-	mi := &file_proto_analysis_proto_msgTypes[8]
+func (x *GetEngineTranscriptRequest) Reset() {
+	*x = GetEngineTranscriptRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[64]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetBestMovesRequest) String() string {
+func (x *GetEngineTranscriptRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetBestMovesRequest) ProtoMessage() {}
+func (*GetEngineTranscriptRequest) ProtoMessage() {}
 
-func (x *GetBestMovesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_analysis_proto_msgTypes[8]
+func (x *GetEngineTranscriptRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[64]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -939,57 +6874,50 @@ func (x *GetBestMovesRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetBestMovesRequest.ProtoReflect.Descriptor instead.
-func (*GetBestMovesRequest) Descriptor() ([]byte, []int) {
-	return file_proto_analysis_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use GetEngineTranscriptRequest.ProtoReflect.Descriptor instead.
+func (*GetEngineTranscriptRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{64}
 }
 
-func (x *GetBestMovesRequest) GetFen() string {
+func (x *GetEngineTranscriptRequest) GetJobId() string {
 	if x != nil {
-		return x.Fen
+		return x.JobId
 	}
 	return ""
 }
 
-func (x *GetBestMovesRequest) GetCount() int32 {
-	if x != nil {
-		return x.Count
-	}
-	return 0
-}
-
-func (x *GetBestMovesRequest) GetDepth() int32 {
+func (x *GetEngineTranscriptRequest) GetPly() int32 {
 	if x != nil {
-		return x.Depth
+		return x.Ply
 	}
 	return 0
 }
 
-// Response with multiple best moves
-type BestMovesResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Fen           string                 `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"`
-	Moves         []*BestMove            `protobuf:"bytes,2,rep,name=moves,proto3" json:"moves,omitempty"`
-	Depth         int32                  `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+// Every UCI line of a position's searches, retries included
+type EngineTranscript struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Lines           []string               `protobuf:"bytes,1,rep,name=lines,proto3" json:"lines,omitempty"`          // Commands sent prefixed "> ", engine output "< "
+	Truncated       bool                   `protobuf:"varint,2,opt,name=truncated,proto3" json:"truncated,omitempty"` // The job reached ENGINE_TRANSCRIPT_MAX_BYTES and its later lines were dropped
+	ExpiresAtUnixMs int64                  `protobuf:"varint,3,opt,name=expires_at_unix_ms,json=expiresAtUnixMs,proto3" json:"expires_at_unix_ms,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
-func (x *BestMovesResponse) Reset() {
-	*x = BestMovesResponse{}
-	mi := &file_proto_analysis_proto_msgTypes[9]
+func (x *EngineTranscript) Reset() {
+	*x = EngineTranscript{}
+	mi := &file_proto_analysis_proto_msgTypes[65]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *BestMovesResponse) String() string {
+func (x *EngineTranscript) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BestMovesResponse) ProtoMessage() {}
+func (*EngineTranscript) ProtoMessage() {}
 
-func (x *BestMovesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_analysis_proto_msgTypes[9]
+func (x *EngineTranscript) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[65]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1000,59 +6928,60 @@ func (x *BestMovesResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BestMovesResponse.ProtoReflect.Descriptor instead.
-func (*BestMovesResponse) Descriptor() ([]byte, []int) {
-	return file_proto_analysis_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use EngineTranscript.ProtoReflect.Descriptor instead.
+func (*EngineTranscript) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{65}
 }
 
-func (x *BestMovesResponse) GetFen() string {
+func (x *EngineTranscript) GetLines() []string {
 	if x != nil {
-		return x.Fen
+		return x.Lines
 	}
-	return ""
+	return nil
 }
 
-func (x *BestMovesResponse) GetMoves() []*BestMove {
+func (x *EngineTranscript) GetTruncated() bool {
 	if x != nil {
-		return x.Moves
+		return x.Truncated
 	}
-	return nil
+	return false
 }
 
-func (x *BestMovesResponse) GetDepth() int32 {
+func (x *EngineTranscript) GetExpiresAtUnixMs() int64 {
 	if x != nil {
-		return x.Depth
+		return x.ExpiresAtUnixMs
 	}
 	return 0
 }
 
-// A single best move with evaluation
-type BestMove struct {
+// Positions to search into the position cache, e.g. the openings of an
+// upcoming tournament. Closing the stream stops the warming; positions
+// searched so far stay cached.
+type WarmCacheRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Rank          int32                  `protobuf:"varint,1,opt,name=rank,proto3" json:"rank,omitempty"`                     // Rank (1 = best, 2 = second best, etc.)
-	MoveUci       string                 `protobuf:"bytes,2,opt,name=move_uci,json=moveUci,proto3" json:"move_uci,omitempty"` // Move in UCI format
-	MoveSan       string                 `protobuf:"bytes,3,opt,name=move_san,json=moveSan,proto3" json:"move_san,omitempty"` // Move in SAN format (if available)
-	Evaluation    *Evaluation            `protobuf:"bytes,4,opt,name=evaluation,proto3" json:"evaluation,omitempty"`          // Evaluation after this move
-	Pv            []string               `protobuf:"bytes,5,rep,name=pv,proto3" json:"pv,omitempty"`                          // Principal variation
+	Fens          []string               `protobuf:"bytes,1,rep,name=fens,proto3" json:"fens,omitempty"`                    // Positions to warm
+	Pgn           string                 `protobuf:"bytes,2,opt,name=pgn,proto3" json:"pgn,omitempty"`                      // One or more games whose every position is warmed, instead of fens
+	Depth         int32                  `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"`                 // 0 = default depth, clamped like any request's
+	DryRun        bool                   `protobuf:"varint,4,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"` // Only count the positions and estimate their engine time
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *BestMove) Reset() {
-	*x = BestMove{}
-	mi := &file_proto_analysis_proto_msgTypes[10]
+func (x *WarmCacheRequest) Reset() {
+	*x = WarmCacheRequest{}
+	mi := &file_proto_analysis_proto_msgTypes[66]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *BestMove) String() string {
+func (x *WarmCacheRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BestMove) ProtoMessage() {}
+func (*WarmCacheRequest) ProtoMessage() {}
 
-func (x *BestMove) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_analysis_proto_msgTypes[10]
+func (x *WarmCacheRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[66]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1063,68 +6992,74 @@ func (x *BestMove) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BestMove.ProtoReflect.Descriptor instead.
-func (*BestMove) Descriptor() ([]byte, []int) {
-	return file_proto_analysis_proto_rawDescGZIP(), []int{10}
-}
-
-func (x *BestMove) GetRank() int32 {
-	if x != nil {
-		return x.Rank
-	}
-	return 0
+// Deprecated: Use WarmCacheRequest.ProtoReflect.Descriptor instead.
+func (*WarmCacheRequest) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{66}
 }
 
-func (x *BestMove) GetMoveUci() string {
+func (x *WarmCacheRequest) GetFens() []string {
 	if x != nil {
-		return x.MoveUci
+		return x.Fens
 	}
-	return ""
+	return nil
 }
 
-func (x *BestMove) GetMoveSan() string {
+func (x *WarmCacheRequest) GetPgn() string {
 	if x != nil {
-		return x.MoveSan
+		return x.Pgn
 	}
 	return ""
 }
 
-func (x *BestMove) GetEvaluation() *Evaluation {
+func (x *WarmCacheRequest) GetDepth() int32 {
 	if x != nil {
-		return x.Evaluation
+		return x.Depth
 	}
-	return nil
+	return 0
 }
 
-func (x *BestMove) GetPv() []string {
+func (x *WarmCacheRequest) GetDryRun() bool {
 	if x != nil {
-		return x.Pv
+		return x.DryRun
 	}
-	return nil
+	return false
 }
 
-// Health check request
-type HealthCheckRequest struct {
+// Progress of a cache warming. Every position given is a duplicate, cached,
+// game over or pending until it is analyzed or fails.
+type WarmCacheProgress struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	Depth         int32                  `protobuf:"varint,1,opt,name=depth,proto3" json:"depth,omitempty"`                       // Depth searched to
+	Positions     int32                  `protobuf:"varint,2,opt,name=positions,proto3" json:"positions,omitempty"`               // Given, duplicates included
+	Duplicates    int32                  `protobuf:"varint,3,opt,name=duplicates,proto3" json:"duplicates,omitempty"`             // Given again after their first time
+	Cached        int32                  `protobuf:"varint,4,opt,name=cached,proto3" json:"cached,omitempty"`                     // Already cached at the depth, skipped
+	GameOver      int32                  `protobuf:"varint,5,opt,name=game_over,json=gameOver,proto3" json:"game_over,omitempty"` // Mate or stalemate, nothing to search
+	Pending       int32                  `protobuf:"varint,6,opt,name=pending,proto3" json:"pending,omitempty"`                   // Left to search
+	Analyzed      int32                  `protobuf:"varint,7,opt,name=analyzed,proto3" json:"analyzed,omitempty"`                 // Searched and cached
+	Failed        int32                  `protobuf:"varint,8,opt,name=failed,proto3" json:"failed,omitempty"`
+	EstimatedMs   int64                  `protobuf:"varint,9,opt,name=estimated_ms,json=estimatedMs,proto3" json:"estimated_ms,omitempty"` // Engine time the pending searches are expected to take
+	Estimated     bool                   `protobuf:"varint,10,opt,name=estimated,proto3" json:"estimated,omitempty"`                       // estimated_ms is known: a search at the depth has finished before
+	Done          bool                   `protobuf:"varint,11,opt,name=done,proto3" json:"done,omitempty"`                                 // Last message; also set on a dry run's only one
+	DryRun        bool                   `protobuf:"varint,12,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HealthCheckRequest) Reset() {
-	*x = HealthCheckRequest{}
-	mi := &file_proto_analysis_proto_msgTypes[11]
+func (x *WarmCacheProgress) Reset() {
+	*x = WarmCacheProgress{}
+	mi := &file_proto_analysis_proto_msgTypes[67]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HealthCheckRequest) String() string {
+func (x *WarmCacheProgress) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HealthCheckRequest) ProtoMessage() {}
+func (*WarmCacheProgress) ProtoMessage() {}
 
-func (x *HealthCheckRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_analysis_proto_msgTypes[11]
+func (x *WarmCacheProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_analysis_proto_msgTypes[67]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1135,107 +7070,112 @@ func (x *HealthCheckRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HealthCheckRequest.ProtoReflect.Descriptor instead.
-func (*HealthCheckRequest) Descriptor() ([]byte, []int) {
-	return file_proto_analysis_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use WarmCacheProgress.ProtoReflect.Descriptor instead.
+func (*WarmCacheProgress) Descriptor() ([]byte, []int) {
+	return file_proto_analysis_proto_rawDescGZIP(), []int{67}
 }
 
-// Health check response
-type HealthCheckResponse struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	Healthy          bool                   `protobuf:"varint,1,opt,name=healthy,proto3" json:"healthy,omitempty"`
-	Status           string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
-	AvailableWorkers int32                  `protobuf:"varint,3,opt,name=available_workers,json=availableWorkers,proto3" json:"available_workers,omitempty"`
-	TotalWorkers     int32                  `protobuf:"varint,4,opt,name=total_workers,json=totalWorkers,proto3" json:"total_workers,omitempty"`
-	StockfishVersion string                 `protobuf:"bytes,5,opt,name=stockfish_version,json=stockfishVersion,proto3" json:"stockfish_version,omitempty"`
-	UptimeSeconds    int64                  `protobuf:"varint,6,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+func (x *WarmCacheProgress) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
 }
 
-func (x *HealthCheckResponse) Reset() {
-	*x = HealthCheckResponse{}
-	mi := &file_proto_analysis_proto_msgTypes[12]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *WarmCacheProgress) GetPositions() int32 {
+	if x != nil {
+		return x.Positions
+	}
+	return 0
 }
 
-func (x *HealthCheckResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *WarmCacheProgress) GetDuplicates() int32 {
+	if x != nil {
+		return x.Duplicates
+	}
+	return 0
 }
 
-func (*HealthCheckResponse) ProtoMessage() {}
-
-func (x *HealthCheckResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_analysis_proto_msgTypes[12]
+func (x *WarmCacheProgress) GetCached() int32 {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.Cached
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use HealthCheckResponse.ProtoReflect.Descriptor instead.
-func (*HealthCheckResponse) Descriptor() ([]byte, []int) {
-	return file_proto_analysis_proto_rawDescGZIP(), []int{12}
+func (x *WarmCacheProgress) GetGameOver() int32 {
+	if x != nil {
+		return x.GameOver
+	}
+	return 0
 }
 
-func (x *HealthCheckResponse) GetHealthy() bool {
+func (x *WarmCacheProgress) GetPending() int32 {
 	if x != nil {
-		return x.Healthy
+		return x.Pending
 	}
-	return false
+	return 0
 }
 
-func (x *HealthCheckResponse) GetStatus() string {
+func (x *WarmCacheProgress) GetAnalyzed() int32 {
 	if x != nil {
-		return x.Status
+		return x.Analyzed
 	}
-	return ""
+	return 0
 }
 
-func (x *HealthCheckResponse) GetAvailableWorkers() int32 {
+func (x *WarmCacheProgress) GetFailed() int32 {
 	if x != nil {
-		return x.AvailableWorkers
+		return x.Failed
 	}
 	return 0
 }
 
-func (x *HealthCheckResponse) GetTotalWorkers() int32 {
+func (x *WarmCacheProgress) GetEstimatedMs() int64 {
 	if x != nil {
-		return x.TotalWorkers
+		return x.EstimatedMs
 	}
 	return 0
 }
 
-func (x *HealthCheckResponse) GetStockfishVersion() string {
+func (x *WarmCacheProgress) GetEstimated() bool {
 	if x != nil {
-		return x.StockfishVersion
+		return x.Estimated
 	}
-	return ""
+	return false
 }
 
-func (x *HealthCheckResponse) GetUptimeSeconds() int64 {
+func (x *WarmCacheProgress) GetDone() bool {
 	if x != nil {
-		return x.UptimeSeconds
+		return x.Done
 	}
-	return 0
+	return false
+}
+
+func (x *WarmCacheProgress) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
 }
 
 var File_proto_analysis_proto protoreflect.FileDescriptor
 
 const file_proto_analysis_proto_rawDesc = "" +
 	"\n" +
-	"\x14proto/analysis.proto\x12\banalysis\"z\n" +
+	"\x14proto/analysis.proto\x12\banalysis\"\xe4\x01\n" +
 	"\x16AnalyzePositionRequest\x12\x10\n" +
 	"\x03fen\x18\x01 \x01(\tR\x03fen\x12\x14\n" +
 	"\x05depth\x18\x02 \x01(\x05R\x05depth\x12\x19\n" +
 	"\bmulti_pv\x18\x03 \x01(\x05R\amultiPv\x12\x1d\n" +
 	"\n" +
-	"timeout_ms\x18\x04 \x01(\x05R\ttimeoutMs\"\xde\x01\n" +
+	"timeout_ms\x18\x04 \x01(\x05R\ttimeoutMs\x12 \n" +
+	"\tuse_cache\x18\x05 \x01(\bH\x00R\buseCache\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"cache_only\x18\x06 \x01(\bR\tcacheOnly\x12\x19\n" +
+	"\bno_store\x18\a \x01(\bR\anoStoreB\f\n" +
+	"\n" +
+	"_use_cache\"\x82\x05\n" +
 	"\x10PositionAnalysis\x12\x10\n" +
 	"\x03fen\x18\x01 \x01(\tR\x03fen\x12\x14\n" +
 	"\x05depth\x18\x02 \x01(\x05R\x05depth\x124\n" +
@@ -1246,7 +7186,20 @@ const file_proto_analysis_proto_rawDesc = "" +
 	"\x02pv\x18\x05 \x03(\tR\x02pv\x12\x14\n" +
 	"\x05nodes\x18\x06 \x01(\x03R\x05nodes\x12\x10\n" +
 	"\x03nps\x18\a \x01(\x03R\x03nps\x12\x17\n" +
-	"\atime_ms\x18\b \x01(\x03R\x06timeMs\"k\n" +
+	"\atime_ms\x18\b \x01(\x03R\x06timeMs\x12!\n" +
+	"\ftarget_depth\x18\t \x01(\x05R\vtargetDepth\x12\x1b\n" +
+	"\ttimed_out\x18\n" +
+	" \x01(\bR\btimedOut\x12\x16\n" +
+	"\x06source\x18\v \x01(\tR\x06source\x12(\n" +
+	"\x10game_over_reason\x18\f \x01(\tR\x0egameOverReason\x12\x19\n" +
+	"\bmulti_pv\x18\r \x01(\x05R\amultiPv\x12\"\n" +
+	"\rqueue_time_ms\x18\x0e \x01(\x03R\vqueueTimeMs\x12$\n" +
+	"\x0esearch_time_ms\x18\x0f \x01(\x03R\fsearchTimeMs\x12!\n" +
+	"\fpool_waiting\x18\x10 \x01(\x05R\vpoolWaiting\x12%\n" +
+	"\x0epool_available\x18\x11 \x01(\x05R\rpoolAvailable\x12&\n" +
+	"\x0fponder_move_uci\x18\x12 \x01(\tR\rponderMoveUci\x12&\n" +
+	"\x0fponder_move_san\x18\x13 \x01(\tR\rponderMoveSan\x12!\n" +
+	"\fserved_stale\x18\x14 \x01(\bR\vservedStale\"k\n" +
 	"\n" +
 	"Evaluation\x12 \n" +
 	"\n" +
@@ -1254,20 +7207,195 @@ const file_proto_analysis_proto_rawDesc = "" +
 	"centipawns\x12\x19\n" +
 	"\amate_in\x18\x02 \x01(\x05H\x00R\x06mateIn\x12\x17\n" +
 	"\ais_mate\x18\x03 \x01(\bR\x06isMateB\a\n" +
-	"\x05score\"\x9e\x01\n" +
+	"\x05score\"\xc6\a\n" +
 	"\x12AnalyzeGameRequest\x12\x17\n" +
 	"\agame_id\x18\x01 \x01(\tR\x06gameId\x12\x10\n" +
 	"\x03pgn\x18\x02 \x01(\tR\x03pgn\x12\x14\n" +
 	"\x05depth\x18\x03 \x01(\x05R\x05depth\x12\x19\n" +
 	"\bmulti_pv\x18\x04 \x01(\x05R\amultiPv\x12,\n" +
-	"\x12include_book_moves\x18\x05 \x01(\bR\x10includeBookMoves\"\x98\x02\n" +
+	"\x12include_book_moves\x18\x05 \x01(\bR\x10includeBookMoves\x12+\n" +
+	"\x11threshold_profile\x18\x06 \x01(\tR\x10thresholdProfile\x12%\n" +
+	"\x0eengine_profile\x18\a \x01(\tR\rengineProfile\x12,\n" +
+	"\x12cross_check_engine\x18\b \x01(\tR\x10crossCheckEngine\x12.\n" +
+	"\x13analyze_until_error\x18\t \x01(\bR\x11analyzeUntilError\x12D\n" +
+	"\x10eval_perspective\x18\n" +
+	" \x01(\x0e2\x19.analysis.EvalPerspectiveR\x0fevalPerspective\x125\n" +
+	"\x14exclude_garbage_time\x18\v \x01(\bH\x00R\x12excludeGarbageTime\x88\x01\x01\x12\x18\n" +
+	"\apersist\x18\f \x01(\bR\apersist\x12\x16\n" +
+	"\x06result\x18\r \x01(\tR\x06result\x12\x1b\n" +
+	"\tstart_ply\x18\x0e \x01(\x05R\bstartPly\x12I\n" +
+	"\x12prefix_evaluations\x18\x0f \x03(\v2\x1a.analysis.PrefixEvaluationR\x11prefixEvaluations\x12>\n" +
+	"\x0eaccuracy_model\x18\x10 \x01(\x0e2\x17.analysis.AccuracyModelR\raccuracyModel\x12 \n" +
+	"\tuse_cache\x18\x11 \x01(\bH\x01R\buseCache\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"cache_only\x18\x12 \x01(\bR\tcacheOnly\x12\x19\n" +
+	"\bno_store\x18\x13 \x01(\bR\anoStore\x12$\n" +
+	"\x0etime_budget_ms\x18\x14 \x01(\x03R\ftimeBudgetMs\x120\n" +
+	"\x14record_engine_output\x18\x15 \x01(\bR\x12recordEngineOutput\x12\x1d\n" +
+	"\n" +
+	"full_depth\x18\x16 \x01(\bR\tfullDepth\x12#\n" +
+	"\rpreview_depth\x18\x17 \x01(\x05R\fpreviewDepthB\x17\n" +
+	"\x15_exclude_garbage_timeB\f\n" +
+	"\n" +
+	"_use_cache\"\xa6\x01\n" +
+	"\x10PrefixEvaluation\x12\x10\n" +
+	"\x03ply\x18\x01 \x01(\x05R\x03ply\x124\n" +
+	"\n" +
+	"evaluation\x18\x02 \x01(\v2\x14.analysis.EvaluationR\n" +
+	"evaluation\x12\"\n" +
+	"\rbest_move_uci\x18\x03 \x01(\tR\vbestMoveUci\x12\x14\n" +
+	"\x05depth\x18\x04 \x01(\x05R\x05depth\x12\x10\n" +
+	"\x03fen\x18\x05 \x01(\tR\x03fen\"\xf3\x0e\n" +
 	"\fGameAnalysis\x12\x17\n" +
 	"\agame_id\x18\x01 \x01(\tR\x06gameId\x12,\n" +
 	"\x05moves\x18\x02 \x03(\v2\x16.analysis.MoveAnalysisR\x05moves\x12:\n" +
 	"\rwhite_metrics\x18\x03 \x01(\v2\x15.analysis.GameMetricsR\fwhiteMetrics\x12:\n" +
 	"\rblack_metrics\x18\x04 \x01(\v2\x15.analysis.GameMetricsR\fblackMetrics\x12\"\n" +
 	"\rtotal_time_ms\x18\x05 \x01(\x03R\vtotalTimeMs\x12%\n" +
-	"\x0eengine_version\x18\x06 \x01(\tR\rengineVersion\"\x98\x02\n" +
+	"\x0eengine_version\x18\x06 \x01(\tR\rengineVersion\x12+\n" +
+	"\x11threshold_profile\x18\a \x01(\tR\x10thresholdProfile\x12B\n" +
+	"\n" +
+	"thresholds\x18\b \x01(\v2\".analysis.ClassificationThresholdsR\n" +
+	"thresholds\x12\x14\n" +
+	"\x05depth\x18\t \x01(\x05R\x05depth\x12\x1b\n" +
+	"\ttimed_out\x18\n" +
+	" \x01(\bR\btimedOut\x12\x1f\n" +
+	"\vtotal_moves\x18\v \x01(\x05R\n" +
+	"totalMoves\x12%\n" +
+	"\x0eengine_profile\x18\f \x01(\tR\rengineProfile\x125\n" +
+	"\vcross_check\x18\r \x01(\v2\x14.analysis.CrossCheckR\n" +
+	"crossCheck\x12\x1c\n" +
+	"\ttruncated\x18\x0e \x01(\bR\ttruncated\x12(\n" +
+	"\x10truncated_at_ply\x18\x0f \x01(\x05R\x0etruncatedAtPly\x12)\n" +
+	"\x10truncation_error\x18\x10 \x01(\tR\x0ftruncationError\x12D\n" +
+	"\x10eval_perspective\x18\x11 \x01(\x0e2\x19.analysis.EvalPerspectiveR\x0fevalPerspective\x120\n" +
+	"\x06stored\x18\x12 \x01(\v2\x18.analysis.StoredAnalysisR\x06stored\x127\n" +
+	"\n" +
+	"white_time\x18\x13 \x01(\v2\x18.analysis.TimeManagementR\twhiteTime\x127\n" +
+	"\n" +
+	"black_time\x18\x14 \x01(\v2\x18.analysis.TimeManagementR\tblackTime\x12+\n" +
+	"\x12started_at_unix_ms\x18\x15 \x01(\x03R\x0fstartedAtUnixMs\x12/\n" +
+	"\x14completed_at_unix_ms\x18\x16 \x01(\x03R\x11completedAtUnixMs\x12%\n" +
+	"\x0ecache_coverage\x18\x17 \x01(\x02R\rcacheCoverage\x12?\n" +
+	"\vdiagnostics\x18\x18 \x01(\v2\x1d.analysis.AnalysisDiagnosticsR\vdiagnostics\x12$\n" +
+	"\x0etime_budget_ms\x18\x19 \x01(\x03R\ftimeBudgetMs\x12$\n" +
+	"\x0ebudget_used_ms\x18\x1a \x01(\x03R\fbudgetUsedMs\x12-\n" +
+	"\x12budget_utilization\x18\x1b \x01(\x02R\x11budgetUtilization\x12*\n" +
+	"\x11transcript_job_id\x18\x1c \x01(\tR\x0ftranscriptJobId\x12\x1b\n" +
+	"\tfast_mode\x18\x1d \x01(\bR\bfastMode\x12\x1a\n" +
+	"\bchecksum\x18\x1e \x01(\tR\bchecksum\x128\n" +
+	"\x06config\x18\x1f \x01(\v2 .analysis.AnalysisConfigSnapshotR\x06config\x12M\n" +
+	"\rsource_counts\x18  \x03(\v2(.analysis.GameAnalysis.SourceCountsEntryR\fsourceCounts\x12\x1a\n" +
+	"\bdegraded\x18! \x01(\bR\bdegraded\x12/\n" +
+	"\tgame_info\x18\" \x01(\v2\x12.analysis.GameInfoR\bgameInfo\x127\n" +
+	"\vtermination\x18# \x01(\x0e2\x15.analysis.TerminationR\vtermination\x12!\n" +
+	"\fwinner_color\x18$ \x01(\tR\vwinnerColor\x12:\n" +
+	"\x1aresult_against_run_of_play\x18% \x01(\bR\x16resultAgainstRunOfPlay\x12%\n" +
+	"\x0eresult_summary\x18& \x01(\tR\rresultSummary\x12\x18\n" +
+	"\asummary\x18' \x01(\tR\asummary\x12 \n" +
+	"\vpreliminary\x18( \x01(\bR\vpreliminary\x12!\n" +
+	"\fpreview_kept\x18) \x01(\x05R\vpreviewKept\x1a?\n" +
+	"\x11SourceCountsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"\xe5\x01\n" +
+	"\bGameInfo\x12\x14\n" +
+	"\x05white\x18\x01 \x01(\tR\x05white\x12\x14\n" +
+	"\x05black\x18\x02 \x01(\tR\x05black\x12 \n" +
+	"\twhite_elo\x18\x03 \x01(\x05H\x00R\bwhiteElo\x88\x01\x01\x12 \n" +
+	"\tblack_elo\x18\x04 \x01(\x05H\x01R\bblackElo\x88\x01\x01\x12\x16\n" +
+	"\x06result\x18\x05 \x01(\tR\x06result\x12!\n" +
+	"\ftime_control\x18\x06 \x01(\tR\vtimeControl\x12\x12\n" +
+	"\x04date\x18\a \x01(\tR\x04dateB\f\n" +
+	"\n" +
+	"_white_eloB\f\n" +
+	"\n" +
+	"_black_elo\"\xab\x02\n" +
+	"\x16AnalysisConfigSnapshot\x12\x18\n" +
+	"\athreads\x18\x01 \x01(\x05R\athreads\x12\x17\n" +
+	"\ahash_mb\x18\x02 \x01(\x05R\x06hashMb\x12\x19\n" +
+	"\bmulti_pv\x18\x03 \x01(\x05R\amultiPv\x12\x14\n" +
+	"\x05depth\x18\x04 \x01(\x05R\x05depth\x12\x1f\n" +
+	"\vnodes_limit\x18\x05 \x01(\x03R\n" +
+	"nodesLimit\x12\x1f\n" +
+	"\vmovetime_ms\x18\x06 \x01(\x03R\n" +
+	"movetimeMs\x12\x19\n" +
+	"\bnnue_net\x18\a \x01(\tR\annueNet\x12*\n" +
+	"\x11cache_hit_percent\x18\b \x01(\x02R\x0fcacheHitPercent\x12$\n" +
+	"\rdeterministic\x18\t \x01(\bR\rdeterministic\"\xe0\x01\n" +
+	"\x13AnalysisDiagnostics\x12\x18\n" +
+	"\aretries\x18\x01 \x01(\x05R\aretries\x12G\n" +
+	"\bfailures\x18\x02 \x03(\v2+.analysis.AnalysisDiagnostics.FailuresEntryR\bfailures\x12)\n" +
+	"\x10failed_positions\x18\x03 \x03(\x05R\x0ffailedPositions\x1a;\n" +
+	"\rFailuresEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"\x96\x03\n" +
+	"\x0eTimeManagement\x12\x1d\n" +
+	"\n" +
+	"time_class\x18\x01 \x01(\tR\ttimeClass\x122\n" +
+	"\x15scramble_threshold_ms\x18\x02 \x01(\x03R\x13scrambleThresholdMs\x12,\n" +
+	"\x12scramble_start_ply\x18\x03 \x01(\x05R\x10scrambleStartPly\x12%\n" +
+	"\x0escramble_moves\x18\x04 \x01(\x05R\rscrambleMoves\x12.\n" +
+	"\x13scramble_error_rate\x18\x05 \x01(\x02R\x11scrambleErrorRate\x12(\n" +
+	"\x10other_error_rate\x18\x06 \x01(\x02R\x0eotherErrorRate\x12(\n" +
+	"\x10thinking_time_ms\x18\a \x01(\x03R\x0ethinkingTimeMs\x12+\n" +
+	"\x12cp_loss_per_second\x18\b \x01(\x02R\x0fcpLossPerSecond\x12+\n" +
+	"\x11scramble_blunders\x18\t \x03(\x05R\x10scrambleBlunders\"w\n" +
+	"\x0eStoredAnalysis\x12\x1e\n" +
+	"\vgame_row_id\x18\x01 \x01(\x03R\tgameRowId\x12 \n" +
+	"\fmove_row_ids\x18\x02 \x03(\x03R\n" +
+	"moveRowIds\x12#\n" +
+	"\rkept_existing\x18\x03 \x01(\bR\fkeptExisting\"n\n" +
+	"\n" +
+	"CrossCheck\x124\n" +
+	"\tsecondary\x18\x01 \x01(\v2\x16.analysis.GameAnalysisR\tsecondary\x12*\n" +
+	"\x04diff\x18\x02 \x01(\v2\x16.analysis.AnalysisDiffR\x04diff\"\xaa\x04\n" +
+	"\fAnalysisDiff\x12\x19\n" +
+	"\bengine_a\x18\x01 \x01(\tR\aengineA\x12\x19\n" +
+	"\bengine_b\x18\x02 \x01(\tR\aengineB\x12%\n" +
+	"\x0ecompared_moves\x18\x03 \x01(\x05R\rcomparedMoves\x12(\n" +
+	"\x05moves\x18\x04 \x03(\v2\x12.analysis.MoveDiffR\x05moves\x121\n" +
+	"\x14classification_diffs\x18\x05 \x01(\x05R\x13classificationDiffs\x120\n" +
+	"\x14centipawn_loss_diffs\x18\x06 \x01(\x05R\x12centipawnLossDiffs\x120\n" +
+	"\x14white_accuracy_delta\x18\a \x01(\x02R\x12whiteAccuracyDelta\x120\n" +
+	"\x14black_accuracy_delta\x18\b \x01(\x02R\x12blackAccuracyDelta\x12&\n" +
+	"\x0fbest_move_diffs\x18\t \x01(\x05R\rbestMoveDiffs\x12\x17\n" +
+	"\adepth_a\x18\n" +
+	" \x01(\x05R\x06depthA\x12\x17\n" +
+	"\adepth_b\x18\v \x01(\x05R\x06depthB\x127\n" +
+	"\vwhite_delta\x18\f \x01(\v2\x16.analysis.MetricsDeltaR\n" +
+	"whiteDelta\x127\n" +
+	"\vblack_delta\x18\r \x01(\v2\x16.analysis.MetricsDeltaR\n" +
+	"blackDelta\"\xc9\x01\n" +
+	"\fMetricsDelta\x12\x1a\n" +
+	"\baccuracy\x18\x01 \x01(\x02R\baccuracy\x12\x12\n" +
+	"\x04acpl\x18\x02 \x01(\x02R\x04acpl\x12\x1a\n" +
+	"\bblunders\x18\x03 \x01(\x05R\bblunders\x12\x1a\n" +
+	"\bmistakes\x18\x04 \x01(\x05R\bmistakes\x12\"\n" +
+	"\finaccuracies\x18\x05 \x01(\x05R\finaccuracies\x12-\n" +
+	"\x12performance_rating\x18\x06 \x01(\x05R\x11performanceRating\"\x97\x02\n" +
+	"\x13DiffAnalysesRequest\x125\n" +
+	"\n" +
+	"analysis_a\x18\x01 \x01(\v2\x16.analysis.GameAnalysisR\tanalysisA\x125\n" +
+	"\n" +
+	"analysis_b\x18\x02 \x01(\v2\x16.analysis.GameAnalysisR\tanalysisB\x12*\n" +
+	"\x11cp_loss_threshold\x18\x03 \x01(\x05R\x0fcpLossThreshold\x12\x18\n" +
+	"\bjob_id_a\x18\x04 \x01(\tR\x06jobIdA\x12\x18\n" +
+	"\bjob_id_b\x18\x05 \x01(\tR\x06jobIdB\x122\n" +
+	"\x15allow_config_mismatch\x18\x06 \x01(\bR\x13allowConfigMismatch\"\x9a\x03\n" +
+	"\bMoveDiff\x12\x10\n" +
+	"\x03ply\x18\x01 \x01(\x05R\x03ply\x12\x1f\n" +
+	"\vmove_number\x18\x02 \x01(\x05R\n" +
+	"moveNumber\x12\x14\n" +
+	"\x05color\x18\x03 \x01(\tR\x05color\x12\x1f\n" +
+	"\vplayed_move\x18\x04 \x01(\tR\n" +
+	"playedMove\x12G\n" +
+	"\x10classification_a\x18\x05 \x01(\x0e2\x1c.analysis.MoveClassificationR\x0fclassificationA\x12G\n" +
+	"\x10classification_b\x18\x06 \x01(\x0e2\x1c.analysis.MoveClassificationR\x0fclassificationB\x12(\n" +
+	"\x10centipawn_loss_a\x18\a \x01(\x05R\x0ecentipawnLossA\x12(\n" +
+	"\x10centipawn_loss_b\x18\b \x01(\x05R\x0ecentipawnLossB\x12\x1e\n" +
+	"\vbest_move_a\x18\t \x01(\tR\tbestMoveA\x12\x1e\n" +
+	"\vbest_move_b\x18\n" +
+	" \x01(\tR\tbestMoveB\"\xff\x06\n" +
 	"\x14GameAnalysisProgress\x12\x17\n" +
 	"\agame_id\x18\x01 \x01(\tR\x06gameId\x12!\n" +
 	"\fcurrent_move\x18\x02 \x01(\x05R\vcurrentMove\x12\x1f\n" +
@@ -1276,7 +7404,37 @@ const file_proto_analysis_proto_rawDesc = "" +
 	"\x10progress_percent\x18\x04 \x01(\x02R\x0fprogressPercent\x12;\n" +
 	"\rmove_analysis\x18\x05 \x01(\v2\x16.analysis.MoveAnalysisR\fmoveAnalysis\x12\x16\n" +
 	"\x06status\x18\x06 \x01(\tR\x06status\x12#\n" +
-	"\rerror_message\x18\a \x01(\tR\ferrorMessage\"\x9c\x04\n" +
+	"\rerror_message\x18\a \x01(\tR\ferrorMessage\x12\x1c\n" +
+	"\theartbeat\x18\b \x01(\bR\theartbeat\x12\x1d\n" +
+	"\n" +
+	"elapsed_ms\x18\t \x01(\x03R\telapsedMs\x12%\n" +
+	"\x0equeue_position\x18\n" +
+	" \x01(\x05R\rqueuePosition\x12\x1b\n" +
+	"\ttimed_out\x18\v \x01(\bR\btimedOut\x12:\n" +
+	"\rwhite_metrics\x18\f \x01(\v2\x15.analysis.GameMetricsR\fwhiteMetrics\x12:\n" +
+	"\rblack_metrics\x18\r \x01(\v2\x15.analysis.GameMetricsR\fblackMetrics\x12)\n" +
+	"\x10chunks_completed\x18\x0e \x01(\x05R\x0fchunksCompleted\x12!\n" +
+	"\fchunks_total\x18\x0f \x01(\x05R\vchunksTotal\x12*\n" +
+	"\x11transcript_job_id\x18\x10 \x01(\tR\x0ftranscriptJobId\x122\n" +
+	"\bestimate\x18\x11 \x01(\v2\x16.analysis.GameEstimateR\bestimate\x12;\n" +
+	"\rbatched_moves\x18\x12 \x03(\v2\x16.analysis.MoveAnalysisR\fbatchedMoves\x12+\n" +
+	"\x11coalesced_updates\x18\x13 \x01(\x05R\x10coalescedUpdates\x12 \n" +
+	"\vpreliminary\x18\x14 \x01(\bR\vpreliminary\x122\n" +
+	"\banalysis\x18\x15 \x01(\v2\x16.analysis.GameAnalysisR\banalysis\"\xba\x02\n" +
+	"\fGameEstimate\x12\x1c\n" +
+	"\tpositions\x18\x01 \x01(\x05R\tpositions\x12\x1d\n" +
+	"\n" +
+	"cache_hits\x18\x02 \x01(\x05R\tcacheHits\x12\x1a\n" +
+	"\bsearches\x18\x03 \x01(\x05R\bsearches\x12\x1a\n" +
+	"\bsearched\x18\x04 \x01(\x05R\bsearched\x12+\n" +
+	"\x11engines_available\x18\x05 \x01(\x05R\x10enginesAvailable\x12\x1b\n" +
+	"\tpool_size\x18\x06 \x01(\x05R\bpoolSize\x12\x18\n" +
+	"\aworkers\x18\a \x01(\x05R\aworkers\x12\x1d\n" +
+	"\n" +
+	"cold_start\x18\b \x01(\bR\tcoldStart\x12\x15\n" +
+	"\x06eta_ms\x18\t \x01(\x03R\x05etaMs\x12\x1b\n" +
+	"\teta_known\x18\n" +
+	" \x01(\bR\betaKnown\"\xde\v\n" +
 	"\fMoveAnalysis\x12\x1f\n" +
 	"\vmove_number\x18\x01 \x01(\x05R\n" +
 	"moveNumber\x12\x10\n" +
@@ -1298,7 +7456,50 @@ const file_proto_analysis_proto_rawDesc = "" +
 	"\x0ecentipawn_loss\x18\f \x01(\x05R\rcentipawnLoss\x12D\n" +
 	"\x0eclassification\x18\r \x01(\x0e2\x1c.analysis.MoveClassificationR\x0eclassification\x12\x0e\n" +
 	"\x02pv\x18\x0e \x03(\tR\x02pv\x12\x14\n" +
-	"\x05depth\x18\x0f \x01(\x05R\x05depth\"\x98\x03\n" +
+	"\x05depth\x18\x0f \x01(\x05R\x05depth\x12#\n" +
+	"\rmove_accuracy\x18\x10 \x01(\x02R\fmoveAccuracy\x12\x16\n" +
+	"\x06forced\x18\x11 \x01(\bR\x06forced\x12'\n" +
+	"\x0frequested_depth\x18\x12 \x01(\x05R\x0erequestedDepth\x12\x1d\n" +
+	"\n" +
+	"from_cache\x18\x13 \x01(\bR\tfromCache\x12!\n" +
+	"\fgarbage_time\x18\x14 \x01(\bR\vgarbageTime\x12+\n" +
+	"\x11missed_repetition\x18\x15 \x01(\bR\x10missedRepetition\x12-\n" +
+	"\x12allowed_repetition\x18\x16 \x01(\bR\x11allowedRepetition\x12;\n" +
+	"\x0fmaterial_before\x18\x17 \x01(\v2\x12.analysis.MaterialR\x0ematerialBefore\x129\n" +
+	"\x0ematerial_after\x18\x18 \x01(\v2\x12.analysis.MaterialR\rmaterialAfter\x12/\n" +
+	"\x13material_sacrificed\x18\x19 \x01(\x05R\x12materialSacrificed\x12-\n" +
+	"\x13analyzed_at_unix_ms\x18\x1a \x01(\x03R\x10analyzedAtUnixMs\x12$\n" +
+	"\x0eengine_time_ms\x18\x1b \x01(\x03R\fengineTimeMs\x12\"\n" +
+	"\rqueue_time_ms\x18\x1c \x01(\x03R\vqueueTimeMs\x12$\n" +
+	"\x0esearch_time_ms\x18\x1d \x01(\x03R\fsearchTimeMs\x12\x1f\n" +
+	"\vmovetime_ms\x18\x1e \x01(\x03R\n" +
+	"movetimeMs\x12 \n" +
+	"\vexplanation\x18\x1f \x01(\tR\vexplanation\x120\n" +
+	"\x06source\x18  \x01(\x0e2\x18.analysis.AnalysisSourceR\x06source\x121\n" +
+	"\x14classification_glyph\x18! \x01(\tR\x13classificationGlyph\x121\n" +
+	"\x14classification_label\x18\" \x01(\tR\x13classificationLabel\x121\n" +
+	"\x14classification_color\x18# \x01(\tR\x13classificationColor\x120\n" +
+	"\x14expected_points_loss\x18$ \x01(\x02R\x12expectedPointsLoss\x124\n" +
+	"\n" +
+	"highlights\x18% \x01(\v2\x14.analysis.HighlightsR\n" +
+	"highlights\"\x9e\x01\n" +
+	"\n" +
+	"Highlights\x122\n" +
+	"\tbest_move\x18\x01 \x01(\v2\x15.analysis.MoveSquaresR\bbestMove\x125\n" +
+	"\n" +
+	"refutation\x18\x02 \x01(\v2\x15.analysis.MoveSquaresR\n" +
+	"refutation\x12%\n" +
+	"\x0ehanging_square\x18\x03 \x01(\tR\rhangingSquare\"\xa1\x01\n" +
+	"\vMoveSquares\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\x12\x1c\n" +
+	"\tpromotion\x18\x03 \x01(\tR\tpromotion\x12\x1b\n" +
+	"\trook_from\x18\x04 \x01(\tR\brookFrom\x12\x17\n" +
+	"\arook_to\x18\x05 \x01(\tR\x06rookTo\x12\x1a\n" +
+	"\bcaptured\x18\x06 \x01(\tR\bcaptured\"6\n" +
+	"\bMaterial\x12\x14\n" +
+	"\x05white\x18\x01 \x01(\x05R\x05white\x12\x14\n" +
+	"\x05black\x18\x02 \x01(\x05R\x05black\"\xb8\x05\n" +
 	"\vGameMetrics\x12\x1a\n" +
 	"\baccuracy\x18\x01 \x01(\x02R\baccuracy\x12\x12\n" +
 	"\x04acpl\x18\x02 \x01(\x02R\x04acpl\x12\x1a\n" +
@@ -1316,15 +7517,39 @@ const file_proto_analysis_proto_rawDesc = "" +
 	" \x01(\x05R\tbookMoves\x12\x1f\n" +
 	"\vtotal_moves\x18\v \x01(\x05R\n" +
 	"totalMoves\x12-\n" +
-	"\x12performance_rating\x18\f \x01(\x05R\x11performanceRating\"S\n" +
+	"\x12performance_rating\x18\f \x01(\x05R\x11performanceRating\x12,\n" +
+	"\x12garbage_time_moves\x18\r \x01(\x05R\x10garbageTimeMoves\x124\n" +
+	"\n" +
+	"resilience\x18\x0e \x01(\v2\x14.analysis.ResilienceR\n" +
+	"resilience\x12>\n" +
+	"\x0eaccuracy_model\x18\x0f \x01(\x0e2\x17.analysis.AccuracyModelR\raccuracyModel\x12'\n" +
+	"\x0faccuracy_stddev\x18\x10 \x01(\x02R\x0eaccuracyStddev\x12\x1f\n" +
+	"\vacpl_stddev\x18\x11 \x01(\x02R\n" +
+	"acplStddev\x120\n" +
+	"\x14expected_points_lost\x18\x12 \x01(\x02R\x12expectedPointsLost\"\x8a\x01\n" +
+	"\n" +
+	"Resilience\x12\x1a\n" +
+	"\bswindles\x18\x01 \x01(\x05R\bswindles\x12!\n" +
+	"\fbotched_wins\x18\x02 \x01(\x05R\vbotchedWins\x12\x14\n" +
+	"\x05gifts\x18\x03 \x01(\x05R\x05gifts\x12'\n" +
+	"\x0fgift_conversion\x18\x04 \x01(\x02R\x0egiftConversion\"S\n" +
 	"\x13GetBestMovesRequest\x12\x10\n" +
 	"\x03fen\x18\x01 \x01(\tR\x03fen\x12\x14\n" +
 	"\x05count\x18\x02 \x01(\x05R\x05count\x12\x14\n" +
-	"\x05depth\x18\x03 \x01(\x05R\x05depth\"e\n" +
+	"\x05depth\x18\x03 \x01(\x05R\x05depth\"\xe6\x02\n" +
 	"\x11BestMovesResponse\x12\x10\n" +
 	"\x03fen\x18\x01 \x01(\tR\x03fen\x12(\n" +
 	"\x05moves\x18\x02 \x03(\v2\x12.analysis.BestMoveR\x05moves\x12\x14\n" +
-	"\x05depth\x18\x03 \x01(\x05R\x05depth\"\x9a\x01\n" +
+	"\x05depth\x18\x03 \x01(\x05R\x05depth\x12\x1b\n" +
+	"\ttimed_out\x18\x04 \x01(\bR\btimedOut\x12\x19\n" +
+	"\bpv_count\x18\x05 \x01(\x05R\apvCount\x12\x14\n" +
+	"\x05notes\x18\x06 \x03(\tR\x05notes\x12(\n" +
+	"\x10game_over_reason\x18\a \x01(\tR\x0egameOverReason\x12!\n" +
+	"\ftarget_depth\x18\b \x01(\x05R\vtargetDepth\x12\x14\n" +
+	"\x05count\x18\t \x01(\x05R\x05count\x12&\n" +
+	"\x0fponder_move_uci\x18\n" +
+	" \x01(\tR\rponderMoveUci\x12&\n" +
+	"\x0fponder_move_san\x18\v \x01(\tR\rponderMoveSan\"\xc8\x01\n" +
 	"\bBestMove\x12\x12\n" +
 	"\x04rank\x18\x01 \x01(\x05R\x04rank\x12\x19\n" +
 	"\bmove_uci\x18\x02 \x01(\tR\amoveUci\x12\x19\n" +
@@ -1332,15 +7557,355 @@ const file_proto_analysis_proto_rawDesc = "" +
 	"\n" +
 	"evaluation\x18\x04 \x01(\v2\x14.analysis.EvaluationR\n" +
 	"evaluation\x12\x0e\n" +
-	"\x02pv\x18\x05 \x03(\tR\x02pv\"\x14\n" +
-	"\x12HealthCheckRequest\"\xed\x01\n" +
+	"\x02pv\x18\x05 \x03(\tR\x02pv\x12,\n" +
+	"\x12transposes_to_rank\x18\x06 \x01(\x05R\x10transposesToRank\"\x14\n" +
+	"\x12HealthCheckRequest\"\xb2\x02\n" +
 	"\x13HealthCheckResponse\x12\x18\n" +
 	"\ahealthy\x18\x01 \x01(\bR\ahealthy\x12\x16\n" +
 	"\x06status\x18\x02 \x01(\tR\x06status\x12+\n" +
 	"\x11available_workers\x18\x03 \x01(\x05R\x10availableWorkers\x12#\n" +
 	"\rtotal_workers\x18\x04 \x01(\x05R\ftotalWorkers\x12+\n" +
 	"\x11stockfish_version\x18\x05 \x01(\tR\x10stockfishVersion\x12%\n" +
-	"\x0euptime_seconds\x18\x06 \x01(\x03R\ruptimeSeconds*\xbd\x01\n" +
+	"\x0euptime_seconds\x18\x06 \x01(\x03R\ruptimeSeconds\x12\x1a\n" +
+	"\bdegraded\x18\a \x01(\bR\bdegraded\x12'\n" +
+	"\x10pool_wait_p95_ms\x18\b \x01(\x03R\rpoolWaitP95Ms\"\x17\n" +
+	"\x15GetServiceInfoRequest\"\xfb\x05\n" +
+	"\vServiceInfo\x12\x17\n" +
+	"\agit_sha\x18\x01 \x01(\tR\x06gitSha\x12\x1d\n" +
+	"\n" +
+	"build_time\x18\x02 \x01(\tR\tbuildTime\x12\x1d\n" +
+	"\n" +
+	"go_version\x18\x03 \x01(\tR\tgoVersion\x12+\n" +
+	"\x11stockfish_version\x18\x04 \x01(\tR\x10stockfishVersion\x12\x19\n" +
+	"\bnnue_net\x18\x05 \x01(\tR\annueNet\x12#\n" +
+	"\rdefault_depth\x18\x06 \x01(\x05R\fdefaultDepth\x12\x1b\n" +
+	"\tmax_depth\x18\a \x01(\x05R\bmaxDepth\x12B\n" +
+	"\n" +
+	"thresholds\x18\b \x01(\v2\".analysis.ClassificationThresholdsR\n" +
+	"thresholds\x12:\n" +
+	"\x19default_threshold_profile\x18\t \x01(\tR\x17defaultThresholdProfile\x12[\n" +
+	"\x12threshold_profiles\x18\n" +
+	" \x03(\v2,.analysis.ServiceInfo.ThresholdProfilesEntryR\x11thresholdProfiles\x12'\n" +
+	"\x0fengine_profiles\x18\v \x03(\tR\x0eengineProfiles\x12$\n" +
+	"\x0emax_game_plies\x18\f \x01(\x05R\fmaxGamePlies\x12&\n" +
+	"\x0ffast_mode_plies\x18\r \x01(\x05R\rfastModePlies\x12&\n" +
+	"\x0ffast_mode_depth\x18\x0e \x01(\x05R\rfastModeDepth\x12%\n" +
+	"\x0estockfish_path\x18\x0f \x01(\tR\rstockfishPath\x1ah\n" +
+	"\x16ThresholdProfilesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x128\n" +
+	"\x05value\x18\x02 \x01(\v2\".analysis.ClassificationThresholdsR\x05value:\x028\x01\"\xde\x01\n" +
+	"\x18ClassificationThresholds\x12\x12\n" +
+	"\x04best\x18\x01 \x01(\x05R\x04best\x12\x1c\n" +
+	"\texcellent\x18\x02 \x01(\x05R\texcellent\x12\x12\n" +
+	"\x04good\x18\x03 \x01(\x05R\x04good\x12\x1e\n" +
+	"\n" +
+	"inaccuracy\x18\x04 \x01(\x05R\n" +
+	"inaccuracy\x12\x18\n" +
+	"\amistake\x18\x05 \x01(\x05R\amistake\x12\x1f\n" +
+	"\vgarbage_win\x18\x06 \x01(\x05R\n" +
+	"garbageWin\x12!\n" +
+	"\fgarbage_loss\x18\a \x01(\x05R\vgarbageLoss\"\x91\x01\n" +
+	"\x19ExportGameAnalysisRequest\x122\n" +
+	"\banalysis\x18\x01 \x01(\v2\x16.analysis.GameAnalysisR\banalysis\x12\x10\n" +
+	"\x03pgn\x18\x02 \x01(\tR\x03pgn\x12.\n" +
+	"\x06format\x18\x03 \x01(\x0e2\x16.analysis.ExportFormatR\x06format\"Y\n" +
+	"\x1aExportGameAnalysisResponse\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\tR\acontent\x12!\n" +
+	"\fcontent_type\x18\x02 \x01(\tR\vcontentType\"\x94\x03\n" +
+	"\x17RecomputeMetricsRequest\x124\n" +
+	"\x05moves\x18\x01 \x03(\v2\x1e.analysis.StoredMoveEvaluationR\x05moves\x12+\n" +
+	"\x11threshold_profile\x18\x02 \x01(\tR\x10thresholdProfile\x12>\n" +
+	"\x0eaccuracy_model\x18\x03 \x01(\x0e2\x17.analysis.AccuracyModelR\raccuracyModel\x125\n" +
+	"\x14exclude_garbage_time\x18\x04 \x01(\bH\x00R\x12excludeGarbageTime\x88\x01\x01\x12\x16\n" +
+	"\x06result\x18\x05 \x01(\tR\x06result\x12D\n" +
+	"\x10eval_perspective\x18\x06 \x01(\x0e2\x19.analysis.EvalPerspectiveR\x0fevalPerspective\x12(\n" +
+	"\x10fen_start_offset\x18\a \x01(\x05R\x0efenStartOffsetB\x17\n" +
+	"\x15_exclude_garbage_time\"\x9f\x02\n" +
+	"\x14StoredMoveEvaluation\x12\x10\n" +
+	"\x03ply\x18\x01 \x01(\x05R\x03ply\x12\x14\n" +
+	"\x05color\x18\x02 \x01(\tR\x05color\x125\n" +
+	"\veval_before\x18\x03 \x01(\v2\x14.analysis.EvaluationR\n" +
+	"evalBefore\x123\n" +
+	"\n" +
+	"eval_after\x18\x04 \x01(\v2\x14.analysis.EvaluationR\tevalAfter\x12\x1b\n" +
+	"\tbest_move\x18\x05 \x01(\bR\bbestMove\x12\x12\n" +
+	"\x04book\x18\x06 \x01(\bR\x04book\x12!\n" +
+	"\fdepth_before\x18\a \x01(\x05R\vdepthBefore\x12\x1f\n" +
+	"\vdepth_after\x18\b \x01(\x05R\n" +
+	"depthAfter\"\x83\x02\n" +
+	"\x18RecomputeMetricsResponse\x12:\n" +
+	"\rwhite_metrics\x18\x01 \x01(\v2\x15.analysis.GameMetricsR\fwhiteMetrics\x12:\n" +
+	"\rblack_metrics\x18\x02 \x01(\v2\x15.analysis.GameMetricsR\fblackMetrics\x12+\n" +
+	"\x11threshold_profile\x18\x03 \x01(\tR\x10thresholdProfile\x12B\n" +
+	"\n" +
+	"thresholds\x18\x04 \x01(\v2\".analysis.ClassificationThresholdsR\n" +
+	"thresholds\"{\n" +
+	"\x18AggregateAnalysesRequest\x12\x16\n" +
+	"\x06player\x18\x01 \x01(\tR\x06player\x12,\n" +
+	"\x05games\x18\x02 \x03(\v2\x16.analysis.AnalyzedGameR\x05games\x12\x19\n" +
+	"\bgame_ids\x18\x03 \x03(\tR\agameIds\"\x9f\x02\n" +
+	"\fAnalyzedGame\x122\n" +
+	"\banalysis\x18\x01 \x01(\v2\x16.analysis.GameAnalysisR\banalysis\x12!\n" +
+	"\fwhite_player\x18\x02 \x01(\tR\vwhitePlayer\x12!\n" +
+	"\fblack_player\x18\x03 \x01(\tR\vblackPlayer\x12\x16\n" +
+	"\x06result\x18\x04 \x01(\tR\x06result\x12\x10\n" +
+	"\x03eco\x18\x05 \x01(\tR\x03eco\x12!\n" +
+	"\fopening_name\x18\x06 \x01(\tR\vopeningName\x12\x1d\n" +
+	"\n" +
+	"time_class\x18\a \x01(\tR\ttimeClass\x12)\n" +
+	"\x11played_at_unix_ms\x18\b \x01(\x03R\x0eplayedAtUnixMs\"\xd1\x06\n" +
+	"\fPlayerReport\x12\x16\n" +
+	"\x06player\x18\x01 \x01(\tR\x06player\x12\x14\n" +
+	"\x05games\x18\x02 \x01(\x05R\x05games\x12\x12\n" +
+	"\x04wins\x18\x03 \x01(\x05R\x04wins\x12\x14\n" +
+	"\x05draws\x18\x04 \x01(\x05R\x05draws\x12\x16\n" +
+	"\x06losses\x18\x05 \x01(\x05R\x06losses\x12$\n" +
+	"\x0egames_as_white\x18\x06 \x01(\x05R\fgamesAsWhite\x12$\n" +
+	"\x0egames_as_black\x18\a \x01(\x05R\fgamesAsBlack\x12#\n" +
+	"\rskipped_games\x18\b \x01(\x05R\fskippedGames\x12)\n" +
+	"\x10average_accuracy\x18\t \x01(\x02R\x0faverageAccuracy\x12\x12\n" +
+	"\x04acpl\x18\n" +
+	" \x01(\x02R\x04acpl\x12\x1f\n" +
+	"\vtotal_moves\x18\v \x01(\x05R\n" +
+	"totalMoves\x12\x1a\n" +
+	"\bblunders\x18\f \x01(\x05R\bblunders\x12!\n" +
+	"\fblunder_rate\x18\r \x01(\x02R\vblunderRate\x12;\n" +
+	"\ftime_classes\x18\x0e \x03(\v2\x18.analysis.TimeClassStatsR\vtimeClasses\x123\n" +
+	"\bopenings\x18\x0f \x03(\v2\x17.analysis.OpeningRecordR\bopenings\x12C\n" +
+	"\x10accuracy_buckets\x18\x10 \x03(\v2\x18.analysis.AccuracyBucketR\x0faccuracyBuckets\x12,\n" +
+	"\x06phases\x18\x11 \x03(\v2\x14.analysis.PhaseStatsR\x06phases\x12.\n" +
+	"\x05trend\x18\x12 \x03(\v2\x18.analysis.GameTrendPointR\x05trend\x12,\n" +
+	"\x12longest_win_streak\x18\x13 \x01(\x05R\x10longestWinStreak\x12.\n" +
+	"\x13longest_loss_streak\x18\x14 \x01(\x05R\x11longestLossStreak\x12%\n" +
+	"\x0ecurrent_streak\x18\x15 \x01(\x05R\rcurrentStreak\x12'\n" +
+	"\x0funknown_results\x18\x16 \x01(\x05R\x0eunknownResults\"\xb2\x01\n" +
+	"\x0eTimeClassStats\x12\x1d\n" +
+	"\n" +
+	"time_class\x18\x01 \x01(\tR\ttimeClass\x12\x14\n" +
+	"\x05games\x18\x02 \x01(\x05R\x05games\x12\x12\n" +
+	"\x04wins\x18\x03 \x01(\x05R\x04wins\x12\x14\n" +
+	"\x05draws\x18\x04 \x01(\x05R\x05draws\x12\x16\n" +
+	"\x06losses\x18\x05 \x01(\x05R\x06losses\x12)\n" +
+	"\x10average_accuracy\x18\x06 \x01(\x02R\x0faverageAccuracy\"\xce\x01\n" +
+	"\rOpeningRecord\x12\x10\n" +
+	"\x03eco\x18\x01 \x01(\tR\x03eco\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05games\x18\x03 \x01(\x05R\x05games\x12\x12\n" +
+	"\x04wins\x18\x04 \x01(\x05R\x04wins\x12\x14\n" +
+	"\x05draws\x18\x05 \x01(\x05R\x05draws\x12\x16\n" +
+	"\x06losses\x18\x06 \x01(\x05R\x06losses\x12\x14\n" +
+	"\x05score\x18\a \x01(\x02R\x05score\x12)\n" +
+	"\x10average_accuracy\x18\b \x01(\x02R\x0faverageAccuracy\"J\n" +
+	"\x0eAccuracyBucket\x12\x10\n" +
+	"\x03min\x18\x01 \x01(\x05R\x03min\x12\x10\n" +
+	"\x03max\x18\x02 \x01(\x05R\x03max\x12\x14\n" +
+	"\x05games\x18\x03 \x01(\x05R\x05games\"\xcb\x01\n" +
+	"\n" +
+	"PhaseStats\x12\x14\n" +
+	"\x05phase\x18\x01 \x01(\tR\x05phase\x12\x14\n" +
+	"\x05moves\x18\x02 \x01(\x05R\x05moves\x12\x1a\n" +
+	"\bblunders\x18\x03 \x01(\x05R\bblunders\x12\x1a\n" +
+	"\bmistakes\x18\x04 \x01(\x05R\bmistakes\x12\"\n" +
+	"\finaccuracies\x18\x05 \x01(\x05R\finaccuracies\x12!\n" +
+	"\fblunder_rate\x18\x06 \x01(\x02R\vblunderRate\x12\x12\n" +
+	"\x04acpl\x18\a \x01(\x02R\x04acpl\"\xc7\x01\n" +
+	"\x0eGameTrendPoint\x12\x17\n" +
+	"\agame_id\x18\x01 \x01(\tR\x06gameId\x12)\n" +
+	"\x11played_at_unix_ms\x18\x02 \x01(\x03R\x0eplayedAtUnixMs\x12\x1a\n" +
+	"\baccuracy\x18\x03 \x01(\x02R\baccuracy\x12\x1a\n" +
+	"\bblunders\x18\x04 \x01(\x05R\bblunders\x12!\n" +
+	"\fblunder_rate\x18\x05 \x01(\x02R\vblunderRate\x12\x16\n" +
+	"\x06result\x18\x06 \x01(\tR\x06result\"\x9c\x01\n" +
+	"\x18AggregateOpeningsRequest\x12\x16\n" +
+	"\x06player\x18\x01 \x01(\tR\x06player\x12,\n" +
+	"\x05games\x18\x02 \x03(\v2\x16.analysis.AnalyzedGameR\x05games\x12\x1b\n" +
+	"\tmin_games\x18\x03 \x01(\x05R\bminGames\x12\x1d\n" +
+	"\n" +
+	"last_games\x18\x04 \x01(\x05R\tlastGames\"\xb8\x01\n" +
+	"\x0eOpeningsReport\x12\x16\n" +
+	"\x06player\x18\x01 \x01(\tR\x06player\x12\x14\n" +
+	"\x05games\x18\x02 \x01(\x05R\x05games\x12\x1b\n" +
+	"\tmin_games\x18\x03 \x01(\x05R\bminGames\x12'\n" +
+	"\x0fhidden_openings\x18\x04 \x01(\x05R\x0ehiddenOpenings\x122\n" +
+	"\bopenings\x18\x05 \x03(\v2\x16.analysis.OpeningStatsR\bopenings\"\xf6\x02\n" +
+	"\fOpeningStats\x12\x16\n" +
+	"\x06family\x18\x01 \x01(\tR\x06family\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05color\x18\x03 \x01(\tR\x05color\x12\x14\n" +
+	"\x05games\x18\x04 \x01(\x05R\x05games\x12\x12\n" +
+	"\x04wins\x18\x05 \x01(\x05R\x04wins\x12\x14\n" +
+	"\x05draws\x18\x06 \x01(\x05R\x05draws\x12\x16\n" +
+	"\x06losses\x18\a \x01(\x05R\x06losses\x12#\n" +
+	"\rscore_percent\x18\b \x01(\x02R\fscorePercent\x12)\n" +
+	"\x10average_accuracy\x18\t \x01(\x02R\x0faverageAccuracy\x12'\n" +
+	"\x10out_of_book_acpl\x18\n" +
+	" \x01(\x02R\routOfBookAcpl\x12#\n" +
+	"\rtop_deviation\x18\v \x01(\tR\ftopDeviation\x12.\n" +
+	"\x13top_deviation_games\x18\f \x01(\x05R\x11topDeviationGames\"\x11\n" +
+	"\x0fGetQuotaRequest\"\xac\x01\n" +
+	"\n" +
+	"QuotaUsage\x12\x1c\n" +
+	"\tprincipal\x18\x01 \x01(\tR\tprincipal\x12\x17\n" +
+	"\aused_ms\x18\x02 \x01(\x03R\x06usedMs\x12\x19\n" +
+	"\blimit_ms\x18\x03 \x01(\x03R\alimitMs\x12!\n" +
+	"\fremaining_ms\x18\x04 \x01(\x03R\vremainingMs\x12)\n" +
+	"\x11resets_at_unix_ms\x18\x05 \x01(\x03R\x0eresetsAtUnixMs\"\x8c\x01\n" +
+	"\x12SetLogLevelRequest\x12\x14\n" +
+	"\x05level\x18\x01 \x01(\tR\x05level\x120\n" +
+	"\x14revert_after_seconds\x18\x02 \x01(\x05R\x12revertAfterSeconds\x12 \n" +
+	"\tuci_debug\x18\x03 \x01(\bH\x00R\buciDebug\x88\x01\x01B\f\n" +
+	"\n" +
+	"_uci_debug\"\x9a\x01\n" +
+	"\x13SetLogLevelResponse\x12%\n" +
+	"\x0eprevious_level\x18\x01 \x01(\tR\rpreviousLevel\x12\x14\n" +
+	"\x05level\x18\x02 \x01(\tR\x05level\x12)\n" +
+	"\x11revert_at_unix_ms\x18\x03 \x01(\x03R\x0erevertAtUnixMs\x12\x1b\n" +
+	"\tuci_debug\x18\x04 \x01(\bR\buciDebug\"B\n" +
+	"\x18ImportEvaluationsRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"\xad\x03\n" +
+	"\x19ImportEvaluationsResponse\x12\x1a\n" +
+	"\bimported\x18\x01 \x01(\x05R\bimported\x12\x18\n" +
+	"\askipped\x18\x02 \x01(\x05R\askipped\x12\x18\n" +
+	"\ainvalid\x18\x03 \x01(\x05R\ainvalid\x12J\n" +
+	"\asources\x18\x04 \x03(\v20.analysis.ImportEvaluationsResponse.SourcesEntryR\asources\x12\x16\n" +
+	"\x06errors\x18\x05 \x03(\tR\x06errors\x12^\n" +
+	"\x0fcache_by_source\x18\x06 \x03(\v26.analysis.ImportEvaluationsResponse.CacheBySourceEntryR\rcacheBySource\x1a:\n" +
+	"\fSourcesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\x1a@\n" +
+	"\x12CacheBySourceEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"\x19\n" +
+	"\x17GetAnalysisStatsRequest\"\xa2\x02\n" +
+	"\rAnalysisStats\x12:\n" +
+	"\rdepth_timings\x18\x01 \x03(\v2\x15.analysis.DepthTimingR\fdepthTimings\x127\n" +
+	"\vdegradation\x18\x02 \x01(\v2\x15.analysis.DegradationR\vdegradation\x12.\n" +
+	"\x13histogram_bounds_ms\x18\x03 \x03(\x03R\x11histogramBoundsMs\x128\n" +
+	"\n" +
+	"pool_usage\x18\x04 \x01(\v2\x19.analysis.EnginePoolUsageR\tpoolUsage\x122\n" +
+	"\n" +
+	"eval_drift\x18\x05 \x01(\v2\x13.analysis.EvalDriftR\tevalDrift\"\x89\x01\n" +
+	"\x0fEnginePoolUsage\x12\x17\n" +
+	"\abusy_ms\x18\x01 \x01(\x03R\x06busyMs\x12,\n" +
+	"\x04tags\x18\x02 \x03(\v2\x18.analysis.EngineTagUsageR\x04tags\x12/\n" +
+	"\aengines\x18\x03 \x03(\v2\x15.analysis.EngineUsageR\aengines\"~\n" +
+	"\x0eEngineTagUsage\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x12\x17\n" +
+	"\abusy_ms\x18\x02 \x01(\x03R\x06busyMs\x12\x14\n" +
+	"\x05share\x18\x03 \x01(\x01R\x05share\x12\x14\n" +
+	"\x05loans\x18\x04 \x01(\x03R\x05loans\x12\x15\n" +
+	"\x06in_use\x18\x05 \x01(\x05R\x05inUse\"\x94\x01\n" +
+	"\vEngineUsage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12:\n" +
+	"\abusy_ms\x18\x02 \x03(\v2!.analysis.EngineUsage.BusyMsEntryR\x06busyMs\x1a9\n" +
+	"\vBusyMsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\xcc\x02\n" +
+	"\tEvalDrift\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x15\n" +
+	"\x06p50_cp\x18\x02 \x01(\x05R\x05p50Cp\x12\x15\n" +
+	"\x06p95_cp\x18\x03 \x01(\x05R\x05p95Cp\x12\x18\n" +
+	"\asamples\x18\x04 \x01(\x05R\asamples\x12\x18\n" +
+	"\asampled\x18\x05 \x01(\x03R\asampled\x12\x16\n" +
+	"\x06failed\x18\x06 \x01(\x03R\x06failed\x12\x1d\n" +
+	"\n" +
+	"backed_off\x18\a \x01(\x03R\tbackedOff\x12\x1c\n" +
+	"\n" +
+	"max_p95_cp\x18\b \x01(\x05R\bmaxP95Cp\x12\x18\n" +
+	"\aflushes\x18\t \x01(\x03R\aflushes\x12'\n" +
+	"\x0fflushed_entries\x18\n" +
+	" \x01(\x03R\x0eflushedEntries\x12+\n" +
+	"\x12last_flush_unix_ms\x18\v \x01(\x03R\x0flastFlushUnixMs\"\x85\x02\n" +
+	"\vDegradation\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x16\n" +
+	"\x06active\x18\x02 \x01(\bR\x06active\x12\"\n" +
+	"\rsince_unix_ms\x18\x03 \x01(\x03R\vsinceUnixMs\x12'\n" +
+	"\x10pool_wait_p95_ms\x18\x04 \x01(\x03R\rpoolWaitP95Ms\x12\x18\n" +
+	"\asamples\x18\x05 \x01(\x05R\asamples\x12\x14\n" +
+	"\x05depth\x18\x06 \x01(\x05R\x05depth\x12 \n" +
+	"\vactivations\x18\a \x01(\x03R\vactivations\x12%\n" +
+	"\x0edegraded_games\x18\b \x01(\x03R\rdegradedGames\"\x88\x02\n" +
+	"\vDepthTiming\x12\x1b\n" +
+	"\tmin_depth\x18\x01 \x01(\x05R\bminDepth\x12\x1b\n" +
+	"\tmax_depth\x18\x02 \x01(\x05R\bmaxDepth\x12&\n" +
+	"\x0fms_per_position\x18\x03 \x01(\x01R\rmsPerPosition\x12\x1a\n" +
+	"\bsearches\x18\x04 \x01(\x03R\bsearches\x12\x14\n" +
+	"\x05phase\x18\x05 \x01(\tR\x05phase\x12\x18\n" +
+	"\asamples\x18\x06 \x01(\x01R\asamples\x12\x1c\n" +
+	"\thistogram\x18\a \x03(\x01R\thistogram\x12-\n" +
+	"\x06phases\x18\b \x03(\v2\x15.analysis.DepthTimingR\x06phases\"W\n" +
+	"\x1aGetCapacityEstimateRequest\x12\x14\n" +
+	"\x05depth\x18\x01 \x01(\x05R\x05depth\x12#\n" +
+	"\raverage_moves\x18\x02 \x01(\x05R\faverageMoves\"\xc9\x03\n" +
+	"\x10CapacityEstimate\x12\x14\n" +
+	"\x05depth\x18\x01 \x01(\x05R\x05depth\x12\x1b\n" +
+	"\tmin_depth\x18\x02 \x01(\x05R\bminDepth\x12\x1b\n" +
+	"\tmax_depth\x18\x03 \x01(\x05R\bmaxDepth\x12#\n" +
+	"\raverage_moves\x18\x04 \x01(\x05R\faverageMoves\x12,\n" +
+	"\x12positions_per_game\x18\x05 \x01(\x05R\x10positionsPerGame\x12\x18\n" +
+	"\aengines\x18\x06 \x01(\x05R\aengines\x12&\n" +
+	"\x0fms_per_position\x18\a \x01(\x01R\rmsPerPosition\x12-\n" +
+	"\x13p90_ms_per_position\x18\b \x01(\x01R\x10p90MsPerPosition\x12$\n" +
+	"\x0egames_per_hour\x18\t \x01(\x01R\fgamesPerHour\x12+\n" +
+	"\x12games_per_hour_p90\x18\n" +
+	" \x01(\x01R\x0fgamesPerHourP90\x12\x18\n" +
+	"\asamples\x18\v \x01(\x01R\asamples\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\f \x01(\x01R\n" +
+	"confidence\x12\x14\n" +
+	"\x05known\x18\r \x01(\bR\x05known\"E\n" +
+	"\x1aGetEngineTranscriptRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x10\n" +
+	"\x03ply\x18\x02 \x01(\x05R\x03ply\"s\n" +
+	"\x10EngineTranscript\x12\x14\n" +
+	"\x05lines\x18\x01 \x03(\tR\x05lines\x12\x1c\n" +
+	"\ttruncated\x18\x02 \x01(\bR\ttruncated\x12+\n" +
+	"\x12expires_at_unix_ms\x18\x03 \x01(\x03R\x0fexpiresAtUnixMs\"g\n" +
+	"\x10WarmCacheRequest\x12\x12\n" +
+	"\x04fens\x18\x01 \x03(\tR\x04fens\x12\x10\n" +
+	"\x03pgn\x18\x02 \x01(\tR\x03pgn\x12\x14\n" +
+	"\x05depth\x18\x03 \x01(\x05R\x05depth\x12\x17\n" +
+	"\adry_run\x18\x04 \x01(\bR\x06dryRun\"\xd8\x02\n" +
+	"\x11WarmCacheProgress\x12\x14\n" +
+	"\x05depth\x18\x01 \x01(\x05R\x05depth\x12\x1c\n" +
+	"\tpositions\x18\x02 \x01(\x05R\tpositions\x12\x1e\n" +
+	"\n" +
+	"duplicates\x18\x03 \x01(\x05R\n" +
+	"duplicates\x12\x16\n" +
+	"\x06cached\x18\x04 \x01(\x05R\x06cached\x12\x1b\n" +
+	"\tgame_over\x18\x05 \x01(\x05R\bgameOver\x12\x18\n" +
+	"\apending\x18\x06 \x01(\x05R\apending\x12\x1a\n" +
+	"\banalyzed\x18\a \x01(\x05R\banalyzed\x12\x16\n" +
+	"\x06failed\x18\b \x01(\x05R\x06failed\x12!\n" +
+	"\festimated_ms\x18\t \x01(\x03R\vestimatedMs\x12\x1c\n" +
+	"\testimated\x18\n" +
+	" \x01(\bR\testimated\x12\x12\n" +
+	"\x04done\x18\v \x01(\bR\x04done\x12\x17\n" +
+	"\adry_run\x18\f \x01(\bR\x06dryRun*\x9a\x01\n" +
+	"\x0eAnalysisSource\x12\x1f\n" +
+	"\x1bANALYSIS_SOURCE_UNSPECIFIED\x10\x00\x12\n" +
+	"\n" +
+	"\x06ENGINE\x10\x01\x12\t\n" +
+	"\x05CACHE\x10\x02\x12\f\n" +
+	"\bIMPORTED\x10\x03\x12\x10\n" +
+	"\fBOOK_SKIPPED\x10\x04\x12\x12\n" +
+	"\x0eFORCED_SKIPPED\x10\x05\x12\n" +
+	"\n" +
+	"\x06FAILED\x10\x06\x12\x10\n" +
+	"\fOUT_OF_RANGE\x10\a*\xc7\x01\n" +
+	"\vTermination\x12\x17\n" +
+	"\x13TERMINATION_UNKNOWN\x10\x00\x12\r\n" +
+	"\tCHECKMATE\x10\x01\x12\x0f\n" +
+	"\vRESIGNATION\x10\x02\x12\x10\n" +
+	"\fTIME_FORFEIT\x10\x03\x12\x0f\n" +
+	"\vABANDONMENT\x10\x04\x12\r\n" +
+	"\tSTALEMATE\x10\x05\x12\x19\n" +
+	"\x15INSUFFICIENT_MATERIAL\x10\x06\x12\x0e\n" +
+	"\n" +
+	"REPETITION\x10\a\x12\x13\n" +
+	"\x0fFIFTY_MOVE_RULE\x10\b\x12\r\n" +
+	"\tAGREEMENT\x10\t*\xbd\x01\n" +
 	"\x12MoveClassification\x12\x1a\n" +
 	"\x16CLASSIFICATION_UNKNOWN\x10\x00\x12\r\n" +
 	"\tBRILLIANT\x10\x01\x12\t\n" +
@@ -1357,14 +7922,42 @@ const file_proto_analysis_proto_rawDesc = "" +
 	"\aBLUNDER\x10\n" +
 	"\x12\x0e\n" +
 	"\n" +
-	"MISSED_WIN\x10\v2\xed\x03\n" +
+	"MISSED_WIN\x10\v*P\n" +
+	"\x0fEvalPerspective\x12 \n" +
+	"\x1cEVAL_PERSPECTIVE_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fSIDE_TO_MOVE\x10\x01\x12\t\n" +
+	"\x05WHITE\x10\x02*a\n" +
+	"\rAccuracyModel\x12\x1e\n" +
+	"\x1aACCURACY_MODEL_UNSPECIFIED\x10\x00\x12\x0e\n" +
+	"\n" +
+	"ELOINSIGHT\x10\x01\x12\v\n" +
+	"\aLICHESS\x10\x02\x12\x13\n" +
+	"\x0fCHESSCOM_APPROX\x10\x03*J\n" +
+	"\fExportFormat\x12\x1d\n" +
+	"\x19EXPORT_FORMAT_UNSPECIFIED\x10\x00\x12\x11\n" +
+	"\rANNOTATED_PGN\x10\x01\x12\b\n" +
+	"\x04JSON\x10\x022\x9b\b\n" +
 	"\x0fAnalysisService\x12O\n" +
 	"\x0fAnalyzePosition\x12 .analysis.AnalyzePositionRequest\x1a\x1a.analysis.PositionAnalysis\x12W\n" +
 	"\x15AnalyzePositionStream\x12 .analysis.AnalyzePositionRequest\x1a\x1a.analysis.PositionAnalysis0\x01\x12C\n" +
 	"\vAnalyzeGame\x12\x1c.analysis.AnalyzeGameRequest\x1a\x16.analysis.GameAnalysis\x12S\n" +
 	"\x11AnalyzeGameStream\x12\x1c.analysis.AnalyzeGameRequest\x1a\x1e.analysis.GameAnalysisProgress0\x01\x12J\n" +
 	"\fGetBestMoves\x12\x1d.analysis.GetBestMovesRequest\x1a\x1b.analysis.BestMovesResponse\x12J\n" +
-	"\vHealthCheck\x12\x1c.analysis.HealthCheckRequest\x1a\x1d.analysis.HealthCheckResponseB.Z,github.com/eloinsight/analysis-service/protob\x06proto3"
+	"\vHealthCheck\x12\x1c.analysis.HealthCheckRequest\x1a\x1d.analysis.HealthCheckResponse\x12H\n" +
+	"\x0eGetServiceInfo\x12\x1f.analysis.GetServiceInfoRequest\x1a\x15.analysis.ServiceInfo\x12_\n" +
+	"\x12ExportGameAnalysis\x12#.analysis.ExportGameAnalysisRequest\x1a$.analysis.ExportGameAnalysisResponse\x12O\n" +
+	"\x11AggregateAnalyses\x12\".analysis.AggregateAnalysesRequest\x1a\x16.analysis.PlayerReport\x12Q\n" +
+	"\x11AggregateOpenings\x12\".analysis.AggregateOpeningsRequest\x1a\x18.analysis.OpeningsReport\x12E\n" +
+	"\fDiffAnalyses\x12\x1d.analysis.DiffAnalysesRequest\x1a\x16.analysis.AnalysisDiff\x12Y\n" +
+	"\x10RecomputeMetrics\x12!.analysis.RecomputeMetricsRequest\x1a\".analysis.RecomputeMetricsResponse\x12;\n" +
+	"\bGetQuota\x12\x19.analysis.GetQuotaRequest\x1a\x14.analysis.QuotaUsage2\x82\x04\n" +
+	"\fAdminService\x12J\n" +
+	"\vSetLogLevel\x12\x1c.analysis.SetLogLevelRequest\x1a\x1d.analysis.SetLogLevelResponse\x12\\\n" +
+	"\x11ImportEvaluations\x12\".analysis.ImportEvaluationsRequest\x1a#.analysis.ImportEvaluationsResponse\x12N\n" +
+	"\x10GetAnalysisStats\x12!.analysis.GetAnalysisStatsRequest\x1a\x17.analysis.AnalysisStats\x12W\n" +
+	"\x13GetCapacityEstimate\x12$.analysis.GetCapacityEstimateRequest\x1a\x1a.analysis.CapacityEstimate\x12W\n" +
+	"\x13GetEngineTranscript\x12$.analysis.GetEngineTranscriptRequest\x1a\x1a.analysis.EngineTranscript\x12F\n" +
+	"\tWarmCache\x12\x1a.analysis.WarmCacheRequest\x1a\x1b.analysis.WarmCacheProgress0\x01B.Z,github.com/eloinsight/analysis-service/protob\x06proto3"
 
 var (
 	file_proto_analysis_proto_rawDescOnce sync.Once
@@ -1378,52 +7971,214 @@ func file_proto_analysis_proto_rawDescGZIP() []byte {
 	return file_proto_analysis_proto_rawDescData
 }
 
-var file_proto_analysis_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_proto_analysis_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_proto_analysis_proto_enumTypes = make([]protoimpl.EnumInfo, 6)
+var file_proto_analysis_proto_msgTypes = make([]protoimpl.MessageInfo, 74)
 var file_proto_analysis_proto_goTypes = []any{
-	(MoveClassification)(0),        // 0: analysis.MoveClassification
-	(*AnalyzePositionRequest)(nil), // 1: analysis.AnalyzePositionRequest
-	(*PositionAnalysis)(nil),       // 2: analysis.PositionAnalysis
-	(*Evaluation)(nil),             // 3: analysis.Evaluation
-	(*AnalyzeGameRequest)(nil),     // 4: analysis.AnalyzeGameRequest
-	(*GameAnalysis)(nil),           // 5: analysis.GameAnalysis
-	(*GameAnalysisProgress)(nil),   // 6: analysis.GameAnalysisProgress
-	(*MoveAnalysis)(nil),           // 7: analysis.MoveAnalysis
-	(*GameMetrics)(nil),            // 8: analysis.GameMetrics
-	(*GetBestMovesRequest)(nil),    // 9: analysis.GetBestMovesRequest
-	(*BestMovesResponse)(nil),      // 10: analysis.BestMovesResponse
-	(*BestMove)(nil),               // 11: analysis.BestMove
-	(*HealthCheckRequest)(nil),     // 12: analysis.HealthCheckRequest
-	(*HealthCheckResponse)(nil),    // 13: analysis.HealthCheckResponse
+	(AnalysisSource)(0),                // 0: analysis.AnalysisSource
+	(Termination)(0),                   // 1: analysis.Termination
+	(MoveClassification)(0),            // 2: analysis.MoveClassification
+	(EvalPerspective)(0),               // 3: analysis.EvalPerspective
+	(AccuracyModel)(0),                 // 4: analysis.AccuracyModel
+	(ExportFormat)(0),                  // 5: analysis.ExportFormat
+	(*AnalyzePositionRequest)(nil),     // 6: analysis.AnalyzePositionRequest
+	(*PositionAnalysis)(nil),           // 7: analysis.PositionAnalysis
+	(*Evaluation)(nil),                 // 8: analysis.Evaluation
+	(*AnalyzeGameRequest)(nil),         // 9: analysis.AnalyzeGameRequest
+	(*PrefixEvaluation)(nil),           // 10: analysis.PrefixEvaluation
+	(*GameAnalysis)(nil),               // 11: analysis.GameAnalysis
+	(*GameInfo)(nil),                   // 12: analysis.GameInfo
+	(*AnalysisConfigSnapshot)(nil),     // 13: analysis.AnalysisConfigSnapshot
+	(*AnalysisDiagnostics)(nil),        // 14: analysis.AnalysisDiagnostics
+	(*TimeManagement)(nil),             // 15: analysis.TimeManagement
+	(*StoredAnalysis)(nil),             // 16: analysis.StoredAnalysis
+	(*CrossCheck)(nil),                 // 17: analysis.CrossCheck
+	(*AnalysisDiff)(nil),               // 18: analysis.AnalysisDiff
+	(*MetricsDelta)(nil),               // 19: analysis.MetricsDelta
+	(*DiffAnalysesRequest)(nil),        // 20: analysis.DiffAnalysesRequest
+	(*MoveDiff)(nil),                   // 21: analysis.MoveDiff
+	(*GameAnalysisProgress)(nil),       // 22: analysis.GameAnalysisProgress
+	(*GameEstimate)(nil),               // 23: analysis.GameEstimate
+	(*MoveAnalysis)(nil),               // 24: analysis.MoveAnalysis
+	(*Highlights)(nil),                 // 25: analysis.Highlights
+	(*MoveSquares)(nil),                // 26: analysis.MoveSquares
+	(*Material)(nil),                   // 27: analysis.Material
+	(*GameMetrics)(nil),                // 28: analysis.GameMetrics
+	(*Resilience)(nil),                 // 29: analysis.Resilience
+	(*GetBestMovesRequest)(nil),        // 30: analysis.GetBestMovesRequest
+	(*BestMovesResponse)(nil),          // 31: analysis.BestMovesResponse
+	(*BestMove)(nil),                   // 32: analysis.BestMove
+	(*HealthCheckRequest)(nil),         // 33: analysis.HealthCheckRequest
+	(*HealthCheckResponse)(nil),        // 34: analysis.HealthCheckResponse
+	(*GetServiceInfoRequest)(nil),      // 35: analysis.GetServiceInfoRequest
+	(*ServiceInfo)(nil),                // 36: analysis.ServiceInfo
+	(*ClassificationThresholds)(nil),   // 37: analysis.ClassificationThresholds
+	(*ExportGameAnalysisRequest)(nil),  // 38: analysis.ExportGameAnalysisRequest
+	(*ExportGameAnalysisResponse)(nil), // 39: analysis.ExportGameAnalysisResponse
+	(*RecomputeMetricsRequest)(nil),    // 40: analysis.RecomputeMetricsRequest
+	(*StoredMoveEvaluation)(nil),       // 41: analysis.StoredMoveEvaluation
+	(*RecomputeMetricsResponse)(nil),   // 42: analysis.RecomputeMetricsResponse
+	(*AggregateAnalysesRequest)(nil),   // 43: analysis.AggregateAnalysesRequest
+	(*AnalyzedGame)(nil),               // 44: analysis.AnalyzedGame
+	(*PlayerReport)(nil),               // 45: analysis.PlayerReport
+	(*TimeClassStats)(nil),             // 46: analysis.TimeClassStats
+	(*OpeningRecord)(nil),              // 47: analysis.OpeningRecord
+	(*AccuracyBucket)(nil),             // 48: analysis.AccuracyBucket
+	(*PhaseStats)(nil),                 // 49: analysis.PhaseStats
+	(*GameTrendPoint)(nil),             // 50: analysis.GameTrendPoint
+	(*AggregateOpeningsRequest)(nil),   // 51: analysis.AggregateOpeningsRequest
+	(*OpeningsReport)(nil),             // 52: analysis.OpeningsReport
+	(*OpeningStats)(nil),               // 53: analysis.OpeningStats
+	(*GetQuotaRequest)(nil),            // 54: analysis.GetQuotaRequest
+	(*QuotaUsage)(nil),                 // 55: analysis.QuotaUsage
+	(*SetLogLevelRequest)(nil),         // 56: analysis.SetLogLevelRequest
+	(*SetLogLevelResponse)(nil),        // 57: analysis.SetLogLevelResponse
+	(*ImportEvaluationsRequest)(nil),   // 58: analysis.ImportEvaluationsRequest
+	(*ImportEvaluationsResponse)(nil),  // 59: analysis.ImportEvaluationsResponse
+	(*GetAnalysisStatsRequest)(nil),    // 60: analysis.GetAnalysisStatsRequest
+	(*AnalysisStats)(nil),              // 61: analysis.AnalysisStats
+	(*EnginePoolUsage)(nil),            // 62: analysis.EnginePoolUsage
+	(*EngineTagUsage)(nil),             // 63: analysis.EngineTagUsage
+	(*EngineUsage)(nil),                // 64: analysis.EngineUsage
+	(*EvalDrift)(nil),                  // 65: analysis.EvalDrift
+	(*Degradation)(nil),                // 66: analysis.Degradation
+	(*DepthTiming)(nil),                // 67: analysis.DepthTiming
+	(*GetCapacityEstimateRequest)(nil), // 68: analysis.GetCapacityEstimateRequest
+	(*CapacityEstimate)(nil),           // 69: analysis.CapacityEstimate
+	(*GetEngineTranscriptRequest)(nil), // 70: analysis.GetEngineTranscriptRequest
+	(*EngineTranscript)(nil),           // 71: analysis.EngineTranscript
+	(*WarmCacheRequest)(nil),           // 72: analysis.WarmCacheRequest
+	(*WarmCacheProgress)(nil),          // 73: analysis.WarmCacheProgress
+	nil,                                // 74: analysis.GameAnalysis.SourceCountsEntry
+	nil,                                // 75: analysis.AnalysisDiagnostics.FailuresEntry
+	nil,                                // 76: analysis.ServiceInfo.ThresholdProfilesEntry
+	nil,                                // 77: analysis.ImportEvaluationsResponse.SourcesEntry
+	nil,                                // 78: analysis.ImportEvaluationsResponse.CacheBySourceEntry
+	nil,                                // 79: analysis.EngineUsage.BusyMsEntry
 }
 var file_proto_analysis_proto_depIdxs = []int32{
-	3,  // 0: analysis.PositionAnalysis.evaluation:type_name -> analysis.Evaluation
-	7,  // 1: analysis.GameAnalysis.moves:type_name -> analysis.MoveAnalysis
-	8,  // 2: analysis.GameAnalysis.white_metrics:type_name -> analysis.GameMetrics
-	8,  // 3: analysis.GameAnalysis.black_metrics:type_name -> analysis.GameMetrics
-	7,  // 4: analysis.GameAnalysisProgress.move_analysis:type_name -> analysis.MoveAnalysis
-	3,  // 5: analysis.MoveAnalysis.eval_before:type_name -> analysis.Evaluation
-	3,  // 6: analysis.MoveAnalysis.eval_after:type_name -> analysis.Evaluation
-	0,  // 7: analysis.MoveAnalysis.classification:type_name -> analysis.MoveClassification
-	11, // 8: analysis.BestMovesResponse.moves:type_name -> analysis.BestMove
-	3,  // 9: analysis.BestMove.evaluation:type_name -> analysis.Evaluation
-	1,  // 10: analysis.AnalysisService.AnalyzePosition:input_type -> analysis.AnalyzePositionRequest
-	1,  // 11: analysis.AnalysisService.AnalyzePositionStream:input_type -> analysis.AnalyzePositionRequest
-	4,  // 12: analysis.AnalysisService.AnalyzeGame:input_type -> analysis.AnalyzeGameRequest
-	4,  // 13: analysis.AnalysisService.AnalyzeGameStream:input_type -> analysis.AnalyzeGameRequest
-	9,  // 14: analysis.AnalysisService.GetBestMoves:input_type -> analysis.GetBestMovesRequest
-	12, // 15: analysis.AnalysisService.HealthCheck:input_type -> analysis.HealthCheckRequest
-	2,  // 16: analysis.AnalysisService.AnalyzePosition:output_type -> analysis.PositionAnalysis
-	2,  // 17: analysis.AnalysisService.AnalyzePositionStream:output_type -> analysis.PositionAnalysis
-	5,  // 18: analysis.AnalysisService.AnalyzeGame:output_type -> analysis.GameAnalysis
-	6,  // 19: analysis.AnalysisService.AnalyzeGameStream:output_type -> analysis.GameAnalysisProgress
-	10, // 20: analysis.AnalysisService.GetBestMoves:output_type -> analysis.BestMovesResponse
-	13, // 21: analysis.AnalysisService.HealthCheck:output_type -> analysis.HealthCheckResponse
-	16, // [16:22] is the sub-list for method output_type
-	10, // [10:16] is the sub-list for method input_type
-	10, // [10:10] is the sub-list for extension type_name
-	10, // [10:10] is the sub-list for extension extendee
-	0,  // [0:10] is the sub-list for field type_name
+	8,  // 0: analysis.PositionAnalysis.evaluation:type_name -> analysis.Evaluation
+	3,  // 1: analysis.AnalyzeGameRequest.eval_perspective:type_name -> analysis.EvalPerspective
+	10, // 2: analysis.AnalyzeGameRequest.prefix_evaluations:type_name -> analysis.PrefixEvaluation
+	4,  // 3: analysis.AnalyzeGameRequest.accuracy_model:type_name -> analysis.AccuracyModel
+	8,  // 4: analysis.PrefixEvaluation.evaluation:type_name -> analysis.Evaluation
+	24, // 5: analysis.GameAnalysis.moves:type_name -> analysis.MoveAnalysis
+	28, // 6: analysis.GameAnalysis.white_metrics:type_name -> analysis.GameMetrics
+	28, // 7: analysis.GameAnalysis.black_metrics:type_name -> analysis.GameMetrics
+	37, // 8: analysis.GameAnalysis.thresholds:type_name -> analysis.ClassificationThresholds
+	17, // 9: analysis.GameAnalysis.cross_check:type_name -> analysis.CrossCheck
+	3,  // 10: analysis.GameAnalysis.eval_perspective:type_name -> analysis.EvalPerspective
+	16, // 11: analysis.GameAnalysis.stored:type_name -> analysis.StoredAnalysis
+	15, // 12: analysis.GameAnalysis.white_time:type_name -> analysis.TimeManagement
+	15, // 13: analysis.GameAnalysis.black_time:type_name -> analysis.TimeManagement
+	14, // 14: analysis.GameAnalysis.diagnostics:type_name -> analysis.AnalysisDiagnostics
+	13, // 15: analysis.GameAnalysis.config:type_name -> analysis.AnalysisConfigSnapshot
+	74, // 16: analysis.GameAnalysis.source_counts:type_name -> analysis.GameAnalysis.SourceCountsEntry
+	12, // 17: analysis.GameAnalysis.game_info:type_name -> analysis.GameInfo
+	1,  // 18: analysis.GameAnalysis.termination:type_name -> analysis.Termination
+	75, // 19: analysis.AnalysisDiagnostics.failures:type_name -> analysis.AnalysisDiagnostics.FailuresEntry
+	11, // 20: analysis.CrossCheck.secondary:type_name -> analysis.GameAnalysis
+	18, // 21: analysis.CrossCheck.diff:type_name -> analysis.AnalysisDiff
+	21, // 22: analysis.AnalysisDiff.moves:type_name -> analysis.MoveDiff
+	19, // 23: analysis.AnalysisDiff.white_delta:type_name -> analysis.MetricsDelta
+	19, // 24: analysis.AnalysisDiff.black_delta:type_name -> analysis.MetricsDelta
+	11, // 25: analysis.DiffAnalysesRequest.analysis_a:type_name -> analysis.GameAnalysis
+	11, // 26: analysis.DiffAnalysesRequest.analysis_b:type_name -> analysis.GameAnalysis
+	2,  // 27: analysis.MoveDiff.classification_a:type_name -> analysis.MoveClassification
+	2,  // 28: analysis.MoveDiff.classification_b:type_name -> analysis.MoveClassification
+	24, // 29: analysis.GameAnalysisProgress.move_analysis:type_name -> analysis.MoveAnalysis
+	28, // 30: analysis.GameAnalysisProgress.white_metrics:type_name -> analysis.GameMetrics
+	28, // 31: analysis.GameAnalysisProgress.black_metrics:type_name -> analysis.GameMetrics
+	23, // 32: analysis.GameAnalysisProgress.estimate:type_name -> analysis.GameEstimate
+	24, // 33: analysis.GameAnalysisProgress.batched_moves:type_name -> analysis.MoveAnalysis
+	11, // 34: analysis.GameAnalysisProgress.analysis:type_name -> analysis.GameAnalysis
+	8,  // 35: analysis.MoveAnalysis.eval_before:type_name -> analysis.Evaluation
+	8,  // 36: analysis.MoveAnalysis.eval_after:type_name -> analysis.Evaluation
+	2,  // 37: analysis.MoveAnalysis.classification:type_name -> analysis.MoveClassification
+	27, // 38: analysis.MoveAnalysis.material_before:type_name -> analysis.Material
+	27, // 39: analysis.MoveAnalysis.material_after:type_name -> analysis.Material
+	0,  // 40: analysis.MoveAnalysis.source:type_name -> analysis.AnalysisSource
+	25, // 41: analysis.MoveAnalysis.highlights:type_name -> analysis.Highlights
+	26, // 42: analysis.Highlights.best_move:type_name -> analysis.MoveSquares
+	26, // 43: analysis.Highlights.refutation:type_name -> analysis.MoveSquares
+	29, // 44: analysis.GameMetrics.resilience:type_name -> analysis.Resilience
+	4,  // 45: analysis.GameMetrics.accuracy_model:type_name -> analysis.AccuracyModel
+	32, // 46: analysis.BestMovesResponse.moves:type_name -> analysis.BestMove
+	8,  // 47: analysis.BestMove.evaluation:type_name -> analysis.Evaluation
+	37, // 48: analysis.ServiceInfo.thresholds:type_name -> analysis.ClassificationThresholds
+	76, // 49: analysis.ServiceInfo.threshold_profiles:type_name -> analysis.ServiceInfo.ThresholdProfilesEntry
+	11, // 50: analysis.ExportGameAnalysisRequest.analysis:type_name -> analysis.GameAnalysis
+	5,  // 51: analysis.ExportGameAnalysisRequest.format:type_name -> analysis.ExportFormat
+	41, // 52: analysis.RecomputeMetricsRequest.moves:type_name -> analysis.StoredMoveEvaluation
+	4,  // 53: analysis.RecomputeMetricsRequest.accuracy_model:type_name -> analysis.AccuracyModel
+	3,  // 54: analysis.RecomputeMetricsRequest.eval_perspective:type_name -> analysis.EvalPerspective
+	8,  // 55: analysis.StoredMoveEvaluation.eval_before:type_name -> analysis.Evaluation
+	8,  // 56: analysis.StoredMoveEvaluation.eval_after:type_name -> analysis.Evaluation
+	28, // 57: analysis.RecomputeMetricsResponse.white_metrics:type_name -> analysis.GameMetrics
+	28, // 58: analysis.RecomputeMetricsResponse.black_metrics:type_name -> analysis.GameMetrics
+	37, // 59: analysis.RecomputeMetricsResponse.thresholds:type_name -> analysis.ClassificationThresholds
+	44, // 60: analysis.AggregateAnalysesRequest.games:type_name -> analysis.AnalyzedGame
+	11, // 61: analysis.AnalyzedGame.analysis:type_name -> analysis.GameAnalysis
+	46, // 62: analysis.PlayerReport.time_classes:type_name -> analysis.TimeClassStats
+	47, // 63: analysis.PlayerReport.openings:type_name -> analysis.OpeningRecord
+	48, // 64: analysis.PlayerReport.accuracy_buckets:type_name -> analysis.AccuracyBucket
+	49, // 65: analysis.PlayerReport.phases:type_name -> analysis.PhaseStats
+	50, // 66: analysis.PlayerReport.trend:type_name -> analysis.GameTrendPoint
+	44, // 67: analysis.AggregateOpeningsRequest.games:type_name -> analysis.AnalyzedGame
+	53, // 68: analysis.OpeningsReport.openings:type_name -> analysis.OpeningStats
+	77, // 69: analysis.ImportEvaluationsResponse.sources:type_name -> analysis.ImportEvaluationsResponse.SourcesEntry
+	78, // 70: analysis.ImportEvaluationsResponse.cache_by_source:type_name -> analysis.ImportEvaluationsResponse.CacheBySourceEntry
+	67, // 71: analysis.AnalysisStats.depth_timings:type_name -> analysis.DepthTiming
+	66, // 72: analysis.AnalysisStats.degradation:type_name -> analysis.Degradation
+	62, // 73: analysis.AnalysisStats.pool_usage:type_name -> analysis.EnginePoolUsage
+	65, // 74: analysis.AnalysisStats.eval_drift:type_name -> analysis.EvalDrift
+	63, // 75: analysis.EnginePoolUsage.tags:type_name -> analysis.EngineTagUsage
+	64, // 76: analysis.EnginePoolUsage.engines:type_name -> analysis.EngineUsage
+	79, // 77: analysis.EngineUsage.busy_ms:type_name -> analysis.EngineUsage.BusyMsEntry
+	67, // 78: analysis.DepthTiming.phases:type_name -> analysis.DepthTiming
+	37, // 79: analysis.ServiceInfo.ThresholdProfilesEntry.value:type_name -> analysis.ClassificationThresholds
+	6,  // 80: analysis.AnalysisService.AnalyzePosition:input_type -> analysis.AnalyzePositionRequest
+	6,  // 81: analysis.AnalysisService.AnalyzePositionStream:input_type -> analysis.AnalyzePositionRequest
+	9,  // 82: analysis.AnalysisService.AnalyzeGame:input_type -> analysis.AnalyzeGameRequest
+	9,  // 83: analysis.AnalysisService.AnalyzeGameStream:input_type -> analysis.AnalyzeGameRequest
+	30, // 84: analysis.AnalysisService.GetBestMoves:input_type -> analysis.GetBestMovesRequest
+	33, // 85: analysis.AnalysisService.HealthCheck:input_type -> analysis.HealthCheckRequest
+	35, // 86: analysis.AnalysisService.GetServiceInfo:input_type -> analysis.GetServiceInfoRequest
+	38, // 87: analysis.AnalysisService.ExportGameAnalysis:input_type -> analysis.ExportGameAnalysisRequest
+	43, // 88: analysis.AnalysisService.AggregateAnalyses:input_type -> analysis.AggregateAnalysesRequest
+	51, // 89: analysis.AnalysisService.AggregateOpenings:input_type -> analysis.AggregateOpeningsRequest
+	20, // 90: analysis.AnalysisService.DiffAnalyses:input_type -> analysis.DiffAnalysesRequest
+	40, // 91: analysis.AnalysisService.RecomputeMetrics:input_type -> analysis.RecomputeMetricsRequest
+	54, // 92: analysis.AnalysisService.GetQuota:input_type -> analysis.GetQuotaRequest
+	56, // 93: analysis.AdminService.SetLogLevel:input_type -> analysis.SetLogLevelRequest
+	58, // 94: analysis.AdminService.ImportEvaluations:input_type -> analysis.ImportEvaluationsRequest
+	60, // 95: analysis.AdminService.GetAnalysisStats:input_type -> analysis.GetAnalysisStatsRequest
+	68, // 96: analysis.AdminService.GetCapacityEstimate:input_type -> analysis.GetCapacityEstimateRequest
+	70, // 97: analysis.AdminService.GetEngineTranscript:input_type -> analysis.GetEngineTranscriptRequest
+	72, // 98: analysis.AdminService.WarmCache:input_type -> analysis.WarmCacheRequest
+	7,  // 99: analysis.AnalysisService.AnalyzePosition:output_type -> analysis.PositionAnalysis
+	7,  // 100: analysis.AnalysisService.AnalyzePositionStream:output_type -> analysis.PositionAnalysis
+	11, // 101: analysis.AnalysisService.AnalyzeGame:output_type -> analysis.GameAnalysis
+	22, // 102: analysis.AnalysisService.AnalyzeGameStream:output_type -> analysis.GameAnalysisProgress
+	31, // 103: analysis.AnalysisService.GetBestMoves:output_type -> analysis.BestMovesResponse
+	34, // 104: analysis.AnalysisService.HealthCheck:output_type -> analysis.HealthCheckResponse
+	36, // 105: analysis.AnalysisService.GetServiceInfo:output_type -> analysis.ServiceInfo
+	39, // 106: analysis.AnalysisService.ExportGameAnalysis:output_type -> analysis.ExportGameAnalysisResponse
+	45, // 107: analysis.AnalysisService.AggregateAnalyses:output_type -> analysis.PlayerReport
+	52, // 108: analysis.AnalysisService.AggregateOpenings:output_type -> analysis.OpeningsReport
+	18, // 109: analysis.AnalysisService.DiffAnalyses:output_type -> analysis.AnalysisDiff
+	42, // 110: analysis.AnalysisService.RecomputeMetrics:output_type -> analysis.RecomputeMetricsResponse
+	55, // 111: analysis.AnalysisService.GetQuota:output_type -> analysis.QuotaUsage
+	57, // 112: analysis.AdminService.SetLogLevel:output_type -> analysis.SetLogLevelResponse
+	59, // 113: analysis.AdminService.ImportEvaluations:output_type -> analysis.ImportEvaluationsResponse
+	61, // 114: analysis.AdminService.GetAnalysisStats:output_type -> analysis.AnalysisStats
+	69, // 115: analysis.AdminService.GetCapacityEstimate:output_type -> analysis.CapacityEstimate
+	71, // 116: analysis.AdminService.GetEngineTranscript:output_type -> analysis.EngineTranscript
+	73, // 117: analysis.AdminService.WarmCache:output_type -> analysis.WarmCacheProgress
+	99, // [99:118] is the sub-list for method output_type
+	80, // [80:99] is the sub-list for method input_type
+	80, // [80:80] is the sub-list for extension type_name
+	80, // [80:80] is the sub-list for extension extendee
+	0,  // [0:80] is the sub-list for field type_name
 }
 
 func init() { file_proto_analysis_proto_init() }
@@ -1431,19 +8186,24 @@ func file_proto_analysis_proto_init() {
 	if File_proto_analysis_proto != nil {
 		return
 	}
+	file_proto_analysis_proto_msgTypes[0].OneofWrappers = []any{}
 	file_proto_analysis_proto_msgTypes[2].OneofWrappers = []any{
 		(*Evaluation_Centipawns)(nil),
 		(*Evaluation_MateIn)(nil),
 	}
+	file_proto_analysis_proto_msgTypes[3].OneofWrappers = []any{}
+	file_proto_analysis_proto_msgTypes[6].OneofWrappers = []any{}
+	file_proto_analysis_proto_msgTypes[34].OneofWrappers = []any{}
+	file_proto_analysis_proto_msgTypes[50].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_analysis_proto_rawDesc), len(file_proto_analysis_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   13,
+			NumEnums:      6,
+			NumMessages:   74,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_proto_analysis_proto_goTypes,
 		DependencyIndexes: file_proto_analysis_proto_depIdxs,
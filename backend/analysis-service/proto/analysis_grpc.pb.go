@@ -25,6 +25,13 @@ const (
 	AnalysisService_AnalyzeGameStream_FullMethodName     = "/analysis.AnalysisService/AnalyzeGameStream"
 	AnalysisService_GetBestMoves_FullMethodName          = "/analysis.AnalysisService/GetBestMoves"
 	AnalysisService_HealthCheck_FullMethodName           = "/analysis.AnalysisService/HealthCheck"
+	AnalysisService_GetServiceInfo_FullMethodName        = "/analysis.AnalysisService/GetServiceInfo"
+	AnalysisService_ExportGameAnalysis_FullMethodName    = "/analysis.AnalysisService/ExportGameAnalysis"
+	AnalysisService_AggregateAnalyses_FullMethodName     = "/analysis.AnalysisService/AggregateAnalyses"
+	AnalysisService_AggregateOpenings_FullMethodName     = "/analysis.AnalysisService/AggregateOpenings"
+	AnalysisService_DiffAnalyses_FullMethodName          = "/analysis.AnalysisService/DiffAnalyses"
+	AnalysisService_RecomputeMetrics_FullMethodName      = "/analysis.AnalysisService/RecomputeMetrics"
+	AnalysisService_GetQuota_FullMethodName              = "/analysis.AnalysisService/GetQuota"
 )
 
 // AnalysisServiceClient is the client API for AnalysisService service.
@@ -45,6 +52,20 @@ type AnalysisServiceClient interface {
 	GetBestMoves(ctx context.Context, in *GetBestMovesRequest, opts ...grpc.CallOption) (*BestMovesResponse, error)
 	// Health check
 	HealthCheck(ctx context.Context, in *HealthCheckRequest, opts ...grpc.CallOption) (*HealthCheckResponse, error)
+	// Build and configuration info of the running service
+	GetServiceInfo(ctx context.Context, in *GetServiceInfoRequest, opts ...grpc.CallOption) (*ServiceInfo, error)
+	// Export a game analysis as an annotated PGN or as JSON
+	ExportGameAnalysis(ctx context.Context, in *ExportGameAnalysisRequest, opts ...grpc.CallOption) (*ExportGameAnalysisResponse, error)
+	// Aggregate a player's analyzed games into a report
+	AggregateAnalyses(ctx context.Context, in *AggregateAnalysesRequest, opts ...grpc.CallOption) (*PlayerReport, error)
+	// Aggregate a player's results by opening family and color
+	AggregateOpenings(ctx context.Context, in *AggregateOpeningsRequest, opts ...grpc.CallOption) (*OpeningsReport, error)
+	// Compare two analyses of the same game, e.g. a quick one and a deeper re-analysis
+	DiffAnalyses(ctx context.Context, in *DiffAnalysesRequest, opts ...grpc.CallOption) (*AnalysisDiff, error)
+	// Score stored per-move evaluations again under other thresholds or accuracy model, without searching
+	RecomputeMetrics(ctx context.Context, in *RecomputeMetricsRequest, opts ...grpc.CallOption) (*RecomputeMetricsResponse, error)
+	// Engine time the caller has used today against its daily quota
+	GetQuota(ctx context.Context, in *GetQuotaRequest, opts ...grpc.CallOption) (*QuotaUsage, error)
 }
 
 type analysisServiceClient struct {
@@ -133,6 +154,76 @@ func (c *analysisServiceClient) HealthCheck(ctx context.Context, in *HealthCheck
 	return out, nil
 }
 
+func (c *analysisServiceClient) GetServiceInfo(ctx context.Context, in *GetServiceInfoRequest, opts ...grpc.CallOption) (*ServiceInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServiceInfo)
+	err := c.cc.Invoke(ctx, AnalysisService_GetServiceInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analysisServiceClient) ExportGameAnalysis(ctx context.Context, in *ExportGameAnalysisRequest, opts ...grpc.CallOption) (*ExportGameAnalysisResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExportGameAnalysisResponse)
+	err := c.cc.Invoke(ctx, AnalysisService_ExportGameAnalysis_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analysisServiceClient) AggregateAnalyses(ctx context.Context, in *AggregateAnalysesRequest, opts ...grpc.CallOption) (*PlayerReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PlayerReport)
+	err := c.cc.Invoke(ctx, AnalysisService_AggregateAnalyses_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analysisServiceClient) AggregateOpenings(ctx context.Context, in *AggregateOpeningsRequest, opts ...grpc.CallOption) (*OpeningsReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OpeningsReport)
+	err := c.cc.Invoke(ctx, AnalysisService_AggregateOpenings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analysisServiceClient) DiffAnalyses(ctx context.Context, in *DiffAnalysesRequest, opts ...grpc.CallOption) (*AnalysisDiff, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AnalysisDiff)
+	err := c.cc.Invoke(ctx, AnalysisService_DiffAnalyses_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analysisServiceClient) RecomputeMetrics(ctx context.Context, in *RecomputeMetricsRequest, opts ...grpc.CallOption) (*RecomputeMetricsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecomputeMetricsResponse)
+	err := c.cc.Invoke(ctx, AnalysisService_RecomputeMetrics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analysisServiceClient) GetQuota(ctx context.Context, in *GetQuotaRequest, opts ...grpc.CallOption) (*QuotaUsage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QuotaUsage)
+	err := c.cc.Invoke(ctx, AnalysisService_GetQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AnalysisServiceServer is the server API for AnalysisService service.
 // All implementations must embed UnimplementedAnalysisServiceServer
 // for forward compatibility.
@@ -151,6 +242,20 @@ type AnalysisServiceServer interface {
 	GetBestMoves(context.Context, *GetBestMovesRequest) (*BestMovesResponse, error)
 	// Health check
 	HealthCheck(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error)
+	// Build and configuration info of the running service
+	GetServiceInfo(context.Context, *GetServiceInfoRequest) (*ServiceInfo, error)
+	// Export a game analysis as an annotated PGN or as JSON
+	ExportGameAnalysis(context.Context, *ExportGameAnalysisRequest) (*ExportGameAnalysisResponse, error)
+	// Aggregate a player's analyzed games into a report
+	AggregateAnalyses(context.Context, *AggregateAnalysesRequest) (*PlayerReport, error)
+	// Aggregate a player's results by opening family and color
+	AggregateOpenings(context.Context, *AggregateOpeningsRequest) (*OpeningsReport, error)
+	// Compare two analyses of the same game, e.g. a quick one and a deeper re-analysis
+	DiffAnalyses(context.Context, *DiffAnalysesRequest) (*AnalysisDiff, error)
+	// Score stored per-move evaluations again under other thresholds or accuracy model, without searching
+	RecomputeMetrics(context.Context, *RecomputeMetricsRequest) (*RecomputeMetricsResponse, error)
+	// Engine time the caller has used today against its daily quota
+	GetQuota(context.Context, *GetQuotaRequest) (*QuotaUsage, error)
 	mustEmbedUnimplementedAnalysisServiceServer()
 }
 
@@ -179,6 +284,27 @@ func (UnimplementedAnalysisServiceServer) GetBestMoves(context.Context, *GetBest
 func (UnimplementedAnalysisServiceServer) HealthCheck(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method HealthCheck not implemented")
 }
+func (UnimplementedAnalysisServiceServer) GetServiceInfo(context.Context, *GetServiceInfoRequest) (*ServiceInfo, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetServiceInfo not implemented")
+}
+func (UnimplementedAnalysisServiceServer) ExportGameAnalysis(context.Context, *ExportGameAnalysisRequest) (*ExportGameAnalysisResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ExportGameAnalysis not implemented")
+}
+func (UnimplementedAnalysisServiceServer) AggregateAnalyses(context.Context, *AggregateAnalysesRequest) (*PlayerReport, error) {
+	return nil, status.Error(codes.Unimplemented, "method AggregateAnalyses not implemented")
+}
+func (UnimplementedAnalysisServiceServer) AggregateOpenings(context.Context, *AggregateOpeningsRequest) (*OpeningsReport, error) {
+	return nil, status.Error(codes.Unimplemented, "method AggregateOpenings not implemented")
+}
+func (UnimplementedAnalysisServiceServer) DiffAnalyses(context.Context, *DiffAnalysesRequest) (*AnalysisDiff, error) {
+	return nil, status.Error(codes.Unimplemented, "method DiffAnalyses not implemented")
+}
+func (UnimplementedAnalysisServiceServer) RecomputeMetrics(context.Context, *RecomputeMetricsRequest) (*RecomputeMetricsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RecomputeMetrics not implemented")
+}
+func (UnimplementedAnalysisServiceServer) GetQuota(context.Context, *GetQuotaRequest) (*QuotaUsage, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetQuota not implemented")
+}
 func (UnimplementedAnalysisServiceServer) mustEmbedUnimplementedAnalysisServiceServer() {}
 func (UnimplementedAnalysisServiceServer) testEmbeddedByValue()                         {}
 
@@ -294,6 +420,132 @@ func _AnalysisService_HealthCheck_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AnalysisService_GetServiceInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetServiceInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalysisServiceServer).GetServiceInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalysisService_GetServiceInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalysisServiceServer).GetServiceInfo(ctx, req.(*GetServiceInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalysisService_ExportGameAnalysis_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportGameAnalysisRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalysisServiceServer).ExportGameAnalysis(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalysisService_ExportGameAnalysis_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalysisServiceServer).ExportGameAnalysis(ctx, req.(*ExportGameAnalysisRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalysisService_AggregateAnalyses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AggregateAnalysesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalysisServiceServer).AggregateAnalyses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalysisService_AggregateAnalyses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalysisServiceServer).AggregateAnalyses(ctx, req.(*AggregateAnalysesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalysisService_AggregateOpenings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AggregateOpeningsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalysisServiceServer).AggregateOpenings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalysisService_AggregateOpenings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalysisServiceServer).AggregateOpenings(ctx, req.(*AggregateOpeningsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalysisService_DiffAnalyses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiffAnalysesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalysisServiceServer).DiffAnalyses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalysisService_DiffAnalyses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalysisServiceServer).DiffAnalyses(ctx, req.(*DiffAnalysesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalysisService_RecomputeMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecomputeMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalysisServiceServer).RecomputeMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalysisService_RecomputeMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalysisServiceServer).RecomputeMetrics(ctx, req.(*RecomputeMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalysisService_GetQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalysisServiceServer).GetQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalysisService_GetQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalysisServiceServer).GetQuota(ctx, req.(*GetQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AnalysisService_ServiceDesc is the grpc.ServiceDesc for AnalysisService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -317,6 +569,34 @@ var AnalysisService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "HealthCheck",
 			Handler:    _AnalysisService_HealthCheck_Handler,
 		},
+		{
+			MethodName: "GetServiceInfo",
+			Handler:    _AnalysisService_GetServiceInfo_Handler,
+		},
+		{
+			MethodName: "ExportGameAnalysis",
+			Handler:    _AnalysisService_ExportGameAnalysis_Handler,
+		},
+		{
+			MethodName: "AggregateAnalyses",
+			Handler:    _AnalysisService_AggregateAnalyses_Handler,
+		},
+		{
+			MethodName: "AggregateOpenings",
+			Handler:    _AnalysisService_AggregateOpenings_Handler,
+		},
+		{
+			MethodName: "DiffAnalyses",
+			Handler:    _AnalysisService_DiffAnalyses_Handler,
+		},
+		{
+			MethodName: "RecomputeMetrics",
+			Handler:    _AnalysisService_RecomputeMetrics_Handler,
+		},
+		{
+			MethodName: "GetQuota",
+			Handler:    _AnalysisService_GetQuota_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -332,3 +612,319 @@ var AnalysisService_ServiceDesc = grpc.ServiceDesc{
 	},
 	Metadata: "proto/analysis.proto",
 }
+
+const (
+	AdminService_SetLogLevel_FullMethodName         = "/analysis.AdminService/SetLogLevel"
+	AdminService_ImportEvaluations_FullMethodName   = "/analysis.AdminService/ImportEvaluations"
+	AdminService_GetAnalysisStats_FullMethodName    = "/analysis.AdminService/GetAnalysisStats"
+	AdminService_GetCapacityEstimate_FullMethodName = "/analysis.AdminService/GetCapacityEstimate"
+	AdminService_GetEngineTranscript_FullMethodName = "/analysis.AdminService/GetEngineTranscript"
+	AdminService_WarmCache_FullMethodName           = "/analysis.AdminService/WarmCache"
+)
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AdminService exposes operational controls for the running service
+type AdminServiceClient interface {
+	// Change the log level, reverting automatically after a timeout
+	SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*SetLogLevelResponse, error)
+	// Load precomputed evaluations into the position cache
+	ImportEvaluations(ctx context.Context, in *ImportEvaluationsRequest, opts ...grpc.CallOption) (*ImportEvaluationsResponse, error)
+	// Engine time per position the analyzer has measured, by depth
+	GetAnalysisStats(ctx context.Context, in *GetAnalysisStatsRequest, opts ...grpc.CallOption) (*AnalysisStats, error)
+	// Game analyses per hour the engine pool can finish at a depth, from the
+	// engine time measured
+	GetCapacityEstimate(ctx context.Context, in *GetCapacityEstimateRequest, opts ...grpc.CallOption) (*CapacityEstimate, error)
+	// UCI conversation of a position of a game analyzed with record_engine_output
+	GetEngineTranscript(ctx context.Context, in *GetEngineTranscriptRequest, opts ...grpc.CallOption) (*EngineTranscript, error)
+	// Search positions into the cache ahead of games expected to reach them,
+	// as background work, streaming progress after each search
+	WarmCache(ctx context.Context, in *WarmCacheRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WarmCacheProgress], error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*SetLogLevelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetLogLevelResponse)
+	err := c.cc.Invoke(ctx, AdminService_SetLogLevel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) ImportEvaluations(ctx context.Context, in *ImportEvaluationsRequest, opts ...grpc.CallOption) (*ImportEvaluationsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportEvaluationsResponse)
+	err := c.cc.Invoke(ctx, AdminService_ImportEvaluations_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetAnalysisStats(ctx context.Context, in *GetAnalysisStatsRequest, opts ...grpc.CallOption) (*AnalysisStats, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AnalysisStats)
+	err := c.cc.Invoke(ctx, AdminService_GetAnalysisStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetCapacityEstimate(ctx context.Context, in *GetCapacityEstimateRequest, opts ...grpc.CallOption) (*CapacityEstimate, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CapacityEstimate)
+	err := c.cc.Invoke(ctx, AdminService_GetCapacityEstimate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetEngineTranscript(ctx context.Context, in *GetEngineTranscriptRequest, opts ...grpc.CallOption) (*EngineTranscript, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EngineTranscript)
+	err := c.cc.Invoke(ctx, AdminService_GetEngineTranscript_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) WarmCache(ctx context.Context, in *WarmCacheRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WarmCacheProgress], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AdminService_ServiceDesc.Streams[0], AdminService_WarmCache_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WarmCacheRequest, WarmCacheProgress]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AdminService_WarmCacheClient = grpc.ServerStreamingClient[WarmCacheProgress]
+
+// AdminServiceServer is the server API for AdminService service.
+// All implementations must embed UnimplementedAdminServiceServer
+// for forward compatibility.
+//
+// AdminService exposes operational controls for the running service
+type AdminServiceServer interface {
+	// Change the log level, reverting automatically after a timeout
+	SetLogLevel(context.Context, *SetLogLevelRequest) (*SetLogLevelResponse, error)
+	// Load precomputed evaluations into the position cache
+	ImportEvaluations(context.Context, *ImportEvaluationsRequest) (*ImportEvaluationsResponse, error)
+	// Engine time per position the analyzer has measured, by depth
+	GetAnalysisStats(context.Context, *GetAnalysisStatsRequest) (*AnalysisStats, error)
+	// Game analyses per hour the engine pool can finish at a depth, from the
+	// engine time measured
+	GetCapacityEstimate(context.Context, *GetCapacityEstimateRequest) (*CapacityEstimate, error)
+	// UCI conversation of a position of a game analyzed with record_engine_output
+	GetEngineTranscript(context.Context, *GetEngineTranscriptRequest) (*EngineTranscript, error)
+	// Search positions into the cache ahead of games expected to reach them,
+	// as background work, streaming progress after each search
+	WarmCache(*WarmCacheRequest, grpc.ServerStreamingServer[WarmCacheProgress]) error
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+// UnimplementedAdminServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServiceServer struct{}
+
+func (UnimplementedAdminServiceServer) SetLogLevel(context.Context, *SetLogLevelRequest) (*SetLogLevelResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetLogLevel not implemented")
+}
+func (UnimplementedAdminServiceServer) ImportEvaluations(context.Context, *ImportEvaluationsRequest) (*ImportEvaluationsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ImportEvaluations not implemented")
+}
+func (UnimplementedAdminServiceServer) GetAnalysisStats(context.Context, *GetAnalysisStatsRequest) (*AnalysisStats, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetAnalysisStats not implemented")
+}
+func (UnimplementedAdminServiceServer) GetCapacityEstimate(context.Context, *GetCapacityEstimateRequest) (*CapacityEstimate, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetCapacityEstimate not implemented")
+}
+func (UnimplementedAdminServiceServer) GetEngineTranscript(context.Context, *GetEngineTranscriptRequest) (*EngineTranscript, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetEngineTranscript not implemented")
+}
+func (UnimplementedAdminServiceServer) WarmCache(*WarmCacheRequest, grpc.ServerStreamingServer[WarmCacheProgress]) error {
+	return status.Error(codes.Unimplemented, "method WarmCache not implemented")
+}
+func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
+func (UnimplementedAdminServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServiceServer will
+// result in compilation errors.
+type UnsafeAdminServiceServer interface {
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+func RegisterAdminServiceServer(s grpc.ServiceRegistrar, srv AdminServiceServer) {
+	// If the following call panics, it indicates UnimplementedAdminServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AdminService_ServiceDesc, srv)
+}
+
+func _AdminService_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLogLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_SetLogLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetLogLevel(ctx, req.(*SetLogLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ImportEvaluations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportEvaluationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ImportEvaluations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_ImportEvaluations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ImportEvaluations(ctx, req.(*ImportEvaluationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetAnalysisStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAnalysisStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetAnalysisStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_GetAnalysisStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetAnalysisStats(ctx, req.(*GetAnalysisStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetCapacityEstimate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCapacityEstimateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetCapacityEstimate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_GetCapacityEstimate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetCapacityEstimate(ctx, req.(*GetCapacityEstimateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetEngineTranscript_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEngineTranscriptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetEngineTranscript(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_GetEngineTranscript_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetEngineTranscript(ctx, req.(*GetEngineTranscriptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_WarmCache_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WarmCacheRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AdminServiceServer).WarmCache(m, &grpc.GenericServerStream[WarmCacheRequest, WarmCacheProgress]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AdminService_WarmCacheServer = grpc.ServerStreamingServer[WarmCacheProgress]
+
+// AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "analysis.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SetLogLevel",
+			Handler:    _AdminService_SetLogLevel_Handler,
+		},
+		{
+			MethodName: "ImportEvaluations",
+			Handler:    _AdminService_ImportEvaluations_Handler,
+		},
+		{
+			MethodName: "GetAnalysisStats",
+			Handler:    _AdminService_GetAnalysisStats_Handler,
+		},
+		{
+			MethodName: "GetCapacityEstimate",
+			Handler:    _AdminService_GetCapacityEstimate_Handler,
+		},
+		{
+			MethodName: "GetEngineTranscript",
+			Handler:    _AdminService_GetEngineTranscript_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WarmCache",
+			Handler:       _AdminService_WarmCache_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/analysis.proto",
+}
@@ -92,6 +92,9 @@ export interface GameAnalysisProgress {
     totalMoves: number;
     progressPercent: number;
     moveAnalysis?: MoveAnalysis;
+    // Moves held back while progress updates are coalesced, in order,
+    // all before moveAnalysis
+    batchedMoves?: MoveAnalysis[];
     status: string;
     errorMessage?: string;
 }
@@ -408,6 +408,7 @@ export class AnalysisController {
                     moveAnalysis: progress.moveAnalysis
                         ? this.mapMoveAnalysis(progress.moveAnalysis)
                         : undefined,
+                    batchedMoves: (progress.batchedMoves || []).map((m) => this.mapMoveAnalysis(m)),
                 },
             } as MessageEvent)),
             catchError((error) => {